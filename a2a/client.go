@@ -0,0 +1,437 @@
+package a2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// ClientOption is a functional option for configuring a Client
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the HTTP client used to reach the remote agent
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithHeader adds a header to every request to the remote agent, such as an
+// Authorization header
+func WithHeader(key string, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// Client calls a remote agent over the A2A protocol
+type Client struct {
+	url        string
+	httpClient *http.Client
+	header     http.Header
+	nextID     atomic.Int64
+}
+
+// NewClient creates a client for the agent served at url
+func NewClient(url string, opts ...ClientOption) *Client {
+	c := &Client{
+		url:        strings.TrimRight(url, "/"),
+		httpClient: http.DefaultClient,
+		header:     http.Header{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Card fetches the remote agent's card
+func (c *Client) Card(ctx context.Context) (Card, error) {
+	var card Card
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+CardPath, nil)
+	if err != nil {
+		return card, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return card, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return card, fmt.Errorf("fetch agent card: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
+		return card, fmt.Errorf("decode agent card: %w", err)
+	}
+	return card, nil
+}
+
+// Send sends a message to the remote agent and returns the resulting task once
+// it finishes. Errors reported by the agent are returned as *Error.
+func (c *Client) Send(ctx context.Context, msg Message) (*Task, error) {
+	if msg.Kind == "" {
+		msg.Kind = "message"
+	}
+	if msg.Role == "" {
+		msg.Role = RoleUser
+	}
+	if msg.MessageID == "" {
+		msg.MessageID = newID()
+	}
+	var task Task
+	params := MessageSendParams{Message: msg, Configuration: &MessageSendConfiguration{Blocking: true}}
+	if err := c.call(ctx, "message/send", params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetTask returns the current state of a task
+func (c *Client) GetTask(ctx context.Context, id string) (*Task, error) {
+	var task Task
+	if err := c.call(ctx, "tasks/get", TaskQueryParams{ID: id}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CancelTask cancels a running task
+func (c *Client) CancelTask(ctx context.Context, id string) (*Task, error) {
+	var task Task
+	if err := c.call(ctx, "tasks/cancel", TaskIDParams{ID: id}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	id, _ := json.Marshal(c.nextID.Add(1))
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for key, values := range c.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return c.httpClient.Do(req)
+}
+
+// Reply returns the text of a finished task: its artifacts when it completed,
+// or an error describing why it did not
+func Reply(task *Task) (string, error) {
+	switch task.Status.State {
+	case TaskStateCompleted:
+		var texts []string
+		for _, artifact := range task.Artifacts {
+			texts = append(texts, partsText(artifact.Parts)...)
+		}
+		return strings.Join(texts, "\n\n"), nil
+	case TaskStateFailed, TaskStateCanceled:
+		err := fmt.Sprintf("remote task %s", task.Status.State)
+		if task.Status.Message != nil {
+			err += ": " + strings.Join(partsText(task.Status.Message.Parts), "\n")
+		}
+		return "", errors.New(err)
+	default:
+		return "", fmt.Errorf("remote task is %s", task.Status.State)
+	}
+}
+
+// partsText returns the text and data parts as strings
+func partsText(parts []Part) []string {
+	var texts []string
+	for _, part := range parts {
+		switch part.Kind {
+		case "text":
+			texts = append(texts, part.Text)
+		case "data":
+			data, _ := json.Marshal(part.Data)
+			texts = append(texts, string(data))
+		}
+	}
+	return texts
+}
+
+// Tool exposes the remote agent as a tool. Each call sends the model's message
+// as a new conversation and returns the agent's reply.
+func (c *Client) Tool(name string, description string) agent.Tool {
+	return remoteTool{client: c, name: name, description: description}
+}
+
+type remoteTool struct {
+	client      *Client
+	name        string
+	description string
+}
+
+func (t remoteTool) Name() string {
+	return t.name
+}
+
+func (t remoteTool) Description() string {
+	return t.description
+}
+
+func (t remoteTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"message": agent.StringSchema("The request for the agent, with all the context it needs"),
+		},
+		Required: []string{"message"},
+	}
+}
+
+func (t remoteTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	message, ok := input["message"].(string)
+	if !ok {
+		return nil, errors.New("message must be a string")
+	}
+	task, err := t.client.Send(ctx, Message{Parts: []Part{TextPart(message)}})
+	if err != nil {
+		return nil, err
+	}
+	return Reply(task)
+}
+
+// NewAgent returns a local agent backed by the remote agent, so it can be used
+// anywhere an *agent.Agent is, such as a handoff target or a supervisor worker.
+// Each run sends the conversation's latest user turn, with any earlier turns
+// included as a transcript, and the remote agent uses its own tools. Local
+// system prompts and tools are not sent.
+func NewAgent(c *Client, opts ...agent.AgentOption) *agent.Agent {
+	client := openai.NewClient(
+		option.WithAPIKey("a2a"),
+		option.WithBaseURL("https://a2a.invalid/"),
+		option.WithHTTPClient(&http.Client{Transport: chatTransport{client: c}}),
+	)
+	return agent.NewAgentWithClient(client, "a2a", opts...)
+}
+
+// chatTransport serves OpenAI chat completion requests by sending A2A messages
+type chatTransport struct {
+	client *Client
+}
+
+// chatMessage is the subset of an OpenAI chat message sent to a remote agent
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+	File struct {
+		FileData string `json:"file_data"`
+		Filename string `json:"filename"`
+	} `json:"file"`
+}
+
+func (t chatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return errorResponse(req, http.StatusNotFound, fmt.Sprintf("%s %s is not supported by A2A agents", req.Method, req.URL.Path)), nil
+	}
+
+	var chat struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("decode chat completion request: %w", err)
+	}
+	req.Body.Close()
+
+	parts, err := toParts(chat.Messages)
+	if err != nil {
+		return errorResponse(req, http.StatusBadRequest, err.Error()), nil
+	}
+
+	task, err := t.client.Send(req.Context(), Message{Parts: parts})
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return errorResponse(req, http.StatusBadRequest, rpcErr.Message), nil
+	} else if err != nil {
+		return nil, err
+	}
+	reply, err := Reply(task)
+	if err != nil {
+		// Not a server error status, which the client would retry
+		return errorResponse(req, http.StatusUnprocessableEntity, err.Error()), nil
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"id":     task.ID,
+		"object": "chat.completion",
+		"model":  chat.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]any{"role": "assistant", "content": reply},
+		}},
+		"usage": map[string]any{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(req, http.StatusOK, data), nil
+}
+
+// toParts converts a conversation into the parts of one A2A message: a
+// transcript of earlier turns, then the user messages after the last reply
+func toParts(messages []chatMessage) ([]Part, error) {
+	latest := len(messages)
+	for latest > 0 && messages[latest-1].Role == "user" {
+		latest--
+	}
+	if latest == len(messages) {
+		return nil, errors.New("conversation must end with a user message")
+	}
+
+	var parts []Part
+	var transcript []string
+	for _, msg := range messages[:latest] {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		content, err := toMessageParts(msg.Content)
+		if err != nil {
+			return nil, err
+		}
+		if text := strings.Join(partsText(content), "\n"); text != "" {
+			transcript = append(transcript, msg.Role+": "+text)
+		}
+	}
+	if len(transcript) > 0 {
+		parts = append(parts, TextPart("Earlier conversation:\n\n"+strings.Join(transcript, "\n\n")))
+	}
+
+	for _, msg := range messages[latest:] {
+		content, err := toMessageParts(msg.Content)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, content...)
+	}
+	return parts, nil
+}
+
+// toMessageParts converts OpenAI message content, a string or an array of parts
+func toMessageParts(content json.RawMessage) ([]Part, error) {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		if text == "" {
+			return nil, nil
+		}
+		return []Part{TextPart(text)}, nil
+	}
+
+	var chatParts []chatContentPart
+	if err := json.Unmarshal(content, &chatParts); err != nil {
+		return nil, fmt.Errorf("decode message content: %w", err)
+	}
+	var parts []Part
+	for _, part := range chatParts {
+		switch part.Type {
+		case "text":
+			parts = append(parts, TextPart(part.Text))
+		case "image_url":
+			mimeType, data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ";base64,")
+			if !ok {
+				return nil, errors.New("images must be sent as base64 data URLs")
+			}
+			file, err := fileContent("", mimeType, data)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, Part{Kind: "file", File: file})
+		case "file":
+			file, err := fileContent(part.File.Filename, "", part.File.FileData)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, Part{Kind: "file", File: file})
+		default:
+			return nil, fmt.Errorf("unsupported content part %q", part.Type)
+		}
+	}
+	return parts, nil
+}
+
+func fileContent(name string, mimeType string, data string) (*FileContent, error) {
+	bytes, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode file data: %w", err)
+	}
+	return &FileContent{Name: name, MimeType: mimeType, Bytes: bytes}, nil
+}
+
+// errorResponse builds an OpenAI error response
+func errorResponse(req *http.Request, status int, message string) *http.Response {
+	data, _ := json.Marshal(map[string]any{
+		"error": map[string]any{"message": message, "type": "a2a_error"},
+	})
+	return jsonResponse(req, status, data)
+}
+
+func jsonResponse(req *http.Request, status int, data []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,91 @@
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProvider replies with the content of the last message it received
+func echoProvider(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		reply, _ := json.Marshal(body.Messages[len(body.Messages)-1].Content)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":"test","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, reply)
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t, echoProvider(t))
+	client := NewClient(server.URL)
+
+	card, err := client.Card(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Support", card.Name)
+
+	task, err := client.Send(context.Background(), Message{Parts: []Part{TextPart("Where is order 42?")}})
+	require.NoError(t, err)
+	reply, err := Reply(task)
+	require.NoError(t, err)
+	assert.Equal(t, "Where is order 42?", reply)
+
+	_, err = client.GetTask(context.Background(), "missing")
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, CodeTaskNotFound, rpcErr.Code)
+}
+
+func TestRemoteTool(t *testing.T) {
+	server := newTestServer(t, echoProvider(t))
+	tool := NewClient(server.URL).Tool("ask_support", "Ask the support agent about an order")
+
+	assert.Equal(t, "ask_support", tool.Name())
+	result, err := tool.Execute(context.Background(), map[string]any{"message": "Where is order 42?"})
+	require.NoError(t, err)
+	assert.Equal(t, "Where is order 42?", result)
+}
+
+func TestRemoteAgent(t *testing.T) {
+	server := newTestServer(t, echoProvider(t))
+	remote := NewAgent(NewClient(server.URL))
+
+	completion, err := remote.ChatCompletion(context.Background(), []agent.Message{
+		agent.UserTextMessage("Where is order 42?"),
+		agent.AssistantTextMessage("It shipped yesterday."),
+		agent.UserTextMessage("And order 43?"),
+	})
+	require.NoError(t, err)
+	require.Len(t, completion.Messages, 1)
+	assert.Equal(t, "Earlier conversation:\n\nuser: Where is order 42?\n\nassistant: It shipped yesterday.\nAnd order 43?", completion.Messages[0])
+}
+
+func TestRemoteAgentFailure(t *testing.T) {
+	calls := 0
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"invalid request","type":"invalid_request_error"}}`)
+	})
+	client := NewClient(server.URL)
+
+	_, err := client.Tool("ask_support", "").Execute(context.Background(), map[string]any{"message": "Hi"})
+	assert.ErrorContains(t, err, "remote task failed")
+
+	_, err = NewAgent(client).ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+	assert.ErrorContains(t, err, "remote task failed")
+	// Failed tasks are not retried
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,167 @@
+package a2a
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Card describes an agent so other agents can discover and invoke it. It is
+// served at /.well-known/agent.json.
+type Card struct {
+	Name               string       `json:"name"`
+	Description        string       `json:"description"`
+	URL                string       `json:"url"`
+	Version            string       `json:"version"`
+	ProtocolVersion    string       `json:"protocolVersion"`
+	Capabilities       Capabilities `json:"capabilities"`
+	DefaultInputModes  []string     `json:"defaultInputModes"`
+	DefaultOutputModes []string     `json:"defaultOutputModes"`
+	Skills             []Skill      `json:"skills"`
+}
+
+// Capabilities are the optional protocol features an agent supports
+type Capabilities struct {
+	Streaming         bool `json:"streaming"`
+	PushNotifications bool `json:"pushNotifications"`
+}
+
+// Skill is a task the agent can perform
+type Skill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+// Role is the sender of a message
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAgent Role = "agent"
+)
+
+// Message is a turn of a conversation between agents
+type Message struct {
+	Kind      string `json:"kind"`
+	Role      Role   `json:"role"`
+	Parts     []Part `json:"parts"`
+	MessageID string `json:"messageId"`
+	ContextID string `json:"contextId,omitempty"`
+	TaskID    string `json:"taskId,omitempty"`
+}
+
+// Part is a piece of message or artifact content: text, a file, or structured data
+type Part struct {
+	Kind string         `json:"kind"`
+	Text string         `json:"text,omitempty"`
+	File *FileContent   `json:"file,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// FileContent is a file carried inline in a part
+type FileContent struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	// Bytes is base64 encoded, as JSON encodes []byte
+	Bytes []byte `json:"bytes"`
+}
+
+// TextPart returns a text part
+func TextPart(text string) Part {
+	return Part{Kind: "text", Text: text}
+}
+
+// TaskState is the lifecycle state of a task
+type TaskState string
+
+const (
+	TaskStateSubmitted TaskState = "submitted"
+	TaskStateWorking   TaskState = "working"
+	TaskStateCompleted TaskState = "completed"
+	TaskStateCanceled  TaskState = "canceled"
+	TaskStateFailed    TaskState = "failed"
+)
+
+// TaskStatus is the current state of a task, with an optional message explaining it
+type TaskStatus struct {
+	State     TaskState `json:"state"`
+	Message   *Message  `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Artifact is an output of a task
+type Artifact struct {
+	ArtifactID string `json:"artifactId"`
+	Name       string `json:"name,omitempty"`
+	Parts      []Part `json:"parts"`
+}
+
+// Task is a unit of work requested by a message
+type Task struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"`
+	ContextID string     `json:"contextId"`
+	Status    TaskStatus `json:"status"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	History   []Message  `json:"history,omitempty"`
+}
+
+// MessageSendParams are the parameters of the message/send method
+type MessageSendParams struct {
+	Message       Message                   `json:"message"`
+	Configuration *MessageSendConfiguration `json:"configuration,omitempty"`
+}
+
+// MessageSendConfiguration configures a message/send call
+type MessageSendConfiguration struct {
+	// Blocking waits for the task to finish before replying, rather than
+	// replying with the task still working
+	Blocking bool `json:"blocking,omitempty"`
+}
+
+// TaskQueryParams are the parameters of the tasks/get method
+type TaskQueryParams struct {
+	ID string `json:"id"`
+}
+
+// TaskIDParams are the parameters of the tasks/cancel method
+type TaskIDParams struct {
+	ID string `json:"id"`
+}
+
+// JSON-RPC error codes used by the protocol
+const (
+	CodeParseError        = -32700
+	CodeInvalidRequest    = -32600
+	CodeMethodNotFound    = -32601
+	CodeInvalidParams     = -32602
+	CodeInternalError     = -32603
+	CodeTaskNotFound      = -32001
+	CodeTaskNotCancelable = -32002
+)
+
+// Error is a JSON-RPC error returned by an agent
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
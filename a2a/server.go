@@ -0,0 +1,378 @@
+// Package a2a implements the Agent-to-Agent (A2A) protocol, so agents built with
+// this module can be discovered and invoked by agents from other frameworks.
+//
+// A Server publishes an agent's card at /.well-known/agent.json and serves the
+// JSON-RPC methods message/send, tasks/get, and tasks/cancel. A message/send
+// replies with the task still working, to be polled with tasks/get or stopped
+// with tasks/cancel, unless its configuration asks to block until it finishes:
+//
+//	card := a2a.NewCard(a, "Support", "Answers questions about orders", "https://support.example.com/a2a")
+//	http.Handle("/a2a/", http.StripPrefix("/a2a", a2a.NewServer(a, card)))
+//
+// A Client calls remote agents, and exposes them locally as a tool or an agent:
+//
+//	remote := a2a.NewClient("https://support.example.com/a2a")
+//	tool := remote.Tool("ask_support", "Ask the support agent about an order")
+package a2a
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// ProtocolVersion is the version of the A2A protocol implemented by this package
+const ProtocolVersion = "0.2.5"
+
+// CardPath is where agent cards are published, relative to the agent's URL
+const CardPath = "/.well-known/agent.json"
+
+// NewCard describes an agent, listing each of its tools as a skill
+func NewCard(a *agent.Agent, name string, description string, url string) Card {
+	card := Card{
+		Name:               name,
+		Description:        description,
+		URL:                url,
+		Version:            "1.0.0",
+		ProtocolVersion:    ProtocolVersion,
+		DefaultInputModes:  []string{"text/plain", "application/json", "image/*", "application/octet-stream"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills:             []Skill{},
+	}
+	for _, tool := range a.Tools() {
+		card.Skills = append(card.Skills, Skill{
+			ID:          tool.Name(),
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Tags:        []string{"tool"},
+		})
+	}
+	return card
+}
+
+// Option is a functional option for configuring a Server
+type Option func(*Server)
+
+// WithMaxContexts bounds the number of conversations kept in memory. The oldest
+// conversation is forgotten when the limit is reached. The default is 1000.
+func WithMaxContexts(n int) Option {
+	return func(s *Server) {
+		s.maxContexts = n
+	}
+}
+
+// Server serves an agent over the A2A protocol. Conversations and tasks are kept
+// in memory.
+type Server struct {
+	agent       agent.ChatAgent
+	card        Card
+	maxContexts int
+
+	mu       sync.Mutex
+	tasks    map[string]*task
+	contexts map[string]*conversation
+	order    []string
+}
+
+// task is a task with the function that cancels its run
+type task struct {
+	Task
+	cancel context.CancelFunc
+}
+
+// conversation is the agent's history for a context
+type conversation struct {
+	mu       sync.Mutex
+	messages []agent.Message
+	tasks    []string
+}
+
+// NewServer creates a server for an agent described by card
+func NewServer(a agent.ChatAgent, card Card, opts ...Option) *Server {
+	s := &Server{
+		agent:       a,
+		card:        card,
+		maxContexts: 1000,
+		tasks:       map[string]*task{},
+		contexts:    map[string]*conversation{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == CardPath:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.card)
+	case r.Method == http.MethodPost && (r.URL.Path == "/" || r.URL.Path == ""):
+		s.serveRPC(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	resp := rpcResponse{JSONRPC: "2.0"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error = &Error{Code: CodeParseError, Message: "parse error: " + err.Error()}
+	} else {
+		resp.ID = req.ID
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			resp.Error = &Error{Code: CodeInvalidRequest, Message: "invalid JSON-RPC request"}
+		} else {
+			resp.Result, resp.Error = s.call(r.Context(), req.Method, req.Params)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) call(ctx context.Context, method string, params json.RawMessage) (any, *Error) {
+	switch method {
+	case "message/send":
+		var p MessageSendParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return result(s.send(ctx, p))
+	case "tasks/get":
+		var p TaskQueryParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return result(s.get(p.ID))
+	case "tasks/cancel":
+		var p TaskIDParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return result(s.cancel(p.ID))
+	default:
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+	}
+}
+
+// result converts a method's task to a JSON-RPC result, which must be absent on error
+func result(t *Task, err *Error) (any, *Error) {
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// send starts a task running the agent on a message, continuing the message's
+// context if it has one. The task takes the message's task ID when it has one.
+// A blocking send returns the finished task; otherwise the task is returned
+// while it works, and runs on after the request ends.
+func (s *Server) send(ctx context.Context, p MessageSendParams) (*Task, *Error) {
+	msg := p.Message
+	input, err := toAgentMessages(msg)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	if msg.ContextID == "" {
+		msg.ContextID = newID()
+	}
+	if msg.TaskID == "" {
+		msg.TaskID = newID()
+	}
+	msg.Kind = "message"
+	blocking := p.Configuration != nil && p.Configuration.Blocking
+	if !blocking {
+		ctx = context.WithoutCancel(ctx)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	t := &task{
+		Task: Task{
+			Kind:      "task",
+			ID:        msg.TaskID,
+			ContextID: msg.ContextID,
+			Status:    TaskStatus{State: TaskStateWorking, Timestamp: time.Now()},
+			History:   []Message{msg},
+		},
+		cancel: cancel,
+	}
+
+	conv := s.conversation(msg.ContextID)
+	s.mu.Lock()
+	if _, ok := s.tasks[t.ID]; ok {
+		s.mu.Unlock()
+		cancel()
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("task %q already exists", t.ID)}
+	}
+	s.tasks[t.ID] = t
+	conv.tasks = append(conv.tasks, t.ID)
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer cancel()
+		s.run(ctx, t, conv, input)
+	}()
+	if blocking {
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := t.Task
+	return &result, nil
+}
+
+// run runs the agent for a task and records how it ended
+func (s *Server) run(ctx context.Context, t *task, conv *conversation, input []agent.Message) {
+	// Runs in one context are serialized so each sees the previous reply
+	conv.mu.Lock()
+	defer conv.mu.Unlock()
+
+	history := append(append([]agent.Message{}, conv.messages...), input...)
+	completion, err := s.agent.ChatCompletion(ctx, history)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.cancel = nil
+	t.Status.Timestamp = time.Now()
+	var canceled *agent.CanceledError
+	switch {
+	case errors.As(err, &canceled):
+		t.Status.State = TaskStateCanceled
+	case err != nil:
+		t.Status.State = TaskStateFailed
+		t.Status.Message = agentMessage(t.Task, err.Error())
+	default:
+		reply := strings.Join(completion.Messages, "\n\n")
+		t.Status.State = TaskStateCompleted
+		t.Artifacts = []Artifact{{ArtifactID: newID(), Name: "response", Parts: []Part{TextPart(reply)}}}
+		t.History = append(t.History, *agentMessage(t.Task, reply))
+		conv.messages = append(history, agent.AssistantTextMessage(reply))
+	}
+}
+
+// conversation returns the conversation for a context, creating it if needed
+func (s *Server) conversation(id string) *conversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conv, ok := s.contexts[id]; ok {
+		return conv
+	}
+	if s.maxContexts > 0 && len(s.order) >= s.maxContexts {
+		oldest := s.order[0]
+		s.order = s.order[1:]
+		for _, taskID := range s.contexts[oldest].tasks {
+			delete(s.tasks, taskID)
+		}
+		delete(s.contexts, oldest)
+	}
+	conv := &conversation{}
+	s.contexts[id] = conv
+	s.order = append(s.order, id)
+	return conv
+}
+
+func (s *Server) get(id string) (*Task, *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, &Error{Code: CodeTaskNotFound, Message: fmt.Sprintf("task %q not found", id)}
+	}
+	result := t.Task
+	return &result, nil
+}
+
+func (s *Server) cancel(id string) (*Task, *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return nil, &Error{Code: CodeTaskNotFound, Message: fmt.Sprintf("task %q not found", id)}
+	}
+	if t.cancel == nil {
+		return nil, &Error{Code: CodeTaskNotCancelable, Message: fmt.Sprintf("task %q is %s", id, t.Status.State)}
+	}
+	t.cancel()
+	result := t.Task
+	return &result, nil
+}
+
+// toAgentMessages converts the parts of a message into user messages: one for
+// the text and data parts, one for the images, and one per other file
+func toAgentMessages(msg Message) ([]agent.Message, error) {
+	if msg.Role != RoleUser {
+		return nil, fmt.Errorf("message role must be %q", RoleUser)
+	}
+	var texts []string
+	var images []agent.Image
+	var files []agent.Message
+	for _, part := range msg.Parts {
+		switch part.Kind {
+		case "text":
+			texts = append(texts, part.Text)
+		case "data":
+			data, err := json.Marshal(part.Data)
+			if err != nil {
+				return nil, err
+			}
+			texts = append(texts, string(data))
+		case "file":
+			if part.File == nil || len(part.File.Bytes) == 0 {
+				return nil, errors.New("file parts must include bytes")
+			}
+			if strings.HasPrefix(part.File.MimeType, "image/") {
+				images = append(images, agent.Image{Data: part.File.Bytes, Name: part.File.Name})
+			} else {
+				files = append(files, agent.UserFileMessage(agent.File{Data: part.File.Bytes, Name: part.File.Name}))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported part kind %q", part.Kind)
+		}
+	}
+
+	var messages []agent.Message
+	if len(texts) > 0 {
+		messages = append(messages, agent.UserTextMessage(strings.Join(texts, "\n")))
+	}
+	if len(images) > 0 {
+		messages = append(messages, agent.UserImagesMessage(images...))
+	}
+	messages = append(messages, files...)
+	if len(messages) == 0 {
+		return nil, errors.New("message has no parts")
+	}
+	return messages, nil
+}
+
+// agentMessage returns a reply from the agent within a task
+func agentMessage(t Task, text string) *Message {
+	return &Message{
+		Kind:      "message",
+		Role:      RoleAgent,
+		Parts:     []Part{TextPart(text)},
+		MessageID: newID(),
+		ContextID: t.ContextID,
+		TaskID:    t.ID,
+	}
+}
+
+// newID returns a random identifier
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
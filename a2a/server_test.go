@@ -0,0 +1,230 @@
+package a2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lookupTool is a tool listed as a skill on the card
+type lookupTool struct{}
+
+func (lookupTool) Name() string        { return "lookup_order" }
+func (lookupTool) Description() string { return "Look up an order by ID" }
+func (lookupTool) Parameters() agent.Parameters {
+	return agent.Parameters{Properties: map[string]any{}}
+}
+func (lookupTool) Execute(context.Context, map[string]any) (any, error) { return "shipped", nil }
+
+// newTestServer serves an agent backed by a fake provider that replies with the
+// number of messages it received
+func newTestServer(t *testing.T, provider http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	if provider == nil {
+		provider = func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Messages []json.RawMessage `json:"messages"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":"test","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"seen %d messages"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, len(body.Messages))
+		}
+	}
+	providerServer := httptest.NewServer(provider)
+	t.Cleanup(providerServer.Close)
+
+	client := openai.NewClient(option.WithAPIKey("test"), option.WithBaseURL(providerServer.URL), option.WithMaxRetries(0))
+	a := agent.NewAgentWithClient(client, "test", agent.WithTools([]agent.Tool{lookupTool{}}))
+
+	server := httptest.NewServer(NewServer(a, NewCard(a, "Support", "Answers order questions", "http://example.com")))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// rpc calls a JSON-RPC method and decodes the raw response
+func rpc(t *testing.T, url string, method string, params any) (json.RawMessage, *Error) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var out struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	assert.Equal(t, 1, out.ID)
+	if out.Error != nil {
+		assert.Empty(t, out.Result, "a response must not have both a result and an error")
+	}
+	return out.Result, out.Error
+}
+
+func TestCard(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	resp, err := http.Get(server.URL + CardPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var card Card
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&card))
+	assert.Equal(t, "Support", card.Name)
+	assert.Equal(t, ProtocolVersion, card.ProtocolVersion)
+	assert.Equal(t, []Skill{{ID: "lookup_order", Name: "lookup_order", Description: "Look up an order by ID", Tags: []string{"tool"}}}, card.Skills)
+}
+
+func TestMessageSend(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	send := func(contextID string, parts ...Part) Task {
+		result, rpcErr := rpc(t, server.URL, "message/send", MessageSendParams{Message: Message{
+			Kind: "message", Role: RoleUser, MessageID: "m", ContextID: contextID, Parts: parts,
+		}, Configuration: &MessageSendConfiguration{Blocking: true}})
+		require.Nil(t, rpcErr)
+		var task Task
+		require.NoError(t, json.Unmarshal(result, &task))
+		return task
+	}
+
+	first := send("", TextPart("Where is order 42?"), Part{Kind: "data", Data: map[string]any{"order": 42}})
+	assert.Equal(t, TaskStateCompleted, first.Status.State)
+	assert.NotEmpty(t, first.ContextID)
+	require.Len(t, first.Artifacts, 1)
+	assert.Equal(t, []Part{TextPart("seen 1 messages")}, first.Artifacts[0].Parts)
+	require.Len(t, first.History, 2)
+	assert.Equal(t, RoleAgent, first.History[1].Role)
+
+	// A follow-up in the same context includes the earlier turn
+	second := send(first.ContextID, TextPart("And order 43?"))
+	assert.Equal(t, first.ContextID, second.ContextID)
+	assert.Equal(t, []Part{TextPart("seen 3 messages")}, second.Artifacts[0].Parts)
+
+	task := getTask(t, server.URL, first.ID)
+	assert.Equal(t, first.ID, task.ID)
+	assert.Equal(t, TaskStateCompleted, task.Status.State)
+}
+
+// getTask calls tasks/get
+func getTask(t *testing.T, url string, id string) Task {
+	t.Helper()
+	result, rpcErr := rpc(t, url, "tasks/get", TaskQueryParams{ID: id})
+	require.Nil(t, rpcErr)
+	var task Task
+	require.NoError(t, json.Unmarshal(result, &task))
+	return task
+}
+
+func TestMessageSendNonBlocking(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	result, rpcErr := rpc(t, server.URL, "message/send", MessageSendParams{Message: Message{Role: RoleUser, Parts: []Part{TextPart("Where is order 42?")}}})
+	require.Nil(t, rpcErr)
+	var task Task
+	require.NoError(t, json.Unmarshal(result, &task))
+
+	// The task finishes after the request returned
+	require.Eventually(t, func() bool {
+		task = getTask(t, server.URL, task.ID)
+		return task.Status.State == TaskStateCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []Part{TextPart("seen 1 messages")}, task.Artifacts[0].Parts)
+}
+
+func TestRPCErrors(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	_, rpcErr := rpc(t, server.URL, "tasks/get", TaskQueryParams{ID: "missing"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeTaskNotFound, rpcErr.Code)
+
+	_, rpcErr = rpc(t, server.URL, "tasks/resubscribe", TaskQueryParams{ID: "missing"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeMethodNotFound, rpcErr.Code)
+
+	_, rpcErr = rpc(t, server.URL, "message/send", MessageSendParams{Message: Message{Role: RoleAgent, Parts: []Part{TextPart("hi")}}})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+}
+
+// newSlowServer serves an agent whose provider never replies, signaling each request
+func newSlowServer(t *testing.T) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+	started := make(chan struct{}, 1)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// Read the body so the server notices when the client gives up
+		io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	})
+	return server, started
+}
+
+func TestTaskCancel(t *testing.T) {
+	server, started := newSlowServer(t)
+
+	// The task works in the background under the client's task ID
+	result, rpcErr := rpc(t, server.URL, "message/send", MessageSendParams{Message: Message{Role: RoleUser, TaskID: "task-1", Parts: []Part{TextPart("slow")}}})
+	require.Nil(t, rpcErr)
+	var task Task
+	require.NoError(t, json.Unmarshal(result, &task))
+	assert.Equal(t, "task-1", task.ID)
+	assert.Equal(t, TaskStateWorking, task.Status.State)
+	<-started
+
+	_, rpcErr = rpc(t, server.URL, "message/send", MessageSendParams{Message: Message{Role: RoleUser, TaskID: "task-1", Parts: []Part{TextPart("again")}}})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+
+	_, rpcErr = rpc(t, server.URL, "tasks/cancel", TaskIDParams{ID: "task-1"})
+	require.Nil(t, rpcErr)
+	require.Eventually(t, func() bool {
+		return getTask(t, server.URL, "task-1").Status.State == TaskStateCanceled
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, rpcErr = rpc(t, server.URL, "tasks/cancel", TaskIDParams{ID: "task-1"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeTaskNotCancelable, rpcErr.Code)
+}
+
+func TestBlockingTaskCancel(t *testing.T) {
+	server, started := newSlowServer(t)
+
+	done := make(chan Task)
+	go func() {
+		result, rpcErr := rpc(t, server.URL, "message/send", MessageSendParams{
+			Message:       Message{Role: RoleUser, TaskID: "task-1", Parts: []Part{TextPart("slow")}},
+			Configuration: &MessageSendConfiguration{Blocking: true},
+		})
+		assert.Nil(t, rpcErr)
+		var task Task
+		assert.NoError(t, json.Unmarshal(result, &task))
+		done <- task
+	}()
+	<-started
+
+	_, rpcErr := rpc(t, server.URL, "tasks/cancel", TaskIDParams{ID: "task-1"})
+	require.Nil(t, rpcErr)
+
+	select {
+	case task := <-done:
+		assert.Equal(t, TaskStateCanceled, task.Status.State)
+	case <-time.After(5 * time.Second):
+		t.Fatal("task was not canceled")
+	}
+}
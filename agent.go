@@ -4,6 +4,14 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -17,6 +25,7 @@ type AgentOption func(*Agent)
 func WithSystemPrompt(prompt string) AgentOption {
 	return func(a *Agent) {
 		a.systemPrompt = prompt
+		a.systemPromptTemplate = nil
 	}
 }
 
@@ -24,6 +33,7 @@ func WithSystemPrompt(prompt string) AgentOption {
 func WithInstructions(instructions string) AgentOption {
 	return func(a *Agent) {
 		a.instructions = instructions
+		a.instructionsTemplate = nil
 	}
 }
 
@@ -41,14 +51,86 @@ func WithMaxIterations(max int) AgentOption {
 	}
 }
 
-// Agent implements the Agent interface using the OpenAI-compatible API
+// WithFallbackModels sets the models to try, in order, when a request to the primary model fails
+func WithFallbackModels(models ...string) AgentOption {
+	return func(a *Agent) {
+		a.fallbackModels = models
+	}
+}
+
+// Agent implements the Agent interface using the OpenAI-compatible API.
+//
+// An Agent is safe for concurrent use by multiple goroutines. Options configure
+// it only while it is created, and it is never modified afterwards: everything
+// a run changes, such as its messages, iteration count, spend, and captured
+// exchanges, lives in that run. State shared between runs is held by values
+// the options supply, such as a ModelPool, SemanticCache, RateLimiter, or
+// ToolRegistry, which synchronize their own access. Use Clone for variations
+// of an agent, such as a per-request model or tool set.
 type Agent struct {
-	client        openai.Client
-	model         string
-	tools         []Tool
-	maxIterations int
-	systemPrompt  string
-	instructions  string
+	client         openai.Client
+	model          string
+	fallbackModels []string
+	tools          []Tool
+	maxIterations  int
+	systemPrompt   string
+	instructions   string
+	// systemPromptTemplate and instructionsTemplate render the prompts per run
+	systemPromptTemplate *promptSource
+	instructionsTemplate *promptSource
+	limits               Limits
+	metrics              MetricsRecorder
+	runStore             RunStore
+	logger               *slog.Logger
+	debug                bool
+	debugFn              func(ctx context.Context, exchange WireExchange)
+	logContent           bool
+	summarizePolicy      SummarizePolicy
+	knowledgeBase        *RetrievalTool
+	memory               Memory
+	memoryRecall         int
+	handoffs             []Handoff
+	killSwitch           *KillSwitch
+	promptCaching        bool
+	outputPolicy         OutputPolicy
+	moderationPolicy     ModerationPolicy
+	injectionPolicy      *InjectionPolicy
+	toolPolicy           ToolPolicy
+	reflections          int
+	criticPrompt         string
+	outputParser         OutputParser
+	parseRetries         int
+	stopConditions       []StopCondition
+	runTimeout           time.Duration
+	maxRunDuration       time.Duration
+	responseFormat       openai.ChatCompletionNewParamsResponseFormatUnion
+	heartbeat            HeartbeatPolicy
+	accessPolicy         AccessPolicy
+	toolChoice           ToolChoice
+	secrets              SecretProvider
+	requestOptions       []option.RequestOption
+	tagPolicy            TagPolicy
+	semanticCache        *SemanticCache
+	referencePolicy      *ReferencePolicy
+	modelPool            *ModelPool
+
+	unavailableFallback func(ctx context.Context, messages []Message) (string, error)
+	toolProgressSummary bool
+
+	multimodalToolMessages bool
+	toolRegistry           *ToolRegistry
+
+	maxCompletionTokens int64
+	reasoningEffort     ReasoningEffort
+	maxTotalTokens      int64
+	maxCostUSD          float64
+	prices              map[string]Price
+	firstTokenDeadline  time.Duration
+	toolTimeout         time.Duration
+	truncatePolicy      TruncatePolicy
+	rateLimiter         RateLimiter
+	tts                 *TTSConfig
+	eventBus            *EventBus
 }
 
 // NewAgent creates a new Agent with the given API key, base URL, and model
@@ -66,6 +148,8 @@ func NewAgent(apiKey string, baseURL string, model string, opts ...AgentOption)
 		maxIterations: 100,
 		systemPrompt:  "",
 		instructions:  "",
+		metrics:       noopMetrics{},
+		logger:        slog.New(slog.DiscardHandler),
 	}
 
 	// Apply options
@@ -86,6 +170,8 @@ func NewAgentWithClient(client openai.Client, model string, opts ...AgentOption)
 		maxIterations: 100,
 		systemPrompt:  "",
 		instructions:  "",
+		metrics:       noopMetrics{},
+		logger:        slog.New(slog.DiscardHandler),
 	}
 
 	// Apply options
@@ -96,217 +182,847 @@ func NewAgentWithClient(client openai.Client, model string, opts ...AgentOption)
 	return agent
 }
 
+// Clone returns a copy of the agent with opts applied on top of its
+// configuration, leaving the agent unchanged. Cloning is cheap: the copy shares
+// the agent's client, tools, and stateful values such as caches and pools, so
+// it suits per-request variations.
+func (agent *Agent) Clone(opts ...AgentOption) *Agent {
+	clone := *agent
+	// Clip the slices options append to, so appends on the clone copy them
+	clone.fallbackModels = slices.Clip(clone.fallbackModels)
+	clone.tools = slices.Clip(clone.tools)
+	clone.handoffs = slices.Clip(clone.handoffs)
+	clone.stopConditions = slices.Clip(clone.stopConditions)
+	clone.requestOptions = slices.Clip(clone.requestOptions)
+	for _, opt := range opts {
+		opt(&clone)
+	}
+	return &clone
+}
+
+// WithModel sets the model the agent requests, for clones of an agent that
+// should use a different one
+func WithModel(model string) AgentOption {
+	return func(a *Agent) {
+		a.model = model
+	}
+}
+
+// ChatCompletion runs the conversation to completion and returns the collected result.
+// On failure, including cancellation of ctx, the result holds what was produced before the error.
 func (agent *Agent) ChatCompletion(
 	ctx context.Context,
 	messages []Message,
+	opts ...RunOption,
 ) (Completion, error) {
-	responseChan, err := agent.StreamChatCompletion(ctx, messages)
+	options := newRunOptions(opts)
+
+	// Check entitlement before serving a stored result
+	if _, err := agent.plan(options); err != nil {
+		return Completion{}, err
+	}
+
+	// Return the stored result if this run already completed
+	if options.runID != "" && agent.runStore != nil {
+		completion, ok, err := agent.runStore.Get(ctx, options.runID)
+		if err != nil {
+			return Completion{}, err
+		}
+		if ok {
+			return completion, nil
+		}
+	}
+
+	responseChan, err := agent.StreamChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return Completion{}, err
+	}
+
+	completion, err := Collect(responseChan)
+	if err != nil {
+		return completion, err
+	}
+
+	// A degraded reply is not the run's result, so a retry should run it again
+	if options.runID != "" && agent.runStore != nil && completion.TerminationReason != TerminationDegraded {
+		if err := agent.runStore.Put(ctx, options.runID, completion); err != nil {
+			return Completion{}, err
+		}
+	}
+
+	return completion, nil
+}
+
+// StreamTo runs the conversation, writing content to w as it arrives, and returns
+// the collected Completion. If w implements http.Flusher it is flushed after each write.
+func (agent *Agent) StreamTo(
+	ctx context.Context,
+	messages []Message,
+	w io.Writer,
+	opts ...RunOption,
+) (Completion, error) {
+	responseChan, err := agent.StreamChatCompletion(ctx, messages, opts...)
 	if err != nil {
 		return Completion{}, err
 	}
 
 	var completion Completion
+	var writeErr error
 
 	for response := range responseChan {
-		completion.Responses = append(completion.Responses, response)
-		if response.IsUsageResponse() {
-			usage := response.Usage()
-			completion.Usage.PromptTokens += usage.PromptTokens
-			completion.Usage.CompletionTokens += usage.CompletionTokens
-			completion.Usage.TotalTokens += usage.TotalTokens
-		}
-		if response.IsContentResponse() {
-			completion.Messages = append(completion.Messages, response.Content())
-		}
+		completion.add(response)
 		if response.IsErrorResponse() {
-			return Completion{}, response.Error()
+			return completion, response.Error()
+		}
+		// Keep draining the channel after a write failure so the run can finish
+		if response.IsContentResponse() && writeErr == nil {
+			if _, writeErr = io.WriteString(w, response.Content()); writeErr == nil {
+				if flusher, ok := w.(http.Flusher); ok {
+					flusher.Flush()
+				}
+			}
 		}
 	}
 
+	if writeErr != nil {
+		return Completion{}, writeErr
+	}
+
 	return completion, nil
 }
 
-// StreamChatCompletion implements the Agent interface
+// StreamChatCompletion implements the ChatAgent interface. When ctx is canceled the run
+// interrupts the in-flight request or tool call, emits a *CanceledError response,
+// and closes the channel.
 func (agent *Agent) StreamChatCompletion(
 	ctx context.Context,
 	messages []Message,
+	opts ...RunOption,
 ) (<-chan Response, error) {
-	responseChan := make(chan Response)
+	options := newRunOptions(opts)
+	run := newRunInfo(ctx, options)
+	ctx = withRunInfo(ctx, run)
+	ctx = withMemorySubject(ctx, options.memorySubject)
 
-	// Convert the messages to OpenAI format and inject system prompt and instructions
-	chatMessages := agent.buildMessages(messages)
+	// Reject oversized input before it reaches the provider
+	if err := agent.limits.Check(messages); err != nil {
+		return nil, err
+	}
 
-	// Initialize tools params
-	var openAITools []openai.ChatCompletionToolParam
-	for _, tool := range agent.tools {
-		openAITools = append(openAITools, openai.ChatCompletionToolParam{
-			Type: "function",
-			Function: openai.FunctionDefinitionParam{
-				Name:        tool.Name(),
-				Description: openai.String(tool.Description()),
-				Parameters:  convertParameters(tool.Parameters()),
-			},
-		})
+	// Resolve the models and budgets the tenant is entitled to
+	plan, err := agent.plan(options)
+	if err != nil {
+		return nil, err
 	}
+	plan = agent.routePool(plan, options)
 
-	// Create params for the completion
-	params := openai.ChatCompletionNewParams{
-		Messages: chatMessages,
-		Model:    openai.ChatModel(agent.model),
-		Tools:    openAITools,
+	// Refuse to start while a kill switch is engaged
+	killSwitches := agent.killSwitches(options)
+	if err := checkKilled(killSwitches); err != nil {
+		return nil, err
+	}
+
+	// Resolve the run's tools once, so registry changes apply from the next run
+	tools := filterToolGroups(agent.runTools(ctx, options.tenant), options)
+	toolsByName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		toolsByName[tool.Name()] = tool
+	}
+	toolChoice, err := agent.toolChoiceParam(options, tools)
+	if err != nil {
+		return nil, err
+	}
+
+	// Render prompt templates with the variables for this request
+	prompts, err := agent.prompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve near-duplicate conversations from the semantic cache
+	cached, record := agent.cacheRun(ctx, prompts, messages)
+	if cached != nil {
+		return agent.publishResponses(run, options, stampResponses(run, agent.speakResponses(ctx, cached))), nil
 	}
 
+	responseChan := make(chan Response)
+
+	// Create params for the completion
+	params := agent.newParams(prompts, messages, tools, toolChoice)
+
+	// Track the conversation in Message form so it can be handed off to other agents
+	history := append([]Message{}, messages...)
+
+	// Cancel the run as soon as a kill switch is engaged
+	parent := ctx
+	ctx, cancelTimeout := agent.withRunTimeout(agent.withSecrets(ctx))
+	ctx, cancel := context.WithCancel(ctx)
+	ctx, archive := agent.withResultArchive(ctx)
+	stopWatching := watchKillSwitches(ctx, cancel, killSwitches)
+
 	go func() {
 		defer close(responseChan)
+		defer cancel()
+		defer cancelTimeout()
+		defer stopWatching()
+		progress, finishHeartbeat := agent.startHeartbeat(ctx, options.runID)
+		capture := &wireCapture{}
+		iterations := 0
+		reflections := 0
+		parseRetries := 0
+		var stopState StopState
+		var spent spend
 		err := func() error {
-			for range agent.maxIterations {
+			if err := agent.moderateInput(ctx, messages, plan.requestOptions, responseChan); err != nil {
+				return err
+			}
+			if err := agent.recallMemories(ctx, messages, &params, responseChan); err != nil {
+				return err
+			}
+			stopped := func() bool {
+				if iterations == 0 || len(agent.stopConditions) == 0 {
+					return false
+				}
+				stopState.Run, stopState.Run.Iteration = run, iterations
+				stopState.Elapsed = time.Since(run.StartedAt)
+				stopState.Usage = spent.usage
+				if !agent.stopRequested(stopState) {
+					return false
+				}
+				agent.logger.DebugContext(ctx, "agent run stopped by condition", "iterations", iterations)
+				return true
+			}
+			for range plan.maxIterations {
+				if err := checkKilled(killSwitches); err != nil {
+					return err
+				}
+				// Stop before another request once the budget is spent
+				if err := spent.check(plan); err != nil {
+					return err
+				}
+				if err := agent.checkRunDuration(run); err != nil {
+					return err
+				}
+				// End the run once a stop condition holds after an iteration
+				if stopped() {
+					return nil
+				}
+				// Wait here while a supervisor has the run paused
+				if options.controller != nil {
+					if err := options.controller.checkpoint(ctx, &params.Messages); err != nil {
+						return err
+					}
+				}
+				iterations++
+				current := run
+				current.Iteration = iterations
+				ctx := withRunInfo(ctx, current)
+				progress.iteration(iterations)
+				agent.logger.DebugContext(ctx, "agent iteration", "messages", len(params.Messages))
+
+				// Replace older tool results with references to keep the context small
+				agent.referenceOldResults(archive, params.Messages)
+
 				// Start streaming completion
-				response, err := agent.client.Chat.Completions.New(ctx, params)
+				response, err := agent.createCompletion(withWireCapture(ctx, capture), params, plan)
+				for _, exchange := range capture.take() {
+					if err := send(ctx, responseChan, NewDebugResponse(exchange)); err != nil {
+						return err
+					}
+				}
 				if err != nil {
+					return agent.degrade(ctx, history, err, responseChan)
+				}
+				// The tool choice only applies to the first iteration
+				params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+
+				usage := convertUsage(response)
+				usage.Iteration = iterations
+				progress.usage(usage)
+				spent.add(agent.prices, usage)
+				if err := send(ctx, responseChan, NewUsageResponse(usage)); err != nil {
 					return err
 				}
 
-				responseChan <- NewUsageResponse(Usage{
-					PromptTokens:     response.Usage.PromptTokens,
-					CompletionTokens: response.Usage.CompletionTokens,
-					TotalTokens:      response.Usage.TotalTokens,
-				})
+				if response.Choices[0].FinishReason == "content_filter" {
+					return &ContentFilterError{}
+				}
 
 				// Check if there are tool calls
 				hasToolCalls := len(response.Choices[0].Message.ToolCalls) > 0
+				for _, toolCall := range response.Choices[0].Message.ToolCalls {
+					stopState.ToolCalls = append(stopState.ToolCalls, ToolCall{ID: toolCall.ID, Name: toolCall.Function.Name, Arguments: toolCall.Function.Arguments})
+				}
 
 				// Add the AI message to our conversation only if it has content or tool calls
 				if response.Choices[0].Message.Content != "" || hasToolCalls {
 					params.Messages = append(params.Messages, response.Choices[0].Message.ToParam())
 				}
 
+				// Critique a final answer, revising it while reflections remain
+				if !hasToolCalls && response.Choices[0].Message.Content != "" && reflections < agent.reflections {
+					reflections++
+					critique, err := agent.critique(ctx, params, plan, capture, &spent, responseChan)
+					if err != nil {
+						return err
+					}
+					if critique != "" {
+						draft := Draft{Revision: reflections, Content: response.Choices[0].Message.Content, Critique: critique}
+						if err := send(ctx, responseChan, NewDraftResponse(draft)); err != nil {
+							return err
+						}
+						params.Messages = append(params.Messages, revisionMessage(critique))
+						continue
+					}
+				}
+
+				// Ask for a corrected final answer while it fails to parse and retries remain
+				if !hasToolCalls && response.Choices[0].Message.Content != "" && agent.outputParser != nil {
+					if _, err := agent.outputParser.Parse(response.Choices[0].Message.Content); err != nil {
+						if parseRetries >= agent.parseRetries {
+							return &OutputParseError{Answer: response.Choices[0].Message.Content, Err: err}
+						}
+						parseRetries++
+						if err := send(ctx, responseChan, NewWarningResponse(fmt.Sprintf("output failed to parse, retrying: %v", err))); err != nil {
+							return err
+						}
+						params.Messages = append(params.Messages, fixMessage(err))
+						continue
+					}
+				}
+
 				// Send content to response channel if present
 				if response.Choices[0].Message.Content != "" {
-					responseChan <- NewContentResponse(response.Choices[0].Message.Content)
+					if err := agent.moderateOutput(ctx, response.Choices[0].Message.Content, plan.requestOptions, responseChan); err != nil {
+						return err
+					}
+					if err := send(ctx, responseChan, NewContentResponse(response.Choices[0].Message.Content)); err != nil {
+						return err
+					}
+					history = append(history, AssistantTextMessage(response.Choices[0].Message.Content))
+					stopState.Messages = append(stopState.Messages, response.Choices[0].Message.Content)
 				}
 
 				// Handle any tool calls
 				if hasToolCalls {
+					var attached ToolResult
 					for _, toolCall := range response.Choices[0].Message.ToolCalls {
-						// TODO: add a lookup map
-						var tool Tool
-						for _, t := range agent.tools {
-							if t.Name() == toolCall.Function.Name {
-								tool = t
-								break
+						progress.tool(toolCall.Function.Name)
+						// Tell the model about calls the run's policies do not permit instead of executing them
+						if violation := agent.checkToolPolicies(ctx, plan, toolCall); violation != "" {
+							params.Messages = append(params.Messages, openai.ToolMessage(violation, toolCall.ID))
+							if err := send(ctx, responseChan, NewWarningResponse(fmt.Sprintf("tool %s call denied by policy", toolCall.Function.Name))); err != nil {
+								return err
 							}
+							if err := send(ctx, responseChan, newToolCallResponse(toolCall, violation)); err != nil {
+								return err
+							}
+							continue
 						}
-
-						// Execute the tool using the tool executor
-						var args map[string]any
-						if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-							return err
-						}
-						toolResult, err := tool.Execute(ctx, args)
-						if err != nil {
-							return err
-						}
-
-						switch v := toolResult.(type) {
-						case string:
-							params.Messages = append(params.Messages, openai.ToolMessage(v, toolCall.ID))
-						case map[string]any, []any:
-							data, err := json.Marshal(v)
+						// Transfer the conversation if the model chose a handoff
+						if handoff := agent.findHandoff(toolCall.Function.Name); handoff != nil {
+							content, transferred, err := agent.runHandoff(ctx, handoff, toolCall, history, responseChan, options)
 							if err != nil {
 								return err
 							}
-							params.Messages = append(params.Messages, openai.ToolMessage(string(data), toolCall.ID))
-						default:
-							data, err := json.Marshal(v)
-							if err != nil {
+							if transferred {
+								return nil
+							}
+							params.Messages = append(params.Messages, openai.ToolMessage(content, toolCall.ID))
+							if err := send(ctx, responseChan, newToolCallResponse(toolCall, content)); err != nil {
 								return err
 							}
-							params.Messages = append(params.Messages, openai.ToolMessage(string(data), toolCall.ID))
+							continue
+						}
+
+						content, attachments, err := agent.executeToolCall(ctx, toolsByName, toolCall, &spent, responseChan)
+						if err != nil {
+							return err
+						}
+						if err := send(ctx, responseChan, newToolCallResponse(toolCall, content)); err != nil {
+							return err
+						}
+						if agent.multimodalToolMessages {
+							params.Messages = append(params.Messages, toolMessageWithParts(content, toolCall.ID, attachments))
+							continue
 						}
+						params.Messages = append(params.Messages, openai.ToolMessage(content, toolCall.ID))
+						attached.Images = append(attached.Images, attachments.Images...)
+						attached.Files = append(attached.Files, attachments.Files...)
+					}
+					// Attachments follow the tool messages, which must directly answer the calls
+					if attached.hasAttachments() {
+						params.Messages = append(params.Messages, openai.ChatCompletionMessageParamUnion{
+							OfUser: &openai.ChatCompletionUserMessageParam{
+								Content: openai.ChatCompletionUserMessageParamContentUnion{
+									OfArrayOfContentParts: attachmentParts(attached),
+								},
+							},
+						})
 					}
 				} else {
-					// No tool calls, exit the loop
-					break
+					// No tool calls, the model has answered
+					return nil
 				}
 			}
-			return nil
+			// A condition met by the last allowed iteration still ends the run cleanly
+			if stopped() {
+				return nil
+			}
+			return &MaxIterationsError{MaxIterations: plan.maxIterations}
 		}()
+		// Report a kill or the caller's cancellation as the terminal error rather than
+		// whatever failure the canceled context caused downstream
+		if killedErr := checkKilled(killSwitches); err != nil && killedErr != nil {
+			err = killedErr
+		} else if err != nil && parent.Err() != nil {
+			err = &CanceledError{Err: context.Cause(parent)}
+		} else if timeoutErr := runTimedOut(ctx); err != nil && timeoutErr != nil {
+			err = timeoutErr
+		}
+		agent.metrics.ObserveRun(iterations, err)
+		finishHeartbeat(err)
+		agent.tagRun(ctx, options.runID, history, err)
+		if err != nil {
+			agent.logger.ErrorContext(ctx, "agent run failed", "iterations", iterations, "error", err)
+		} else {
+			agent.logger.InfoContext(ctx, "agent run finished", "iterations", iterations)
+		}
+		// The terminal error is always delivered; consumers must drain the channel until it closes
 		if err != nil {
 			responseChan <- NewErrorResponse(err)
 		}
 	}()
 
-	return responseChan, nil
+	// Screen content before it reaches the caller, stopping the run on a halt
+	if len(agent.outputPolicy.Phrases) > 0 {
+		return agent.publishResponses(run, options, stampResponses(run, agent.speakResponses(parent, record(filterResponses(responseChan, agent.outputPolicy, cancel))))), nil
+	}
+
+	return agent.publishResponses(run, options, stampResponses(run, agent.speakResponses(parent, record(responseChan)))), nil
+}
+
+// Tools returns the tools available to the model, including built-in tools
+// enabled by options and, with a registry, the tools it offers to runs without
+// a tenant
+func (agent *Agent) Tools() []Tool {
+	return append([]Tool{}, agent.runTools(context.Background(), "")...)
+}
+
+// allTools returns the configured tools plus any built-in tools enabled by
+// options for the run ctx belongs to
+func (agent *Agent) allTools(ctx context.Context) []Tool {
+	if agent.knowledgeBase == nil && len(agent.handoffs) == 0 && agent.referencePolicy == nil && agent.memory == nil {
+		return agent.tools
+	}
+	tools := append([]Tool{}, agent.tools...)
+	if agent.knowledgeBase != nil {
+		tools = append(tools, agent.knowledgeBase)
+	}
+	tools = append(tools, agent.memoryTools(ctx)...)
+	if agent.referencePolicy != nil {
+		tools = append(tools, toolResultTool{})
+	}
+	for _, handoff := range agent.handoffs {
+		tools = append(tools, handoffTool{handoff: handoff})
+	}
+	return tools
+}
+
+// executeTool runs a tool, returning as soon as ctx is canceled even if the tool
+// ignores its context. An abandoned tool's result is discarded when it finishes.
+func executeTool(ctx context.Context, tool Tool, args map[string]any) (any, error) {
+	type result struct {
+		value any
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := tool.Execute(ctx, args)
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+// formatToolResult converts a tool result to message content, encoding non-string results as JSON
+func formatToolResult(result any) (string, error) {
+	if v, ok := result.(string); ok {
+		return v, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// executeToolCall runs the tool requested by the model and returns the content
+// of its result and any images and files it carries. The usage of summarizing
+// the result is added to spent.
+func (agent *Agent) executeToolCall(
+	ctx context.Context,
+	tools map[string]Tool,
+	toolCall openai.ChatCompletionMessageToolCall,
+	spent *spend,
+	responseChan chan<- Response,
+) (string, ToolResult, error) {
+	tool, ok := tools[toolCall.Function.Name]
+	if !ok {
+		return "", ToolResult{}, &ToolExecutionError{Tool: toolCall.Function.Name, Err: errors.New("no such tool")}
+	}
+
+	// Execute the tool using the tool executor
+	var args map[string]any
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		return "", ToolResult{}, &ToolExecutionError{Tool: toolCall.Function.Name, Err: err}
+	}
+	if err := ValidateArguments(tool.Parameters(), args); err != nil {
+		return "", ToolResult{}, &ToolExecutionError{Tool: tool.Name(), Err: err}
+	}
+	start := time.Now()
+	toolCtx, cancel := agent.withToolTimeout(ctx, tool)
+	defer cancel()
+	toolCtx, reporter := withProgress(toolCtx, tool.Name(), toolCall.ID, responseChan)
+	toolResult, err := executeTool(toolCtx, tool, args)
+	reporter.finish()
+	agent.metrics.ObserveToolCall(tool.Name(), time.Since(start), err)
+	agent.logger.DebugContext(ctx, "agent tool call",
+		"tool", tool.Name(),
+		"call_id", toolCall.ID,
+		"duration", time.Since(start),
+		agent.contentAttr("arguments", toolCall.Function.Arguments),
+		"error", err,
+	)
+	// A timed out tool is abandoned and the model told, unless the run itself ended
+	var timeoutErr *ToolTimeoutError
+	if errors.As(err, &timeoutErr) && ctx.Err() == nil {
+		agent.logger.WarnContext(ctx, "agent tool call timed out", "tool", tool.Name(), "call_id", toolCall.ID, "timeout", timeoutErr.Timeout)
+		if err := send(ctx, responseChan, NewWarningResponse(timeoutErr.Error())); err != nil {
+			return "", ToolResult{}, err
+		}
+		return "Error: " + timeoutErr.Error() + ". It may still be running; do not assume it had no effect.", ToolResult{}, nil
+	}
+	if err != nil {
+		return "", ToolResult{}, &ToolExecutionError{Tool: tool.Name(), Err: err}
+	}
+
+	toolResult, attachments := splitToolResult(toolResult)
+	content, err := formatToolResult(toolResult)
+	if err != nil {
+		return "", ToolResult{}, err
+	}
+	content = redactResolvedSecrets(ctx, content)
+
+	content, err = agent.scanToolResult(ctx, tool, content, responseChan)
+	if err != nil {
+		return "", ToolResult{}, err
+	}
+
+	// Condense large results with the summarizer model if configured
+	if agent.summarizePolicy.shouldSummarize(content) {
+		var usage Usage
+		content, usage, err = agent.summarizeToolResult(ctx, tool, content, agent.summarizePolicy.Model, agent.summarizePolicy.Threshold)
+		if err != nil {
+			return "", ToolResult{}, err
+		}
+		spent.add(agent.prices, usage)
+		if err := send(ctx, responseChan, NewUsageResponse(usage)); err != nil {
+			return "", ToolResult{}, err
+		}
+	}
+
+	content, err = agent.truncateToolResult(ctx, tool, content, spent, responseChan)
+	if err != nil {
+		return "", ToolResult{}, err
+	}
+
+	if agent.toolProgressSummary {
+		if summary := reporter.summary(); summary != "" {
+			content += "\n\n" + redactResolvedSecrets(ctx, summary)
+		}
+	}
+
+	return content, attachments, nil
+}
+
+// createCompletion requests a completion from the run's primary model, falling
+// back to the next models in order when the request fails
+func (agent *Agent) createCompletion(
+	ctx context.Context,
+	params openai.ChatCompletionNewParams,
+	plan runPlan,
+) (*openai.ChatCompletion, error) {
+	var err error
+	messages := params.Messages
+	for _, model := range plan.models {
+		params.Model = openai.ChatModel(model)
+		params.Messages = adaptMessagesForModel(messages, model)
+		agent.applyModelParams(&params, model, plan.maxCompletionTokens)
+		agent.logger.DebugContext(ctx, "agent request", "model", model, "messages", len(params.Messages), "tools", len(params.Tools))
+		var response *openai.ChatCompletion
+		start := time.Now()
+		response, err = agent.requestCompletion(ctx, params, model, plan)
+		var usage Usage
+		if err == nil {
+			usage = convertUsage(response)
+		} else {
+			err = wrapProviderError(err)
+		}
+		agent.metrics.ObserveRequest(model, time.Since(start), usage, err)
+		if agent.modelPool != nil {
+			price, _ := priceFor(agent.prices, model)
+			agent.modelPool.observe(model, usage, price.Cost(usage), err)
+		}
+		if err == nil {
+			agent.logger.DebugContext(ctx, "agent response",
+				"model", response.Model,
+				"duration", time.Since(start),
+				"prompt_tokens", usage.PromptTokens,
+				"completion_tokens", usage.CompletionTokens,
+				"finish_reason", response.Choices[0].FinishReason,
+				"tool_calls", len(response.Choices[0].Message.ToolCalls),
+				agent.contentAttr("content", response.Choices[0].Message.Content),
+			)
+			return response, nil
+		}
+		if !shouldFallback(ctx, err) {
+			return nil, err
+		}
+		agent.logger.WarnContext(ctx, "agent request failed", "model", model, "error", err)
+	}
+	return nil, err
+}
+
+// requestCompletion sends a single request, abandoning it with a
+// *FirstTokenTimeoutError if the model misses the first token deadline
+func (agent *Agent) requestCompletion(
+	ctx context.Context,
+	params openai.ChatCompletionNewParams,
+	model string,
+	plan runPlan,
+) (*openai.ChatCompletion, error) {
+	estimated, err := agent.waitForRateLimit(ctx, model, params)
+	if err != nil {
+		return nil, err
+	}
+	response, err := agent.sendCompletion(ctx, params, model, plan)
+	var used int64
+	if err == nil {
+		used = response.Usage.TotalTokens
+	}
+	agent.recordRateLimit(model, estimated, used)
+	return response, err
+}
+
+// sendCompletion sends a completion request, abandoning it at the first token deadline
+func (agent *Agent) sendCompletion(
+	ctx context.Context,
+	params openai.ChatCompletionNewParams,
+	model string,
+	plan runPlan,
+) (*openai.ChatCompletion, error) {
+	opts := agent.debugOptions(plan.requestOptions)
+	if agent.firstTokenDeadline <= 0 {
+		return agent.client.Chat.Completions.New(ctx, params, opts...)
+	}
+
+	timeout := &FirstTokenTimeoutError{Model: model, Deadline: agent.firstTokenDeadline}
+	requestCtx, cancel := context.WithTimeoutCause(ctx, agent.firstTokenDeadline, timeout)
+	defer cancel()
+	response, err := agent.client.Chat.Completions.New(requestCtx, params, opts...)
+	if err != nil && ctx.Err() == nil && context.Cause(requestCtx) == timeout {
+		return nil, timeout
+	}
+	return response, err
+}
+
+// shouldFallback reports whether a failed request should be retried on the next model.
+// Rate limits, server errors, context overflows, and transport errors fall back;
+// other client errors and cancellations do not.
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var rateLimitErr *RateLimitError
+	var contextErr *ContextLengthExceededError
+	var apiErr *openai.Error
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &contextErr):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	default:
+		return true
+	}
+}
+
+// convertUsage extracts the usage of a completion, tagged with the model that served it
+func convertUsage(response *openai.ChatCompletion) Usage {
+	return Usage{
+		Model:                 response.Model,
+		PromptTokens:          response.Usage.PromptTokens,
+		CachedPromptTokens:    response.Usage.PromptTokensDetails.CachedTokens,
+		AudioPromptTokens:     response.Usage.PromptTokensDetails.AudioTokens,
+		CompletionTokens:      response.Usage.CompletionTokens,
+		ReasoningTokens:       response.Usage.CompletionTokensDetails.ReasoningTokens,
+		AudioCompletionTokens: response.Usage.CompletionTokensDetails.AudioTokens,
+		TotalTokens:           response.Usage.TotalTokens,
+	}
 }
 
 // convertMessages converts models.Message to OpenAI format
 func convertMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
 	var chatMessages []openai.ChatCompletionMessageParamUnion
 	for _, msg := range messages {
-		switch msg.Role() {
-		case RoleSystem:
-			chatMessages = append(chatMessages, openai.SystemMessage(msg.Text()))
-		case RoleAssistant:
-			chatMessages = append(chatMessages, openai.AssistantMessage(msg.Text()))
-		case RoleUser:
-			switch msg.Kind() {
-			case MessageKindText:
-				chatMessages = append(chatMessages, openai.UserMessage(msg.Text()))
-			case MessageKindFile:
-				base64Data := base64.StdEncoding.EncodeToString(msg.File().Data)
-				chatMessages = append(chatMessages, openai.ChatCompletionMessageParamUnion{
-					OfUser: &openai.ChatCompletionUserMessageParam{
-						Content: openai.ChatCompletionUserMessageParamContentUnion{
-							OfArrayOfContentParts: []openai.ChatCompletionContentPartUnionParam{
-								{
-									OfFile: &openai.ChatCompletionContentPartFileParam{
-										File: openai.ChatCompletionContentPartFileFileParam{
-											FileData: openai.String(base64Data),
-											Filename: openai.String(msg.File().Name),
-										},
-									},
-								},
-							},
-						},
+		chatMessages = append(chatMessages, convertMessage(msg))
+	}
+	return chatMessages
+}
+
+// convertMessage converts a single message to OpenAI format
+func convertMessage(msg Message) openai.ChatCompletionMessageParamUnion {
+	converted := convertMessageContent(msg)
+	if msg.CacheControl() {
+		converted = applyCacheControl(converted)
+	}
+	return converted
+}
+
+// convertMessageContent converts a message's role and content to OpenAI format
+func convertMessageContent(msg Message) openai.ChatCompletionMessageParamUnion {
+	switch msg.Role() {
+	case RoleSystem:
+		return openai.SystemMessage(msg.Text())
+	case RoleDeveloper:
+		return openai.DeveloperMessage(msg.Text())
+	case RoleAssistant:
+		if len(msg.ToolCalls()) == 0 {
+			return openai.AssistantMessage(msg.Text())
+		}
+		assistant := openai.ChatCompletionAssistantMessageParam{}
+		if msg.Text() != "" {
+			assistant.Content.OfString = openai.String(msg.Text())
+		}
+		for _, call := range msg.ToolCalls() {
+			assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+				ID: call.ID,
+				Function: openai.ChatCompletionMessageToolCallFunctionParam{
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				},
+			})
+		}
+		return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+	case RoleTool:
+		return openai.ToolMessage(msg.Text(), msg.ToolCallID())
+	case RoleUser:
+		switch msg.Kind() {
+		case MessageKindFile:
+			return openai.ChatCompletionMessageParamUnion{
+				OfUser: &openai.ChatCompletionUserMessageParam{
+					Content: openai.ChatCompletionUserMessageParamContentUnion{
+						OfArrayOfContentParts: []openai.ChatCompletionContentPartUnionParam{filePart(msg.File())},
 					},
-				})
-			case MessageKindImage:
-				base64Data := base64.StdEncoding.EncodeToString(msg.Image().Data)
-				chatMessages = append(chatMessages, openai.ChatCompletionMessageParamUnion{
-					OfUser: &openai.ChatCompletionUserMessageParam{
-						Content: openai.ChatCompletionUserMessageParamContentUnion{
-							OfArrayOfContentParts: []openai.ChatCompletionContentPartUnionParam{
-								{
-									OfImageURL: &openai.ChatCompletionContentPartImageParam{
-										ImageURL: openai.ChatCompletionContentPartImageImageURLParam{
-											URL: "data:image/png;base64," + base64Data,
-										},
-									},
-								},
-							},
-						},
+				},
+			}
+		case MessageKindImage:
+			var parts []openai.ChatCompletionContentPartUnionParam
+			for _, image := range msg.Images() {
+				parts = append(parts, imagePart(image))
+			}
+			return openai.ChatCompletionMessageParamUnion{
+				OfUser: &openai.ChatCompletionUserMessageParam{
+					Content: openai.ChatCompletionUserMessageParamContentUnion{
+						OfArrayOfContentParts: parts,
 					},
-				})
+				},
 			}
 		default:
-			chatMessages = append(chatMessages, openai.UserMessage(msg.Text()))
+			return openai.UserMessage(msg.Text())
 		}
+	default:
+		return openai.UserMessage(msg.Text())
 	}
-	return chatMessages
+}
+
+// imagePart converts an image to a content part with a data URL
+func imagePart(image Image) openai.ChatCompletionContentPartUnionParam {
+	base64Data := base64.StdEncoding.EncodeToString(image.Data)
+	return openai.ChatCompletionContentPartUnionParam{
+		OfImageURL: &openai.ChatCompletionContentPartImageParam{
+			ImageURL: openai.ChatCompletionContentPartImageImageURLParam{
+				URL: "data:" + imageMediaType(image.Data) + ";base64," + base64Data,
+			},
+		},
+	}
+}
+
+// filePart converts a file to a content part with inline data
+func filePart(file File) openai.ChatCompletionContentPartUnionParam {
+	return openai.ChatCompletionContentPartUnionParam{
+		OfFile: &openai.ChatCompletionContentPartFileParam{
+			File: openai.ChatCompletionContentPartFileFileParam{
+				FileData: openai.String(base64.StdEncoding.EncodeToString(file.Data)),
+				Filename: openai.String(file.Name),
+			},
+		},
+	}
+}
+
+// attachmentParts converts the images and files of a tool result to content parts
+func attachmentParts(attachments ToolResult) []openai.ChatCompletionContentPartUnionParam {
+	var parts []openai.ChatCompletionContentPartUnionParam
+	for _, image := range attachments.Images {
+		parts = append(parts, imagePart(image))
+	}
+	for _, file := range attachments.Files {
+		parts = append(parts, filePart(file))
+	}
+	return parts
+}
+
+// newParams creates the params of a run's first completion request
+func (agent *Agent) newParams(prompts runPrompts, messages []Message, tools []Tool, toolChoice openai.ChatCompletionToolChoiceOptionUnionParam) openai.ChatCompletionNewParams {
+	// Convert the messages to OpenAI format and inject system prompt and instructions
+	params := openai.ChatCompletionNewParams{
+		Messages:       agent.buildMessages(prompts, messages),
+		Model:          openai.ChatModel(agent.model),
+		Tools:          convertTools(tools),
+		ToolChoice:     toolChoice,
+		ResponseFormat: agent.responseFormat,
+	}
+	if agent.promptCaching && len(params.Tools) > 0 {
+		params.Tools[len(params.Tools)-1].SetExtraFields(cacheControlField)
+	}
+	return params
 }
 
 // buildMessages converts messages and injects system prompt and instructions
-func (agent *Agent) buildMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
+func (agent *Agent) buildMessages(prompts runPrompts, messages []Message) []openai.ChatCompletionMessageParamUnion {
 	var chatMessages []openai.ChatCompletionMessageParamUnion
 
 	// Add system prompt if provided
-	if agent.systemPrompt != "" {
-		chatMessages = append(chatMessages, openai.SystemMessage(agent.systemPrompt))
+	if prompts.system != "" {
+		systemMessage := openai.SystemMessage(prompts.system)
+		if agent.promptCaching {
+			systemMessage = applyCacheControl(systemMessage)
+		}
+		chatMessages = append(chatMessages, systemMessage)
 	}
 
 	// Add instructions as first user message if provided
-	if agent.instructions != "" {
-		chatMessages = append(chatMessages, openai.UserMessage(agent.instructions))
+	if prompts.instructions != "" {
+		instructionsMessage := openai.UserMessage(prompts.instructions)
+		if agent.promptCaching {
+			instructionsMessage = applyCacheControl(instructionsMessage)
+		}
+		chatMessages = append(chatMessages, instructionsMessage)
 	}
 
 	// Convert and append the provided messages
@@ -316,6 +1032,39 @@ func (agent *Agent) buildMessages(messages []Message) []openai.ChatCompletionMes
 	return chatMessages
 }
 
+// convertTools converts tools to OpenAI function tool definitions sorted by name.
+// Together with the system prompt and instructions leading the messages, this
+// keeps the request prefix byte-identical across iterations and runs so that
+// provider-side prompt caching can reuse it regardless of registration order.
+func convertTools(tools []Tool) []openai.ChatCompletionToolParam {
+	var openAITools []openai.ChatCompletionToolParam
+	for _, tool := range tools {
+		openAITools = append(openAITools, convertTool(tool))
+	}
+	sort.SliceStable(openAITools, func(i, j int) bool {
+		return openAITools[i].Function.Name < openAITools[j].Function.Name
+	})
+	return openAITools
+}
+
+// convertTool converts a Tool to an OpenAI function tool definition
+func convertTool(tool Tool) openai.ChatCompletionToolParam {
+	parameters := convertParameters(tool.Parameters())
+	if t, ok := tool.(ToolWithExamples); ok {
+		if examples := t.Examples(); len(examples) > 0 {
+			parameters["examples"] = examples
+		}
+	}
+	return openai.ChatCompletionToolParam{
+		Type: "function",
+		Function: openai.FunctionDefinitionParam{
+			Name:        tool.Name(),
+			Description: openai.String(tool.Description()),
+			Parameters:  parameters,
+		},
+	}
+}
+
 func convertParameters(parameters Parameters) shared.FunctionParameters {
 	return shared.FunctionParameters{
 		"type":       "object",
@@ -2,10 +2,16 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -396,3 +402,286 @@ func TestNewAgentOptionsPattern(t *testing.T) {
 	assert.Equal(t, tools, agent3.tools)
 	assert.Equal(t, 50, agent3.maxIterations)
 }
+
+// newTestAgent creates an agent backed by an httptest server running handler
+func newTestAgent(t *testing.T, model string, handler http.HandlerFunc, opts ...AgentOption) *Agent {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := openai.NewClient(
+		option.WithAPIKey("test-key"),
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(0),
+	)
+	return NewAgentWithClient(client, model, opts...)
+}
+
+// writeCompletion writes a chat completion response with the given content
+func writeCompletion(w http.ResponseWriter, model string, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%q}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, model, content)
+}
+
+// writeToolCall writes a chat completion response requesting a single tool call
+func writeToolCall(w http.ResponseWriter, model string, name string, arguments string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":%q,"choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":%q,"arguments":%q}}]}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, model, name, arguments)
+}
+
+func TestFallbackModels(t *testing.T) {
+	var requested []string
+	testAgent := newTestAgent(t, "primary", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requested = append(requested, body.Model)
+
+		if body.Model == "primary" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"rate limited","type":"rate_limit_error"}}`)
+			return
+		}
+		writeCompletion(w, body.Model, "hello")
+	}, WithFallbackModels("secondary"))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{
+		UserTextMessage("Hello"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"primary", "secondary"}, requested)
+	assert.Equal(t, []string{"hello"}, completion.Messages)
+	require.True(t, completion.Responses[0].IsUsageResponse())
+	assert.Equal(t, "secondary", completion.Responses[0].Usage().Model)
+}
+
+func TestDeveloperMessage(t *testing.T) {
+	msg := DeveloperMessage("Follow the style guide")
+
+	assert.Equal(t, RoleDeveloper, msg.Role())
+	assert.Equal(t, "Follow the style guide", msg.Text())
+
+	converted := convertMessages([]Message{msg, SystemMessage("Be brief")})
+	require.Len(t, converted, 2)
+	require.NotNil(t, converted[0].OfDeveloper)
+	require.NotNil(t, converted[1].OfSystem)
+
+	tests := []struct {
+		model     string
+		developer bool
+	}{
+		{model: "o3-mini", developer: true},
+		{model: "openai/o4-mini", developer: true},
+		{model: "gpt-4o", developer: false},
+		{model: "claude-sonnet-4-20250514", developer: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			adapted := adaptMessagesForModel(converted, tt.model)
+			require.Len(t, adapted, 2)
+			if tt.developer {
+				assert.NotNil(t, adapted[0].OfDeveloper)
+				require.NotNil(t, adapted[1].OfDeveloper, "reasoning models reject system messages")
+				assert.Equal(t, "Be brief", adapted[1].OfDeveloper.Content.OfString.Value)
+			} else {
+				require.NotNil(t, adapted[0].OfSystem)
+				assert.Equal(t, "Follow the style guide", adapted[0].OfSystem.Content.OfString.Value)
+				assert.NotNil(t, adapted[1].OfSystem)
+			}
+			assert.NotNil(t, converted[0].OfDeveloper, "input should not be modified")
+			assert.NotNil(t, converted[1].OfSystem, "input should not be modified")
+		})
+	}
+}
+
+func TestReasoningModelParams(t *testing.T) {
+	tests := []struct {
+		model string
+		want  map[string]any
+	}{
+		{model: "o3-mini", want: map[string]any{"max_completion_tokens": float64(500), "reasoning_effort": "high"}},
+		{model: "gpt-4o", want: map[string]any{"max_tokens": float64(500)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			var body map[string]any
+			testAgent := newTestAgent(t, tt.model, func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				writeCompletion(w, tt.model, "hello")
+			}, WithSystemPrompt("system"), WithMaxCompletionTokens(500), WithReasoningEffort(ReasoningEffortHigh))
+
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+			require.NoError(t, err)
+
+			for _, key := range []string{"max_tokens", "max_completion_tokens", "reasoning_effort"} {
+				assert.Equal(t, tt.want[key], body[key], key)
+			}
+		})
+	}
+}
+
+// ExampleTool is a MockTool that provides example invocations
+type ExampleTool struct {
+	MockTool
+	examples []map[string]any
+}
+
+func (e ExampleTool) Examples() []map[string]any {
+	return e.examples
+}
+
+func TestConvertToolExamples(t *testing.T) {
+	examples := []map[string]any{{"location": "Tokyo"}}
+	tool := ExampleTool{
+		MockTool: MockTool{name: "get_weather", description: "Get the weather"},
+		examples: examples,
+	}
+
+	result := convertTool(tool)
+	assert.Equal(t, "get_weather", result.Function.Name)
+	assert.Equal(t, examples, result.Function.Parameters["examples"])
+
+	plain := convertTool(MockTool{name: "plain"})
+	assert.NotContains(t, plain.Function.Parameters, "examples")
+}
+
+func TestChatCompletionRunID(t *testing.T) {
+	requests := 0
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeCompletion(w, "test-model", "hello")
+	}, WithRunStore(NewMemoryRunStore()))
+
+	first, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunID("run-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", first.RunID)
+
+	second, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunID("run-1"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, requests, "completed run should not be executed again")
+
+	_, err = testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunID("run-2"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+func TestStreamTo(t *testing.T) {
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "test-model", "hello world")
+	})
+
+	var buf strings.Builder
+	completion, err := testAgent.StreamTo(context.Background(), []Message{UserTextMessage("Hello")}, &buf)
+
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", buf.String())
+	assert.Equal(t, []string{"hello world"}, completion.Messages)
+	assert.Equal(t, int64(2), completion.Usage.TotalTokens)
+}
+
+func TestStablePromptPrefix(t *testing.T) {
+	var bodies []map[string]json.RawMessage
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies)%2 == 1 {
+			fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"b_tool","arguments":"{}"}}]}}],"usage":{"prompt_tokens":100,"completion_tokens":1,"total_tokens":101,"prompt_tokens_details":{"cached_tokens":0}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"2","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"done"}}],"usage":{"prompt_tokens":100,"completion_tokens":1,"total_tokens":101,"prompt_tokens_details":{"cached_tokens":80}}}`)
+	}
+
+	a := MockTool{name: "a_tool", parameters: Parameters{Properties: map[string]any{"x": map[string]any{"type": "string"}, "y": map[string]any{"type": "string"}}}}
+	b := MockTool{name: "b_tool"}
+
+	first := newTestAgent(t, "test-model", handler, WithSystemPrompt("system"), WithTools([]Tool{a, b}))
+	completion, err := first.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+
+	second := newTestAgent(t, "test-model", handler, WithSystemPrompt("system"), WithTools([]Tool{b, a}))
+	_, err = second.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+
+	require.Len(t, bodies, 4)
+	for _, body := range bodies[1:] {
+		assert.JSONEq(t, string(bodies[0]["tools"]), string(body["tools"]))
+		assert.Equal(t, string(bodies[0]["tools"]), string(body["tools"]), "tools should be byte-identical")
+	}
+
+	assert.Equal(t, int64(80), completion.Usage.CachedPromptTokens)
+	assert.InDelta(t, 0.4, completion.Usage.CacheHitRate(), 0.0001)
+}
+
+func TestUsageSteps(t *testing.T) {
+	requests := 0
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"test_tool","arguments":"{}"}}]}}],"usage":{"prompt_tokens":50,"completion_tokens":30,"total_tokens":80,"prompt_tokens_details":{"cached_tokens":10,"audio_tokens":5},"completion_tokens_details":{"reasoning_tokens":20}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"2","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"done"}}],"usage":{"prompt_tokens":70,"completion_tokens":10,"total_tokens":80,"prompt_tokens_details":{"cached_tokens":40},"completion_tokens_details":{"reasoning_tokens":4,"audio_tokens":2}}}`)
+	}, WithTools([]Tool{MockTool{name: "test_tool"}}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+
+	require.Len(t, completion.Steps, 2)
+	assert.Equal(t, Usage{
+		Model: "test-model", Iteration: 1, PromptTokens: 50, CachedPromptTokens: 10, AudioPromptTokens: 5,
+		CompletionTokens: 30, ReasoningTokens: 20, TotalTokens: 80,
+	}, completion.Steps[0])
+	assert.Equal(t, 2, completion.Steps[1].Iteration)
+	assert.Equal(t, int64(2), completion.Steps[1].AudioCompletionTokens)
+
+	assert.Equal(t, Usage{
+		PromptTokens: 120, CachedPromptTokens: 50, AudioPromptTokens: 5,
+		CompletionTokens: 40, ReasoningTokens: 24, AudioCompletionTokens: 2, TotalTokens: 160,
+	}, completion.Usage)
+}
+
+func TestReplayToolMessages(t *testing.T) {
+	var body struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeCompletion(w, "test-model", "It is sunny")
+	})
+
+	history := []Message{
+		UserTextMessage("What's the weather?"),
+		AssistantToolCallMessage("", ToolCall{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Paris"}`}),
+		ToolResultMessage("call_1", "sunny"),
+		UserTextMessage("Summarize"),
+	}
+	assert.Equal(t, RoleTool, history[2].Role())
+	assert.Equal(t, "call_1", history[2].ToolCallID())
+	assert.Equal(t, "sunny", history[2].Text())
+
+	_, err := testAgent.ChatCompletion(context.Background(), history)
+	require.NoError(t, err)
+
+	require.Len(t, body.Messages, 4)
+	assistant := body.Messages[1]
+	assert.Equal(t, "assistant", assistant["role"])
+	assert.NotContains(t, assistant, "content")
+	calls := assistant["tool_calls"].([]any)
+	require.Len(t, calls, 1)
+	call := calls[0].(map[string]any)
+	assert.Equal(t, "call_1", call["id"])
+	assert.Equal(t, "function", call["type"])
+	assert.Equal(t, map[string]any{"name": "get_weather", "arguments": `{"city":"Paris"}`}, call["function"])
+
+	assert.Equal(t, map[string]any{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}, body.Messages[2])
+}
@@ -0,0 +1,247 @@
+// Package agentconfig builds agents from declarative YAML or JSON definitions,
+// so teams can version their agents alongside code and construct them at
+// runtime with the tools of a registry.
+//
+// A file defines agents by name:
+//
+//	agents:
+//	  support:
+//	    model: gpt-4o
+//	    base_url: ${OPENAI_BASE_URL}
+//	    api_key: ${OPENAI_API_KEY}
+//	    system_prompt: You are a support agent for Acme.
+//	    tools: [lookup_order, search_docs]
+//	    max_iterations: 20
+//	    tool_timeout: 30s
+package agentconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultBaseURL is the base URL of agents that do not set one
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Config is a set of agent definitions
+type Config struct {
+	Agents map[string]AgentConfig `yaml:"agents"`
+}
+
+// AgentConfig defines an agent. BaseURL, APIKey, and Model may refer to
+// environment variables as ${NAME}.
+type AgentConfig struct {
+	Model               string                `yaml:"model"`
+	BaseURL             string                `yaml:"base_url"`
+	APIKey              string                `yaml:"api_key"`
+	FallbackModels      []string              `yaml:"fallback_models"`
+	SystemPrompt        string                `yaml:"system_prompt"`
+	Instructions        string                `yaml:"instructions"`
+	Tools               []string              `yaml:"tools"`
+	MaxIterations       int                   `yaml:"max_iterations"`
+	MaxCompletionTokens int64                 `yaml:"max_completion_tokens"`
+	ReasoningEffort     agent.ReasoningEffort `yaml:"reasoning_effort"`
+	PromptCaching       bool                  `yaml:"prompt_caching"`
+	ToolTimeout         time.Duration         `yaml:"tool_timeout"`
+	Headers             map[string]string     `yaml:"headers"`
+	Limits              *LimitsConfig         `yaml:"limits"`
+	Budget              *BudgetConfig         `yaml:"budget"`
+}
+
+// LimitsConfig is the input limits of an agent, see agent.Limits
+type LimitsConfig struct {
+	MaxMessages     int `yaml:"max_messages"`
+	MaxMessageBytes int `yaml:"max_message_bytes"`
+	MaxAttachments  int `yaml:"max_attachments"`
+}
+
+// BudgetConfig is the per-run budget of an agent, see agent.WithRunBudget
+type BudgetConfig struct {
+	MaxTotalTokens int64   `yaml:"max_total_tokens"`
+	MaxCostUSD     float64 `yaml:"max_cost_usd"`
+}
+
+// Load reads agent definitions from a YAML or JSON file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Parse decodes agent definitions from YAML or JSON. Unknown fields are
+// rejected, so typos fail loudly instead of silently leaving defaults.
+func Parse(data []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+	for _, name := range config.Names() {
+		if config.Agents[name].Model == "" {
+			return nil, fmt.Errorf("agent %s: no model", name)
+		}
+	}
+	return &config, nil
+}
+
+// Names returns the names of the defined agents, sorted
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Agents))
+	for name := range c.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Agent builds the named agent, taking the tools it refers to from registry,
+// which may be nil for agents without tools. opts apply after the definition,
+// for options that cannot be declared, such as loggers and metrics.
+func (c *Config) Agent(name string, registry *agent.ToolRegistry, opts ...agent.AgentOption) (*agent.Agent, error) {
+	definition, ok := c.Agents[name]
+	if !ok {
+		return nil, fmt.Errorf("agent %s: not defined", name)
+	}
+	a, err := definition.Build(registry, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+	return a, nil
+}
+
+// Build builds every defined agent, by name
+func (c *Config) Build(registry *agent.ToolRegistry, opts ...agent.AgentOption) (map[string]*agent.Agent, error) {
+	agents := make(map[string]*agent.Agent, len(c.Agents))
+	for _, name := range c.Names() {
+		a, err := c.Agent(name, registry, opts...)
+		if err != nil {
+			return nil, err
+		}
+		agents[name] = a
+	}
+	return agents, nil
+}
+
+// Build creates the agent the definition describes
+func (d AgentConfig) Build(registry *agent.ToolRegistry, opts ...agent.AgentOption) (*agent.Agent, error) {
+	var missing []string
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok && !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+	model, baseURL, apiKey := expand(d.Model), expand(d.BaseURL), expand(d.APIKey)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	tools, err := d.resolveTools(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []agent.AgentOption
+	if d.SystemPrompt != "" {
+		options = append(options, agent.WithSystemPrompt(d.SystemPrompt))
+	}
+	if d.Instructions != "" {
+		options = append(options, agent.WithInstructions(d.Instructions))
+	}
+	if len(tools) > 0 {
+		options = append(options, agent.WithTools(tools))
+	}
+	if len(d.FallbackModels) > 0 {
+		options = append(options, agent.WithFallbackModels(d.FallbackModels...))
+	}
+	if d.MaxIterations > 0 {
+		options = append(options, agent.WithMaxIterations(d.MaxIterations))
+	}
+	if d.MaxCompletionTokens > 0 {
+		options = append(options, agent.WithMaxCompletionTokens(d.MaxCompletionTokens))
+	}
+	if d.ReasoningEffort != "" {
+		options = append(options, agent.WithReasoningEffort(d.ReasoningEffort))
+	}
+	if d.PromptCaching {
+		options = append(options, agent.WithPromptCaching())
+	}
+	if d.ToolTimeout > 0 {
+		options = append(options, agent.WithToolTimeout(d.ToolTimeout))
+	}
+	for _, key := range sortedKeys(d.Headers) {
+		options = append(options, agent.WithHeader(key, d.Headers[key]))
+	}
+	if d.Limits != nil {
+		options = append(options, agent.WithLimits(agent.Limits{
+			MaxMessages:     d.Limits.MaxMessages,
+			MaxMessageBytes: d.Limits.MaxMessageBytes,
+			MaxAttachments:  d.Limits.MaxAttachments,
+		}))
+	}
+	if d.Budget != nil {
+		options = append(options, agent.WithRunBudget(d.Budget.MaxTotalTokens, d.Budget.MaxCostUSD))
+	}
+
+	return agent.NewAgent(apiKey, baseURL, model, append(options, opts...)...), nil
+}
+
+// resolveTools looks up the definition's tools in registry by name
+func (d AgentConfig) resolveTools(registry *agent.ToolRegistry) ([]agent.Tool, error) {
+	if len(d.Tools) == 0 {
+		return nil, nil
+	}
+	if registry == nil {
+		return nil, errors.New("tools are referenced but no registry was given")
+	}
+	registered := map[string]agent.Tool{}
+	for _, tool := range registry.List() {
+		registered[tool.Name()] = tool
+	}
+	tools := make([]agent.Tool, 0, len(d.Tools))
+	var unknown []string
+	for _, name := range d.Tools {
+		tool, ok := registered[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown tools: %s", strings.Join(unknown, ", "))
+	}
+	return tools, nil
+}
+
+// sortedKeys returns the keys of m, sorted
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
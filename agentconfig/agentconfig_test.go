@@ -0,0 +1,132 @@
+package agentconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedTool is a tool that only has a name
+type namedTool string
+
+func (t namedTool) Name() string                 { return string(t) }
+func (t namedTool) Description() string          { return "A tool" }
+func (t namedTool) Parameters() agent.Parameters { return agent.Parameters{} }
+func (t namedTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return "ok", nil
+}
+
+const supportYAML = `
+agents:
+  support:
+    model: gpt-test
+    base_url: ${TEST_BASE_URL}
+    api_key: ${TEST_API_KEY}
+    system_prompt: |
+      You are a support agent.
+    tools: [lookup_order]
+    max_iterations: 5
+    tool_timeout: 30s
+    headers:
+      X-Team: support
+    limits:
+      max_messages: 10
+  triage:
+    model: gpt-mini
+    budget:
+      max_total_tokens: 1000
+`
+
+func TestLoad(t *testing.T) {
+	var request struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"gpt-test","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	defer server.Close()
+	t.Setenv("TEST_BASE_URL", server.URL)
+	t.Setenv("TEST_API_KEY", "secret")
+
+	path := filepath.Join(t.TempDir(), "agents.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(supportYAML), 0o644))
+	config, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support", "triage"}, config.Names())
+	assert.Equal(t, 30*time.Second, config.Agents["support"].ToolTimeout)
+	assert.Equal(t, &BudgetConfig{MaxTotalTokens: 1000}, config.Agents["triage"].Budget)
+
+	registry := agent.NewToolRegistry(namedTool("lookup_order"), namedTool("refund"))
+	agents, err := config.Build(registry)
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+
+	support := agents["support"]
+	assert.Len(t, support.Tools(), 1)
+	_, err = support.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-test", request.Model)
+	assert.Equal(t, "You are a support agent.\n", request.Messages[0].Content)
+	require.Len(t, request.Tools, 1)
+	assert.Equal(t, "lookup_order", request.Tools[0].Function.Name)
+	assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+	assert.Equal(t, "support", header.Get("X-Team"))
+}
+
+func TestParseJSON(t *testing.T) {
+	config, err := Parse([]byte(`{"agents": {"writer": {"model": "gpt-test", "max_iterations": 3}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, AgentConfig{Model: "gpt-test", MaxIterations: 3}, config.Agents["writer"])
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte("agents:\n  writer:\n    model: gpt-test\n    max_iteration: 3\n"))
+	assert.ErrorContains(t, err, "field max_iteration not found")
+
+	_, err = Parse([]byte("agents:\n  writer:\n    system_prompt: hi\n"))
+	assert.EqualError(t, err, "agent writer: no model")
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBuildErrors(t *testing.T) {
+	config, err := Parse([]byte("agents:\n  writer:\n    model: gpt-test\n    api_key: ${UNSET_TEST_KEY}\n    tools: [search, fetch]\n"))
+	require.NoError(t, err)
+
+	_, err = config.Agent("writer", agent.NewToolRegistry())
+	assert.EqualError(t, err, "agent writer: environment variables not set: UNSET_TEST_KEY")
+
+	t.Setenv("UNSET_TEST_KEY", "key")
+	_, err = config.Agent("writer", agent.NewToolRegistry(namedTool("search")))
+	assert.EqualError(t, err, "agent writer: unknown tools: fetch")
+
+	_, err = config.Agent("writer", nil)
+	assert.EqualError(t, err, "agent writer: tools are referenced but no registry was given")
+
+	_, err = config.Agent("reader", nil)
+	assert.EqualError(t, err, "agent reader: not defined")
+}
@@ -0,0 +1,236 @@
+// Package agenttest provides helpers for testing agents and their tools.
+//
+// GenerateToolTests exercises each tool with inputs derived from its parameter
+// schema, both valid and deliberately malformed, and fails when a tool panics,
+// hangs, or returns a result that cannot be sent back to the model:
+//
+//	func TestTools(t *testing.T) {
+//		agenttest.GenerateToolTests(t, myAgent.Tools())
+//	}
+//
+// Server is a fake Chat Completions endpoint answering with scripted replies,
+// for testing the full agent loop without a provider or keys.
+package agenttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sort"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Option configures the generated tests
+type Option func(*config)
+
+type config struct {
+	seed    uint64
+	cases   int
+	timeout time.Duration
+	ctx     context.Context
+}
+
+// WithSeed sets the seed for generated inputs. The default is fixed, so runs are reproducible.
+func WithSeed(seed uint64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// WithCases sets the number of random valid inputs tried per tool (default 20)
+func WithCases(n int) Option {
+	return func(c *config) {
+		c.cases = n
+	}
+}
+
+// WithTimeout sets how long a single call may take before it is reported as a
+// hang (default 5s). The call's context is canceled at the timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithContext sets the parent context for tool calls, for tools that read
+// values such as credentials from it
+func WithContext(ctx context.Context) Option {
+	return func(c *config) {
+		c.ctx = ctx
+	}
+}
+
+// Failure is a tool call that panicked, hung, or returned an unusable result
+type Failure struct {
+	Tool string
+	// Case describes the generated input, such as "valid" or `wrong type for "path"`
+	Case  string
+	Input map[string]any
+	// Reason explains the failure
+	Reason string
+	// Stack is the goroutine stack of a panic
+	Stack string
+}
+
+func (f Failure) String() string {
+	input, _ := json.Marshal(f.Input)
+	s := fmt.Sprintf("%s (%s) with input %s: %s", f.Tool, f.Case, input, f.Reason)
+	if f.Stack != "" {
+		s += "\n" + f.Stack
+	}
+	return s
+}
+
+// GenerateToolTests runs a subtest per tool that calls CheckTool and reports each failure
+func GenerateToolTests(t *testing.T, tools []agent.Tool, opts ...Option) {
+	t.Helper()
+	for _, tool := range tools {
+		t.Run(tool.Name(), func(t *testing.T) {
+			for _, failure := range CheckTool(tool, opts...) {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+// CheckTool calls tool with generated inputs and returns the calls that failed.
+//
+// Valid inputs satisfy the parameter schema: required properties are always set
+// and optional ones sometimes, with values drawn from each property's type and
+// constraints (enum, pattern, length, and range). Invalid inputs omit each
+// required property in turn and give each property a null or wrongly typed
+// value, as a model might. Tools may return errors for any input; a call fails
+// only if it panics, does not return before the timeout, or, for valid inputs,
+// returns a result that cannot be encoded as JSON.
+func CheckTool(tool agent.Tool, opts ...Option) []Failure {
+	cfg := config{seed: 1, cases: 20, timeout: 5 * time.Second, ctx: context.Background()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	parameters := tool.Parameters()
+	gen := &generator{rand: rand.New(rand.NewPCG(cfg.seed, cfg.seed))}
+
+	var failures []Failure
+	fail := func(name string, input map[string]any, reason string, stack string) {
+		failures = append(failures, Failure{Tool: tool.Name(), Case: name, Input: input, Reason: reason, Stack: stack})
+	}
+
+	// A required property without a definition can never be generated correctly
+	for _, name := range parameters.Required {
+		if _, ok := parameters.Properties[name]; !ok {
+			fail("schema", nil, fmt.Sprintf("required property %q is not defined", name), "")
+		}
+	}
+
+	// call runs one input and reports whether the tool returned normally
+	call := func(name string, input map[string]any, valid bool) {
+		result := execute(cfg, tool, input)
+		switch {
+		case result.panicked:
+			fail(name, input, fmt.Sprintf("panic: %v", result.panic), result.stack)
+		case result.hung:
+			fail(name, input, fmt.Sprintf("did not return within %v", cfg.timeout), "")
+		case valid && result.err == nil:
+			if _, err := json.Marshal(result.value); err != nil {
+				fail(name, input, fmt.Sprintf("result cannot be encoded: %v", err), "")
+			}
+		}
+	}
+
+	// Invalid inputs are made by breaking one property of a valid input
+	base := gen.arguments(parameters, 1)
+	for i := range cfg.cases {
+		input := gen.arguments(parameters, i)
+		// Generated values may miss constraints the generator cannot model,
+		// such as a pattern that conflicts with a length limit
+		if agent.ValidateArguments(parameters, input) != nil {
+			continue
+		}
+		call("valid", input, true)
+	}
+
+	call("nil input", nil, false)
+	call("empty input", map[string]any{}, false)
+	for _, name := range parameters.Required {
+		input := clone(base)
+		delete(input, name)
+		call(fmt.Sprintf("missing %q", name), input, false)
+	}
+	for _, name := range sortedKeys(parameters.Properties) {
+		input := clone(base)
+		input[name] = nil
+		call(fmt.Sprintf("null %q", name), input, false)
+
+		schema, _ := asSchema(parameters.Properties[name])
+		for _, value := range wrongTypes(schemaType(schema)) {
+			input := clone(base)
+			input[name] = value
+			call(fmt.Sprintf("wrong type for %q", name), input, false)
+		}
+	}
+	return failures
+}
+
+// callResult is the outcome of one tool call
+type callResult struct {
+	value    any
+	err      error
+	panicked bool
+	panic    any
+	stack    string
+	hung     bool
+}
+
+// execute calls the tool, recovering panics and giving up after the timeout
+func execute(cfg config, tool agent.Tool, input map[string]any) callResult {
+	ctx, cancel := context.WithTimeout(cfg.ctx, cfg.timeout)
+	defer cancel()
+
+	done := make(chan callResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- callResult{panicked: true, panic: r, stack: string(debug.Stack())}
+			}
+		}()
+		value, err := tool.Execute(ctx, input)
+		done <- callResult{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		// Allow a moment for the tool to notice cancellation
+		select {
+		case result := <-done:
+			return result
+		case <-time.After(cfg.timeout / 10):
+			return callResult{hung: true}
+		}
+	}
+}
+
+// clone copies the top level of an input
+func clone(input map[string]any) map[string]any {
+	out := make(map[string]any, len(input))
+	for k, v := range input {
+		out[k] = v
+	}
+	return out
+}
+
+// sortedKeys returns the keys of m in order, so generation is deterministic
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
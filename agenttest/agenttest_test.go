@@ -0,0 +1,166 @@
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTool struct {
+	parameters agent.Parameters
+	execute    func(ctx context.Context, input map[string]any) (any, error)
+}
+
+func (t testTool) Name() string                 { return "test_tool" }
+func (t testTool) Description() string          { return "A tool under test" }
+func (t testTool) Parameters() agent.Parameters { return t.parameters }
+func (t testTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return t.execute(ctx, input)
+}
+
+var weatherParameters = agent.Parameters{
+	Properties: map[string]any{
+		"city":  agent.StringSchema("The city").Pattern(`^[A-Z][a-z]+( [A-Z][a-z]+)?$`),
+		"days":  agent.IntegerSchema("Forecast days").Range(1, 7),
+		"units": agent.StringSchema("Units").Enum("metric", "imperial"),
+		"hours": agent.ArraySchema("Hours of the day", agent.IntegerSchema("Hour").Range(0, 23)),
+		"location": agent.ObjectSchema("Coordinates", map[string]agent.Schema{
+			"lat": agent.NumberSchema("Latitude").Range(-90, 90),
+			"lon": agent.NumberSchema("Longitude").Range(-180, 180),
+		}),
+	},
+	Required: []string{"city"},
+}
+
+func TestGenerateToolTests(t *testing.T) {
+	var inputs []map[string]any
+	tool := testTool{
+		parameters: weatherParameters,
+		execute: func(ctx context.Context, input map[string]any) (any, error) {
+			inputs = append(inputs, input)
+			city, ok := input["city"].(string)
+			if !ok {
+				return nil, fmt.Errorf("city must be a string")
+			}
+			days, _ := input["days"].(float64)
+			return map[string]any{"city": city, "days": days}, nil
+		},
+	}
+
+	GenerateToolTests(t, []agent.Tool{tool}, WithCases(30))
+
+	// Valid inputs satisfy the schema and vary between calls
+	valid := inputs[:30]
+	cities := map[string]bool{}
+	for _, input := range valid {
+		require.NoError(t, agent.ValidateArguments(weatherParameters, input), input)
+		cities[input["city"].(string)] = true
+		if hours, ok := input["hours"].([]any); ok {
+			for _, hour := range hours {
+				assert.IsType(t, float64(0), hour)
+			}
+		}
+		if location, ok := input["location"].(map[string]any); ok {
+			assert.Contains(t, location, "lat")
+			assert.Contains(t, location, "lon")
+		}
+	}
+	assert.Greater(t, len(cities), 10)
+	assert.Equal(t, map[string]any{"city": valid[0]["city"]}, valid[0])
+	assert.Len(t, valid[1], 5)
+
+	// Then nil, empty, missing, null, and wrongly typed inputs
+	invalid := inputs[30:]
+	assert.Nil(t, invalid[0])
+	assert.Equal(t, map[string]any{}, invalid[1])
+	assert.NotContains(t, invalid[2], "city")
+	assert.Len(t, invalid, 3+5+2*5)
+}
+
+func TestCheckToolFailures(t *testing.T) {
+	t.Run("panic", func(t *testing.T) {
+		tool := testTool{
+			parameters: weatherParameters,
+			execute: func(ctx context.Context, input map[string]any) (any, error) {
+				return len(input["city"].(string)), nil
+			},
+		}
+		failures := CheckTool(tool)
+		var cases []string
+		for _, failure := range failures {
+			cases = append(cases, failure.Case)
+			assert.Contains(t, failure.Reason, "panic: interface conversion")
+			assert.Contains(t, failure.Stack, "agenttest")
+		}
+		assert.Equal(t, []string{
+			"nil input",
+			"empty input",
+			`missing "city"`,
+			`null "city"`,
+			`wrong type for "city"`,
+			`wrong type for "city"`,
+		}, cases)
+		assert.Contains(t, failures[4].String(), `test_tool (wrong type for "city") with input {"city":42`)
+	})
+
+	t.Run("hang", func(t *testing.T) {
+		tool := testTool{
+			parameters: agent.Parameters{Properties: map[string]any{"n": agent.NumberSchema("A number")}},
+			execute: func(ctx context.Context, input map[string]any) (any, error) {
+				if _, ok := input["n"].(string); ok {
+					select {}
+				}
+				return nil, nil
+			},
+		}
+		start := time.Now()
+		failures := CheckTool(tool, WithCases(1), WithTimeout(50*time.Millisecond))
+		require.Len(t, failures, 1)
+		assert.Equal(t, `wrong type for "n"`, failures[0].Case)
+		assert.Equal(t, "did not return within 50ms", failures[0].Reason)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("unencodable result", func(t *testing.T) {
+		tool := testTool{
+			parameters: agent.Parameters{Properties: map[string]any{}},
+			execute: func(ctx context.Context, input map[string]any) (any, error) {
+				return make(chan int), nil
+			},
+		}
+		failures := CheckTool(tool, WithCases(1))
+		require.Len(t, failures, 1)
+		assert.Equal(t, "valid", failures[0].Case)
+		assert.Contains(t, failures[0].Reason, "result cannot be encoded")
+	})
+
+	t.Run("undefined required property", func(t *testing.T) {
+		tool := testTool{
+			parameters: agent.Parameters{Properties: map[string]any{}, Required: []string{"path"}},
+			execute: func(ctx context.Context, input map[string]any) (any, error) {
+				return nil, fmt.Errorf("not implemented")
+			},
+		}
+		failures := CheckTool(tool, WithCases(1))
+		require.NotEmpty(t, failures)
+		assert.Equal(t, `required property "path" is not defined`, failures[0].Reason)
+	})
+}
+
+func TestGeneratePattern(t *testing.T) {
+	gen := &generator{rand: rand.New(rand.NewPCG(7, 7))}
+	for _, pattern := range []string{`^[a-z]{2,4}-\d+$`, `^(foo|bar)\.go$`, `^v\d+\.\d+(\.\d+)?$`, `^\w+@\w+\.com$`} {
+		re := regexp.MustCompile(pattern)
+		for range 20 {
+			value := gen.string(map[string]any{"pattern": pattern})
+			assert.Regexp(t, re, value)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package agenttest
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// maxRepeat bounds unbounded repetition in patterns, string lengths, and array sizes
+const maxRepeat = 8
+
+// sampleRunes are drawn for unconstrained strings, including whitespace,
+// punctuation that needs escaping, and multibyte characters
+var sampleRunes = []rune("abcXYZ019 _-./\\\"'\n\té日本🙂")
+
+// wordChars are drawn for the wildcard in patterns
+const wordChars = "abcXYZ019"
+
+// generator produces values in the form json.Unmarshal gives tool arguments:
+// float64, string, bool, []any, map[string]any, and nil
+type generator struct {
+	rand *rand.Rand
+}
+
+// arguments generates a valid input for parameters. Case 0 sets only required
+// properties and case 1 sets every property; later cases set optional
+// properties at random.
+func (g *generator) arguments(parameters agent.Parameters, n int) map[string]any {
+	return g.object(parameters.Properties, parameters.Required, n)
+}
+
+// object generates an object with the required properties and some optional ones
+func (g *generator) object(properties map[string]any, required []string, n int) map[string]any {
+	isRequired := make(map[string]bool, len(required))
+	for _, name := range required {
+		isRequired[name] = true
+	}
+	out := make(map[string]any)
+	for _, name := range sortedKeys(properties) {
+		include := isRequired[name] || n == 1 || (n > 1 && g.rand.IntN(2) == 0)
+		if !include {
+			continue
+		}
+		schema, _ := asSchema(properties[name])
+		out[name] = g.value(schema)
+	}
+	return out
+}
+
+// value generates a value satisfying schema
+func (g *generator) value(schema map[string]any) any {
+	if enum := reflect.ValueOf(schema["enum"]); enum.Kind() == reflect.Slice && enum.Len() > 0 {
+		return normalize(enum.Index(g.rand.IntN(enum.Len())).Interface())
+	}
+
+	switch schemaType(schema) {
+	case "string":
+		return g.string(schema)
+	case "number", "integer":
+		return g.number(schema)
+	case "boolean":
+		return g.rand.IntN(2) == 0
+	case "array":
+		items, _ := asSchema(schema["items"])
+		lo, hi := bounds(schema, "minItems", "maxItems", 0, 3)
+		out := make([]any, lo+g.rand.IntN(hi-lo+1))
+		for i := range out {
+			out[i] = g.value(items)
+		}
+		return out
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+		return g.object(properties, stringList(schema["required"]), 2)
+	case "null":
+		return nil
+	default:
+		// Untyped properties accept anything; a string is the most common intent
+		return g.string(schema)
+	}
+}
+
+// string generates a string within the schema's pattern and length limits
+func (g *generator) string(schema map[string]any) string {
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := syntax.Parse(pattern, syntax.Perl); err == nil {
+			var b strings.Builder
+			g.match(&b, re.Simplify())
+			return b.String()
+		}
+	}
+	lo, hi := bounds(schema, "minLength", "maxLength", 0, maxRepeat)
+	runes := make([]rune, lo+g.rand.IntN(hi-lo+1))
+	for i := range runes {
+		runes[i] = sampleRunes[g.rand.IntN(len(sampleRunes))]
+	}
+	return string(runes)
+}
+
+// number generates a number within the schema's range, often at its edges
+func (g *generator) number(schema map[string]any) float64 {
+	lo, hasLo := toFloat(schema["minimum"])
+	hi, hasHi := toFloat(schema["maximum"])
+	if !hasLo {
+		lo = -1000
+	}
+	if !hasHi {
+		hi = 1000
+	}
+	if hasLo && !hasHi {
+		hi = lo + 2000
+	} else if hasHi && !hasLo {
+		lo = hi - 2000
+	}
+
+	var n float64
+	switch g.rand.IntN(4) {
+	case 0:
+		n = lo
+	case 1:
+		n = hi
+	case 2:
+		n = math.Max(lo, math.Min(hi, 0))
+	default:
+		n = lo + g.rand.Float64()*(hi-lo)
+	}
+	if schemaType(schema) == "integer" {
+		n = math.Ceil(n)
+		if n > hi {
+			n = math.Floor(hi)
+		}
+	}
+	return n
+}
+
+// match writes a string matched by the regular expression
+func (g *generator) match(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		// Rune holds inclusive ranges as pairs
+		if len(re.Rune) == 0 {
+			return
+		}
+		i := g.rand.IntN(len(re.Rune)/2) * 2
+		lo, hi := re.Rune[i], re.Rune[i+1]
+		hi = min(hi, lo+255)
+		b.WriteRune(lo + rune(g.rand.IntN(int(hi-lo)+1)))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte(wordChars[g.rand.IntN(len(wordChars))])
+	case syntax.OpCapture:
+		g.match(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.match(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.match(b, re.Sub[g.rand.IntN(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lo, hi := 0, maxRepeat
+		switch re.Op {
+		case syntax.OpPlus:
+			lo = 1
+		case syntax.OpQuest:
+			hi = 1
+		case syntax.OpRepeat:
+			lo = re.Min
+			hi = re.Max
+			if hi < 0 {
+				hi = lo + maxRepeat
+			}
+		}
+		for range lo + g.rand.IntN(hi-lo+1) {
+			g.match(b, re.Sub[0])
+		}
+	}
+	// Anchors, word boundaries, and empty matches write nothing
+}
+
+// wrongTypes returns values of a different JSON type than typ
+func wrongTypes(typ string) []any {
+	switch typ {
+	case "string":
+		return []any{float64(42), []any{"a"}}
+	case "number", "integer":
+		return []any{"42", true}
+	case "boolean":
+		return []any{"true", float64(1)}
+	case "array":
+		return []any{"a,b", map[string]any{"0": "a"}}
+	case "object":
+		return []any{"{}", []any{}}
+	default:
+		return nil
+	}
+}
+
+// asSchema returns a property definition as a map, if it is one
+func asSchema(property any) (map[string]any, bool) {
+	switch p := property.(type) {
+	case agent.Schema:
+		return p, true
+	case map[string]any:
+		return p, true
+	default:
+		return nil, false
+	}
+}
+
+// schemaType returns the schema's type, choosing the first non-null type of a union
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	case []string:
+		for _, s := range t {
+			if s != "null" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// bounds returns the schema's integer limits, falling back to defaults
+func bounds(schema map[string]any, minKey string, maxKey string, lo int, hi int) (int, int) {
+	if n, ok := toFloat(schema[minKey]); ok {
+		lo = int(n)
+	}
+	if n, ok := toFloat(schema[maxKey]); ok {
+		hi = int(n)
+	} else if hi < lo {
+		hi = lo + maxRepeat
+	}
+	return lo, max(hi, lo)
+}
+
+// normalize converts Go numbers to float64, as decoded JSON would have them
+func normalize(v any) any {
+	if n, ok := toFloat(v); ok {
+		return n
+	}
+	return v
+}
+
+// stringList converts a list of strings in either []string or []any form
+func stringList(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		var out []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
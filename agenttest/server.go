@@ -0,0 +1,423 @@
+package agenttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Reply is a scripted response of a Server
+type Reply struct {
+	Content   string
+	ToolCalls []agent.ToolCall
+	// FinishReason defaults to "tool_calls" for replies with tool calls and "stop" otherwise
+	FinishReason string
+	// Usage defaults to an estimate from the request and reply text
+	Usage *agent.Usage
+	// Status, when not 200, fails the request with ErrorMessage, as the
+	// provider does for rate limits (429) and outages (5xx)
+	Status       int
+	ErrorMessage string
+	// Delay holds the response back, for testing timeouts and cancellation
+	Delay time.Duration
+}
+
+// Text creates a reply answering with content
+func Text(content string) Reply {
+	return Reply{Content: content}
+}
+
+// CallTool creates a reply calling a tool with JSON arguments. Calls are given
+// IDs call_1, call_2, and so on in the order the server sends them.
+func CallTool(name string, arguments string) Reply {
+	return Reply{ToolCalls: []agent.ToolCall{{Name: name, Arguments: arguments}}}
+}
+
+// Fail creates a reply failing the request with an HTTP status
+func Fail(status int, message string) Reply {
+	return Reply{Status: status, ErrorMessage: message}
+}
+
+// Request is a chat completion request received by a Server
+type Request struct {
+	Model    string
+	Messages []RequestMessage
+	// Tools holds the names of the tools offered to the model
+	Tools  []string
+	Stream bool
+	Header http.Header
+	// Body is the raw request, for assertions on other parameters
+	Body json.RawMessage
+}
+
+// RequestMessage is a message of a received request. The text of content
+// parts is joined.
+type RequestMessage struct {
+	Role       string
+	Content    string
+	ToolCalls  []agent.ToolCall
+	ToolCallID string
+}
+
+// LastMessage returns the last message of the request
+func (r Request) LastMessage() RequestMessage {
+	if len(r.Messages) == 0 {
+		return RequestMessage{}
+	}
+	return r.Messages[len(r.Messages)-1]
+}
+
+// Server is a fake OpenAI-compatible Chat Completions endpoint for testing the
+// full agent loop without a provider or keys. It answers requests with
+// scripted replies in order, in JSON or, for streaming requests, as
+// server-sent events:
+//
+//	server := agenttest.NewServer(t,
+//		agenttest.CallTool("get_weather", `{"city":"Paris"}`),
+//		agenttest.Text("It is sunny in Paris."),
+//	)
+//	a := agent.NewAgent("test-key", server.URL, "gpt-test", agent.WithTools(tools))
+type Server struct {
+	// URL is the base URL to give the agent
+	URL string
+
+	t        testing.TB
+	server   *httptest.Server
+	mu       sync.Mutex
+	replies  []Reply
+	handler  func(Request) Reply
+	requests []Request
+	calls    int
+}
+
+// NewServer starts a server answering with replies in order. It is closed when the test ends.
+func NewServer(t testing.TB, replies ...Reply) *Server {
+	s := &Server{t: t, replies: replies}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.server.URL + "/v1"
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Enqueue adds replies to answer the next requests with
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Handle answers requests with handler once the scripted replies run out
+func (s *Server) Handle(handler func(Request) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handler = handler
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// wireRequest is the part of a chat completion request the server reads
+type wireRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCallID string          `json:"tool_call_id"`
+		ToolCalls  []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		writeError(w, http.StatusNotFound, "agenttest.Server only serves POST /chat/completions")
+		return
+	}
+	var body json.RawMessage
+	var wire wireRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || json.Unmarshal(body, &wire) != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	request := parseRequest(wire, body, r.Header)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	var reply Reply
+	switch {
+	case len(s.replies) > 0:
+		reply = s.replies[0]
+		s.replies = s.replies[1:]
+	case s.handler != nil:
+		handler := s.handler
+		s.mu.Unlock()
+		reply = handler(request)
+		s.mu.Lock()
+	default:
+		s.mu.Unlock()
+		s.t.Errorf("agenttest.Server: unexpected request %d, no replies left", len(s.requests))
+		writeError(w, http.StatusInternalServerError, "no replies left")
+		return
+	}
+	// Number the tool calls in the order they are sent
+	calls := make([]agent.ToolCall, len(reply.ToolCalls))
+	for i, call := range reply.ToolCalls {
+		if call.ID == "" {
+			s.calls++
+			call.ID = fmt.Sprintf("call_%d", s.calls)
+		}
+		calls[i] = call
+	}
+	reply.ToolCalls = calls
+	s.mu.Unlock()
+
+	if reply.Delay > 0 {
+		select {
+		case <-time.After(reply.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		writeError(w, reply.Status, reply.ErrorMessage)
+		return
+	}
+	if reply.FinishReason == "" {
+		reply.FinishReason = "stop"
+		if len(reply.ToolCalls) > 0 {
+			reply.FinishReason = "tool_calls"
+		}
+	}
+	if reply.Usage == nil {
+		reply.Usage = estimateUsage(request, reply)
+	}
+	usage := *reply.Usage
+	usage.Model = request.Model
+	reply.Usage = &usage
+
+	if request.Stream {
+		writeStream(w, reply, wire.StreamOptions.IncludeUsage)
+		return
+	}
+	writeCompletion(w, reply)
+}
+
+// parseRequest converts a wire request to a Request
+func parseRequest(wire wireRequest, body json.RawMessage, header http.Header) Request {
+	request := Request{Model: wire.Model, Stream: wire.Stream, Header: header.Clone(), Body: body}
+	for _, tool := range wire.Tools {
+		request.Tools = append(request.Tools, tool.Function.Name)
+	}
+	for _, msg := range wire.Messages {
+		message := RequestMessage{Role: msg.Role, Content: contentText(msg.Content), ToolCallID: msg.ToolCallID}
+		for _, call := range msg.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, agent.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+		}
+		request.Messages = append(request.Messages, message)
+	}
+	return request
+}
+
+// contentText returns message content given as a string or as parts
+func contentText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(content, &parts)
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// estimateUsage estimates token counts at four bytes per token
+func estimateUsage(request Request, reply Reply) *agent.Usage {
+	prompt := 0
+	for _, msg := range request.Messages {
+		prompt += len(msg.Content)
+	}
+	completion := len(reply.Content)
+	for _, call := range reply.ToolCalls {
+		completion += len(call.Name) + len(call.Arguments)
+	}
+	usage := &agent.Usage{PromptTokens: int64(prompt+3)/4 + 1, CompletionTokens: int64(completion+3)/4 + 1}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
+// wireToolCall is a tool call in a response
+type wireToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// wireUsage is the usage of a response
+type wireUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+func toolCalls(calls []agent.ToolCall, indexed bool) []wireToolCall {
+	var wire []wireToolCall
+	for i, call := range calls {
+		c := wireToolCall{ID: call.ID, Type: "function"}
+		if indexed {
+			c.Index = &i
+		}
+		c.Function.Name = call.Name
+		c.Function.Arguments = call.Arguments
+		wire = append(wire, c)
+	}
+	return wire
+}
+
+func usage(u *agent.Usage) wireUsage {
+	return wireUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+}
+
+// writeCompletion writes a reply as a chat completion
+func writeCompletion(w http.ResponseWriter, reply Reply) {
+	type message struct {
+		Role      string         `json:"role"`
+		Content   string         `json:"content"`
+		ToolCalls []wireToolCall `json:"tool_calls,omitempty"`
+	}
+	type choice struct {
+		Index        int     `json:"index"`
+		FinishReason string  `json:"finish_reason"`
+		Message      message `json:"message"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ID      string    `json:"id"`
+		Object  string    `json:"object"`
+		Created int64     `json:"created"`
+		Model   string    `json:"model"`
+		Choices []choice  `json:"choices"`
+		Usage   wireUsage `json:"usage"`
+	}{
+		ID:      "chatcmpl-agenttest",
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   reply.Usage.Model,
+		Choices: []choice{{FinishReason: reply.FinishReason, Message: message{Role: "assistant", Content: reply.Content, ToolCalls: toolCalls(reply.ToolCalls, false)}}},
+		Usage:   usage(reply.Usage),
+	})
+}
+
+// writeStream writes a reply as server-sent chat completion chunks: the
+// content in a few deltas, then the tool calls, the finish reason, and the
+// usage if requested
+func writeStream(w http.ResponseWriter, reply Reply, includeUsage bool) {
+	type delta struct {
+		Role      string         `json:"role,omitempty"`
+		Content   string         `json:"content,omitempty"`
+		ToolCalls []wireToolCall `json:"tool_calls,omitempty"`
+	}
+	type choice struct {
+		Index        int     `json:"index"`
+		Delta        delta   `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	}
+	type chunk struct {
+		ID      string     `json:"id"`
+		Object  string     `json:"object"`
+		Created int64      `json:"created"`
+		Model   string     `json:"model"`
+		Choices []choice   `json:"choices"`
+		Usage   *wireUsage `json:"usage,omitempty"`
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	send := func(choices []choice, u *wireUsage) {
+		data, _ := json.Marshal(chunk{ID: "chatcmpl-agenttest", Object: "chat.completion.chunk", Created: time.Now().Unix(), Model: reply.Usage.Model, Choices: choices, Usage: u})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send([]choice{{Delta: delta{Role: "assistant"}}}, nil)
+	for _, part := range splitWords(reply.Content, 3) {
+		send([]choice{{Delta: delta{Content: part}}}, nil)
+	}
+	if len(reply.ToolCalls) > 0 {
+		send([]choice{{Delta: delta{ToolCalls: toolCalls(reply.ToolCalls, true)}}}, nil)
+	}
+	send([]choice{{FinishReason: &reply.FinishReason}}, nil)
+	if includeUsage {
+		u := usage(reply.Usage)
+		send([]choice{}, &u)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// splitWords splits text into about n parts at spaces, keeping the spaces
+func splitWords(text string, n int) []string {
+	if text == "" {
+		return nil
+	}
+	size := max(len(text)/n, 1)
+	var parts []string
+	for len(text) > size {
+		cut := strings.IndexByte(text[size:], ' ')
+		if cut < 0 {
+			break
+		}
+		parts = append(parts, text[:size+cut+1])
+		text = text[size+cut+1:]
+	}
+	return append(parts, text)
+}
+
+// writeError writes an error in the provider's format
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "type": http.StatusText(status)}})
+}
@@ -0,0 +1,95 @@
+package agenttest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerToolLoop(t *testing.T) {
+	server := NewServer(t,
+		CallTool("test_tool", `{"city":"Paris"}`),
+		Text("It is sunny in Paris."),
+	)
+	tool := testTool{parameters: weatherParameters, execute: func(ctx context.Context, input map[string]any) (any, error) {
+		return "sunny in " + input["city"].(string), nil
+	}}
+	a := agent.NewAgent("test-key", server.URL, "gpt-test", agent.WithTools([]agent.Tool{tool}))
+
+	completion, err := a.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Weather in Paris?")})
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Paris.", completion.Messages[len(completion.Messages)-1])
+	require.Len(t, completion.ToolCalls, 1)
+	assert.Equal(t, agent.ToolCall{ID: "call_1", Name: "test_tool", Arguments: `{"city":"Paris"}`}, completion.ToolCalls[0].Call)
+	assert.Positive(t, completion.Usage.TotalTokens)
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "gpt-test", requests[0].Model)
+	assert.Equal(t, []string{"test_tool"}, requests[0].Tools)
+	assert.Equal(t, "Bearer test-key", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "Weather in Paris?", requests[0].LastMessage().Content)
+	assert.Equal(t, RequestMessage{Role: "tool", Content: "sunny in Paris", ToolCallID: "call_1"}, requests[1].LastMessage())
+}
+
+func TestServerStreaming(t *testing.T) {
+	server := NewServer(t,
+		Text("The quick brown fox jumps over the lazy dog."),
+		Reply{ToolCalls: []agent.ToolCall{{Name: "a", Arguments: `{}`}, {Name: "b", Arguments: `{"x":1}`}}},
+	)
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	params := openai.ChatCompletionNewParams{
+		Model:         "gpt-test",
+		Messages:      []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")},
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+	}
+
+	stream := client.Chat.Completions.NewStreaming(context.Background(), params)
+	var acc openai.ChatCompletionAccumulator
+	chunks := 0
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+		chunks++
+	}
+	require.NoError(t, stream.Err())
+	assert.Greater(t, chunks, 3, "content is sent in several deltas")
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog.", acc.Choices[0].Message.Content)
+	assert.Equal(t, "stop", acc.Choices[0].FinishReason)
+	assert.Positive(t, acc.Usage.TotalTokens)
+
+	stream = client.Chat.Completions.NewStreaming(context.Background(), params)
+	acc = openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	require.NoError(t, stream.Err())
+	calls := acc.Choices[0].Message.ToolCalls
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_2", calls[1].ID)
+	assert.Equal(t, "b", calls[1].Function.Name)
+	assert.Equal(t, `{"x":1}`, calls[1].Function.Arguments)
+	assert.Equal(t, "tool_calls", acc.Choices[0].FinishReason)
+	assert.True(t, server.Requests()[1].Stream)
+}
+
+func TestServerHandleAndFail(t *testing.T) {
+	server := NewServer(t, Fail(http.StatusBadRequest, "context length exceeded"))
+	server.Handle(func(r Request) Reply {
+		return Text("echo: " + r.LastMessage().Content)
+	})
+	a := agent.NewAgent("test-key", server.URL, "gpt-test")
+
+	_, err := a.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("hello")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "context length exceeded")
+
+	completion, err := a.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo: hello"}, completion.Messages)
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PIIKind is a category of personally identifiable information
+type PIIKind string
+
+const (
+	PIIKindName  PIIKind = "NAME"
+	PIIKindEmail PIIKind = "EMAIL"
+	PIIKindPhone PIIKind = "PHONE"
+	PIIKindID    PIIKind = "ID"
+)
+
+// PIIMatch is a span of text detected as PII
+type PIIMatch struct {
+	Kind  PIIKind
+	Start int
+	End   int
+}
+
+// PIIDetector finds PII in text
+type PIIDetector interface {
+	Detect(text string) []PIIMatch
+}
+
+var piiPatterns = []struct {
+	kind    PIIKind
+	pattern *regexp.Regexp
+}{
+	{PIIKindEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{PIIKindID, regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)},
+	{PIIKindPhone, regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)},
+	{PIIKindID, regexp.MustCompile(`\b\d{6,}\b`)},
+}
+
+// PatternDetector detects emails, phone numbers, UUIDs, and long numeric IDs
+// with regular expressions, plus any explicitly listed names
+type PatternDetector struct {
+	names []string
+}
+
+// NewPatternDetector creates a PatternDetector that also matches the given names
+func NewPatternDetector(names ...string) *PatternDetector {
+	return &PatternDetector{names: names}
+}
+
+func (d *PatternDetector) Detect(text string) []PIIMatch {
+	var matches []PIIMatch
+	for _, p := range piiPatterns {
+		for _, loc := range p.pattern.FindAllStringIndex(text, -1) {
+			matches = append(matches, PIIMatch{Kind: p.kind, Start: loc[0], End: loc[1]})
+		}
+	}
+	for _, name := range d.names {
+		if name == "" {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(name) + `\b`)
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			matches = append(matches, PIIMatch{Kind: PIIKindName, Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+// Anonymizer replaces PII with placeholders such as [EMAIL_1]. The same value
+// always maps to the same placeholder, so references stay consistent across a session.
+// An Anonymizer is safe for concurrent use.
+type Anonymizer struct {
+	detector PIIDetector
+
+	mu           sync.Mutex
+	placeholders map[string]string
+	counts       map[PIIKind]int
+}
+
+// NewAnonymizer creates an Anonymizer using the given detector
+func NewAnonymizer(detector PIIDetector) *Anonymizer {
+	return &Anonymizer{
+		detector:     detector,
+		placeholders: map[string]string{},
+		counts:       map[PIIKind]int{},
+	}
+}
+
+// Anonymize replaces the PII in text with placeholders
+func (a *Anonymizer) Anonymize(text string) string {
+	matches := a.detector.Detect(text)
+	if len(matches) == 0 {
+		return text
+	}
+
+	// Prefer earlier and then longer matches, skipping any that overlap
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m.Start < last {
+			continue
+		}
+		b.WriteString(text[last:m.Start])
+		b.WriteString(a.placeholder(m.Kind, text[m.Start:m.End]))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// placeholder returns the placeholder for a value, assigning a new one on first use
+func (a *Anonymizer) placeholder(kind PIIKind, value string) string {
+	key := string(kind) + ":" + strings.ToLower(value)
+	if p, ok := a.placeholders[key]; ok {
+		return p
+	}
+	a.counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", kind, a.counts[kind])
+	a.placeholders[key] = p
+	return p
+}
+
+// AnonymizeMessages returns a copy of messages with PII replaced. Files and images
+// cannot be inspected, so they are replaced by text messages noting their removal.
+func (a *Anonymizer) AnonymizeMessages(messages []Message) []Message {
+	anonymized := make([]Message, len(messages))
+	for i, msg := range messages {
+		text := msg.Text()
+		switch msg.Kind() {
+		case MessageKindFile:
+			text = "[file removed]"
+		case MessageKindImage:
+			text = fmt.Sprintf("[%d image(s) removed]", len(msg.Images()))
+		}
+		anonymized[i] = Message{
+			role:       msg.Role(),
+			kind:       MessageKindText,
+			text:       a.Anonymize(text),
+			toolCallID: msg.ToolCallID(),
+		}
+		for _, call := range msg.ToolCalls() {
+			call.Arguments = a.Anonymize(call.Arguments)
+			anonymized[i].toolCalls = append(anonymized[i].toolCalls, call)
+		}
+	}
+	return anonymized
+}
+
+// ExportAnonymized returns the full transcript of a run — the input messages
+// followed by the assistant's replies and tool calls — with PII replaced by
+// consistent placeholders, suitable for sharing in bug reports and eval datasets
+func ExportAnonymized(anonymizer *Anonymizer, messages []Message, completion Completion) []Message {
+	return anonymizer.AnonymizeMessages(completion.Conversation(messages))
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizer(t *testing.T) {
+	anonymizer := NewAnonymizer(NewPatternDetector("Alice Smith"))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "Contact Alice Smith at alice@example.com or +1 (555) 123-4567.",
+			expected: "Contact [NAME_1] at [EMAIL_1] or [PHONE_1].",
+		},
+		{
+			input:    "Order 12345678 for alice smith, reply to bob@example.com",
+			expected: "Order [ID_1] for [NAME_1], reply to [EMAIL_2]",
+		},
+		{
+			input:    "Request 3f2b8c1e-9a4d-4e2f-8b1a-2c3d4e5f6a7b from ALICE@example.com",
+			expected: "Request [ID_2] from [EMAIL_1]",
+		},
+		{
+			input:    "Nothing to hide",
+			expected: "Nothing to hide",
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, anonymizer.Anonymize(tt.input))
+	}
+}
+
+func TestExportAnonymized(t *testing.T) {
+	anonymizer := NewAnonymizer(NewPatternDetector())
+
+	transcript := ExportAnonymized(anonymizer, []Message{
+		SystemMessage("You are helpful"),
+		UserTextMessage("My email is jane@example.com"),
+		UserImageMessage(Image{Data: []byte("png")}),
+	}, Completion{Messages: []string{"Thanks, I will email jane@example.com"}})
+
+	require.Len(t, transcript, 4)
+	assert.Equal(t, RoleSystem, transcript[0].Role())
+	assert.Equal(t, "My email is [EMAIL_1]", transcript[1].Text())
+	assert.Equal(t, "[1 image(s) removed]", transcript[2].Text())
+	assert.Equal(t, RoleAssistant, transcript[3].Role())
+	assert.Equal(t, "Thanks, I will email [EMAIL_1]", transcript[3].Text())
+}
+
+func TestAnonymizeToolMessages(t *testing.T) {
+	anonymizer := NewAnonymizer(NewPatternDetector())
+	messages := anonymizer.AnonymizeMessages([]Message{
+		AssistantToolCallMessage("", ToolCall{ID: "call_1", Name: "send_email", Arguments: `{"to":"alice@example.com"}`}),
+		ToolResultMessage("call_1", "sent to alice@example.com"),
+	})
+
+	require.Len(t, messages[0].ToolCalls(), 1)
+	assert.Equal(t, "call_1", messages[0].ToolCalls()[0].ID)
+	assert.NotContains(t, messages[0].ToolCalls()[0].Arguments, "alice@example.com")
+	assert.Equal(t, "call_1", messages[1].ToolCallID())
+	assert.NotContains(t, messages[1].Text(), "alice@example.com")
+}
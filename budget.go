@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBudgetExceeded is matched by every BudgetExceededError via errors.Is
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError is the terminal error of a run that spent its token or cost
+// budget before finishing
+type BudgetExceededError struct {
+	// Usage is the total usage of the run's model requests
+	Usage Usage
+	// Cost is the total cost in USD of the run's model requests with a known price
+	Cost           float64
+	MaxTotalTokens int64
+	MaxCostUSD     float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.MaxTotalTokens > 0 && e.Usage.TotalTokens >= e.MaxTotalTokens {
+		return fmt.Sprintf("budget exceeded: used %d of %d tokens", e.Usage.TotalTokens, e.MaxTotalTokens)
+	}
+	return fmt.Sprintf("budget exceeded: spent $%.4f of $%.4f", e.Cost, e.MaxCostUSD)
+}
+
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// WithRunBudget stops a run with a *BudgetExceededError instead of starting another
+// iteration once its model requests have used maxTotalTokens or cost maxCostUSD.
+// Requests summarizing tool results or critiquing answers count toward the
+// budget along with the run's own. A zero value disables that limit. Cost is computed from the prices set with WithPrices.
+func WithRunBudget(maxTotalTokens int64, maxCostUSD float64) AgentOption {
+	return func(a *Agent) {
+		a.maxTotalTokens = maxTotalTokens
+		a.maxCostUSD = maxCostUSD
+	}
+}
+
+// WithPrices sets the per-model prices used to enforce cost budgets. Usage of a
+// dated model snapshot such as gpt-4o-2024-08-06 is priced as gpt-4o when it has
+// no price of its own.
+func WithPrices(prices map[string]Price) AgentOption {
+	return func(a *Agent) {
+		a.prices = prices
+	}
+}
+
+// priceFor returns the price of a model, falling back to the longest priced model
+// name it starts with
+func priceFor(prices map[string]Price, model string) (Price, bool) {
+	if price, ok := prices[model]; ok {
+		return price, true
+	}
+	var best string
+	for name := range prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// spend tracks a run's usage against its budget
+type spend struct {
+	usage Usage
+	cost  float64
+}
+
+func (s *spend) add(prices map[string]Price, usage Usage) {
+	s.usage = s.usage.Add(usage)
+	if price, ok := priceFor(prices, usage.Model); ok {
+		s.cost += price.Cost(usage)
+	}
+}
+
+// check returns a *BudgetExceededError once the spend reaches the plan's budget
+func (s *spend) check(plan runPlan) error {
+	if (plan.maxTotalTokens > 0 && s.usage.TotalTokens >= plan.maxTotalTokens) ||
+		(plan.maxCostUSD > 0 && s.cost >= plan.maxCostUSD) {
+		return &BudgetExceededError{
+			Usage:          s.usage,
+			Cost:           s.cost,
+			MaxTotalTokens: plan.maxTotalTokens,
+			MaxCostUSD:     plan.maxCostUSD,
+		}
+	}
+	return nil
+}
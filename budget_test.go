@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBudget(t *testing.T) {
+	loop := MockTool{name: "loop", parameters: Parameters{Properties: map[string]any{}}}
+
+	tests := []struct {
+		name     string
+		opts     []AgentOption
+		requests int
+		message  string
+	}{
+		{
+			name:     "tokens",
+			opts:     []AgentOption{WithRunBudget(5, 0)},
+			requests: 3,
+			message:  "budget exceeded: used 6 of 5 tokens",
+		},
+		{
+			// Each request costs $3 at the price of gpt-4o, the longest prefix of the snapshot name
+			name:     "cost",
+			opts:     []AgentOption{WithRunBudget(0, 5), WithPrices(map[string]Price{"gpt": {Prompt: 100}, "gpt-4o": {Prompt: 1_000_000, Completion: 2_000_000}})},
+			requests: 2,
+			message:  "budget exceeded: spent $6.0000 of $5.0000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				writeToolCall(w, "gpt-4o-2024-08-06", "loop", `{}`)
+			}, append([]AgentOption{WithTools([]Tool{loop})}, tt.opts...)...)
+
+			completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Go")})
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrBudgetExceeded))
+			assert.EqualError(t, err, tt.message)
+			assert.Equal(t, tt.requests, requests)
+
+			var budgetErr *BudgetExceededError
+			require.ErrorAs(t, err, &budgetErr)
+			assert.Equal(t, completion.Usage.TotalTokens, budgetErr.Usage.TotalTokens)
+
+			// The error is the final event of the stream
+			last := completion.Responses[len(completion.Responses)-1]
+			assert.True(t, last.IsErrorResponse())
+		})
+	}
+}
+
+func TestRunBudgetAuxiliaryUsage(t *testing.T) {
+	search := MockTool{name: "search", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		return strings.Repeat("lots of output ", 100), nil
+	}}
+
+	tests := []struct {
+		name string
+		opts []AgentOption
+	}{
+		{name: "summarizer", opts: []AgentOption{WithToolResultSummarizer(SummarizePolicy{Model: "cheap-model", Threshold: 50})}},
+		{name: "truncation", opts: []AgentOption{WithToolResultLimit(TruncatePolicy{MaxBytes: 200, Strategy: TruncateSummarize, Model: "cheap-model"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Model string `json:"model"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				requests = append(requests, body.Model)
+				if body.Model == "cheap-model" {
+					writeCompletion(w, "cheap-model", "short summary")
+					return
+				}
+				writeToolCall(w, "gpt-4o", "search", `{}`)
+			}, append([]AgentOption{WithTools([]Tool{search}), WithRunBudget(4, 0)}, tt.opts...)...)
+
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Search")})
+
+			// The summary's tokens spend the budget before a second request
+			assert.EqualError(t, err, "budget exceeded: used 4 of 4 tokens")
+			assert.Equal(t, []string{"gpt-4o", "cheap-model"}, requests)
+		})
+	}
+}
+
+func TestRunBudgetEntitlement(t *testing.T) {
+	testAgent := NewAgent("key", "http://localhost", "gpt-4o",
+		WithRunBudget(10_000, 1),
+		WithAccessPolicy(AccessPolicy{"free": {MaxTotalTokens: 2_000}, "pro": {MaxCostUSD: 5}}),
+	)
+
+	plan, err := testAgent.plan(runOptions{tenant: "free"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2_000), plan.maxTotalTokens)
+	assert.Equal(t, 1.0, plan.maxCostUSD)
+
+	plan, err = testAgent.plan(runOptions{tenant: "pro"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10_000), plan.maxTotalTokens)
+	assert.Equal(t, 1.0, plan.maxCostUSD, "an entitlement cannot raise the agent's budget")
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/param"
+)
+
+// cacheControlField marks a prompt cache breakpoint. Providers that support explicit
+// breakpoints (Anthropic) cache the request prefix up to and including the marked
+// block; others ignore it and cache automatically (OpenAI).
+var cacheControlField = map[string]any{
+	"cache_control": map[string]any{"type": "ephemeral"},
+}
+
+// WithPromptCaching places cache breakpoints after the system prompt, the
+// instructions, and the tool definitions, so the static prefix of each request
+// is served from the provider's prompt cache on every iteration of the tool loop
+func WithPromptCaching() AgentOption {
+	return func(a *Agent) {
+		a.promptCaching = true
+	}
+}
+
+// WithCacheControl returns a copy of the message marked as a prompt cache breakpoint
+func (m Message) WithCacheControl() Message {
+	m.cacheControl = true
+	return m
+}
+
+// CacheControl reports whether the message is a prompt cache breakpoint
+func (m Message) CacheControl() bool {
+	return m.cacheControl
+}
+
+// applyCacheControl marks the last content block of a message as a cache breakpoint,
+// converting string content to a single text block as needed
+func applyCacheControl(msg openai.ChatCompletionMessageParamUnion) openai.ChatCompletionMessageParamUnion {
+	switch {
+	case msg.OfSystem != nil:
+		system := *msg.OfSystem
+		system.Content.OfArrayOfContentParts = markTextParts(system.Content.OfString, system.Content.OfArrayOfContentParts)
+		system.Content.OfString = param.Opt[string]{}
+		msg.OfSystem = &system
+	case msg.OfDeveloper != nil:
+		developer := *msg.OfDeveloper
+		developer.Content.OfArrayOfContentParts = markTextParts(developer.Content.OfString, developer.Content.OfArrayOfContentParts)
+		developer.Content.OfString = param.Opt[string]{}
+		msg.OfDeveloper = &developer
+	case msg.OfUser != nil:
+		user := *msg.OfUser
+		parts := append([]openai.ChatCompletionContentPartUnionParam{}, user.Content.OfArrayOfContentParts...)
+		if user.Content.OfString.Valid() {
+			parts = []openai.ChatCompletionContentPartUnionParam{{
+				OfText: &openai.ChatCompletionContentPartTextParam{Text: user.Content.OfString.Value},
+			}}
+		}
+		if len(parts) > 0 {
+			last := parts[len(parts)-1]
+			switch {
+			case last.OfText != nil:
+				text := *last.OfText
+				text.SetExtraFields(cacheControlField)
+				last.OfText = &text
+			case last.OfImageURL != nil:
+				image := *last.OfImageURL
+				image.SetExtraFields(cacheControlField)
+				last.OfImageURL = &image
+			case last.OfFile != nil:
+				file := *last.OfFile
+				file.SetExtraFields(cacheControlField)
+				last.OfFile = &file
+			}
+			parts[len(parts)-1] = last
+		}
+		user.Content.OfString = param.Opt[string]{}
+		user.Content.OfArrayOfContentParts = parts
+		msg.OfUser = &user
+	case msg.OfAssistant != nil && msg.OfAssistant.Content.OfString.Valid():
+		assistant := *msg.OfAssistant
+		text := openai.ChatCompletionContentPartTextParam{Text: assistant.Content.OfString.Value}
+		text.SetExtraFields(cacheControlField)
+		assistant.Content.OfString = param.Opt[string]{}
+		assistant.Content.OfArrayOfContentParts = []openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{{OfText: &text}}
+		msg.OfAssistant = &assistant
+	}
+	return msg
+}
+
+// markTextParts returns text content as parts with the last part marked as a cache breakpoint
+func markTextParts(
+	content param.Opt[string],
+	parts []openai.ChatCompletionContentPartTextParam,
+) []openai.ChatCompletionContentPartTextParam {
+	parts = append([]openai.ChatCompletionContentPartTextParam{}, parts...)
+	if content.Valid() {
+		parts = []openai.ChatCompletionContentPartTextParam{{Text: content.Value}}
+	}
+	if len(parts) > 0 {
+		parts[len(parts)-1].SetExtraFields(cacheControlField)
+	}
+	return parts
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptCachingBreakpoints(t *testing.T) {
+	var body struct {
+		Messages []map[string]any `json:"messages"`
+		Tools    []map[string]any `json:"tools"`
+	}
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeCompletion(w, "test-model", "hello")
+	},
+		WithSystemPrompt("system"),
+		WithTools([]Tool{MockTool{name: "a_tool"}, MockTool{name: "b_tool"}}),
+		WithPromptCaching(),
+	)
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{
+		UserTextMessage("document").WithCacheControl(),
+		UserTextMessage("question"),
+	})
+	require.NoError(t, err)
+
+	ephemeral := map[string]any{"type": "ephemeral"}
+
+	require.Len(t, body.Messages, 3)
+	system := body.Messages[0]["content"].([]any)
+	assert.Equal(t, ephemeral, system[len(system)-1].(map[string]any)["cache_control"])
+
+	document := body.Messages[1]["content"].([]any)
+	assert.Equal(t, "document", document[0].(map[string]any)["text"])
+	assert.Equal(t, ephemeral, document[0].(map[string]any)["cache_control"])
+
+	assert.Equal(t, "question", body.Messages[2]["content"])
+
+	require.Len(t, body.Tools, 2)
+	assert.NotContains(t, body.Tools[0], "cache_control")
+	assert.Equal(t, ephemeral, body.Tools[1]["cache_control"])
+}
+
+func TestPromptCachingDisabled(t *testing.T) {
+	var raw map[string]json.RawMessage
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&raw))
+		writeCompletion(w, "test-model", "hello")
+	}, WithSystemPrompt("system"), WithTools([]Tool{MockTool{name: "a_tool"}}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(raw["messages"]), "cache_control")
+	assert.NotContains(t, string(raw["tools"]), "cache_control")
+}
+
+func TestMessageWithCacheControl(t *testing.T) {
+	msg := UserTextMessage("hello")
+	marked := msg.WithCacheControl()
+
+	assert.False(t, msg.CacheControl())
+	assert.True(t, marked.CacheControl())
+	assert.Equal(t, msg.Text(), marked.Text())
+
+	converted := convertMessages([]Message{AssistantTextMessage("reply").WithCacheControl()})
+	require.Len(t, converted, 1)
+	require.NotNil(t, converted[0].OfAssistant)
+	parts := converted[0].OfAssistant.Content.OfArrayOfContentParts
+	require.Len(t, parts, 1)
+	assert.Equal(t, "reply", parts[0].OfText.Text)
+}
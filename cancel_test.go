@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTool ignores its context and blocks until released
+type blockingTool struct {
+	MockTool
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b blockingTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	close(b.started)
+	<-b.release
+	return "late", nil
+}
+
+func TestCancelInterruptsToolExecution(t *testing.T) {
+	tool := blockingTool{MockTool: MockTool{name: "slow_tool"}, started: make(chan struct{}), release: make(chan struct{})}
+	t.Cleanup(func() { close(tool.release) })
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeToolCall(w, "test-model", "slow_tool", "{}")
+	}, WithTools([]Tool{tool}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-tool.started
+		cancel()
+	}()
+
+	done := make(chan struct{})
+	var completion Completion
+	var err error
+	go func() {
+		defer close(done)
+		completion, err = testAgent.ChatCompletion(ctx, []Message{UserTextMessage("Hello")})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not interrupted")
+	}
+
+	var canceledErr *CanceledError
+	require.ErrorAs(t, err, &canceledErr)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The usage of the first request was produced before the cancellation
+	require.Len(t, completion.Steps, 1)
+	require.NotEmpty(t, completion.Responses)
+	assert.True(t, completion.Responses[len(completion.Responses)-1].IsErrorResponse())
+}
+
+func TestCancelDuringRequest(t *testing.T) {
+	release := make(chan struct{})
+	requested := make(chan struct{})
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		close(requested)
+		<-release
+	})
+	// Registered after the server so it runs first and unblocks the handler before Close
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	responseChan, err := testAgent.StreamChatCompletion(ctx, []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+
+	<-requested
+	cancel()
+
+	var responses []Response
+	for response := range responseChan {
+		responses = append(responses, response)
+	}
+
+	require.Len(t, responses, 1)
+	require.True(t, responses[0].IsErrorResponse())
+	assert.ErrorIs(t, responses[0].Error(), context.Canceled)
+}
@@ -0,0 +1,31 @@
+package agent
+
+import "context"
+
+// ChatAgent runs conversations. *Agent is the standard implementation; code that
+// only runs conversations, such as handoffs, supervisors, and workflows, accepts
+// a ChatAgent so agents can be wrapped with decorators or replaced with fakes.
+type ChatAgent interface {
+	// ChatCompletion runs the conversation to completion and returns the collected result
+	ChatCompletion(ctx context.Context, messages []Message, opts ...RunOption) (Completion, error)
+	// StreamChatCompletion runs the conversation, sending responses on the
+	// returned channel, which is closed when the run ends
+	StreamChatCompletion(ctx context.Context, messages []Message, opts ...RunOption) (<-chan Response, error)
+}
+
+var _ ChatAgent = (*Agent)(nil)
+
+// Collect reads responses until the channel closes and returns them as a
+// Completion. It stops at the first error response, returning it with what was
+// collected so far. Decorators that wrap StreamChatCompletion can use it to
+// implement ChatCompletion.
+func Collect(responses <-chan Response) (Completion, error) {
+	var completion Completion
+	for response := range responses {
+		completion.add(response)
+		if response.IsErrorResponse() {
+			return completion, response.Error()
+		}
+	}
+	return completion, nil
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingAgent is a decorator that counts the runs of the agent it wraps
+type countingAgent struct {
+	ChatAgent
+	runs int
+}
+
+func (c *countingAgent) StreamChatCompletion(ctx context.Context, messages []Message, opts ...RunOption) (<-chan Response, error) {
+	c.runs++
+	return c.ChatAgent.StreamChatCompletion(ctx, messages, opts...)
+}
+
+func (c *countingAgent) ChatCompletion(ctx context.Context, messages []Message, opts ...RunOption) (Completion, error) {
+	responses, err := c.StreamChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return Completion{}, err
+	}
+	return Collect(responses)
+}
+
+// fakeAgent replies with fixed responses without calling a provider
+type fakeAgent struct {
+	responses []Response
+}
+
+func (f fakeAgent) StreamChatCompletion(ctx context.Context, messages []Message, opts ...RunOption) (<-chan Response, error) {
+	responseChan := make(chan Response, len(f.responses))
+	for _, response := range f.responses {
+		responseChan <- response
+	}
+	close(responseChan)
+	return responseChan, nil
+}
+
+func (f fakeAgent) ChatCompletion(ctx context.Context, messages []Message, opts ...RunOption) (Completion, error) {
+	responses, _ := f.StreamChatCompletion(ctx, messages, opts...)
+	return Collect(responses)
+}
+
+func TestChatAgentDecorator(t *testing.T) {
+	var decorated ChatAgent = &countingAgent{ChatAgent: newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "Hello")
+	})}
+
+	completion, err := decorated.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello"}, completion.Messages)
+	assert.Equal(t, int64(2), completion.Usage.TotalTokens)
+	assert.Equal(t, 1, decorated.(*countingAgent).runs)
+}
+
+func TestCollect(t *testing.T) {
+	failure := errors.New("provider down")
+	completion, err := fakeAgent{responses: []Response{
+		NewContentResponse("partial"),
+		NewErrorResponse(failure),
+		NewContentResponse("ignored"),
+	}}.ChatCompletion(context.Background(), nil)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"partial"}, completion.Messages)
+}
+
+func TestHandoffToChatAgent(t *testing.T) {
+	triage := newTestAgent(t, "triage", func(w http.ResponseWriter, r *http.Request) {
+		writeToolCall(w, "triage", "transfer_to_billing", `{"reason":"invoice question"}`)
+	}, WithHandoffs(Handoff{
+		Name:   "billing",
+		Agent:  fakeAgent{responses: []Response{NewContentResponse("Your invoice is paid")}},
+		Policy: HandoffTransfer,
+	}))
+
+	completion, err := triage.ChatCompletion(context.Background(), []Message{UserTextMessage("Is my invoice paid?")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Your invoice is paid"}, completion.Messages)
+}
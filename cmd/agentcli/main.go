@@ -0,0 +1,122 @@
+// Command agentcli is an interactive terminal chat with an agent, for trying
+// prompts, models, and tools during development. Replies are printed as the
+// run produces them, with each tool call and its result.
+//
+// The agent is defined by flags or by an agent of an agentconfig file:
+//
+//	agentcli -model gpt-4o -tools read_file,list_files,run_tests
+//	agentcli -config agents.yaml -agent support
+//
+// The built-in tools work on the directory given by -root: read_file,
+// list_files, write_file, delete_file, edit_file, run_tests, and run_command,
+// which asks before running each command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agentconfig"
+	"github.com/campbel/go-agents/tools/codeedit"
+	"github.com/campbel/go-agents/tools/filesystem"
+	"github.com/campbel/go-agents/tools/gotest"
+	"github.com/campbel/go-agents/tools/shell"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "agentcli: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		configPath = flag.String("config", "", "agentconfig file defining the agent")
+		agentName  = flag.String("agent", "", "agent of the config file (default: its only agent)")
+		model      = flag.String("model", "", "model, overriding the config file")
+		baseURL    = flag.String("base-url", "", "base URL of the provider, overriding the config file (default: $OPENAI_BASE_URL or "+agentconfig.DefaultBaseURL+")")
+		apiKey     = flag.String("api-key", "", "API key, overriding the config file (default: $OPENAI_API_KEY)")
+		system     = flag.String("system", "", "system prompt, overriding the config file")
+		toolNames  = flag.String("tools", "", "comma-separated built-in tools to offer, overriding the config file")
+		root       = flag.String("root", ".", "directory the built-in tools work on")
+		load       = flag.String("load", "", "conversation file to continue")
+	)
+	flag.Parse()
+
+	definition := agentconfig.AgentConfig{BaseURL: os.Getenv("OPENAI_BASE_URL"), APIKey: os.Getenv("OPENAI_API_KEY")}
+	if *configPath != "" {
+		config, err := agentconfig.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		name := *agentName
+		if name == "" {
+			names := config.Names()
+			if len(names) != 1 {
+				return fmt.Errorf("%s defines agents %s, choose one with -agent", *configPath, strings.Join(names, ", "))
+			}
+			name = names[0]
+		}
+		var ok bool
+		if definition, ok = config.Agents[name]; !ok {
+			return fmt.Errorf("%s defines no agent %s", *configPath, name)
+		}
+	}
+	if *model != "" {
+		definition.Model = *model
+	}
+	if *baseURL != "" {
+		definition.BaseURL = *baseURL
+	}
+	if *apiKey != "" {
+		definition.APIKey = *apiKey
+	}
+	if *system != "" {
+		definition.SystemPrompt = *system
+	}
+	if *toolNames != "" {
+		definition.Tools = strings.Split(*toolNames, ",")
+	}
+	if definition.Model == "" {
+		return errors.New("no model, set one with -model or -config")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	var r *repl
+	registry := builtinTools(*root, func(ctx context.Context, command shell.Command) (bool, error) {
+		return r.confirm(fmt.Sprintf("Run %q?", command.String()))
+	})
+	r, err := newREPL(os.Stdin, os.Stdout, definition.Model, func(model string) (*agent.Agent, error) {
+		definition := definition
+		definition.Model = model
+		return definition.Build(registry)
+	})
+	if err != nil {
+		return err
+	}
+	if *load != "" {
+		if _, err := r.command("/load " + *load); err != nil {
+			return err
+		}
+	}
+	return r.run(ctx)
+}
+
+// builtinTools returns a registry of the tools agentcli offers, working on root
+func builtinTools(root string, approver shell.Approver) *agent.ToolRegistry {
+	registry := agent.NewToolRegistry(filesystem.New(filesystem.WithRoot(root)).Tools()...)
+	registry.Register(codeedit.New(codeedit.WithRoot(root)))
+	registry.Register(gotest.New(gotest.WithDir(root)))
+	registry.Register(shell.New(shell.WithRoot(root), shell.WithApprover(approver)))
+	return registry
+}
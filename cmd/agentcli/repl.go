@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// maxShown caps the characters of tool arguments and results shown in the terminal
+const maxShown = 200
+
+// repl is an interactive chat with an agent
+type repl struct {
+	in  *bufio.Reader
+	out io.Writer
+	// inMu serializes reads of in, which tool approvals share with the prompt
+	inMu sync.Mutex
+
+	// build creates the agent for a model
+	build func(model string) (*agent.Agent, error)
+	agent *agent.Agent
+	model string
+
+	history []agent.Message
+	usage   agent.Usage
+	// interrupt returns a context canceled by Ctrl-C, which stops the current
+	// turn instead of the program
+	interrupt func(ctx context.Context) (context.Context, context.CancelFunc)
+}
+
+func newREPL(in io.Reader, out io.Writer, model string, build func(model string) (*agent.Agent, error)) (*repl, error) {
+	r := &repl{
+		in:    bufio.NewReader(in),
+		out:   out,
+		build: build,
+		interrupt: func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return signal.NotifyContext(ctx, os.Interrupt)
+		},
+	}
+	if err := r.switchModel(model); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// readLine prompts for a line of input. It returns io.EOF when input ends.
+func (r *repl) readLine(prompt string) (string, error) {
+	r.inMu.Lock()
+	defer r.inMu.Unlock()
+	fmt.Fprint(r.out, prompt)
+	line, err := r.in.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// confirm asks a yes or no question
+func (r *repl) confirm(question string) (bool, error) {
+	answer, err := r.readLine(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"), nil
+}
+
+// run reads messages and commands until input ends or /quit
+func (r *repl) run(ctx context.Context) error {
+	fmt.Fprintf(r.out, "Chatting with %s. Type /help for commands.\n", r.model)
+	for {
+		line, err := r.readLine("> ")
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(r.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "/"):
+			quit, err := r.command(line)
+			if err != nil {
+				fmt.Fprintf(r.out, "error: %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+		default:
+			if err := r.turn(ctx, line); err != nil {
+				fmt.Fprintf(r.out, "error: %v\n", err)
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+const help = `Commands:
+  /tools         list the agent's tools
+  /model [name]  show or switch the model; the conversation is kept
+  /save <file>   save the conversation as JSON
+  /load <file>   load a saved conversation
+  /clear         start a new conversation
+  /usage         show the tokens used so far
+  /quit          exit
+Ctrl-C stops the current reply.`
+
+// command runs a slash command, reporting whether to quit
+func (r *repl) command(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "/help":
+		fmt.Fprintln(r.out, help)
+	case "/quit", "/exit":
+		return true, nil
+	case "/tools":
+		tools := r.agent.Tools()
+		if len(tools) == 0 {
+			fmt.Fprintln(r.out, "No tools.")
+		}
+		for _, tool := range tools {
+			fmt.Fprintf(r.out, "  %s: %s\n", tool.Name(), tool.Description())
+		}
+	case "/model":
+		if arg == "" {
+			fmt.Fprintln(r.out, r.model)
+			return false, nil
+		}
+		if err := r.switchModel(arg); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(r.out, "Switched to %s.\n", r.model)
+	case "/save":
+		if arg == "" {
+			return false, errors.New("usage: /save <file>")
+		}
+		if err := saveConversation(arg, r.history); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(r.out, "Saved %d messages to %s.\n", len(r.history), arg)
+	case "/load":
+		if arg == "" {
+			return false, errors.New("usage: /load <file>")
+		}
+		history, err := loadConversation(arg)
+		if err != nil {
+			return false, err
+		}
+		r.history = history
+		fmt.Fprintf(r.out, "Loaded %d messages from %s.\n", len(r.history), arg)
+	case "/clear":
+		r.history = nil
+		r.usage = agent.Usage{}
+		fmt.Fprintln(r.out, "Started a new conversation.")
+	case "/usage":
+		fmt.Fprintf(r.out, "%d prompt tokens (%d cached), %d completion tokens\n",
+			r.usage.PromptTokens, r.usage.CachedPromptTokens, r.usage.CompletionTokens)
+	default:
+		return false, fmt.Errorf("unknown command %s, type /help for commands", name)
+	}
+	return false, nil
+}
+
+// switchModel rebuilds the agent for model
+func (r *repl) switchModel(model string) error {
+	a, err := r.build(model)
+	if err != nil {
+		return err
+	}
+	r.agent, r.model = a, model
+	return nil
+}
+
+// turn sends a message and prints the reply as it streams in, with the tools
+// called along the way. The conversation keeps the turn only if it completes.
+func (r *repl) turn(ctx context.Context, text string) error {
+	ctx, cancel := r.interrupt(ctx)
+	defer cancel()
+
+	messages := append(append([]agent.Message{}, r.history...), agent.UserTextMessage(text))
+	responses, err := r.agent.StreamChatCompletion(ctx, messages)
+	if err != nil {
+		return err
+	}
+
+	var received []agent.Response
+	for response := range responses {
+		r.show(response)
+		received = append(received, response)
+	}
+	collected := make(chan agent.Response, len(received))
+	for _, response := range received {
+		collected <- response
+	}
+	close(collected)
+	completion, err := agent.Collect(collected)
+	r.usage = r.usage.Add(completion.Usage)
+	if ctx.Err() != nil {
+		return errors.New("interrupted")
+	}
+	if err != nil {
+		return err
+	}
+	r.history = completion.Conversation(messages)
+	return nil
+}
+
+// show prints a response of a run
+func (r *repl) show(response agent.Response) {
+	switch {
+	case response.IsContentResponse():
+		fmt.Fprintln(r.out, response.Content())
+	case response.IsToolCallResponse():
+		record := response.ToolCallRecord()
+		fmt.Fprintf(r.out, "  → %s(%s)\n", record.Call.Name, shorten(record.Call.Arguments))
+		fmt.Fprintf(r.out, "  ← %s\n", shorten(record.Result))
+	case response.IsProgressResponse():
+		progress := response.Progress()
+		fmt.Fprintf(r.out, "  … %s: %s\n", progress.Tool, progress.Message)
+	case response.IsWarningResponse():
+		fmt.Fprintf(r.out, "  ! %s\n", response.Warning())
+	}
+}
+
+// shorten collapses text to one line of at most maxShown characters
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxShown {
+		return string(runes[:maxShown]) + "…"
+	}
+	return text
+}
+
+// savedMessage is a message of a saved conversation. The REPL only produces
+// text, tool call, and tool result messages.
+type savedMessage struct {
+	Role       agent.Role       `json:"role"`
+	Content    string           `json:"content,omitempty"`
+	ToolCalls  []agent.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+func saveConversation(path string, history []agent.Message) error {
+	saved := make([]savedMessage, len(history))
+	for i, msg := range history {
+		saved[i] = savedMessage{Role: msg.Role(), Content: msg.Text(), ToolCalls: msg.ToolCalls(), ToolCallID: msg.ToolCallID()}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func loadConversation(path string) ([]agent.Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var saved []savedMessage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	history := make([]agent.Message, 0, len(saved))
+	for i, msg := range saved {
+		switch msg.Role {
+		case agent.RoleUser:
+			history = append(history, agent.UserTextMessage(msg.Content))
+		case agent.RoleAssistant:
+			history = append(history, agent.AssistantToolCallMessage(msg.Content, msg.ToolCalls...))
+		case agent.RoleTool:
+			history = append(history, agent.ToolResultMessage(msg.ToolCallID, msg.Content))
+		case agent.RoleSystem:
+			history = append(history, agent.SystemMessage(msg.Content))
+		case agent.RoleDeveloper:
+			history = append(history, agent.DeveloperMessage(msg.Content))
+		default:
+			return nil, fmt.Errorf("parse %s: message %d has unknown role %q", path, i, msg.Role)
+		}
+	}
+	return history, nil
+}
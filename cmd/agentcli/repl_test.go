@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agentconfig"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestREPL creates a REPL reading input, with the built-in tools working on a temporary directory
+func newTestREPL(t *testing.T, server *agenttest.Server, input string, out *strings.Builder) *repl {
+	t.Helper()
+	registry := builtinTools(t.TempDir(), nil)
+	r, err := newREPL(strings.NewReader(input), out, "gpt-test", func(model string) (*agent.Agent, error) {
+		definition := agentconfig.AgentConfig{Model: model, BaseURL: server.URL, APIKey: "test-key", Tools: []string{"list_files"}}
+		return definition.Build(registry)
+	})
+	require.NoError(t, err)
+	r.interrupt = func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return context.WithCancel(ctx)
+	}
+	return r
+}
+
+func TestREPL(t *testing.T) {
+	server := agenttest.NewServer(t,
+		agenttest.CallTool("list_files", `{"path":"."}`),
+		agenttest.Text("The directory is empty."),
+		agenttest.Text("Still empty."),
+	)
+	saved := filepath.Join(t.TempDir(), "conversation.json")
+	input := strings.Join([]string{
+		"What files are there?",
+		"/tools",
+		"/model gpt-other",
+		"And now?",
+		"/save " + saved,
+		"/clear",
+		"/load " + saved,
+		"/bogus",
+		"/quit",
+	}, "\n")
+
+	var out strings.Builder
+	r := newTestREPL(t, server, input, &out)
+	require.NoError(t, r.run(context.Background()))
+
+	output := out.String()
+	assert.Contains(t, output, `  → list_files({"path":"."})`)
+	assert.Contains(t, output, "The directory is empty.")
+	assert.Contains(t, output, "  list_files: ")
+	assert.Contains(t, output, "Switched to gpt-other.")
+	assert.Contains(t, output, "Saved 6 messages to "+saved)
+	assert.Contains(t, output, "Loaded 6 messages from "+saved)
+	assert.Contains(t, output, "error: unknown command /bogus")
+
+	requests := server.Requests()
+	require.Len(t, requests, 3)
+	assert.Equal(t, "gpt-other", requests[2].Model)
+	assert.Len(t, requests[2].Messages, 5, "the conversation is kept across the model switch")
+
+	require.Len(t, r.history, 6)
+	assert.Equal(t, agent.RoleTool, r.history[2].Role())
+	assert.Equal(t, []agent.ToolCall{{ID: "call_1", Name: "list_files", Arguments: `{"path":"."}`}}, r.history[1].ToolCalls())
+	assert.Equal(t, "Still empty.", r.history[5].Text())
+}
+
+func TestREPLFailedTurn(t *testing.T) {
+	server := agenttest.NewServer(t, agenttest.Fail(400, "model not found"))
+	var out strings.Builder
+	r := newTestREPL(t, server, "hello\n", &out)
+	require.NoError(t, r.run(context.Background()))
+
+	assert.Contains(t, out.String(), "error: ")
+	assert.Contains(t, out.String(), "model not found")
+	assert.Empty(t, r.history, "a failed turn is not kept")
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests share one agent between goroutines; run them with -race to check
+// that runs keep their state to themselves.
+
+// newEchoToolAgent creates an agent whose model calls echo with the last user
+// message, then answers with the tool's result
+func newEchoToolAgent(t *testing.T, opts ...AgentOption) *Agent {
+	t.Helper()
+	echo := MockTool{name: "echo", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		return "echo: " + input["text"].(string), nil
+	}}
+	opts = append([]AgentOption{WithTools([]Tool{echo})}, opts...)
+	return newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		last := body.Messages[len(body.Messages)-1]
+		if last.Role == "tool" {
+			writeCompletion(w, "gpt-4o", last.Content)
+			return
+		}
+		arguments, _ := json.Marshal(map[string]string{"text": last.Content})
+		writeToolCall(w, "gpt-4o", "echo", string(arguments))
+	}, opts...)
+}
+
+func TestAgentConcurrentRuns(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+	go func() {
+		for range sub.Events() {
+		}
+	}()
+	registry := NewToolRegistry()
+	testAgent := newEchoToolAgent(t,
+		WithEventBus(bus),
+		WithToolRegistry(registry),
+		WithRunBudget(1000, 0),
+		WithStopCondition(StopAfterTool("never")),
+	)
+
+	var wg sync.WaitGroup
+	// Change the registry while runs resolve it
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			name := fmt.Sprintf("extra_%d", i%3)
+			registry.Register(MockTool{name: name})
+			registry.Unregister(name)
+		}
+	}()
+
+	const runs = 20
+	completions := make([]Completion, runs)
+	errs := make([]error, runs)
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			completions[i], errs[i] = testAgent.ChatCompletion(context.Background(),
+				[]Message{UserTextMessage(fmt.Sprintf("message %d", i))}, WithRunID(fmt.Sprintf("run-%d", i)))
+		}()
+	}
+	wg.Wait()
+
+	for i := range runs {
+		require.NoError(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("run-%d", i), completions[i].RunID)
+		assert.Equal(t, []string{fmt.Sprintf("echo: message %d", i)}, completions[i].Messages)
+		assert.Len(t, completions[i].Steps, 2)
+		assert.Len(t, completions[i].ToolCalls, 1)
+	}
+}
+
+func TestAgentClone(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	base := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		seen[body.Messages[len(body.Messages)-1].Content] = body.Model + " " + r.Header.Get("X-Clone")
+		mu.Unlock()
+		writeCompletion(w, body.Model, "ok")
+	}, WithHeader("X-A", "1"), WithHeader("X-B", "1"), WithHeader("X-C", "1"))
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Clones made at the same time from spare slice capacity must not share it
+			clone := base.Clone(WithModel(fmt.Sprintf("model-%d", i)), WithHeader("X-Clone", fmt.Sprint(i)))
+			_, err := clone.ChatCompletion(context.Background(), []Message{UserTextMessage(fmt.Sprintf("clone %d", i))})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := base.ChatCompletion(context.Background(), []Message{UserTextMessage("base")})
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	for i := range 10 {
+		assert.Equal(t, fmt.Sprintf("model-%d %d", i, i), seen[fmt.Sprintf("clone %d", i)])
+	}
+	assert.Equal(t, "gpt-4o ", seen["base"])
+}
+
+func TestAgentCloneLeavesOriginal(t *testing.T) {
+	base := NewAgent("key", "http://localhost", "gpt-4o", WithSystemPrompt("You are terse."), WithMaxIterations(5))
+
+	clone := base.Clone(WithSystemPrompt("You are chatty."), WithTools([]Tool{MockTool{name: "lookup"}}))
+
+	assert.Equal(t, "You are terse.", base.systemPrompt)
+	assert.Empty(t, base.Tools())
+	assert.Equal(t, "You are chatty.", clone.systemPrompt)
+	assert.Equal(t, 5, clone.maxIterations)
+	assert.Equal(t, []string{"lookup"}, toolNames(clone.Tools()))
+}
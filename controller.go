@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrNotPaused is returned when inspecting or editing a run that is not paused
+var ErrNotPaused = errors.New("run is not paused")
+
+// RunController pauses a run between iterations so a supervisor can inspect the
+// pending request, optionally edit it, and resume. It is safe for concurrent use.
+type RunController struct {
+	mu       sync.Mutex
+	pausing  bool
+	paused   bool
+	pausedCh chan struct{}
+	resumeCh chan struct{}
+	pending  []openai.ChatCompletionMessageParamUnion
+}
+
+// NewRunController creates a RunController for a run
+func NewRunController() *RunController {
+	return &RunController{}
+}
+
+// WithRunController attaches a controller to a single run
+func WithRunController(controller *RunController) RunOption {
+	return func(o *runOptions) {
+		o.controller = controller
+	}
+}
+
+// Pause stops the run before its next request to the model
+func (c *RunController) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.pausing {
+		c.pausing = true
+		c.pausedCh = make(chan struct{})
+		c.resumeCh = make(chan struct{})
+	}
+}
+
+// Resume continues a paused run with the, possibly edited, pending messages
+func (c *RunController) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pausing {
+		c.pausing = false
+		close(c.resumeCh)
+	}
+}
+
+// IsPaused reports whether the run is stopped at an iteration boundary
+func (c *RunController) IsPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// WaitPaused blocks until the run stops after Pause, or ctx is done
+func (c *RunController) WaitPaused(ctx context.Context) error {
+	c.mu.Lock()
+	pausedCh := c.pausedCh
+	pausing := c.pausing
+	c.mu.Unlock()
+	if !pausing {
+		return ErrNotPaused
+	}
+	select {
+	case <-pausedCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pending returns the messages the run will send in its next request
+func (c *RunController) Pending() ([]openai.ChatCompletionMessageParamUnion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return nil, ErrNotPaused
+	}
+	return append([]openai.ChatCompletionMessageParamUnion{}, c.pending...), nil
+}
+
+// SetPending replaces the messages the run will send in its next request
+func (c *RunController) SetPending(messages []openai.ChatCompletionMessageParamUnion) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return ErrNotPaused
+	}
+	c.pending = messages
+	return nil
+}
+
+// Inject appends messages to the next request, for example a supervisor's correction
+func (c *RunController) Inject(messages ...Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return ErrNotPaused
+	}
+	c.pending = append(c.pending, convertMessages(messages)...)
+	return nil
+}
+
+// checkpoint blocks the run while a pause is requested, then applies any edits to messages
+func (c *RunController) checkpoint(ctx context.Context, messages *[]openai.ChatCompletionMessageParamUnion) error {
+	c.mu.Lock()
+	if !c.pausing {
+		c.mu.Unlock()
+		return nil
+	}
+	c.paused = true
+	c.pending = *messages
+	close(c.pausedCh)
+	resumeCh := c.resumeCh
+	c.mu.Unlock()
+
+	var err error
+	select {
+	case <-resumeCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = false
+	*messages = c.pending
+	c.pending = nil
+	return err
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunControllerPauseInspectEditResume(t *testing.T) {
+	var received []map[string]any
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received = body.Messages
+		writeCompletion(w, "test-model", "ok")
+	})
+
+	controller := NewRunController()
+	controller.Pause()
+
+	responseChan, err := testAgent.StreamChatCompletion(context.Background(), []Message{
+		UserTextMessage("Delete the production database"),
+	}, WithRunController(controller))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, controller.WaitPaused(ctx))
+	assert.True(t, controller.IsPaused())
+
+	pending, err := controller.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Delete the production database", pending[0].OfUser.Content.OfString.Value)
+
+	require.NoError(t, controller.Inject(UserTextMessage("Only describe what you would do.")))
+	controller.Resume()
+
+	var messages []string
+	for response := range responseChan {
+		require.False(t, response.IsErrorResponse(), "unexpected error: %v", response.Error())
+		if response.IsContentResponse() {
+			messages = append(messages, response.Content())
+		}
+	}
+
+	assert.Equal(t, []string{"ok"}, messages)
+	require.Len(t, received, 2)
+	assert.Equal(t, "Only describe what you would do.", received[1]["content"])
+	assert.False(t, controller.IsPaused())
+
+	_, err = controller.Pending()
+	assert.ErrorIs(t, err, ErrNotPaused)
+}
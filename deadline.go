@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// FirstTokenTimeoutError is returned when a model does not start responding within
+// the first token deadline. Like a rate limit, it falls back to the next model.
+type FirstTokenTimeoutError struct {
+	Model    string
+	Deadline time.Duration
+}
+
+func (e *FirstTokenTimeoutError) Error() string {
+	return fmt.Sprintf("model %s did not respond within %s", e.Model, e.Deadline)
+}
+
+// WithFirstTokenDeadline abandons a request that has not produced a token within d
+// and falls back to the next model, bounding worst-case interactive latency.
+// Requests are not streamed, so the first token arrives with the complete
+// response and d bounds each model's time to respond.
+func WithFirstTokenDeadline(d time.Duration) AgentOption {
+	return func(a *Agent) {
+		a.firstTokenDeadline = d
+	}
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirstTokenDeadline(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Model == "slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		writeCompletion(w, body.Model, "fast answer")
+	}
+
+	testAgent := newTestAgent(t, "slow", handler, WithFallbackModels("fast"), WithFirstTokenDeadline(50*time.Millisecond))
+
+	start := time.Now()
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fast answer"}, completion.Messages)
+	require.Len(t, completion.Steps, 1)
+	assert.Equal(t, "fast", completion.Steps[0].Model)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// The last model missing the deadline fails the run
+	onlySlow := newTestAgent(t, "slow", handler, WithFirstTokenDeadline(50*time.Millisecond))
+	_, err = onlySlow.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	var timeoutErr *FirstTokenTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "slow", timeoutErr.Model)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Deadline)
+}
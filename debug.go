@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/option"
+)
+
+// ResponseKindDebug carries a provider request and its response captured with WithDebug
+const ResponseKindDebug ResponseKind = "debug"
+
+// WireExchange is a provider request and its response as sent over the wire,
+// for debugging schema and provider compatibility issues. Credentials in
+// headers are redacted, as are API keys, bearer tokens, and resolved secrets
+// in the bodies.
+type WireExchange struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers"`
+	RequestBody     string      `json:"request_body"`
+	StatusCode      int         `json:"status_code,omitempty"`
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	ResponseBody    string      `json:"response_body,omitempty"`
+	// Error is the transport error of a request that got no response
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// credentialHeaders are the request headers that carry credentials
+var credentialHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "Proxy-Authorization"}
+
+// WithDebug captures every model request and response of the agent's runs,
+// including retries and fallbacks, as sent over the wire. Each exchange is
+// sent as a debug response, collected in Completion.Raw, and passed to fn if
+// it is not nil. Captured bodies are kept in memory, so leave debugging off in
+// production.
+func WithDebug(fn func(ctx context.Context, exchange WireExchange)) AgentOption {
+	return func(a *Agent) {
+		a.debug = true
+		a.debugFn = fn
+	}
+}
+
+// NewDebugResponse creates a response carrying a captured exchange
+func NewDebugResponse(exchange WireExchange) Response {
+	return Response{
+		Kind:     ResponseKindDebug,
+		exchange: exchange,
+	}
+}
+
+// IsDebugResponse reports whether the response carries a captured exchange
+func (r Response) IsDebugResponse() bool {
+	return r.Kind == ResponseKindDebug
+}
+
+// WireExchange returns the captured exchange of a debug response
+func (r Response) WireExchange() WireExchange {
+	if r.Kind != ResponseKindDebug {
+		return WireExchange{}
+	}
+	return r.exchange
+}
+
+// wireCapture collects the exchanges of a run's request until they are sent
+type wireCapture struct {
+	mu        sync.Mutex
+	exchanges []WireExchange
+}
+
+type wireCaptureKey struct{}
+
+// withWireCapture collects the exchanges of requests made with ctx in capture
+func withWireCapture(ctx context.Context, capture *wireCapture) context.Context {
+	return context.WithValue(ctx, wireCaptureKey{}, capture)
+}
+
+// take returns the exchanges captured since the last call
+func (c *wireCapture) take() []WireExchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exchanges := c.exchanges
+	c.exchanges = nil
+	return exchanges
+}
+
+// debugOptions adds the capturing middleware to a request's options when debugging
+func (agent *Agent) debugOptions(opts []option.RequestOption) []option.RequestOption {
+	if !agent.debug {
+		return opts
+	}
+	return append(append([]option.RequestOption{}, opts...), option.WithMiddleware(agent.captureWire))
+}
+
+// captureWire is middleware recording an exchange
+func (agent *Agent) captureWire(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	ctx := req.Context()
+	exchange := WireExchange{Method: req.Method, URL: req.URL.String(), RequestHeaders: req.Header.Clone()}
+	for _, name := range credentialHeaders {
+		if exchange.RequestHeaders.Get(name) != "" {
+			exchange.RequestHeaders.Set(name, "[REDACTED]")
+		}
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = redactResolvedSecrets(ctx, redactSecrets(string(body)))
+	}
+
+	start := time.Now()
+	resp, err := next(req)
+	exchange.Duration = time.Since(start)
+	if err != nil {
+		exchange.Error = err.Error()
+	} else {
+		exchange.StatusCode = resp.StatusCode
+		exchange.ResponseHeaders = resp.Header.Clone()
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.ResponseBody = redactResolvedSecrets(ctx, redactSecrets(string(body)))
+		if readErr != nil {
+			exchange.Error = readErr.Error()
+			resp, err = nil, readErr
+		}
+	}
+
+	if capture, ok := ctx.Value(wireCaptureKey{}).(*wireCapture); ok {
+		capture.mu.Lock()
+		capture.exchanges = append(capture.exchanges, exchange)
+		capture.mu.Unlock()
+	}
+	if agent.debugFn != nil {
+		agent.debugFn(ctx, exchange)
+	}
+	return resp, err
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugCapturesExchanges(t *testing.T) {
+	var mu sync.Mutex
+	var called []WireExchange
+	testAgent := newTestAgent(t, "primary", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Model == "primary" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"rate limited","type":"rate_limit_error"}}`)
+			return
+		}
+		writeCompletion(w, body.Model, "hello")
+	}, WithFallbackModels("secondary"), WithDebug(func(ctx context.Context, exchange WireExchange) {
+		mu.Lock()
+		defer mu.Unlock()
+		called = append(called, exchange)
+	}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, completion.Messages)
+
+	require.Len(t, completion.Raw, 2)
+	failed, succeeded := completion.Raw[0], completion.Raw[1]
+	assert.Equal(t, http.MethodPost, failed.Method)
+	assert.True(t, strings.HasSuffix(failed.URL, "/chat/completions"))
+	assert.Equal(t, "[REDACTED]", failed.RequestHeaders.Get("Authorization"))
+	assert.Contains(t, failed.RequestBody, `"model":"primary"`)
+	assert.Contains(t, failed.RequestBody, "Hello")
+	assert.Equal(t, http.StatusTooManyRequests, failed.StatusCode)
+	assert.Contains(t, failed.ResponseBody, "rate limited")
+	assert.Contains(t, succeeded.RequestBody, `"model":"secondary"`)
+	assert.Equal(t, http.StatusOK, succeeded.StatusCode)
+	assert.Contains(t, succeeded.ResponseBody, `"content":"hello"`)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, completion.Raw, called)
+}
+
+func TestDebugRedactsBodies(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "ok")
+	}, WithDebug(nil))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{
+		UserTextMessage("my key is sk-abcdefghijklmnopqrstuvwxyz123456"),
+	})
+	require.NoError(t, err)
+	require.Len(t, completion.Raw, 1)
+	assert.NotContains(t, completion.Raw[0].RequestBody, "sk-abcdefghijklmnopqrstuvwxyz123456")
+	assert.NotContains(t, completion.Raw[0].RequestHeaders.Get("Authorization"), "test-key")
+}
+
+func TestDebugDisabled(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "ok")
+	})
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("hi")})
+	require.NoError(t, err)
+	assert.Empty(t, completion.Raw)
+	for _, response := range completion.Responses {
+		assert.False(t, response.IsDebugResponse())
+	}
+}
+
+func TestDebugResponseJSON(t *testing.T) {
+	exchange := WireExchange{Method: "POST", URL: "http://example.com/chat/completions", RequestBody: `{"model":"gpt-4o"}`, StatusCode: 200, ResponseBody: `{}`}
+	data, err := json.Marshal(NewDebugResponse(exchange))
+	require.NoError(t, err)
+
+	var decoded Response
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.True(t, decoded.IsDebugResponse())
+	assert.Equal(t, exchange, decoded.WireExchange())
+}
+
+func TestDebugCapturesAuxiliaryRequests(t *testing.T) {
+	var mu sync.Mutex
+	var captured []string
+	var chatRequests int
+	testAgent := newTestAgent(t, "main-model", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/audio/speech" {
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write([]byte("mp3!"))
+			return
+		}
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Model == "cheap-model" {
+			writeCompletion(w, "cheap-model", "short summary")
+			return
+		}
+		chatRequests++
+		if chatRequests == 1 {
+			writeToolCall(w, "main-model", "search", `{}`)
+			return
+		}
+		writeCompletion(w, "main-model", "done")
+	},
+		WithTools([]Tool{MockTool{
+			name: "search",
+			executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+				return strings.Repeat("lots of output ", 100), nil
+			},
+		}}),
+		WithToolResultSummarizer(SummarizePolicy{Model: "cheap-model", Threshold: 50}),
+		WithTTS(TTSConfig{Voice: "coral"}),
+		WithDebug(func(ctx context.Context, exchange WireExchange) {
+			mu.Lock()
+			defer mu.Unlock()
+			captured = append(captured, exchange.URL)
+		}),
+	)
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Search")})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var completions, speech int
+	for _, url := range captured {
+		switch {
+		case strings.HasSuffix(url, "/audio/speech"):
+			speech++
+		case strings.HasSuffix(url, "/chat/completions"):
+			completions++
+		}
+	}
+	// Two run completions and the summarizer's
+	assert.Equal(t, 3, completions)
+	assert.Equal(t, 1, speech)
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/openai/openai-go"
+)
+
+// WithUnavailableFallback sets a function that produces the reply when the
+// provider is unavailable: every model failed with a rate limit, server error,
+// first token timeout, or transport error after retries and fallbacks. The reply
+// is sent as a degraded content response and the run ends without an error,
+// with the TerminationDegraded reason. Requests the provider rejects, such as
+// authentication failures or oversized contexts, still fail the run. If the
+// function returns an error, the run fails with both errors.
+//
+// The function receives the conversation so far and can return a canned
+// apology or a cached answer.
+func WithUnavailableFallback(fallback func(ctx context.Context, messages []Message) (string, error)) AgentOption {
+	return func(a *Agent) {
+		a.unavailableFallback = fallback
+	}
+}
+
+// IsDegraded reports whether the response is a fallback reply produced because
+// the provider was unavailable
+func (r Response) IsDegraded() bool {
+	return r.degraded
+}
+
+// degrade replaces a provider failure with the unavailable fallback's reply,
+// returning nil once the reply is sent, or the error if it does not apply
+func (agent *Agent) degrade(ctx context.Context, messages []Message, err error, responseChan chan<- Response) error {
+	if agent.unavailableFallback == nil || !isUnavailable(ctx, err) {
+		return err
+	}
+	agent.logger.WarnContext(ctx, "agent provider unavailable, sending fallback reply", "error", err)
+
+	content, fallbackErr := agent.unavailableFallback(ctx, messages)
+	if fallbackErr != nil {
+		return errors.Join(err, fmt.Errorf("unavailable fallback: %w", fallbackErr))
+	}
+	response := NewContentResponse(content)
+	response.degraded = true
+	return send(ctx, responseChan, response)
+}
+
+// isUnavailable reports whether err means the provider could not serve the
+// request, as opposed to rejecting it
+func isUnavailable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var rateLimitErr *RateLimitError
+	var timeoutErr *FirstTokenTimeoutError
+	var apiErr *openai.Error
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &timeoutErr):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	default:
+		return true
+	}
+}
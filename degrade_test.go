@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnavailableFallback(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	requests := 0
+	var received []Message
+	testAgent := newTestAgent(t, "primary", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if status == http.StatusOK {
+			writeCompletion(w, "primary", "Hello")
+			return
+		}
+		w.WriteHeader(status)
+	},
+		WithFallbackModels("secondary"),
+		WithRunStore(NewMemoryRunStore()),
+		WithUnavailableFallback(func(ctx context.Context, messages []Message) (string, error) {
+			received = messages
+			return "We're having trouble right now. Please try again shortly.", nil
+		}),
+	)
+	messages := []Message{UserTextMessage("Hi")}
+
+	// Every model is tried before degrading
+	completion, err := testAgent.ChatCompletion(context.Background(), messages, WithRunID("run-1"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, []string{"We're having trouble right now. Please try again shortly."}, completion.Messages)
+	assert.Equal(t, TerminationDegraded, completion.TerminationReason)
+	require.Len(t, completion.Responses, 1)
+	assert.True(t, completion.Responses[0].IsDegraded())
+	assert.Equal(t, messages, received)
+
+	// A degraded reply is not stored, so retrying the run reaches the provider
+	status = http.StatusOK
+	completion, err = testAgent.ChatCompletion(context.Background(), messages, WithRunID("run-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello"}, completion.Messages)
+	assert.Equal(t, TerminationCompleted, completion.TerminationReason)
+	assert.False(t, completion.Responses[1].IsDegraded())
+
+	// Rejected requests still fail
+	status = http.StatusUnauthorized
+	received = nil
+	_, err = testAgent.ChatCompletion(context.Background(), messages)
+	var authErr *AuthenticationError
+	assert.ErrorAs(t, err, &authErr)
+	assert.Nil(t, received)
+}
+
+func TestUnavailableFallbackError(t *testing.T) {
+	testAgent := newTestAgent(t, "primary", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, WithUnavailableFallback(func(ctx context.Context, messages []Message) (string, error) {
+		return "", errors.New("no cached answer")
+	}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	var rateLimitErr *RateLimitError
+	assert.ErrorAs(t, err, &rateLimitErr)
+	assert.ErrorContains(t, err, "unavailable fallback: no cached answer")
+	assert.Equal(t, TerminationError, completion.TerminationReason)
+}
+
+func TestUnavailableFallbackWithTTS(t *testing.T) {
+	store := NewMemoryRunStore()
+	testAgent := newTestAgent(t, "primary", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/audio/speech" {
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write([]byte("mp3!"))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	},
+		WithRunStore(store),
+		WithTTS(TTSConfig{Voice: "coral"}),
+		WithUnavailableFallback(func(ctx context.Context, messages []Message) (string, error) {
+			return "We're having trouble right now.", nil
+		}),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, WithRunID("run-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("mp3!"), completion.Audio)
+	// The speech that follows the degraded reply keeps the run degraded and unstored
+	assert.Equal(t, TerminationDegraded, completion.TerminationReason)
+	_, ok, err := store.Get(context.Background(), "run-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
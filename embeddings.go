@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// EmbedderOption is a functional option for configuring an Embedder
+type EmbedderOption func(*Embedder)
+
+// WithDimensions sets the number of dimensions of the returned embeddings, for models that support it
+func WithDimensions(dimensions int) EmbedderOption {
+	return func(e *Embedder) {
+		e.dimensions = dimensions
+	}
+}
+
+// Embedder creates embeddings using the OpenAI-compatible API
+type Embedder struct {
+	client     openai.Client
+	model      string
+	dimensions int
+}
+
+// NewEmbedder creates a new Embedder with the given API key, base URL, and model
+func NewEmbedder(apiKey string, baseURL string, model string, opts ...EmbedderOption) *Embedder {
+	client := openai.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(baseURL),
+	)
+
+	return NewEmbedderWithClient(client, model, opts...)
+}
+
+// NewEmbedderWithClient creates a new Embedder with an existing OpenAI client
+func NewEmbedderWithClient(client openai.Client, model string, opts ...EmbedderOption) *Embedder {
+	embedder := &Embedder{
+		client: client,
+		model:  model,
+	}
+
+	for _, opt := range opts {
+		opt(embedder)
+	}
+
+	return embedder
+}
+
+// Embed returns one embedding per text, in the same order as texts
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	params := openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model: openai.EmbeddingModel(e.model),
+	}
+	if e.dimensions > 0 {
+		params.Dimensions = openai.Int(int64(e.dimensions))
+	}
+
+	response, err := e.client.Embeddings.New(ctx, params)
+	if err != nil {
+		return nil, wrapProviderError(err)
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || int(data.Index) >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vector := make([]float32, len(data.Embedding))
+		for i, v := range data.Embedding {
+			vector[i] = float32(v)
+		}
+		embeddings[data.Index] = vector
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// RateLimitError is returned when the provider rejects a request due to rate limiting
+type RateLimitError struct {
+	// RetryAfter is the delay requested by the provider, or zero if none was given
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limited: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// ContextLengthExceededError is returned when the conversation exceeds the model's context window
+type ContextLengthExceededError struct {
+	Err error
+}
+
+func (e *ContextLengthExceededError) Error() string {
+	return fmt.Sprintf("context length exceeded: %v", e.Err)
+}
+
+func (e *ContextLengthExceededError) Unwrap() error {
+	return e.Err
+}
+
+// AuthenticationError is returned when the provider rejects the credentials
+type AuthenticationError struct {
+	Err error
+}
+
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("authentication failed: %v", e.Err)
+}
+
+func (e *AuthenticationError) Unwrap() error {
+	return e.Err
+}
+
+// ContentFilterError is returned when the provider blocks the request or response content
+type ContentFilterError struct {
+	Err error
+}
+
+func (e *ContentFilterError) Error() string {
+	if e.Err == nil {
+		return "content filtered by provider"
+	}
+	return fmt.Sprintf("content filtered by provider: %v", e.Err)
+}
+
+func (e *ContentFilterError) Unwrap() error {
+	return e.Err
+}
+
+// CanceledError is the terminal error of a run whose context was canceled or
+// timed out. It wraps the context's cause, so errors.Is matches context.Canceled
+// and context.DeadlineExceeded.
+type CanceledError struct {
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("run canceled: %v", e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// MaxIterationsError is the terminal error of a run that reached its iteration
+// limit while the model was still calling tools
+type MaxIterationsError struct {
+	MaxIterations int
+}
+
+func (e *MaxIterationsError) Error() string {
+	return fmt.Sprintf("max iterations reached: the model was still calling tools after %d iterations", e.MaxIterations)
+}
+
+// ToolExecutionError is returned when a tool call fails
+type ToolExecutionError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ToolExecutionError) Error() string {
+	return fmt.Sprintf("tool %s: %v", e.Tool, e.Err)
+}
+
+func (e *ToolExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// wrapProviderError converts provider API errors into the typed errors above.
+// Errors that do not match a known category are returned unchanged.
+func wrapProviderError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{RetryAfter: retryAfter(apiErr.Response), Err: err}
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return &AuthenticationError{Err: err}
+	case apiErr.Code == "context_length_exceeded":
+		return &ContextLengthExceededError{Err: err}
+	case apiErr.Code == "content_filter" || apiErr.Code == "content_policy_violation":
+		return &ContentFilterError{Err: err}
+	default:
+		return err
+	}
+}
+
+// retryAfter parses the retry delay from the response headers
+func retryAfter(response *http.Response) time.Duration {
+	if response == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(response.Header.Get("Retry-After-Ms"), 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := response.Header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
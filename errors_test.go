@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header map[string]string
+		body   string
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "rate limit",
+			status: http.StatusTooManyRequests,
+			header: map[string]string{"Retry-After": "2"},
+			body:   `{"error":{"message":"slow down","type":"rate_limit_error"}}`,
+			check: func(t *testing.T, err error) {
+				var rateLimitErr *RateLimitError
+				require.ErrorAs(t, err, &rateLimitErr)
+				assert.Equal(t, 2*time.Second, rateLimitErr.RetryAfter)
+			},
+		},
+		{
+			name:   "authentication",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"message":"bad key","type":"authentication_error"}}`,
+			check: func(t *testing.T, err error) {
+				var authErr *AuthenticationError
+				assert.ErrorAs(t, err, &authErr)
+			},
+		},
+		{
+			name:   "context length",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"message":"too long","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			check: func(t *testing.T, err error) {
+				var contextErr *ContextLengthExceededError
+				assert.ErrorAs(t, err, &contextErr)
+			},
+		},
+		{
+			name:   "content filter",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"message":"blocked","type":"invalid_request_error","code":"content_filter"}}`,
+			check: func(t *testing.T, err error) {
+				var filterErr *ContentFilterError
+				assert.ErrorAs(t, err, &filterErr)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+				for key, value := range tt.header {
+					w.Header().Set(key, value)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+			require.Error(t, err)
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestToolExecutionError(t *testing.T) {
+	toolErr := errors.New("boom")
+	tool := MockTool{
+		name: "explode",
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			return nil, toolErr
+		},
+	}
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"explode","arguments":"{}"}}]}}]}`)
+	}, WithTools([]Tool{tool}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+
+	var execErr *ToolExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, "explode", execErr.Tool)
+	assert.ErrorIs(t, err, toolErr)
+}
+
+func TestMaxIterationsError(t *testing.T) {
+	requests := 0
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeToolCall(w, "test-model", "noop", "{}")
+	}, WithTools([]Tool{MockTool{name: "noop", parameters: Parameters{Properties: map[string]any{}}}}), WithMaxIterations(3))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Loop")})
+
+	var maxErr *MaxIterationsError
+	require.ErrorAs(t, err, &maxErr)
+	assert.Equal(t, 3, maxErr.MaxIterations)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, TerminationMaxIterations, completion.TerminationReason)
+	assert.Len(t, completion.Steps, 3)
+}
+
+func TestTerminationReason(t *testing.T) {
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "test-model", "Done")
+	})
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, TerminationCompleted, completion.TerminationReason)
+
+	assert.Equal(t, TerminationBudgetExceeded, terminationReason(&BudgetExceededError{}))
+	assert.Equal(t, TerminationCanceled, terminationReason(&CanceledError{Err: context.Canceled}))
+	assert.Equal(t, TerminationCanceled, terminationReason(ErrKilled))
+	assert.Equal(t, TerminationError, terminationReason(&ToolExecutionError{Tool: "x", Err: errors.New("boom")}))
+}
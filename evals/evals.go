@@ -0,0 +1,146 @@
+// Package evals runs test cases against an agent and scores the results, for
+// catching regressions when prompts, tools, or models change.
+//
+// A case is a conversation and the behaviors expected of the agent's reply,
+// checked by scorers such as string matchers, tool call checks, JSON schema
+// checks, and LLM-as-judge graders:
+//
+//	cases := []evals.Case{{
+//		Name:     "order status",
+//		Messages: []agent.Message{agent.UserTextMessage("Where is order 7?")},
+//		Scorers:  []evals.Scorer{evals.CalledTool("lookup_order"), evals.Contains("shipped")},
+//	}}
+//	report := evals.NewRunner(a, evals.WithConcurrency(8)).Run(ctx, cases)
+//	report.WriteJUnit(os.Stdout)
+package evals
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Case is a conversation to run and the behaviors expected of the reply
+type Case struct {
+	Name     string
+	Messages []agent.Message
+	// Scorers check the reply, in addition to the runner's scorers
+	Scorers []Scorer
+	// RunOptions apply to this case's run, after the runner's
+	RunOptions []agent.RunOption
+}
+
+// Option is a functional option for configuring a Runner
+type Option func(*Runner)
+
+// WithConcurrency sets how many cases run at once, 4 by default
+func WithConcurrency(n int) Option {
+	return func(r *Runner) {
+		r.concurrency = n
+	}
+}
+
+// WithScorers adds scorers applied to every case
+func WithScorers(scorers ...Scorer) Option {
+	return func(r *Runner) {
+		r.scorers = append(r.scorers, scorers...)
+	}
+}
+
+// WithRunOptions applies run options to every case's run
+func WithRunOptions(opts ...agent.RunOption) Option {
+	return func(r *Runner) {
+		r.runOptions = append(r.runOptions, opts...)
+	}
+}
+
+// WithCaseTimeout bounds how long each case's run and scoring may take
+func WithCaseTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.caseTimeout = d
+	}
+}
+
+// Runner runs cases against an agent
+type Runner struct {
+	agent       agent.ChatAgent
+	concurrency int
+	scorers     []Scorer
+	runOptions  []agent.RunOption
+	caseTimeout time.Duration
+}
+
+// NewRunner creates a runner for cases against a
+func NewRunner(a agent.ChatAgent, opts ...Option) *Runner {
+	runner := &Runner{agent: a, concurrency: 4}
+	for _, opt := range opts {
+		opt(runner)
+	}
+	if runner.concurrency < 1 {
+		runner.concurrency = 1
+	}
+	return runner
+}
+
+// Run runs every case and scores its reply. A case passes when its run
+// succeeds and every scorer passes. Results are in the order of cases.
+func (r *Runner) Run(ctx context.Context, cases []Case) *Report {
+	start := time.Now()
+	results := make([]Result, len(cases))
+	slots := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i, c := range cases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+			}
+			// Cases still waiting for a slot when ctx ends are not started
+			if ctx.Err() != nil {
+				results[i] = Result{Name: c.Name, Error: context.Cause(ctx).Error()}
+				return
+			}
+			results[i] = r.runCase(ctx, c)
+		}()
+	}
+	wg.Wait()
+	return newReport(results, time.Since(start))
+}
+
+// runCase runs a case and scores its reply
+func (r *Runner) runCase(ctx context.Context, c Case) Result {
+	if r.caseTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.caseTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	result := Result{Name: c.Name}
+	opts := append(append([]agent.RunOption{}, r.runOptions...), c.RunOptions...)
+	completion, err := r.agent.ChatCompletion(ctx, c.Messages, opts...)
+	result.Completion = completion
+	result.Output = Answer(completion)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	result.Passed = true
+	for _, scorer := range append(append([]Scorer{}, r.scorers...), c.Scorers...) {
+		score, err := scorer.Score(ctx, c, completion)
+		if err != nil {
+			score = Score{Reason: "scorer failed: " + err.Error()}
+		}
+		score.Scorer = scorer.Name()
+		result.Scores = append(result.Scores, score)
+		result.Passed = result.Passed && score.Passed
+	}
+	result.Duration = time.Since(start)
+	return result
+}
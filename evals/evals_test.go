@@ -0,0 +1,93 @@
+package evals
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent replies to each conversation with reply
+type fakeAgent struct {
+	reply   func(messages []agent.Message) (agent.Completion, error)
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (f *fakeAgent) ChatCompletion(ctx context.Context, messages []agent.Message, opts ...agent.RunOption) (agent.Completion, error) {
+	running := f.running.Add(1)
+	defer f.running.Add(-1)
+	for {
+		peak := f.peak.Load()
+		if running <= peak || f.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return f.reply(messages)
+}
+
+func (f *fakeAgent) StreamChatCompletion(ctx context.Context, messages []agent.Message, opts ...agent.RunOption) (<-chan agent.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+// replyWith replies with text and the usage of a single request
+func replyWith(text string) agent.Completion {
+	return agent.Completion{Messages: []string{text}, Usage: agent.Usage{TotalTokens: 10}}
+}
+
+func TestRunner(t *testing.T) {
+	a := &fakeAgent{reply: func(messages []agent.Message) (agent.Completion, error) {
+		switch messages[0].Text() {
+		case "fail":
+			return agent.Completion{}, errors.New("provider down")
+		case "capital":
+			return replyWith("The capital of France is Paris."), nil
+		}
+		return replyWith("I don't know."), nil
+	}}
+
+	var cases []Case
+	for range 5 {
+		cases = append(cases, Case{Name: "capital", Messages: []agent.Message{agent.UserTextMessage("capital")}, Scorers: []Scorer{Contains("Paris")}})
+	}
+	cases = append(cases,
+		Case{Name: "unknown", Messages: []agent.Message{agent.UserTextMessage("unknown")}, Scorers: []Scorer{Contains("Paris")}},
+		Case{Name: "fail", Messages: []agent.Message{agent.UserTextMessage("fail")}},
+	)
+
+	report := NewRunner(a, WithConcurrency(2), WithScorers(NotContains("sorry"))).Run(context.Background(), cases)
+	require.Len(t, report.Results, 7)
+	assert.Equal(t, 5, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	assert.InDelta(t, 5.0/7, report.PassRate(), 0.001)
+	assert.Equal(t, int64(60), report.Usage.TotalTokens)
+	assert.LessOrEqual(t, a.peak.Load(), int32(2))
+
+	unknown := report.Results[5]
+	assert.Equal(t, "unknown", unknown.Name)
+	assert.False(t, unknown.Passed)
+	assert.Equal(t, "I don't know.", unknown.Output)
+	require.Len(t, unknown.Scores, 2)
+	assert.Equal(t, Score{Scorer: `not contains "sorry"`, Passed: true, Value: 1}, unknown.Scores[0])
+	assert.Equal(t, Score{Scorer: `contains "Paris"`, Reason: `answer does not contain "Paris"`}, unknown.Scores[1])
+
+	assert.Equal(t, "provider down", report.Results[6].Error)
+	assert.Empty(t, report.Results[6].Scores)
+}
+
+func TestRunnerCanceled(t *testing.T) {
+	a := &fakeAgent{reply: func(messages []agent.Message) (agent.Completion, error) {
+		return replyWith("ok"), nil
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := NewRunner(a, WithConcurrency(1)).Run(ctx, []Case{{Name: "a"}, {Name: "b"}})
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, "context canceled", report.Results[1].Error)
+}
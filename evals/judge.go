@@ -0,0 +1,240 @@
+package evals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Criterion is an aspect of a reply a Judge scores separately, such as accuracy or tone
+type Criterion struct {
+	Name        string
+	Description string
+}
+
+// JudgeOption is a functional option for configuring a Judge
+type JudgeOption func(*Judge)
+
+// WithCriteria scores each criterion separately, in addition to the overall score
+func WithCriteria(criteria ...Criterion) JudgeOption {
+	return func(j *Judge) {
+		j.criteria = append(j.criteria, criteria...)
+	}
+}
+
+// Judge grades replies against a rubric with a grader model, for evals and for
+// scoring a sample of production runs. Use a capable model as the grader,
+// different from the one being judged where possible. A Judge is safe for
+// concurrent use if its grader is.
+type Judge struct {
+	grader   agent.ChatAgent
+	rubric   string
+	criteria []Criterion
+}
+
+// NewJudge creates a judge grading against rubric with grader
+func NewJudge(grader agent.ChatAgent, rubric string, opts ...JudgeOption) *Judge {
+	judge := &Judge{grader: grader, rubric: rubric}
+	for _, opt := range opts {
+		opt(judge)
+	}
+	return judge
+}
+
+// Verdict is a judge's grade of a reply
+type Verdict struct {
+	// Score grades the reply against the rubric from 0 to 1
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+	// Criteria holds the score of each criterion from 0 to 1, by name
+	Criteria map[string]float64 `json:"criteria,omitempty"`
+	// Usage is what grading cost
+	Usage agent.Usage `json:"usage"`
+}
+
+// Preference is which of two replies a judge prefers
+type Preference string
+
+const (
+	PreferA   Preference = "a"
+	PreferB   Preference = "b"
+	PreferTie Preference = "tie"
+)
+
+// Comparison is a judge's preference between two replies
+type Comparison struct {
+	Winner Preference `json:"winner"`
+	Reason string     `json:"reason"`
+	// Usage is what comparing cost
+	Usage agent.Usage `json:"usage"`
+}
+
+// scorePrompt asks a grader to score a reply against a rubric
+const scorePrompt = `You are grading an AI assistant's reply against a rubric.
+
+Rubric:
+%s
+%s
+Conversation:
+%s
+Reply to grade:
+%s
+
+Respond with only a JSON object: %s`
+
+// comparePrompt asks a grader which of two replies better satisfies a rubric
+const comparePrompt = `You are comparing two AI assistant replies to the same conversation against a rubric.
+
+Rubric:
+%s
+
+Conversation:
+%s
+Reply A:
+%s
+
+Reply B:
+%s
+
+Respond with only a JSON object: {"winner": "a", "b", or "tie", "reason": "<one sentence>"}`
+
+// Score grades the reply of a run over messages
+func (j *Judge) Score(ctx context.Context, messages []agent.Message, completion agent.Completion) (Verdict, error) {
+	var criteria strings.Builder
+	format := `{"score": <number from 0 to 1>, "reason": "<one sentence>"`
+	if len(j.criteria) > 0 {
+		criteria.WriteString("\nScore each criterion from 0 to 1:\n")
+		format += `, "criteria": {`
+		for i, criterion := range j.criteria {
+			fmt.Fprintf(&criteria, "- %s: %s\n", criterion.Name, criterion.Description)
+			if i > 0 {
+				format += ", "
+			}
+			format += fmt.Sprintf("%q: <number>", criterion.Name)
+		}
+		format += "}"
+	}
+	format += "}"
+	prompt := fmt.Sprintf(scorePrompt, j.rubric, criteria.String(), transcript(messages), reply(completion), format)
+
+	var verdict struct {
+		Score    *float64           `json:"score"`
+		Reason   string             `json:"reason"`
+		Criteria map[string]float64 `json:"criteria"`
+	}
+	usage, err := j.ask(ctx, prompt, &verdict)
+	if err != nil {
+		return Verdict{}, err
+	}
+	if verdict.Score == nil {
+		return Verdict{}, errors.New("grader reply has no score")
+	}
+	result := Verdict{Score: clamp(*verdict.Score), Reason: verdict.Reason, Usage: usage}
+	for _, criterion := range j.criteria {
+		score, ok := verdict.Criteria[criterion.Name]
+		if !ok {
+			return Verdict{}, fmt.Errorf("grader reply has no score for %s", criterion.Name)
+		}
+		if result.Criteria == nil {
+			result.Criteria = map[string]float64{}
+		}
+		result.Criteria[criterion.Name] = clamp(score)
+	}
+	return result, nil
+}
+
+// Compare judges which of two replies to messages better satisfies the rubric.
+// Graders favor replies by position, so the replies are compared in both
+// orders, and a preference that does not survive the swap is a tie.
+func (j *Judge) Compare(ctx context.Context, messages []agent.Message, a, b agent.Completion) (Comparison, error) {
+	conversation := transcript(messages)
+	first, err := j.compareOnce(ctx, conversation, reply(a), reply(b))
+	if err != nil {
+		return Comparison{}, err
+	}
+	second, err := j.compareOnce(ctx, conversation, reply(b), reply(a))
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	comparison := Comparison{Winner: PreferTie, Reason: first.Reason, Usage: first.Usage.Add(second.Usage)}
+	swapped := map[Preference]Preference{PreferA: PreferB, PreferB: PreferA, PreferTie: PreferTie}[second.Winner]
+	if first.Winner == swapped {
+		comparison.Winner = first.Winner
+	}
+	return comparison, nil
+}
+
+// compareOnce asks the grader to compare two replies in the given order
+func (j *Judge) compareOnce(ctx context.Context, conversation string, a string, b string) (Comparison, error) {
+	var comparison Comparison
+	usage, err := j.ask(ctx, fmt.Sprintf(comparePrompt, j.rubric, conversation, a, b), &comparison)
+	if err != nil {
+		return Comparison{}, err
+	}
+	comparison.Winner = Preference(strings.ToLower(string(comparison.Winner)))
+	if comparison.Winner != PreferA && comparison.Winner != PreferB && comparison.Winner != PreferTie {
+		return Comparison{}, fmt.Errorf("grader reply has unknown winner %q", comparison.Winner)
+	}
+	comparison.Usage = usage
+	return comparison, nil
+}
+
+// ask sends prompt to the grader and decodes the last JSON object of its reply into v
+func (j *Judge) ask(ctx context.Context, prompt string, v any) (agent.Usage, error) {
+	graded, err := j.grader.ChatCompletion(ctx, []agent.Message{agent.UserTextMessage(prompt)})
+	if err != nil {
+		return graded.Usage, err
+	}
+	objects := graded.JSONObjects()
+	if len(objects) == 0 {
+		return graded.Usage, errors.New("grader reply has no JSON")
+	}
+	if err := json.Unmarshal(objects[len(objects)-1], v); err != nil {
+		return graded.Usage, fmt.Errorf("parse grader reply: %w", err)
+	}
+	return graded.Usage, nil
+}
+
+// Scorer returns a scorer that passes when the judge's score is at least threshold
+func (j *Judge) Scorer(threshold float64) Scorer {
+	return ScorerFunc{ScorerName: "judge", Fn: func(ctx context.Context, c Case, completion agent.Completion) (Score, error) {
+		verdict, err := j.Score(ctx, c.Messages, completion)
+		if err != nil {
+			return Score{}, err
+		}
+		return Score{Passed: verdict.Score >= threshold, Value: verdict.Score, Reason: verdict.Reason}, nil
+	}}
+}
+
+// transcript formats the text of a conversation for the grader
+func transcript(messages []agent.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		if text := msg.Text(); text != "" {
+			fmt.Fprintf(&b, "%s: %s\n", msg.Role(), text)
+		}
+	}
+	return b.String()
+}
+
+// reply formats a reply for the grader: its final answer, preceded by the
+// tools it called
+func reply(completion agent.Completion) string {
+	var b strings.Builder
+	for _, call := range completion.ToolCalls {
+		fmt.Fprintf(&b, "[called %s(%s) -> %s]\n", call.Call.Name, call.Call.Arguments, call.Result)
+	}
+	b.WriteString(Answer(completion))
+	return b.String()
+}
+
+// clamp bounds a score to [0, 1]
+func clamp(score float64) float64 {
+	return math.Max(0, math.Min(1, score))
+}
@@ -0,0 +1,125 @@
+package evals
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graderReplying creates a grader answering each prompt with the next reply
+func graderReplying(prompts *[]string, replies ...string) *fakeAgent {
+	return &fakeAgent{reply: func(messages []agent.Message) (agent.Completion, error) {
+		*prompts = append(*prompts, messages[0].Text())
+		if len(replies) == 0 {
+			return agent.Completion{}, errors.New("no more replies")
+		}
+		reply := replies[0]
+		replies = replies[1:]
+		return agent.Completion{Messages: []string{reply}, Usage: agent.Usage{TotalTokens: 100}}, nil
+	}}
+}
+
+func TestJudgeScore(t *testing.T) {
+	var prompts []string
+	grader := graderReplying(&prompts, "```json\n"+`{"score": 1.2, "reason": "Accurate.", "criteria": {"accuracy": 0.9, "tone": 0.6}}`+"\n```")
+	judge := NewJudge(grader, "The reply states the order status.", WithCriteria(
+		Criterion{Name: "accuracy", Description: "Facts match the tool results"},
+		Criterion{Name: "tone", Description: "Friendly and professional"},
+	))
+
+	messages := []agent.Message{agent.UserTextMessage("Where is order 7?")}
+	completion := agent.Completion{
+		Messages:  []string{"It shipped on Monday."},
+		ToolCalls: []agent.ToolCallRecord{{Call: agent.ToolCall{Name: "lookup_order", Arguments: `{"id":"7"}`}, Result: "shipped"}},
+	}
+	verdict, err := judge.Score(context.Background(), messages, completion)
+	require.NoError(t, err)
+	assert.Equal(t, Verdict{
+		Score:    1,
+		Reason:   "Accurate.",
+		Criteria: map[string]float64{"accuracy": 0.9, "tone": 0.6},
+		Usage:    agent.Usage{TotalTokens: 100},
+	}, verdict)
+
+	require.Len(t, prompts, 1)
+	assert.Contains(t, prompts[0], "The reply states the order status.")
+	assert.Contains(t, prompts[0], "- tone: Friendly and professional")
+	assert.Contains(t, prompts[0], "user: Where is order 7?")
+	assert.Contains(t, prompts[0], "Reply to grade:\n[called lookup_order({\"id\":\"7\"}) -> shipped]\nIt shipped on Monday.")
+	assert.Contains(t, prompts[0], `"criteria": {"accuracy": <number>, "tone": <number>}`)
+}
+
+func TestJudgeScoreErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		err   string
+	}{
+		{name: "no json", reply: "Looks fine to me.", err: "grader reply has no JSON"},
+		{name: "no score", reply: `{"reason": "fine"}`, err: "grader reply has no score"},
+		{name: "missing criterion", reply: `{"score": 1, "criteria": {}}`, err: "grader reply has no score for accuracy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			judge := NewJudge(graderReplying(&prompts, tt.reply), "rubric", WithCriteria(Criterion{Name: "accuracy"}))
+			_, err := judge.Score(context.Background(), nil, agent.Completion{})
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	var prompts []string
+	_, err := NewJudge(graderReplying(&prompts), "rubric").Score(context.Background(), nil, agent.Completion{})
+	assert.EqualError(t, err, "no more replies")
+}
+
+func TestJudgeCompare(t *testing.T) {
+	a := agent.Completion{Messages: []string{"It shipped on Monday."}}
+	b := agent.Completion{Messages: []string{"Shipped."}}
+
+	tests := []struct {
+		name    string
+		replies []string
+		want    Preference
+	}{
+		{name: "consistent", replies: []string{`{"winner": "a", "reason": "More detail."}`, `{"winner": "B", "reason": "More detail."}`}, want: PreferA},
+		{name: "position bias", replies: []string{`{"winner": "a", "reason": "First."}`, `{"winner": "a", "reason": "First."}`}, want: PreferTie},
+		{name: "tie", replies: []string{`{"winner": "tie", "reason": "Same."}`, `{"winner": "tie", "reason": "Same."}`}, want: PreferTie},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			comparison, err := NewJudge(graderReplying(&prompts, tt.replies...), "rubric").Compare(context.Background(), nil, a, b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, comparison.Winner)
+			assert.Equal(t, int64(200), comparison.Usage.TotalTokens)
+			require.Len(t, prompts, 2)
+			assert.Contains(t, prompts[0], "Reply A:\nIt shipped on Monday.\n\nReply B:\nShipped.")
+			assert.Contains(t, prompts[1], "Reply A:\nShipped.\n\nReply B:\nIt shipped on Monday.")
+		})
+	}
+
+	var prompts []string
+	_, err := NewJudge(graderReplying(&prompts, `{"winner": "neither"}`), "rubric").Compare(context.Background(), nil, a, b)
+	assert.EqualError(t, err, `grader reply has unknown winner "neither"`)
+}
+
+func TestJudgeScorer(t *testing.T) {
+	var prompts []string
+	grader := graderReplying(&prompts, `{"score": 0.8, "reason": "Terse."}`, `{"score": 0.8, "reason": "Terse."}`)
+	c := Case{Messages: []agent.Message{agent.UserTextMessage("Where is order 7?")}}
+	reply := agent.Completion{Messages: []string{"Shipped."}}
+
+	s, err := LLMJudge(grader, "rubric", 0.7).Score(context.Background(), c, reply)
+	require.NoError(t, err)
+	assert.Equal(t, Score{Passed: true, Value: 0.8, Reason: "Terse."}, s)
+
+	s, err = NewJudge(grader, "rubric").Scorer(0.9).Score(context.Background(), c, reply)
+	require.NoError(t, err)
+	assert.False(t, s.Passed)
+	assert.Contains(t, prompts[1], "user: Where is order 7?")
+}
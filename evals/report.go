@@ -0,0 +1,135 @@
+package evals
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Result is the outcome of a case
+type Result struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Error is the error the case's run failed with, which fails the case
+	Error string `json:"error,omitempty"`
+	// Output is the agent's final answer
+	Output   string        `json:"output"`
+	Scores   []Score       `json:"scores,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Completion is everything the run produced
+	Completion agent.Completion `json:"-"`
+}
+
+// Report is the outcome of running a set of cases
+type Report struct {
+	Results  []Result      `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Duration time.Duration `json:"duration"`
+	// Usage is the total across every case's run
+	Usage agent.Usage `json:"usage"`
+}
+
+// newReport summarizes results
+func newReport(results []Result, duration time.Duration) *Report {
+	report := &Report{Results: results, Duration: duration}
+	for _, result := range results {
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Usage = report.Usage.Add(result.Completion.Usage)
+	}
+	return report
+}
+
+// PassRate returns the fraction of cases that passed
+func (r *Report) PassRate() float64 {
+	if len(r.Results) == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(len(r.Results))
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML for CI systems. Failed runs are
+// reported as errors and failed scorers as failures.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{Name: "evals", Tests: len(r.Results), Time: junitTime(r.Duration)}
+	for _, result := range r.Results {
+		testCase := junitCase{Name: result.Name, ClassName: "evals", Time: junitTime(result.Duration), SystemOut: result.Output}
+		switch {
+		case result.Error != "":
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: result.Error}
+		case !result.Passed:
+			suite.Failures++
+			var failed []string
+			var details strings.Builder
+			for _, score := range result.Scores {
+				if !score.Passed {
+					failed = append(failed, score.Scorer)
+					fmt.Fprintf(&details, "%s: %s\n", score.Scorer, score.Reason)
+				}
+			}
+			testCase.Failure = &junitProblem{Message: "failed " + strings.Join(failed, ", "), Text: details.String()}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTime formats a duration in seconds, as JUnit expects
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,53 @@
+package evals
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	return newReport([]Result{
+		{Name: "capital", Passed: true, Output: "Paris", Duration: 1500 * time.Millisecond, Scores: []Score{{Scorer: `contains "Paris"`, Passed: true, Value: 1}}},
+		{Name: "unknown", Output: "I don't know.", Duration: time.Second, Scores: []Score{{Scorer: `contains "Paris"`, Reason: `answer does not contain "Paris"`}}},
+		{Name: "down", Error: "provider down"},
+	}, 2*time.Second)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, testReport().WriteJUnit(&b))
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="evals" tests="3" failures="1" errors="1" time="2.000">
+    <testcase name="capital" classname="evals" time="1.500">
+      <system-out>Paris</system-out>
+    </testcase>
+    <testcase name="unknown" classname="evals" time="1.000">
+      <failure message="failed contains &#34;Paris&#34;">contains &#34;Paris&#34;: answer does not contain &#34;Paris&#34;&#xA;</failure>
+      <system-out>I don&#39;t know.</system-out>
+    </testcase>
+    <testcase name="down" classname="evals" time="0.000">
+      <error message="provider down"></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, b.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, testReport().WriteJSON(&b))
+
+	var decoded Report
+	require.NoError(t, json.Unmarshal([]byte(b.String()), &decoded))
+	assert.Equal(t, 1, decoded.Passed)
+	assert.Equal(t, 2, decoded.Failed)
+	assert.Equal(t, "provider down", decoded.Results[2].Error)
+	assert.Equal(t, testReport().Results[1].Scores, decoded.Results[1].Scores)
+}
@@ -0,0 +1,286 @@
+package evals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Score is a scorer's verdict on a reply
+type Score struct {
+	Scorer string `json:"scorer"`
+	Passed bool   `json:"passed"`
+	// Value grades the reply from 0 to 1; binary scorers give 0 or 1
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Scorer checks an expected behavior of a case's reply. An error means the
+// reply could not be scored, which fails the case.
+type Scorer interface {
+	Name() string
+	Score(ctx context.Context, c Case, completion agent.Completion) (Score, error)
+}
+
+// ScorerFunc adapts a function to a Scorer
+type ScorerFunc struct {
+	ScorerName string
+	Fn         func(ctx context.Context, c Case, completion agent.Completion) (Score, error)
+}
+
+func (s ScorerFunc) Name() string { return s.ScorerName }
+
+func (s ScorerFunc) Score(ctx context.Context, c Case, completion agent.Completion) (Score, error) {
+	return s.Fn(ctx, c, completion)
+}
+
+// Answer returns the final answer of a completion, its last message
+func Answer(completion agent.Completion) string {
+	if len(completion.Messages) == 0 {
+		return ""
+	}
+	return completion.Messages[len(completion.Messages)-1]
+}
+
+// check creates a binary scorer from a check of the answer
+func check(name string, fn func(completion agent.Completion) (bool, string)) Scorer {
+	return ScorerFunc{ScorerName: name, Fn: func(ctx context.Context, c Case, completion agent.Completion) (Score, error) {
+		passed, reason := fn(completion)
+		score := Score{Passed: passed, Reason: reason}
+		if passed {
+			score.Value = 1
+		}
+		return score, nil
+	}}
+}
+
+// Contains passes when the answer contains substr
+func Contains(substr string) Scorer {
+	return check(fmt.Sprintf("contains %q", substr), func(completion agent.Completion) (bool, string) {
+		if strings.Contains(Answer(completion), substr) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("answer does not contain %q", substr)
+	})
+}
+
+// NotContains passes when the answer does not contain substr
+func NotContains(substr string) Scorer {
+	return check(fmt.Sprintf("not contains %q", substr), func(completion agent.Completion) (bool, string) {
+		if !strings.Contains(Answer(completion), substr) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("answer contains %q", substr)
+	})
+}
+
+// Equals passes when the answer, trimmed of surrounding space, is want
+func Equals(want string) Scorer {
+	return check(fmt.Sprintf("equals %q", want), func(completion agent.Completion) (bool, string) {
+		if got := strings.TrimSpace(Answer(completion)); got != want {
+			return false, fmt.Sprintf("answer is %q", got)
+		}
+		return true, ""
+	})
+}
+
+// Matches passes when the answer matches the regular expression. It panics if
+// pattern does not compile.
+func Matches(pattern string) Scorer {
+	re := regexp.MustCompile(pattern)
+	return check(fmt.Sprintf("matches %s", pattern), func(completion agent.Completion) (bool, string) {
+		if re.MatchString(Answer(completion)) {
+			return true, ""
+		}
+		return false, "answer does not match"
+	})
+}
+
+// CalledTool passes when the agent called the tool during the run
+func CalledTool(name string) Scorer {
+	return check("called "+name, func(completion agent.Completion) (bool, string) {
+		if slices.ContainsFunc(completion.ToolCalls, func(call agent.ToolCallRecord) bool { return call.Call.Name == name }) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("%s was not called", name)
+	})
+}
+
+// NotCalledTool passes when the agent did not call the tool during the run
+func NotCalledTool(name string) Scorer {
+	return check("did not call "+name, func(completion agent.Completion) (bool, string) {
+		if slices.ContainsFunc(completion.ToolCalls, func(call agent.ToolCallRecord) bool { return call.Call.Name == name }) {
+			return false, fmt.Sprintf("%s was called", name)
+		}
+		return true, ""
+	})
+}
+
+// MatchesSchema passes when the last JSON value in the answer conforms to
+// schema. It checks type, properties, required, additionalProperties, items,
+// enum, and the string and number constraints of agent.Schema.
+func MatchesSchema(schema agent.Schema) Scorer {
+	return check("matches schema", func(completion agent.Completion) (bool, string) {
+		values := agent.ParseJSONObjects(Answer(completion))
+		if len(values) == 0 {
+			return false, "answer has no JSON"
+		}
+		var value any
+		if err := json.Unmarshal(values[len(values)-1], &value); err != nil {
+			return false, err.Error()
+		}
+		if err := validate(schema, value, "$"); err != nil {
+			return false, err.Error()
+		}
+		return true, ""
+	})
+}
+
+// validate checks value against schema, naming violations by their path
+func validate(schema map[string]any, value any, path string) error {
+	if enum, ok := schema["enum"]; ok {
+		if !slices.ContainsFunc(toSlice(enum), func(v any) bool { return reflect.DeepEqual(normalize(v), value) }) {
+			return fmt.Errorf("%s: must be one of %v", path, enum)
+		}
+	}
+
+	kind, _ := schema["type"].(string)
+	switch v := value.(type) {
+	case map[string]any:
+		if kind != "" && kind != "object" {
+			return fmt.Errorf("%s: must be %s, not object", path, kind)
+		}
+		properties, _ := schemaMap(schema["properties"])
+		for _, name := range toSlice(schema["required"]) {
+			if _, ok := v[fmt.Sprint(name)]; !ok {
+				return fmt.Errorf("%s: missing %s", path, name)
+			}
+		}
+		for name, item := range v {
+			property, ok := schemaMap(properties[name])
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %s", path, name)
+				}
+				continue
+			}
+			if err := validate(property, item, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if kind != "" && kind != "array" {
+			return fmt.Errorf("%s: must be %s, not array", path, kind)
+		}
+		if items, ok := schemaMap(schema["items"]); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if kind != "" && kind != "string" {
+			return fmt.Errorf("%s: must be %s, not string", path, kind)
+		}
+		length := utf8.RuneCountInString(v)
+		if n, ok := number(schema["minLength"]); ok && float64(length) < n {
+			return fmt.Errorf("%s: shorter than %v characters", path, n)
+		}
+		if n, ok := number(schema["maxLength"]); ok && float64(length) > n {
+			return fmt.Errorf("%s: longer than %v characters", path, n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern: %w", path, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s: does not match %s", path, pattern)
+			}
+		}
+	case float64:
+		if kind == "integer" && v != math.Trunc(v) {
+			return fmt.Errorf("%s: must be integer", path)
+		}
+		if kind != "" && kind != "number" && kind != "integer" {
+			return fmt.Errorf("%s: must be %s, not number", path, kind)
+		}
+		if n, ok := number(schema["minimum"]); ok && v < n {
+			return fmt.Errorf("%s: less than %v", path, n)
+		}
+		if n, ok := number(schema["maximum"]); ok && v > n {
+			return fmt.Errorf("%s: greater than %v", path, n)
+		}
+	case bool:
+		if kind != "" && kind != "boolean" {
+			return fmt.Errorf("%s: must be %s, not boolean", path, kind)
+		}
+	case nil:
+		if kind != "" && kind != "null" {
+			return fmt.Errorf("%s: must be %s, not null", path, kind)
+		}
+	}
+	return nil
+}
+
+// schemaMap returns a schema given as agent.Schema or a map
+func schemaMap(v any) (map[string]any, bool) {
+	switch s := v.(type) {
+	case agent.Schema:
+		return s, true
+	case map[string]any:
+		return s, true
+	}
+	return nil, false
+}
+
+// toSlice returns the elements of a slice of any type
+func toSlice(v any) []any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+// number converts a schema's numeric keyword to a float
+func number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// normalize converts Go numbers to float64, as decoded JSON holds them
+func normalize(v any) any {
+	if n, ok := number(v); ok {
+		return n
+	}
+	return v
+}
+
+// LLMJudge grades the answer against a rubric with a grader agent, passing
+// when the grader's score is at least threshold. Use a capable model as the
+// grader, different from the one under test where possible.
+func LLMJudge(grader agent.ChatAgent, rubric string, threshold float64) Scorer {
+	return NewJudge(grader, rubric).Scorer(threshold)
+}
@@ -0,0 +1,70 @@
+package evals
+
+import (
+	"context"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// score runs a scorer on a reply
+func score(t *testing.T, scorer Scorer, completion agent.Completion) Score {
+	t.Helper()
+	s, err := scorer.Score(context.Background(), Case{}, completion)
+	require.NoError(t, err)
+	return s
+}
+
+func TestStringScorers(t *testing.T) {
+	reply := agent.Completion{Messages: []string{"Let me check.", " Order 7 shipped on Monday. "}}
+
+	assert.True(t, score(t, Contains("shipped"), reply).Passed)
+	assert.False(t, score(t, Contains("Let me"), reply).Passed, "only the final answer is scored")
+	assert.True(t, score(t, NotContains("delayed"), reply).Passed)
+	assert.True(t, score(t, Equals("Order 7 shipped on Monday."), reply).Passed)
+	assert.Equal(t, `answer is "Order 7 shipped on Monday."`, score(t, Equals("Shipped"), reply).Reason)
+	assert.True(t, score(t, Matches(`(?i)order \d+ shipped`), reply).Passed)
+	assert.Panics(t, func() { Matches("(") })
+}
+
+func TestToolScorers(t *testing.T) {
+	reply := agent.Completion{ToolCalls: []agent.ToolCallRecord{{Call: agent.ToolCall{ID: "call_1", Name: "lookup_order"}}}}
+
+	assert.True(t, score(t, CalledTool("lookup_order"), reply).Passed)
+	assert.Equal(t, "refund was not called", score(t, CalledTool("refund"), reply).Reason)
+	assert.True(t, score(t, NotCalledTool("refund"), reply).Passed)
+	assert.False(t, score(t, NotCalledTool("lookup_order"), reply).Passed)
+}
+
+func TestMatchesSchema(t *testing.T) {
+	schema := agent.ObjectSchema("An order", map[string]agent.Schema{
+		"id":     agent.StringSchema("Order ID").Pattern(`^\d+$`),
+		"status": agent.StringSchema("Status").Enum("shipped", "pending"),
+		"items":  agent.ArraySchema("Items", agent.IntegerSchema("Quantity").Range(1, 10)),
+	})
+
+	tests := []struct {
+		name   string
+		answer string
+		reason string
+	}{
+		{name: "valid", answer: "Here it is:\n```json\n{\"id\":\"7\",\"status\":\"shipped\",\"items\":[1,2]}\n```"},
+		{name: "no json", answer: "It shipped.", reason: "answer has no JSON"},
+		{name: "missing", answer: `{"id":"7","items":[]}`, reason: "$: missing status"},
+		{name: "extra", answer: `{"id":"7","status":"shipped","items":[],"note":"x"}`, reason: "$: unexpected property note"},
+		{name: "pattern", answer: `{"id":"seven","status":"shipped","items":[]}`, reason: `$.id: does not match ^\d+$`},
+		{name: "enum", answer: `{"id":"7","status":"lost","items":[]}`, reason: "$.status: must be one of [shipped pending]"},
+		{name: "integer", answer: `{"id":"7","status":"shipped","items":[1.5]}`, reason: "$.items[0]: must be integer"},
+		{name: "range", answer: `{"id":"7","status":"shipped","items":[11]}`, reason: "$.items[0]: greater than 10"},
+		{name: "type", answer: `{"id":7,"status":"shipped","items":[]}`, reason: "$.id: must be string, not number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := score(t, MatchesSchema(schema), agent.Completion{Messages: []string{tt.answer}})
+			assert.Equal(t, tt.reason == "", s.Passed)
+			assert.Equal(t, tt.reason, s.Reason)
+		})
+	}
+}
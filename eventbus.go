@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies what an Event reports
+type EventKind string
+
+const (
+	// EventRunStarted is published when a run starts
+	EventRunStarted EventKind = "run_started"
+	// EventResponse carries a response of a run, as its caller receives it
+	EventResponse EventKind = "response"
+	// EventRunFinished is published when a run's stream closes, with its error if it failed
+	EventRunFinished EventKind = "run_finished"
+	// EventModerationFlagged carries a moderation response, after its response event
+	EventModerationFlagged EventKind = "moderation_flagged"
+	// EventDraft carries a draft response, after its response event
+	EventDraft EventKind = "draft"
+)
+
+// Event is something that happened in a run, published on an EventBus
+type Event struct {
+	Kind  EventKind
+	Time  time.Time
+	RunID string
+	// ParentRunID is the run that started the run, or empty
+	ParentRunID string
+	Tenant      string
+	// Response is the response of a response event
+	Response Response
+	// Err is the error a run failed with, on a run finished event
+	Err error
+}
+
+// DropPolicy is what a subscription does with events published while its buffer is full
+type DropPolicy int
+
+const (
+	// DropNewest discards the event being published
+	DropNewest DropPolicy = iota
+	// DropOldest discards the oldest buffered event to make room
+	DropOldest
+	// Block waits for the subscriber to make room, slowing every publishing
+	// run to the subscriber's pace
+	Block
+)
+
+// SubscribeOption is a functional option for configuring a Subscription
+type SubscribeOption func(*Subscription)
+
+// WithEventBuffer sets how many events a subscription holds before its drop
+// policy applies, 256 by default. Sizes below 1 hold a single event.
+func WithEventBuffer(size int) SubscribeOption {
+	return func(s *Subscription) {
+		s.buffer = size
+	}
+}
+
+// WithDropPolicy sets what happens to events published while the buffer is full
+func WithDropPolicy(policy DropPolicy) SubscribeOption {
+	return func(s *Subscription) {
+		s.policy = policy
+	}
+}
+
+// WithEventFilter delivers only the events for which filter returns true
+func WithEventFilter(filter func(Event) bool) SubscribeOption {
+	return func(s *Subscription) {
+		s.filter = filter
+	}
+}
+
+// EventBus fans the events of agent runs out to any number of subscribers,
+// such as a logger, metrics, and UI connections, without consuming the
+// responses of the runs themselves. An EventBus is safe for concurrent use.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscription receives the events published on an EventBus
+type Subscription struct {
+	bus     *EventBus
+	buffer  int
+	policy  DropPolicy
+	filter  func(Event) bool
+	events  chan Event
+	done    chan struct{}
+	dropped atomic.Int64
+	// pushMu makes dropping the oldest event and buffering the new one atomic
+	pushMu    sync.Mutex
+	closeOnce sync.Once
+}
+
+// Subscribe starts receiving events published on the bus
+func (b *EventBus) Subscribe(opts ...SubscribeOption) *Subscription {
+	s := &Subscription{bus: b, buffer: 256, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// Dropping the oldest event needs room to buffer the new one
+	s.buffer = max(s.buffer, 1)
+	s.events = make(chan Event, s.buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Publish delivers an event to every subscription
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		s.deliver(event)
+	}
+}
+
+// Events delivers the subscription's events. It is closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops the subscription and closes its events channel
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		// Release publishers blocked on this subscription before taking the lock
+		close(s.done)
+		s.bus.mu.Lock()
+		defer s.bus.mu.Unlock()
+		delete(s.bus.subs, s)
+		close(s.events)
+	})
+}
+
+// deliver buffers an event according to the drop policy
+func (s *Subscription) deliver(event Event) {
+	if s.filter != nil && !s.filter(event) {
+		return
+	}
+	switch s.policy {
+	case Block:
+		select {
+		case s.events <- event:
+		case <-s.done:
+		}
+	case DropOldest:
+		s.pushMu.Lock()
+		defer s.pushMu.Unlock()
+		for {
+			select {
+			case s.events <- event:
+				return
+			default:
+			}
+			select {
+			case <-s.events:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.events <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// WithEventBus publishes the events of the agent's runs on bus
+func WithEventBus(bus *EventBus) AgentOption {
+	return func(a *Agent) {
+		a.eventBus = bus
+	}
+}
+
+// publishResponses passes a run's responses through, publishing each on the
+// agent's event bus along with the start and end of the run
+func (agent *Agent) publishResponses(run RunInfo, options runOptions, in <-chan Response) <-chan Response {
+	if agent.eventBus == nil {
+		return in
+	}
+	publish := func(event Event) {
+		event.RunID = run.ID
+		event.ParentRunID = run.ParentID
+		event.Tenant = options.tenant
+		agent.eventBus.Publish(event)
+	}
+	publish(Event{Kind: EventRunStarted})
+
+	out := make(chan Response)
+	go func() {
+		defer close(out)
+		var err error
+		for response := range in {
+			if response.IsErrorResponse() {
+				err = response.Error()
+			}
+			publish(Event{Kind: EventResponse, Response: response})
+			if response.IsModerationResponse() {
+				publish(Event{Kind: EventModerationFlagged, Response: response})
+			}
+			if response.IsDraftResponse() {
+				publish(Event{Kind: EventDraft, Response: response})
+			}
+			out <- response
+		}
+		publish(Event{Kind: EventRunFinished, Err: err})
+	}()
+	return out
+}
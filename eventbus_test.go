@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drain returns the events buffered in a subscription
+func drain(s *Subscription) []string {
+	var ids []string
+	for {
+		select {
+		case event := <-s.Events():
+			ids = append(ids, event.RunID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	newest := bus.Subscribe(WithEventBuffer(2))
+	oldest := bus.Subscribe(WithEventBuffer(2), WithDropPolicy(DropOldest))
+	filtered := bus.Subscribe(WithEventFilter(func(e Event) bool { return e.RunID != "b" }))
+
+	for _, id := range []string{"a", "b", "c"} {
+		bus.Publish(Event{Kind: EventRunStarted, RunID: id})
+	}
+	assert.Equal(t, []string{"a", "b"}, drain(newest))
+	assert.Equal(t, int64(1), newest.Dropped())
+	assert.Equal(t, []string{"b", "c"}, drain(oldest))
+	assert.Equal(t, int64(1), oldest.Dropped())
+	assert.Equal(t, []string{"a", "c"}, drain(filtered))
+
+	// Closed subscriptions stop receiving
+	newest.Close()
+	oldest.Close()
+	filtered.Close()
+	_, open := <-newest.Events()
+	assert.False(t, open)
+	bus.Publish(Event{RunID: "d"})
+}
+
+func TestEventBusMinimumBuffer(t *testing.T) {
+	bus := NewEventBus()
+	zero := bus.Subscribe(WithEventBuffer(0), WithDropPolicy(DropOldest))
+	negative := bus.Subscribe(WithEventBuffer(-1))
+
+	for _, id := range []string{"a", "b"} {
+		bus.Publish(Event{Kind: EventRunStarted, RunID: id})
+	}
+	assert.Equal(t, []string{"b"}, drain(zero))
+	assert.Equal(t, int64(1), zero.Dropped())
+	assert.Equal(t, []string{"a"}, drain(negative))
+	assert.Equal(t, int64(1), negative.Dropped())
+}
+
+func TestEventBusBlock(t *testing.T) {
+	bus := NewEventBus()
+	blocking := bus.Subscribe(WithEventBuffer(1), WithDropPolicy(Block))
+	bus.Publish(Event{RunID: "a"})
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(Event{RunID: "b"})
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("publish did not wait for the subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, "a", (<-blocking.Events()).RunID)
+	<-published
+	assert.Equal(t, "b", (<-blocking.Events()).RunID)
+
+	// Closing releases a blocked publisher
+	bus.Publish(Event{RunID: "c"})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		blocking.Close()
+	}()
+	bus.Publish(Event{RunID: "d"})
+}
+
+func TestWithEventBus(t *testing.T) {
+	bus := NewEventBus()
+	logger := bus.Subscribe()
+	ui := bus.Subscribe(WithEventFilter(func(e Event) bool { return e.Response.IsContentResponse() }))
+	testAgent := newTestAgent(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "model", "hello")
+	}, WithEventBus(bus))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, WithRunID("run-1"), WithTenant("acme"))
+	require.NoError(t, err)
+	// The run's own stream is unaffected
+	assert.Equal(t, []string{"hello"}, completion.Messages)
+
+	var kinds []EventKind
+	for range 4 {
+		event := <-logger.Events()
+		assert.Equal(t, "run-1", event.RunID)
+		assert.Equal(t, "acme", event.Tenant)
+		kinds = append(kinds, event.Kind)
+	}
+	assert.Equal(t, []EventKind{EventRunStarted, EventResponse, EventResponse, EventRunFinished}, kinds)
+	assert.Equal(t, "hello", (<-ui.Events()).Response.Content())
+	assert.Empty(t, ui.Events())
+}
@@ -0,0 +1,260 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ToolCallRecord is a tool call the model made during a run and the result it was given
+type ToolCallRecord struct {
+	Call   ToolCall `json:"call"`
+	Result string   `json:"result"`
+}
+
+// NewToolCallResponse creates a response recording a completed tool call
+func NewToolCallResponse(record ToolCallRecord) Response {
+	return Response{
+		Kind:     ResponseKindToolCall,
+		toolCall: record,
+	}
+}
+
+// newToolCallResponse records the result the model was given for a tool call
+func newToolCallResponse(toolCall openai.ChatCompletionMessageToolCall, result string) Response {
+	return NewToolCallResponse(ToolCallRecord{
+		Call:   ToolCall{ID: toolCall.ID, Name: toolCall.Function.Name, Arguments: toolCall.Function.Arguments},
+		Result: result,
+	})
+}
+
+// IsToolCallResponse reports whether the response records a tool call
+func (r Response) IsToolCallResponse() bool {
+	return r.Kind == ResponseKindToolCall
+}
+
+// ToolCallRecord returns the tool call and result of a tool call response
+func (r Response) ToolCallRecord() ToolCallRecord {
+	if r.Kind != ResponseKindToolCall {
+		return ToolCallRecord{}
+	}
+	return r.toolCall
+}
+
+// Conversation returns the full conversation of a run: the input messages
+// followed by the assistant's replies, the tool calls it made, and their
+// results. A completion without responses contributes its messages only.
+func (c Completion) Conversation(messages []Message) []Message {
+	conversation := append([]Message{}, messages...)
+	if len(c.Responses) == 0 {
+		for _, content := range c.Messages {
+			conversation = append(conversation, AssistantTextMessage(content))
+		}
+		return conversation
+	}
+
+	// Each request's reply is its content and tool calls, followed by the results
+	var content string
+	var calls []ToolCallRecord
+	flush := func() {
+		if content == "" && len(calls) == 0 {
+			return
+		}
+		var toolCalls []ToolCall
+		for _, call := range calls {
+			toolCalls = append(toolCalls, call.Call)
+		}
+		conversation = append(conversation, AssistantToolCallMessage(content, toolCalls...))
+		for _, call := range calls {
+			conversation = append(conversation, ToolResultMessage(call.Call.ID, call.Result))
+		}
+		content, calls = "", nil
+	}
+	for _, response := range c.Responses {
+		switch {
+		// A run's requests report their iteration, unlike summaries of tool results
+		case response.IsUsageResponse() && response.Usage().Iteration > 0:
+			flush()
+		case response.IsContentResponse():
+			if content != "" || len(calls) > 0 {
+				flush()
+			}
+			content = response.Content()
+		case response.IsToolCallResponse():
+			calls = append(calls, response.ToolCallRecord())
+		}
+	}
+	flush()
+	return conversation
+}
+
+// ExportMarkdown writes the transcript of a run, with its tool calls and usage,
+// as Markdown for review and sharing
+func ExportMarkdown(w io.Writer, messages []Message, completion Completion) error {
+	var b strings.Builder
+	for _, msg := range completion.Conversation(messages) {
+		if msg.Role() == RoleTool {
+			fmt.Fprintf(&b, "### Tool result `%s`\n\n%s\n\n", msg.ToolCallID(), codeBlock("", msg.Text()))
+			continue
+		}
+		fmt.Fprintf(&b, "### %s\n\n", roleTitle(msg.Role()))
+		if text := transcriptText(msg); text != "" {
+			fmt.Fprintf(&b, "%s\n\n", text)
+		}
+		for _, call := range msg.ToolCalls() {
+			fmt.Fprintf(&b, "Tool call `%s` `%s`:\n\n%s\n\n", call.Name, call.ID, codeBlock("json", call.Arguments))
+		}
+	}
+
+	if len(completion.Steps) > 0 {
+		b.WriteString("### Usage\n\n| Step | Model | Prompt | Completion | Total |\n| --- | --- | --- | --- | --- |\n")
+		for i, step := range completion.Steps {
+			fmt.Fprintf(&b, "| %d | %s | %d | %d | %d |\n", i+1, step.Model, step.PromptTokens, step.CompletionTokens, step.TotalTokens)
+		}
+		usage := completion.Usage
+		fmt.Fprintf(&b, "| Total | | %d | %d | %d |\n", usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fineTuneMessage is a message in the OpenAI fine-tuning chat format
+type fineTuneMessage struct {
+	Role       Role               `json:"role"`
+	Content    string             `json:"content,omitempty"`
+	ToolCalls  []fineTuneToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string             `json:"tool_call_id,omitempty"`
+}
+
+type fineTuneToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// ExportFineTuning writes the transcript of a run as one line of JSON in the
+// OpenAI fine-tuning chat format, {"messages":[...]}, so the transcripts of
+// many runs can be appended to a single training or evaluation file. Images
+// and files are replaced by placeholders.
+func ExportFineTuning(w io.Writer, messages []Message, completion Completion) error {
+	var example struct {
+		Messages []fineTuneMessage `json:"messages"`
+	}
+	for _, msg := range completion.Conversation(messages) {
+		message := fineTuneMessage{Role: msg.Role(), Content: transcriptText(msg), ToolCallID: msg.ToolCallID()}
+		for _, call := range msg.ToolCalls() {
+			toolCall := fineTuneToolCall{ID: call.ID, Type: "function"}
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = call.Arguments
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		example.Messages = append(example.Messages, message)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(example)
+}
+
+// transcriptHTML renders a transcript as a self-contained page
+var transcriptHTML = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #1f2328; }
+.message { border-radius: 8px; padding: 0.75em 1em; margin: 1em 0; background: #f6f8fa; }
+.user { background: #ddf4ff; }
+.assistant { background: #f6f8fa; }
+.system, .developer { background: #fff8c5; }
+.tool { background: #fbefff; }
+.role { font-weight: 600; font-size: 0.85em; text-transform: uppercase; margin-bottom: 0.5em; }
+.text { white-space: pre-wrap; }
+pre { background: #fff; padding: 0.5em; overflow-x: auto; white-space: pre-wrap; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #d0d7de; padding: 0.25em 0.75em; text-align: right; }
+</style>
+</head>
+<body>
+{{range .Messages}}<div class="message {{.Role}}">
+<div class="role">{{.Role}}{{with .ToolCallID}} · {{.}}{{end}}</div>
+{{with .Text}}<div class="text">{{.}}</div>{{end}}
+{{range .ToolCalls}}<details open><summary>Tool call <code>{{.Name}}</code> · {{.ID}}</summary><pre>{{.Arguments}}</pre></details>
+{{end}}</div>
+{{end}}{{if .Steps}}<h2>Usage</h2>
+<table>
+<tr><th>Step</th><th>Model</th><th>Prompt</th><th>Completion</th><th>Total</th></tr>
+{{range .Steps}}<tr><td>{{.Step}}</td><td>{{.Model}}</td><td>{{.PromptTokens}}</td><td>{{.CompletionTokens}}</td><td>{{.TotalTokens}}</td></tr>
+{{end}}<tr><th>Total</th><td></td><td>{{.Usage.PromptTokens}}</td><td>{{.Usage.CompletionTokens}}</td><td>{{.Usage.TotalTokens}}</td></tr>
+</table>
+{{end}}</body>
+</html>
+`))
+
+// ExportHTML writes the transcript of a run, with its tool calls and usage, as
+// a self-contained HTML page for stakeholders
+func ExportHTML(w io.Writer, messages []Message, completion Completion) error {
+	type htmlMessage struct {
+		Role       Role
+		Text       string
+		ToolCalls  []ToolCall
+		ToolCallID string
+	}
+	type htmlStep struct {
+		Usage
+		Step int
+	}
+	var page struct {
+		Messages []htmlMessage
+		Steps    []htmlStep
+		Usage    Usage
+	}
+	for _, msg := range completion.Conversation(messages) {
+		page.Messages = append(page.Messages, htmlMessage{Role: msg.Role(), Text: transcriptText(msg), ToolCalls: msg.ToolCalls(), ToolCallID: msg.ToolCallID()})
+	}
+	for i, step := range completion.Steps {
+		page.Steps = append(page.Steps, htmlStep{Usage: step, Step: i + 1})
+	}
+	page.Usage = completion.Usage
+	return transcriptHTML.Execute(w, page)
+}
+
+// transcriptText returns the text of a message, describing images and files
+func transcriptText(msg Message) string {
+	switch msg.Kind() {
+	case MessageKindFile:
+		return fmt.Sprintf("[file %s]", msg.File().Name)
+	case MessageKindImage:
+		names := make([]string, len(msg.Images()))
+		for i, image := range msg.Images() {
+			names[i] = image.Name
+		}
+		return fmt.Sprintf("[image %s]", strings.Join(names, ", "))
+	}
+	return msg.Text()
+}
+
+// roleTitle capitalizes a role for headings
+func roleTitle(role Role) string {
+	if role == "" {
+		return ""
+	}
+	return strings.ToUpper(string(role[:1])) + string(role[1:])
+}
+
+// codeBlock fences text as a Markdown code block, with a fence longer than any
+// backtick run in the text
+func codeBlock(language string, text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + strings.TrimRight(text, "\n") + "\n" + fence
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runWithToolCall runs a conversation in which the model looks up an order
+// before answering
+func runWithToolCall(t *testing.T) ([]Message, Completion) {
+	t.Helper()
+	requests := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			writeToolCall(w, "model", "lookup_order", `{"id":"7"}`)
+			return
+		}
+		writeCompletion(w, "model", "Order 7 <shipped> yesterday.")
+	}
+	lookup := MockTool{name: "lookup_order", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		return map[string]any{"status": "shipped"}, nil
+	}}
+	testAgent := newTestAgent(t, "model", handler, WithTools([]Tool{lookup}))
+
+	messages := []Message{SystemMessage("You are helpful."), UserTextMessage("Where is order 7?")}
+	completion, err := testAgent.ChatCompletion(context.Background(), messages)
+	require.NoError(t, err)
+	return messages, completion
+}
+
+func TestCompletionConversation(t *testing.T) {
+	messages, completion := runWithToolCall(t)
+	require.Len(t, completion.ToolCalls, 1)
+	assert.Equal(t, ToolCallRecord{Call: ToolCall{ID: "call_1", Name: "lookup_order", Arguments: `{"id":"7"}`}, Result: `{"status":"shipped"}`}, completion.ToolCalls[0])
+
+	conversation := completion.Conversation(messages)
+	require.Len(t, conversation, 5)
+	assert.Equal(t, RoleAssistant, conversation[2].Role())
+	assert.Equal(t, []ToolCall{completion.ToolCalls[0].Call}, conversation[2].ToolCalls())
+	assert.Equal(t, ToolResultMessage("call_1", `{"status":"shipped"}`), conversation[3])
+	assert.Equal(t, AssistantTextMessage("Order 7 <shipped> yesterday."), conversation[4])
+}
+
+func TestExportMarkdown(t *testing.T) {
+	messages, completion := runWithToolCall(t)
+	var b strings.Builder
+	require.NoError(t, ExportMarkdown(&b, messages, completion))
+
+	markdown := b.String()
+	assert.Contains(t, markdown, "### User\n\nWhere is order 7?\n\n")
+	assert.Contains(t, markdown, "Tool call `lookup_order` `call_1`:\n\n```json\n{\"id\":\"7\"}\n```")
+	assert.Contains(t, markdown, "### Tool result `call_1`\n\n```\n{\"status\":\"shipped\"}\n```")
+	assert.Contains(t, markdown, "| 2 | model | 1 | 1 | 2 |\n| Total | | 2 | 2 | 4 |\n")
+	assert.Equal(t, "````\nuse ```go\n````", codeBlock("", "use ```go\n"))
+}
+
+func TestExportFineTuning(t *testing.T) {
+	messages, completion := runWithToolCall(t)
+	var b bytes.Buffer
+	require.NoError(t, ExportFineTuning(&b, messages, completion))
+	require.NoError(t, ExportFineTuning(&b, []Message{UserImageMessage(Image{Name: "chart.png"})}, Completion{Messages: []string{"A chart."}}))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"messages":[
+		{"role":"system","content":"You are helpful."},
+		{"role":"user","content":"Where is order 7?"},
+		{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup_order","arguments":"{\"id\":\"7\"}"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":"{\"status\":\"shipped\"}"},
+		{"role":"assistant","content":"Order 7 <shipped> yesterday."}
+	]}`, lines[0])
+	assert.JSONEq(t, `{"messages":[{"role":"user","content":"[image chart.png]"},{"role":"assistant","content":"A chart."}]}`, lines[1])
+}
+
+func TestExportHTML(t *testing.T) {
+	messages, completion := runWithToolCall(t)
+	var b strings.Builder
+	require.NoError(t, ExportHTML(&b, messages, completion))
+
+	page := b.String()
+	assert.Contains(t, page, `<div class="message tool">`)
+	assert.Contains(t, page, "Tool call <code>lookup_order</code> · call_1")
+	assert.Contains(t, page, "Order 7 &lt;shipped&gt; yesterday.")
+	assert.Contains(t, page, "<tr><th>Total</th><td></td><td>2</td><td>2</td><td>4</td></tr>")
+}
+
+func TestToolCallResponseJSON(t *testing.T) {
+	response := NewToolCallResponse(ToolCallRecord{Call: ToolCall{ID: "call_1", Name: "search", Arguments: `{}`}, Result: "ok"})
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"kind":"tool_call","tool_call":{"call":{"id":"call_1","name":"search","arguments":"{}"},"result":"ok"}}`, string(data))
+
+	var decoded Response
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, response, decoded)
+}
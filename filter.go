@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrOutputBlocked is matched by every OutputBlockedError via errors.Is
+var ErrOutputBlocked = errors.New("output blocked")
+
+// FilterAction is what an output filter does when it finds a banned phrase
+type FilterAction int
+
+const (
+	// FilterMask replaces each banned phrase with asterisks and keeps streaming
+	FilterMask FilterAction = iota
+	// FilterHalt stops the stream at the first banned phrase with an OutputBlockedError
+	FilterHalt
+)
+
+// OutputPolicy configures the filter applied to content before it reaches end users.
+// Phrases match case-insensitively on word boundaries, including when a phrase is
+// split across streamed chunks.
+type OutputPolicy struct {
+	Phrases []string
+	Action  FilterAction
+}
+
+// OutputBlockedError is returned when a FilterHalt policy stops a stream
+type OutputBlockedError struct {
+	Phrase string
+}
+
+func (e *OutputBlockedError) Error() string {
+	return fmt.Sprintf("output blocked: matched %q", e.Phrase)
+}
+
+func (e *OutputBlockedError) Is(target error) bool {
+	return target == ErrOutputBlocked
+}
+
+// WithOutputFilter filters streamed content through the policy before it is returned
+func WithOutputFilter(policy OutputPolicy) AgentOption {
+	return func(a *Agent) {
+		a.outputPolicy = policy
+	}
+}
+
+// FilterStream applies the policy to the content responses of any response stream.
+// Other responses pass through unchanged. On a halt, the remaining input is drained.
+func FilterStream(in <-chan Response, policy OutputPolicy) <-chan Response {
+	return filterResponses(in, policy, func() {})
+}
+
+// filterResponses applies the policy to in, calling stop when the stream is halted
+func filterResponses(in <-chan Response, policy OutputPolicy, stop context.CancelFunc) <-chan Response {
+	out := make(chan Response)
+	go func() {
+		defer close(out)
+		filter := newOutputFilter(policy)
+		halt := func(text string, err error) {
+			stop()
+			if text != "" {
+				out <- NewContentResponse(text)
+			}
+			out <- NewErrorResponse(err)
+			for range in {
+			}
+		}
+		for response := range in {
+			if response.IsContentResponse() {
+				text, err := filter.write(response.Content())
+				if err != nil {
+					halt(text, err)
+					return
+				}
+				if text != "" {
+					out <- NewContentResponse(text)
+				}
+				continue
+			}
+			// Content held back for a possible split phrase is released before other responses
+			text, err := filter.flush()
+			if err != nil {
+				halt(text, err)
+				return
+			}
+			if text != "" {
+				out <- NewContentResponse(text)
+			}
+			out <- response
+		}
+		text, err := filter.flush()
+		if err != nil {
+			halt(text, err)
+			return
+		}
+		if text != "" {
+			out <- NewContentResponse(text)
+		}
+	}()
+	return out
+}
+
+// outputFilter scans a stream of text chunks with a sliding window, holding back
+// the tail of each chunk until it can no longer be the start of a banned phrase
+type outputFilter struct {
+	policy  OutputPolicy
+	window  int
+	pending string
+	// lastWord reports whether the last released rune was a word character
+	lastWord bool
+}
+
+func newOutputFilter(policy OutputPolicy) *outputFilter {
+	window := 0
+	for _, phrase := range policy.Phrases {
+		window = max(window, len(phrase))
+	}
+	return &outputFilter{policy: policy, window: window}
+}
+
+// write adds a chunk and returns the text that is safe to release
+func (f *outputFilter) write(chunk string) (string, error) {
+	f.pending += chunk
+	// Hold back one phrase length so a match and the rune after it are always complete
+	safe := len(f.pending) - f.window
+	if safe <= 0 {
+		return "", nil
+	}
+	for safe > 0 && !utf8.RuneStart(f.pending[safe]) {
+		safe--
+	}
+	return f.release(safe, false)
+}
+
+// flush releases all held back text at the end of a stream
+func (f *outputFilter) flush() (string, error) {
+	return f.release(len(f.pending), true)
+}
+
+// release filters and returns pending text up to safe, extending it past any match that crosses it
+func (f *outputFilter) release(safe int, final bool) (string, error) {
+	text := f.pending
+	var out strings.Builder
+	i := 0
+	for i < safe {
+		if phrase, n := f.match(text, i, final); n > 0 {
+			if f.policy.Action == FilterHalt {
+				f.pending = ""
+				return out.String(), &OutputBlockedError{Phrase: phrase}
+			}
+			out.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[i:i+n])))
+			i += n
+			safe = max(safe, i)
+			r, _ := utf8.DecodeLastRuneInString(phrase)
+			f.lastWord = isWordRune(r)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		out.WriteString(text[i : i+size])
+		f.lastWord = isWordRune(r)
+		i += size
+	}
+	f.pending = text[i:]
+	return out.String(), nil
+}
+
+// match returns the longest banned phrase starting at i on word boundaries and its length in text
+func (f *outputFilter) match(text string, i int, final bool) (string, int) {
+	if f.lastWord {
+		return "", 0
+	}
+	var best string
+	for _, phrase := range f.policy.Phrases {
+		n := len(phrase)
+		if n == 0 || n <= len(best) || i+n > len(text) || !strings.EqualFold(text[i:i+n], phrase) {
+			continue
+		}
+		if i+n == len(text) {
+			// The following rune is unknown until more text arrives
+			if !final {
+				continue
+			}
+		} else if r, _ := utf8.DecodeRuneInString(text[i+n:]); isWordRune(r) {
+			continue
+		}
+		best = phrase
+	}
+	return best, len(best)
+}
+
+// isWordRune reports whether r is part of a word for phrase boundary checks
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
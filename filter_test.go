@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectFiltered runs chunks through FilterStream and returns the joined content and any error
+func collectFiltered(chunks []string, policy OutputPolicy) (string, error) {
+	in := make(chan Response)
+	go func() {
+		defer close(in)
+		for _, chunk := range chunks {
+			in <- NewContentResponse(chunk)
+		}
+	}()
+
+	var content strings.Builder
+	var err error
+	for response := range FilterStream(in, policy) {
+		if response.IsErrorResponse() {
+			err = response.Error()
+			continue
+		}
+		content.WriteString(response.Content())
+	}
+	return content.String(), err
+}
+
+func TestFilterStreamMask(t *testing.T) {
+	policy := OutputPolicy{Phrases: []string{"darn", "heck no"}}
+
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{name: "single chunk", chunks: []string{"well darn it"}, want: "well **** it"},
+		{name: "split across chunks", chunks: []string{"well da", "rn it"}, want: "well **** it"},
+		{name: "split one byte at a time", chunks: strings.Split("oh heck no!", ""), want: "oh *******!"},
+		{name: "case insensitive", chunks: []string{"DARN"}, want: "****"},
+		{name: "phrase at end of stream", chunks: []string{"so ", "darn"}, want: "so ****"},
+		{name: "inside a word", chunks: []string{"darned ", "undarn"}, want: "darned undarn"},
+		{name: "multibyte text", chunks: []string{"café d", "arn ☕"}, want: "café **** ☕"},
+		{name: "no match", chunks: []string{"hello ", "world"}, want: "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collectFiltered(tt.chunks, policy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFilterStreamHalt(t *testing.T) {
+	policy := OutputPolicy{Phrases: []string{"secret plan"}, Action: FilterHalt}
+
+	got, err := collectFiltered([]string{"here is the sec", "ret plan: step one", " and more"}, policy)
+
+	assert.Equal(t, "here is the ", got)
+	var blockedErr *OutputBlockedError
+	require.ErrorAs(t, err, &blockedErr)
+	assert.Equal(t, "secret plan", blockedErr.Phrase)
+	assert.ErrorIs(t, err, ErrOutputBlocked)
+}
+
+func TestFilterStreamPassesOtherResponses(t *testing.T) {
+	in := make(chan Response, 3)
+	in <- NewContentResponse("da")
+	in <- NewUsageResponse(Usage{TotalTokens: 3})
+	in <- NewContentResponse("rn")
+	close(in)
+
+	var responses []Response
+	for response := range FilterStream(in, OutputPolicy{Phrases: []string{"darn"}}) {
+		responses = append(responses, response)
+	}
+
+	// Held back content is released before the usage response, ending the window
+	require.Len(t, responses, 3)
+	assert.Equal(t, "da", responses[0].Content())
+	assert.True(t, responses[1].IsUsageResponse())
+	assert.Equal(t, "rn", responses[2].Content())
+}
+
+func TestAgentOutputFilter(t *testing.T) {
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "test-model", "that is a darn shame")
+	}, WithOutputFilter(OutputPolicy{Phrases: []string{"darn"}}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, "that is a **** shame", strings.Join(completion.Messages, ""))
+
+	halting := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "test-model", "that is a darn shame")
+	}, WithOutputFilter(OutputPolicy{Phrases: []string{"darn"}, Action: FilterHalt}))
+
+	_, err = halting.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	assert.ErrorIs(t, err, ErrOutputBlocked)
+}
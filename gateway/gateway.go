@@ -0,0 +1,302 @@
+// Package gateway serves chat with an agent over WebSocket, for browser and
+// mobile clients that keep a conversation open. Each connection is a Session
+// holding its conversation. Clients send JSON frames:
+//
+//	{"kind":"message","content":"Where is order 7?"}
+//	{"kind":"cancel"}
+//	{"kind":"reset"}
+//
+// A message starts a run over the session's conversation. The run's responses
+// are sent as JSON frames in the form of agent.Response's MarshalJSON, such as
+// {"kind":"content","content":"..."}, {"kind":"tool_call","tool_call":{...}},
+// and {"kind":"usage","usage":{...}}, followed by {"kind":"done"}. The first
+// frame is {"kind":"session","session_id":"..."}. Cancel stops the current
+// run, and reset starts a new conversation. Problems with a client's frames are
+// reported as {"kind":"error","error":"..."} and leave the session open.
+//
+// Browsers send cookies with WebSocket handshakes from any site, so only pages
+// from the gateway's own host may connect unless WithCheckOrigin allows more.
+//
+//	http.Handle("/chat", gateway.New(a, gateway.WithAuth(authenticate)))
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/internal/websocket"
+)
+
+// Frame kinds the gateway sends besides the kinds of agent responses
+const (
+	// KindSession opens a connection and carries the session's ID
+	KindSession = "session"
+	// KindDone ends the responses of a run
+	KindDone = "done"
+)
+
+// Frame kinds clients send
+const (
+	KindMessage = "message"
+	KindCancel  = "cancel"
+	KindReset   = "reset"
+)
+
+// Option is a functional option for configuring a Gateway
+type Option func(*Gateway)
+
+// WithAuth authenticates connections before they are upgraded. The user it
+// returns is kept in the Session; an error rejects the connection with 401.
+func WithAuth(fn func(r *http.Request) (user string, err error)) Option {
+	return func(g *Gateway) {
+		g.auth = fn
+	}
+}
+
+// WithCheckOrigin sets which connections are accepted by their request, such
+// as by an allowlist of Origin headers. A rejected connection gets 403. The
+// default accepts requests whose Origin is the gateway's own host, and
+// requests without an Origin, which come from clients other than browsers.
+func WithCheckOrigin(fn func(r *http.Request) bool) Option {
+	return func(g *Gateway) {
+		g.checkOrigin = fn
+	}
+}
+
+// WithRunOptions derives the options of a session's runs, for example to set
+// the tenant from the session's user
+func WithRunOptions(fn func(s *Session) []agent.RunOption) Option {
+	return func(g *Gateway) {
+		g.runOptions = fn
+	}
+}
+
+// WithPingInterval sets how often connections are pinged (default 30s). A
+// connection that sends nothing, not even a pong, for two intervals is closed.
+func WithPingInterval(d time.Duration) Option {
+	return func(g *Gateway) {
+		g.pingInterval = d
+	}
+}
+
+// Gateway is an http.Handler that upgrades requests to WebSocket chat sessions
+type Gateway struct {
+	agent        agent.ChatAgent
+	auth         func(r *http.Request) (string, error)
+	checkOrigin  func(r *http.Request) bool
+	runOptions   func(s *Session) []agent.RunOption
+	pingInterval time.Duration
+}
+
+// New creates a gateway for an agent
+func New(a agent.ChatAgent, opts ...Option) *Gateway {
+	g := &Gateway{agent: a, checkOrigin: sameOrigin, pingInterval: 30 * time.Second}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Session is the conversation of a connection. It runs one message at a time.
+type Session struct {
+	ID string
+	// User is the user returned by the auth hook, or empty without one
+	User string
+
+	mu      sync.Mutex
+	history []agent.Message
+	cancel  context.CancelFunc
+}
+
+// History returns the session's conversation so far
+func (s *Session) History() []agent.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]agent.Message{}, s.history...)
+}
+
+// clientFrame is a frame sent by a client
+type clientFrame struct {
+	Kind    string `json:"kind"`
+	Content string `json:"content"`
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	session := &Session{ID: newID()}
+	if g.auth != nil {
+		user, err := g.auth(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		session.User = user
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Runs are canceled when the connection ends, and waited for
+	var runs sync.WaitGroup
+	defer runs.Wait()
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+
+	// Keep the connection alive, closing it when the client stops answering
+	deadline := func() { conn.SetReadDeadline(time.Now().Add(2 * g.pingInterval)) }
+	deadline()
+	conn.SetPongHandler(deadline)
+	go func() {
+		ticker := time.NewTicker(g.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if conn.Ping(nil) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	if writeFrame(conn, map[string]string{"kind": KindSession, "session_id": session.ID}) != nil {
+		return
+	}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		deadline()
+
+		var frame clientFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			writeError(conn, fmt.Errorf("invalid frame: %w", err))
+			continue
+		}
+		switch frame.Kind {
+		case KindMessage:
+			if err := g.start(ctx, conn, session, frame.Content, &runs); err != nil {
+				writeError(conn, err)
+			}
+		case KindCancel:
+			session.mu.Lock()
+			if session.cancel != nil {
+				session.cancel()
+			}
+			session.mu.Unlock()
+		case KindReset:
+			session.mu.Lock()
+			if session.cancel != nil {
+				writeError(conn, errors.New("cannot reset while a run is in progress"))
+			} else {
+				session.history = nil
+			}
+			session.mu.Unlock()
+		default:
+			writeError(conn, fmt.Errorf("unknown frame kind %q", frame.Kind))
+		}
+	}
+}
+
+// start runs a message over the session's conversation in the background
+func (g *Gateway) start(ctx context.Context, conn *websocket.Conn, session *Session, content string, runs *sync.WaitGroup) error {
+	if content == "" {
+		return errors.New("message has no content")
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.cancel != nil {
+		return errors.New("a run is already in progress")
+	}
+
+	var opts []agent.RunOption
+	if g.runOptions != nil {
+		opts = g.runOptions(session)
+	}
+	messages := append(append([]agent.Message{}, session.history...), agent.UserTextMessage(content))
+	ctx, cancel := context.WithCancel(ctx)
+	responses, err := g.agent.StreamChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	session.cancel = cancel
+
+	runs.Add(1)
+	go func() {
+		defer runs.Done()
+		defer cancel()
+
+		// Responses are kept to record the turn in the conversation
+		var received []agent.Response
+		for response := range responses {
+			received = append(received, response)
+			writeFrame(conn, response)
+		}
+		collected := make(chan agent.Response, len(received))
+		for _, response := range received {
+			collected <- response
+		}
+		close(collected)
+		completion, err := agent.Collect(collected)
+
+		session.mu.Lock()
+		if err == nil {
+			session.history = completion.Conversation(messages)
+		}
+		session.cancel = nil
+		session.mu.Unlock()
+		writeFrame(conn, map[string]string{"kind": KindDone})
+	}()
+	return nil
+}
+
+func writeFrame(conn *websocket.Conn, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func writeError(conn *websocket.Conn, err error) error {
+	return writeFrame(conn, agent.NewErrorResponse(err))
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sameOrigin accepts requests without an Origin header and requests whose
+// Origin names the host they were sent to
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,228 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/campbel/go-agents/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderTool struct{}
+
+func (orderTool) Name() string                 { return "lookup_order" }
+func (orderTool) Description() string          { return "Look up an order" }
+func (orderTool) Parameters() agent.Parameters { return agent.Parameters{Properties: map[string]any{}} }
+func (orderTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return "shipped", nil
+}
+
+// frame is a frame received by a test client
+type frame struct {
+	Kind      string                `json:"kind"`
+	SessionID string                `json:"session_id"`
+	Content   string                `json:"content"`
+	Error     string                `json:"error"`
+	ToolCall  *agent.ToolCallRecord `json:"tool_call"`
+	Usage     *agent.Usage          `json:"usage"`
+}
+
+// dial starts a gateway and connects to it
+func dial(t *testing.T, g *Gateway, header http.Header) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(g)
+	t.Cleanup(server.Close)
+	conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), header)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, kind string, content string) {
+	t.Helper()
+	data, _ := json.Marshal(clientFrame{Kind: kind, Content: content})
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, data))
+}
+
+func read(t *testing.T, conn *websocket.Conn) frame {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var f frame
+	require.NoError(t, json.Unmarshal(data, &f))
+	return f
+}
+
+// readRun reads the frames of a run up to its done frame
+func readRun(t *testing.T, conn *websocket.Conn) []frame {
+	t.Helper()
+	var frames []frame
+	for {
+		f := read(t, conn)
+		if f.Kind == KindDone {
+			return frames
+		}
+		frames = append(frames, f)
+	}
+}
+
+// contents returns the text of a run's content frames
+func contents(frames []frame) []string {
+	var texts []string
+	for _, f := range frames {
+		if f.Kind == "content" {
+			texts = append(texts, f.Content)
+		}
+	}
+	return texts
+}
+
+func TestGateway(t *testing.T) {
+	provider := agenttest.NewServer(t,
+		agenttest.CallTool("lookup_order", `{}`),
+		agenttest.Text("Order 7 shipped."),
+		agenttest.Text("You're welcome."),
+	)
+	a := agent.NewAgent("test-key", provider.URL, "gpt-test", agent.WithTools([]agent.Tool{orderTool{}}))
+	var sessions []string
+	g := New(a,
+		WithAuth(func(r *http.Request) (string, error) { return r.Header.Get("X-User"), nil }),
+		WithRunOptions(func(s *Session) []agent.RunOption {
+			sessions = append(sessions, s.User)
+			return nil
+		}),
+	)
+	conn := dial(t, g, http.Header{"X-User": {"ada"}})
+
+	opened := read(t, conn)
+	assert.Equal(t, KindSession, opened.Kind)
+	assert.NotEmpty(t, opened.SessionID)
+
+	send(t, conn, KindMessage, "Where is order 7?")
+	frames := readRun(t, conn)
+	var kinds []string
+	for _, f := range frames {
+		kinds = append(kinds, f.Kind)
+	}
+	assert.Equal(t, []string{"usage", "tool_call", "usage", "content"}, kinds)
+	assert.Equal(t, "lookup_order", frames[1].ToolCall.Call.Name)
+	assert.Equal(t, "shipped", frames[1].ToolCall.Result)
+	assert.Equal(t, []string{"Order 7 shipped."}, contents(frames))
+
+	send(t, conn, KindMessage, "Thanks")
+	frames = readRun(t, conn)
+	assert.Equal(t, []string{"You're welcome."}, contents(frames))
+	assert.Equal(t, []string{"ada", "ada"}, sessions)
+
+	// The conversation is kept: user, tool call, tool result, reply, user
+	requests := provider.Requests()
+	require.Len(t, requests, 3)
+	assert.Len(t, requests[2].Messages, 5)
+	assert.Equal(t, "Thanks", requests[2].LastMessage().Content)
+
+	send(t, conn, "bogus", "")
+	assert.Equal(t, frame{Kind: "error", Error: `unknown frame kind "bogus"`}, read(t, conn))
+	send(t, conn, KindMessage, "")
+	assert.Equal(t, frame{Kind: "error", Error: "message has no content"}, read(t, conn))
+}
+
+func TestGatewayCancelAndReset(t *testing.T) {
+	provider := agenttest.NewServer(t, agenttest.Reply{Content: "slow", Delay: 10 * time.Second})
+	provider.Handle(func(r agenttest.Request) agenttest.Reply {
+		return agenttest.Text("fresh")
+	})
+	a := agent.NewAgent("test-key", provider.URL, "gpt-test")
+	conn := dial(t, New(a), nil)
+	read(t, conn)
+
+	send(t, conn, KindMessage, "Take your time")
+	send(t, conn, KindMessage, "Hurry")
+	assert.Equal(t, frame{Kind: "error", Error: "a run is already in progress"}, read(t, conn))
+	require.Eventually(t, func() bool { return len(provider.Requests()) == 1 }, time.Second, time.Millisecond)
+	send(t, conn, KindCancel, "")
+	frames := readRun(t, conn)
+	require.NotEmpty(t, frames)
+	assert.Equal(t, "error", frames[len(frames)-1].Kind)
+
+	send(t, conn, KindReset, "")
+	send(t, conn, KindMessage, "Hello")
+	frames = readRun(t, conn)
+	assert.Equal(t, []string{"fresh"}, contents(frames))
+	assert.Len(t, provider.Requests()[1].Messages, 1, "the canceled turn is not kept")
+}
+
+func TestGatewayAuth(t *testing.T) {
+	g := New(agent.NewAgent("test-key", "http://localhost", "gpt-test"), WithAuth(func(r *http.Request) (string, error) {
+		return "", errors.New("invalid token")
+	}))
+	server := httptest.NewServer(g)
+	defer server.Close()
+
+	_, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestGatewayOrigin(t *testing.T) {
+	a := agent.NewAgent("test-key", "http://localhost", "gpt-test")
+	tests := []struct {
+		name   string
+		origin string
+		opts   []Option
+		err    string
+	}{
+		{name: "no origin"},
+		{name: "same origin", origin: "http://{host}"},
+		{name: "cross origin", origin: "https://evil.example", err: "403"},
+		{name: "allowed origin", origin: "https://app.example", opts: []Option{WithCheckOrigin(func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "https://app.example"
+		})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(New(a, tt.opts...))
+			defer server.Close()
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", strings.ReplaceAll(tt.origin, "{host}", strings.TrimPrefix(server.URL, "http://")))
+			}
+
+			conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), header)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			defer conn.Close()
+			assert.Equal(t, KindSession, read(t, conn).Kind)
+		})
+	}
+}
+
+func TestGatewayKeepalive(t *testing.T) {
+	conn := dial(t, New(agent.NewAgent("test-key", "http://localhost", "gpt-test"), WithPingInterval(20*time.Millisecond)), nil)
+	read(t, conn)
+
+	// Reading answers the gateway's pings, so the connection stays open
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("connection closed: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ToolGroupSeparator joins a tool's group and name in the name the model sees.
+// Provider tool names may only contain letters, digits, underscores, and
+// hyphens, so the read tool in group "fs" is called "fs__read".
+const ToolGroupSeparator = "__"
+
+// Namespace puts tools in a group, prefixing their names so tools from several
+// sources, such as registries or MCP servers, do not collide. Groups nest:
+// namespacing tools already in group "fs" under "mcp" puts them in group
+// "mcp.fs", named "mcp__fs__read". Group names should only contain letters,
+// digits, underscores, and hyphens, with dots between nested groups.
+func Namespace(group string, tools ...Tool) []Tool {
+	namespaced := make([]Tool, len(tools))
+	for i, tool := range tools {
+		if grouped, ok := tool.(groupedTool); ok {
+			grouped.group = group + "." + grouped.group
+			namespaced[i] = grouped
+			continue
+		}
+		namespaced[i] = groupedTool{Tool: tool, group: group}
+	}
+	return namespaced
+}
+
+// ToolGroup returns the group of a tool put in one with Namespace, or an empty string
+func ToolGroup(tool Tool) string {
+	if grouped, ok := tool.(groupedTool); ok {
+		return grouped.group
+	}
+	return ""
+}
+
+// WithToolGroups offers the run only the grouped tools in the given groups and
+// their nested groups. Tools without a group are still offered.
+func WithToolGroups(groups ...string) RunOption {
+	return func(o *runOptions) {
+		o.toolGroups = groups
+	}
+}
+
+// WithoutToolGroups withholds the tools in the given groups and their nested
+// groups from the run
+func WithoutToolGroups(groups ...string) RunOption {
+	return func(o *runOptions) {
+		o.withoutToolGroups = groups
+	}
+}
+
+// groupedTool is a tool in a group, named with the group as a prefix
+type groupedTool struct {
+	Tool
+	group string
+}
+
+func (t groupedTool) Name() string {
+	return strings.ReplaceAll(t.group, ".", ToolGroupSeparator) + ToolGroupSeparator + t.Tool.Name()
+}
+
+// Examples forwards the examples of a ToolWithExamples
+func (t groupedTool) Examples() []map[string]any {
+	if tool, ok := t.Tool.(ToolWithExamples); ok {
+		return tool.Examples()
+	}
+	return nil
+}
+
+// SummaryHints forwards the hints of a ToolWithSummaryHints
+func (t groupedTool) SummaryHints() []string {
+	if tool, ok := t.Tool.(ToolWithSummaryHints); ok {
+		return tool.SummaryHints()
+	}
+	return nil
+}
+
+// Timeout forwards the timeout of a ToolWithTimeout
+func (t groupedTool) Timeout() time.Duration {
+	if tool, ok := t.Tool.(ToolWithTimeout); ok {
+		return tool.Timeout()
+	}
+	return 0
+}
+
+// filterToolGroups returns the tools the run's group options leave enabled
+func filterToolGroups(tools []Tool, options runOptions) []Tool {
+	if options.toolGroups == nil && len(options.withoutToolGroups) == 0 {
+		return tools
+	}
+	return slices.DeleteFunc(slices.Clone(tools), func(tool Tool) bool {
+		group := ToolGroup(tool)
+		if group == "" {
+			return false
+		}
+		if options.toolGroups != nil && !inToolGroups(group, options.toolGroups) {
+			return true
+		}
+		return inToolGroups(group, options.withoutToolGroups)
+	})
+}
+
+// inToolGroups reports whether group is one of groups or nested in one
+func inToolGroups(group string, groups []string) bool {
+	return slices.ContainsFunc(groups, func(g string) bool {
+		return group == g || strings.HasPrefix(group, g+".")
+	})
+}
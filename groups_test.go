@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exampleTool is a tool with examples and summary hints
+type exampleTool struct {
+	MockTool
+}
+
+func (exampleTool) Examples() []map[string]any {
+	return []map[string]any{{"path": "README.md"}}
+}
+
+func (exampleTool) SummaryHints() []string {
+	return []string{"path"}
+}
+
+func TestNamespace(t *testing.T) {
+	fs := Namespace("fs", exampleTool{MockTool{name: "read"}}, MockTool{name: "write"})
+	nested := Namespace("mcp", Namespace("github", MockTool{name: "read"})...)
+
+	assert.Equal(t, []string{"fs__read", "fs__write"}, toolNames(fs))
+	assert.Equal(t, []string{"mcp__github__read"}, toolNames(nested))
+	assert.Equal(t, "fs", ToolGroup(fs[0]))
+	assert.Equal(t, "mcp.github", ToolGroup(nested[0]))
+	assert.Equal(t, "", ToolGroup(MockTool{name: "read"}))
+
+	// Optional interfaces of the wrapped tool still apply
+	assert.Equal(t, []map[string]any{{"path": "README.md"}}, fs[0].(ToolWithExamples).Examples())
+	assert.Equal(t, []string{"path"}, fs[0].(ToolWithSummaryHints).SummaryHints())
+	assert.Nil(t, fs[1].(ToolWithExamples).Examples())
+	assert.Equal(t, time.Second, Namespace("web", timeoutTool{MockTool{name: "search"}, time.Second})[0].(ToolWithTimeout).Timeout())
+
+	result, err := fs[1].Execute(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "mock result", result)
+}
+
+func TestToolGroups(t *testing.T) {
+	var offered []string
+	var called string
+	tools := []Tool{MockTool{name: "clock"}}
+	tools = append(tools, Namespace("fs", MockTool{name: "read"}, MockTool{name: "write"})...)
+	tools = append(tools, Namespace("mcp", Namespace("github", MockTool{name: "read", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		called = "github read"
+		return "ok", nil
+	}})...)...)
+	testAgent := newTestAgent(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+			Tools    []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if len(body.Messages) == 1 {
+			offered = nil
+			for _, tool := range body.Tools {
+				offered = append(offered, tool.Function.Name)
+			}
+			writeToolCall(w, "model", offered[len(offered)-1], `{}`)
+			return
+		}
+		writeCompletion(w, "model", "done")
+	}, WithTools(tools))
+
+	tests := []struct {
+		name string
+		opts []RunOption
+		want []string
+	}{
+		{name: "all", want: []string{"clock", "fs__read", "fs__write", "mcp__github__read"}},
+		{name: "enabled groups", opts: []RunOption{WithToolGroups("mcp")}, want: []string{"clock", "mcp__github__read"}},
+		{name: "disabled groups", opts: []RunOption{WithoutToolGroups("fs")}, want: []string{"clock", "mcp__github__read"}},
+		{name: "nested group", opts: []RunOption{WithToolGroups("fs", "mcp"), WithoutToolGroups("mcp.github")}, want: []string{"clock", "fs__read", "fs__write"}},
+		{name: "group prefix is not a group", opts: []RunOption{WithoutToolGroups("f", "mcp.git")}, want: []string{"clock", "fs__read", "fs__write", "mcp__github__read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, offered)
+		})
+	}
+
+	// Calls to a namespaced tool reach the wrapped tool
+	called = ""
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, WithToolGroups("mcp.github"))
+	require.NoError(t, err)
+	assert.Equal(t, "github read", called)
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Schema is a JSON schema for a tool parameter. Its constraint keywords
+// (pattern, minLength, maxLength, minimum, maximum, enum) are both sent to the
+// model and enforced on the arguments before the tool executes.
+type Schema map[string]any
+
+// StringSchema creates a schema for a string parameter
+func StringSchema(description string) Schema {
+	return Schema{"type": "string", "description": description}
+}
+
+// NumberSchema creates a schema for a number parameter
+func NumberSchema(description string) Schema {
+	return Schema{"type": "number", "description": description}
+}
+
+// IntegerSchema creates a schema for an integer parameter
+func IntegerSchema(description string) Schema {
+	return Schema{"type": "integer", "description": description}
+}
+
+// BooleanSchema creates a schema for a boolean parameter
+func BooleanSchema(description string) Schema {
+	return Schema{"type": "boolean", "description": description}
+}
+
+// ObjectSchema creates a schema for an object with the given properties, all of
+// which are required, and no additional properties, as strict structured output expects
+func ObjectSchema(description string, properties map[string]Schema) Schema {
+	required := make([]string, 0, len(properties))
+	props := make(map[string]any, len(properties))
+	for name, property := range properties {
+		required = append(required, name)
+		props[name] = property
+	}
+	sort.Strings(required)
+	return Schema{
+		"type":                 "object",
+		"description":          description,
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// ArraySchema creates a schema for an array of items
+func ArraySchema(description string, items Schema) Schema {
+	return Schema{"type": "array", "description": description, "items": items}
+}
+
+// Pattern requires string values to match the regular expression
+func (s Schema) Pattern(pattern string) Schema {
+	s["pattern"] = pattern
+	return s
+}
+
+// MinLength requires string values to have at least n characters
+func (s Schema) MinLength(n int) Schema {
+	s["minLength"] = n
+	return s
+}
+
+// MaxLength requires string values to have at most n characters
+func (s Schema) MaxLength(n int) Schema {
+	s["maxLength"] = n
+	return s
+}
+
+// Range requires numeric values to be within [min, max]
+func (s Schema) Range(min float64, max float64) Schema {
+	s["minimum"] = min
+	s["maximum"] = max
+	return s
+}
+
+// Enum requires values to be one of the given values
+func (s Schema) Enum(values ...any) Schema {
+	s["enum"] = values
+	return s
+}
+
+// ValidationError is returned when tool arguments violate the parameter schema
+type ValidationError struct {
+	Param  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid argument %q: %s", e.Param, e.Reason)
+}
+
+// ValidateArguments checks args against the required parameters and the
+// constraint keywords of each property schema
+func ValidateArguments(parameters Parameters, args map[string]any) error {
+	for _, name := range parameters.Required {
+		if _, ok := args[name]; !ok {
+			return &ValidationError{Param: name, Reason: "required"}
+		}
+	}
+	for name, value := range args {
+		schema, ok := propertySchema(parameters.Properties[name])
+		if !ok {
+			continue
+		}
+		if reason := checkConstraints(schema, value); reason != "" {
+			return &ValidationError{Param: name, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// propertySchema returns a property definition as a map, if it is one
+func propertySchema(property any) (map[string]any, bool) {
+	switch p := property.(type) {
+	case Schema:
+		return p, true
+	case map[string]any:
+		return p, true
+	default:
+		return nil, false
+	}
+}
+
+// checkConstraints returns the reason value violates schema, or "" if it does not
+func checkConstraints(schema map[string]any, value any) string {
+	if enum, ok := schema["enum"]; ok && !inEnum(enum, value) {
+		return fmt.Sprintf("must be one of %v", enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := toFloat(schema["minLength"]); ok && float64(length) < n {
+			return fmt.Sprintf("must be at least %v characters", n)
+		}
+		if n, ok := toFloat(schema["maxLength"]); ok && float64(length) > n {
+			return fmt.Sprintf("must be at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Sprintf("invalid pattern %q: %v", pattern, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Sprintf("must match pattern %q", pattern)
+			}
+		}
+	case float64:
+		if n, ok := toFloat(schema["minimum"]); ok && v < n {
+			return fmt.Sprintf("must be at least %v", n)
+		}
+		if n, ok := toFloat(schema["maximum"]); ok && v > n {
+			return fmt.Sprintf("must be at most %v", n)
+		}
+		if schema["type"] == "integer" && v != float64(int64(v)) {
+			return "must be an integer"
+		}
+	}
+	return ""
+}
+
+// inEnum reports whether value equals one of the enum values, comparing numbers by value
+func inEnum(enum any, value any) bool {
+	values := reflect.ValueOf(enum)
+	if values.Kind() != reflect.Slice {
+		return true
+	}
+	for i := range values.Len() {
+		candidate := values.Index(i).Interface()
+		if a, ok := toFloat(candidate); ok {
+			if b, ok := toFloat(value); ok && a == b {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
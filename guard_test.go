@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateArguments(t *testing.T) {
+	parameters := Parameters{
+		Properties: map[string]any{
+			"city":  StringSchema("The city").Pattern(`^[A-Z][a-z]+$`).MaxLength(10),
+			"days":  IntegerSchema("Forecast days").Range(1, 7),
+			"units": StringSchema("Units").Enum("metric", "imperial"),
+			"note": map[string]any{
+				"type":      "string",
+				"minLength": 2,
+			},
+		},
+		Required: []string{"city"},
+	}
+
+	tests := []struct {
+		name  string
+		args  map[string]any
+		param string
+	}{
+		{name: "valid", args: map[string]any{"city": "Tokyo", "days": 3.0, "units": "metric"}},
+		{name: "missing required", args: map[string]any{"days": 3.0}, param: "city"},
+		{name: "pattern mismatch", args: map[string]any{"city": "tokyo"}, param: "city"},
+		{name: "too long", args: map[string]any{"city": "Llanfairpwll"}, param: "city"},
+		{name: "below minimum", args: map[string]any{"city": "Tokyo", "days": 0.0}, param: "days"},
+		{name: "above maximum", args: map[string]any{"city": "Tokyo", "days": 8.0}, param: "days"},
+		{name: "not an integer", args: map[string]any{"city": "Tokyo", "days": 2.5}, param: "days"},
+		{name: "not in enum", args: map[string]any{"city": "Tokyo", "units": "kelvin"}, param: "units"},
+		{name: "plain map constraint", args: map[string]any{"city": "Tokyo", "note": "x"}, param: "note"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(parameters, tt.args)
+			if tt.param == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.param, validationErr.Param)
+		})
+	}
+}
+
+func TestToolArgumentsValidatedBeforeExecution(t *testing.T) {
+	executed := false
+	tool := MockTool{
+		name: "get_weather",
+		parameters: Parameters{
+			Properties: map[string]any{"city": StringSchema("The city").MaxLength(3)},
+			Required:   []string{"city"},
+		},
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			executed = true
+			return "sunny", nil
+		},
+	}
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeToolCall(w, "test-model", "get_weather", `{"city":"Tokyo"}`)
+	}, WithTools([]Tool{tool}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Weather?")})
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.False(t, executed)
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// HandoffPolicy controls what happens after the target of a handoff finishes
+type HandoffPolicy string
+
+const (
+	// HandoffReturn returns the target agent's reply to the calling agent as the
+	// tool result, and the calling agent continues the conversation
+	HandoffReturn HandoffPolicy = "return"
+	// HandoffTransfer streams the target agent's reply to the caller and ends
+	// the run; the target agent has the final word
+	HandoffTransfer HandoffPolicy = "transfer"
+)
+
+// Handoff lets an agent transfer the conversation to another named agent. The
+// model sees each handoff as a tool named transfer_to_<Name>.
+type Handoff struct {
+	Name        string
+	Description string
+	Agent       ChatAgent
+	Policy      HandoffPolicy
+}
+
+// WithHandoffs sets the agents this agent can hand the conversation off to
+func WithHandoffs(handoffs ...Handoff) AgentOption {
+	return func(a *Agent) {
+		a.handoffs = handoffs
+	}
+}
+
+// handoffTool exposes a Handoff to the model as a tool. It is executed by the
+// agent loop rather than through Execute, since it needs the conversation history.
+type handoffTool struct {
+	handoff Handoff
+}
+
+func (h handoffTool) Name() string {
+	return "transfer_to_" + h.handoff.Name
+}
+
+func (h handoffTool) Description() string {
+	description := "Transfer the conversation to the " + h.handoff.Name + " agent"
+	if h.handoff.Description != "" {
+		description += ": " + h.handoff.Description
+	}
+	return description
+}
+
+func (h handoffTool) Parameters() Parameters {
+	return Parameters{
+		Properties: map[string]any{
+			"reason": StringSchema("Why the conversation is being transferred"),
+		},
+		Required: []string{"reason"},
+	}
+}
+
+func (h handoffTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return nil, fmt.Errorf("handoff %s must be run by the agent", h.handoff.Name)
+}
+
+// findHandoff returns the handoff exposed under the tool name, or nil if there is none
+func (agent *Agent) findHandoff(toolName string) *Handoff {
+	for i := range agent.handoffs {
+		if (handoffTool{handoff: agent.handoffs[i]}).Name() == toolName {
+			return &agent.handoffs[i]
+		}
+	}
+	return nil
+}
+
+// runHandoff runs the target agent on the conversation history. It returns the
+// target's reply for HandoffReturn, or reports that the run was transferred.
+func (agent *Agent) runHandoff(
+	ctx context.Context,
+	handoff *Handoff,
+	toolCall openai.ChatCompletionMessageToolCall,
+	history []Message,
+	responseChan chan<- Response,
+	options runOptions,
+) (string, bool, error) {
+	var args struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+		return "", false, &ToolExecutionError{Tool: toolCall.Function.Name, Err: err}
+	}
+
+	agent.logger.InfoContext(ctx, "agent handoff", "target", handoff.Name, "policy", handoff.Policy, "reason", args.Reason)
+
+	note := fmt.Sprintf("The conversation was transferred to you. Reason: %s", args.Reason)
+	// The target enforces its own access policy for the same tenant
+	var opts []RunOption
+	if options.tenant != "" {
+		opts = append(opts, WithTenant(options.tenant))
+	}
+	targetCtx, cancel := context.WithCancel(ctx)
+	targetChan, err := handoff.Agent.StreamChatCompletion(targetCtx, append([]Message{SystemMessage(note)}, history...), opts...)
+	if err != nil {
+		cancel()
+		return "", false, err
+	}
+	// Stop the target on an early return and drain it, so its run can finish
+	defer func() {
+		cancel()
+		for range targetChan {
+		}
+	}()
+
+	transfer := handoff.Policy == HandoffTransfer
+	var contents []string
+	for response := range targetChan {
+		switch {
+		case response.IsErrorResponse():
+			return "", false, response.Error()
+		case response.IsContentResponse():
+			contents = append(contents, response.Content())
+			if transfer {
+				if err := send(ctx, responseChan, response); err != nil {
+					return "", false, err
+				}
+			}
+		default:
+			if err := send(ctx, responseChan, response); err != nil {
+				return "", false, err
+			}
+		}
+	}
+
+	return strings.Join(contents, "\n"), transfer, nil
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandoff(t *testing.T) {
+	tests := []struct {
+		policy   HandoffPolicy
+		messages []string
+	}{
+		{policy: HandoffTransfer, messages: []string{"Let me transfer you", "Your invoice is paid"}},
+		{policy: HandoffReturn, messages: []string{"Let me transfer you", "Billing says your invoice is paid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			triageCalls := 0
+			var billingMessages []map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Model    string           `json:"model"`
+					Messages []map[string]any `json:"messages"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+				if body.Model == "billing" {
+					billingMessages = body.Messages
+					writeCompletion(w, "billing", "Your invoice is paid")
+					return
+				}
+
+				triageCalls++
+				if triageCalls == 1 {
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`{"id":"1","object":"chat.completion","model":"triage","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"Let me transfer you","tool_calls":[{"id":"call_1","type":"function","function":{"name":"transfer_to_billing","arguments":"{\"reason\":\"invoice question\"}"}}]}}]}`))
+					return
+				}
+				writeCompletion(w, "triage", "Billing says your invoice is paid")
+			}))
+			defer server.Close()
+
+			client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+			billing := NewAgentWithClient(client, "billing")
+			triage := NewAgentWithClient(client, "triage", WithHandoffs(Handoff{
+				Name:        "billing",
+				Description: "Handles invoices and payments",
+				Agent:       billing,
+				Policy:      tt.policy,
+			}))
+
+			completion, err := triage.ChatCompletion(context.Background(), []Message{UserTextMessage("Is my invoice paid?")})
+			require.NoError(t, err)
+			assert.Equal(t, tt.messages, completion.Messages)
+
+			// The billing agent receives the transfer note and the conversation history
+			require.Len(t, billingMessages, 3)
+			assert.Contains(t, billingMessages[0]["content"], "invoice question")
+			assert.Equal(t, "Is my invoice paid?", billingMessages[1]["content"])
+			assert.Equal(t, "Let me transfer you", billingMessages[2]["content"])
+		})
+	}
+}
+
+func TestHandoffCanceledTargetFinishes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Model == "billing" {
+			writeToolCall(w, "billing", "lookup", `{}`)
+			return
+		}
+		writeToolCall(w, "triage", "transfer_to_billing", `{"reason":"invoice question"}`)
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	billing := NewAgentWithClient(client, "billing", WithTools([]Tool{MockTool{name: "lookup"}}))
+	triage := NewAgentWithClient(client, "triage", WithHandoffs(Handoff{Name: "billing", Agent: billing, Policy: HandoffTransfer}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	responses, err := triage.StreamChatCompletion(ctx, []Message{UserTextMessage("Is my invoice paid?")}, WithRunID("triage"))
+	require.NoError(t, err)
+	for response := range responses {
+		if response.RunID != "triage" {
+			break
+		}
+	}
+	// Cancel while the handoff is forwarding a target response no one reads yet
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	for range responses {
+	}
+
+	assert.Eventually(t, func() bool {
+		stacks := make([]byte, 1<<20)
+		stacks = stacks[:runtime.Stack(stacks, true)]
+		return !bytes.Contains(stacks, []byte("(*Agent).StreamChatCompletion.func"))
+	}, 2*time.Second, 10*time.Millisecond, "the target run did not finish")
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RunState is the lifecycle state of a run
+type RunState string
+
+const (
+	RunStateRunning  RunState = "running"
+	RunStateFinished RunState = "finished"
+	RunStateFailed   RunState = "failed"
+)
+
+// RunStatus is a snapshot of a run's progress. A running status whose UpdatedAt
+// is older than a few heartbeat intervals indicates a stuck or crashed run.
+type RunStatus struct {
+	RunID     string    `json:"run_id"`
+	State     RunState  `json:"state"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Iteration int       `json:"iteration"`
+	// LastTool is the most recent tool the run called
+	LastTool string `json:"last_tool,omitempty"`
+	// Usage is the total usage of the run so far
+	Usage Usage  `json:"usage"`
+	Error string `json:"error,omitempty"`
+}
+
+// StatusStore persists run statuses reported by heartbeats
+type StatusStore interface {
+	// PutStatus stores the latest status of a run
+	PutStatus(ctx context.Context, status RunStatus) error
+	// Status returns the latest status of a run, reporting whether it was found
+	Status(ctx context.Context, runID string) (RunStatus, bool, error)
+}
+
+// HeartbeatPolicy configures liveness reporting for long-running runs
+type HeartbeatPolicy struct {
+	Store StatusStore
+	// Interval is the time between heartbeats. The first heartbeat is written once a
+	// run has lasted one interval, so short runs only record their final status.
+	Interval time.Duration
+}
+
+// WithHeartbeat periodically persists the progress of runs started with WithRunID
+func WithHeartbeat(policy HeartbeatPolicy) AgentOption {
+	return func(a *Agent) {
+		a.heartbeat = policy
+	}
+}
+
+// Status returns the latest status of a run recorded by the heartbeat
+func (agent *Agent) Status(ctx context.Context, runID string) (RunStatus, bool, error) {
+	if agent.heartbeat.Store == nil {
+		return RunStatus{}, false, errors.New("heartbeat is not configured")
+	}
+	return agent.heartbeat.Store.Status(ctx, runID)
+}
+
+// progress tracks a run's status for heartbeats. Updates to a nil progress are ignored.
+type progress struct {
+	mu     sync.Mutex
+	status RunStatus
+}
+
+func (p *progress) iteration(iteration int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Iteration = iteration
+}
+
+func (p *progress) usage(usage Usage) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Usage = p.status.Usage.Add(usage)
+}
+
+func (p *progress) tool(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.LastTool = name
+}
+
+func (p *progress) snapshot() RunStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.status
+	status.UpdatedAt = time.Now()
+	return status
+}
+
+// startHeartbeat begins reporting progress for a run. The returned function stops
+// the heartbeat and records the final status. It returns nil progress when
+// heartbeats are disabled or the run has no ID.
+func (agent *Agent) startHeartbeat(ctx context.Context, runID string) (*progress, func(err error)) {
+	if agent.heartbeat.Store == nil || agent.heartbeat.Interval <= 0 || runID == "" {
+		return nil, func(error) {}
+	}
+
+	p := &progress{status: RunStatus{RunID: runID, State: RunStateRunning, StartedAt: time.Now()}}
+	put := func(status RunStatus) {
+		// Persist even after the run's context is canceled so the final state is recorded
+		if err := agent.heartbeat.Store.PutStatus(context.WithoutCancel(ctx), status); err != nil {
+			agent.logger.WarnContext(ctx, "agent heartbeat failed", "error", err)
+		}
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(agent.heartbeat.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				put(p.snapshot())
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return p, func(err error) {
+		close(stop)
+		wg.Wait()
+		status := p.snapshot()
+		status.State = RunStateFinished
+		if err != nil {
+			status.State = RunStateFailed
+			status.Error = err.Error()
+		}
+		put(status)
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatReportsProgress(t *testing.T) {
+	store := NewMemoryRunStore()
+	requests := 0
+	release := make(chan struct{})
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			writeToolCall(w, "test-model", "test_tool", "{}")
+			return
+		}
+		// Hold the second request open so heartbeats are written mid-run
+		<-release
+		writeCompletion(w, "test-model", "done")
+	}, WithTools([]Tool{MockTool{name: "test_tool"}}), WithHeartbeat(HeartbeatPolicy{Store: store, Interval: 10 * time.Millisecond}))
+
+	done := make(chan error)
+	go func() {
+		_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunID("run-1"))
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		status, ok, err := testAgent.Status(context.Background(), "run-1")
+		return err == nil && ok && status.Iteration == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status, _, err := testAgent.Status(context.Background(), "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, RunStateRunning, status.State)
+	assert.Equal(t, "test_tool", status.LastTool)
+	assert.Equal(t, int64(2), status.Usage.TotalTokens)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	status, ok, err := testAgent.Status(context.Background(), "run-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, RunStateFinished, status.State)
+	assert.Equal(t, int64(4), status.Usage.TotalTokens)
+	assert.False(t, status.UpdatedAt.Before(status.StartedAt))
+}
+
+func TestHeartbeatRecordsFailure(t *testing.T) {
+	store := NewMemoryRunStore()
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}, WithHeartbeat(HeartbeatPolicy{Store: store, Interval: time.Hour}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunID("run-2"))
+	require.Error(t, err)
+
+	status, ok, err := testAgent.Status(context.Background(), "run-2")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, RunStateFailed, status.State)
+	assert.NotEmpty(t, status.Error)
+
+	// Runs without an ID are not tracked
+	_, _ = testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	_, ok, err = testAgent.Status(context.Background(), "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStatusWithoutHeartbeat(t *testing.T) {
+	_, _, err := NewAgent("test-key", "http://127.0.0.1:0", "test-model").Status(context.Background(), "run")
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Provider identifies a model provider family for provider-specific behavior
+type Provider string
+
+const (
+	ProviderOpenAI    Provider = "openai"
+	ProviderAnthropic Provider = "anthropic"
+)
+
+// ImageOptions controls how images are prepared for a request
+type ImageOptions struct {
+	// MaxDimension is the maximum width or height; larger images are scaled down
+	MaxDimension int
+	// MaxImages is the maximum number of images sent; above it, images are tiled into grids
+	MaxImages int
+}
+
+// ImageOptionsFor returns the image limits of a provider
+func ImageOptionsFor(provider Provider) ImageOptions {
+	switch provider {
+	case ProviderAnthropic:
+		return ImageOptions{MaxDimension: 1568, MaxImages: 20}
+	default:
+		return ImageOptions{MaxDimension: 2048, MaxImages: 10}
+	}
+}
+
+// PrepareImages scales images down to opts.MaxDimension and, when there are more
+// than opts.MaxImages, tiles them into grids so they fit in a single request
+func PrepareImages(images []Image, opts ImageOptions) ([]Image, error) {
+	if opts.MaxImages > 0 && len(images) > opts.MaxImages {
+		perTile := (len(images) + opts.MaxImages - 1) / opts.MaxImages
+		var tiled []Image
+		for start := 0; start < len(images); start += perTile {
+			tile, err := TileImages(images[start:min(start+perTile, len(images))], opts.MaxDimension)
+			if err != nil {
+				return nil, err
+			}
+			tiled = append(tiled, tile)
+		}
+		return tiled, nil
+	}
+
+	prepared := make([]Image, len(images))
+	for i, img := range images {
+		resized, err := ResizeImage(img, opts.MaxDimension)
+		if err != nil {
+			return nil, err
+		}
+		prepared[i] = resized
+	}
+	return prepared, nil
+}
+
+// ResizeImage scales an image down so neither side exceeds maxDimension,
+// preserving its aspect ratio. Images already within bounds are returned unchanged.
+func ResizeImage(img Image, maxDimension int) (Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return Image{}, fmt.Errorf("decode image %s: %w", img.Name, err)
+	}
+	if maxDimension <= 0 || (config.Width <= maxDimension && config.Height <= maxDimension) {
+		return img, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return Image{}, fmt.Errorf("decode image %s: %w", img.Name, err)
+	}
+	width, height := fitWithin(config.Width, config.Height, maxDimension)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	return encodeImage(img.Name, format, dst)
+}
+
+// TileImages combines images into a single grid image whose sides do not exceed
+// maxDimension. Each image is scaled to fit its cell and centered on a white background.
+func TileImages(images []Image, maxDimension int) (Image, error) {
+	if len(images) == 0 {
+		return Image{}, fmt.Errorf("no images to tile")
+	}
+	if maxDimension <= 0 {
+		maxDimension = ImageOptionsFor(ProviderOpenAI).MaxDimension
+	}
+
+	columns := int(math.Ceil(math.Sqrt(float64(len(images)))))
+	rows := (len(images) + columns - 1) / columns
+	cell := maxDimension / max(columns, rows)
+
+	dst := image.NewRGBA(image.Rect(0, 0, columns*cell, rows*cell))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	names := make([]string, len(images))
+	for i, img := range images {
+		src, _, err := image.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return Image{}, fmt.Errorf("decode image %s: %w", img.Name, err)
+		}
+		names[i] = img.Name
+
+		width, height := fitWithin(src.Bounds().Dx(), src.Bounds().Dy(), cell)
+		x := (i%columns)*cell + (cell-width)/2
+		y := (i/columns)*cell + (cell-height)/2
+		draw.CatmullRom.Scale(dst, image.Rect(x, y, x+width, y+height), src, src.Bounds(), draw.Over, nil)
+	}
+
+	return encodeImage(strings.Join(names, "+"), "png", dst)
+}
+
+// EstimateImageTokens estimates the prompt tokens an image costs with the given provider
+func EstimateImageTokens(img Image, provider Provider) (int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return 0, fmt.Errorf("decode image %s: %w", img.Name, err)
+	}
+
+	switch provider {
+	case ProviderAnthropic:
+		// Images are scaled to fit 1568px on the long edge, costing about width*height/750 tokens
+		width, height := fitWithin(config.Width, config.Height, 1568)
+		return (width*height + 749) / 750, nil
+	default:
+		// High detail: fit within 2048x2048, scale the short side to 768, then 170 tokens per 512px tile plus 85
+		width, height := fitWithin(config.Width, config.Height, 2048)
+		if short := min(width, height); short > 768 {
+			width = width * 768 / short
+			height = height * 768 / short
+		}
+		tiles := ((width + 511) / 512) * ((height + 511) / 512)
+		return 85 + 170*tiles, nil
+	}
+}
+
+// fitWithin scales width and height down to fit within a square of side limit
+func fitWithin(width int, height int, limit int) (int, int) {
+	if width <= limit && height <= limit {
+		return width, height
+	}
+	scale := float64(limit) / float64(max(width, height))
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
+
+// encodeImage encodes img as JPEG if the source was JPEG and as PNG otherwise
+func encodeImage(name string, format string, img image.Image) (Image, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Data: buf.Bytes(), Name: name}, nil
+}
+
+// imageMediaType detects the media type of image data, defaulting to PNG
+func imageMediaType(data []byte) string {
+	if mediaType := http.DetectContentType(data); strings.HasPrefix(mediaType, "image/") {
+		return mediaType
+	}
+	return "image/png"
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testImage creates a PNG image of the given size
+func testImage(t *testing.T, width int, height int) Image {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return Image{Data: buf.Bytes(), Name: "test.png"}
+}
+
+// imageSize decodes the dimensions of an image
+func imageSize(t *testing.T, img Image) (int, int) {
+	t.Helper()
+	config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	return config.Width, config.Height
+}
+
+func TestResizeImage(t *testing.T) {
+	resized, err := ResizeImage(testImage(t, 4000, 2000), 1000)
+	require.NoError(t, err)
+	width, height := imageSize(t, resized)
+	assert.Equal(t, 1000, width)
+	assert.Equal(t, 500, height)
+
+	small := testImage(t, 100, 100)
+	unchanged, err := ResizeImage(small, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, small, unchanged)
+}
+
+func TestPrepareImagesTiles(t *testing.T) {
+	images := []Image{
+		testImage(t, 300, 200),
+		testImage(t, 300, 200),
+		testImage(t, 300, 200),
+		testImage(t, 300, 200),
+		testImage(t, 300, 200),
+	}
+
+	prepared, err := PrepareImages(images, ImageOptions{MaxDimension: 800, MaxImages: 2})
+	require.NoError(t, err)
+	require.Len(t, prepared, 2)
+
+	width, height := imageSize(t, prepared[0])
+	assert.LessOrEqual(t, width, 800)
+	assert.LessOrEqual(t, height, 800)
+}
+
+func TestEstimateImageTokens(t *testing.T) {
+	img := testImage(t, 1024, 1024)
+
+	openAITokens, err := EstimateImageTokens(img, ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Equal(t, 765, openAITokens)
+
+	anthropicTokens, err := EstimateImageTokens(img, ProviderAnthropic)
+	require.NoError(t, err)
+	assert.Equal(t, 1399, anthropicTokens)
+}
+
+func TestUserImagesMessage(t *testing.T) {
+	msg := UserImagesMessage(testImage(t, 10, 10), testImage(t, 20, 20))
+
+	assert.Len(t, msg.Images(), 2)
+	assert.Equal(t, msg.Images()[0], msg.Image())
+
+	converted := convertMessages([]Message{msg})
+	require.Len(t, converted, 1)
+	parts := converted[0].OfUser.Content.OfArrayOfContentParts
+	require.Len(t, parts, 2)
+	assert.Contains(t, parts[0].OfImageURL.ImageURL.URL, "data:image/png;base64,")
+}
@@ -0,0 +1,154 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Authorization")
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Echo messages back until the client closes
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, err := Dial(context.Background(), url, http.Header{"Authorization": {"Bearer key"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer key", gotHeader)
+
+	messages := []struct {
+		messageType int
+		data        []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, bytes.Repeat([]byte{1, 2, 3}, 1000)},
+		{BinaryMessage, bytes.Repeat([]byte{4}, 70000)},
+		{TextMessage, []byte{}},
+	}
+	for _, m := range messages {
+		require.NoError(t, conn.WriteMessage(m.messageType, m.data))
+		messageType, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, m.messageType, messageType)
+		assert.Equal(t, m.data, data)
+	}
+
+	// Pings are answered while reading, fragments are joined
+	pongs := 0
+	conn.SetPongHandler(func() { pongs++ })
+	require.NoError(t, conn.Ping([]byte("ping")))
+	conn.writeMu.Lock()
+	require.NoError(t, conn.writeFragment(TextMessage, false, []byte("frag")))
+	require.NoError(t, conn.writeFragment(0, true, []byte("ment")))
+	conn.writeMu.Unlock()
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "fragment", string(data))
+	assert.Equal(t, 1, pongs)
+
+	require.NoError(t, conn.Close())
+	assert.Error(t, conn.WriteMessage(TextMessage, []byte("late")))
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := Upgrade(w, r)
+		assert.Error(t, err)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, err = Dial(context.Background(), server.URL, nil)
+	assert.ErrorContains(t, err, "unsupported scheme")
+}
+
+func TestCloseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		conn.closeWith(CloseGoingAway, "restarting")
+	}))
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseGoingAway, closeErr.Code)
+	assert.Equal(t, "restarting", closeErr.Reason)
+}
+
+func TestReadRejectsInvalidFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode int
+		fin    bool
+		data   []byte
+		// unmasked sends the frame without the mask clients must apply
+		unmasked bool
+		err      string
+	}{
+		{name: "unmasked client frame", opcode: TextMessage, fin: true, data: []byte("hi"), unmasked: true, err: "unmasked frame from client"},
+		{name: "fragmented control frame", opcode: pingMessage, data: []byte("ping"), err: "invalid control frame"},
+		{name: "oversized control frame", opcode: pingMessage, fin: true, data: bytes.Repeat([]byte{1}, 126), err: "invalid control frame"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverErr := make(chan error, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := Upgrade(w, r)
+				if err != nil {
+					return
+				}
+				_, _, err = conn.ReadMessage()
+				serverErr <- err
+			}))
+			defer server.Close()
+
+			conn, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			require.NoError(t, err)
+			conn.writeMu.Lock()
+			conn.client = !tt.unmasked
+			require.NoError(t, conn.writeFragment(tt.opcode, tt.fin, tt.data))
+			conn.client = true
+			conn.writeMu.Unlock()
+
+			assert.ErrorContains(t, <-serverErr, tt.err)
+			_, _, err = conn.ReadMessage()
+			var closeErr *CloseError
+			require.ErrorAs(t, err, &closeErr)
+			assert.Equal(t, CloseProtocolError, closeErr.Code)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// responseJSON is the wire form of a Response
+type responseJSON struct {
+	Kind        ResponseKind `json:"kind"`
+	Source      string       `json:"source,omitempty"`
+	RunID       string       `json:"run_id,omitempty"`
+	ParentRunID string       `json:"parent_run_id,omitempty"`
+	Iteration   int          `json:"iteration,omitempty"`
+	Time        *time.Time   `json:"time,omitempty"`
+	Content     string       `json:"content,omitempty"`
+	// Degraded marks a fallback reply sent because the provider was unavailable
+	Degraded   bool              `json:"degraded,omitempty"`
+	Usage      *Usage            `json:"usage,omitempty"`
+	Progress   *ToolProgress     `json:"progress,omitempty"`
+	Warning    string            `json:"warning,omitempty"`
+	Audio      *AudioChunk       `json:"audio,omitempty"`
+	ToolCall   *ToolCallRecord   `json:"tool_call,omitempty"`
+	Moderation *ModerationResult `json:"moderation,omitempty"`
+	Draft      *Draft            `json:"draft,omitempty"`
+	Debug      *WireExchange     `json:"debug,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the response as an object with a kind and the field for that
+// kind: {"kind":"content","content":"..."}, {"kind":"usage","usage":{...}},
+// {"kind":"progress","progress":{...}}, {"kind":"warning","warning":"..."},
+// {"kind":"audio","audio":{"format":"mp3","data":"<base64>"}},
+// {"kind":"tool_call","tool_call":{"call":{...},"result":"..."}},
+// {"kind":"moderation","moderation":{"source":"input",...}},
+// {"kind":"draft","draft":{"revision":1,...}},
+// {"kind":"debug","debug":{"method":"POST",...}}, or
+// {"kind":"error","error":"..."}. A merged stream's responses also carry their
+// "source", and responses of a run its "run_id", "parent_run_id", "iteration", and "time".
+func (r Response) MarshalJSON() ([]byte, error) {
+	v := responseJSON{Kind: r.Kind, Source: r.Source, RunID: r.RunID, ParentRunID: r.ParentRunID, Iteration: r.Iteration}
+	if !r.Time.IsZero() {
+		v.Time = &r.Time
+	}
+	switch r.Kind {
+	case ResponseKindContent:
+		v.Content = r.content
+		v.Degraded = r.degraded
+	case ResponseKindUsage:
+		v.Usage = &r.usage
+	case ResponseKindProgress:
+		v.Progress = &r.progress
+	case ResponseKindWarning:
+		v.Warning = r.warning
+	case ResponseKindAudio:
+		v.Audio = &r.audio
+	case ResponseKindToolCall:
+		v.ToolCall = &r.toolCall
+	case ResponseKindModeration:
+		v.Moderation = &r.moderation
+	case ResponseKindDraft:
+		v.Draft = &r.draft
+	case ResponseKindDebug:
+		v.Debug = &r.exchange
+	case ResponseKindError:
+		v.Error = "unknown error"
+		if r.err != nil {
+			v.Error = r.err.Error()
+		}
+	}
+	// Keep content such as HTML or code readable in logs
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// UnmarshalJSON decodes a response encoded by MarshalJSON. Errors are restored as
+// plain errors carrying the original message.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var v responseJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v.Kind {
+	case ResponseKindContent:
+		*r = NewContentResponse(v.Content)
+		r.degraded = v.Degraded
+	case ResponseKindUsage:
+		if v.Usage == nil {
+			return errors.New("usage response without usage")
+		}
+		*r = NewUsageResponse(*v.Usage)
+	case ResponseKindProgress:
+		if v.Progress == nil {
+			return errors.New("progress response without progress")
+		}
+		*r = NewProgressResponse(*v.Progress)
+	case ResponseKindWarning:
+		*r = NewWarningResponse(v.Warning)
+	case ResponseKindAudio:
+		if v.Audio == nil {
+			return errors.New("audio response without audio")
+		}
+		*r = NewAudioResponse(*v.Audio)
+	case ResponseKindToolCall:
+		if v.ToolCall == nil {
+			return errors.New("tool call response without tool call")
+		}
+		*r = NewToolCallResponse(*v.ToolCall)
+	case ResponseKindModeration:
+		if v.Moderation == nil {
+			return errors.New("moderation response without result")
+		}
+		*r = NewModerationResponse(*v.Moderation)
+	case ResponseKindDraft:
+		if v.Draft == nil {
+			return errors.New("draft response without draft")
+		}
+		*r = NewDraftResponse(*v.Draft)
+	case ResponseKindDebug:
+		if v.Debug == nil {
+			return errors.New("debug response without exchange")
+		}
+		*r = NewDebugResponse(*v.Debug)
+	case ResponseKindError:
+		*r = NewErrorResponse(errors.New(v.Error))
+	default:
+		return fmt.Errorf("unknown response kind %q", v.Kind)
+	}
+	r.Source = v.Source
+	r.RunID, r.ParentRunID, r.Iteration = v.RunID, v.ParentRunID, v.Iteration
+	if v.Time != nil {
+		r.Time = *v.Time
+	}
+	return nil
+}
+
+// WriteJSONLines writes each response as one line of JSON to w and returns the
+// collected Completion. If w implements http.Flusher it is flushed after each line.
+// A run error is written as an error line before it is returned.
+func WriteJSONLines(w io.Writer, responses <-chan Response) (Completion, error) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	var completion Completion
+	var writeErr error
+
+	for response := range responses {
+		completion.add(response)
+		// Keep draining the channel after a write failure so the run can finish
+		if writeErr == nil {
+			if writeErr = encoder.Encode(response); writeErr == nil {
+				if flusher, ok := w.(http.Flusher); ok {
+					flusher.Flush()
+				}
+			}
+		}
+		if response.IsErrorResponse() {
+			return completion, response.Error()
+		}
+	}
+
+	if writeErr != nil {
+		return completion, writeErr
+	}
+
+	return completion, nil
+}
+
+// StreamJSONLines runs the conversation, writing each response to w as a line of
+// JSON for shell pipelines and log processors, and returns the collected Completion
+func (agent *Agent) StreamJSONLines(
+	ctx context.Context,
+	messages []Message,
+	w io.Writer,
+	opts ...RunOption,
+) (Completion, error) {
+	responseChan, err := agent.StreamChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return Completion{}, err
+	}
+	return WriteJSONLines(w, responseChan)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseJSON(t *testing.T) {
+	tests := []struct {
+		response Response
+		want     string
+	}{
+		{response: NewContentResponse("<b>hi</b>"), want: `{"kind":"content","content":"<b>hi</b>"}`},
+		{response: NewUsageResponse(Usage{Model: "gpt-4o", PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}), want: `{"kind":"usage","usage":{"model":"gpt-4o","prompt_tokens":3,"cached_prompt_tokens":0,"completion_tokens":2,"total_tokens":5}}`},
+		{response: NewErrorResponse(errors.New("boom")), want: `{"kind":"error","error":"boom"}`},
+		{response: Response{Kind: ResponseKindContent, Source: "supervisor/writer", content: "hi"}, want: `{"kind":"content","source":"supervisor/writer","content":"hi"}`},
+		{response: Response{Kind: ResponseKindContent, content: "sorry", degraded: true}, want: `{"kind":"content","content":"sorry","degraded":true}`},
+		{response: NewProgressResponse(ToolProgress{Tool: "download", CallID: "call_1", Message: "retrying", Elapsed: time.Second}), want: `{"kind":"progress","progress":{"tool":"download","call_id":"call_1","message":"retrying","elapsed":1000000000}}`},
+		{response: NewAudioResponse(AudioChunk{Format: "mp3", Data: []byte("mp3")}), want: `{"kind":"audio","audio":{"format":"mp3","data":"bXAz"}}`},
+		{response: NewWarningResponse("tool search timed out after 1s"), want: `{"kind":"warning","warning":"tool search timed out after 1s"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.response.Kind), func(t *testing.T) {
+			data, err := json.Marshal(tt.response)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+
+			var decoded Response
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, tt.response.Kind, decoded.Kind)
+			assert.Equal(t, tt.response.Source, decoded.Source)
+			assert.Equal(t, tt.response.IsDegraded(), decoded.IsDegraded())
+			assert.Equal(t, tt.response.Content(), decoded.Content())
+			assert.Equal(t, tt.response.Usage(), decoded.Usage())
+			assert.Equal(t, tt.response.Progress(), decoded.Progress())
+			assert.Equal(t, tt.response.Warning(), decoded.Warning())
+			assert.Equal(t, tt.response.Audio(), decoded.Audio())
+			if tt.response.IsErrorResponse() {
+				assert.EqualError(t, decoded.Error(), tt.response.Error().Error())
+			}
+		})
+	}
+
+	var decoded Response
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"kind":"unknown"}`), &decoded), "unknown response kind")
+}
+
+func TestStreamJSONLines(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "Hello <world>")
+	})
+
+	var out bytes.Buffer
+	completion, err := testAgent.StreamJSONLines(context.Background(), []Message{UserTextMessage("Hi")}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello <world>"}, completion.Messages)
+
+	var kinds []ResponseKind
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var response Response
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &response), scanner.Text())
+		kinds = append(kinds, response.Kind)
+		if response.IsContentResponse() {
+			assert.Contains(t, scanner.Text(), "<world>", "HTML should not be escaped")
+		}
+	}
+	assert.Equal(t, []ResponseKind{ResponseKindUsage, ResponseKindContent}, kinds)
+}
+
+func TestWriteJSONLinesError(t *testing.T) {
+	responses := make(chan Response, 2)
+	responses <- NewContentResponse("partial")
+	responses <- NewErrorResponse(errors.New("provider failed"))
+	close(responses)
+
+	var out bytes.Buffer
+	completion, err := WriteJSONLines(&out, responses)
+	assert.EqualError(t, err, "provider failed")
+	assert.Equal(t, []string{"partial"}, completion.Messages)
+	assert.Equal(t, "{\"kind\":\"content\",\"content\":\"partial\"}\n{\"kind\":\"error\",\"error\":\"provider failed\"}\n", out.String())
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrKilled is matched by every KilledError via errors.Is
+var ErrKilled = errors.New("run killed")
+
+// KilledError is the terminal error of a run halted by a KillSwitch. It is
+// delivered as the final error response of the stream.
+type KilledError struct {
+	Reason string
+}
+
+func (e *KilledError) Error() string {
+	return fmt.Sprintf("run killed: %s", e.Reason)
+}
+
+func (e *KilledError) Is(target error) bool {
+	return target == ErrKilled
+}
+
+// KillSwitch halts every in-flight run it is attached to. A single switch can be
+// shared across agents (WithKillSwitch) or attached to individual runs
+// (WithRunKillSwitch). It is safe for concurrent use.
+type KillSwitch struct {
+	mu     sync.Mutex
+	done   chan struct{}
+	reason string
+}
+
+// NewKillSwitch creates an armed KillSwitch
+func NewKillSwitch() *KillSwitch {
+	return &KillSwitch{done: make(chan struct{})}
+}
+
+// Kill halts all runs using the switch and rejects new ones until Reset
+func (k *KillSwitch) Kill(reason string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	select {
+	case <-k.done:
+	default:
+		k.reason = reason
+		close(k.done)
+	}
+}
+
+// Reset re-arms a killed switch so new runs can start
+func (k *KillSwitch) Reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	select {
+	case <-k.done:
+		k.done = make(chan struct{})
+		k.reason = ""
+	default:
+	}
+}
+
+// Killed reports whether the switch has been killed, and why
+func (k *KillSwitch) Killed() (bool, string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	select {
+	case <-k.done:
+		return true, k.reason
+	default:
+		return false, ""
+	}
+}
+
+// Done returns a channel that is closed when the switch is killed
+func (k *KillSwitch) Done() <-chan struct{} {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.done
+}
+
+// WithKillSwitch attaches a kill switch to every run of the agent
+func WithKillSwitch(killSwitch *KillSwitch) AgentOption {
+	return func(a *Agent) {
+		a.killSwitch = killSwitch
+	}
+}
+
+// WithRunKillSwitch attaches a kill switch to a single run
+func WithRunKillSwitch(killSwitch *KillSwitch) RunOption {
+	return func(o *runOptions) {
+		o.killSwitch = killSwitch
+	}
+}
+
+// killSwitches returns the switches that apply to a run
+func (agent *Agent) killSwitches(options runOptions) []*KillSwitch {
+	var switches []*KillSwitch
+	for _, k := range []*KillSwitch{agent.killSwitch, options.killSwitch} {
+		if k != nil {
+			switches = append(switches, k)
+		}
+	}
+	return switches
+}
+
+// checkKilled returns a KilledError if any of the switches has been killed
+func checkKilled(switches []*KillSwitch) error {
+	for _, k := range switches {
+		if killed, reason := k.Killed(); killed {
+			return &KilledError{Reason: reason}
+		}
+	}
+	return nil
+}
+
+// watchKillSwitches cancels the run context as soon as any switch is killed.
+// The returned function stops watching.
+func watchKillSwitches(ctx context.Context, cancel context.CancelFunc, switches []*KillSwitch) func() {
+	stop := make(chan struct{})
+	for _, k := range switches {
+		go func(done <-chan struct{}) {
+			select {
+			case <-done:
+				cancel()
+			case <-ctx.Done():
+			case <-stop:
+			}
+		}(k.Done())
+	}
+	return func() { close(stop) }
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKillSwitchHaltsInFlightRun(t *testing.T) {
+	killSwitch := NewKillSwitch()
+	requested := make(chan struct{})
+	release := make(chan struct{})
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		close(requested)
+		<-release
+	}, WithKillSwitch(killSwitch))
+	t.Cleanup(func() { close(release) })
+
+	go func() {
+		<-requested
+		killSwitch.Kill("incident 42")
+	}()
+
+	done := make(chan error)
+	go func() {
+		_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		var killedErr *KilledError
+		require.ErrorAs(t, err, &killedErr)
+		assert.Equal(t, "incident 42", killedErr.Reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not halted")
+	}
+}
+
+func TestKillSwitchRejectsNewRuns(t *testing.T) {
+	killSwitch := NewKillSwitch()
+	killSwitch.Kill("maintenance")
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "test-model", "hello")
+	})
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunKillSwitch(killSwitch))
+	assert.ErrorIs(t, err, ErrKilled)
+
+	killSwitch.Reset()
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunKillSwitch(killSwitch))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, completion.Messages)
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is matched by every LimitError via errors.Is
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// LimitKind identifies which limit was exceeded
+type LimitKind string
+
+const (
+	LimitKindMessages     LimitKind = "messages"
+	LimitKindMessageBytes LimitKind = "message_bytes"
+	LimitKindAttachments  LimitKind = "attachments"
+)
+
+// Limits bounds the input accepted by an Agent before it reaches the provider.
+// A zero value for any field disables that check.
+type Limits struct {
+	// MaxMessages is the maximum number of messages in a single request
+	MaxMessages int
+	// MaxMessageBytes is the maximum size of a single message's text or attachment data
+	MaxMessageBytes int
+	// MaxAttachments is the maximum number of files and images in a single message
+	MaxAttachments int
+}
+
+// LimitError is returned when a request violates the configured Limits
+type LimitError struct {
+	Kind   LimitKind
+	Max    int
+	Actual int
+	// Index is the offending message index, or -1 for request-wide limits
+	Index int
+}
+
+func (e *LimitError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s limit exceeded: %d > %d", e.Kind, e.Actual, e.Max)
+	}
+	return fmt.Sprintf("%s limit exceeded for message %d: %d > %d", e.Kind, e.Index, e.Actual, e.Max)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// WithLimits sets the input limits enforced by the agent
+func WithLimits(limits Limits) AgentOption {
+	return func(a *Agent) {
+		a.limits = limits
+	}
+}
+
+// Check validates messages against the limits
+func (l Limits) Check(messages []Message) error {
+	if l.MaxMessages > 0 && len(messages) > l.MaxMessages {
+		return &LimitError{Kind: LimitKindMessages, Max: l.MaxMessages, Actual: len(messages), Index: -1}
+	}
+	for i, msg := range messages {
+		if l.MaxMessageBytes > 0 {
+			if size := messageSize(msg); size > l.MaxMessageBytes {
+				return &LimitError{Kind: LimitKindMessageBytes, Max: l.MaxMessageBytes, Actual: size, Index: i}
+			}
+		}
+		if l.MaxAttachments > 0 {
+			if count := attachmentCount(msg); count > l.MaxAttachments {
+				return &LimitError{Kind: LimitKindAttachments, Max: l.MaxAttachments, Actual: count, Index: i}
+			}
+		}
+	}
+	return nil
+}
+
+// messageSize returns the payload size of a message in bytes
+func messageSize(msg Message) int {
+	switch msg.Kind() {
+	case MessageKindFile:
+		return len(msg.File().Data)
+	case MessageKindImage:
+		size := 0
+		for _, image := range msg.Images() {
+			size += len(image.Data)
+		}
+		return size
+	default:
+		return len(msg.Text())
+	}
+}
+
+// attachmentCount returns the number of files and images carried by a message
+func attachmentCount(msg Message) int {
+	switch msg.Kind() {
+	case MessageKindFile:
+		return 1
+	case MessageKindImage:
+		return len(msg.Images())
+	default:
+		return 0
+	}
+}
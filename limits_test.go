@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   Limits
+		messages []Message
+		kind     LimitKind
+		index    int
+	}{
+		{
+			name:     "no limits",
+			limits:   Limits{},
+			messages: []Message{UserTextMessage("hello"), UserTextMessage("world")},
+		},
+		{
+			name:     "too many messages",
+			limits:   Limits{MaxMessages: 1},
+			messages: []Message{UserTextMessage("hello"), UserTextMessage("world")},
+			kind:     LimitKindMessages,
+			index:    -1,
+		},
+		{
+			name:     "message too large",
+			limits:   Limits{MaxMessageBytes: 4},
+			messages: []Message{UserTextMessage("hi"), UserTextMessage("hello")},
+			kind:     LimitKindMessageBytes,
+			index:    1,
+		},
+		{
+			name:     "attachment too large",
+			limits:   Limits{MaxMessageBytes: 4},
+			messages: []Message{UserFileMessage(File{Data: []byte("12345"), Name: "a.txt"})},
+			kind:     LimitKindMessageBytes,
+			index:    0,
+		},
+		{
+			name:     "within limits",
+			limits:   Limits{MaxMessages: 2, MaxMessageBytes: 5, MaxAttachments: 1},
+			messages: []Message{UserTextMessage("hello"), UserImageMessage(Image{Data: []byte("png")})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(tt.messages)
+			if tt.kind == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var limitErr *LimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tt.kind, limitErr.Kind)
+			assert.Equal(t, tt.index, limitErr.Index)
+			assert.True(t, errors.Is(err, ErrLimitExceeded))
+		})
+	}
+}
+
+func TestStreamChatCompletionEnforcesLimits(t *testing.T) {
+	testAgent := NewAgent("test-key", "http://127.0.0.1:0", "test-model", WithLimits(Limits{MaxMessages: 1}))
+
+	responseChan, err := testAgent.StreamChatCompletion(context.Background(), []Message{
+		UserTextMessage("one"),
+		UserTextMessage("two"),
+	})
+
+	assert.Nil(t, responseChan)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+}
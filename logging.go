@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+)
+
+// WithLogger sets the structured logger for requests, responses, tool calls,
+// fallbacks, and iterations. Logs are discarded by default. Records logged
+// during a run carry its run_id, parent_run_id, and iteration.
+func WithLogger(logger *slog.Logger) AgentOption {
+	return func(a *Agent) {
+		a.logger = slog.New(runLogHandler{logger.Handler()})
+	}
+}
+
+// runLogHandler adds the run a record is logged in to the record
+type runLogHandler struct {
+	slog.Handler
+}
+
+func (h runLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if run, ok := RunInfoFromContext(ctx); ok {
+		record.AddAttrs(slog.String("run_id", run.ID))
+		if run.ParentID != "" {
+			record.AddAttrs(slog.String("parent_run_id", run.ParentID))
+		}
+		if run.Iteration > 0 {
+			record.AddAttrs(slog.Int("iteration", run.Iteration))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h runLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return runLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h runLogHandler) WithGroup(name string) slog.Handler {
+	return runLogHandler{h.Handler.WithGroup(name)}
+}
+
+// WithLogContent controls whether message content and tool arguments are included
+// in logs. Content is redacted by default; when included, API keys and bearer
+// tokens in the content are still masked.
+func WithLogContent(include bool) AgentOption {
+	return func(a *Agent) {
+		a.logContent = include
+	}
+}
+
+// secretPattern matches common API key and bearer token formats
+var secretPattern = regexp.MustCompile(`(sk-[A-Za-z0-9_-]{8,}|(?i:bearer)\s+[A-Za-z0-9._~+/=-]{8,})`)
+
+// redactSecrets masks API keys and bearer tokens in s
+func redactSecrets(s string) string {
+	return secretPattern.ReplaceAllString(s, "[REDACTED]")
+}
+
+// contentAttr returns a log attribute for content, honoring the content redaction setting
+func (agent *Agent) contentAttr(key string, content string) slog.Attr {
+	if !agent.logContent {
+		return slog.Int(key+"_bytes", len(content))
+	}
+	return slog.String(key, redactSecrets(content))
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogging(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []AgentOption
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "content redacted by default",
+			contains: []string{"agent request", "agent response", "agent run finished", "content_bytes"},
+			excludes: []string{"sk-abcdefghijklmnop", "[REDACTED]"},
+		},
+		{
+			name:     "content included with secrets masked",
+			opts:     []AgentOption{WithLogContent(true)},
+			contains: []string{"your key is [REDACTED]"},
+			excludes: []string{"sk-abcdefghijklmnop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+				writeCompletion(w, "test-model", "your key is sk-abcdefghijklmnop")
+			}, append(tt.opts, WithLogger(logger))...)
+
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+			require.NoError(t, err)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, buf.String(), s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, buf.String(), s)
+			}
+		})
+	}
+}
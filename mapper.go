@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// MapOption is a functional option for configuring Map
+type MapOption func(*mapOptions)
+
+type mapOptions struct {
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	runOptions  []RunOption
+}
+
+// WithMapConcurrency sets how many inputs run at once, 4 by default
+func WithMapConcurrency(n int) MapOption {
+	return func(o *mapOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithItemRetries retries an input up to n times when its run fails on a
+// rate limit, a server error, a first token timeout, or a transport error
+func WithItemRetries(n int) MapOption {
+	return func(o *mapOptions) {
+		o.retries = n
+	}
+}
+
+// WithRetryBackoff sets the delay before an input's first retry, 1s by
+// default. It doubles with each further retry.
+func WithRetryBackoff(d time.Duration) MapOption {
+	return func(o *mapOptions) {
+		o.backoff = d
+	}
+}
+
+// WithMapRunOptions applies run options to every input's run
+func WithMapRunOptions(opts ...RunOption) MapOption {
+	return func(o *mapOptions) {
+		o.runOptions = append(o.runOptions, opts...)
+	}
+}
+
+// MapItem is the outcome of one input of Map
+type MapItem struct {
+	// Index is the input's position in the inputs
+	Index      int
+	Completion Completion
+	Err        error
+	// Attempts counts the runs made for the input, including retries
+	Attempts int
+}
+
+// MapResult is the outcome of Map
+type MapResult struct {
+	// Items holds one item per input, in input order
+	Items []MapItem
+	// Usage is the total across every run, including failed attempts
+	Usage Usage
+	// Failed counts the items whose last attempt failed
+	Failed int
+}
+
+// Map runs an independent conversation for each input message on a bounded
+// pool of workers, for bulk jobs such as classifying or extracting from many
+// documents. Failed runs are retried with exponential backoff as set with
+// WithItemRetries. When the provider rate limits a run and says when to retry,
+// every worker waits until then before starting another run, so the pool backs
+// off as a whole instead of each input spending its retries.
+//
+// Map returns every item even when some fail; the error then reports how many
+// failed and the first failure. If ctx is canceled, inputs not yet started
+// fail with its error.
+func Map(ctx context.Context, a ChatAgent, inputs []Message, opts ...MapOption) (MapResult, error) {
+	options := mapOptions{concurrency: 4, backoff: time.Second}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	result := MapResult{Items: make([]MapItem, len(inputs))}
+	gate := &rateLimitGate{}
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range max(options.concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				item, usage := mapItem(ctx, a, inputs[i], options, gate)
+				item.Index = i
+				mu.Lock()
+				result.Items[i] = item
+				result.Usage = result.Usage.Add(usage)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range inputs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var first error
+	for _, item := range result.Items {
+		if item.Err != nil {
+			result.Failed++
+			if first == nil {
+				first = fmt.Errorf("input %d: %w", item.Index, item.Err)
+			}
+		}
+	}
+	if first != nil {
+		return result, fmt.Errorf("%d of %d inputs failed, first: %w", result.Failed, len(inputs), first)
+	}
+	return result, nil
+}
+
+// mapItem runs one input, retrying as the options allow, and returns it with
+// the usage of every attempt
+func mapItem(ctx context.Context, a ChatAgent, input Message, options mapOptions, gate *rateLimitGate) (MapItem, Usage) {
+	var item MapItem
+	var usage Usage
+	backoff := options.backoff
+	for {
+		if err := gate.wait(ctx); err != nil {
+			item.Err = err
+			return item, usage
+		}
+		item.Attempts++
+		item.Completion, item.Err = a.ChatCompletion(ctx, []Message{input}, options.runOptions...)
+		usage = usage.Add(item.Completion.Usage)
+		if item.Err == nil || item.Attempts > options.retries || !retryableRun(ctx, item.Err) {
+			return item, usage
+		}
+
+		var rateLimitErr *RateLimitError
+		if errors.As(item.Err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			gate.pause(rateLimitErr.RetryAfter)
+			continue
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			item.Err = context.Cause(ctx)
+			return item, usage
+		}
+		backoff *= 2
+	}
+}
+
+// retryableRun reports whether a failed run may succeed if run again
+func retryableRun(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var rateLimitErr *RateLimitError
+	var timeoutErr *FirstTokenTimeoutError
+	var apiErr *openai.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &timeoutErr), errors.As(err, &netErr):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	default:
+		return false
+	}
+}
+
+// rateLimitGate holds back every worker of a Map until a rate limit has passed
+type rateLimitGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// pause holds back the workers for d, unless they are already held back longer
+func (g *rateLimitGate) pause(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := time.Now().Add(d); until.After(g.until) {
+		g.until = until
+	}
+}
+
+// wait returns once the gate is open or ctx is done
+func (g *rateLimitGate) wait(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		wait := time.Until(g.until)
+		g.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return context.Cause(ctx)
+		}
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
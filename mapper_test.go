@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lastUserContent decodes a request and returns the content of its last message
+func lastUserContent(t *testing.T, r *http.Request) string {
+	t.Helper()
+	var body struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	return body.Messages[len(body.Messages)-1].Content
+}
+
+func TestMap(t *testing.T) {
+	var inflight, peak atomic.Int32
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		content := lastUserContent(t, r)
+		time.Sleep(10 * time.Millisecond)
+		writeCompletion(w, "gpt-4o", strings.ToUpper(content))
+	})
+
+	var inputs []Message
+	for i := range 10 {
+		inputs = append(inputs, UserTextMessage(fmt.Sprintf("item %d", i)))
+	}
+	result, err := Map(context.Background(), testAgent, inputs, WithMapConcurrency(3))
+
+	require.NoError(t, err)
+	require.Len(t, result.Items, 10)
+	for i, item := range result.Items {
+		assert.Equal(t, i, item.Index)
+		assert.Equal(t, 1, item.Attempts)
+		assert.Equal(t, []string{fmt.Sprintf("ITEM %d", i)}, item.Completion.Messages)
+	}
+	assert.Equal(t, int64(20), result.Usage.TotalTokens)
+	assert.Zero(t, result.Failed)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+}
+
+func TestMapRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		content := lastUserContent(t, r)
+		mu.Lock()
+		attempts[content]++
+		n := attempts[content]
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case content == "flaky" && n == 1:
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, `{"error":{"message":"bad gateway","type":"server_error"}}`)
+		case content == "invalid":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"invalid","type":"invalid_request_error"}}`)
+		default:
+			writeCompletion(w, "gpt-4o", "ok")
+		}
+	})
+
+	result, err := Map(context.Background(), testAgent,
+		[]Message{UserTextMessage("flaky"), UserTextMessage("invalid"), UserTextMessage("fine")},
+		WithItemRetries(2), WithRetryBackoff(time.Millisecond))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 inputs failed")
+	assert.Contains(t, err.Error(), "input 1")
+	assert.Equal(t, 1, result.Failed)
+
+	assert.Equal(t, 2, result.Items[0].Attempts)
+	assert.NoError(t, result.Items[0].Err)
+	assert.Equal(t, []string{"ok"}, result.Items[0].Completion.Messages)
+	// Client errors are not retried
+	assert.Equal(t, 1, result.Items[1].Attempts)
+	assert.Error(t, result.Items[1].Err)
+	assert.Equal(t, 1, result.Items[2].Attempts)
+}
+
+func TestMapRateLimitPausesPool(t *testing.T) {
+	var limited atomic.Bool
+	var mu sync.Mutex
+	var times []time.Time
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		content := lastUserContent(t, r)
+		if content == "first" && limited.CompareAndSwap(false, true) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After-Ms", "100")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"slow down","type":"rate_limit_error"}}`)
+			return
+		}
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		writeCompletion(w, "gpt-4o", "ok")
+	})
+
+	start := time.Now()
+	result, err := Map(context.Background(), testAgent,
+		[]Message{UserTextMessage("first"), UserTextMessage("second"), UserTextMessage("third")},
+		WithMapConcurrency(1), WithItemRetries(1))
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Items[0].Attempts)
+	require.Len(t, times, 3)
+	for _, at := range times {
+		assert.GreaterOrEqual(t, at.Sub(start), 100*time.Millisecond)
+	}
+}
+
+func TestMapCanceled(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "ok")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := Map(ctx, testAgent, []Message{UserTextMessage("a"), UserTextMessage("b")})
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, result.Failed)
+	assert.Zero(t, result.Items[0].Attempts)
+}
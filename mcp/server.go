@@ -0,0 +1,387 @@
+// Package mcp serves tools written against this module's Tool interface over
+// the Model Context Protocol (MCP), so desktop clients and other MCP hosts can
+// call them.
+//
+// A Server exposes a ToolRegistry over stdio, for clients that launch the
+// server as a subprocess, or over HTTP:
+//
+//	registry := agent.NewToolRegistry(tools...)
+//	server := mcp.NewServer(registry, mcp.WithServerInfo("orders", "1.0.0"))
+//	err := server.ServeStdio(ctx, os.Stdin, os.Stdout)
+//
+//	http.Handle("/mcp", server)
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// ProtocolVersion is the latest version of the MCP protocol implemented by this package
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the protocol versions a client may ask for, newest first
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// Option is a functional option for configuring a Server
+type Option func(*Server)
+
+// WithServerInfo sets the name and version the server reports to clients. The
+// default is "go-agents" "1.0.0".
+func WithServerInfo(name string, version string) Option {
+	return func(s *Server) {
+		s.info = Implementation{Name: name, Version: version}
+	}
+}
+
+// WithInstructions sets instructions on using the server's tools, which
+// clients may add to their model's prompt
+func WithInstructions(instructions string) Option {
+	return func(s *Server) {
+		s.instructions = instructions
+	}
+}
+
+// WithTenant serves the tools the registry offers to runs for tenant, instead
+// of those offered to runs without one
+func WithTenant(tenant string) Option {
+	return func(s *Server) {
+		s.tenant = tenant
+	}
+}
+
+// Server serves a registry's tools over MCP. The tools are resolved on every
+// request, so tools registered or unregistered while the server runs are
+// listed and callable right away.
+type Server struct {
+	registry     *agent.ToolRegistry
+	info         Implementation
+	instructions string
+	tenant       string
+}
+
+// NewServer creates a server for the tools in registry
+func NewServer(registry *agent.ToolRegistry, opts ...Option) *Server {
+	s := &Server{
+		registry: registry,
+		info:     Implementation{Name: "go-agents", Version: "1.0.0"},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ServeHTTP implements the Streamable HTTP transport without server-sent
+// events: each POSTed request is answered with a JSON response, and
+// notifications are accepted with no body. Servers reachable from a browser
+// should check the Origin header before calling it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, rpcResponse{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: "parse error: " + err.Error()}})
+		return
+	}
+	resp, ok := s.handle(r.Context(), req)
+	if !ok {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, resp rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ServeStdio implements the stdio transport, reading newline-delimited JSON-RPC
+// messages from in and writing responses to out. Requests are handled
+// concurrently, and a notifications/cancelled message cancels the named
+// request's tool call. ServeStdio returns when in is exhausted and every
+// request has been answered, or when ctx is done.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		writeMu  sync.Mutex
+		mu       sync.Mutex
+		inflight = map[string]context.CancelFunc{}
+		writeErr error
+	)
+	write := func(resp rpcResponse) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := out.Write(append(data, '\n')); err != nil && writeErr == nil {
+			writeErr = err
+			cancel()
+		}
+	}
+
+	lines := make(chan []byte)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- slices.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+
+	for {
+		var line []byte
+		var more bool
+		select {
+		case line, more = <-lines:
+		case <-ctx.Done():
+			more = false
+		}
+		if !more {
+			break
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			write(rpcResponse{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: "parse error: " + err.Error()}})
+			continue
+		}
+		if req.Method == "notifications/cancelled" {
+			var p CancelledParams
+			if json.Unmarshal(req.Params, &p) == nil {
+				mu.Lock()
+				if cancelRequest, ok := inflight[string(p.RequestID)]; ok {
+					cancelRequest()
+				}
+				mu.Unlock()
+			}
+			continue
+		}
+
+		reqCtx, cancelRequest := context.WithCancel(ctx)
+		id := string(req.ID)
+		if len(req.ID) > 0 {
+			mu.Lock()
+			inflight[id] = cancelRequest
+			mu.Unlock()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancelRequest()
+			resp, ok := s.handle(reqCtx, req)
+			if len(req.ID) > 0 {
+				mu.Lock()
+				delete(inflight, id)
+				mu.Unlock()
+			}
+			// Cancelled requests must not be answered
+			if ok && reqCtx.Err() == nil {
+				write(resp)
+			}
+		}()
+	}
+
+	wg.Wait()
+	if writeErr != nil {
+		return writeErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case err := <-scanErr:
+		return err
+	default:
+		return nil
+	}
+}
+
+// handle answers a JSON-RPC message, reporting false for notifications and
+// responses, which are not answered
+func (s *Server) handle(ctx context.Context, req rpcRequest) (rpcResponse, bool) {
+	if len(req.ID) == 0 || req.Method == "" {
+		return rpcResponse{}, false
+	}
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" {
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "invalid JSON-RPC request"}
+		return resp, true
+	}
+	resp.Result, resp.Error = s.call(ctx, req.Method, req.Params)
+	if resp.Error != nil {
+		resp.Result = nil
+	}
+	return resp, true
+}
+
+func (s *Server) call(ctx context.Context, method string, params json.RawMessage) (any, *Error) {
+	switch method {
+	case "initialize":
+		var p InitializeParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return s.initialize(p), nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(ctx), nil
+	case "tools/call":
+		var p CallToolParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return s.callTool(ctx, p)
+	default:
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+	}
+}
+
+// initialize agrees on the version the client asked for if it is supported,
+// and otherwise offers the latest
+func (s *Server) initialize(p InitializeParams) InitializeResult {
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	return InitializeResult{
+		ProtocolVersion: version,
+		Capabilities:    ServerCapabilities{Tools: &ToolsCapability{}},
+		ServerInfo:      s.info,
+		Instructions:    s.instructions,
+	}
+}
+
+func (s *Server) listTools(ctx context.Context) ListToolsResult {
+	result := ListToolsResult{Tools: []Tool{}}
+	for _, tool := range s.registry.Resolve(ctx, s.tenant) {
+		result.Tools = append(result.Tools, describeTool(tool))
+	}
+	return result
+}
+
+func (s *Server) callTool(ctx context.Context, p CallToolParams) (*CallToolResult, *Error) {
+	tools := s.registry.Resolve(ctx, s.tenant)
+	i := slices.IndexFunc(tools, func(tool agent.Tool) bool { return tool.Name() == p.Name })
+	if i < 0 {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+	tool := tools[i]
+
+	arguments := p.Arguments
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+	output, err := tool.Execute(ctx, arguments)
+	if err != nil {
+		return &CallToolResult{Content: []Content{TextContent(err.Error())}, IsError: true}, nil
+	}
+	result, err := toolResult(output)
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: fmt.Sprintf("encode result of %s: %v", p.Name, err)}
+	}
+	return result, nil
+}
+
+// describeTool converts a Tool to an MCP tool, its parameters becoming an
+// object schema
+func describeTool(tool agent.Tool) Tool {
+	parameters := tool.Parameters()
+	schema := map[string]any{"type": "object", "properties": parameters.Properties}
+	if parameters.Properties == nil {
+		schema["properties"] = map[string]any{}
+	}
+	if len(parameters.Required) > 0 {
+		schema["required"] = parameters.Required
+	}
+	if t, ok := tool.(agent.ToolWithExamples); ok {
+		if examples := t.Examples(); len(examples) > 0 {
+			schema["examples"] = examples
+		}
+	}
+	return Tool{Name: tool.Name(), Description: tool.Description(), InputSchema: schema}
+}
+
+// toolResult converts a tool's output to MCP content. Strings are sent as
+// text, images and files of a ToolResult or Image as image and resource
+// content, and anything else as JSON text.
+func toolResult(output any) (*CallToolResult, error) {
+	var attachments agent.ToolResult
+	switch r := output.(type) {
+	case string:
+		return &CallToolResult{Content: []Content{TextContent(r)}}, nil
+	case agent.ToolResult:
+		attachments = r
+	case *agent.ToolResult:
+		if r != nil {
+			attachments = *r
+		}
+	case agent.Image:
+		attachments.Images = []agent.Image{r}
+	case *agent.Image:
+		if r != nil {
+			attachments.Images = []agent.Image{*r}
+		}
+	default:
+		data, err := json.Marshal(output)
+		if err != nil {
+			return nil, err
+		}
+		result := &CallToolResult{Content: []Content{TextContent(string(data))}}
+		if r, ok := output.(agent.ImageResult); ok {
+			for _, image := range r.ResultImages() {
+				result.Content = append(result.Content, imageContent(image))
+			}
+		}
+		return result, nil
+	}
+
+	result := &CallToolResult{Content: []Content{}}
+	if attachments.Text != "" {
+		result.Content = append(result.Content, TextContent(attachments.Text))
+	}
+	for _, image := range attachments.Images {
+		result.Content = append(result.Content, imageContent(image))
+	}
+	for _, file := range attachments.Files {
+		result.Content = append(result.Content, Content{Type: "resource", Resource: &Resource{
+			URI:      "file:///" + file.Name,
+			MimeType: http.DetectContentType(file.Data),
+			Blob:     file.Data,
+		}})
+	}
+	return result, nil
+}
+
+func imageContent(image agent.Image) Content {
+	return Content{Type: "image", Data: image.Data, MimeType: http.DetectContentType(image.Data)}
+}
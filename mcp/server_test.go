@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderTool looks up orders, failing for unknown IDs
+type orderTool struct{}
+
+func (orderTool) Name() string        { return "lookup_order" }
+func (orderTool) Description() string { return "Look up an order by ID" }
+func (orderTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{"id": map[string]any{"type": "string"}},
+		Required:   []string{"id"},
+	}
+}
+func (orderTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	if input["id"] != "42" {
+		return nil, errors.New("order not found")
+	}
+	return map[string]any{"id": "42", "status": "shipped"}, nil
+}
+
+// slowTool blocks until its call is canceled
+type slowTool struct{ canceled chan struct{} }
+
+func (slowTool) Name() string                 { return "slow" }
+func (slowTool) Description() string          { return "Never finishes" }
+func (slowTool) Parameters() agent.Parameters { return agent.Parameters{} }
+func (t slowTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	<-ctx.Done()
+	close(t.canceled)
+	return nil, ctx.Err()
+}
+
+// rpc calls a JSON-RPC method over HTTP and decodes the raw response
+func rpc(t *testing.T, url string, method string, params any) (json.RawMessage, *Error) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var out struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	assert.Equal(t, 1, out.ID)
+	return out.Result, out.Error
+}
+
+func TestServerHTTP(t *testing.T) {
+	registry := agent.NewToolRegistry(orderTool{})
+	server := httptest.NewServer(NewServer(registry, WithServerInfo("orders", "2.0.0")))
+	t.Cleanup(server.Close)
+
+	raw, rpcErr := rpc(t, server.URL, "initialize", InitializeParams{ProtocolVersion: "2025-03-26", ClientInfo: Implementation{Name: "test"}})
+	require.Nil(t, rpcErr)
+	var initialized InitializeResult
+	require.NoError(t, json.Unmarshal(raw, &initialized))
+	assert.Equal(t, "2025-03-26", initialized.ProtocolVersion)
+	assert.Equal(t, Implementation{Name: "orders", Version: "2.0.0"}, initialized.ServerInfo)
+	assert.NotNil(t, initialized.Capabilities.Tools)
+
+	// Notifications are accepted without a response
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	raw, rpcErr = rpc(t, server.URL, "tools/list", nil)
+	require.Nil(t, rpcErr)
+	var list ListToolsResult
+	require.NoError(t, json.Unmarshal(raw, &list))
+	require.Len(t, list.Tools, 1)
+	assert.Equal(t, "lookup_order", list.Tools[0].Name)
+	assert.Equal(t, "object", list.Tools[0].InputSchema["type"])
+	assert.Equal(t, []any{"id"}, list.Tools[0].InputSchema["required"])
+
+	raw, rpcErr = rpc(t, server.URL, "tools/call", CallToolParams{Name: "lookup_order", Arguments: map[string]any{"id": "42"}})
+	require.Nil(t, rpcErr)
+	var result CallToolResult
+	require.NoError(t, json.Unmarshal(raw, &result))
+	assert.Equal(t, CallToolResult{Content: []Content{TextContent(`{"id":"42","status":"shipped"}`)}}, result)
+
+	// Tool failures are results the model can see
+	raw, rpcErr = rpc(t, server.URL, "tools/call", CallToolParams{Name: "lookup_order", Arguments: map[string]any{"id": "7"}})
+	require.Nil(t, rpcErr)
+	require.NoError(t, json.Unmarshal(raw, &result))
+	assert.Equal(t, CallToolResult{Content: []Content{TextContent("order not found")}, IsError: true}, result)
+
+	_, rpcErr = rpc(t, server.URL, "tools/call", CallToolParams{Name: "missing"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+
+	_, rpcErr = rpc(t, server.URL, "resources/list", nil)
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeMethodNotFound, rpcErr.Code)
+
+	// Tools registered while serving are listed right away
+	registry.Unregister("lookup_order")
+	raw, _ = rpc(t, server.URL, "tools/list", nil)
+	require.NoError(t, json.Unmarshal(raw, &list))
+	assert.Empty(t, list.Tools)
+}
+
+func TestServerTenant(t *testing.T) {
+	registry := agent.NewToolRegistry()
+	registry.Register(orderTool{}, agent.ForTenants("acme"))
+
+	for tenant, want := range map[string]int{"": 0, "acme": 1} {
+		server := httptest.NewServer(NewServer(registry, WithTenant(tenant)))
+		raw, rpcErr := rpc(t, server.URL, "tools/list", nil)
+		server.Close()
+		require.Nil(t, rpcErr)
+		var list ListToolsResult
+		require.NoError(t, json.Unmarshal(raw, &list))
+		assert.Len(t, list.Tools, want, tenant)
+	}
+}
+
+func TestToolResult(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	result, err := toolResult(agent.ToolResult{
+		Text:   "chart attached",
+		Images: []agent.Image{{Data: png, Name: "chart.png"}},
+		Files:  []agent.File{{Data: []byte("a,b\n"), Name: "data.csv"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Content, 3)
+	assert.Equal(t, TextContent("chart attached"), result.Content[0])
+	assert.Equal(t, Content{Type: "image", Data: png, MimeType: "image/png"}, result.Content[1])
+	assert.Equal(t, "resource", result.Content[2].Type)
+	assert.Equal(t, "file:///data.csv", result.Content[2].Resource.URI)
+}
+
+func TestServeStdio(t *testing.T) {
+	canceled := make(chan struct{})
+	server := NewServer(agent.NewToolRegistry(orderTool{}, slowTool{canceled: canceled}))
+
+	in, input := io.Pipe()
+	output, out := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServeStdio(context.Background(), in, out)
+		out.Close()
+	}()
+	responses := json.NewDecoder(output)
+	send := func(message string) {
+		_, err := io.WriteString(input, message+"\n")
+		require.NoError(t, err)
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01","clientInfo":{"name":"test","version":"1"}}}`)
+	var resp struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	require.NoError(t, responses.Decode(&resp))
+	assert.JSONEq(t, `1`, string(resp.ID))
+	assert.Contains(t, string(resp.Result), `"protocolVersion":"`+ProtocolVersion+`"`)
+
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	// A slow call does not hold up the next request, and can be cancelled
+	send(`{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"slow"}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"lookup_order","arguments":{"id":"42"}}}`)
+	require.NoError(t, responses.Decode(&resp))
+	assert.JSONEq(t, `2`, string(resp.ID))
+	assert.Contains(t, string(resp.Result), "shipped")
+
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow"}}`)
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow call was not canceled")
+	}
+
+	send(`not json`)
+	require.NoError(t, responses.Decode(&resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, CodeParseError, resp.Error.Code)
+
+	input.Close()
+	require.NoError(t, <-done)
+	// The cancelled request is never answered
+	assert.False(t, responses.More())
+}
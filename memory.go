@@ -0,0 +1,257 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Memory keeps facts across sessions, such as a user's preferences, grouped by
+// subject. Runs name their subject with WithMemorySubject; runs without one
+// neither recall nor remember facts, so one user's facts never reach another's
+// runs.
+type Memory interface {
+	// Store remembers a fact about subject
+	Store(ctx context.Context, subject string, fact string) error
+	// Recall returns up to k of subject's facts most relevant to query, most relevant first
+	Recall(ctx context.Context, subject string, query string, k int) ([]string, error)
+}
+
+// WithMemory gives runs started with WithMemorySubject long-term memory:
+// remember and recall tools for the model, and, when each run starts, the k
+// facts most relevant to the trailing user messages, added before them as a
+// system message. A failed recall at run start is reported with a warning
+// response and the run goes on without memories.
+func WithMemory(memory Memory, k int) AgentOption {
+	return func(a *Agent) {
+		a.memory = memory
+		a.memoryRecall = k
+	}
+}
+
+// WithMemorySubject sets whose memories a run stores and recalls, such as a
+// user ID. Runs without a subject have no memory.
+func WithMemorySubject(subject string) RunOption {
+	return func(o *runOptions) {
+		o.memorySubject = subject
+	}
+}
+
+type memorySubjectKey struct{}
+
+// MemorySubjectFromContext returns the memory subject of the run a context
+// belongs to, such as the context a tool is executed with
+func MemorySubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(memorySubjectKey{}).(string)
+	return subject
+}
+
+func withMemorySubject(ctx context.Context, subject string) context.Context {
+	if subject == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, memorySubjectKey{}, subject)
+}
+
+// VectorMemory is a Memory that stores facts as embedded documents in a
+// VectorStore. Subjects share the store, so Recall searches the closest
+// candidates across subjects and keeps the subject's; a store shared by many
+// subjects should be given one VectorMemory per subject group.
+type VectorMemory struct {
+	embedder TextEmbedder
+	store    VectorStore
+}
+
+// NewVectorMemory creates a Memory backed by embedder and store
+func NewVectorMemory(embedder TextEmbedder, store VectorStore) *VectorMemory {
+	return &VectorMemory{embedder: embedder, store: store}
+}
+
+// recallCandidates is how many of the closest documents Recall filters per fact wanted
+const recallCandidates = 10
+
+// errNoMemorySubject is returned by the memory tools outside a run with a subject
+var errNoMemorySubject = errors.New("memory is only available to runs started with WithMemorySubject")
+
+// Store embeds fact and adds it to the store as a document for subject
+func (m *VectorMemory) Store(ctx context.Context, subject string, fact string) error {
+	embeddings, err := m.embedder.Embed(ctx, []string{fact})
+	if err != nil {
+		return err
+	}
+	if len(embeddings) != 1 {
+		return fmt.Errorf("expected 1 embedding, got %d", len(embeddings))
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return m.store.Upsert(ctx, []Document{{
+		ID:        hex.EncodeToString(id),
+		Text:      fact,
+		Metadata:  map[string]any{"subject": subject, "stored_at": time.Now().UTC().Format(time.RFC3339)},
+		Embedding: embeddings[0],
+	}})
+}
+
+// Recall embeds query and returns up to k of subject's facts among the closest
+// documents in the store, closest first
+func (m *VectorMemory) Recall(ctx context.Context, subject string, query string, k int) ([]string, error) {
+	embeddings, err := m.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(embeddings))
+	}
+	documents, err := m.store.Query(ctx, embeddings[0], k*recallCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var facts []string
+	for _, doc := range documents {
+		if doc.Metadata["subject"] == subject && len(facts) < k {
+			facts = append(facts, doc.Text)
+		}
+	}
+	return facts, nil
+}
+
+// rememberTool lets the model store a fact about the run's subject
+type rememberTool struct {
+	memory Memory
+}
+
+func (t rememberTool) Name() string {
+	return "remember"
+}
+
+func (t rememberTool) Description() string {
+	return "Remember a lasting fact about the user, such as a preference or detail they shared, for future conversations"
+}
+
+func (t rememberTool) Parameters() Parameters {
+	return Parameters{
+		Properties: map[string]any{
+			"fact": map[string]any{
+				"type":        "string",
+				"description": "The fact, stated on its own so it makes sense later",
+			},
+		},
+		Required: []string{"fact"},
+	}
+}
+
+func (t rememberTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	fact, ok := input["fact"].(string)
+	if !ok || strings.TrimSpace(fact) == "" {
+		return nil, fmt.Errorf("fact must be a non-empty string")
+	}
+	subject := MemorySubjectFromContext(ctx)
+	if subject == "" {
+		return nil, errNoMemorySubject
+	}
+	if err := t.memory.Store(ctx, subject, fact); err != nil {
+		return nil, err
+	}
+	return "Remembered.", nil
+}
+
+// recallTool lets the model search the run's subject's memories
+type recallTool struct {
+	memory Memory
+	k      int
+}
+
+func (t recallTool) Name() string {
+	return "recall"
+}
+
+func (t recallTool) Description() string {
+	return "Search facts remembered about the user in earlier conversations"
+}
+
+func (t recallTool) Parameters() Parameters {
+	return Parameters{
+		Properties: map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to look for",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (t recallTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	query, ok := input["query"].(string)
+	if !ok {
+		return nil, fmt.Errorf("query must be a string")
+	}
+	subject := MemorySubjectFromContext(ctx)
+	if subject == "" {
+		return nil, errNoMemorySubject
+	}
+	facts, err := t.memory.Recall(ctx, subject, query, max(t.k, 1))
+	if err != nil {
+		return nil, err
+	}
+	if len(facts) == 0 {
+		return "No relevant memories.", nil
+	}
+	return facts, nil
+}
+
+// memoryTools returns the remember and recall tools, or nil without memory or
+// outside a run with a subject
+func (agent *Agent) memoryTools(ctx context.Context) []Tool {
+	if agent.memory == nil || MemorySubjectFromContext(ctx) == "" {
+		return nil
+	}
+	return []Tool{rememberTool{memory: agent.memory}, recallTool{memory: agent.memory, k: agent.memoryRecall}}
+}
+
+// recallMemories adds the facts most relevant to the trailing user messages
+// before them, as a system message
+func (agent *Agent) recallMemories(ctx context.Context, messages []Message, params *openai.ChatCompletionNewParams, responseChan chan<- Response) error {
+	subject := MemorySubjectFromContext(ctx)
+	if agent.memory == nil || agent.memoryRecall <= 0 || subject == "" {
+		return nil
+	}
+	var texts []string
+	for i := len(messages) - 1; i >= 0 && messages[i].Role() == RoleUser; i-- {
+		if text := messages[i].Text(); text != "" {
+			texts = append([]string{text}, texts...)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	facts, err := agent.memory.Recall(ctx, subject, strings.Join(texts, "\n\n"), agent.memoryRecall)
+	if err != nil {
+		agent.logger.WarnContext(ctx, "agent memory recall failed", "error", err)
+		return send(ctx, responseChan, NewWarningResponse(fmt.Sprintf("memory recall failed: %v", err)))
+	}
+	if len(facts) == 0 {
+		return nil
+	}
+
+	var content strings.Builder
+	content.WriteString("What you remember about the user from earlier conversations:")
+	for _, fact := range facts {
+		content.WriteString("\n- " + fact)
+	}
+	// Insert before the trailing user messages, keeping the cached prefix intact
+	at := len(params.Messages)
+	for at > 0 && params.Messages[at-1].OfUser != nil {
+		at--
+	}
+	params.Messages = append(params.Messages[:at:at], append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(content.String())}, params.Messages[at:]...)...)
+	return nil
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVectorMemory(t *testing.T) {
+	ctx := context.Background()
+	memory := NewVectorMemory(topicEmbedder{}, NewMemoryVectorStore())
+
+	require.NoError(t, memory.Store(ctx, "alice", "Alice wants refunds as store credit"))
+	require.NoError(t, memory.Store(ctx, "alice", "Alice prefers express shipping"))
+	require.NoError(t, memory.Store(ctx, "bob", "Bob wants refunds to his card"))
+
+	facts, err := memory.Recall(ctx, "alice", "How do I get a refund?", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice wants refunds as store credit"}, facts)
+
+	facts, err = memory.Recall(ctx, "bob", "How do I get a refund?", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bob wants refunds to his card"}, facts)
+
+	facts, err = memory.Recall(ctx, "carol", "How do I get a refund?", 5)
+	require.NoError(t, err)
+	assert.Empty(t, facts)
+}
+
+func TestAgentMemoryAcrossRuns(t *testing.T) {
+	var mu sync.Mutex
+	var systemMessages []string
+	memory := NewVectorMemory(topicEmbedder{}, NewMemoryVectorStore())
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		last := body.Messages[len(body.Messages)-1]
+		switch {
+		case last.Role == "tool":
+			writeCompletion(w, "gpt-4o", "Noted.")
+		case last.Content == "Refunds should go to store credit for me.":
+			writeToolCall(w, "gpt-4o", "remember", `{"fact":"Wants refunds as store credit"}`)
+		default:
+			mu.Lock()
+			for _, message := range body.Messages {
+				if message.Role == "system" {
+					systemMessages = append(systemMessages, message.Content)
+				}
+			}
+			mu.Unlock()
+			writeCompletion(w, "gpt-4o", "Sure.")
+		}
+	}, WithMemory(memory, 3))
+
+	_, err := testAgent.ChatCompletion(context.Background(),
+		[]Message{UserTextMessage("Refunds should go to store credit for me.")}, WithMemorySubject("alice"))
+	require.NoError(t, err)
+
+	_, err = testAgent.ChatCompletion(context.Background(),
+		[]Message{UserTextMessage("I need a refund.")}, WithMemorySubject("alice"))
+	require.NoError(t, err)
+	require.Len(t, systemMessages, 1)
+	assert.Equal(t, "What you remember about the user from earlier conversations:\n- Wants refunds as store credit", systemMessages[0])
+
+	// Another subject's memories stay out of the run
+	_, err = testAgent.ChatCompletion(context.Background(),
+		[]Message{UserTextMessage("I need a refund.")}, WithMemorySubject("bob"))
+	require.NoError(t, err)
+	assert.Len(t, systemMessages, 1)
+}
+
+// failingMemory is a Memory whose recalls fail
+type failingMemory struct{}
+
+func (failingMemory) Store(ctx context.Context, subject string, fact string) error {
+	return nil
+}
+
+func (failingMemory) Recall(ctx context.Context, subject string, query string, k int) ([]string, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestAgentMemoryRecallFailure(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "Hi.")
+	}, WithMemory(failingMemory{}, 3))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithMemorySubject("alice"))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi."}, completion.Messages)
+	assert.Equal(t, []string{"memory recall failed: store unavailable"}, completion.Warnings)
+}
+
+func TestAgentMemoryWithoutSubject(t *testing.T) {
+	memory := NewVectorMemory(topicEmbedder{}, NewMemoryVectorStore())
+	require.NoError(t, memory.Store(context.Background(), "alice", "Wants refunds as store credit"))
+	require.NoError(t, memory.Store(context.Background(), "", "Wants refunds to their card"))
+	var body struct {
+		Messages []map[string]any `json:"messages"`
+		Tools    []map[string]any `json:"tools"`
+	}
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeCompletion(w, "gpt-4o", "Sure.")
+	}, WithMemory(memory, 3))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("I need a refund.")})
+	require.NoError(t, err)
+
+	// Runs without a subject have no memory at all
+	assert.Len(t, body.Messages, 1)
+	assert.Empty(t, body.Tools)
+	_, err = rememberTool{memory: memory}.Execute(context.Background(), map[string]any{"fact": "Lives in Paris"})
+	assert.ErrorIs(t, err, errNoMemorySubject)
+	_, err = recallTool{memory: memory, k: 3}.Execute(context.Background(), map[string]any{"query": "refund"})
+	assert.ErrorIs(t, err, errNoMemorySubject)
+}
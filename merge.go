@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// Source is a response stream labeled with the ID of the agent producing it
+type Source struct {
+	ID        string
+	Responses <-chan Response
+}
+
+// MergeStreams combines response streams into one, setting each response's
+// Source to its stream's ID. Responses from one source arrive in the order that
+// source sent them; responses from different sources interleave as they arrive.
+// A response that already has a Source, from a nested merge, gets the ID as a
+// prefix, as in "supervisor/researcher". The merged stream closes once every
+// source has closed.
+//
+// If ctx is canceled, the remaining responses are discarded, but the sources
+// are still drained so the runs behind them can finish. Error responses, which
+// end their source, are still delivered, so a canceled merge reports how each
+// run ended; keep reading the merged stream until it closes.
+func MergeStreams(ctx context.Context, sources ...Source) <-chan Response {
+	merged := make(chan Response)
+
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for response := range source.Responses {
+				switch {
+				case source.ID == "":
+				case response.Source == "":
+					response.Source = source.ID
+				default:
+					response.Source = source.ID + "/" + response.Source
+				}
+				// Keep draining after cancellation instead of blocking the source
+				switch {
+				case response.IsErrorResponse():
+					merged <- response
+				case ctx.Err() == nil:
+					_ = send(ctx, merged, response)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	return merged
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamOf returns a closed channel holding the responses
+func streamOf(responses ...Response) <-chan Response {
+	ch := make(chan Response, len(responses))
+	for _, response := range responses {
+		ch <- response
+	}
+	close(ch)
+	return ch
+}
+
+func TestMergeStreams(t *testing.T) {
+	var a, b []Response
+	for i := range 50 {
+		a = append(a, NewContentResponse(fmt.Sprint(i)))
+		b = append(b, NewContentResponse(fmt.Sprint(i)))
+	}
+	nested := MergeStreams(context.Background(), Source{ID: "critic", Responses: streamOf(NewContentResponse("ok"))})
+
+	got := map[string][]string{}
+	for response := range MergeStreams(context.Background(),
+		Source{ID: "a", Responses: streamOf(a...)},
+		Source{ID: "b", Responses: streamOf(b...)},
+		Source{ID: "review", Responses: nested},
+		Source{Responses: streamOf(NewErrorResponse(fmt.Errorf("failed")))},
+	) {
+		got[response.Source] = append(got[response.Source], response.Content())
+	}
+
+	// Each source's responses keep their order
+	var want []string
+	for i := range 50 {
+		want = append(want, fmt.Sprint(i))
+	}
+	assert.Equal(t, want, got["a"])
+	assert.Equal(t, want, got["b"])
+	assert.Equal(t, []string{"ok"}, got["review/critic"])
+	assert.Equal(t, []string{""}, got[""])
+}
+
+func TestMergeStreamsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := make(chan Response)
+	merged := MergeStreams(ctx, Source{ID: "a", Responses: source})
+
+	source <- NewContentResponse("first")
+	response := <-merged
+	assert.Equal(t, "first", response.Content())
+
+	// After cancellation the source is drained even though nothing reads the merged stream
+	cancel()
+	for range 3 {
+		select {
+		case source <- NewContentResponse("more"):
+		case <-time.After(5 * time.Second):
+			t.Fatal("source was not drained")
+		}
+	}
+	// The source's terminal error still reaches the caller
+	source <- NewErrorResponse(&CanceledError{Err: context.Canceled})
+	close(source)
+
+	response, ok := <-merged
+	require.True(t, ok)
+	var canceled *CanceledError
+	assert.ErrorAs(t, response.Error(), &canceled)
+	assert.Equal(t, "a", response.Source)
+	_, ok = <-merged
+	require.False(t, ok)
+}
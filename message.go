@@ -1,5 +1,10 @@
 package agent
 
+import (
+	"errors"
+	"time"
+)
+
 type MessageKind string
 
 const (
@@ -14,15 +19,29 @@ const (
 	RoleUser      Role = "user"
 	RoleAssistant Role = "assistant"
 	RoleSystem    Role = "system"
+	RoleDeveloper Role = "developer"
+	RoleTool      Role = "tool"
 )
 
 type Message struct {
 	role Role
 	kind MessageKind
 
-	text  string
-	file  File
-	image Image
+	text   string
+	file   File
+	images []Image
+
+	toolCalls  []ToolCall
+	toolCallID string
+
+	cacheControl bool
+}
+
+// ToolCall is a tool invocation requested by the model
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 type File struct {
@@ -70,10 +89,28 @@ func (m Message) File() File {
 }
 
 func (m Message) Image() Image {
-	if m.kind != MessageKindImage {
+	if m.kind != MessageKindImage || len(m.images) == 0 {
 		return Image{}
 	}
-	return m.image
+	return m.images[0]
+}
+
+// Images returns all images of an image message
+func (m Message) Images() []Image {
+	if m.kind != MessageKindImage {
+		return nil
+	}
+	return m.images
+}
+
+// ToolCalls returns the tool calls requested by an assistant message
+func (m Message) ToolCalls() []ToolCall {
+	return m.toolCalls
+}
+
+// ToolCallID returns the ID of the tool call a tool result message answers
+func (m Message) ToolCallID() string {
+	return m.toolCallID
 }
 
 func UserTextMessage(text string) Message {
@@ -94,9 +131,19 @@ func UserFileMessage(file File) Message {
 
 func UserImageMessage(image Image) Message {
 	return Message{
-		role:  RoleUser,
-		kind:  MessageKindImage,
-		image: image,
+		role:   RoleUser,
+		kind:   MessageKindImage,
+		images: []Image{image},
+	}
+}
+
+// UserImagesMessage creates a single user message carrying several images,
+// for example to ask the model to compare them
+func UserImagesMessage(images ...Image) Message {
+	return Message{
+		role:   RoleUser,
+		kind:   MessageKindImage,
+		images: images,
 	}
 }
 
@@ -108,6 +155,27 @@ func AssistantTextMessage(content string) Message {
 	}
 }
 
+// AssistantToolCallMessage creates an assistant message that requested tool calls,
+// with optional text the model produced alongside them, for replaying a prior conversation
+func AssistantToolCallMessage(content string, calls ...ToolCall) Message {
+	return Message{
+		role:      RoleAssistant,
+		kind:      MessageKindText,
+		text:      content,
+		toolCalls: calls,
+	}
+}
+
+// ToolResultMessage creates the result of a tool call, answering the call with the given ID
+func ToolResultMessage(toolCallID string, content string) Message {
+	return Message{
+		role:       RoleTool,
+		kind:       MessageKindText,
+		text:       content,
+		toolCallID: toolCallID,
+	}
+}
+
 func SystemMessage(text string) Message {
 	return Message{
 		role: RoleSystem,
@@ -116,20 +184,58 @@ func SystemMessage(text string) Message {
 	}
 }
 
+// DeveloperMessage creates a developer message. It is sent with the developer
+// role to models that support it (OpenAI o-series) and as a system message otherwise.
+func DeveloperMessage(text string) Message {
+	return Message{
+		role: RoleDeveloper,
+		kind: MessageKindText,
+		text: text,
+	}
+}
+
 type ResponseKind string
 
 const (
 	ResponseKindContent ResponseKind = "content"
 	ResponseKindUsage   ResponseKind = "usage"
 	ResponseKindError   ResponseKind = "error"
+	// ResponseKindProgress carries an update from a running tool
+	ResponseKindProgress ResponseKind = "progress"
+	// ResponseKindWarning reports a problem the run recovered from
+	ResponseKindWarning ResponseKind = "warning"
+	// ResponseKindAudio carries a chunk of speech synthesized with WithTTS
+	ResponseKindAudio ResponseKind = "audio"
+	// ResponseKindToolCall records a tool call the model made and its result
+	ResponseKindToolCall ResponseKind = "tool_call"
 )
 
 type Response struct {
 	Kind ResponseKind
-
-	content string
-	err     error
-	usage   Usage
+	// Source identifies the agent that produced the response in a merged
+	// stream, as a slash-separated path for nested merges, or is empty
+	Source string
+	// RunID and ParentRunID identify the run that produced the response and
+	// the run that started it, see RunInfo
+	RunID       string
+	ParentRunID string
+	// Iteration is the tool loop iteration the response belongs to, or zero
+	// for responses before the first request
+	Iteration int
+	// Time is when the response left the run
+	Time time.Time
+
+	content    string
+	err        error
+	usage      Usage
+	progress   ToolProgress
+	warning    string
+	audio      AudioChunk
+	toolCall   ToolCallRecord
+	exchange   WireExchange
+	moderation ModerationResult
+	draft      Draft
+	degraded   bool
 }
 
 func (r Response) IsContentResponse() bool {
@@ -187,13 +293,151 @@ func NewErrorResponse(err error) Response {
 }
 
 type Usage struct {
-	PromptTokens     int64 `json:"prompt_tokens"`
-	CompletionTokens int64 `json:"completion_tokens"`
-	TotalTokens      int64 `json:"total_tokens"`
+	Model string `json:"model,omitempty"`
+	// Iteration is the tool loop iteration that incurred the usage, or zero for
+	// auxiliary requests such as tool result summaries and for totals
+	Iteration    int   `json:"iteration,omitempty"`
+	PromptTokens int64 `json:"prompt_tokens"`
+	// CachedPromptTokens is the portion of PromptTokens served from the provider's prompt cache
+	CachedPromptTokens int64 `json:"cached_prompt_tokens"`
+	// AudioPromptTokens is the portion of PromptTokens spent on audio input
+	AudioPromptTokens int64 `json:"audio_prompt_tokens,omitempty"`
+	CompletionTokens  int64 `json:"completion_tokens"`
+	// ReasoningTokens is the portion of CompletionTokens spent on hidden reasoning
+	ReasoningTokens int64 `json:"reasoning_tokens,omitempty"`
+	// AudioCompletionTokens is the portion of CompletionTokens spent on audio output
+	AudioCompletionTokens int64 `json:"audio_completion_tokens,omitempty"`
+	TotalTokens           int64 `json:"total_tokens"`
+}
+
+// Add returns the sum of the token counts of u and other, keeping the model and iteration of u
+func (u Usage) Add(other Usage) Usage {
+	u.PromptTokens += other.PromptTokens
+	u.CachedPromptTokens += other.CachedPromptTokens
+	u.AudioPromptTokens += other.AudioPromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.AudioCompletionTokens += other.AudioCompletionTokens
+	u.TotalTokens += other.TotalTokens
+	return u
+}
+
+// CacheHitRate returns the fraction of prompt tokens served from the provider's prompt cache
+func (u Usage) CacheHitRate() float64 {
+	if u.PromptTokens == 0 {
+		return 0
+	}
+	return float64(u.CachedPromptTokens) / float64(u.PromptTokens)
+}
+
+// TerminationReason is why a run stopped
+type TerminationReason string
+
+const (
+	// TerminationCompleted means the model finished its answer
+	TerminationCompleted TerminationReason = "completed"
+	// TerminationMaxIterations means the run reached its iteration limit while
+	// the model was still calling tools
+	TerminationMaxIterations TerminationReason = "max_iterations"
+	// TerminationBudgetExceeded means the run spent its token or cost budget
+	TerminationBudgetExceeded TerminationReason = "budget_exceeded"
+	// TerminationTimeout means the run reached the limit set with WithRunTimeout
+	// or WithMaxRunDuration
+	TerminationTimeout TerminationReason = "timeout"
+	// TerminationCanceled means the run's context was canceled or a kill switch was engaged
+	TerminationCanceled TerminationReason = "canceled"
+	// TerminationError means the run failed with any other error
+	TerminationError TerminationReason = "error"
+	// TerminationDegraded means the provider was unavailable and the run ended
+	// with the reply from WithUnavailableFallback
+	TerminationDegraded TerminationReason = "degraded"
+)
+
+// terminationReason classifies the terminal error of a run
+func terminationReason(err error) TerminationReason {
+	var maxIterationsErr *MaxIterationsError
+	var canceledErr *CanceledError
+	switch {
+	case err == nil:
+		return TerminationCompleted
+	case errors.As(err, &maxIterationsErr):
+		return TerminationMaxIterations
+	case errors.Is(err, ErrBudgetExceeded):
+		return TerminationBudgetExceeded
+	case errors.Is(err, ErrRunTimeout):
+		return TerminationTimeout
+	case errors.As(err, &canceledErr), errors.Is(err, ErrKilled):
+		return TerminationCanceled
+	default:
+		return TerminationError
+	}
 }
 
 type Completion struct {
-	Usage     Usage
-	Messages  []string
+	RunID string
+	// ParentRunID is the run that started this one, or empty
+	ParentRunID string
+	// TerminationReason is why the run stopped
+	TerminationReason TerminationReason
+	// Usage is the total across every request made by the run
+	Usage Usage
+	// Steps holds the usage of each request in the order it was made
+	Steps    []Usage
+	Messages []string
+	// Warnings holds the messages of the run's warning responses
+	Warnings []string
+	// Audio is the speech of the run's answer with WithTTS, joined from its chunks
+	Audio []byte
+	// ToolCalls holds the tool calls the model made and their results, in order
+	ToolCalls []ToolCallRecord
+	// Moderations holds the content flagged by a ModerationFlag policy
+	Moderations []ModerationResult
+	// Drafts holds the answers replaced after a critique with WithReflection, in order
+	Drafts []Draft
+	// Raw holds the provider exchanges captured with WithDebug, in order
+	Raw       []WireExchange
 	Responses []Response
 }
+
+// add accumulates a response into the completion. The termination reason is
+// completed until an error response arrives.
+func (c *Completion) add(response Response) {
+	c.Responses = append(c.Responses, response)
+	if c.RunID == "" {
+		c.RunID, c.ParentRunID = response.RunID, response.ParentRunID
+	}
+	// Responses following the end of the run, such as its speech, keep its reason
+	switch {
+	case response.IsDegraded():
+		c.TerminationReason = TerminationDegraded
+	case response.IsErrorResponse():
+		c.TerminationReason = terminationReason(response.Error())
+	case c.TerminationReason == "":
+		c.TerminationReason = TerminationCompleted
+	}
+	if response.IsUsageResponse() {
+		c.Usage = c.Usage.Add(response.Usage())
+		c.Steps = append(c.Steps, response.Usage())
+	}
+	if response.IsContentResponse() {
+		c.Messages = append(c.Messages, response.Content())
+	}
+	if response.IsWarningResponse() {
+		c.Warnings = append(c.Warnings, response.Warning())
+	}
+	if response.IsAudioResponse() {
+		c.Audio = append(c.Audio, response.Audio().Data...)
+	}
+	if response.IsToolCallResponse() {
+		c.ToolCalls = append(c.ToolCalls, response.ToolCallRecord())
+	}
+	if response.IsModerationResponse() {
+		c.Moderations = append(c.Moderations, response.Moderation())
+	}
+	if response.IsDraftResponse() {
+		c.Drafts = append(c.Drafts, response.Draft())
+	}
+	if response.IsDebugResponse() {
+		c.Raw = append(c.Raw, response.WireExchange())
+	}
+}
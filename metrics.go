@@ -0,0 +1,28 @@
+package agent
+
+import "time"
+
+// MetricsRecorder receives measurements from agent runs. Implementations must
+// be safe for concurrent use. See the metrics subpackage for a Prometheus collector.
+type MetricsRecorder interface {
+	// ObserveRequest is called after each request to the provider, including fallback attempts
+	ObserveRequest(model string, duration time.Duration, usage Usage, err error)
+	// ObserveToolCall is called after each tool execution
+	ObserveToolCall(tool string, duration time.Duration, err error)
+	// ObserveRun is called once when a run finishes
+	ObserveRun(iterations int, err error)
+}
+
+// WithMetrics sets the recorder that receives the agent's measurements
+func WithMetrics(recorder MetricsRecorder) AgentOption {
+	return func(a *Agent) {
+		a.metrics = recorder
+	}
+}
+
+// noopMetrics discards all measurements
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveRequest(string, time.Duration, Usage, error) {}
+func (noopMetrics) ObserveToolCall(string, time.Duration, error)       {}
+func (noopMetrics) ObserveRun(int, error)                              {}
@@ -0,0 +1,198 @@
+// Package metrics provides a Prometheus collector for agent measurements.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option is a functional option for configuring a Collector
+type Option func(*Collector)
+
+// WithNamespace sets the namespace prefixed to every metric name (default: "agent")
+func WithNamespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+// WithPrices sets the per-model prices used to compute cost
+func WithPrices(prices map[string]agent.Price) Option {
+	return func(c *Collector) {
+		c.prices = prices
+	}
+}
+
+// Collector records agent measurements as Prometheus metrics. It implements both
+// agent.MetricsRecorder and prometheus.Collector, so it can be passed to
+// agent.WithMetrics and registered with a Prometheus registry.
+type Collector struct {
+	namespace string
+	prices    map[string]agent.Price
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	tokens          *prometheus.CounterVec
+	cost            *prometheus.CounterVec
+	toolCalls       *prometheus.CounterVec
+	toolDuration    *prometheus.HistogramVec
+	iterations      prometheus.Histogram
+	errors          *prometheus.CounterVec
+}
+
+// New creates a new Collector
+func New(opts ...Option) *Collector {
+	c := &Collector{
+		namespace: "agent",
+		prices:    map[string]agent.Price{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "requests_total",
+		Help:      "Total number of provider requests.",
+	}, []string{"model", "status"})
+	c.requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: c.namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of provider requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"model"})
+	c.tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "tokens_total",
+		Help:      "Total number of tokens consumed.",
+	}, []string{"model", "direction"})
+	c.cost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "cost_usd_total",
+		Help:      "Total cost in USD of models with a configured price.",
+	}, []string{"model"})
+	c.toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "tool_calls_total",
+		Help:      "Total number of tool executions.",
+	}, []string{"tool", "status"})
+	c.toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: c.namespace,
+		Name:      "tool_duration_seconds",
+		Help:      "Latency of tool executions.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tool"})
+	c.iterations = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: c.namespace,
+		Name:      "run_iterations",
+		Help:      "Number of iterations per run.",
+		Buckets:   []float64{1, 2, 3, 5, 10, 20, 50, 100},
+	})
+	c.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: c.namespace,
+		Name:      "errors_total",
+		Help:      "Total number of errors by type.",
+	}, []string{"type"})
+
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.requests,
+		c.requestDuration,
+		c.tokens,
+		c.cost,
+		c.toolCalls,
+		c.toolDuration,
+		c.iterations,
+		c.errors,
+	}
+}
+
+// ObserveRequest implements agent.MetricsRecorder
+func (c *Collector) ObserveRequest(model string, duration time.Duration, usage agent.Usage, err error) {
+	c.requests.WithLabelValues(model, status(err)).Inc()
+	c.requestDuration.WithLabelValues(model).Observe(duration.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(errorType(err)).Inc()
+		return
+	}
+	c.tokens.WithLabelValues(model, "prompt").Add(float64(usage.PromptTokens))
+	c.tokens.WithLabelValues(model, "completion").Add(float64(usage.CompletionTokens))
+	if price, ok := c.prices[model]; ok {
+		c.cost.WithLabelValues(model).Add(price.Cost(usage))
+	}
+}
+
+// ObserveToolCall implements agent.MetricsRecorder
+func (c *Collector) ObserveToolCall(tool string, duration time.Duration, err error) {
+	c.toolCalls.WithLabelValues(tool, status(err)).Inc()
+	c.toolDuration.WithLabelValues(tool).Observe(duration.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues("tool").Inc()
+	}
+}
+
+// ObserveRun implements agent.MetricsRecorder
+func (c *Collector) ObserveRun(iterations int, err error) {
+	c.iterations.Observe(float64(iterations))
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// errorType classifies a request error for the errors_total metric
+func errorType(err error) string {
+	var rateLimitErr *agent.RateLimitError
+	var contextErr *agent.ContextLengthExceededError
+	var authErr *agent.AuthenticationError
+	var filterErr *agent.ContentFilterError
+	var apiErr *openai.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &rateLimitErr):
+		return "rate_limit"
+	case errors.As(err, &contextErr):
+		return "context_length"
+	case errors.As(err, &authErr):
+		return "authentication"
+	case errors.As(err, &filterErr):
+		return "content_filter"
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode >= http.StatusInternalServerError {
+			return "server"
+		}
+		return "client"
+	default:
+		return "transport"
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	collector := New(WithPrices(map[string]agent.Price{
+		"gpt-4o": {Prompt: 2.5, Completion: 10},
+	}))
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	collector.ObserveRequest("gpt-4o", time.Second, agent.Usage{
+		PromptTokens:     1_000_000,
+		CompletionTokens: 100_000,
+		TotalTokens:      1_100_000,
+	}, nil)
+	collector.ObserveRequest("gpt-4o", time.Second, agent.Usage{}, errors.New("connection reset"))
+	collector.ObserveToolCall("get_weather", time.Millisecond, nil)
+	collector.ObserveRun(2, nil)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("gpt-4o", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.requests.WithLabelValues("gpt-4o", "error")))
+	assert.Equal(t, 1_000_000.0, testutil.ToFloat64(collector.tokens.WithLabelValues("gpt-4o", "prompt")))
+	assert.Equal(t, 100_000.0, testutil.ToFloat64(collector.tokens.WithLabelValues("gpt-4o", "completion")))
+	assert.InDelta(t, 3.5, testutil.ToFloat64(collector.cost.WithLabelValues("gpt-4o")), 0.0001)
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.toolCalls.WithLabelValues("get_weather", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.errors.WithLabelValues("transport")))
+
+	count, err := testutil.GatherAndCount(registry, "agent_run_iterations")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// ResponseKindModeration reports content that a moderation policy flagged
+const ResponseKindModeration ResponseKind = "moderation"
+
+// ErrModerationBlocked is matched by every ModerationError via errors.Is
+var ErrModerationBlocked = errors.New("moderation blocked")
+
+// DefaultModerationModel is the moderation model used unless the policy sets one
+const DefaultModerationModel = "omni-moderation-latest"
+
+// ModerationAction is what a moderation policy does with flagged content
+type ModerationAction int
+
+const (
+	// ModerationBlock stops the run with a ModerationError
+	ModerationBlock ModerationAction = iota
+	// ModerationFlag reports the content with a moderation response and lets the run continue
+	ModerationFlag
+)
+
+// ModerationSource is the content a moderation result is about
+type ModerationSource string
+
+const (
+	// ModerationInput is the user input a run starts with
+	ModerationInput ModerationSource = "input"
+	// ModerationOutput is the model's reply
+	ModerationOutput ModerationSource = "output"
+)
+
+// ModerationPolicy configures the moderation guardrail. The trailing user
+// messages of each run and the model's replies are checked with the provider's
+// Moderations API, as selected by Input and Output.
+type ModerationPolicy struct {
+	// Model is the moderation model, DefaultModerationModel if empty
+	Model  string
+	Input  bool
+	Output bool
+	// Categories limits the policy to these categories, such as "violence" or
+	// "self-harm/intent". Empty means every category.
+	Categories []string
+	// Thresholds flag a category when its score reaches the threshold, instead
+	// of when the provider flags it
+	Thresholds map[string]float64
+	Action     ModerationAction
+}
+
+// ModerationResult is the moderation of one piece of content
+type ModerationResult struct {
+	Source ModerationSource `json:"source"`
+	// Categories are the categories the policy flagged, sorted
+	Categories []string `json:"categories"`
+	// Scores are the provider's scores of every category
+	Scores map[string]float64 `json:"scores"`
+}
+
+// Flagged reports whether the policy flagged any category
+func (r ModerationResult) Flagged() bool {
+	return len(r.Categories) > 0
+}
+
+// ModerationError is returned when a ModerationBlock policy flags content
+type ModerationError struct {
+	Result ModerationResult
+}
+
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("%s blocked by moderation: %s", e.Result.Source, strings.Join(e.Result.Categories, ", "))
+}
+
+func (e *ModerationError) Is(target error) bool {
+	return target == ErrModerationBlocked
+}
+
+// WithModeration checks run input and model output with the Moderations API.
+// Flagged content either stops the run or is reported with a moderation
+// response, which an event bus also publishes as an EventModerationFlagged.
+func WithModeration(policy ModerationPolicy) AgentOption {
+	return func(a *Agent) {
+		a.moderationPolicy = policy
+	}
+}
+
+// NewModerationResponse creates a response reporting flagged content
+func NewModerationResponse(result ModerationResult) Response {
+	return Response{
+		Kind:       ResponseKindModeration,
+		moderation: result,
+	}
+}
+
+// IsModerationResponse reports whether the response reports flagged content
+func (r Response) IsModerationResponse() bool {
+	return r.Kind == ResponseKindModeration
+}
+
+// Moderation returns the result of a moderation response
+func (r Response) Moderation() ModerationResult {
+	if r.Kind != ResponseKindModeration {
+		return ModerationResult{}
+	}
+	return r.moderation
+}
+
+// moderateInput checks the trailing user messages of a run's input
+func (agent *Agent) moderateInput(ctx context.Context, messages []Message, opts []option.RequestOption, responseChan chan<- Response) error {
+	if !agent.moderationPolicy.Input {
+		return nil
+	}
+	var texts []string
+	for i := len(messages) - 1; i >= 0 && messages[i].Role() == RoleUser; i-- {
+		if text := messages[i].Text(); text != "" {
+			texts = append([]string{text}, texts...)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	return agent.moderate(ctx, ModerationInput, strings.Join(texts, "\n\n"), opts, responseChan)
+}
+
+// moderateOutput checks a reply of the model before it is sent
+func (agent *Agent) moderateOutput(ctx context.Context, content string, opts []option.RequestOption, responseChan chan<- Response) error {
+	if !agent.moderationPolicy.Output {
+		return nil
+	}
+	return agent.moderate(ctx, ModerationOutput, content, opts, responseChan)
+}
+
+// moderate checks text, returning a *ModerationError if the policy blocks it.
+// Moderation failures fail the run rather than letting content through unchecked.
+func (agent *Agent) moderate(ctx context.Context, source ModerationSource, text string, opts []option.RequestOption, responseChan chan<- Response) error {
+	policy := agent.moderationPolicy
+	model := policy.Model
+	if model == "" {
+		model = DefaultModerationModel
+	}
+	response, err := agent.client.Moderations.New(ctx, openai.ModerationNewParams{
+		Model: openai.ModerationModel(model),
+		Input: openai.ModerationNewParamsInputUnion{OfString: openai.String(text)},
+	}, agent.debugOptions(opts)...)
+	if err != nil {
+		return fmt.Errorf("moderation: %w", wrapProviderError(err))
+	}
+	if len(response.Results) == 0 {
+		return errors.New("moderation: no result")
+	}
+
+	result, err := policy.evaluate(source, response.Results[0])
+	if err != nil {
+		return err
+	}
+	if !result.Flagged() {
+		return nil
+	}
+	agent.logger.WarnContext(ctx, "agent moderation flagged", "source", source, "categories", result.Categories)
+	if policy.Action == ModerationBlock {
+		return &ModerationError{Result: result}
+	}
+	return send(ctx, responseChan, NewModerationResponse(result))
+}
+
+// evaluate applies the policy's categories and thresholds to a provider result
+func (p ModerationPolicy) evaluate(source ModerationSource, moderation openai.Moderation) (ModerationResult, error) {
+	var flags map[string]bool
+	if err := json.Unmarshal([]byte(moderation.Categories.RawJSON()), &flags); err != nil {
+		return ModerationResult{}, fmt.Errorf("moderation: decode categories: %w", err)
+	}
+	result := ModerationResult{Source: source}
+	if err := json.Unmarshal([]byte(moderation.CategoryScores.RawJSON()), &result.Scores); err != nil {
+		return ModerationResult{}, fmt.Errorf("moderation: decode scores: %w", err)
+	}
+
+	categories := p.Categories
+	if len(categories) == 0 {
+		for category := range result.Scores {
+			categories = append(categories, category)
+		}
+		for category := range flags {
+			if _, ok := result.Scores[category]; !ok {
+				categories = append(categories, category)
+			}
+		}
+	}
+	for _, category := range categories {
+		flagged := flags[category]
+		if threshold, ok := p.Thresholds[category]; ok {
+			flagged = result.Scores[category] >= threshold
+		}
+		if flagged {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
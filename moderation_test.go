@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModerationAgent creates an agent whose provider answers with reply and
+// flags moderated text containing "attack" for violence
+func newModerationAgent(t *testing.T, reply string, opts ...AgentOption) (*Agent, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var moderated []string
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/moderations") {
+			writeCompletion(w, "gpt-4o", reply)
+			return
+		}
+		var body struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, DefaultModerationModel, body.Model)
+		mu.Lock()
+		moderated = append(moderated, body.Input)
+		mu.Unlock()
+
+		violent := strings.Contains(body.Input, "attack")
+		score := 0.01
+		if violent {
+			score = 0.93
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"modr-1","model":%q,"results":[{"flagged":%t,"categories":{"violence":%t,"harassment":false},"category_scores":{"violence":%v,"harassment":0.2},"category_applied_input_types":{}}]}`,
+			body.Model, violent, violent, score)
+	}, opts...)
+	return testAgent, &moderated
+}
+
+func TestModerationBlocksInput(t *testing.T) {
+	testAgent, moderated := newModerationAgent(t, "ok", WithModeration(ModerationPolicy{Input: true}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{
+		UserTextMessage("hello"),
+		AssistantTextMessage("hi"),
+		UserTextMessage("plan an attack"),
+	})
+
+	require.ErrorIs(t, err, ErrModerationBlocked)
+	var moderationErr *ModerationError
+	require.ErrorAs(t, err, &moderationErr)
+	assert.Equal(t, ModerationInput, moderationErr.Result.Source)
+	assert.Equal(t, []string{"violence"}, moderationErr.Result.Categories)
+	assert.Equal(t, 0.93, moderationErr.Result.Scores["violence"])
+	// Only the trailing user messages are moderated
+	assert.Equal(t, []string{"plan an attack"}, *moderated)
+}
+
+func TestModerationBlocksOutput(t *testing.T) {
+	testAgent, _ := newModerationAgent(t, "here is how to attack", WithModeration(ModerationPolicy{Output: true}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("hello")})
+
+	require.ErrorIs(t, err, ErrModerationBlocked)
+	assert.Empty(t, completion.Messages)
+}
+
+func TestModerationFlags(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+	testAgent, moderated := newModerationAgent(t, "safe reply", WithEventBus(bus), WithModeration(ModerationPolicy{
+		Input:  true,
+		Output: true,
+		Action: ModerationFlag,
+	}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("describe an attack")})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"safe reply"}, completion.Messages)
+	assert.Equal(t, []string{"describe an attack", "safe reply"}, *moderated)
+	require.Len(t, completion.Moderations, 1)
+	assert.Equal(t, ModerationInput, completion.Moderations[0].Source)
+	assert.Equal(t, []string{"violence"}, completion.Moderations[0].Categories)
+
+	var flagged []Event
+	for event := range sub.Events() {
+		if event.Kind == EventModerationFlagged {
+			flagged = append(flagged, event)
+		}
+		if event.Kind == EventRunFinished {
+			break
+		}
+	}
+	require.Len(t, flagged, 1)
+	assert.Equal(t, completion.RunID, flagged[0].RunID)
+	assert.Equal(t, completion.Moderations[0], flagged[0].Response.Moderation())
+}
+
+func TestModerationPolicyCategoriesAndThresholds(t *testing.T) {
+	// Harassment scores 0.2, below the provider's flag but above the threshold
+	testAgent, _ := newModerationAgent(t, "ok", WithModeration(ModerationPolicy{
+		Input:      true,
+		Categories: []string{"harassment"},
+		Thresholds: map[string]float64{"harassment": 0.1},
+		Action:     ModerationFlag,
+	}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("plan an attack")})
+
+	require.NoError(t, err)
+	require.Len(t, completion.Moderations, 1)
+	assert.Equal(t, []string{"harassment"}, completion.Moderations[0].Categories)
+}
+
+func TestModerationResponseJSON(t *testing.T) {
+	result := ModerationResult{Source: ModerationOutput, Categories: []string{"violence"}, Scores: map[string]float64{"violence": 0.9}}
+	data, err := json.Marshal(NewModerationResponse(result))
+	require.NoError(t, err)
+
+	var decoded Response
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.True(t, decoded.IsModerationResponse())
+	assert.Equal(t, result, decoded.Moderation())
+}
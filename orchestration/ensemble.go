@@ -0,0 +1,171 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Consensus is the answer an ensemble agreed on
+type Consensus struct {
+	Answer string
+	// Usage is what reaching the consensus cost, such as a judge's requests
+	Usage agent.Usage
+}
+
+// Reducer combines the answers of an ensemble's workers into a consensus.
+// Traces holds every worker's run, in worker order; failed runs have an Err.
+type Reducer func(ctx context.Context, messages []agent.Message, traces []Trace) (Consensus, error)
+
+// EnsembleOption is a functional option for configuring an Ensemble
+type EnsembleOption func(*Ensemble)
+
+// WithReducer sets how an ensemble combines its answers, MajorityVote by default
+func WithReducer(reducer Reducer) EnsembleOption {
+	return func(e *Ensemble) {
+		e.reducer = reducer
+	}
+}
+
+// EnsembleResult is the outcome of an Ensemble run
+type EnsembleResult struct {
+	Consensus string
+	// Traces holds each worker's completion, in worker order
+	Traces []Trace
+	// Usage is the total across the workers and the reducer
+	Usage agent.Usage
+}
+
+// Ensemble runs the same conversation on several agents, typically on
+// different models, in parallel and combines their answers
+type Ensemble struct {
+	workers []Worker
+	reducer Reducer
+}
+
+// NewEnsemble creates an ensemble of workers
+func NewEnsemble(workers []Worker, opts ...EnsembleOption) *Ensemble {
+	e := &Ensemble{
+		workers: workers,
+		reducer: MajorityVote(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Run runs the conversation on every worker with opts and reduces their
+// answers. Workers that fail are left out of the consensus; Run fails only if
+// every worker fails or the reducer does.
+func (e *Ensemble) Run(ctx context.Context, messages []agent.Message, opts ...agent.RunOption) (EnsembleResult, error) {
+	traces := make([]Trace, len(e.workers))
+	var wg sync.WaitGroup
+	for i, worker := range e.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			completion, err := worker.Agent.ChatCompletion(ctx, messages, opts...)
+			traces[i] = Trace{Worker: worker.Name, Task: Task{Worker: worker.Name, Messages: messages}, Completion: completion, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	result := EnsembleResult{Traces: traces}
+	var errs []error
+	for _, trace := range traces {
+		result.Usage = result.Usage.Add(trace.Completion.Usage)
+		if trace.Err != nil {
+			errs = append(errs, fmt.Errorf("worker %s: %w", trace.Worker, trace.Err))
+		}
+	}
+	if len(errs) == len(traces) {
+		return result, errors.Join(errs...)
+	}
+
+	consensus, err := e.reducer(ctx, messages, traces)
+	result.Usage = result.Usage.Add(consensus.Usage)
+	if err != nil {
+		return result, fmt.Errorf("reduce answers: %w", err)
+	}
+	result.Consensus = consensus.Answer
+	return result, nil
+}
+
+// answer returns a worker's final answer: the last content of its run
+func answer(trace Trace) string {
+	if len(trace.Completion.Messages) == 0 {
+		return ""
+	}
+	return trace.Completion.Messages[len(trace.Completion.Messages)-1]
+}
+
+// MajorityVote picks the answer given by the most workers, comparing answers
+// ignoring case, surrounding space, and a trailing period. Ties go to the
+// answer of the earliest worker. It suits short answers, such as labels,
+// choices, and numbers.
+func MajorityVote() Reducer {
+	return func(ctx context.Context, messages []agent.Message, traces []Trace) (Consensus, error) {
+		votes := map[string]int{}
+		var order []string
+		first := map[string]string{}
+		for _, trace := range traces {
+			if trace.Err != nil {
+				continue
+			}
+			key := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(answer(trace))), ".")
+			if _, ok := votes[key]; !ok {
+				order = append(order, key)
+				first[key] = strings.TrimSpace(answer(trace))
+			}
+			votes[key]++
+		}
+		if len(order) == 0 {
+			return Consensus{}, errors.New("no answers")
+		}
+		winner := order[0]
+		for _, key := range order[1:] {
+			if votes[key] > votes[winner] {
+				winner = key
+			}
+		}
+		return Consensus{Answer: first[winner]}, nil
+	}
+}
+
+// Judge has a judge agent read the conversation and the workers' answers and
+// reply with the best answer, corrected or combined as needed. It suits long
+// answers that rarely match word for word.
+func Judge(judge agent.ChatAgent) Reducer {
+	return func(ctx context.Context, messages []agent.Message, traces []Trace) (Consensus, error) {
+		var prompt strings.Builder
+		prompt.WriteString("Several assistants answered the conversation below. Reply with the best answer, " +
+			"correcting or combining the candidates as needed, and nothing else.\n\nConversation:\n")
+		for _, msg := range messages {
+			if (msg.Role() == agent.RoleUser || msg.Role() == agent.RoleAssistant) && msg.Text() != "" {
+				fmt.Fprintf(&prompt, "%s: %s\n", msg.Role(), msg.Text())
+			}
+		}
+		n := 0
+		for _, trace := range traces {
+			if trace.Err != nil {
+				continue
+			}
+			n++
+			fmt.Fprintf(&prompt, "\nCandidate %d:\n%s\n", n, answer(trace))
+		}
+
+		completion, err := judge.ChatCompletion(ctx, []agent.Message{agent.UserTextMessage(prompt.String())})
+		consensus := Consensus{Answer: strings.Join(completion.Messages, "\n\n"), Usage: completion.Usage}
+		if err != nil {
+			return consensus, fmt.Errorf("judge: %w", err)
+		}
+		return consensus, nil
+	}
+}
@@ -0,0 +1,103 @@
+package orchestration
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEnsembleTestClient creates a client for a server that answers each model
+// with its reply, failing models without one
+func newEnsembleTestClient(t *testing.T, replies map[string]string) openai.Client {
+	t.Helper()
+	return newTestClient(t, func(request agenttest.Request) agenttest.Reply {
+		reply, ok := replies[request.Model]
+		if !ok {
+			return agenttest.Fail(http.StatusBadRequest, "unknown model")
+		}
+		if request.Model == "judge" {
+			prompt := request.LastMessage().Content
+			assert.Contains(t, prompt, "user: What is the capital of France?")
+			assert.Contains(t, prompt, "Candidate 3:\nLyon")
+		}
+		return agenttest.Text(reply)
+	})
+}
+
+func ensembleWorkers(client openai.Client, models ...string) []Worker {
+	workers := make([]Worker, len(models))
+	for i, model := range models {
+		workers[i] = Worker{Name: model, Agent: agent.NewAgentWithClient(client, model)}
+	}
+	return workers
+}
+
+var question = []agent.Message{agent.UserTextMessage("What is the capital of France?")}
+
+func TestEnsembleMajorityVote(t *testing.T) {
+	client := newEnsembleTestClient(t, map[string]string{"a": "Lyon", "b": "Paris", "c": "paris."})
+
+	result, err := NewEnsemble(ensembleWorkers(client, "a", "b", "c", "broken")).Run(context.Background(), question)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", result.Consensus)
+	require.Len(t, result.Traces, 4)
+	assert.Equal(t, "a", result.Traces[0].Worker)
+	assert.Equal(t, []string{"Lyon"}, result.Traces[0].Completion.Messages)
+	assert.Error(t, result.Traces[3].Err)
+	assert.Equal(t, int64(33), result.Usage.TotalTokens)
+}
+
+func TestEnsembleMajorityVoteTie(t *testing.T) {
+	client := newEnsembleTestClient(t, map[string]string{"a": "Lyon", "b": "Paris"})
+
+	result, err := NewEnsemble(ensembleWorkers(client, "a", "b")).Run(context.Background(), question)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Lyon", result.Consensus)
+}
+
+func TestEnsembleJudge(t *testing.T) {
+	client := newEnsembleTestClient(t, map[string]string{"a": "Paris", "b": "It's Paris", "c": "Lyon", "judge": "Paris"})
+
+	ensemble := NewEnsemble(ensembleWorkers(client, "a", "b", "c"), WithReducer(Judge(agent.NewAgentWithClient(client, "judge"))))
+	result, err := ensemble.Run(context.Background(), question)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", result.Consensus)
+	// Three workers and the judge
+	assert.Equal(t, int64(44), result.Usage.TotalTokens)
+}
+
+func TestEnsembleCustomReducer(t *testing.T) {
+	client := newEnsembleTestClient(t, map[string]string{"a": "Paris", "b": "Lyon"})
+
+	longest := func(ctx context.Context, messages []agent.Message, traces []Trace) (Consensus, error) {
+		var answers []string
+		for _, trace := range traces {
+			answers = append(answers, trace.Completion.Messages...)
+		}
+		return Consensus{Answer: strings.Join(answers, " or ")}, nil
+	}
+	result, err := NewEnsemble(ensembleWorkers(client, "a", "b"), WithReducer(longest)).Run(context.Background(), question)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Paris or Lyon", result.Consensus)
+}
+
+func TestEnsembleAllFail(t *testing.T) {
+	client := newEnsembleTestClient(t, nil)
+
+	result, err := NewEnsemble(ensembleWorkers(client, "x", "y")).Run(context.Background(), question)
+
+	assert.ErrorContains(t, err, "worker x")
+	assert.ErrorContains(t, err, "worker y")
+	assert.Empty(t, result.Consensus)
+}
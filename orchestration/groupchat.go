@@ -0,0 +1,187 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Turn is what a participant said in a group chat
+type Turn struct {
+	Speaker string
+	Content string
+}
+
+// TurnPolicy picks who speaks next in a group chat, given the turns so far
+type TurnPolicy interface {
+	Next(ctx context.Context, turns []Turn, participants []Worker) (string, error)
+}
+
+// TurnPolicyFunc adapts a function to the TurnPolicy interface
+type TurnPolicyFunc func(ctx context.Context, turns []Turn, participants []Worker) (string, error)
+
+func (f TurnPolicyFunc) Next(ctx context.Context, turns []Turn, participants []Worker) (string, error) {
+	return f(ctx, turns, participants)
+}
+
+// RoundRobin lets the participants speak in order
+func RoundRobin() TurnPolicy {
+	return TurnPolicyFunc(func(ctx context.Context, turns []Turn, participants []Worker) (string, error) {
+		return participants[len(turns)%len(participants)].Name, nil
+	})
+}
+
+// ModelSelected has a selector agent read the participants' descriptions and
+// the chat so far and name who speaks next
+func ModelSelected(selector agent.ChatAgent) TurnPolicy {
+	return TurnPolicyFunc(func(ctx context.Context, turns []Turn, participants []Worker) (string, error) {
+		var prompt strings.Builder
+		prompt.WriteString("You moderate a group chat. The participants are:\n")
+		for _, participant := range participants {
+			fmt.Fprintf(&prompt, "- %s: %s\n", participant.Name, participant.Description)
+		}
+		prompt.WriteString("\nReply with only the name of the participant who should speak next.")
+
+		var chat strings.Builder
+		for _, turn := range turns {
+			fmt.Fprintf(&chat, "%s: %s\n", turn.Speaker, turn.Content)
+		}
+		if chat.Len() == 0 {
+			chat.WriteString("(no one has spoken yet)")
+		}
+
+		completion, err := selector.ChatCompletion(ctx, []agent.Message{
+			agent.SystemMessage(prompt.String()),
+			agent.UserTextMessage(chat.String()),
+		})
+		if err != nil {
+			return "", fmt.Errorf("select next speaker: %w", err)
+		}
+		reply := strings.Join(completion.Messages, "")
+		if participant := matchWorker(participants, reply); participant != nil {
+			return participant.Name, nil
+		}
+		return "", fmt.Errorf("select next speaker: %w: selector replied %q", ErrNoRoute, reply)
+	})
+}
+
+// GroupChatOption is a functional option for configuring a GroupChat
+type GroupChatOption func(*GroupChat)
+
+// WithTurnPolicy sets who speaks next, RoundRobin by default
+func WithTurnPolicy(policy TurnPolicy) GroupChatOption {
+	return func(g *GroupChat) {
+		g.policy = policy
+	}
+}
+
+// WithRounds sets how many rounds the chat runs, 1 by default. A round is one
+// turn per participant, so a chat of three runs 3n turns.
+func WithRounds(n int) GroupChatOption {
+	return func(g *GroupChat) {
+		g.rounds = n
+	}
+}
+
+// GroupChatResult is the outcome of a GroupChat run
+type GroupChatResult struct {
+	Turns []Turn
+	// Traces holds each turn's run, in order
+	Traces []Trace
+	// Usage is the total across every turn
+	Usage agent.Usage
+}
+
+// GroupChat has agents with distinct personas, set with their system
+// prompts, converse on a topic, for debates and brainstorming. Each speaker
+// sees the topic, the other participants' turns as user messages prefixed
+// with their names, and its own turns as its replies.
+type GroupChat struct {
+	participants []Worker
+	policy       TurnPolicy
+	rounds       int
+}
+
+// NewGroupChat creates a group chat of participants, whose Descriptions
+// introduce them to a ModelSelected policy
+func NewGroupChat(participants []Worker, opts ...GroupChatOption) *GroupChat {
+	g := &GroupChat{
+		participants: participants,
+		policy:       RoundRobin(),
+		rounds:       1,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Run runs the chat on the topic. If a turn fails, the chat stops and the
+// error is returned with the turns so far.
+func (g *GroupChat) Run(ctx context.Context, topic []agent.Message) (GroupChatResult, error) {
+	return g.run(ctx, topic, nil)
+}
+
+// Stream runs the chat like Run and returns each turn's responses as they are
+// produced, labeled with the speaker's name in Source. A failing turn's error
+// response ends the stream.
+func (g *GroupChat) Stream(ctx context.Context, topic []agent.Message) (<-chan agent.Response, error) {
+	if len(g.participants) == 0 {
+		return nil, fmt.Errorf("group chat has no participants")
+	}
+	out := make(chan agent.Response)
+	go func() {
+		defer close(out)
+		// Keep draining after the caller cancels so the turn can finish
+		g.run(ctx, topic, forward(ctx, out))
+	}()
+	return out, nil
+}
+
+// run runs the chat, passing each labeled response to emit if it is set
+func (g *GroupChat) run(ctx context.Context, topic []agent.Message, emit func(agent.Response)) (GroupChatResult, error) {
+	var result GroupChatResult
+	if len(g.participants) == 0 {
+		return result, fmt.Errorf("group chat has no participants")
+	}
+
+	for range g.rounds * len(g.participants) {
+		name, err := g.policy.Next(ctx, result.Turns, g.participants)
+		if err == nil && findWorker(g.participants, name) == nil {
+			err = fmt.Errorf("unknown participant %q", name)
+		}
+		if err != nil {
+			if emit != nil {
+				emit(agent.NewErrorResponse(err))
+			}
+			return result, err
+		}
+		speaker := findWorker(g.participants, name)
+
+		trace := runStage(ctx, Stage{Name: speaker.Name, Agent: speaker.Agent}, perspective(topic, result.Turns, speaker.Name), emit)
+		result.Traces = append(result.Traces, trace)
+		result.Usage = result.Usage.Add(trace.Completion.Usage)
+		if trace.Err != nil {
+			return result, fmt.Errorf("turn %d (%s): %w", len(result.Traces), speaker.Name, trace.Err)
+		}
+		result.Turns = append(result.Turns, Turn{Speaker: speaker.Name, Content: answer(trace)})
+	}
+	return result, nil
+}
+
+// perspective returns the conversation as the speaker sees it
+func perspective(topic []agent.Message, turns []Turn, speaker string) []agent.Message {
+	messages := append([]agent.Message{}, topic...)
+	for _, turn := range turns {
+		if turn.Speaker == speaker {
+			messages = append(messages, agent.AssistantTextMessage(turn.Content))
+			continue
+		}
+		messages = append(messages, agent.UserTextMessage(turn.Speaker+": "+turn.Content))
+	}
+	return messages
+}
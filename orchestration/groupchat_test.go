@@ -0,0 +1,119 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGroupChatTestClient creates a client for a server that answers each
+// participant model with "<model> #<number of messages>", and the model
+// "selector" with select's reply to the chat transcript
+func newGroupChatTestClient(t *testing.T, selectNext func(transcript string) string) openai.Client {
+	t.Helper()
+	return newTestClient(t, func(request agenttest.Request) agenttest.Reply {
+		if request.Model == "selector" {
+			return agenttest.Text(selectNext(request.LastMessage().Content))
+		}
+		return agenttest.Text(fmt.Sprintf("%s #%d", request.Model, len(request.Messages)))
+	})
+}
+
+func groupChatParticipants(client openai.Client) []Worker {
+	return []Worker{
+		{Name: "optimist", Description: "Argues for the idea", Agent: agent.NewAgentWithClient(client, "pro")},
+		{Name: "skeptic", Description: "Argues against the idea", Agent: agent.NewAgentWithClient(client, "con")},
+	}
+}
+
+func TestGroupChatRoundRobin(t *testing.T) {
+	client := newGroupChatTestClient(t, nil)
+	chat := NewGroupChat(groupChatParticipants(client), WithRounds(2))
+
+	result, err := chat.Run(context.Background(), []agent.Message{agent.UserTextMessage("Should we rewrite it in Rust?")})
+
+	require.NoError(t, err)
+	assert.Equal(t, []Turn{
+		{Speaker: "optimist", Content: "pro #1"},
+		{Speaker: "skeptic", Content: "con #2"},
+		{Speaker: "optimist", Content: "pro #3"},
+		{Speaker: "skeptic", Content: "con #4"},
+	}, result.Turns)
+	assert.Equal(t, int64(44), result.Usage.TotalTokens)
+
+	// Each speaker sees its own turns as replies and the others' as named messages
+	assert.Equal(t, []agent.Message{
+		agent.UserTextMessage("Should we rewrite it in Rust?"),
+		agent.AssistantTextMessage("pro #1"),
+		agent.UserTextMessage("skeptic: con #2"),
+	}, result.Traces[2].Task.Messages)
+}
+
+func TestGroupChatModelSelected(t *testing.T) {
+	client := newGroupChatTestClient(t, func(transcript string) string {
+		if strings.Contains(transcript, "no one has spoken") {
+			return "Skeptic"
+		}
+		return "optimist"
+	})
+	chat := NewGroupChat(groupChatParticipants(client),
+		WithTurnPolicy(ModelSelected(agent.NewAgentWithClient(client, "selector"))))
+
+	responses, err := chat.Stream(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	require.NoError(t, err)
+
+	var contents []string
+	for response := range responses {
+		if response.IsContentResponse() {
+			contents = append(contents, response.Source+": "+response.Content())
+		}
+	}
+	assert.Equal(t, []string{"skeptic: con #1", "optimist: pro #2"}, contents)
+}
+
+func TestGroupChatStreamCanceled(t *testing.T) {
+	client := newGroupChatTestClient(t, nil)
+	chat := NewGroupChat(groupChatParticipants(client))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	responses, err := chat.Stream(ctx, []agent.Message{agent.UserTextMessage("topic")})
+	require.NoError(t, err)
+	// Give the turn time to end before anything reads the stream
+	time.Sleep(20 * time.Millisecond)
+
+	var last agent.Response
+	for response := range responses {
+		last = response
+	}
+	var canceled *agent.CanceledError
+	assert.ErrorAs(t, last.Error(), &canceled)
+	assert.Equal(t, "optimist", last.Source)
+}
+
+func TestGroupChatNoSpeaker(t *testing.T) {
+	client := newGroupChatTestClient(t, func(transcript string) string {
+		return "the moderator"
+	})
+	chat := NewGroupChat(groupChatParticipants(client),
+		WithTurnPolicy(ModelSelected(agent.NewAgentWithClient(client, "selector"))))
+
+	result, err := chat.Run(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	assert.ErrorIs(t, err, ErrNoRoute)
+	assert.Empty(t, result.Turns)
+
+	chat = NewGroupChat(groupChatParticipants(client), WithTurnPolicy(TurnPolicyFunc(
+		func(ctx context.Context, turns []Turn, participants []Worker) (string, error) {
+			return "nobody", nil
+		})))
+	_, err = chat.Run(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	assert.ErrorContains(t, err, `unknown participant "nobody"`)
+}
@@ -0,0 +1,140 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Stage is a step of a Pipeline
+type Stage struct {
+	Name  string
+	Agent agent.ChatAgent
+	// Transform turns the previous stage's output into this stage's input, for
+	// example to wrap it in instructions or extract part of it. Without one the
+	// output is passed on as is. The first stage's Transform is not used.
+	Transform func(ctx context.Context, output string) (string, error)
+}
+
+// PipelineResult is the outcome of a Pipeline run
+type PipelineResult struct {
+	// Output is the last stage's answer
+	Output string
+	// Traces holds each stage's part of the run, in order, up to the stage that failed
+	Traces []Trace
+	// Usage is the total across every stage
+	Usage agent.Usage
+}
+
+// Pipeline chains agents: the first stage runs the conversation, and each
+// later stage gets the previous stage's answer, its last content, as a user
+// message
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline creates a pipeline of stages
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Run runs the stages in order and returns the last stage's output. If a stage
+// fails, the run stops and the error is returned with the partial result.
+func (p *Pipeline) Run(ctx context.Context, messages []agent.Message) (PipelineResult, error) {
+	return p.run(ctx, messages, nil)
+}
+
+// Stream runs the stages like Run and returns every stage's responses as one
+// stream, each labeled with its stage's name in Source, so intermediate
+// outputs can be shown as they are produced. A failing stage's error response
+// ends the stream.
+func (p *Pipeline) Stream(ctx context.Context, messages []agent.Message) (<-chan agent.Response, error) {
+	if len(p.stages) == 0 {
+		return nil, fmt.Errorf("pipeline has no stages")
+	}
+	out := make(chan agent.Response)
+	go func() {
+		defer close(out)
+		// Keep draining after the caller cancels so the stage can finish
+		p.run(ctx, messages, forward(ctx, out))
+	}()
+	return out, nil
+}
+
+// run runs the stages, passing each labeled response to emit if it is set
+func (p *Pipeline) run(ctx context.Context, messages []agent.Message, emit func(agent.Response)) (PipelineResult, error) {
+	var result PipelineResult
+	if len(p.stages) == 0 {
+		return result, fmt.Errorf("pipeline has no stages")
+	}
+
+	input := messages
+	for i, stage := range p.stages {
+		if i > 0 {
+			output := result.Output
+			if stage.Transform != nil {
+				var err error
+				if output, err = stage.Transform(ctx, output); err != nil {
+					err = fmt.Errorf("stage %s: transform: %w", stage.Name, err)
+					if emit != nil {
+						emit(labeled(stage.Name, agent.NewErrorResponse(err)))
+					}
+					return result, err
+				}
+			}
+			input = []agent.Message{agent.UserTextMessage(output)}
+		}
+
+		trace := runStage(ctx, stage, input, emit)
+		result.Traces = append(result.Traces, trace)
+		result.Usage = result.Usage.Add(trace.Completion.Usage)
+		if trace.Err != nil {
+			return result, fmt.Errorf("stage %s: %w", stage.Name, trace.Err)
+		}
+		result.Output = answer(trace)
+	}
+	return result, nil
+}
+
+// runStage streams a stage's run, passing each response to emit if it is set
+func runStage(ctx context.Context, stage Stage, input []agent.Message, emit func(agent.Response)) Trace {
+	trace := Trace{Worker: stage.Name, Task: Task{Worker: stage.Name, Messages: input}}
+
+	responseChan, err := stage.Agent.StreamChatCompletion(ctx, input)
+	if err != nil {
+		trace.Err = err
+		if emit != nil {
+			emit(labeled(stage.Name, agent.NewErrorResponse(err)))
+		}
+		return trace
+	}
+
+	for response := range responseChan {
+		if emit != nil {
+			emit(labeled(stage.Name, response))
+		}
+		trace.Completion.Responses = append(trace.Completion.Responses, response)
+		switch {
+		case response.IsUsageResponse():
+			usage := response.Usage()
+			trace.Completion.Usage = trace.Completion.Usage.Add(usage)
+			trace.Completion.Steps = append(trace.Completion.Steps, usage)
+		case response.IsContentResponse():
+			trace.Completion.Messages = append(trace.Completion.Messages, response.Content())
+		case response.IsErrorResponse():
+			trace.Err = response.Error()
+		}
+	}
+	return trace
+}
+
+// labeled sets a response's Source to name, as a prefix of a nested source
+func labeled(name string, response agent.Response) agent.Response {
+	if response.Source == "" {
+		response.Source = name
+	} else {
+		response.Source = name + "/" + response.Source
+	}
+	return response
+}
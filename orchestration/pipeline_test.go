@@ -0,0 +1,130 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPipelineTestClient creates a client for a server that answers each model
+// with "<model>(<last message>)", failing the model "fail"
+func newPipelineTestClient(t *testing.T) openai.Client {
+	t.Helper()
+	return newTestClient(t, func(request agenttest.Request) agenttest.Reply {
+		if request.Model == "fail" {
+			return agenttest.Fail(http.StatusBadRequest, "bad request")
+		}
+		return agenttest.Text(fmt.Sprintf("%s(%s)", request.Model, request.LastMessage().Content))
+	})
+}
+
+func TestPipelineRun(t *testing.T) {
+	client := newPipelineTestClient(t)
+	pipeline := NewPipeline(
+		Stage{Name: "draft", Agent: agent.NewAgentWithClient(client, "writer")},
+		Stage{Name: "edit", Agent: agent.NewAgentWithClient(client, "editor"), Transform: func(ctx context.Context, output string) (string, error) {
+			return "Tighten: " + output, nil
+		}},
+	)
+
+	result, err := pipeline.Run(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+
+	require.NoError(t, err)
+	assert.Equal(t, "editor(Tighten: writer(topic))", result.Output)
+	require.Len(t, result.Traces, 2)
+	assert.Equal(t, "draft", result.Traces[0].Worker)
+	assert.Equal(t, []string{"writer(topic)"}, result.Traces[0].Completion.Messages)
+	assert.Equal(t, []agent.Message{agent.UserTextMessage("Tighten: writer(topic)")}, result.Traces[1].Task.Messages)
+	assert.Equal(t, int64(22), result.Usage.TotalTokens)
+}
+
+func TestPipelineStream(t *testing.T) {
+	client := newPipelineTestClient(t)
+	pipeline := NewPipeline(
+		Stage{Name: "draft", Agent: agent.NewAgentWithClient(client, "writer")},
+		Stage{Name: "edit", Agent: agent.NewAgentWithClient(client, "editor")},
+	)
+
+	responses, err := pipeline.Stream(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	require.NoError(t, err)
+
+	var contents []string
+	for response := range responses {
+		if response.IsContentResponse() {
+			contents = append(contents, response.Source+": "+response.Content())
+		}
+	}
+	assert.Equal(t, []string{"draft: writer(topic)", "edit: editor(writer(topic))"}, contents)
+}
+
+func TestPipelineStreamCanceled(t *testing.T) {
+	client := newPipelineTestClient(t)
+	pipeline := NewPipeline(
+		Stage{Name: "draft", Agent: agent.NewAgentWithClient(client, "writer")},
+		Stage{Name: "edit", Agent: agent.NewAgentWithClient(client, "editor")},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	responses, err := pipeline.Stream(ctx, []agent.Message{agent.UserTextMessage("topic")})
+	require.NoError(t, err)
+	// Give the stage time to end before anything reads the stream
+	time.Sleep(20 * time.Millisecond)
+
+	var last agent.Response
+	for response := range responses {
+		last = response
+	}
+	var canceled *agent.CanceledError
+	assert.ErrorAs(t, last.Error(), &canceled)
+	assert.Equal(t, "draft", last.Source)
+}
+
+func TestPipelineStageError(t *testing.T) {
+	client := newPipelineTestClient(t)
+	pipeline := NewPipeline(
+		Stage{Name: "draft", Agent: agent.NewAgentWithClient(client, "writer")},
+		Stage{Name: "review", Agent: agent.NewAgentWithClient(client, "fail")},
+		Stage{Name: "edit", Agent: agent.NewAgentWithClient(client, "editor")},
+	)
+
+	result, err := pipeline.Run(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	assert.ErrorContains(t, err, "stage review")
+	assert.Len(t, result.Traces, 2)
+	assert.Equal(t, "writer(topic)", result.Output)
+
+	responses, err := pipeline.Stream(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	require.NoError(t, err)
+	var last agent.Response
+	for response := range responses {
+		last = response
+	}
+	assert.Equal(t, "review", last.Source)
+	assert.Error(t, last.Error())
+}
+
+func TestPipelineTransformError(t *testing.T) {
+	client := newPipelineTestClient(t)
+	pipeline := NewPipeline(
+		Stage{Name: "draft", Agent: agent.NewAgentWithClient(client, "writer")},
+		Stage{Name: "edit", Agent: agent.NewAgentWithClient(client, "editor"), Transform: func(ctx context.Context, output string) (string, error) {
+			if !strings.HasPrefix(output, "{") {
+				return "", errors.New("not JSON")
+			}
+			return output, nil
+		}},
+	)
+
+	_, err := pipeline.Run(context.Background(), []agent.Message{agent.UserTextMessage("topic")})
+	assert.ErrorContains(t, err, "stage edit: transform: not JSON")
+}
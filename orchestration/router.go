@@ -0,0 +1,169 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// ErrNoRoute is returned when a RouterAgent's classifier picks no known worker
+// and no fallback is configured
+var ErrNoRoute = errors.New("no route")
+
+// RouterOption is a functional option for configuring a RouterAgent
+type RouterOption func(*RouterAgent)
+
+// WithFallbackWorker sends conversations the classifier cannot place to the named worker
+func WithFallbackWorker(name string) RouterOption {
+	return func(r *RouterAgent) {
+		r.fallback = name
+	}
+}
+
+// RouterAgent is a ChatAgent that dispatches each conversation to one of its
+// workers by intent. A classifier, typically a small and cheap model, reads the
+// conversation and the workers' descriptions and names the worker to run it.
+type RouterAgent struct {
+	classifier agent.ChatAgent
+	workers    []Worker
+	fallback   string
+}
+
+var _ agent.ChatAgent = (*RouterAgent)(nil)
+
+// NewRouterAgent creates a router that classifies with classifier and
+// dispatches to workers, which should each have a Description of the requests
+// they handle
+func NewRouterAgent(classifier agent.ChatAgent, workers []Worker, opts ...RouterOption) *RouterAgent {
+	r := &RouterAgent{
+		classifier: classifier,
+		workers:    workers,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// ChatCompletion routes the conversation and returns the selected worker's result
+func (r *RouterAgent) ChatCompletion(ctx context.Context, messages []agent.Message, opts ...agent.RunOption) (agent.Completion, error) {
+	responses, err := r.StreamChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return agent.Completion{}, err
+	}
+	return agent.Collect(responses)
+}
+
+// StreamChatCompletion classifies the conversation and streams the selected
+// worker's run, with opts, labeled with the worker's name in Source. The
+// classifier's usage responses come first, unlabeled.
+func (r *RouterAgent) StreamChatCompletion(ctx context.Context, messages []agent.Message, opts ...agent.RunOption) (<-chan agent.Response, error) {
+	worker, classification, err := r.route(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := worker.Agent.StreamChatCompletion(ctx, messages, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan agent.Response)
+	go func() {
+		defer close(out)
+		send := forward(ctx, out)
+		for _, response := range classification.Responses {
+			if response.IsUsageResponse() {
+				send(response)
+			}
+		}
+		// Keep draining after the caller cancels so the worker can finish
+		for response := range agent.MergeStreams(ctx, agent.Source{ID: worker.Name, Responses: responses}) {
+			send(response)
+		}
+	}()
+	return out, nil
+}
+
+// Route returns the name of the worker the classifier picks for the conversation
+func (r *RouterAgent) Route(ctx context.Context, messages []agent.Message) (string, error) {
+	worker, _, err := r.route(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	return worker.Name, nil
+}
+
+// route classifies the conversation, returning the worker and the classifier's completion
+func (r *RouterAgent) route(ctx context.Context, messages []agent.Message) (*Worker, agent.Completion, error) {
+	if len(r.workers) == 1 {
+		return &r.workers[0], agent.Completion{}, nil
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("You route requests to the agent best suited to handle them. The agents are:\n")
+	for _, worker := range r.workers {
+		fmt.Fprintf(&prompt, "- %s: %s\n", worker.Name, worker.Description)
+	}
+	prompt.WriteString("\nReply with only the name of one agent.")
+
+	var transcript strings.Builder
+	for _, msg := range messages {
+		if (msg.Role() == agent.RoleUser || msg.Role() == agent.RoleAssistant) && msg.Text() != "" {
+			fmt.Fprintf(&transcript, "%s: %s\n", msg.Role(), msg.Text())
+		}
+	}
+
+	classification, err := r.classifier.ChatCompletion(ctx, []agent.Message{
+		agent.SystemMessage(prompt.String()),
+		agent.UserTextMessage(transcript.String()),
+	})
+	if err != nil {
+		return nil, classification, fmt.Errorf("classify request: %w", err)
+	}
+
+	reply := strings.Join(classification.Messages, "")
+	if worker := matchWorker(r.workers, reply); worker != nil {
+		return worker, classification, nil
+	}
+	if r.fallback != "" {
+		if worker := findWorker(r.workers, r.fallback); worker != nil {
+			return worker, classification, nil
+		}
+		return nil, classification, fmt.Errorf("unknown fallback worker %q", r.fallback)
+	}
+	return nil, classification, fmt.Errorf("%w: classifier replied %q", ErrNoRoute, reply)
+}
+
+// matchWorker returns the worker a model's reply names: exactly, or as the
+// only worker name it mentions
+func matchWorker(workers []Worker, reply string) *Worker {
+	name := strings.Trim(strings.TrimSpace(reply), "\"'`.")
+	if worker := findWorker(workers, name); worker != nil {
+		return worker
+	}
+	var mentioned *Worker
+	for i := range workers {
+		if strings.Contains(strings.ToLower(reply), strings.ToLower(workers[i].Name)) {
+			if mentioned != nil {
+				return nil
+			}
+			mentioned = &workers[i]
+		}
+	}
+	return mentioned
+}
+
+// findWorker returns the worker with a name, ignoring case
+func findWorker(workers []Worker, name string) *Worker {
+	for i := range workers {
+		if strings.EqualFold(workers[i].Name, name) {
+			return &workers[i]
+		}
+	}
+	return nil
+}
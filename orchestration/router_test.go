@@ -0,0 +1,105 @@
+package orchestration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRouterTestAgents creates a classifier that answers with classify's reply
+// to the transcript, and billing and support workers that answer "<model> done"
+func newRouterTestAgents(t *testing.T, classify func(transcript string) string) (agent.ChatAgent, []Worker) {
+	t.Helper()
+	client := newTestClient(t, func(request agenttest.Request) agenttest.Reply {
+		if request.Model == "classifier" {
+			assert.Contains(t, request.Messages[0].Content, "- billing: Invoices and refunds")
+			return agenttest.Text(classify(request.LastMessage().Content))
+		}
+		return agenttest.Text(request.Model + " done")
+	})
+	return agent.NewAgentWithClient(client, "classifier"), []Worker{
+		{Name: "billing", Description: "Invoices and refunds", Agent: agent.NewAgentWithClient(client, "billing-model")},
+		{Name: "support", Description: "Technical problems", Agent: agent.NewAgentWithClient(client, "support-model")},
+	}
+}
+
+func TestRouterAgent(t *testing.T) {
+	classifier, workers := newRouterTestAgents(t, func(transcript string) string {
+		if strings.Contains(transcript, "refund") {
+			return "billing"
+		}
+		return "Support."
+	})
+	router := NewRouterAgent(classifier, workers)
+
+	completion, err := router.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("I want a refund")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"billing-model done"}, completion.Messages)
+	// The classifier's usage is included, unlabeled, before the worker's
+	require.Len(t, completion.Steps, 2)
+	assert.Equal(t, "classifier", completion.Steps[0].Model)
+	assert.Equal(t, "", completion.Responses[0].Source)
+	assert.Equal(t, "billing", completion.Responses[1].Source)
+
+	name, err := router.Route(context.Background(), []agent.Message{agent.UserTextMessage("The app crashes")})
+	require.NoError(t, err)
+	assert.Equal(t, "support", name)
+}
+
+func TestRouterAgentCanceled(t *testing.T) {
+	classifier, workers := newRouterTestAgents(t, nil)
+	// A single worker is picked without asking the classifier
+	router := NewRouterAgent(classifier, workers[:1])
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	responses, err := router.StreamChatCompletion(ctx, []agent.Message{agent.UserTextMessage("I want a refund")})
+	require.NoError(t, err)
+	// Give the worker time to end before anything reads the stream
+	time.Sleep(20 * time.Millisecond)
+
+	completion, err := agent.Collect(responses)
+	var canceled *agent.CanceledError
+	require.ErrorAs(t, err, &canceled)
+	assert.Equal(t, agent.TerminationCanceled, completion.TerminationReason)
+}
+
+func TestRouterAgentNoRoute(t *testing.T) {
+	classifier, workers := newRouterTestAgents(t, func(transcript string) string {
+		return "I am not sure"
+	})
+
+	_, err := NewRouterAgent(classifier, workers).ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("hello")})
+	assert.ErrorIs(t, err, ErrNoRoute)
+
+	completion, err := NewRouterAgent(classifier, workers, WithFallbackWorker("support")).
+		ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support-model done"}, completion.Messages)
+}
+
+func TestMatchWorker(t *testing.T) {
+	workers := []Worker{{Name: "billing"}, {Name: "support"}}
+
+	for reply, want := range map[string]string{
+		"billing":                      "billing",
+		" `Support` ":                  "support",
+		"This is for the billing team": "billing",
+		"billing or support":           "",
+		"sales":                        "",
+	} {
+		worker := matchWorker(workers, reply)
+		if want == "" {
+			assert.Nil(t, worker, reply)
+			continue
+		}
+		require.NotNil(t, worker, reply)
+		assert.Equal(t, want, worker.Name, reply)
+	}
+}
@@ -0,0 +1,24 @@
+package orchestration
+
+import (
+	"context"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// forward returns a function sending responses to out. Once ctx is done,
+// responses other than errors are discarded so the runs behind the stream can
+// finish, but an error response is still sent so the caller learns how the
+// run ended.
+func forward(ctx context.Context, out chan<- agent.Response) func(agent.Response) {
+	return func(response agent.Response) {
+		if response.IsErrorResponse() {
+			out <- response
+			return
+		}
+		select {
+		case out <- response:
+		case <-ctx.Done():
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package orchestration coordinates multiple agents working on a shared goal.
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// ErrBudgetExceeded is returned when a Supervisor run exceeds its Budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Worker is a named agent owned by a Supervisor
+type Worker struct {
+	Name        string
+	Description string
+	Agent       agent.ChatAgent
+}
+
+// Task is a subtask for a worker. If Worker is empty the Supervisor's Router picks one.
+type Task struct {
+	Worker   string
+	Messages []agent.Message
+}
+
+// Router picks the worker for a task that does not name one
+type Router interface {
+	Route(ctx context.Context, task Task, workers []Worker) (string, error)
+}
+
+// RouterFunc adapts a function to the Router interface
+type RouterFunc func(ctx context.Context, task Task, workers []Worker) (string, error)
+
+func (f RouterFunc) Route(ctx context.Context, task Task, workers []Worker) (string, error) {
+	return f(ctx, task, workers)
+}
+
+// Budget bounds a Supervisor run across all workers. A zero value for any field disables that limit.
+type Budget struct {
+	// MaxIterations is the maximum number of model requests across all workers
+	MaxIterations int
+	// MaxCost is the maximum cost in USD across all workers, priced with Prices
+	MaxCost float64
+	// Prices maps model names to their price; models without a price cost nothing
+	Prices map[string]agent.Price
+}
+
+// Trace records one worker's part of a run
+type Trace struct {
+	Worker     string
+	Task       Task
+	Completion agent.Completion
+	Err        error
+}
+
+// Result is the outcome of a Supervisor run
+type Result struct {
+	// Completion combines the workers' completions in task order
+	Completion agent.Completion
+	Traces     []Trace
+	Cost       float64
+}
+
+// Option is a functional option for configuring a Supervisor
+type Option func(*Supervisor)
+
+// WithRouter sets the router for tasks that do not name a worker
+func WithRouter(router Router) Option {
+	return func(s *Supervisor) {
+		s.router = router
+	}
+}
+
+// WithBudget sets the global budget enforced across all workers
+func WithBudget(budget Budget) Option {
+	return func(s *Supervisor) {
+		s.budget = budget
+	}
+}
+
+// Supervisor owns a set of worker agents, routes subtasks to them, runs them
+// concurrently, and aggregates their results under a global budget
+type Supervisor struct {
+	workers []Worker
+	router  Router
+	budget  Budget
+}
+
+// NewSupervisor creates a Supervisor over the given workers
+func NewSupervisor(workers []Worker, opts ...Option) *Supervisor {
+	s := &Supervisor{
+		workers: workers,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Run executes the tasks concurrently and returns the combined result. If the
+// budget is exceeded, all workers are canceled and ErrBudgetExceeded is returned
+// along with the partial result.
+func (s *Supervisor) Run(ctx context.Context, tasks []Task) (Result, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	assigned, err := s.assignAll(ctx, tasks)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tracker := &budgetTracker{budget: s.budget, cancel: cancel}
+	traces := make([]Trace, len(tasks))
+
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			traces[i] = s.runTask(ctx, assigned[i], task, tracker, nil)
+		}()
+	}
+	wg.Wait()
+
+	result := Result{Traces: traces, Cost: tracker.cost}
+	for _, trace := range traces {
+		result.Completion.Usage = result.Completion.Usage.Add(trace.Completion.Usage)
+		result.Completion.Steps = append(result.Completion.Steps, trace.Completion.Steps...)
+		result.Completion.Messages = append(result.Completion.Messages, trace.Completion.Messages...)
+		result.Completion.Responses = append(result.Completion.Responses, trace.Completion.Responses...)
+	}
+
+	if cause := context.Cause(ctx); errors.Is(cause, ErrBudgetExceeded) {
+		return result, cause
+	}
+	for _, trace := range traces {
+		if trace.Err != nil {
+			return result, fmt.Errorf("worker %s: %w", trace.Worker, trace.Err)
+		}
+	}
+	return result, nil
+}
+
+// Stream executes the tasks concurrently like Run and returns the workers'
+// responses as one stream, each labeled with its worker's name in Source. A
+// worker given several tasks is labeled "name#2", "name#3", and so on for the
+// later ones. Each worker's responses arrive in order. If the budget is
+// exceeded, the workers are canceled and the stream ends with an unlabeled
+// error response wrapping ErrBudgetExceeded.
+func (s *Supervisor) Stream(ctx context.Context, tasks []Task) (<-chan agent.Response, error) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+
+	assigned, err := s.assignAll(runCtx, tasks)
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+
+	tracker := &budgetTracker{budget: s.budget, cancel: cancel}
+	sources := make([]agent.Source, 0, len(tasks))
+	counts := map[string]int{}
+
+	for i, task := range tasks {
+		worker := assigned[i]
+		counts[worker.Name]++
+		label := worker.Name
+		if n := counts[worker.Name]; n > 1 {
+			label = fmt.Sprintf("%s#%d", worker.Name, n)
+		}
+
+		responses := make(chan agent.Response)
+		sources = append(sources, agent.Source{ID: label, Responses: responses})
+		go func() {
+			defer close(responses)
+			s.runTask(runCtx, worker, task, tracker, func(response agent.Response) {
+				responses <- response
+			})
+		}()
+	}
+
+	// The merged stream closes once every worker has finished, so a budget
+	// overrun is reported after all of their responses
+	merged := agent.MergeStreams(ctx, sources...)
+	out := make(chan agent.Response)
+	go func() {
+		defer close(out)
+		defer cancel(nil)
+		send := forward(ctx, out)
+		// Keep draining after the caller cancels so the workers can finish
+		for response := range merged {
+			send(response)
+		}
+		if cause := context.Cause(runCtx); errors.Is(cause, ErrBudgetExceeded) {
+			send(agent.NewErrorResponse(cause))
+		}
+	}()
+
+	return out, nil
+}
+
+// assignAll resolves every task's worker up front so routing errors fail fast
+func (s *Supervisor) assignAll(ctx context.Context, tasks []Task) ([]*Worker, error) {
+	assigned := make([]*Worker, len(tasks))
+	for i, task := range tasks {
+		worker, err := s.assign(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		assigned[i] = worker
+	}
+	return assigned, nil
+}
+
+// assign returns the worker for a task, consulting the router if the task names none
+func (s *Supervisor) assign(ctx context.Context, task Task) (*Worker, error) {
+	name := task.Worker
+	if name == "" {
+		switch {
+		case s.router != nil:
+			var err error
+			if name, err = s.router.Route(ctx, task, s.workers); err != nil {
+				return nil, err
+			}
+		case len(s.workers) == 1:
+			name = s.workers[0].Name
+		default:
+			return nil, fmt.Errorf("task names no worker and no router is configured")
+		}
+	}
+	for i := range s.workers {
+		if s.workers[i].Name == name {
+			return &s.workers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown worker %q", name)
+}
+
+// runTask streams a task through its worker, charging each request to the
+// budget and passing each response to emit if it is set
+func (s *Supervisor) runTask(ctx context.Context, worker *Worker, task Task, tracker *budgetTracker, emit func(agent.Response)) Trace {
+	trace := Trace{Worker: worker.Name, Task: task}
+
+	responseChan, err := worker.Agent.StreamChatCompletion(ctx, task.Messages)
+	if err != nil {
+		trace.Err = err
+		if emit != nil {
+			emit(agent.NewErrorResponse(err))
+		}
+		return trace
+	}
+
+	for response := range responseChan {
+		if emit != nil {
+			emit(response)
+		}
+		trace.Completion.Responses = append(trace.Completion.Responses, response)
+		switch {
+		case response.IsUsageResponse():
+			usage := response.Usage()
+			trace.Completion.Usage = trace.Completion.Usage.Add(usage)
+			trace.Completion.Steps = append(trace.Completion.Steps, usage)
+			tracker.charge(usage)
+		case response.IsContentResponse():
+			trace.Completion.Messages = append(trace.Completion.Messages, response.Content())
+		case response.IsErrorResponse():
+			trace.Err = response.Error()
+		}
+	}
+	return trace
+}
+
+// budgetTracker accumulates spend across workers and cancels the run when the budget is exceeded
+type budgetTracker struct {
+	budget Budget
+	cancel context.CancelCauseFunc
+
+	mu         sync.Mutex
+	iterations int
+	cost       float64
+}
+
+func (b *budgetTracker) charge(usage agent.Usage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.iterations++
+	if price, ok := b.budget.Prices[usage.Model]; ok {
+		b.cost += price.Cost(usage)
+	}
+
+	switch {
+	case b.budget.MaxIterations > 0 && b.iterations > b.budget.MaxIterations:
+		b.cancel(fmt.Errorf("%w: %d iterations > %d", ErrBudgetExceeded, b.iterations, b.budget.MaxIterations))
+	case b.budget.MaxCost > 0 && b.cost > b.budget.MaxCost:
+		b.cancel(fmt.Errorf("%w: $%.4f > $%.4f", ErrBudgetExceeded, b.cost, b.budget.MaxCost))
+	}
+}
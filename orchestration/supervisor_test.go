@@ -0,0 +1,176 @@
+package orchestration
+
+import (
+	"context"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient creates a client for an agenttest.Server that answers each
+// request with handle's reply, using 11 tokens unless the reply sets its usage
+func newTestClient(t *testing.T, handle func(request agenttest.Request) agenttest.Reply) openai.Client {
+	t.Helper()
+	server := agenttest.NewServer(t)
+	server.Handle(func(request agenttest.Request) agenttest.Reply {
+		reply := handle(request)
+		if reply.Usage == nil {
+			reply.Usage = &agent.Usage{PromptTokens: 10, CompletionTokens: 1, TotalTokens: 11}
+		}
+		return reply
+	})
+	return openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL+"/"), option.WithMaxRetries(0))
+}
+
+// workerReply answers each model with "<model> done", or with a tool call to
+// loop forever when the model is "looper"
+func workerReply(request agenttest.Request) agenttest.Reply {
+	if request.Model == "looper" {
+		return agenttest.CallTool("noop", "{}")
+	}
+	return agenttest.Text(request.Model + " done")
+}
+
+// noopTool does nothing
+type noopTool struct{}
+
+func (noopTool) Name() string                 { return "noop" }
+func (noopTool) Description() string          { return "Does nothing" }
+func (noopTool) Parameters() agent.Parameters { return agent.Parameters{} }
+func (noopTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return "ok", nil
+}
+
+func TestSupervisorRun(t *testing.T) {
+	client := newTestClient(t, workerReply)
+	supervisor := NewSupervisor([]Worker{
+		{Name: "researcher", Agent: agent.NewAgentWithClient(client, "research-model")},
+		{Name: "writer", Agent: agent.NewAgentWithClient(client, "writer-model")},
+	}, WithRouter(RouterFunc(func(ctx context.Context, task Task, workers []Worker) (string, error) {
+		return "writer", nil
+	})))
+
+	result, err := supervisor.Run(context.Background(), []Task{
+		{Worker: "researcher", Messages: []agent.Message{agent.UserTextMessage("Find facts")}},
+		{Messages: []agent.Message{agent.UserTextMessage("Write it up")}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"research-model done", "writer-model done"}, result.Completion.Messages)
+	assert.Equal(t, int64(22), result.Completion.Usage.TotalTokens)
+	require.Len(t, result.Traces, 2)
+	assert.Equal(t, "researcher", result.Traces[0].Worker)
+	assert.Equal(t, "writer", result.Traces[1].Worker)
+}
+
+func TestSupervisorBudget(t *testing.T) {
+	client := newTestClient(t, workerReply)
+
+	tests := []struct {
+		name   string
+		budget Budget
+	}{
+		{name: "iterations", budget: Budget{MaxIterations: 3}},
+		{name: "cost", budget: Budget{MaxCost: 0.01, Prices: map[string]agent.Price{"looper": {Prompt: 100, Completion: 100}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supervisor := NewSupervisor([]Worker{
+				{Name: "looper", Agent: agent.NewAgentWithClient(client, "looper", agent.WithTools([]agent.Tool{noopTool{}}))},
+			}, WithBudget(tt.budget))
+
+			_, err := supervisor.Run(context.Background(), []Task{
+				{Messages: []agent.Message{agent.UserTextMessage("Loop")}},
+			})
+			assert.ErrorIs(t, err, ErrBudgetExceeded)
+		})
+	}
+}
+
+func TestSupervisorUnknownWorker(t *testing.T) {
+	supervisor := NewSupervisor([]Worker{
+		{Name: "a", Agent: agent.NewAgent("key", "http://127.0.0.1:0", "model")},
+		{Name: "b", Agent: agent.NewAgent("key", "http://127.0.0.1:0", "model")},
+	})
+
+	_, err := supervisor.Run(context.Background(), []Task{{Worker: "c"}})
+	assert.ErrorContains(t, err, `unknown worker "c"`)
+
+	_, err = supervisor.Run(context.Background(), []Task{{}})
+	assert.ErrorContains(t, err, "no router")
+}
+
+func TestSupervisorStream(t *testing.T) {
+	client := newTestClient(t, workerReply)
+	supervisor := NewSupervisor([]Worker{
+		{Name: "researcher", Agent: agent.NewAgentWithClient(client, "research-model")},
+		{Name: "writer", Agent: agent.NewAgentWithClient(client, "writer-model")},
+	})
+
+	responses, err := supervisor.Stream(context.Background(), []Task{
+		{Worker: "researcher", Messages: []agent.Message{agent.UserTextMessage("Find facts")}},
+		{Worker: "writer", Messages: []agent.Message{agent.UserTextMessage("Write it up")}},
+		{Worker: "writer", Messages: []agent.Message{agent.UserTextMessage("Write a summary")}},
+	})
+	require.NoError(t, err)
+
+	kinds := map[string][]agent.ResponseKind{}
+	content := map[string]string{}
+	for response := range responses {
+		kinds[response.Source] = append(kinds[response.Source], response.Kind)
+		content[response.Source] += response.Content()
+	}
+	// Each worker's usage precedes its content
+	for _, source := range []string{"researcher", "writer", "writer#2"} {
+		assert.Equal(t, []agent.ResponseKind{agent.ResponseKindUsage, agent.ResponseKindContent}, kinds[source], source)
+	}
+	assert.Len(t, kinds, 3)
+	assert.Equal(t, map[string]string{"researcher": "research-model done", "writer": "writer-model done", "writer#2": "writer-model done"}, content)
+}
+
+func TestSupervisorStreamBudget(t *testing.T) {
+	client := newTestClient(t, workerReply)
+	supervisor := NewSupervisor([]Worker{
+		{Name: "looper", Agent: agent.NewAgentWithClient(client, "looper", agent.WithTools([]agent.Tool{noopTool{}}))},
+	}, WithBudget(Budget{MaxIterations: 3}))
+
+	responses, err := supervisor.Stream(context.Background(), []Task{
+		{Messages: []agent.Message{agent.UserTextMessage("Loop")}},
+	})
+	require.NoError(t, err)
+
+	var last agent.Response
+	for response := range responses {
+		last = response
+	}
+	assert.Equal(t, "", last.Source)
+	assert.ErrorIs(t, last.Error(), ErrBudgetExceeded)
+}
+
+func TestSupervisorStreamCanceled(t *testing.T) {
+	client := newTestClient(t, workerReply)
+	supervisor := NewSupervisor([]Worker{
+		{Name: "researcher", Agent: agent.NewAgentWithClient(client, "research-model")},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	responses, err := supervisor.Stream(ctx, []Task{
+		{Worker: "researcher", Messages: []agent.Message{agent.UserTextMessage("Find facts")}},
+	})
+	require.NoError(t, err)
+
+	var last agent.Response
+	for response := range responses {
+		last = response
+	}
+	var canceled *agent.CanceledError
+	assert.ErrorAs(t, last.Error(), &canceled)
+	assert.Equal(t, "researcher", last.Source)
+}
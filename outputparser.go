@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// OutputParser turns a final answer into a value. A parser's error should say
+// what is wrong with the answer, since WithOutputParser shows it to the model.
+type OutputParser interface {
+	Parse(text string) (any, error)
+}
+
+// ParserFunc adapts a function to the OutputParser interface
+type ParserFunc func(text string) (any, error)
+
+func (f ParserFunc) Parse(text string) (any, error) {
+	return f(text)
+}
+
+// OutputParseError is returned when a final answer still fails to parse after
+// WithOutputParser's retries
+type OutputParseError struct {
+	Answer string
+	Err    error
+}
+
+func (e *OutputParseError) Error() string {
+	return fmt.Sprintf("parse output: %v", e.Err)
+}
+
+func (e *OutputParseError) Unwrap() error {
+	return e.Err
+}
+
+// WithOutputParser checks the final answer with parser. When it fails to parse,
+// the model is shown the error and asked for a corrected answer, up to retries
+// times, and each rejected answer is reported with a warning response. The
+// answer returned is one the parser accepts; parse it with Completion.Parse.
+func WithOutputParser(parser OutputParser, retries int) AgentOption {
+	return func(a *Agent) {
+		a.outputParser = parser
+		a.parseRetries = retries
+	}
+}
+
+// Parse parses the completion's last message with parser
+func (c Completion) Parse(parser OutputParser) (any, error) {
+	if len(c.Messages) == 0 {
+		return nil, errors.New("completion has no messages")
+	}
+	return parser.Parse(c.Messages[len(c.Messages)-1])
+}
+
+// fixMessage asks the model to correct an answer that failed to parse
+func fixMessage(err error) openai.ChatCompletionMessageParamUnion {
+	return openai.UserMessage(fmt.Sprintf("Your answer could not be parsed: %v\n\nReply with the complete corrected answer only.", err))
+}
+
+// JSONParser parses the first JSON object or array in an answer, found as
+// ParseJSONObjects does, into a json.RawMessage. With Into set, the value is
+// also decoded into a new value from Into, which is returned instead, so decode
+// errors are shown to the model too.
+type JSONParser struct {
+	Into func() any
+}
+
+func (p JSONParser) Parse(text string) (any, error) {
+	objects := ParseJSONObjects(text)
+	if len(objects) == 0 {
+		return nil, errors.New("no JSON object or array found")
+	}
+	if p.Into == nil {
+		return objects[0], nil
+	}
+	v := p.Into()
+	decoder := json.NewDecoder(strings.NewReader(string(objects[0])))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return v, nil
+}
+
+// XMLTagParser parses the contents of XML-style tags, such as
+// <answer>...</answer>, into a map from tag name to trimmed content. Every tag
+// in Tags must be present; other tags are included when Tags is empty. When a
+// tag appears more than once, the last one wins.
+type XMLTagParser struct {
+	Tags []string
+}
+
+// xmlTag matches a tag with its content, which may span lines
+var xmlTag = regexp.MustCompile(`(?s)<([A-Za-z][\w.-]*)>(.*?)</([A-Za-z][\w.-]*)>`)
+
+func (p XMLTagParser) Parse(text string) (any, error) {
+	values := map[string]string{}
+	for _, match := range xmlTag.FindAllStringSubmatch(text, -1) {
+		if match[1] == match[3] {
+			values[match[1]] = strings.TrimSpace(match[2])
+		}
+	}
+	if len(p.Tags) == 0 {
+		if len(values) == 0 {
+			return nil, errors.New("no tags found")
+		}
+		return values, nil
+	}
+
+	result := map[string]string{}
+	var missing []string
+	for _, tag := range p.Tags {
+		value, ok := values[tag]
+		if !ok {
+			missing = append(missing, "<"+tag+">")
+			continue
+		}
+		result[tag] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing tags %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+// ListParser parses the items of a bulleted or numbered list into a []string.
+// Items may start with -, *, +, or a number followed by . or ), and lines
+// indented under an item continue it. With Min set, shorter lists are rejected.
+type ListParser struct {
+	Min int
+}
+
+// listItem matches the marker and text of a list item
+var listItem = regexp.MustCompile(`^\s{0,3}(?:[-*+]|\d+[.)])\s+(.*)$`)
+
+func (p ListParser) Parse(text string) (any, error) {
+	var items []string
+	for _, line := range strings.Split(text, "\n") {
+		if match := listItem.FindStringSubmatch(line); match != nil {
+			items = append(items, strings.TrimSpace(match[1]))
+			continue
+		}
+		// Indented lines continue the previous item; anything else ends the list
+		if trimmed := strings.TrimSpace(line); trimmed != "" && len(items) > 0 && line != strings.TrimLeft(line, " \t") {
+			items[len(items)-1] += " " + trimmed
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("no list items found")
+	}
+	if len(items) < p.Min {
+		return nil, fmt.Errorf("found %d list items, want at least %d", len(items), p.Min)
+	}
+	return items, nil
+}
+
+// KeyValueParser parses "key: value" lines into a map from key to trimmed
+// value. Keys are matched case-insensitively against Keys and stored as
+// spelled there, and every key in Keys must be present; other keys are
+// included when Keys is empty. Markdown emphasis and list markers around keys
+// are ignored.
+type KeyValueParser struct {
+	Keys []string
+}
+
+// keyValueLine matches a "key: value" line, allowing a list marker and bold key
+var keyValueLine = regexp.MustCompile(`^\s*(?:[-*+]\s+)?\**([^:*]+?)\**\s*:\s*(.*)$`)
+
+func (p KeyValueParser) Parse(text string) (any, error) {
+	values := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		if match := keyValueLine.FindStringSubmatch(line); match != nil {
+			values[strings.ToLower(strings.TrimSpace(match[1]))] = strings.TrimSpace(match[2])
+		}
+	}
+	if len(p.Keys) == 0 {
+		if len(values) == 0 {
+			return nil, errors.New("no key: value lines found")
+		}
+		return values, nil
+	}
+
+	result := map[string]string{}
+	var missing []string
+	for _, key := range p.Keys {
+		value, ok := values[strings.ToLower(key)]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		result[key] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing keys %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputParsers(t *testing.T) {
+	type verdict struct {
+		Approved bool `json:"approved"`
+	}
+
+	tests := []struct {
+		name   string
+		parser OutputParser
+		text   string
+		want   any
+		err    string
+	}{
+		{"json block", JSONParser{}, "Here:\n```json\n{\"approved\": true}\n```", json.RawMessage(`{"approved": true}`), ""},
+		{"json into", JSONParser{Into: func() any { return &verdict{} }}, `{"approved": true}`, &verdict{Approved: true}, ""},
+		{"json unknown field", JSONParser{Into: func() any { return &verdict{} }}, `{"ok": true}`, nil, `unknown field "ok"`},
+		{"json missing", JSONParser{}, "I approve.", nil, "no JSON object"},
+		{"xml tags", XMLTagParser{Tags: []string{"answer"}}, "<thinking>hmm</thinking>\n<answer>\n42\n</answer>", map[string]string{"answer": "42"}, ""},
+		{"xml any tags", XMLTagParser{}, "<a>1</a><b>2</b>", map[string]string{"a": "1", "b": "2"}, ""},
+		{"xml missing", XMLTagParser{Tags: []string{"answer", "source"}}, "<answer>42</answer>", nil, "missing tags <source>"},
+		{"list", ListParser{}, "Ideas:\n- one\n* two\n  continued\n3) three", []string{"one", "two continued", "three"}, ""},
+		{"list too short", ListParser{Min: 3}, "1. one\n2. two", nil, "found 2 list items, want at least 3"},
+		{"key values", KeyValueParser{Keys: []string{"Name", "Severity"}}, "- **name**: login bug\nSEVERITY: high\nnotes: none", map[string]string{"Name": "login bug", "Severity": "high"}, ""},
+		{"key values missing", KeyValueParser{Keys: []string{"Name", "Severity"}}, "name: login bug", nil, "missing keys Severity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.Parse(tt.text)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOutputParserRetries(t *testing.T) {
+	var mu sync.Mutex
+	answers := []string{"The answer is 42.", "<answer>42</answer>"}
+	var fixes []string
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		last := lastUserContent(t, r)
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(last, "Your answer could not be parsed") {
+			fixes = append(fixes, last)
+		}
+		writeCompletion(w, "gpt-4o", answers[0])
+		answers = answers[1:]
+	}, WithOutputParser(XMLTagParser{Tags: []string{"answer"}}, 2))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is 6 x 7?")})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<answer>42</answer>"}, completion.Messages)
+	require.Len(t, fixes, 1)
+	assert.Contains(t, fixes[0], "missing tags <answer>")
+	assert.Equal(t, []string{"output failed to parse, retrying: missing tags <answer>"}, completion.Warnings)
+
+	parsed, err := completion.Parse(XMLTagParser{Tags: []string{"answer"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"answer": "42"}, parsed)
+}
+
+func TestOutputParserGivesUp(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "no list here")
+	}, WithOutputParser(ListParser{}, 1))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("List three colors")})
+
+	var parseErr *OutputParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, "no list here", parseErr.Answer)
+	assert.Empty(t, completion.Messages)
+	assert.Len(t, completion.Steps, 2)
+}
@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// CodeBlock is a fenced code block from model output
+type CodeBlock struct {
+	// Language is the first word of the fence's info string, such as "go", or empty
+	Language string
+	Code     string
+}
+
+// Table is a Markdown pipe table from model output. Every row has as many cells
+// as the header.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// CodeBlocks returns the fenced code blocks in the completion's messages
+func (c Completion) CodeBlocks() []CodeBlock {
+	var blocks []CodeBlock
+	for _, message := range c.Messages {
+		blocks = append(blocks, ParseCodeBlocks(message)...)
+	}
+	return blocks
+}
+
+// Tables returns the Markdown tables in the completion's messages
+func (c Completion) Tables() []Table {
+	var tables []Table
+	for _, message := range c.Messages {
+		tables = append(tables, ParseTables(message)...)
+	}
+	return tables
+}
+
+// JSONObjects returns the JSON objects and arrays in the completion's messages
+func (c Completion) JSONObjects() []json.RawMessage {
+	var objects []json.RawMessage
+	for _, message := range c.Messages {
+		objects = append(objects, ParseJSONObjects(message)...)
+	}
+	return objects
+}
+
+// ParseCodeBlocks returns the fenced code blocks in text. Fences of backticks or
+// tildes may be indented up to three spaces, and a block is closed only by a
+// fence of the same character at least as long as the opening one, so blocks
+// can contain shorter fences. A block left open at the end of text, as in a
+// truncated reply, runs to the end.
+func ParseCodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	for _, segment := range splitFences(text) {
+		if segment.fenced {
+			blocks = append(blocks, CodeBlock{Language: segment.language, Code: segment.text})
+		}
+	}
+	return blocks
+}
+
+// ParseTables returns the Markdown pipe tables in text, outside code blocks
+func ParseTables(text string) []Table {
+	var tables []Table
+	for _, segment := range splitFences(text) {
+		if segment.fenced {
+			continue
+		}
+		lines := strings.Split(segment.text, "\n")
+		for i := 0; i+1 < len(lines); i++ {
+			if !strings.Contains(lines[i], "|") || !tableDelimiter.MatchString(lines[i+1]) {
+				continue
+			}
+			header := splitRow(lines[i])
+			if len(header) != len(splitRow(lines[i+1])) {
+				continue
+			}
+			table := Table{Header: header}
+			i += 2
+			for ; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				row := splitRow(lines[i])
+				// Pad or trim rows to the header's width
+				row = append(row, make([]string, max(0, len(header)-len(row)))...)
+				table.Rows = append(table.Rows, row[:len(header)])
+			}
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// ParseJSONObjects returns the JSON objects and arrays in text, in order. Code
+// blocks tagged json, or untagged, are parsed whole when they hold a single
+// value; otherwise values are found by scanning for the first valid value at
+// each opening brace or bracket. Code in other languages is skipped.
+func ParseJSONObjects(text string) []json.RawMessage {
+	var objects []json.RawMessage
+	for _, segment := range splitFences(text) {
+		if segment.fenced {
+			language := strings.ToLower(segment.language)
+			if language != "" && language != "json" && language != "jsonc" && language != "jsonl" {
+				continue
+			}
+			trimmed := strings.TrimSpace(segment.text)
+			if json.Valid([]byte(trimmed)) && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) {
+				objects = append(objects, json.RawMessage(trimmed))
+				continue
+			}
+		}
+		objects = append(objects, scanJSON(segment.text)...)
+	}
+	return objects
+}
+
+// scanJSON finds the JSON objects and arrays embedded in prose
+func scanJSON(text string) []json.RawMessage {
+	var objects []json.RawMessage
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(text[i:]))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			continue
+		}
+		// Skip empty and trivial values such as "[1]" in "see note [1]"
+		compact := bytes.TrimSpace(raw)
+		if raw[0] == '[' && !bytes.ContainsAny(compact, "{\"") {
+			continue
+		}
+		objects = append(objects, raw)
+		i += int(decoder.InputOffset()) - 1
+	}
+	return objects
+}
+
+// tableDelimiter matches the row separating a table's header from its body
+var tableDelimiter = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+// splitRow splits a table row into trimmed cells, honoring escaped pipes
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// textSegment is a run of prose or the content of a fenced code block
+type textSegment struct {
+	fenced   bool
+	language string
+	text     string
+}
+
+// openingFence matches a code fence with up to three spaces of indentation and
+// an optional info string
+var openingFence = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*)$")
+
+// splitFences splits text into prose and fenced code blocks
+func splitFences(text string) []textSegment {
+	var segments []textSegment
+	var prose []string
+	lines := strings.Split(text, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		match := openingFence.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if match == nil {
+			prose = append(prose, lines[i])
+			continue
+		}
+		if len(prose) > 0 {
+			segments = append(segments, textSegment{text: strings.Join(prose, "\n")})
+			prose = nil
+		}
+
+		indent, fence := len(match[1]), match[2]
+		language, _, _ := strings.Cut(strings.TrimSpace(match[3]), " ")
+		language = strings.Trim(language, "{}.")
+		var code []string
+		for i++; i < len(lines); i++ {
+			line := strings.TrimRight(lines[i], "\r")
+			if closingFence(line, fence) {
+				break
+			}
+			// Remove the opening fence's indentation from the content
+			for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+				line = line[1:]
+			}
+			code = append(code, line)
+		}
+		segments = append(segments, textSegment{fenced: true, language: language, text: strings.Join(code, "\n")})
+	}
+	if len(prose) > 0 {
+		segments = append(segments, textSegment{text: strings.Join(prose, "\n")})
+	}
+	return segments
+}
+
+// closingFence reports whether line closes a block opened with fence
+func closingFence(line string, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	run := len(trimmed) - len(strings.TrimLeft(trimmed, fence[:1]))
+	return run >= len(fence) && strings.TrimSpace(trimmed[run:]) == ""
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCodeBlocks(t *testing.T) {
+	text := "Here is the fix:\n\n" +
+		"```go title=\"main.go\"\nfunc main() {}\n```\n\n" +
+		"  ~~~{.python}\n  print('hi')\n    indented\n  ~~~\n\n" +
+		"````markdown\nUse a fence:\n```sh\nls\n```\n````\n\n" +
+		"```\nplain\n```\n" +
+		"```bash\necho truncated"
+
+	assert.Equal(t, []CodeBlock{
+		{Language: "go", Code: "func main() {}"},
+		{Language: "python", Code: "print('hi')\n  indented"},
+		{Language: "markdown", Code: "Use a fence:\n```sh\nls\n```"},
+		{Language: "", Code: "plain"},
+		{Language: "bash", Code: "echo truncated"},
+	}, ParseCodeBlocks(text))
+}
+
+func TestParseTables(t *testing.T) {
+	text := "Results:\n\n" +
+		"| Name | Score | Note |\n" +
+		"|:-----|------:|------|\n" +
+		"| Ada  | 10    | a \\| b |\n" +
+		"| Bob  | 7 |\n" +
+		"\n" +
+		"```\n| not | a table |\n|---|---|\n```\n" +
+		"a | b\n--- | ---\n1 | 2\n"
+
+	assert.Equal(t, []Table{
+		{
+			Header: []string{"Name", "Score", "Note"},
+			Rows:   [][]string{{"Ada", "10", "a | b"}, {"Bob", "7", ""}},
+		},
+		{
+			Header: []string{"a", "b"},
+			Rows:   [][]string{{"1", "2"}},
+		},
+	}, ParseTables(text))
+}
+
+func TestParseJSONObjects(t *testing.T) {
+	text := "The result is {\"status\": \"ok\", \"items\": [1, 2]} as noted [1].\n\n" +
+		"```json\n[{\"id\": 1}, {\"id\": 2}]\n```\n\n" +
+		"```js\nconst x = {\"ignored\": true}\n```\n\n" +
+		"```jsonl\n{\"line\": 1}\n{\"line\": 2}\n```\n" +
+		"Broken: {\"a\": } then {\"b\": 2}"
+
+	var got []string
+	for _, raw := range ParseJSONObjects(text) {
+		got = append(got, string(raw))
+	}
+	assert.Equal(t, []string{
+		`{"status": "ok", "items": [1, 2]}`,
+		`[{"id": 1}, {"id": 2}]`,
+		`{"line": 1}`,
+		`{"line": 2}`,
+		`{"b": 2}`,
+	}, got)
+}
+
+func TestCompletionParsers(t *testing.T) {
+	completion := Completion{Messages: []string{
+		"Checking the data.",
+		"```sql\nSELECT 1\n```\n| a |\n|---|\n| 1 |\n\n```json\n{\"answer\": 42}\n```",
+	}}
+
+	assert.Equal(t, []CodeBlock{{Language: "sql", Code: "SELECT 1"}, {Language: "json", Code: `{"answer": 42}`}}, completion.CodeBlocks())
+	assert.Equal(t, []Table{{Header: []string{"a"}, Rows: [][]string{{"1"}}}}, completion.Tables())
+
+	objects := completion.JSONObjects()
+	require.Len(t, objects, 1)
+	var answer struct {
+		Answer int `json:"answer"`
+	}
+	require.NoError(t, json.Unmarshal(objects[0], &answer))
+	assert.Equal(t, 42, answer.Answer)
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// JSONStream parses JSON that arrives in pieces, such as structured output
+// streamed as text deltas, so a UI can render the value before it is complete.
+// After each piece, the value parsed so far is valid JSON: strings being
+// written are closed, open objects and arrays are closed, and keys, numbers,
+// and literals that are still being written are left out until they finish.
+// Text before the first brace or bracket, such as a code fence, is skipped.
+// Each piece is scanned once, so long values stream in linear time. The zero
+// value is ready to use. A JSONStream is not safe for concurrent use.
+type JSONStream struct {
+	text     strings.Builder
+	parser   *partialParser
+	value    json.RawMessage
+	complete bool
+}
+
+// Append adds a piece of text and reports whether the value parsed so far changed
+func (s *JSONStream) Append(delta string) bool {
+	if s.complete {
+		return false
+	}
+	if s.parser == nil {
+		start := strings.IndexAny(delta, "{[")
+		if start < 0 {
+			return false
+		}
+		delta = delta[start:]
+		s.parser = newPartialParser()
+	}
+
+	offset := s.text.Len()
+	s.text.WriteString(delta)
+	text := s.text.String()
+	value := json.RawMessage(nil)
+	for i := offset; i < len(text); i++ {
+		if s.parser.step(text, i) {
+			value, s.complete = json.RawMessage(text[:i+1]), true
+			break
+		}
+	}
+	if !s.complete {
+		value = s.parser.completion(text)
+	}
+
+	changed := string(value) != string(s.value)
+	s.value = value
+	return changed
+}
+
+// Value returns the value parsed so far, or nil before it starts
+func (s *JSONStream) Value() json.RawMessage {
+	return s.value
+}
+
+// Decode decodes the value parsed so far into v. Fields that have not arrived
+// yet are left untouched.
+func (s *JSONStream) Decode(v any) error {
+	if s.value == nil {
+		return nil
+	}
+	return json.Unmarshal(s.value, v)
+}
+
+// Complete reports whether the whole value has arrived. Text after it is ignored.
+func (s *JSONStream) Complete() bool {
+	return s.complete
+}
+
+// ParsePartialJSON returns the JSON object or array in text, which may be cut
+// off, completed as described for JSONStream. It reports whether the value was
+// already complete, and returns nil if no value has started.
+func ParsePartialJSON(text string) (json.RawMessage, bool) {
+	var s JSONStream
+	s.Append(text)
+	return s.Value(), s.Complete()
+}
+
+// partialParser scans JSON text, remembering the last point at which the text
+// could be cut off and closed to form a valid value
+type partialParser struct {
+	// stack holds the closing character of each open object and array
+	stack []byte
+	// expectKey is set inside an object while a key, or its end, may come next
+	expectKey bool
+
+	inString    bool
+	stringIsKey bool
+	// escape is the index of the backslash of an escape sequence being read, or -1
+	escape int
+	// literal is the index at which a number or literal started, or -1
+	literal int
+
+	// cut is where the text can be cut off, and closers what closes it there
+	cut     int
+	closers string
+}
+
+func newPartialParser() *partialParser {
+	return &partialParser{escape: -1, literal: -1}
+}
+
+// step scans the character at i, reporting whether it completed the top-level value
+func (p *partialParser) step(text string, i int) bool {
+	c := text[i]
+
+	if p.inString {
+		switch {
+		case p.escape >= 0:
+			// \uXXXX escapes end after four hex digits, the rest after one character
+			if text[p.escape+1] != 'u' || i-p.escape == 5 {
+				p.escape = -1
+			}
+		case c == '\\':
+			p.escape = i
+		case c == '"':
+			p.inString = false
+			if p.stringIsKey {
+				p.expectKey = false
+			} else {
+				p.checkpoint(i + 1)
+			}
+		}
+		return false
+	}
+
+	if p.literal >= 0 {
+		if strings.IndexByte("+-.0123456789eEtruefalsn", c) >= 0 {
+			return false
+		}
+		p.literal = -1
+		p.checkpoint(i)
+	}
+
+	switch c {
+	case '{':
+		p.stack = append(p.stack, '}')
+		p.expectKey = true
+		p.checkpoint(i + 1)
+	case '[':
+		p.stack = append(p.stack, ']')
+		p.expectKey = false
+		p.checkpoint(i + 1)
+	case '}', ']':
+		if len(p.stack) > 0 {
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+		if len(p.stack) == 0 {
+			return true
+		}
+		p.expectKey = false
+		p.checkpoint(i + 1)
+	case '"':
+		p.inString = true
+		p.stringIsKey = p.expectKey
+	case ',':
+		p.expectKey = len(p.stack) > 0 && p.stack[len(p.stack)-1] == '}'
+	case ':', ' ', '\t', '\n', '\r':
+	default:
+		p.literal = i
+	}
+	return false
+}
+
+// checkpoint records that the text up to end can be closed with the open
+// objects' and arrays' closing characters
+func (p *partialParser) checkpoint(end int) {
+	p.cut, p.closers = end, p.closing()
+}
+
+// closing returns the closing characters of the open objects and arrays, innermost first
+func (p *partialParser) closing() string {
+	closers := make([]byte, len(p.stack))
+	for i, closer := range p.stack {
+		closers[len(p.stack)-1-i] = closer
+	}
+	return string(closers)
+}
+
+// completion closes the text scanned so far. A string value being written is
+// closed where it stands, without a partial escape sequence; anything else
+// unfinished is cut off at the last checkpoint.
+func (p *partialParser) completion(text string) json.RawMessage {
+	if p.inString && !p.stringIsKey {
+		end := len(text)
+		if p.escape >= 0 {
+			end = p.escape
+		}
+		return json.RawMessage(text[:end] + `"` + p.closing())
+	}
+	return json.RawMessage(text[:p.cut] + p.closers)
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePartialJSON(t *testing.T) {
+	for text, want := range map[string]string{
+		`Here you go: `:                  ``,
+		"```json\n{":                     `{}`,
+		`{"title": "Go`:                  `{"title": "Go"}`,
+		`{"title": "Go\`:                 `{"title": "Go"}`,
+		`{"title": "Go\u00`:              `{"title": "Go"}`,
+		`{"title": "Go", "ti`:            `{"title": "Go"}`,
+		`{"title": "Go", "tags":`:        `{"title": "Go"}`,
+		`{"title": "Go", "tags": ["a", `: `{"title": "Go", "tags": ["a"]}`,
+		`{"count": 12`:                   `{}`,
+		`{"count": 12, "ok": tr`:         `{"count": 12}`,
+		`[{"a": [1, {"b": null}`:         `[{"a": [1, {"b": null}]}]`,
+	} {
+		value, complete := ParsePartialJSON(text)
+		assert.False(t, complete, text)
+		assert.Equal(t, want, string(value), text)
+	}
+
+	value, complete := ParsePartialJSON("```json\n{\"a\": \"}\"}\n```")
+	assert.True(t, complete)
+	assert.Equal(t, `{"a": "}"}`, string(value))
+}
+
+func TestJSONStream(t *testing.T) {
+	type recipe struct {
+		Title  string   `json:"title"`
+		Steps  []string `json:"steps"`
+		Serves int      `json:"serves"`
+	}
+	text := `{"title": "Pancakes \"fluffy\" é", "steps": ["Mix", "Fry"], "serves": 4, "vegan": false, "notes": {"tip": null}}`
+
+	// Every prefix parses to valid JSON, one byte at a time
+	var stream JSONStream
+	var titles []string
+	for i := range len(text) {
+		changed := stream.Append(text[i : i+1])
+		if stream.Value() == nil {
+			continue
+		}
+		require.True(t, json.Valid(stream.Value()), "%q", stream.Value())
+		if changed {
+			var r recipe
+			require.NoError(t, stream.Decode(&r))
+			titles = append(titles, r.Title)
+		}
+	}
+	assert.True(t, stream.Complete())
+	assert.JSONEq(t, text, string(stream.Value()))
+	assert.Equal(t, []string{"", "", "P", "Pa"}, titles[:4])
+	assert.Contains(t, titles, "Pancakes \"fluffy\"")
+
+	var r recipe
+	require.NoError(t, stream.Decode(&r))
+	assert.Equal(t, recipe{Title: "Pancakes \"fluffy\" é", Steps: []string{"Mix", "Fry"}, Serves: 4}, r)
+
+	// Text after the value is ignored
+	assert.False(t, stream.Append(`{"more": 1}`))
+}
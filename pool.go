@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ModelWeight is a model's share of a pool's runs, relative to the other weights
+type ModelWeight struct {
+	Model  string
+	Weight int
+}
+
+// PoolStats is a model's traffic through a pool
+type PoolStats struct {
+	// Runs is the number of runs routed to the model
+	Runs int
+	// Requests and Errors count the completion requests sent to the model,
+	// including requests it served as a fallback
+	Requests int
+	Errors   int
+	Usage    Usage
+	// Cost is the USD cost of Usage at the prices set with WithPrices
+	Cost float64
+}
+
+// ModelPool spreads runs across models by weight with smooth weighted
+// round-robin, so {gpt-4o-mini: 80, gpt-4o: 20} sends four of every five runs
+// to gpt-4o-mini, interleaved rather than in bursts. Each run stays on its
+// model for every iteration. A ModelPool is safe for concurrent use and its
+// weights can be changed while agents use it.
+type ModelPool struct {
+	mu      sync.Mutex
+	weights []ModelWeight
+	current []int
+	stats   map[string]PoolStats
+}
+
+// NewModelPool creates a pool over the models with positive weights
+func NewModelPool(weights ...ModelWeight) *ModelPool {
+	p := &ModelPool{stats: map[string]PoolStats{}}
+	p.SetWeights(weights...)
+	return p
+}
+
+// ParseModelWeights parses weights written as "model=weight" pairs separated by
+// commas, such as "gpt-4o-mini=80,gpt-4o=20", for pools configured from
+// environment variables or flags
+func ParseModelWeights(s string) ([]ModelWeight, error) {
+	var weights []ModelWeight
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		model, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("model weight %q is not model=weight", pair)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("model weight %q has an invalid weight", pair)
+		}
+		weights = append(weights, ModelWeight{Model: strings.TrimSpace(model), Weight: weight})
+	}
+	return weights, nil
+}
+
+// SetWeights replaces the pool's models and weights. Models with a weight of
+// zero or less receive no runs. Stats are kept.
+func (p *ModelPool) SetWeights(weights ...ModelWeight) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.weights = slices.DeleteFunc(slices.Clone(weights), func(w ModelWeight) bool {
+		return w.Weight <= 0 || w.Model == ""
+	})
+	p.current = make([]int, len(p.weights))
+}
+
+// Weights returns the pool's models and weights
+func (p *ModelPool) Weights() []ModelWeight {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.weights)
+}
+
+// Stats returns the traffic each model has received, keyed by model
+func (p *ModelPool) Stats() map[string]PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make(map[string]PoolStats, len(p.stats)+len(p.weights))
+	for _, w := range p.weights {
+		stats[w.Model] = PoolStats{}
+	}
+	for model, s := range p.stats {
+		stats[model] = s
+	}
+	return stats
+}
+
+// next picks the model for a run from those allowed, or returns false if the
+// pool has no allowed model
+func (p *ModelPool) next(allowed func(model string) bool) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best, total := -1, 0
+	for i, w := range p.weights {
+		if !allowed(w.Model) {
+			continue
+		}
+		p.current[i] += w.Weight
+		total += w.Weight
+		if best < 0 || p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	p.current[best] -= total
+
+	model := p.weights[best].Model
+	s := p.stats[model]
+	s.Runs++
+	p.stats[model] = s
+	return model, true
+}
+
+// observe records a completion request sent to a model in the pool
+func (p *ModelPool) observe(model string, usage Usage, cost float64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !slices.ContainsFunc(p.weights, func(w ModelWeight) bool { return w.Model == model }) {
+		if _, ok := p.stats[model]; !ok {
+			return
+		}
+	}
+	s := p.stats[model]
+	s.Requests++
+	if err != nil {
+		s.Errors++
+	}
+	s.Usage = s.Usage.Add(usage)
+	s.Cost += cost
+	p.stats[model] = s
+}
+
+// WithModelPool routes each run to a model drawn from the pool in place of the
+// agent's model. If the drawn model fails, the agent's model and fallback
+// models are tried in order. Under an access policy, only models the tenant is
+// entitled to are drawn, and tenants with a Route keep it.
+func WithModelPool(pool *ModelPool) AgentOption {
+	return func(a *Agent) {
+		a.modelPool = pool
+	}
+}
+
+// routePool makes a model drawn from the pool the plan's primary model
+func (agent *Agent) routePool(plan runPlan, options runOptions) runPlan {
+	if agent.modelPool == nil {
+		return plan
+	}
+	allowed := func(string) bool { return true }
+	if agent.accessPolicy != nil {
+		entitlement := agent.accessPolicy[options.tenant]
+		if len(entitlement.Route) > 0 {
+			return plan
+		}
+		if len(entitlement.Models) > 0 {
+			allowed = func(model string) bool { return slices.Contains(entitlement.Models, model) }
+		}
+	}
+	model, ok := agent.modelPool.next(allowed)
+	if !ok {
+		return plan
+	}
+
+	fallbacks := slices.DeleteFunc(slices.Clone(plan.models), func(m string) bool { return m == model })
+	plan.models = append([]string{model}, fallbacks...)
+	return plan
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelPoolNext(t *testing.T) {
+	pool := NewModelPool(ModelWeight{Model: "mini", Weight: 80}, ModelWeight{Model: "full", Weight: 20}, ModelWeight{Model: "off", Weight: 0})
+	all := func(string) bool { return true }
+
+	var picks []string
+	for range 10 {
+		model, ok := pool.next(all)
+		require.True(t, ok)
+		picks = append(picks, model)
+	}
+	// Four of every five runs go to mini, interleaved
+	assert.Equal(t, "mini mini full mini mini mini mini full mini mini", strings.Join(picks, " "))
+	assert.Equal(t, 8, pool.Stats()["mini"].Runs)
+	assert.Equal(t, 2, pool.Stats()["full"].Runs)
+	assert.NotContains(t, pool.Stats(), "off")
+
+	model, ok := pool.next(func(model string) bool { return model == "full" })
+	assert.True(t, ok)
+	assert.Equal(t, "full", model)
+	_, ok = pool.next(func(string) bool { return false })
+	assert.False(t, ok)
+
+	// New weights apply to later runs and stats are kept
+	pool.SetWeights(ModelWeight{Model: "full", Weight: 1})
+	model, _ = pool.next(all)
+	assert.Equal(t, "full", model)
+	assert.Equal(t, 8, pool.Stats()["mini"].Runs)
+}
+
+func TestParseModelWeights(t *testing.T) {
+	weights, err := ParseModelWeights(" gpt-4o-mini=80, gpt-4o = 20 ,")
+	require.NoError(t, err)
+	assert.Equal(t, []ModelWeight{{Model: "gpt-4o-mini", Weight: 80}, {Model: "gpt-4o", Weight: 20}}, weights)
+
+	_, err = ParseModelWeights("gpt-4o-mini")
+	assert.ErrorContains(t, err, "not model=weight")
+	_, err = ParseModelWeights("gpt-4o-mini=lots")
+	assert.ErrorContains(t, err, "invalid weight")
+}
+
+func TestWithModelPool(t *testing.T) {
+	var requested []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requested = append(requested, body.Model)
+		if body.Model == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeCompletion(w, body.Model, "ok")
+	}
+	pool := NewModelPool(ModelWeight{Model: "mini", Weight: 2}, ModelWeight{Model: "full", Weight: 1})
+	testAgent := newTestAgent(t, "default", handler,
+		WithModelPool(pool),
+		WithPrices(map[string]Price{"mini": {Prompt: 1_000_000, Completion: 1_000_000}}),
+	)
+
+	for range 3 {
+		_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"mini", "full", "mini"}, requested)
+	assert.Equal(t, map[string]PoolStats{
+		"mini": {Runs: 2, Requests: 2, Usage: Usage{PromptTokens: 2, CompletionTokens: 2, TotalTokens: 4}, Cost: 4},
+		"full": {Runs: 1, Requests: 1, Usage: Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2}},
+	}, pool.Stats())
+
+	// A failing pool model falls back to the agent's model
+	requested = nil
+	pool.SetWeights(ModelWeight{Model: "broken", Weight: 1})
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken", "default"}, requested)
+	assert.Equal(t, PoolStats{Runs: 1, Requests: 1, Errors: 1}, pool.Stats()["broken"])
+}
+
+func TestModelPoolAccessPolicy(t *testing.T) {
+	var requested []string
+	testAgent := newTestAgent(t, "mini", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requested = append(requested, body.Model)
+		writeCompletion(w, body.Model, "ok")
+	},
+		WithModelPool(NewModelPool(ModelWeight{Model: "full", Weight: 1}, ModelWeight{Model: "mini", Weight: 1})),
+		WithAccessPolicy(AccessPolicy{
+			"free":       {Models: []string{"mini"}},
+			"enterprise": {},
+			"research":   {Route: []string{"o3"}},
+		}),
+	)
+
+	for _, tenant := range []string{"free", "free", "enterprise", "research"} {
+		_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, WithTenant(tenant))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"mini", "mini", "full", "o3"}, requested)
+}
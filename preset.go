@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"github.com/openai/openai-go"
+)
+
+// Preset is a reusable agent configuration for a task: a system prompt, the tools
+// the task needs, and an optional schema the final answer must follow
+type Preset struct {
+	Name         string
+	Description  string
+	SystemPrompt string
+	Tools        []Tool
+	// ResponseSchema constrains the final answer to JSON matching the schema
+	ResponseSchema Schema
+	// Options are applied after the preset's prompt, tools, and schema
+	Options []AgentOption
+}
+
+// WithPreset configures the agent from a preset. The preset's tools are added to
+// any already configured, and its system prompt replaces the current one.
+func WithPreset(preset Preset) AgentOption {
+	return func(a *Agent) {
+		if preset.SystemPrompt != "" {
+			WithSystemPrompt(preset.SystemPrompt)(a)
+		}
+		a.tools = append(append([]Tool{}, a.tools...), preset.Tools...)
+		if preset.ResponseSchema != nil {
+			WithResponseSchema(preset.Name, preset.ResponseSchema)(a)
+		}
+		for _, opt := range preset.Options {
+			opt(a)
+		}
+	}
+}
+
+// WithResponseSchema requires the model's final answer to be JSON matching the
+// schema, using the provider's structured output mode
+func WithResponseSchema(name string, schema Schema) AgentOption {
+	return func(a *Agent) {
+		a.responseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   name,
+					Schema: map[string]any(schema),
+					Strict: openai.Bool(true),
+				},
+			},
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPreset(t *testing.T) {
+	var body map[string]any
+	preset := Preset{
+		Name:         "triage",
+		SystemPrompt: "You triage bugs",
+		Tools:        []Tool{MockTool{name: "lookup"}},
+		ResponseSchema: ObjectSchema("A triage decision", map[string]Schema{
+			"priority": StringSchema("The priority").Enum("low", "high"),
+			"reason":   StringSchema("Why"),
+		}),
+		Options: []AgentOption{WithMaxIterations(5)},
+	}
+
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeCompletion(w, "test-model", `{"priority":"high","reason":"crash"}`)
+	}, WithTools([]Tool{MockTool{name: "existing"}}), WithPreset(preset))
+
+	assert.Equal(t, "You triage bugs", testAgent.systemPrompt)
+	assert.Equal(t, 5, testAgent.maxIterations)
+	require.Len(t, testAgent.tools, 2)
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("App crashes on start")})
+	require.NoError(t, err)
+
+	format := body["response_format"].(map[string]any)
+	assert.Equal(t, "json_schema", format["type"])
+	jsonSchema := format["json_schema"].(map[string]any)
+	assert.Equal(t, "triage", jsonSchema["name"])
+	assert.Equal(t, true, jsonSchema["strict"])
+	schema := jsonSchema["schema"].(map[string]any)
+	assert.Equal(t, []any{"priority", "reason"}, schema["required"])
+	assert.Equal(t, false, schema["additionalProperties"])
+}
+
+func TestNoResponseFormatByDefault(t *testing.T) {
+	var body map[string]any
+	testAgent := newTestAgent(t, "test-model", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		writeCompletion(w, "test-model", "hello")
+	})
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+	assert.NotContains(t, body, "response_format")
+}
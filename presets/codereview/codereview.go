@@ -0,0 +1,170 @@
+// Package codereview is a preset that configures an agent to review a git diff
+// and report findings with file, line, and severity. It is a reference for
+// building task presets with agent.Preset.
+package codereview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Severity ranks how urgently a finding should be addressed
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// Finding is a single review comment anchored to a line of the new version of a file
+type Finding struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Severity   Severity `json:"severity"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion"`
+}
+
+// Report is the structured result of a review
+type Report struct {
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+}
+
+const systemPrompt = `You are a meticulous senior engineer reviewing a code change.
+Review only the lines the diff adds or modifies. Look for bugs, security issues, race
+conditions, error handling mistakes, and missing tests before style.
+Report each problem once, anchored to the line number in the new version of the file.
+Use "critical" for exploitable or data-losing defects, "error" for bugs, "warning" for
+risky code, and "info" for suggestions. Leave suggestion empty when there is no concrete fix.
+If the change has no problems, return an empty findings list.`
+
+// Option is a functional option for configuring the preset
+type Option func(*settings)
+
+type settings struct {
+	root string
+}
+
+// WithRoot gives the reviewer read access to files under dir for context beyond the diff
+func WithRoot(dir string) Option {
+	return func(s *settings) {
+		s.root = dir
+	}
+}
+
+// Preset returns the code review agent configuration
+func Preset(opts ...Option) agent.Preset {
+	var s settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	preset := agent.Preset{
+		Name:         "code_review",
+		Description:  "Reviews a git diff and reports findings with file, line, and severity",
+		SystemPrompt: systemPrompt,
+		ResponseSchema: agent.ObjectSchema("A code review report", map[string]agent.Schema{
+			"summary": agent.StringSchema("A one paragraph assessment of the change"),
+			"findings": agent.ArraySchema("Problems found in the change", agent.ObjectSchema("A review finding", map[string]agent.Schema{
+				"file":       agent.StringSchema("The path of the file, as named in the diff"),
+				"line":       agent.IntegerSchema("The line number in the new version of the file"),
+				"severity":   agent.StringSchema("How urgently the finding should be addressed").Enum("info", "warning", "error", "critical"),
+				"message":    agent.StringSchema("What is wrong and why it matters"),
+				"suggestion": agent.StringSchema("A concrete fix, or empty"),
+			})),
+		}),
+	}
+	if s.root != "" {
+		preset.Tools = []agent.Tool{&readFileTool{root: s.root}}
+	}
+	return preset
+}
+
+// Review asks an agent configured with the preset to review diff and parses its report
+func Review(ctx context.Context, a agent.ChatAgent, diff string) (Report, error) {
+	if strings.TrimSpace(diff) == "" {
+		return Report{}, errors.New("diff is empty")
+	}
+
+	completion, err := a.ChatCompletion(ctx, []agent.Message{
+		agent.UserTextMessage("Review this diff:\n\n```diff\n" + diff + "\n```"),
+	})
+	if err != nil {
+		return Report{}, err
+	}
+	if len(completion.Messages) == 0 {
+		return Report{}, errors.New("review returned no content")
+	}
+
+	// The report is the final answer, after any commentary between tool calls
+	var report Report
+	if err := json.Unmarshal([]byte(completion.Messages[len(completion.Messages)-1]), &report); err != nil {
+		return Report{}, fmt.Errorf("parse review report: %w", err)
+	}
+	return report, nil
+}
+
+// readFileTool returns numbered lines of a file under root
+type readFileTool struct {
+	root string
+}
+
+func (t *readFileTool) Name() string {
+	return "read_file"
+}
+
+func (t *readFileTool) Description() string {
+	return "Read lines of a file in the repository, with line numbers, to see context the diff omits"
+}
+
+func (t *readFileTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"path":       agent.StringSchema("The path of the file relative to the repository root"),
+			"start_line": agent.IntegerSchema("The first line to read (default: 1)").Range(1, 1e9),
+			"end_line":   agent.IntegerSchema("The last line to read (default: 200 lines after start_line)").Range(1, 1e9),
+		},
+		Required: []string{"path"},
+	}
+}
+
+func (t *readFileTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	path, _ := input["path"].(string)
+	full := filepath.Join(t.root, path)
+	rel, err := filepath.Rel(t.root, full)
+	if err != nil || filepath.IsAbs(path) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("path %q is outside the repository", path)
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	start := 1
+	if v, ok := input["start_line"].(float64); ok {
+		start = int(v)
+	}
+	end := start + 199
+	if v, ok := input["end_line"].(float64); ok {
+		end = int(v)
+	}
+	start, end = max(start, 1), min(end, len(lines))
+
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "%d\t%s\n", i, lines[i-1])
+	}
+	return b.String(), nil
+}
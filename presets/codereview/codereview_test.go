@@ -0,0 +1,74 @@
+package codereview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReview(t *testing.T) {
+	report := Report{
+		Summary: "Introduces an off-by-one error",
+		Findings: []Finding{
+			{File: "main.go", Line: 12, Severity: SeverityError, Message: "loop skips the last element", Suggestion: "use <= len-1"},
+		},
+	}
+	content, err := json.Marshal(report)
+	require.NoError(t, err)
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		prompt = body.Messages[len(body.Messages)-1].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%q}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, content)
+	}))
+	t.Cleanup(server.Close)
+
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	reviewer := agent.NewAgentWithClient(client, "test-model", agent.WithPreset(Preset()))
+
+	got, err := Review(context.Background(), reviewer, "--- a/main.go\n+++ b/main.go\n@@ -10 +10 @@\n-for i := 0; i < n; i++ {\n+for i := 0; i < n-1; i++ {")
+	require.NoError(t, err)
+	assert.Equal(t, report, got)
+	assert.Contains(t, prompt, "```diff\n--- a/main.go")
+
+	_, err = Review(context.Background(), reviewer, " ")
+	assert.Error(t, err)
+}
+
+func TestReadFileTool(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+
+	preset := Preset(WithRoot(root))
+	require.Len(t, preset.Tools, 1)
+	tool := preset.Tools[0]
+
+	result, err := tool.Execute(context.Background(), map[string]any{"path": "main.go", "start_line": float64(2), "end_line": float64(3)})
+	require.NoError(t, err)
+	assert.Equal(t, "2\t\n3\tfunc main() {}\n", result)
+
+	_, err = tool.Execute(context.Background(), map[string]any{"path": "../secret"})
+	assert.Error(t, err)
+
+	assert.Empty(t, Preset().Tools)
+}
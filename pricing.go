@@ -0,0 +1,12 @@
+package agent
+
+// Price is the cost of a model's tokens in USD per million tokens
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Cost returns the cost in USD of the given usage at this price
+func (p Price) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Prompt + float64(usage.CompletionTokens)*p.Completion) / 1_000_000
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToolProgress is an update reported by a running tool
+type ToolProgress struct {
+	Tool   string `json:"tool"`
+	CallID string `json:"call_id"`
+	// Message describes what the tool is doing, such as "retrying download (2/3)"
+	Message string `json:"message"`
+	// Elapsed is the time since the tool started
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// maxProgressSummary is the number of updates kept for the progress summary
+const maxProgressSummary = 10
+
+// ReportProgress reports the progress of the tool executing with ctx. The update
+// is sent on the run's response stream as a progress response, and with
+// WithToolProgressSummary it is also summarized to the model in the tool's
+// result. It does nothing outside a tool call. Resolved secrets are masked.
+func ReportProgress(ctx context.Context, message string) {
+	reporter, ok := ctx.Value(progressKey{}).(*progressReporter)
+	if !ok {
+		return
+	}
+	reporter.report(ctx, redactResolvedSecrets(ctx, message))
+}
+
+// WithToolProgressSummary appends a summary of the progress a tool reported,
+// with its duration, to its result, such as "[download_file took 45s;
+// progress: connecting; retry 1 after timeout; done]", so the model can take
+// slow or flaky tools into account. Tools that report no progress are not
+// affected.
+func WithToolProgressSummary() AgentOption {
+	return func(a *Agent) {
+		a.toolProgressSummary = true
+	}
+}
+
+type progressKey struct{}
+
+// progressReporter sends the progress of one tool call to the run's stream
+type progressReporter struct {
+	tool      string
+	callID    string
+	start     time.Time
+	responses chan<- Response
+
+	mu       sync.Mutex
+	messages []string
+	dropped  int
+	finished time.Time
+}
+
+// withProgress returns a context through which the tool call reports progress
+func withProgress(ctx context.Context, tool string, callID string, responses chan<- Response) (context.Context, *progressReporter) {
+	reporter := &progressReporter{tool: tool, callID: callID, start: time.Now(), responses: responses}
+	return context.WithValue(ctx, progressKey{}, reporter), reporter
+}
+
+func (r *progressReporter) report(ctx context.Context, message string) {
+	// Holding the lock while sending keeps finish from returning, and the run
+	// from closing the stream, while an update is in flight
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.finished.IsZero() {
+		return
+	}
+	r.messages = append(r.messages, message)
+	if len(r.messages) > maxProgressSummary {
+		r.messages = r.messages[1:]
+		r.dropped++
+	}
+	send(ctx, r.responses, NewProgressResponse(ToolProgress{
+		Tool:    r.tool,
+		CallID:  r.callID,
+		Message: message,
+		Elapsed: time.Since(r.start),
+	}))
+}
+
+// finish stops updates from the tool call, including any from goroutines the
+// tool left running
+func (r *progressReporter) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = time.Now()
+}
+
+// summary describes the progress reported by the finished tool call, or is
+// empty if there was none
+func (r *progressReporter) summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.messages) == 0 {
+		return ""
+	}
+	messages := r.messages
+	if r.dropped > 0 {
+		messages = append([]string{fmt.Sprintf("%d earlier updates", r.dropped)}, messages...)
+	}
+	elapsed := r.finished.Sub(r.start).Round(time.Millisecond)
+	if elapsed >= time.Second {
+		elapsed = elapsed.Round(time.Second)
+	}
+	return fmt.Sprintf("[%s took %s; progress: %s]", r.tool, elapsed, strings.Join(messages, "; "))
+}
+
+// NewProgressResponse creates a response carrying a tool's progress
+func NewProgressResponse(progress ToolProgress) Response {
+	return Response{
+		Kind:     ResponseKindProgress,
+		progress: progress,
+	}
+}
+
+// IsProgressResponse reports whether the response carries a tool's progress
+func (r Response) IsProgressResponse() bool {
+	return r.Kind == ResponseKindProgress
+}
+
+// Progress returns the tool progress of a progress response
+func (r Response) Progress() ToolProgress {
+	if r.Kind != ResponseKindProgress {
+		return ToolProgress{}
+	}
+	return r.progress
+}
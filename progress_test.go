@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolProgress(t *testing.T) {
+	for _, summarize := range []bool{false, true} {
+		t.Run(fmt.Sprintf("summary=%v", summarize), func(t *testing.T) {
+			t.Setenv("TEST_PROGRESS_token", "s3cret")
+			var toolContent string
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Messages []map[string]any `json:"messages"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				if len(body.Messages) == 1 {
+					writeToolCall(w, "model", "download", `{}`)
+					return
+				}
+				toolContent = body.Messages[2]["content"].(string)
+				writeCompletion(w, "model", "Downloaded")
+			}
+			opts := []AgentOption{
+				WithSecrets(EnvSecrets{Prefix: "TEST_PROGRESS_"}),
+				WithTools([]Tool{MockTool{
+					name: "download",
+					executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+						token, err := Secret(ctx, "token")
+						require.NoError(t, err)
+						ReportProgress(ctx, "connecting with "+token)
+						for i := 1; i <= 11; i++ {
+							ReportProgress(ctx, fmt.Sprintf("retry %d", i))
+						}
+						return "ok", nil
+					},
+				}}),
+			}
+			if summarize {
+				opts = append(opts, WithToolProgressSummary())
+			}
+			testAgent := newTestAgent(t, "model", handler, opts...)
+
+			completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Download it")})
+			require.NoError(t, err)
+
+			var updates []ToolProgress
+			for _, response := range completion.Responses {
+				if response.IsProgressResponse() {
+					updates = append(updates, response.Progress())
+				}
+			}
+			require.Len(t, updates, 12)
+			assert.Equal(t, "download", updates[0].Tool)
+			assert.Equal(t, "call_1", updates[0].CallID)
+			assert.Equal(t, "connecting with [REDACTED]", updates[0].Message)
+			assert.Equal(t, "retry 11", updates[11].Message)
+			assert.Equal(t, TerminationCompleted, completion.TerminationReason)
+
+			if !summarize {
+				assert.Equal(t, "ok", toolContent)
+				return
+			}
+			assert.Regexp(t, `^ok\n\n\[download took \d+m?s; progress: 2 earlier updates; retry 2; retry 3; .*; retry 11\]$`, toolContent)
+		})
+	}
+}
+
+func TestReportProgressOutsideToolCall(t *testing.T) {
+	// Tools called directly, for example in tests, can report freely
+	ReportProgress(context.Background(), "ignored")
+}
@@ -0,0 +1,189 @@
+// Package azure runs agents on Azure OpenAI deployments, authenticating with an
+// API key or a Microsoft Entra ID token credential.
+//
+// NewClient returns an OpenAI client that routes each request to the deployment
+// named by the request's model and adds the api-version query parameter, so the
+// client works with agent.NewAgentWithClient unchanged:
+//
+//	client := azure.NewClient("https://my-resource.openai.azure.com", "2024-10-21",
+//		azure.WithTokenCredential(credential))
+//	a := agent.NewAgentWithClient(client, "gpt-4o-prod")
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// DefaultScope is the Entra ID scope for Azure OpenAI
+const DefaultScope = "https://cognitiveservices.azure.com/.default"
+
+// Token is an Entra ID access token
+type Token struct {
+	Token     string
+	ExpiresOn time.Time
+}
+
+// TokenCredential issues access tokens. It mirrors azcore.TokenCredential, so
+// azidentity credentials can be adapted with TokenFunc:
+//
+//	cred, _ := azidentity.NewDefaultAzureCredential(nil)
+//	azure.TokenFunc(func(ctx context.Context, scopes []string) (azure.Token, error) {
+//		tok, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: scopes})
+//		return azure.Token{Token: tok.Token, ExpiresOn: tok.ExpiresOn}, err
+//	})
+type TokenCredential interface {
+	GetToken(ctx context.Context, scopes []string) (Token, error)
+}
+
+// TokenFunc adapts a function to a TokenCredential
+type TokenFunc func(ctx context.Context, scopes []string) (Token, error)
+
+func (f TokenFunc) GetToken(ctx context.Context, scopes []string) (Token, error) {
+	return f(ctx, scopes)
+}
+
+// Option is a functional option for configuring the client
+type Option func(*config)
+
+// WithAPIKey authenticates with a resource key, sent in the api-key header
+func WithAPIKey(apiKey string) Option {
+	return func(c *config) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithTokenCredential authenticates with Entra ID tokens from credential. Tokens
+// are cached until shortly before they expire.
+func WithTokenCredential(credential TokenCredential) Option {
+	return func(c *config) {
+		c.credential = credential
+	}
+}
+
+// WithScope overrides the scope requested from the token credential
+func WithScope(scope string) Option {
+	return func(c *config) {
+		c.scope = scope
+	}
+}
+
+// WithDeployments maps model names to deployment names, for deployments not
+// named after their model. Models without an entry are used as deployment names.
+func WithDeployments(deployments map[string]string) Option {
+	return func(c *config) {
+		c.deployments = deployments
+	}
+}
+
+// WithRequestOptions adds OpenAI request options to the client, such as an HTTP client
+func WithRequestOptions(opts ...option.RequestOption) Option {
+	return func(c *config) {
+		c.requestOptions = append(c.requestOptions, opts...)
+	}
+}
+
+type config struct {
+	apiKey         string
+	credential     TokenCredential
+	scope          string
+	deployments    map[string]string
+	requestOptions []option.RequestOption
+
+	mu    sync.Mutex
+	token Token
+}
+
+// NewClient returns an OpenAI client for the Azure OpenAI resource at endpoint,
+// targeting apiVersion. Authenticate with WithAPIKey or WithTokenCredential.
+func NewClient(endpoint string, apiVersion string, opts ...Option) openai.Client {
+	c := &config{scope: DefaultScope}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return openai.NewClient(append([]option.RequestOption{
+		// Replaced by the middleware with the configured credential
+		option.WithAPIKey("azure"),
+		option.WithBaseURL(strings.TrimRight(endpoint, "/") + "/openai/"),
+		option.WithQuery("api-version", apiVersion),
+		option.WithMiddleware(c.middleware),
+	}, c.requestOptions...)...)
+}
+
+// deploymentRoutes are the routes served per deployment, identified by the model
+// in their JSON body
+var deploymentRoutes = map[string]bool{
+	"/openai/chat/completions":   true,
+	"/openai/completions":        true,
+	"/openai/embeddings":         true,
+	"/openai/images/generations": true,
+	"/openai/audio/speech":       true,
+}
+
+func (c *config) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if deploymentRoutes[req.URL.Path] && req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(data))
+
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("decode request model: %w", err)
+		}
+		deployment := body.Model
+		if name, ok := c.deployments[body.Model]; ok {
+			deployment = name
+		}
+		suffix := strings.TrimPrefix(req.URL.Path, "/openai/")
+		req.URL.Path = "/openai/deployments/" + deployment + "/" + suffix
+		req.URL.RawPath = "/openai/deployments/" + url.PathEscape(deployment) + "/" + suffix
+	}
+
+	req.Header.Del("Authorization")
+	switch {
+	case c.credential != nil:
+		token, err := c.getToken(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("azure token credential: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.apiKey != "":
+		req.Header.Set("Api-Key", c.apiKey)
+	default:
+		return nil, errors.New("azure: no API key or token credential configured")
+	}
+	return next(req)
+}
+
+// getToken returns the cached token, refreshing it within five minutes of expiry
+func (c *config) getToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token.Token != "" && time.Until(c.token.ExpiresOn) > 5*time.Minute {
+		return c.token.Token, nil
+	}
+	token, err := c.credential.GetToken(ctx, []string{c.scope})
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	return token.Token, nil
+}
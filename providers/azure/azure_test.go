@@ -0,0 +1,87 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer returns a fake Azure OpenAI resource that records each request
+func newTestServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hello"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTokenCredential(t *testing.T) {
+	var requests []*http.Request
+	server := newTestServer(t, &requests)
+
+	var scopes [][]string
+	credential := TokenFunc(func(ctx context.Context, s []string) (Token, error) {
+		scopes = append(scopes, s)
+		return Token{Token: fmt.Sprintf("token-%d", len(scopes)), ExpiresOn: time.Now().Add(time.Hour)}, nil
+	})
+	client := NewClient(server.URL+"/", "2024-10-21", WithTokenCredential(credential), WithDeployments(map[string]string{"gpt-4o": "gpt-4o-prod"}))
+	testAgent := agent.NewAgentWithClient(client, "gpt-4o")
+
+	for range 2 {
+		completion, err := testAgent.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Hello"}, completion.Messages)
+	}
+
+	require.Len(t, requests, 2)
+	for _, r := range requests {
+		assert.Equal(t, "/openai/deployments/gpt-4o-prod/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-10-21", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("Api-Key"))
+	}
+	// The token is reused until it nears expiry
+	assert.Equal(t, [][]string{{DefaultScope}}, scopes)
+}
+
+func TestAPIKey(t *testing.T) {
+	var requests []*http.Request
+	server := newTestServer(t, &requests)
+
+	client := NewClient(server.URL, "2024-10-21", WithAPIKey("resource-key"))
+	_, err := agent.NewAgentWithClient(client, "my deployment").ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+	require.NoError(t, err)
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/openai/deployments/my%20deployment/chat/completions", requests[0].URL.EscapedPath())
+	assert.Equal(t, "resource-key", requests[0].Header.Get("Api-Key"))
+	assert.Empty(t, requests[0].Header.Get("Authorization"))
+}
+
+func TestTokenErrors(t *testing.T) {
+	var requests []*http.Request
+	server := newTestServer(t, &requests)
+
+	credential := TokenFunc(func(ctx context.Context, s []string) (Token, error) {
+		return Token{}, errors.New("no managed identity")
+	})
+	client := NewClient(server.URL, "2024-10-21", WithTokenCredential(credential), WithRequestOptions(option.WithMaxRetries(0)))
+	_, err := agent.NewAgentWithClient(client, "gpt-4o").ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+	assert.ErrorContains(t, err, "no managed identity")
+	assert.Empty(t, requests)
+
+	_, err = agent.NewAgentWithClient(NewClient(server.URL, "2024-10-21", WithRequestOptions(option.WithMaxRetries(0))), "gpt-4o").ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+	assert.ErrorContains(t, err, "no API key or token credential")
+}
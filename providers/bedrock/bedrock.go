@@ -0,0 +1,215 @@
+// Package bedrock runs agents on models hosted by AWS Bedrock, such as Anthropic
+// Claude, Meta Llama, and Amazon Nova, through the Converse API.
+//
+// NewClient returns an OpenAI client whose transport translates chat completion
+// requests, including tool use, into Converse requests signed with AWS Signature
+// Version 4, so the client works with agent.NewAgentWithClient unchanged:
+//
+//	client := bedrock.NewClient("us-east-1")
+//	a := agent.NewAgentWithClient(client, "anthropic.claude-3-5-sonnet-20240620-v1:0")
+package bedrock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Option is a functional option for configuring the client
+type Option func(*transport)
+
+// WithCredentials sets the AWS credentials. By default they are read from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment
+// variables on each request.
+func WithCredentials(creds Credentials) Option {
+	return func(t *transport) {
+		t.credentials = func() (Credentials, error) { return creds, nil }
+	}
+}
+
+// WithCredentialsFunc resolves credentials on each request, for credentials that rotate
+func WithCredentialsFunc(fn func() (Credentials, error)) Option {
+	return func(t *transport) {
+		t.credentials = fn
+	}
+}
+
+// WithEndpoint overrides the Bedrock runtime endpoint, such as a VPC endpoint
+func WithEndpoint(endpoint string) Option {
+	return func(t *transport) {
+		t.endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to reach Bedrock
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *transport) {
+		t.client = client
+	}
+}
+
+// NewClient returns an OpenAI client that sends chat completions to Bedrock in region
+func NewClient(region string, opts ...Option) openai.Client {
+	t := &transport{
+		region:      region,
+		endpoint:    "https://bedrock-runtime." + region + ".amazonaws.com",
+		client:      http.DefaultClient,
+		credentials: envCredentials,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return openai.NewClient(
+		// The API key is unused; requests are signed with AWS credentials instead
+		option.WithAPIKey("bedrock"),
+		option.WithBaseURL("https://bedrock.invalid/"),
+		option.WithHTTPClient(&http.Client{Transport: t}),
+	)
+}
+
+// envCredentials reads credentials from the standard AWS environment variables
+func envCredentials() (Credentials, error) {
+	creds := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return Credentials{}, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	return creds, nil
+}
+
+// transport serves OpenAI chat completion requests by calling the Converse API
+type transport struct {
+	region      string
+	endpoint    string
+	client      *http.Client
+	credentials func() (Credentials, error)
+	now         func() time.Time
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return errorResponse(req, http.StatusNotFound, "NotFound", "", fmt.Sprintf("%s %s is not supported by Bedrock", req.Method, req.URL.Path)), nil
+	}
+
+	var chat chatRequest
+	if err := json.NewDecoder(req.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("decode chat completion request: %w", err)
+	}
+	req.Body.Close()
+
+	converse, err := toConverse(chat)
+	if err != nil {
+		return errorResponse(req, http.StatusBadRequest, "ValidationException", "", err.Error()), nil
+	}
+	body, err := json.Marshal(converse)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := t.credentials()
+	if err != nil {
+		return nil, fmt.Errorf("aws credentials: %w", err)
+	}
+
+	target, err := url.Parse(t.endpoint + "/model/" + uriEncode(chat.Model) + "/converse")
+	if err != nil {
+		return nil, err
+	}
+	bedrockReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	bedrockReq.Header.Set("Content-Type", "application/json")
+	bedrockReq.Header.Set("Accept", "application/json")
+	sign(bedrockReq, body, creds, t.region, "bedrock", t.now())
+
+	resp, err := t.client.Do(bedrockReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return bedrockError(req, resp, data), nil
+	}
+
+	var converseResp converseResponse
+	if err := json.Unmarshal(data, &converseResp); err != nil {
+		return nil, fmt.Errorf("decode converse response: %w", err)
+	}
+	completion, err := json.Marshal(fromConverse(chat.Model, converseResp))
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(req, http.StatusOK, completion, resp.Header), nil
+}
+
+// bedrockError converts a Bedrock error into an OpenAI error response with the
+// same status, so the agent's typed errors and fallbacks apply
+func bedrockError(req *http.Request, resp *http.Response, data []byte) *http.Response {
+	var body struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(data, &body)
+	if body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+	}
+
+	errType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+	code := ""
+	if resp.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(body.Message), "too long") {
+		code = "context_length_exceeded"
+	}
+
+	errResp := errorResponse(req, resp.StatusCode, errType, code, body.Message)
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		errResp.Header.Set("Retry-After", retryAfter)
+	}
+	return errResp
+}
+
+// errorResponse builds an OpenAI error response
+func errorResponse(req *http.Request, status int, errType string, code string, message string) *http.Response {
+	data, _ := json.Marshal(map[string]any{
+		"error": map[string]any{"message": message, "type": errType, "code": code},
+	})
+	return jsonResponse(req, status, data, nil)
+}
+
+func jsonResponse(req *http.Request, status int, data []byte, header http.Header) *http.Response {
+	h := http.Header{}
+	if requestID := header.Get("X-Amzn-Requestid"); requestID != "" {
+		h.Set("X-Request-Id", requestID)
+	}
+	h.Set("Content-Type", "application/json")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,182 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVanilla(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	sign(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"),
+	)
+}
+
+func TestCanonicalURI(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-v2%3A1/converse", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/model/anthropic.claude-v2%253A1/converse", canonicalURI(req.URL))
+}
+
+// newTestClient returns a client for a fake Bedrock endpoint running handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) openai.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient("us-west-2",
+		WithEndpoint(server.URL),
+		WithCredentials(Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}),
+	)
+}
+
+// weatherTool reports a fixed temperature for a city
+type weatherTool struct{}
+
+func (weatherTool) Name() string        { return "get_weather" }
+func (weatherTool) Description() string { return "Get the weather" }
+func (weatherTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{"city": agent.StringSchema("The city")},
+		Required:   []string{"city"},
+	}
+}
+func (weatherTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return map[string]any{"city": input["city"], "temperature": 21}, nil
+}
+
+func TestConverseToolUse(t *testing.T) {
+	var requests []converseRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/model/anthropic.claude-3-5-sonnet-20240620-v1:0/converse", r.URL.Path)
+		assert.Equal(t, "/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/converse", r.URL.EscapedPath())
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-west-2/bedrock/aws4_request")
+		assert.Equal(t, "session", r.Header.Get("X-Amz-Security-Token"))
+
+		var req converseRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Checking."},{"toolUse":{"toolUseId":"tu_1","name":"get_weather","input":{"city":"Paris"}}}]}},"stopReason":"tool_use","usage":{"inputTokens":20,"outputTokens":10,"totalTokens":30}}`)
+			return
+		}
+		fmt.Fprint(w, `{"output":{"message":{"role":"assistant","content":[{"text":"It is 21 degrees in Paris."}]}},"stopReason":"end_turn","usage":{"inputTokens":40,"outputTokens":8,"totalTokens":48,"cacheReadInputTokens":16}}`)
+	})
+	testAgent := agent.NewAgentWithClient(client, "anthropic.claude-3-5-sonnet-20240620-v1:0",
+		agent.WithSystemPrompt("Be brief"),
+		agent.WithTools([]agent.Tool{weatherTool{}}),
+		agent.WithMaxCompletionTokens(256),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Weather in Paris?")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Checking.", "It is 21 degrees in Paris."}, completion.Messages)
+	assert.Equal(t, int64(78), completion.Usage.TotalTokens)
+	assert.Equal(t, int64(16), completion.Usage.CachedPromptTokens)
+
+	require.Len(t, requests, 2)
+	first := requests[0]
+	assert.Equal(t, []contentBlock{{Text: "Be brief"}}, first.System)
+	require.NotNil(t, first.InferenceConfig)
+	assert.Equal(t, int64(256), *first.InferenceConfig.MaxTokens)
+	require.NotNil(t, first.ToolConfig)
+	require.Len(t, first.ToolConfig.Tools, 1)
+	assert.Equal(t, "get_weather", first.ToolConfig.Tools[0].ToolSpec.Name)
+	assert.Equal(t, []any{"city"}, first.ToolConfig.Tools[0].ToolSpec.InputSchema.JSON["required"])
+
+	// The tool result is returned in a user message after the assistant's tool use
+	second := requests[1]
+	require.Len(t, second.Messages, 3)
+	assert.Equal(t, "assistant", second.Messages[1].Role)
+	require.Len(t, second.Messages[1].Content, 2)
+	assert.Equal(t, "tu_1", second.Messages[1].Content[1].ToolUse.ToolUseID)
+	assert.Equal(t, map[string]any{"city": "Paris"}, second.Messages[1].Content[1].ToolUse.Input)
+	assert.Equal(t, "user", second.Messages[2].Role)
+	result := second.Messages[2].Content[0].ToolResult
+	require.NotNil(t, result)
+	assert.Equal(t, "tu_1", result.ToolUseID)
+	assert.JSONEq(t, `{"city":"Paris","temperature":21}`, result.Content[0].Text)
+}
+
+func TestConverseErrors(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Amzn-ErrorType", "ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"message":"Too many requests, please wait before trying again."}`)
+	})
+	testAgent := agent.NewAgentWithClient(client, "meta.llama3-70b-instruct-v1:0")
+
+	_, err := testAgent.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+	var rateLimitErr *agent.RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Contains(t, err.Error(), "Too many requests")
+
+	// Unsupported request features fail before reaching Bedrock
+	schemaAgent := agent.NewAgentWithClient(client, "amazon.nova-pro-v1:0", agent.WithResponseSchema("answer", agent.ObjectSchema("An answer", map[string]agent.Schema{
+		"text": agent.StringSchema("The answer"),
+	})))
+	_, err = schemaAgent.ChatCompletion(context.Background(), []agent.Message{agent.UserTextMessage("Hi")})
+	assert.ErrorContains(t, err, "not supported by the Converse API")
+}
+
+func TestToConverse(t *testing.T) {
+	var req chatRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "anthropic.claude-3-haiku-20240307-v1:0",
+		"messages": [
+			{"role": "developer", "content": "Answer in French"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "Describe these"},
+				{"type": "image_url", "image_url": {"url": "data:image/jpg;base64,aGk="}},
+				{"type": "file", "file": {"file_data": "aGk=", "filename": "Q3 report (final).pdf"}}
+			]},
+			{"role": "user", "content": "Please"}
+		],
+		"tools": [{"type": "function", "function": {"name": "lookup"}}],
+		"tool_choice": {"type": "function", "function": {"name": "lookup"}},
+		"stop": "END"
+	}`), &req))
+
+	converse, err := toConverse(req)
+	require.NoError(t, err)
+	assert.Equal(t, []contentBlock{{Text: "Answer in French"}}, converse.System)
+
+	// Consecutive user messages are merged so roles alternate
+	require.Len(t, converse.Messages, 1)
+	content := converse.Messages[0].Content
+	require.Len(t, content, 4)
+	assert.Equal(t, &imageBlock{Format: "jpeg", Source: bytesSource{Bytes: []byte("hi")}}, content[1].Image)
+	assert.Equal(t, &documentBlock{Format: "pdf", Name: "Q3 report (final)", Source: bytesSource{Bytes: []byte("hi")}}, content[2].Document)
+	assert.Equal(t, "Please", content[3].Text)
+
+	assert.Equal(t, []string{"END"}, converse.InferenceConfig.StopSequences)
+	assert.Equal(t, map[string]any{"tool": map[string]any{"name": "lookup"}}, converse.ToolConfig.ToolChoice)
+	assert.Equal(t, map[string]any{"type": "object", "properties": map[string]any{}}, converse.ToolConfig.Tools[0].ToolSpec.InputSchema.JSON)
+
+	req.ToolChoice = json.RawMessage(`"none"`)
+	converse, err = toConverse(req)
+	require.NoError(t, err)
+	assert.Nil(t, converse.ToolConfig)
+}
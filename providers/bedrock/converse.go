@@ -0,0 +1,428 @@
+package bedrock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// chatRequest is the subset of an OpenAI chat completion request that Converse supports
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Tools    []struct {
+		Function struct {
+			Name        string         `json:"name"`
+			Description string         `json:"description"`
+			Parameters  map[string]any `json:"parameters"`
+		} `json:"function"`
+	} `json:"tools"`
+	ToolChoice          json.RawMessage `json:"tool_choice"`
+	MaxTokens           *int64          `json:"max_tokens"`
+	MaxCompletionTokens *int64          `json:"max_completion_tokens"`
+	Temperature         *float64        `json:"temperature"`
+	TopP                *float64        `json:"top_p"`
+	Stop                json.RawMessage `json:"stop"`
+	ResponseFormat      *struct {
+		Type string `json:"type"`
+	} `json:"response_format"`
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCallID string          `json:"tool_call_id"`
+	ToolCalls  []chatToolCall  `json:"tool_calls"`
+}
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+	File struct {
+		FileData string `json:"file_data"`
+		Filename string `json:"filename"`
+	} `json:"file"`
+}
+
+// converseRequest is the body of a Bedrock Converse request
+type converseRequest struct {
+	Messages        []converseMessage `json:"messages"`
+	System          []contentBlock    `json:"system,omitempty"`
+	InferenceConfig *inferenceConfig  `json:"inferenceConfig,omitempty"`
+	ToolConfig      *toolConfig       `json:"toolConfig,omitempty"`
+}
+
+type converseMessage struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlock struct {
+	Text       string           `json:"text,omitempty"`
+	Image      *imageBlock      `json:"image,omitempty"`
+	Document   *documentBlock   `json:"document,omitempty"`
+	ToolUse    *toolUseBlock    `json:"toolUse,omitempty"`
+	ToolResult *toolResultBlock `json:"toolResult,omitempty"`
+}
+
+type imageBlock struct {
+	Format string      `json:"format"`
+	Source bytesSource `json:"source"`
+}
+
+type documentBlock struct {
+	Format string      `json:"format"`
+	Name   string      `json:"name"`
+	Source bytesSource `json:"source"`
+}
+
+type bytesSource struct {
+	// Bytes is base64 encoded, as JSON encodes []byte
+	Bytes []byte `json:"bytes"`
+}
+
+type toolUseBlock struct {
+	ToolUseID string         `json:"toolUseId"`
+	Name      string         `json:"name"`
+	Input     map[string]any `json:"input"`
+}
+
+type toolResultBlock struct {
+	ToolUseID string         `json:"toolUseId"`
+	Content   []contentBlock `json:"content"`
+}
+
+type inferenceConfig struct {
+	MaxTokens     *int64   `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type toolConfig struct {
+	Tools      []toolSpecBlock `json:"tools"`
+	ToolChoice map[string]any  `json:"toolChoice,omitempty"`
+}
+
+type toolSpecBlock struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema struct {
+			JSON map[string]any `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+// converseResponse is the body of a Bedrock Converse response
+type converseResponse struct {
+	Output struct {
+		Message converseMessage `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens          int64 `json:"inputTokens"`
+		OutputTokens         int64 `json:"outputTokens"`
+		TotalTokens          int64 `json:"totalTokens"`
+		CacheReadInputTokens int64 `json:"cacheReadInputTokens"`
+	} `json:"usage"`
+}
+
+// toConverse translates an OpenAI chat completion request into a Converse request
+func toConverse(req chatRequest) (converseRequest, error) {
+	var out converseRequest
+	if req.ResponseFormat != nil && req.ResponseFormat.Type != "" && req.ResponseFormat.Type != "text" {
+		return out, fmt.Errorf("response_format %q is not supported by the Converse API", req.ResponseFormat.Type)
+	}
+
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			blocks, err := convertContent(msg.Content)
+			if err != nil {
+				return out, err
+			}
+			out.System = append(out.System, blocks...)
+		case "user":
+			blocks, err := convertContent(msg.Content)
+			if err != nil {
+				return out, err
+			}
+			out.Messages = appendMessage(out.Messages, "user", blocks)
+		case "assistant":
+			blocks, err := convertContent(msg.Content)
+			if err != nil {
+				return out, err
+			}
+			for _, call := range msg.ToolCalls {
+				input := map[string]any{}
+				if call.Function.Arguments != "" {
+					if err := json.Unmarshal([]byte(call.Function.Arguments), &input); err != nil {
+						return out, fmt.Errorf("tool call %s arguments: %w", call.ID, err)
+					}
+				}
+				blocks = append(blocks, contentBlock{ToolUse: &toolUseBlock{ToolUseID: call.ID, Name: call.Function.Name, Input: input}})
+			}
+			out.Messages = appendMessage(out.Messages, "assistant", blocks)
+		case "tool":
+			blocks, err := convertContent(msg.Content)
+			if err != nil {
+				return out, err
+			}
+			if len(blocks) == 0 {
+				blocks = []contentBlock{{Text: " "}}
+			}
+			// Converse carries tool results in user messages
+			out.Messages = appendMessage(out.Messages, "user", []contentBlock{{ToolResult: &toolResultBlock{ToolUseID: msg.ToolCallID, Content: blocks}}})
+		default:
+			return out, fmt.Errorf("unsupported message role %q", msg.Role)
+		}
+	}
+
+	config := inferenceConfig{Temperature: req.Temperature, TopP: req.TopP, MaxTokens: req.MaxTokens}
+	if req.MaxCompletionTokens != nil {
+		config.MaxTokens = req.MaxCompletionTokens
+	}
+	if len(req.Stop) > 0 {
+		var one string
+		if err := json.Unmarshal(req.Stop, &one); err == nil {
+			config.StopSequences = []string{one}
+		} else if err := json.Unmarshal(req.Stop, &config.StopSequences); err != nil {
+			return out, fmt.Errorf("stop: %w", err)
+		}
+	}
+	if config.MaxTokens != nil || config.Temperature != nil || config.TopP != nil || len(config.StopSequences) > 0 {
+		out.InferenceConfig = &config
+	}
+
+	if len(req.Tools) > 0 {
+		out.ToolConfig = &toolConfig{}
+		for _, tool := range req.Tools {
+			var spec toolSpecBlock
+			spec.ToolSpec.Name = tool.Function.Name
+			spec.ToolSpec.Description = tool.Function.Description
+			spec.ToolSpec.InputSchema.JSON = tool.Function.Parameters
+			if spec.ToolSpec.InputSchema.JSON == nil {
+				spec.ToolSpec.InputSchema.JSON = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			out.ToolConfig.Tools = append(out.ToolConfig.Tools, spec)
+		}
+		choice, err := convertToolChoice(req.ToolChoice)
+		if err != nil {
+			return out, err
+		}
+		if choice == nil && len(req.ToolChoice) > 0 {
+			// Converse has no "none" choice, so the tools are withheld instead
+			out.ToolConfig = nil
+		} else {
+			out.ToolConfig.ToolChoice = choice
+		}
+	}
+
+	return out, nil
+}
+
+// convertToolChoice returns the Converse tool choice, or nil for the default or "none"
+func convertToolChoice(raw json.RawMessage) (map[string]any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		switch mode {
+		case "auto":
+			return map[string]any{"auto": map[string]any{}}, nil
+		case "required":
+			return map[string]any{"any": map[string]any{}}, nil
+		case "none":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unsupported tool_choice %q", mode)
+		}
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return nil, fmt.Errorf("tool_choice: %w", err)
+	}
+	return map[string]any{"tool": map[string]any{"name": named.Function.Name}}, nil
+}
+
+// appendMessage adds content to the conversation, merging consecutive messages
+// with the same role because Converse requires roles to alternate
+func appendMessage(messages []converseMessage, role string, blocks []contentBlock) []converseMessage {
+	if len(blocks) == 0 {
+		return messages
+	}
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = append(messages[n-1].Content, blocks...)
+		return messages
+	}
+	return append(messages, converseMessage{Role: role, Content: blocks})
+}
+
+// convertContent converts OpenAI message content, a string or a list of parts
+func convertContent(raw json.RawMessage) ([]contentBlock, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if text == "" {
+			return nil, nil
+		}
+		return []contentBlock{{Text: text}}, nil
+	}
+
+	var parts []chatContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return nil, fmt.Errorf("message content: %w", err)
+	}
+	var blocks []contentBlock
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				blocks = append(blocks, contentBlock{Text: part.Text})
+			}
+		case "image_url":
+			image, err := convertImage(part.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, contentBlock{Image: image})
+		case "file":
+			data, err := base64.StdEncoding.DecodeString(part.File.FileData)
+			if err != nil {
+				return nil, fmt.Errorf("file %s: %w", part.File.Filename, err)
+			}
+			blocks = append(blocks, contentBlock{Document: &documentBlock{
+				Format: documentFormat(part.File.Filename),
+				Name:   documentName(part.File.Filename),
+				Source: bytesSource{Bytes: data},
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported content part %q", part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+// convertImage decodes an image data URL
+func convertImage(url string) (*imageBlock, error) {
+	header, data, ok := strings.Cut(url, ",")
+	if !ok || !strings.HasPrefix(header, "data:image/") || !strings.HasSuffix(header, ";base64") {
+		return nil, errors.New("only base64 data URL images are supported")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("image: %w", err)
+	}
+	format := strings.TrimSuffix(strings.TrimPrefix(header, "data:image/"), ";base64")
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	return &imageBlock{Format: format, Source: bytesSource{Bytes: decoded}}, nil
+}
+
+// documentFormat maps a file extension to a Converse document format, defaulting to txt
+func documentFormat(name string) string {
+	switch ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), "."); ext {
+	case "pdf", "csv", "doc", "docx", "xls", "xlsx", "html", "md":
+		return ext
+	case "htm":
+		return "html"
+	default:
+		return "txt"
+	}
+}
+
+// invalidNameChars matches characters Converse does not allow in document names
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9\s\-()\[\]]+`)
+
+// documentName sanitizes a file name for use as a document name
+func documentName(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.Join(strings.Fields(invalidNameChars.ReplaceAllString(name, " ")), " ")
+	if name == "" {
+		return "document"
+	}
+	return name
+}
+
+// fromConverse translates a Converse response into an OpenAI chat completion
+func fromConverse(model string, resp converseResponse) map[string]any {
+	var text strings.Builder
+	var toolCalls []map[string]any
+	for _, block := range resp.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			arguments, _ := json.Marshal(block.ToolUse.Input)
+			toolCalls = append(toolCalls, map[string]any{
+				"id":   block.ToolUse.ToolUseID,
+				"type": "function",
+				"function": map[string]any{
+					"name":      block.ToolUse.Name,
+					"arguments": string(arguments),
+				},
+			})
+		case block.Text != "":
+			text.WriteString(block.Text)
+		}
+	}
+
+	message := map[string]any{"role": "assistant", "content": text.String()}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	return map[string]any{
+		"id":     "bedrock",
+		"object": "chat.completion",
+		"model":  model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": finishReason(resp.StopReason),
+			"message":       message,
+		}},
+		"usage": map[string]any{
+			"prompt_tokens":         resp.Usage.InputTokens,
+			"completion_tokens":     resp.Usage.OutputTokens,
+			"total_tokens":          resp.Usage.TotalTokens,
+			"prompt_tokens_details": map[string]any{"cached_tokens": resp.Usage.CacheReadInputTokens},
+		},
+	}
+}
+
+// finishReason maps a Converse stop reason to an OpenAI finish reason
+func finishReason(stopReason string) string {
+	switch stopReason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	case "content_filtered", "guardrail_intervened":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
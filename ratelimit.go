@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is wrapped by the *RateLimitError returned when a fail-fast
+// RateLimiter has no capacity for a request
+var ErrRateLimited = errors.New("client rate limit reached")
+
+// RateLimiter paces provider requests so that services running many agents do
+// not trip the provider's rate limits. A limiter is usually shared by every
+// agent using the same provider account.
+type RateLimiter interface {
+	// Wait blocks until a request to model estimated to use tokens may be sent,
+	// or returns an error if it may not
+	Wait(ctx context.Context, model string, tokens int64) error
+	// Record reports the tokens a request admitted by Wait actually used, zero
+	// if it failed, so the limiter can correct its estimate
+	Record(model string, estimated int64, used int64)
+}
+
+// RateLimit configures a limiter created with NewRateLimiter
+type RateLimit struct {
+	// RequestsPerSecond is the sustained request rate, or zero for no limit
+	RequestsPerSecond float64
+	// TokensPerMinute is the sustained token rate, or zero for no limit
+	TokensPerMinute int64
+	// FailFast returns a *RateLimitError wrapping ErrRateLimited when a request
+	// would have to wait, instead of waiting. The error's RetryAfter is the wait.
+	FailFast bool
+}
+
+// WithRateLimiter paces the agent's provider requests, including fallbacks and
+// tool result summaries, with limiter. Share one limiter between agents to
+// apply a limit across all of them. A request's tokens are estimated from its
+// size before it is sent and corrected with its usage afterwards.
+func WithRateLimiter(limiter RateLimiter) AgentOption {
+	return func(a *Agent) {
+		a.rateLimiter = limiter
+	}
+}
+
+// TokenBucketLimiter is a RateLimiter allowing bursts of up to one second of
+// requests and one minute of tokens. It is safe for concurrent use.
+type TokenBucketLimiter struct {
+	failFast bool
+
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+}
+
+// NewRateLimiter creates a limiter enforcing limit across every model
+func NewRateLimiter(limit RateLimit) *TokenBucketLimiter {
+	l := &TokenBucketLimiter{failFast: limit.FailFast}
+	now := time.Now()
+	if limit.RequestsPerSecond > 0 {
+		l.requests = newBucket(limit.RequestsPerSecond, max(limit.RequestsPerSecond, 1), now)
+	}
+	if limit.TokensPerMinute > 0 {
+		l.tokens = newBucket(float64(limit.TokensPerMinute)/60, float64(limit.TokensPerMinute), now)
+	}
+	return l
+}
+
+// Wait reserves capacity for a request and waits until the reservation is due.
+// A request larger than the token burst waits for a full bucket.
+func (l *TokenBucketLimiter) Wait(ctx context.Context, model string, tokens int64) error {
+	l.mu.Lock()
+	now := time.Now()
+	var wait time.Duration
+	if l.requests != nil {
+		wait = max(wait, l.requests.wait(1, now))
+	}
+	n := float64(tokens)
+	if l.tokens != nil {
+		n = min(n, l.tokens.burst)
+		wait = max(wait, l.tokens.wait(n, now))
+	}
+	if wait > 0 && l.failFast {
+		l.mu.Unlock()
+		return &RateLimitError{RetryAfter: wait, Err: ErrRateLimited}
+	}
+	l.take(1, n)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give back the reservation so later requests need not wait for it
+		l.mu.Lock()
+		l.take(-1, -n)
+		l.mu.Unlock()
+		return context.Cause(ctx)
+	}
+}
+
+// Record charges or refunds the difference between a request's estimated and
+// actual token use
+func (l *TokenBucketLimiter) Record(model string, estimated int64, used int64) {
+	if l.tokens == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.advance(time.Now())
+	// Wait reserved no more than the burst, so only that much can be refunded
+	reserved := min(float64(estimated), l.tokens.burst)
+	l.tokens.level = min(l.tokens.burst, l.tokens.level-(float64(used)-reserved))
+}
+
+// take removes requests and tokens from the buckets, which may go into debt
+// for reservations that are waiting
+func (l *TokenBucketLimiter) take(requests float64, tokens float64) {
+	if l.requests != nil {
+		l.requests.level -= requests
+	}
+	if l.tokens != nil {
+		l.tokens.level -= tokens
+	}
+}
+
+// bucket is a token bucket refilled continuously at rate per second up to burst
+type bucket struct {
+	rate  float64
+	burst float64
+	level float64
+	last  time.Time
+}
+
+func newBucket(rate float64, burst float64, now time.Time) *bucket {
+	return &bucket{rate: rate, burst: burst, level: burst, last: now}
+}
+
+// advance refills the bucket for the time since it was last advanced
+func (b *bucket) advance(now time.Time) {
+	if now.After(b.last) {
+		b.level = min(b.burst, b.level+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until the bucket holds n
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	b.advance(now)
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.rate * float64(time.Second))
+}
+
+// waitForRateLimit waits for the agent's rate limiter to admit a request of
+// the given size, returning the token estimate to pass to recordRateLimit
+func (agent *Agent) waitForRateLimit(ctx context.Context, model string, request any) (int64, error) {
+	if agent.rateLimiter == nil {
+		return 0, nil
+	}
+	var estimated int64
+	if data, err := json.Marshal(request); err == nil {
+		estimated = int64(estimateTokens(string(data)))
+	}
+	return estimated, agent.rateLimiter.Wait(ctx, model, estimated)
+}
+
+// recordRateLimit reports a request's token use to the agent's rate limiter
+func (agent *Agent) recordRateLimit(model string, estimated int64, used int64) {
+	if agent.rateLimiter != nil {
+		agent.rateLimiter.Record(model, estimated, used)
+	}
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("requests", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 10, FailFast: true})
+		for range 10 {
+			require.NoError(t, limiter.Wait(ctx, "model", 0))
+		}
+		err := limiter.Wait(ctx, "model", 0)
+		var rateLimitErr *RateLimitError
+		require.ErrorAs(t, err, &rateLimitErr)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.InDelta(t, 100*time.Millisecond, rateLimitErr.RetryAfter, float64(20*time.Millisecond))
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{TokensPerMinute: 6000, FailFast: true})
+		require.NoError(t, limiter.Wait(ctx, "model", 5000))
+		assert.ErrorIs(t, limiter.Wait(ctx, "model", 2000), ErrRateLimited)
+
+		// Using fewer tokens than estimated refunds the difference
+		limiter.Record("model", 5000, 1000)
+		require.NoError(t, limiter.Wait(ctx, "model", 2000))
+
+		// A request larger than the burst waits for a full bucket
+		full := NewRateLimiter(RateLimit{TokensPerMinute: 6000, FailFast: true})
+		assert.NoError(t, full.Wait(ctx, "model", 10000))
+		// and using the whole burst refunds nothing, as the rest was never taken
+		full.Record("model", 10000, 6000)
+		assert.ErrorIs(t, full.Wait(ctx, "model", 2000), ErrRateLimited)
+	})
+
+	t.Run("blocking", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 20})
+		for range 20 {
+			require.NoError(t, limiter.Wait(ctx, "model", 0))
+		}
+		start := time.Now()
+		require.NoError(t, limiter.Wait(ctx, "model", 0))
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+		// A canceled wait returns its reservation
+		canceled, cancel := context.WithCancelCause(ctx)
+		cause := errors.New("client gone")
+		cancel(cause)
+		assert.ErrorIs(t, limiter.Wait(canceled, "model", 0), cause)
+		start = time.Now()
+		require.NoError(t, limiter.Wait(ctx, "model", 0))
+		assert.Less(t, time.Since(start), 90*time.Millisecond)
+	})
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "model", "hello")
+	}
+	// Agents sharing a limiter share its capacity
+	limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 1, FailFast: true})
+	first := newTestAgent(t, "model", handler, WithRateLimiter(limiter))
+	second := newTestAgent(t, "model", handler, WithRateLimiter(limiter))
+
+	completion, err := first.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, completion.Messages)
+
+	_, err = second.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// clientEvent is an event sent to the realtime API
+type clientEvent struct {
+	Type    string         `json:"type"`
+	Session *sessionConfig `json:"session,omitempty"`
+	Audio   string         `json:"audio,omitempty"`
+	Item    *item          `json:"item,omitempty"`
+}
+
+type sessionConfig struct {
+	Modalities              []string         `json:"modalities"`
+	Instructions            string           `json:"instructions,omitempty"`
+	Voice                   string           `json:"voice,omitempty"`
+	InputAudioFormat        string           `json:"input_audio_format"`
+	OutputAudioFormat       string           `json:"output_audio_format"`
+	InputAudioTranscription *transcription   `json:"input_audio_transcription,omitempty"`
+	TurnDetection           *turnDetection   `json:"turn_detection"`
+	Tools                   []toolDefinition `json:"tools,omitempty"`
+	ToolChoice              string           `json:"tool_choice,omitempty"`
+}
+
+type transcription struct {
+	Model string `json:"model"`
+}
+
+type turnDetection struct {
+	Type string `json:"type"`
+}
+
+type toolDefinition struct {
+	Type        string         `json:"type"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type item struct {
+	Type    string        `json:"type"`
+	Role    string        `json:"role,omitempty"`
+	Content []itemContent `json:"content,omitempty"`
+	CallID  string        `json:"call_id,omitempty"`
+	Output  string        `json:"output,omitempty"`
+}
+
+type itemContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// serverEvent is an event received from the realtime API. Only the fields
+// used by the event types this package handles are decoded.
+type serverEvent struct {
+	Type       string          `json:"type"`
+	Delta      string          `json:"delta"`
+	Transcript string          `json:"transcript"`
+	CallID     string          `json:"call_id"`
+	Name       string          `json:"name"`
+	Arguments  string          `json:"arguments"`
+	Error      *Error          `json:"error"`
+	Response   json.RawMessage `json:"response"`
+}
+
+type responseDone struct {
+	Status string `json:"status"`
+	Usage  struct {
+		TotalTokens       int64 `json:"total_tokens"`
+		InputTokens       int64 `json:"input_tokens"`
+		OutputTokens      int64 `json:"output_tokens"`
+		InputTokenDetails struct {
+			CachedTokens int64 `json:"cached_tokens"`
+			AudioTokens  int64 `json:"audio_tokens"`
+		} `json:"input_token_details"`
+		OutputTokenDetails struct {
+			AudioTokens int64 `json:"audio_tokens"`
+		} `json:"output_token_details"`
+	} `json:"usage"`
+}
+
+// Error is an error reported by the realtime API. The session stays open.
+type Error struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("realtime %s (%s): %s", e.Type, e.Code, e.Message)
+	}
+	return fmt.Sprintf("realtime %s: %s", e.Type, e.Message)
+}
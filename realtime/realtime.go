@@ -0,0 +1,499 @@
+// Package realtime connects to WebSocket realtime APIs, such as OpenAI's, for
+// low-latency speech-to-speech agents. Audio flows through Go channels as raw
+// 16-bit PCM, and the model calls the same agent.Tool implementations as text
+// agents:
+//
+//	session, err := realtime.Connect(ctx, apiKey,
+//		realtime.WithInstructions("You are a friendly phone assistant."),
+//		realtime.WithTools([]agent.Tool{orderTool}),
+//	)
+//	go func() {
+//		for chunk := range microphone {
+//			session.AudioIn() <- chunk
+//		}
+//	}()
+//	go func() {
+//		for event := range session.Events() {
+//			if event.Type == realtime.EventSpeechStarted {
+//				speaker.Flush() // the user is talking over the model
+//			}
+//		}
+//	}()
+//	for chunk := range session.AudioOut() {
+//		speaker.Write(chunk)
+//	}
+package realtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/internal/websocket"
+)
+
+// DefaultURL is the endpoint of OpenAI's realtime API
+const DefaultURL = "wss://api.openai.com/v1/realtime"
+
+// DefaultModel is the realtime model used unless WithModel is given
+const DefaultModel = "gpt-4o-realtime-preview"
+
+// Option is a functional option for configuring a Session
+type Option func(*Session)
+
+// WithURL sets the realtime endpoint, for compatible providers and proxies
+func WithURL(url string) Option {
+	return func(s *Session) {
+		s.url = url
+	}
+}
+
+// WithModel sets the realtime model
+func WithModel(model string) Option {
+	return func(s *Session) {
+		s.model = model
+	}
+}
+
+// WithVoice sets the voice the model speaks with, such as "alloy" or "verse"
+func WithVoice(voice string) Option {
+	return func(s *Session) {
+		s.config.Voice = voice
+	}
+}
+
+// WithInstructions sets the session's system instructions
+func WithInstructions(instructions string) Option {
+	return func(s *Session) {
+		s.config.Instructions = instructions
+	}
+}
+
+// WithTools makes tools available to the model. Calls are executed as they
+// arrive and their results are sent back before the model continues.
+func WithTools(tools []agent.Tool) Option {
+	return func(s *Session) {
+		for _, tool := range tools {
+			s.tools[tool.Name()] = tool
+		}
+	}
+}
+
+// WithTranscription transcribes the user's speech with model, such as
+// "whisper-1", reported as EventInputTranscript events
+func WithTranscription(model string) Option {
+	return func(s *Session) {
+		s.config.InputAudioTranscription = &transcription{Model: model}
+	}
+}
+
+// WithManualTurns turns off the server's voice activity detection, so the
+// model only responds when Respond is called
+func WithManualTurns() Option {
+	return func(s *Session) {
+		s.config.TurnDetection = nil
+	}
+}
+
+// WithHeader adds a header to the WebSocket handshake
+func WithHeader(key string, value string) Option {
+	return func(s *Session) {
+		s.header.Add(key, value)
+	}
+}
+
+// EventType identifies what an Event reports
+type EventType string
+
+const (
+	// EventSpeechStarted means the user started speaking. Stop playing the
+	// model's audio: the server interrupts its response.
+	EventSpeechStarted EventType = "speech_started"
+	// EventSpeechStopped means the user stopped speaking
+	EventSpeechStopped EventType = "speech_stopped"
+	// EventInputTranscript carries the transcript of the user's speech
+	EventInputTranscript EventType = "input_transcript"
+	// EventTranscript carries a piece of the transcript of the model's speech
+	EventTranscript EventType = "transcript"
+	// EventText carries a piece of a text response
+	EventText EventType = "text"
+	// EventToolCall reports a tool call the session executed
+	EventToolCall EventType = "tool_call"
+	// EventResponseDone means the model finished a response, and carries its usage
+	EventResponseDone EventType = "response_done"
+	// EventError carries an error reported by the API; the session stays open
+	EventError EventType = "error"
+)
+
+// Event is something that happened in a session, other than audio output
+type Event struct {
+	Type EventType
+	// Text is the transcript or text of transcript and text events
+	Text string
+	// ToolCall is the call of a tool call event
+	ToolCall *ToolCall
+	// Usage is the usage of a response done event
+	Usage agent.Usage
+	// Err is the error of an error event
+	Err error
+}
+
+// ToolCall is a tool call executed by a session
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+	// Result is the output sent to the model, which describes Err if the call failed
+	Result string
+	Err    error
+}
+
+// Session is a connection to a realtime model. Its channels are closed when
+// the session ends.
+type Session struct {
+	url    string
+	model  string
+	header http.Header
+	config sessionConfig
+	tools  map[string]agent.Tool
+
+	conn     *websocket.Conn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	audioIn  chan []byte
+	audioOut chan []byte
+	events   chan Event
+	done     chan struct{}
+	calls    sync.WaitGroup
+
+	mu      sync.Mutex
+	closing bool
+	err     error
+}
+
+// Connect opens a realtime session authenticated with apiKey. The session
+// runs until Close is called or the connection fails; ctx only bounds
+// connecting, though its values reach tool calls.
+func Connect(ctx context.Context, apiKey string, opts ...Option) (*Session, error) {
+	s := &Session{
+		url:    DefaultURL,
+		model:  DefaultModel,
+		header: http.Header{},
+		config: sessionConfig{
+			Modalities:        []string{"audio", "text"},
+			InputAudioFormat:  "pcm16",
+			OutputAudioFormat: "pcm16",
+			TurnDetection:     &turnDetection{Type: "server_vad"},
+		},
+		tools:    map[string]agent.Tool{},
+		audioIn:  make(chan []byte, 64),
+		audioOut: make(chan []byte, 64),
+		events:   make(chan Event, 64),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.tools)) {
+		tool := s.tools[name]
+		s.config.Tools = append(s.config.Tools, toolDefinition{
+			Type:        "function",
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": tool.Parameters().Properties,
+				"required":   tool.Parameters().Required,
+			},
+		})
+	}
+	if len(s.config.Tools) > 0 {
+		s.config.ToolChoice = "auto"
+	}
+
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("model", s.model)
+	u.RawQuery = query.Encode()
+	s.header.Set("Authorization", "Bearer "+apiKey)
+	s.header.Set("OpenAI-Beta", "realtime=v1")
+
+	s.conn, err = websocket.Dial(ctx, u.String(), s.header)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.send(clientEvent{Type: "session.update", Session: &s.config}); err != nil {
+		s.conn.Close()
+		return nil, err
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	go s.read()
+	go s.writeAudio()
+	return s, nil
+}
+
+// AudioIn accepts chunks of the user's speech as 16-bit little-endian mono PCM
+// at 24kHz. Closing it stops sending audio without ending the session.
+func (s *Session) AudioIn() chan<- []byte {
+	return s.audioIn
+}
+
+// AudioOut delivers chunks of the model's speech as 16-bit little-endian mono
+// PCM at 24kHz
+func (s *Session) AudioOut() <-chan []byte {
+	return s.audioOut
+}
+
+// Events delivers transcripts, tool calls, and other session events. Keep
+// reading it, along with AudioOut, for the session to make progress.
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed when the session ends
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the session ended, or nil if it was closed with Close or is
+// still running
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// SendText adds a user message to the conversation and asks for a response
+func (s *Session) SendText(text string) error {
+	err := s.send(clientEvent{Type: "conversation.item.create", Item: &item{
+		Type:    "message",
+		Role:    "user",
+		Content: []itemContent{{Type: "input_text", Text: text}},
+	}})
+	if err != nil {
+		return err
+	}
+	return s.send(clientEvent{Type: "response.create"})
+}
+
+// Respond commits the audio sent so far as the user's turn and asks for a
+// response, for sessions with WithManualTurns
+func (s *Session) Respond() error {
+	if err := s.send(clientEvent{Type: "input_audio_buffer.commit"}); err != nil {
+		return err
+	}
+	return s.send(clientEvent{Type: "response.create"})
+}
+
+// Interrupt cancels the response in progress, such as when the user talks
+// over the model with manual turns
+func (s *Session) Interrupt() error {
+	return s.send(clientEvent{Type: "response.cancel"})
+}
+
+// Close ends the session
+func (s *Session) Close() error {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+// send writes a client event to the connection
+func (s *Session) send(event clientEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// writeAudio forwards chunks from AudioIn to the input audio buffer
+func (s *Session) writeAudio() {
+	for {
+		select {
+		case chunk, ok := <-s.audioIn:
+			if !ok {
+				return
+			}
+			if err := s.send(clientEvent{Type: "input_audio_buffer.append", Audio: base64.StdEncoding.EncodeToString(chunk)}); err != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// read dispatches server events until the connection ends, then closes the
+// session's channels
+func (s *Session) read() {
+	err := s.dispatch()
+
+	s.mu.Lock()
+	var closeErr *websocket.CloseError
+	normal := errors.As(err, &closeErr) && closeErr.Code == websocket.CloseNormal
+	if !s.closing && !normal {
+		s.err = err
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	close(s.done)
+	s.conn.Close()
+	// Tool calls stop sending once done is closed
+	s.calls.Wait()
+	close(s.events)
+	close(s.audioOut)
+}
+
+func (s *Session) dispatch() error {
+	// The tool calls of the response in progress, which the model continues
+	// from together once the response is done
+	var turn *sync.WaitGroup
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		var event serverEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+
+		switch event.Type {
+		case "response.audio.delta":
+			chunk, err := base64.StdEncoding.DecodeString(event.Delta)
+			if err != nil {
+				return err
+			}
+			select {
+			case s.audioOut <- chunk:
+			case <-s.ctx.Done():
+				return nil
+			}
+		case "input_audio_buffer.speech_started":
+			s.emit(Event{Type: EventSpeechStarted})
+		case "input_audio_buffer.speech_stopped":
+			s.emit(Event{Type: EventSpeechStopped})
+		case "conversation.item.input_audio_transcription.completed":
+			s.emit(Event{Type: EventInputTranscript, Text: event.Transcript})
+		case "response.audio_transcript.delta":
+			s.emit(Event{Type: EventTranscript, Text: event.Delta})
+		case "response.text.delta":
+			s.emit(Event{Type: EventText, Text: event.Delta})
+		case "response.function_call_arguments.done":
+			call := &ToolCall{ID: event.CallID, Name: event.Name, Arguments: event.Arguments}
+			if turn == nil {
+				turn = &sync.WaitGroup{}
+			}
+			s.calls.Add(1)
+			turn.Add(1)
+			go s.callTool(call, turn)
+		case "response.done":
+			var response responseDone
+			if err := json.Unmarshal(event.Response, &response); err != nil {
+				return err
+			}
+			if turn != nil {
+				s.calls.Add(1)
+				go s.continueAfter(turn)
+				turn = nil
+			}
+			s.emit(Event{Type: EventResponseDone, Usage: agent.Usage{
+				Model:                 s.model,
+				PromptTokens:          response.Usage.InputTokens,
+				CachedPromptTokens:    response.Usage.InputTokenDetails.CachedTokens,
+				AudioPromptTokens:     response.Usage.InputTokenDetails.AudioTokens,
+				CompletionTokens:      response.Usage.OutputTokens,
+				AudioCompletionTokens: response.Usage.OutputTokenDetails.AudioTokens,
+				TotalTokens:           response.Usage.TotalTokens,
+			}})
+		case "error":
+			if event.Error != nil {
+				s.emit(Event{Type: EventError, Err: event.Error})
+			}
+		}
+	}
+}
+
+// emit delivers an event unless the session ends first
+func (s *Session) emit(event Event) {
+	select {
+	case s.events <- event:
+	case <-s.ctx.Done():
+	}
+}
+
+// callTool executes a tool call and sends its result back to the model
+func (s *Session) callTool(call *ToolCall, turn *sync.WaitGroup) {
+	defer s.calls.Done()
+	defer turn.Done()
+
+	call.Result, call.Err = s.execute(call)
+	if call.Err != nil {
+		call.Result = "Error: " + call.Err.Error()
+	}
+	err := s.send(clientEvent{Type: "conversation.item.create", Item: &item{
+		Type:   "function_call_output",
+		CallID: call.ID,
+		Output: call.Result,
+	}})
+	if err != nil {
+		return
+	}
+	s.emit(Event{Type: EventToolCall, ToolCall: call})
+}
+
+// continueAfter asks the model to continue once every tool call of a
+// response has sent its result
+func (s *Session) continueAfter(turn *sync.WaitGroup) {
+	defer s.calls.Done()
+
+	turn.Wait()
+	s.send(clientEvent{Type: "response.create"})
+}
+
+// execute runs the tool named by a call and formats its result
+func (s *Session) execute(call *ToolCall) (string, error) {
+	tool, ok := s.tools[call.Name]
+	if !ok {
+		return "", errors.New("no such tool: " + call.Name)
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return "", err
+	}
+	if err := agent.ValidateArguments(tool.Parameters(), args); err != nil {
+		return "", err
+	}
+	result, err := tool.Execute(s.ctx, args)
+	if err != nil {
+		return "", err
+	}
+	if text, ok := result.(string); ok {
+		return text, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
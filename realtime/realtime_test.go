@@ -0,0 +1,200 @@
+package realtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lookupTool looks up an order's status
+type lookupTool struct{}
+
+func (lookupTool) Name() string        { return "lookup_order" }
+func (lookupTool) Description() string { return "Look up an order" }
+func (lookupTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{"id": map[string]any{"type": "string"}},
+		Required:   []string{"id"},
+	}
+}
+func (lookupTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return map[string]any{"id": input["id"], "status": "shipped"}, nil
+}
+
+// fakeServer plays a realtime API: it answers audio with a tool call, and the
+// tool result with speech
+func fakeServer(t *testing.T, received chan<- map[string]any) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		assert.Equal(t, "gpt-test", r.URL.Query().Get("model"))
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		send := func(event string) {
+			assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(event)))
+		}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var event map[string]any
+			assert.NoError(t, json.Unmarshal(data, &event))
+			received <- event
+			switch event["type"] {
+			case "input_audio_buffer.append":
+				send(`{"type":"input_audio_buffer.speech_started"}`)
+				send(`{"type":"conversation.item.input_audio_transcription.completed","transcript":"where is order 7?"}`)
+				send(`{"type":"response.function_call_arguments.done","call_id":"call_1","name":"lookup_order","arguments":"{\"id\":\"7\"}"}`)
+				send(`{"type":"response.done","response":{"status":"completed"}}`)
+			case "response.create":
+				send(`{"type":"response.audio.delta","delta":"` + base64.StdEncoding.EncodeToString([]byte{1, 2, 3, 4}) + `"}`)
+				send(`{"type":"response.audio_transcript.delta","delta":"It shipped."}`)
+				send(`{"type":"error","error":{"type":"invalid_request_error","code":"bad","message":"ignored"}}`)
+				send(`{"type":"response.done","response":{"status":"completed","usage":{"total_tokens":30,"input_tokens":20,"output_tokens":10,"input_token_details":{"audio_tokens":15},"output_token_details":{"audio_tokens":8}}}}`)
+			}
+		}
+	}))
+}
+
+func TestSession(t *testing.T) {
+	received := make(chan map[string]any, 16)
+	server := fakeServer(t, received)
+	defer server.Close()
+
+	session, err := Connect(context.Background(), "key",
+		WithURL("ws"+strings.TrimPrefix(server.URL, "http")),
+		WithModel("gpt-test"),
+		WithVoice("verse"),
+		WithInstructions("Be brief."),
+		WithTools([]agent.Tool{lookupTool{}}),
+	)
+	require.NoError(t, err)
+
+	update := <-received
+	assert.Equal(t, "session.update", update["type"])
+	config := update["session"].(map[string]any)
+	assert.Equal(t, "verse", config["voice"])
+	assert.Equal(t, "Be brief.", config["instructions"])
+	assert.Equal(t, "server_vad", config["turn_detection"].(map[string]any)["type"])
+	assert.Equal(t, "lookup_order", config["tools"].([]any)[0].(map[string]any)["name"])
+
+	session.AudioIn() <- []byte{9, 9}
+	appended := <-received
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{9, 9}), appended["audio"])
+
+	// Events are keyed by type, up to the response that follows the tool call
+	events := map[EventType]Event{}
+	for event := range session.Events() {
+		events[event.Type] = event
+		if event.Type == EventResponseDone && event.Usage.TotalTokens > 0 {
+			break
+		}
+	}
+	assert.Contains(t, events, EventSpeechStarted)
+	assert.Equal(t, "where is order 7?", events[EventInputTranscript].Text)
+	assert.Equal(t, `{"id":"7","status":"shipped"}`, events[EventToolCall].ToolCall.Result)
+	assert.Equal(t, "It shipped.", events[EventTranscript].Text)
+	assert.EqualError(t, events[EventError].Err, "realtime invalid_request_error (bad): ignored")
+	assert.Equal(t, agent.Usage{Model: "gpt-test", PromptTokens: 20, AudioPromptTokens: 15, CompletionTokens: 10, AudioCompletionTokens: 8, TotalTokens: 30}, events[EventResponseDone].Usage)
+	assert.Equal(t, []byte{1, 2, 3, 4}, <-session.AudioOut())
+
+	// The tool result went back before the model was asked to continue
+	output := <-received
+	assert.Equal(t, "conversation.item.create", output["type"])
+	assert.Equal(t, map[string]any{"type": "function_call_output", "call_id": "call_1", "output": `{"id":"7","status":"shipped"}`}, output["item"])
+	assert.Equal(t, "response.create", (<-received)["type"])
+
+	require.NoError(t, session.Close())
+	assert.NoError(t, session.Err())
+	_, open := <-session.AudioOut()
+	assert.False(t, open)
+}
+
+func TestSessionParallelToolCalls(t *testing.T) {
+	received := make(chan map[string]any, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var event map[string]any
+			assert.NoError(t, json.Unmarshal(data, &event))
+			received <- event
+			if event["type"] == "session.update" {
+				for _, call := range []string{
+					`{"type":"response.function_call_arguments.done","call_id":"call_1","name":"lookup_order","arguments":"{\"id\":\"7\"}"}`,
+					`{"type":"response.function_call_arguments.done","call_id":"call_2","name":"lookup_order","arguments":"{\"id\":\"8\"}"}`,
+					`{"type":"response.done","response":{"status":"completed"}}`,
+				} {
+					assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(call)))
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	session, err := Connect(context.Background(), "key",
+		WithURL("ws"+strings.TrimPrefix(server.URL, "http")),
+		WithTools([]agent.Tool{lookupTool{}}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "session.update", (<-received)["type"])
+
+	// Both results go back before the model is asked to continue, once
+	var types []string
+	for range 3 {
+		types = append(types, (<-received)["type"].(string))
+	}
+	assert.Equal(t, []string{"conversation.item.create", "conversation.item.create", "response.create"}, types)
+
+	require.NoError(t, session.Close())
+	for range session.Events() {
+	}
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected %v after the response was created", event["type"])
+	default:
+	}
+}
+
+func TestSessionServerClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		conn.ReadMessage()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.text.delta","delta":"bye"}`))
+		conn.Close()
+	}))
+	defer server.Close()
+
+	session, err := Connect(context.Background(), "key", WithURL("ws"+strings.TrimPrefix(server.URL, "http")), WithManualTurns())
+	require.NoError(t, err)
+
+	var texts []string
+	for event := range session.Events() {
+		texts = append(texts, event.Text)
+	}
+	assert.Equal(t, []string{"bye"}, texts)
+	<-session.Done()
+	assert.NoError(t, session.Err())
+}
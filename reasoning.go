@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ReasoningEffort bounds how much hidden reasoning a reasoning model does before answering
+type ReasoningEffort string
+
+const (
+	ReasoningEffortLow    ReasoningEffort = "low"
+	ReasoningEffortMedium ReasoningEffort = "medium"
+	ReasoningEffortHigh   ReasoningEffort = "high"
+)
+
+// WithMaxCompletionTokens caps the tokens generated per request, including hidden
+// reasoning. It is sent as max_completion_tokens to reasoning models and as
+// max_tokens to other models.
+func WithMaxCompletionTokens(n int64) AgentOption {
+	return func(a *Agent) {
+		a.maxCompletionTokens = n
+	}
+}
+
+// WithReasoningEffort sets the reasoning effort of reasoning models (OpenAI o-series).
+// It is not sent to other models, which reject it.
+func WithReasoningEffort(effort ReasoningEffort) AgentOption {
+	return func(a *Agent) {
+		a.reasoningEffort = effort
+	}
+}
+
+// isReasoningModel reports whether the model is an OpenAI reasoning model, which
+// accepts developer messages in place of system messages and reasoning parameters
+func isReasoningModel(model string) bool {
+	model = model[strings.LastIndex(model, "/")+1:]
+	for _, prefix := range []string{"o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyModelParams sets the token limit and reasoning parameters in the form the model accepts
+func (agent *Agent) applyModelParams(params *openai.ChatCompletionNewParams, model string, maxCompletionTokens int64) {
+	params.MaxTokens = openai.ChatCompletionNewParams{}.MaxTokens
+	params.MaxCompletionTokens = openai.ChatCompletionNewParams{}.MaxCompletionTokens
+	params.ReasoningEffort = ""
+
+	reasoning := isReasoningModel(model)
+	if maxCompletionTokens > 0 {
+		if reasoning {
+			params.MaxCompletionTokens = openai.Int(maxCompletionTokens)
+		} else {
+			params.MaxTokens = openai.Int(maxCompletionTokens)
+		}
+	}
+	if reasoning && agent.reasoningEffort != "" {
+		params.ReasoningEffort = openai.ReasoningEffort(agent.reasoningEffort)
+	}
+}
+
+// adaptMessagesForModel rewrites system and developer messages into the role the
+// model accepts: reasoning models reject system messages and other models reject
+// developer messages. The input slice is not modified.
+func adaptMessagesForModel(
+	messages []openai.ChatCompletionMessageParamUnion,
+	model string,
+) []openai.ChatCompletionMessageParamUnion {
+	reasoning := isReasoningModel(model)
+	adapted := make([]openai.ChatCompletionMessageParamUnion, len(messages))
+	for i, msg := range messages {
+		switch {
+		case reasoning && msg.OfSystem != nil:
+			msg = openai.ChatCompletionMessageParamUnion{
+				OfDeveloper: &openai.ChatCompletionDeveloperMessageParam{
+					Content: openai.ChatCompletionDeveloperMessageParamContentUnion{
+						OfString:              msg.OfSystem.Content.OfString,
+						OfArrayOfContentParts: msg.OfSystem.Content.OfArrayOfContentParts,
+					},
+					Name: msg.OfSystem.Name,
+				},
+			}
+		case !reasoning && msg.OfDeveloper != nil:
+			msg = openai.ChatCompletionMessageParamUnion{
+				OfSystem: &openai.ChatCompletionSystemMessageParam{
+					Content: openai.ChatCompletionSystemMessageParamContentUnion{
+						OfString:              msg.OfDeveloper.Content.OfString,
+						OfArrayOfContentParts: msg.OfDeveloper.Content.OfArrayOfContentParts,
+					},
+					Name: msg.OfDeveloper.Name,
+				},
+			}
+		}
+		adapted[i] = msg
+	}
+	return adapted
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go"
+)
+
+// ReferencePolicy configures replacing older tool results in the conversation
+// with short references, keeping long runs within the context window. The model
+// can fetch a referenced result in full with the get_tool_result tool.
+type ReferencePolicy struct {
+	// KeepRecent is the number of most recent tool results kept inline. The default is 3.
+	KeepRecent int
+	// MinTokens is the estimated token count below which results are always kept
+	// inline, since a reference would save little. The default is 200.
+	MinTokens int
+}
+
+// WithToolResultReferences replaces tool results older than the most recent few
+// with references before each request. Rewriting earlier messages changes the
+// request prefix, so it reduces the benefit of prompt caching.
+func WithToolResultReferences(policy ReferencePolicy) AgentOption {
+	return func(a *Agent) {
+		if policy.KeepRecent <= 0 {
+			policy.KeepRecent = 3
+		}
+		if policy.MinTokens <= 0 {
+			policy.MinTokens = 200
+		}
+		a.referencePolicy = &policy
+	}
+}
+
+type resultArchiveKey struct{}
+
+// resultArchive holds the full content of the tool results referenced in a run
+type resultArchive struct {
+	mu      sync.Mutex
+	results []string
+	byCall  map[string]int
+}
+
+// withResultArchive returns a context holding an archive for the run's referenced results
+func (agent *Agent) withResultArchive(ctx context.Context) (context.Context, *resultArchive) {
+	if agent.referencePolicy == nil {
+		return ctx, nil
+	}
+	archive := &resultArchive{byCall: map[string]int{}}
+	return context.WithValue(ctx, resultArchiveKey{}, archive), archive
+}
+
+// get returns the result with the given number, counting from 1
+func (a *resultArchive) get(n int) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if n < 1 || n > len(a.results) {
+		return "", false
+	}
+	return a.results[n-1], true
+}
+
+// add archives the result of a tool call and returns its number. Content that
+// is already archived, such as a result fetched again, keeps its number.
+func (a *resultArchive) add(toolCallID string, content string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, result := range a.results {
+		if result == content {
+			a.byCall[toolCallID] = i + 1
+			return i + 1
+		}
+	}
+	a.results = append(a.results, content)
+	a.byCall[toolCallID] = len(a.results)
+	return len(a.results)
+}
+
+func (a *resultArchive) referenced(toolCallID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.byCall[toolCallID]
+	return ok
+}
+
+// referenceOldResults replaces tool results older than the policy's most recent
+// ones with references to their archived content
+func (agent *Agent) referenceOldResults(archive *resultArchive, messages []openai.ChatCompletionMessageParamUnion) {
+	if archive == nil {
+		return
+	}
+
+	toolNames := map[string]string{}
+	var toolMessages []int
+	for i, msg := range messages {
+		if msg.OfAssistant != nil {
+			for _, toolCall := range msg.OfAssistant.ToolCalls {
+				toolNames[toolCall.ID] = toolCall.Function.Name
+			}
+		}
+		if msg.OfTool != nil {
+			toolMessages = append(toolMessages, i)
+		}
+	}
+
+	for _, i := range toolMessages[:max(0, len(toolMessages)-agent.referencePolicy.KeepRecent)] {
+		toolCallID := messages[i].OfTool.ToolCallID
+		content := messages[i].OfTool.Content.OfString.Value
+		if !messages[i].OfTool.Content.OfString.Valid() || archive.referenced(toolCallID) ||
+			estimateTokens(content) < agent.referencePolicy.MinTokens {
+			continue
+		}
+		n := archive.add(toolCallID, content)
+		reference := fmt.Sprintf("[result #%d from %s: %s, ~%d tokens. Call %s with id %d for the full content.]",
+			n, toolNames[toolCallID], describeResult(content), estimateTokens(content), toolResultToolName, n)
+		messages[i] = openai.ToolMessage(reference, toolCallID)
+	}
+}
+
+// describeResult summarizes the shape of a tool result in a few words
+func describeResult(content string) string {
+	var value any
+	if err := json.Unmarshal([]byte(content), &value); err == nil {
+		switch v := value.(type) {
+		case []any:
+			return fmt.Sprintf("%d items", len(v))
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if len(keys) > 5 {
+				keys = append(keys[:5], "...")
+			}
+			return "object with keys " + strings.Join(keys, ", ")
+		}
+	}
+	return fmt.Sprintf("%d lines", strings.Count(content, "\n")+1)
+}
+
+const toolResultToolName = "get_tool_result"
+
+// toolResultTool fetches the full content of a referenced tool result
+type toolResultTool struct{}
+
+func (toolResultTool) Name() string {
+	return toolResultToolName
+}
+
+func (toolResultTool) Description() string {
+	return "Get the full content of an earlier tool result that was replaced by a reference"
+}
+
+func (toolResultTool) Parameters() Parameters {
+	return Parameters{
+		Properties: map[string]any{
+			"id": IntegerSchema("The number of the result, from its reference"),
+		},
+		Required: []string{"id"},
+	}
+}
+
+func (toolResultTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	archive, ok := ctx.Value(resultArchiveKey{}).(*resultArchive)
+	if !ok {
+		return nil, fmt.Errorf("no tool results have been referenced")
+	}
+	id, _ := input["id"].(float64)
+	content, ok := archive.get(int(id))
+	if !ok {
+		return nil, fmt.Errorf("no result #%v", input["id"])
+	}
+	return content, nil
+}
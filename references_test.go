@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolResultReferences(t *testing.T) {
+	orders := make([]map[string]any, 50)
+	for i := range orders {
+		orders[i] = map[string]any{"id": i, "status": "shipped"}
+	}
+	listOrders := MockTool{
+		name:        "list_orders",
+		description: "List orders",
+		parameters:  Parameters{Properties: map[string]any{}},
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			return orders, nil
+		},
+	}
+	full, err := json.Marshal(orders)
+	require.NoError(t, err)
+
+	type toolMessage struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	var requests [][]toolMessage
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []toolMessage `json:"messages"`
+			Tools    []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body.Messages)
+		require.Len(t, body.Tools, 2)
+		assert.Equal(t, "get_tool_result", body.Tools[0].Function.Name)
+
+		w.Header().Set("Content-Type", "application/json")
+		call := func(name string, arguments string) {
+			fmt.Fprintf(w, `{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_%d","type":"function","function":{"name":%q,"arguments":%q}}]}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`, len(requests), name, arguments)
+		}
+		switch len(requests) {
+		case 1, 2:
+			call("list_orders", "{}")
+		case 3:
+			call("get_tool_result", `{"id":1}`)
+		default:
+			writeCompletion(w, "gpt-4o", "All 50 orders shipped")
+		}
+	}, WithTools([]Tool{listOrders}), WithToolResultReferences(ReferencePolicy{KeepRecent: 1}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Did my orders ship?")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"All 50 orders shipped"}, completion.Messages)
+	require.Len(t, requests, 4)
+
+	// The first result is inline until a newer result arrives
+	assert.Equal(t, string(full), requests[1][2].Content)
+	third := requests[2]
+	require.Len(t, third, 5)
+	assert.Equal(t, fmt.Sprintf("[result #1 from list_orders: 50 items, ~%d tokens. Call get_tool_result with id 1 for the full content.]", estimateTokens(string(full))), third[2].Content)
+	assert.Equal(t, string(full), third[4].Content)
+
+	// The model can fetch the full result, and a result fetched again keeps its number
+	fourth := requests[3]
+	assert.Equal(t, string(full), fourth[6].Content)
+	assert.Contains(t, fourth[4].Content, "[result #1 from list_orders")
+}
+
+func TestDescribeResult(t *testing.T) {
+	assert.Equal(t, "3 items", describeResult(`[1,2,3]`))
+	assert.Equal(t, "object with keys a, b, c, d, e, ...", describeResult(`{"f":1,"e":1,"d":1,"c":1,"b":1,"a":1}`))
+	assert.Equal(t, "2 lines", describeResult("total 2\nfile.go"))
+}
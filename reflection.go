@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ResponseKindDraft carries an answer that was critiqued and revised with WithReflection
+const ResponseKindDraft ResponseKind = "draft"
+
+// DefaultCriticPrompt asks the model to review its answer when WithReflection has no prompt
+const DefaultCriticPrompt = "Critique your last answer. Check it for errors, omissions, unsupported claims, and " +
+	"unclear writing, and say concretely what to change."
+
+// approvedVerdict is how the critic accepts an answer as final
+const approvedVerdict = "APPROVED"
+
+// Draft is an answer that was revised after a critique
+type Draft struct {
+	// Revision counts the critiques, starting at 1
+	Revision int    `json:"revision"`
+	Content  string `json:"content"`
+	Critique string `json:"critique"`
+}
+
+// WithReflection has the agent critique its final answer with criticPrompt
+// and revise it, up to n times, before returning it. The critique is a request
+// to the same models without tools; the answer is final once the critic
+// approves it or n critiques are spent. Each replaced answer is sent as a draft
+// response, which an event bus also publishes as an EventDraft. An empty
+// criticPrompt uses DefaultCriticPrompt.
+func WithReflection(n int, criticPrompt string) AgentOption {
+	return func(a *Agent) {
+		a.reflections = n
+		a.criticPrompt = criticPrompt
+	}
+}
+
+// NewDraftResponse creates a response carrying a replaced draft
+func NewDraftResponse(draft Draft) Response {
+	return Response{
+		Kind:  ResponseKindDraft,
+		draft: draft,
+	}
+}
+
+// IsDraftResponse reports whether the response carries a replaced draft
+func (r Response) IsDraftResponse() bool {
+	return r.Kind == ResponseKindDraft
+}
+
+// Draft returns the draft of a draft response
+func (r Response) Draft() Draft {
+	if r.Kind != ResponseKindDraft {
+		return Draft{}
+	}
+	return r.draft
+}
+
+// critique asks the critic to review the answer ending params' messages, adding
+// its usage to spent. It returns the critique, or an empty string if the critic
+// approves the answer.
+func (agent *Agent) critique(
+	ctx context.Context,
+	params openai.ChatCompletionNewParams,
+	plan runPlan,
+	capture *wireCapture,
+	spent *spend,
+	responseChan chan<- Response,
+) (string, error) {
+	prompt := agent.criticPrompt
+	if prompt == "" {
+		prompt = DefaultCriticPrompt
+	}
+	prompt += fmt.Sprintf("\n\nIf the answer needs no changes, reply with only %s.", approvedVerdict)
+
+	params.Messages = append(slices.Clone(params.Messages), openai.UserMessage(prompt))
+	params.Tools = nil
+	params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{}
+	response, err := agent.createCompletion(withWireCapture(ctx, capture), params, plan)
+	for _, exchange := range capture.take() {
+		if err := send(ctx, responseChan, NewDebugResponse(exchange)); err != nil {
+			return "", err
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("critique: %w", err)
+	}
+	// Critiques are auxiliary requests, outside the tool loop's iterations, but
+	// count toward the run's budget
+	usage := convertUsage(response)
+	spent.add(agent.prices, usage)
+	if err := send(ctx, responseChan, NewUsageResponse(usage)); err != nil {
+		return "", err
+	}
+
+	critique := strings.TrimSpace(response.Choices[0].Message.Content)
+	if critique == "" || strings.HasPrefix(strings.ToUpper(critique), approvedVerdict) {
+		return "", nil
+	}
+	return critique, nil
+}
+
+// revisionMessage asks the model to revise its answer after a critique
+func revisionMessage(critique string) openai.ChatCompletionMessageParamUnion {
+	return openai.UserMessage("Revise your answer based on this critique. Reply with the complete revised answer only.\n\n" + critique)
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReflectionAgent creates an agent that answers with the next of answers
+// and critiques with the next of critiques, recording the critic requests
+func newReflectionAgent(t *testing.T, answers []string, critiques []string, opts ...AgentOption) (*Agent, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var criticPrompts []string
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+			Tools []any `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		last := body.Messages[len(body.Messages)-1].Content
+
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Contains(last, "reply with only APPROVED") {
+			assert.Empty(t, body.Tools)
+			criticPrompts = append(criticPrompts, last)
+			writeCompletion(w, "gpt-4o", critiques[0])
+			critiques = critiques[1:]
+			return
+		}
+		writeCompletion(w, "gpt-4o", answers[0])
+		answers = answers[1:]
+	}, opts...)
+	return testAgent, &criticPrompts
+}
+
+func TestReflectionRevisesUntilApproved(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+	testAgent, criticPrompts := newReflectionAgent(t,
+		[]string{"Paris", "Paris is the capital of France."},
+		[]string{"Answer in a full sentence.", "APPROVED"},
+		WithReflection(3, "Check the answer is a full sentence."),
+		WithEventBus(bus),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is the capital of France?")})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Paris is the capital of France."}, completion.Messages)
+	assert.Equal(t, []Draft{{Revision: 1, Content: "Paris", Critique: "Answer in a full sentence."}}, completion.Drafts)
+	require.Len(t, *criticPrompts, 2)
+	assert.True(t, strings.HasPrefix((*criticPrompts)[0], "Check the answer is a full sentence."))
+	// Two answers and two critiques
+	assert.Len(t, completion.Steps, 4)
+
+	var drafts []Draft
+	for event := range sub.Events() {
+		if event.Kind == EventDraft {
+			drafts = append(drafts, event.Response.Draft())
+		}
+		if event.Kind == EventRunFinished {
+			break
+		}
+	}
+	assert.Equal(t, completion.Drafts, drafts)
+}
+
+func TestReflectionLimit(t *testing.T) {
+	testAgent, criticPrompts := newReflectionAgent(t,
+		[]string{"first", "second"},
+		[]string{"Try again."},
+		WithReflection(1, ""),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("hi")})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, completion.Messages)
+	require.Len(t, completion.Drafts, 1)
+	assert.Equal(t, "first", completion.Drafts[0].Content)
+	require.Len(t, *criticPrompts, 1)
+	assert.True(t, strings.HasPrefix((*criticPrompts)[0], DefaultCriticPrompt))
+}
+
+func TestReflectionBudget(t *testing.T) {
+	testAgent, criticPrompts := newReflectionAgent(t,
+		[]string{"Paris", "Paris is the capital of France."},
+		[]string{"Answer in a full sentence.", "APPROVED"},
+		WithReflection(3, ""),
+		WithRunBudget(4, 0),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is the capital of France?")})
+
+	// The critique spends the budget, so no revision is requested
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Len(t, *criticPrompts, 1)
+	assert.Equal(t, int64(4), completion.Usage.TotalTokens)
+}
+
+func TestReflectionApprovedFirstAnswer(t *testing.T) {
+	testAgent, _ := newReflectionAgent(t, []string{"fine"}, []string{"approved."}, WithReflection(2, ""))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("hi")})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fine"}, completion.Messages)
+	assert.Empty(t, completion.Drafts)
+}
+
+func TestDraftResponseJSON(t *testing.T) {
+	draft := Draft{Revision: 2, Content: "draft", Critique: "shorter"}
+	data, err := json.Marshal(NewDraftResponse(draft))
+	require.NoError(t, err)
+
+	var decoded Response
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.True(t, decoded.IsDraftResponse())
+	assert.Equal(t, draft, decoded.Draft())
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// ToolCondition decides whether a registered tool is offered to a run, given
+// the context the run was started with and its tenant, which is empty for runs
+// without one
+type ToolCondition func(ctx context.Context, tenant string) bool
+
+// ForTenants offers a tool only to runs made on behalf of the given tenants
+func ForTenants(tenants ...string) ToolCondition {
+	return func(ctx context.Context, tenant string) bool {
+		return slices.Contains(tenants, tenant)
+	}
+}
+
+// ToolRegistry is a set of tools that can change while agents use it, for
+// per-tenant tool sets, feature-flagged tools, and replacing tools in
+// long-running services without rebuilding agents. An agent resolves the
+// registry's tools when each run starts; a run keeps the tools it started
+// with. A ToolRegistry is safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools []registeredTool
+}
+
+type registeredTool struct {
+	tool       Tool
+	conditions []ToolCondition
+}
+
+// NewToolRegistry creates a registry holding tools, offered to every run
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{}
+	for _, tool := range tools {
+		r.Register(tool)
+	}
+	return r
+}
+
+// Register adds a tool, replacing a registered tool with the same name in
+// place. With conditions, the tool is only offered to runs for which every
+// condition holds.
+func (r *ToolRegistry) Register(tool Tool, conditions ...ToolCondition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := registeredTool{tool: tool, conditions: conditions}
+	for i, registered := range r.tools {
+		if registered.tool.Name() == tool.Name() {
+			r.tools[i] = entry
+			return
+		}
+	}
+	r.tools = append(r.tools, entry)
+}
+
+// Unregister removes the tool with the given name, reporting whether it was registered
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.tools)
+	r.tools = slices.DeleteFunc(r.tools, func(registered registeredTool) bool {
+		return registered.tool.Name() == name
+	})
+	return len(r.tools) < before
+}
+
+// List returns every registered tool in registration order, regardless of conditions
+func (r *ToolRegistry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, len(r.tools))
+	for i, registered := range r.tools {
+		tools[i] = registered.tool
+	}
+	return tools
+}
+
+// Resolve returns the tools offered to a run with the given context and tenant
+func (r *ToolRegistry) Resolve(ctx context.Context, tenant string) []Tool {
+	r.mu.RLock()
+	registered := slices.Clone(r.tools)
+	r.mu.RUnlock()
+
+	// Conditions run unlocked, so they may consult the registry or block briefly
+	var tools []Tool
+	for _, entry := range registered {
+		offered := true
+		for _, condition := range entry.conditions {
+			if !condition(ctx, tenant) {
+				offered = false
+				break
+			}
+		}
+		if offered {
+			tools = append(tools, entry.tool)
+		}
+	}
+	return tools
+}
+
+// WithToolRegistry makes the agent resolve the registry's tools when each run
+// starts, in addition to those set with WithTools. A registered tool replaces
+// a WithTools tool with the same name.
+func WithToolRegistry(registry *ToolRegistry) AgentOption {
+	return func(a *Agent) {
+		a.toolRegistry = registry
+	}
+}
+
+// runTools returns the tools offered to a run: the configured tools, the
+// registry's tools for the run, and built-in tools enabled by options
+func (agent *Agent) runTools(ctx context.Context, tenant string) []Tool {
+	if agent.toolRegistry == nil {
+		return agent.allTools(ctx)
+	}
+	registered := agent.toolRegistry.Resolve(ctx, tenant)
+	tools := slices.DeleteFunc(slices.Clone(agent.allTools(ctx)), func(tool Tool) bool {
+		return slices.ContainsFunc(registered, func(r Tool) bool { return r.Name() == tool.Name() })
+	})
+	return append(tools, registered...)
+}
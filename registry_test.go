@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolNames returns the names of tools in order
+func toolNames(tools []Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name()
+	}
+	return names
+}
+
+func TestToolRegistry(t *testing.T) {
+	registry := NewToolRegistry(MockTool{name: "search"}, MockTool{name: "calculator"})
+	var beta atomic.Bool
+	registry.Register(MockTool{name: "billing"}, ForTenants("acme"))
+	registry.Register(MockTool{name: "preview"}, func(ctx context.Context, tenant string) bool { return beta.Load() })
+
+	assert.Equal(t, []string{"search", "calculator", "billing", "preview"}, toolNames(registry.List()))
+	assert.Equal(t, []string{"search", "calculator"}, toolNames(registry.Resolve(context.Background(), "")))
+	assert.Equal(t, []string{"search", "calculator", "billing"}, toolNames(registry.Resolve(context.Background(), "acme")))
+	beta.Store(true)
+	assert.Equal(t, []string{"search", "calculator", "preview"}, toolNames(registry.Resolve(context.Background(), "globex")))
+
+	// Registering a name again replaces the tool in place
+	registry.Register(MockTool{name: "search", description: "v2"})
+	assert.Equal(t, "v2", registry.List()[0].Description())
+
+	assert.True(t, registry.Unregister("calculator"))
+	assert.False(t, registry.Unregister("calculator"))
+	assert.Equal(t, []string{"search", "billing", "preview"}, toolNames(registry.List()))
+}
+
+func TestWithToolRegistry(t *testing.T) {
+	var offered []string
+	var unregister func()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+			Tools    []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		offered = nil
+		for _, tool := range body.Tools {
+			offered = append(offered, tool.Function.Name)
+		}
+		if unregister != nil && len(body.Messages) == 1 {
+			// The tool is removed while the run is using it
+			unregister()
+			writeToolCall(w, "model", "weather", `{}`)
+			return
+		}
+		writeCompletion(w, "model", "done")
+	}
+
+	registry := NewToolRegistry(MockTool{name: "lookup"})
+	registry.Register(MockTool{name: "billing"}, ForTenants("acme"))
+	testAgent := newTestAgent(t, "model", handler,
+		WithTools([]Tool{MockTool{name: "lookup", description: "static"}, MockTool{name: "clock"}}),
+		WithToolRegistry(registry),
+	)
+	run := func(opts ...RunOption) error {
+		_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, opts...)
+		return err
+	}
+
+	// The registry's lookup replaces the static one, and billing is only for acme
+	require.NoError(t, run())
+	assert.Equal(t, []string{"clock", "lookup"}, offered)
+	require.NoError(t, run(WithTenant("acme")))
+	assert.Equal(t, []string{"billing", "clock", "lookup"}, offered)
+	assert.Equal(t, []string{"clock", "lookup"}, toolNames(testAgent.Tools()))
+	assert.Equal(t, "", testAgent.Tools()[1].Description())
+
+	// Tools registered between runs are offered to the next run
+	registry.Register(MockTool{name: "weather"})
+	require.NoError(t, run())
+	assert.Equal(t, []string{"clock", "lookup", "weather"}, offered)
+
+	// A run keeps the tools it started with
+	unregister = func() { registry.Unregister("weather") }
+	require.NoError(t, run())
+	assert.Equal(t, []string{"clock", "lookup", "weather"}, offered)
+	unregister = nil
+	require.NoError(t, run())
+	assert.Equal(t, []string{"clock", "lookup"}, offered)
+}
+
+func TestUnknownToolCall(t *testing.T) {
+	testAgent := newTestAgent(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		writeToolCall(w, "model", "missing", `{}`)
+	}, WithTools([]Tool{MockTool{name: "lookup"}}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	var toolErr *ToolExecutionError
+	require.ErrorAs(t, err, &toolErr)
+	assert.Equal(t, "missing", toolErr.Tool)
+	assert.ErrorContains(t, err, "no such tool")
+}
@@ -0,0 +1,219 @@
+// Package repomap builds a compact outline of a source repository — files,
+// sizes, and exported symbols — that fits a token budget, for injecting code
+// context into coding-agent prompts.
+package repomap
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option is a functional option for configuring a Map
+type Option func(*Map)
+
+// WithTokenBudget sets the approximate maximum size of the rendered outline (default: 2000)
+func WithTokenBudget(tokens int) Option {
+	return func(m *Map) {
+		m.budget = tokens
+	}
+}
+
+// WithIgnore skips files and directories whose base name matches any of the glob patterns.
+// Hidden entries, vendor, and node_modules are always skipped.
+func WithIgnore(patterns ...string) Option {
+	return func(m *Map) {
+		m.ignore = append(m.ignore, patterns...)
+	}
+}
+
+// WithMaxFileSize skips files larger than size bytes (default: 1 MiB)
+func WithMaxFileSize(size int64) Option {
+	return func(m *Map) {
+		m.maxFileSize = size
+	}
+}
+
+// File is a source file in the outline
+type File struct {
+	Path    string
+	Size    int64
+	Lines   int
+	Symbols []string
+	modTime time.Time
+}
+
+// Map is an incrementally refreshed outline of the files under a root directory
+type Map struct {
+	root        string
+	budget      int
+	ignore      []string
+	maxFileSize int64
+
+	mu    sync.Mutex
+	files map[string]File
+}
+
+// New creates a map of the repository at root. The tree is scanned on the first Refresh or String.
+func New(root string, opts ...Option) *Map {
+	m := &Map{
+		root:        root,
+		budget:      2000,
+		maxFileSize: 1 << 20,
+		files:       map[string]File{},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Refresh rescans the tree, re-reading only files whose size or modification time
+// changed since the last scan, and returns the paths that were added, changed, or removed
+func (m *Map) Refresh() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var changed []string
+	seen := map[string]bool{}
+	err := filepath.WalkDir(m.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != m.root && m.ignored(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > m.maxFileSize {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		if existing, ok := m.files[rel]; ok && existing.Size == info.Size() && existing.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		m.files[rel] = File{
+			Path:    rel,
+			Size:    info.Size(),
+			Lines:   lineCount(src),
+			Symbols: symbols(rel, src),
+			modTime: info.ModTime(),
+		}
+		changed = append(changed, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for path := range m.files {
+		if !seen[path] {
+			delete(m.files, path)
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
+
+// Files returns the files in the map sorted by path
+func (m *Map) Files() []File {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make([]File, 0, len(m.files))
+	for _, file := range m.files {
+		files = append(files, file)
+	}
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+	return files
+}
+
+// String refreshes the map and renders it within the token budget
+func (m *Map) String() string {
+	if _, err := m.Refresh(); err != nil {
+		return fmt.Sprintf("repository map unavailable: %v", err)
+	}
+	return Render(m.Files(), m.budget)
+}
+
+// Render formats files as an outline of approximately at most budget tokens. Every
+// file is listed with its symbols while the budget allows; after that, files are
+// listed by path alone, and the remainder is summarized as a count.
+func Render(files []File, budget int) string {
+	// Approximate tokens as characters / 4
+	limit := budget * 4
+
+	var b strings.Builder
+	withSymbols := true
+	for i, file := range files {
+		// Leave room to summarize the files after this one
+		reserve := 0
+		if remaining := len(files) - i - 1; remaining > 0 {
+			reserve = len(fmt.Sprintf("... %d more files\n", remaining))
+		}
+		entry := fmt.Sprintf("%s (%d lines)\n", file.Path, file.Lines)
+		if withSymbols && len(file.Symbols) > 0 {
+			full := entry + "  " + strings.Join(file.Symbols, ", ") + "\n"
+			if b.Len()+len(full)+reserve <= limit {
+				b.WriteString(full)
+				continue
+			}
+			withSymbols = false
+		}
+		if b.Len()+len(entry)+reserve > limit {
+			fmt.Fprintf(&b, "... %d more files\n", len(files)-i)
+			break
+		}
+		b.WriteString(entry)
+	}
+	return b.String()
+}
+
+// ignored reports whether an entry with the given base name is skipped
+func (m *Map) ignored(name string) bool {
+	if strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" {
+		return true
+	}
+	for _, pattern := range m.ignore {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lineCount returns the number of lines in src, counting a final unterminated line
+func lineCount(src []byte) int {
+	lines := strings.Count(string(src), "\n")
+	if len(src) > 0 && src[len(src)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
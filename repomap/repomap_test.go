@@ -0,0 +1,109 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, root string, path string, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+	require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+}
+
+func TestSymbols(t *testing.T) {
+	tests := []struct {
+		path string
+		src  string
+		want []string
+	}{
+		{
+			path: "agent.go",
+			src:  "package agent\n\ntype Agent struct{}\ntype config struct{}\n\nconst Version = \"1\"\n\nfunc New() *Agent { return nil }\nfunc (a *Agent) Run() {}\nfunc (a *Agent) run() {}\nfunc (c config) Get() {}\nfunc helper() {}\n",
+			want: []string{"Agent", "Version", "New", "Agent.Run"},
+		},
+		{
+			path: "tool.py",
+			src:  "class Tool:\n    def run(self):\n        pass\n\ndef main():\n    pass\n\ndef _private():\n    pass\n",
+			want: []string{"Tool", "main"},
+		},
+		{
+			path: "index.ts",
+			src:  "export interface Options {}\nexport async function run() {}\nfunction internal() {}\nexport default class Agent {}\n",
+			want: []string{"Options", "run", "Agent"},
+		},
+		{path: "README.md", src: "# Title\n"},
+		{path: "broken.go", src: "package broken\nfunc {"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, symbols(tt.path, []byte(tt.src)))
+		})
+	}
+}
+
+func TestMapRefresh(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "main.go", "package main\n\nfunc Main() {}\n")
+	writeFile(t, root, "pkg/util.go", "package pkg\n\nfunc Helper() {}\n")
+	writeFile(t, root, ".git/config", "ignored")
+	writeFile(t, root, "node_modules/lib/index.js", "export function ignored() {}\n")
+	writeFile(t, root, "build/out.js", "export function generated() {}\n")
+
+	m := New(root, WithIgnore("build"))
+
+	changed, err := m.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "pkg/util.go"}, changed)
+
+	changed, err = m.Refresh()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// Only the modified and removed files are reported on the next refresh
+	writeFile(t, root, "pkg/util.go", "package pkg\n\nfunc Helper() {}\nfunc Other() {}\n")
+	require.NoError(t, os.Chtimes(filepath.Join(root, "pkg/util.go"), time.Now(), time.Now().Add(time.Second)))
+	require.NoError(t, os.Remove(filepath.Join(root, "main.go")))
+
+	changed, err = m.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "pkg/util.go"}, changed)
+
+	files := m.Files()
+	require.Len(t, files, 1)
+	assert.Equal(t, []string{"Helper", "Other"}, files[0].Symbols)
+	assert.Equal(t, 4, files[0].Lines)
+}
+
+func TestRender(t *testing.T) {
+	files := []File{
+		{Path: "a.go", Lines: 10, Symbols: []string{"Alpha", "Beta"}},
+		{Path: "b.go", Lines: 20, Symbols: []string{"Gamma"}},
+		{Path: "c.go", Lines: 30, Symbols: []string{"Delta"}},
+		{Path: "d.go", Lines: 40},
+	}
+
+	assert.Equal(t, "a.go (10 lines)\n  Alpha, Beta\nb.go (20 lines)\n  Gamma\nc.go (30 lines)\n  Delta\nd.go (40 lines)\n", Render(files, 1000))
+
+	// A small budget drops symbols first, then files
+	small := Render(files, 16)
+	assert.LessOrEqual(t, len(small), 16*4)
+	assert.True(t, strings.HasPrefix(small, "a.go (10 lines)\n  Alpha, Beta\nb.go (20 lines)\n"))
+	assert.NotContains(t, small, "Gamma")
+	assert.True(t, strings.HasSuffix(small, "more files\n"))
+}
+
+func TestMapString(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "main.go", "package main\n\nfunc Main() {}\n")
+
+	assert.Equal(t, "main.go (3 lines)\n  Main\n", New(root).String())
+}
@@ -0,0 +1,103 @@
+package repomap
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// symbolPatterns extract top-level declarations from languages without a Go parser
+var symbolPatterns = map[string]*regexp.Regexp{
+	".py": regexp.MustCompile(`(?m)^(?:async\s+)?(?:def|class)\s+([A-Za-z]\w*)`),
+	".js": regexp.MustCompile(`(?m)^export\s+(?:default\s+)?(?:async\s+)?(?:function\*?|class|const|let|var)\s+([A-Za-z_$][\w$]*)`),
+	".ts": regexp.MustCompile(`(?m)^export\s+(?:default\s+)?(?:abstract\s+)?(?:async\s+)?(?:function\*?|class|const|let|var|interface|type|enum)\s+([A-Za-z_$][\w$]*)`),
+	".rs": regexp.MustCompile(`(?m)^pub\s+(?:async\s+)?(?:fn|struct|enum|trait|type|const|mod)\s+([A-Za-z_]\w*)`),
+}
+
+func init() {
+	symbolPatterns[".jsx"] = symbolPatterns[".js"]
+	symbolPatterns[".mjs"] = symbolPatterns[".js"]
+	symbolPatterns[".tsx"] = symbolPatterns[".ts"]
+}
+
+// symbols returns the exported declarations of a source file
+func symbols(path string, src []byte) []string {
+	ext := filepath.Ext(path)
+	if ext == ".go" {
+		return goSymbols(path, src)
+	}
+	pattern, ok := symbolPatterns[ext]
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, match := range pattern.FindAllSubmatch(src, -1) {
+		name := string(match[1])
+		// Python has no export keyword; leading underscores mark private names
+		if ext == ".py" && strings.HasPrefix(name, "_") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// goSymbols returns the exported functions, methods, types, and values of a Go file
+func goSymbols(path string, src []byte) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				receiver := receiverName(decl.Recv.List[0].Type)
+				if receiver == "" || !ast.IsExported(receiver) {
+					continue
+				}
+				names = append(names, receiver+"."+decl.Name.Name)
+				continue
+			}
+			names = append(names, decl.Name.Name)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						names = append(names, spec.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							names = append(names, name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/openai/openai-go/option"
+)
+
+// RunOption is a functional option for configuring a single run
+type RunOption func(*runOptions)
+
+type runOptions struct {
+	runID      string
+	parentID   string
+	killSwitch *KillSwitch
+	controller *RunController
+	tenant     string
+	toolChoice *ToolChoice
+	// toolGroups, when set, and withoutToolGroups restrict the run's grouped tools
+	toolGroups        []string
+	withoutToolGroups []string
+	toolPolicies      []ToolPolicy
+	// requestOptions are applied to the run's provider requests
+	requestOptions []option.RequestOption
+	memorySubject  string
+}
+
+// WithRunID sets a client-supplied ID for the run. When the agent has a RunStore
+// and a run with the same ID already completed, its stored result is returned.
+func WithRunID(id string) RunOption {
+	return func(o *runOptions) {
+		o.runID = id
+	}
+}
+
+// WithParentRunID sets the run that started this one, for runs started on
+// another service. Runs started within a run, such as by a tool or a handoff,
+// get their parent from the context.
+func WithParentRunID(id string) RunOption {
+	return func(o *runOptions) {
+		o.parentID = id
+	}
+}
+
+func newRunOptions(opts []RunOption) runOptions {
+	var options runOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// send delivers a response unless the run is canceled first, so a run never
+// blocks on a consumer that stopped reading after cancellation
+func send(ctx context.Context, responseChan chan<- Response, response Response) error {
+	select {
+	case responseChan <- response:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// RunInfo identifies a run, for correlating its responses, events, logs, and
+// tool calls across services. Every run has one: its ID is the one given with
+// WithRunID or a generated one.
+type RunInfo struct {
+	ID string
+	// ParentID is the run that started this one, or empty
+	ParentID string
+	// Iteration is the tool loop iteration in progress, or zero before the first request
+	Iteration int
+	StartedAt time.Time
+}
+
+type runInfoKey struct{}
+
+// RunInfoFromContext returns the run a context belongs to, such as the context
+// a tool is executed with
+func RunInfoFromContext(ctx context.Context) (RunInfo, bool) {
+	info, ok := ctx.Value(runInfoKey{}).(RunInfo)
+	return info, ok
+}
+
+func withRunInfo(ctx context.Context, info RunInfo) context.Context {
+	return context.WithValue(ctx, runInfoKey{}, info)
+}
+
+// newRunInfo identifies a new run. A run started within another run is its child.
+func newRunInfo(ctx context.Context, options runOptions) RunInfo {
+	info := RunInfo{ID: options.runID, ParentID: options.parentID, StartedAt: time.Now()}
+	if info.ID == "" {
+		b := make([]byte, 12)
+		rand.Read(b)
+		info.ID = "run_" + hex.EncodeToString(b)
+	}
+	if parent, ok := RunInfoFromContext(ctx); ok && info.ParentID == "" {
+		info.ParentID = parent.ID
+	}
+	return info
+}
+
+// stampResponses passes a run's responses through, marking each with the run,
+// its iteration, and the time. The iteration is followed from the run's usage
+// responses, which start each iteration. Responses forwarded from other runs,
+// such as a handoff's, keep their marks.
+func stampResponses(info RunInfo, in <-chan Response) <-chan Response {
+	out := make(chan Response)
+	go func() {
+		defer close(out)
+		iteration := 0
+		for response := range in {
+			if response.RunID == "" {
+				if response.IsUsageResponse() && response.Usage().Iteration > 0 {
+					iteration = response.Usage().Iteration
+				}
+				response.RunID = info.ID
+				response.ParentRunID = info.ParentID
+				response.Iteration = iteration
+				response.Time = time.Now()
+			}
+			out <- response
+		}
+	}()
+	return out
+}
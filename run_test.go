@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInfo(t *testing.T) {
+	child := newTestAgent(t, "child", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "child", "child answer")
+	})
+
+	var toolRun RunInfo
+	var childCompletion Completion
+	tool := MockTool{name: "ask_child", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		toolRun, _ = RunInfoFromContext(ctx)
+		var err error
+		childCompletion, err = child.ChatCompletion(ctx, []Message{UserTextMessage("help")})
+		return childCompletion.Messages[0], err
+	}}
+	var calls atomic.Int32
+	parent := newTestAgent(t, "parent", func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			writeToolCall(w, "parent", "ask_child", `{}`)
+			return
+		}
+		writeCompletion(w, "parent", "done")
+	}, WithTools([]Tool{tool}))
+
+	completion, err := parent.ChatCompletion(context.Background(), []Message{UserTextMessage("hi")})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(completion.RunID, "run_"))
+	assert.Empty(t, completion.ParentRunID)
+	for _, response := range completion.Responses {
+		assert.Equal(t, completion.RunID, response.RunID)
+		assert.False(t, response.Time.IsZero())
+	}
+	var iterations []int
+	for _, response := range completion.Responses {
+		iterations = append(iterations, response.Iteration)
+	}
+	assert.Equal(t, []int{1, 1, 2, 2}, iterations, "usage and tool call of the first request, usage and content of the second")
+
+	assert.Equal(t, completion.RunID, toolRun.ID)
+	assert.Equal(t, 1, toolRun.Iteration)
+	assert.Equal(t, completion.RunID, childCompletion.ParentRunID, "runs started by a tool are its run's children")
+	assert.NotEqual(t, completion.RunID, childCompletion.RunID)
+
+	completion, err = child.ChatCompletion(context.Background(), []Message{UserTextMessage("hi")}, WithRunID("job-7"), WithParentRunID("remote-3"))
+	require.NoError(t, err)
+	assert.Equal(t, "job-7", completion.RunID)
+	assert.Equal(t, "remote-3", completion.ParentRunID)
+	assert.Equal(t, "remote-3", completion.Responses[0].ParentRunID)
+}
+
+func TestRunInfoLogsAndJSON(t *testing.T) {
+	var logs bytes.Buffer
+	a := newTestAgent(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "model", "hello")
+	}, WithLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	completion, err := a.ChatCompletion(context.Background(), []Message{UserTextMessage("hi")}, WithRunID("job-7"))
+	require.NoError(t, err)
+
+	var iterationLogged bool
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "job-7", record["run_id"], record["msg"])
+		if record["msg"] == "agent iteration" {
+			iterationLogged = assert.Equal(t, float64(1), record["iteration"])
+		}
+	}
+	assert.True(t, iterationLogged)
+
+	data, err := json.Marshal(completion.Responses[1])
+	require.NoError(t, err)
+	var decoded Response
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "job-7", decoded.RunID)
+	assert.Equal(t, 1, decoded.Iteration)
+	assert.True(t, completion.Responses[1].Time.Equal(decoded.Time))
+}
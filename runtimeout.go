@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRunTimeout is matched by every RunTimeoutError via errors.Is
+var ErrRunTimeout = errors.New("run timed out")
+
+// RunTimeoutError is the terminal error of a run that reached its time limit.
+// The completion collected up to that point is returned with it.
+type RunTimeoutError struct {
+	// Limit is the WithRunTimeout or WithMaxRunDuration limit that was reached
+	Limit time.Duration
+	// Interrupted is set when WithRunTimeout cut off a request or tool call in
+	// progress, rather than WithMaxRunDuration stopping between iterations
+	Interrupted bool
+}
+
+func (e *RunTimeoutError) Error() string {
+	if e.Interrupted {
+		return fmt.Sprintf("run timed out after %s", e.Limit)
+	}
+	return fmt.Sprintf("run reached its maximum duration of %s", e.Limit)
+}
+
+func (e *RunTimeoutError) Is(target error) bool {
+	return target == ErrRunTimeout
+}
+
+// WithRunTimeout bounds each run, including its tool calls, to d. A run still
+// going at the timeout is interrupted, even mid-request, and fails with a
+// *RunTimeoutError and the TerminationTimeout reason, keeping the content
+// returned so far. Unlike a deadline on the caller's context, the timeout is
+// reported as such rather than as a cancellation.
+func WithRunTimeout(d time.Duration) AgentOption {
+	return func(a *Agent) {
+		a.runTimeout = d
+	}
+}
+
+// WithMaxRunDuration stops each run that has run for d before it starts another
+// iteration, letting the request or tool call in progress finish. The run
+// fails with a *RunTimeoutError and the TerminationTimeout reason, keeping the
+// content returned so far. Combine it with a longer WithRunTimeout to also
+// bound a single slow iteration.
+func WithMaxRunDuration(d time.Duration) AgentOption {
+	return func(a *Agent) {
+		a.maxRunDuration = d
+	}
+}
+
+// withRunTimeout applies the run timeout to a run's context
+func (agent *Agent) withRunTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if agent.runTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, agent.runTimeout, &RunTimeoutError{Limit: agent.runTimeout, Interrupted: true})
+}
+
+// checkRunDuration returns a *RunTimeoutError once a run has reached its maximum duration
+func (agent *Agent) checkRunDuration(run RunInfo) error {
+	if agent.maxRunDuration > 0 && time.Since(run.StartedAt) >= agent.maxRunDuration {
+		return &RunTimeoutError{Limit: agent.maxRunDuration}
+	}
+	return nil
+}
+
+// runTimedOut returns the run's *RunTimeoutError if its context timed out
+func runTimedOut(ctx context.Context) error {
+	if err := context.Cause(ctx); errors.Is(err, ErrRunTimeout) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTimeoutInterruptsTool(t *testing.T) {
+	var requests atomic.Int32
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			writeToolCall(w, "gpt-4o", "slow", `{}`)
+			return
+		}
+		writeCompletion(w, "gpt-4o", "done")
+	}, WithRunTimeout(50*time.Millisecond), WithTools([]Tool{MockTool{name: "slow", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}}))
+
+	start := time.Now()
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("go")})
+
+	var timeoutErr *RunTimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "%v", err)
+	assert.True(t, timeoutErr.Interrupted)
+	assert.ErrorIs(t, err, ErrRunTimeout)
+	assert.Equal(t, TerminationTimeout, completion.TerminationReason)
+	assert.Len(t, completion.Steps, 1)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestMaxRunDurationStopsBetweenIterations(t *testing.T) {
+	var requests atomic.Int32
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		writeToolCall(w, "gpt-4o", "step", `{}`)
+	}, WithMaxRunDuration(30*time.Millisecond), WithTools([]Tool{MockTool{name: "step", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		// The tool call in progress is not interrupted
+		time.Sleep(20 * time.Millisecond)
+		return "ok", ctx.Err()
+	}}}))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("go")})
+
+	var timeoutErr *RunTimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "%v", err)
+	assert.False(t, timeoutErr.Interrupted)
+	assert.Equal(t, 30*time.Millisecond, timeoutErr.Limit)
+	assert.Equal(t, TerminationTimeout, completion.TerminationReason)
+	assert.Equal(t, int32(2), requests.Load())
+	// Every started tool call finished
+	require.Len(t, completion.ToolCalls, 2)
+	assert.Equal(t, "ok", completion.ToolCalls[1].Result)
+}
+
+func TestRunTimeoutNotReached(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "done")
+	}, WithRunTimeout(time.Minute), WithMaxRunDuration(time.Minute))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("go")})
+
+	require.NoError(t, err)
+	assert.Equal(t, TerminationCompleted, completion.TerminationReason)
+	assert.Equal(t, []string{"done"}, completion.Messages)
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrSecretNotFound is returned by a SecretProvider that has no secret with the requested name
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretProvider resolves credentials by name when a tool needs them
+type SecretProvider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// WithSecrets makes a provider available to tools through Secret. Resolved values
+// are masked in tool results before they are sent to the model.
+func WithSecrets(provider SecretProvider) AgentOption {
+	return func(a *Agent) {
+		a.secrets = provider
+	}
+}
+
+// Secret resolves a credential from the provider of the agent executing the tool.
+// Tools call it from Execute instead of holding secrets in their fields.
+func Secret(ctx context.Context, name string) (string, error) {
+	scope, ok := ctx.Value(secretsKey{}).(*secretScope)
+	if !ok {
+		return "", fmt.Errorf("secret %q: no secret provider configured", name)
+	}
+	value, err := scope.provider.Secret(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("secret %q: %w", name, err)
+	}
+	scope.remember(value)
+	return value, nil
+}
+
+type secretsKey struct{}
+
+// secretScope records the secrets resolved during a run so they can be masked
+type secretScope struct {
+	provider SecretProvider
+	mu       sync.Mutex
+	values   []string
+}
+
+// withSecrets returns a context from which tools can resolve secrets
+func (agent *Agent) withSecrets(ctx context.Context) context.Context {
+	if agent.secrets == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, secretsKey{}, &secretScope{provider: agent.secrets})
+}
+
+func (s *secretScope) remember(value string) {
+	if value == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.values {
+		if v == value {
+			return
+		}
+	}
+	s.values = append(s.values, value)
+}
+
+// redactResolvedSecrets masks every secret resolved in ctx that appears in content
+func redactResolvedSecrets(ctx context.Context, content string) string {
+	scope, ok := ctx.Value(secretsKey{}).(*secretScope)
+	if !ok {
+		return content
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	for _, value := range scope.values {
+		content = strings.ReplaceAll(content, value, "[REDACTED]")
+	}
+	return content
+}
+
+// EnvSecrets resolves secrets from environment variables named prefix + name
+type EnvSecrets struct {
+	Prefix string
+}
+
+func (p EnvSecrets) Secret(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(p.Prefix + name)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// FileSecrets resolves secrets from files named after the secret in Dir, such as
+// Kubernetes or Docker secret mounts. Trailing newlines are removed.
+type FileSecrets struct {
+	Dir string
+}
+
+func (p FileSecrets) Secret(ctx context.Context, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// ChainSecrets resolves a secret from the first provider that has it
+type ChainSecrets []SecretProvider
+
+func (c ChainSecrets) Secret(ctx context.Context, name string) (string, error) {
+	for _, provider := range c {
+		value, err := provider.Secret(ctx, name)
+		if !errors.Is(err, ErrSecretNotFound) {
+			return value, err
+		}
+	}
+	return "", ErrSecretNotFound
+}
@@ -0,0 +1,103 @@
+// Package vault resolves agent secrets from a HashiCorp Vault KV version 2 secrets engine.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Provider is an agent.SecretProvider backed by Vault. Secret names have the form
+// "path#field", such as "github/bot#token"; without a field, "value" is read.
+type Provider struct {
+	addr   string
+	token  string
+	mount  string
+	client *http.Client
+}
+
+var _ agent.SecretProvider = (*Provider)(nil)
+
+// Option is a functional option for configuring the provider
+type Option func(*Provider)
+
+// WithMount sets the mount path of the KV engine (default: "secret")
+func WithMount(mount string) Option {
+	return func(p *Provider) {
+		p.mount = strings.Trim(mount, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to reach Vault
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// New creates a provider for the Vault server at addr authenticated with token
+func New(addr string, token string, opts ...Option) *Provider {
+	p := &Provider{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  "secret",
+		client: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Provider) Secret(ctx context.Context, name string) (string, error) {
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		field = "value"
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+p.mount+"/data/"+strings.Join(segments, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", agent.ErrSecretNotFound
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	value, ok := body.Data.Data[field].(string)
+	if !ok {
+		return "", agent.ErrSecretNotFound
+	}
+	return value, nil
+}
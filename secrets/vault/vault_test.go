@@ -0,0 +1,50 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/github/bot":
+			fmt.Fprint(w, `{"data":{"data":{"token":"ghp_abc","value":"default"},"metadata":{"version":3}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider := New(server.URL, "root", WithMount("/kv/"))
+	ctx := context.Background()
+
+	value, err := provider.Secret(ctx, "github/bot#token")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_abc", value)
+
+	value, err = provider.Secret(ctx, "github/bot")
+	require.NoError(t, err)
+	assert.Equal(t, "default", value)
+
+	_, err = provider.Secret(ctx, "github/bot#missing")
+	assert.ErrorIs(t, err, agent.ErrSecretNotFound)
+
+	_, err = provider.Secret(ctx, "slack/bot#token")
+	assert.ErrorIs(t, err, agent.ErrSecretNotFound)
+
+	_, err = New(server.URL, "wrong", WithMount("kv")).Secret(ctx, "github/bot#token")
+	assert.ErrorContains(t, err, "403")
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretProviders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("AGENT_SECRET_API_TOKEN", "from-env")
+	value, err := EnvSecrets{Prefix: "AGENT_SECRET_"}.Secret(ctx, "API_TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+	_, err = EnvSecrets{Prefix: "AGENT_SECRET_"}.Secret(ctx, "MISSING")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db_password"), []byte("from-file\n"), 0o600))
+	files := FileSecrets{Dir: dir}
+	value, err = files.Secret(ctx, "db_password")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+	_, err = files.Secret(ctx, "missing")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	_, err = files.Secret(ctx, "../db_password")
+	assert.ErrorContains(t, err, "invalid secret name")
+
+	chain := ChainSecrets{EnvSecrets{Prefix: "AGENT_SECRET_"}, files}
+	value, err = chain.Secret(ctx, "db_password")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+	_, err = chain.Secret(ctx, "missing")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestSecretInjection(t *testing.T) {
+	t.Setenv("TEST_API_TOKEN", "s3cr3t-value")
+
+	var resolved string
+	tool := MockTool{
+		name:       "call_api",
+		parameters: Parameters{Properties: map[string]any{}},
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			token, err := Secret(ctx, "API_TOKEN")
+			if err != nil {
+				return nil, err
+			}
+			resolved = token
+			return map[string]any{"echo": "authorized with " + token}, nil
+		},
+	}
+
+	var toolResult string
+	requests := 0
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			writeToolCall(w, "gpt-4o", "call_api", `{}`)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		toolResult = string(body)
+		writeCompletion(w, "gpt-4o", "done")
+	}, WithTools([]Tool{tool}), WithSecrets(EnvSecrets{Prefix: "TEST_"}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Call the API")})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-value", resolved)
+
+	// The resolved secret never reaches the model
+	assert.NotContains(t, toolResult, "s3cr3t-value")
+	assert.Contains(t, toolResult, "authorized with [REDACTED]")
+}
+
+func TestSecretWithoutProvider(t *testing.T) {
+	_, err := Secret(context.Background(), "API_TOKEN")
+	assert.ErrorContains(t, err, "no secret provider configured")
+}
@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheHit describes a run served from a SemanticCache
+type CacheHit struct {
+	// Prompt is the text of the conversation that was served
+	Prompt string
+	// CachedPrompt is the text of the earlier conversation whose reply was reused
+	CachedPrompt string
+	// Score is the cosine similarity of the two prompts
+	Score float32
+}
+
+// SemanticCacheOption is a functional option for configuring a SemanticCache
+type SemanticCacheOption func(*SemanticCache)
+
+// WithSimilarityThreshold sets the minimum cosine similarity for a cached reply
+// to be reused. The default is 0.95.
+func WithSimilarityThreshold(threshold float32) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.threshold = threshold
+	}
+}
+
+// WithCacheTTL expires cached replies after ttl. By default they do not expire.
+func WithCacheTTL(ttl time.Duration) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheHitHandler calls fn each time a run is served from the cache
+func WithCacheHitHandler(fn func(ctx context.Context, hit CacheHit)) SemanticCacheOption {
+	return func(c *SemanticCache) {
+		c.onHit = fn
+	}
+}
+
+// SemanticCache reuses replies for conversations similar to earlier ones. The
+// text of each conversation is embedded and stored with the reply in a
+// VectorStore. It is safe for concurrent use.
+type SemanticCache struct {
+	embedder  TextEmbedder
+	store     VectorStore
+	threshold float32
+	ttl       time.Duration
+	onHit     func(ctx context.Context, hit CacheHit)
+	now       func() time.Time
+
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+// NewSemanticCache creates a cache that embeds prompts with embedder and stores
+// replies in store
+func NewSemanticCache(embedder TextEmbedder, store VectorStore, opts ...SemanticCacheOption) *SemanticCache {
+	c := &SemanticCache{
+		embedder:  embedder,
+		store:     store,
+		threshold: 0.95,
+		now:       time.Now,
+		ids:       map[string]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithSemanticCache serves runs from cache when their conversation is similar
+// to an earlier successful run of an agent with the same model and prompts.
+// Only conversations of text messages are cached.
+func WithSemanticCache(cache *SemanticCache) AgentOption {
+	return func(a *Agent) {
+		a.semanticCache = cache
+	}
+}
+
+// Invalidate removes cached replies for prompts similar to prompt
+func (c *SemanticCache) Invalidate(ctx context.Context, prompt string) error {
+	embedding, err := c.embed(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	matches, err := c.store.Query(ctx, embedding, 100)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, match := range matches {
+		if match.Score >= c.threshold {
+			ids = append(ids, match.ID)
+		}
+	}
+	return c.delete(ctx, ids)
+}
+
+// Clear removes every reply stored by this cache
+func (c *SemanticCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	ids := make([]string, 0, len(c.ids))
+	for id := range c.ids {
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+	return c.delete(ctx, ids)
+}
+
+func (c *SemanticCache) delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := c.store.Delete(ctx, ids); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		delete(c.ids, id)
+	}
+	return nil
+}
+
+func (c *SemanticCache) embed(ctx context.Context, prompt string) ([]float32, error) {
+	embeddings, err := c.embedder.Embed(ctx, []string{prompt})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(embeddings))
+	}
+	return embeddings[0], nil
+}
+
+// lookup returns the cached reply for the most similar prompt in scope, and the
+// prompt's embedding for storing a new reply on a miss
+func (c *SemanticCache) lookup(ctx context.Context, scope string, prompt string) ([]string, []float32, error) {
+	embedding, err := c.embed(ctx, prompt)
+	if err != nil {
+		return nil, nil, err
+	}
+	matches, err := c.store.Query(ctx, embedding, 10)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, match := range matches {
+		if match.Score < c.threshold {
+			break
+		}
+		if match.Metadata["scope"] != scope || c.expired(match.Metadata) {
+			continue
+		}
+		if c.onHit != nil {
+			c.onHit(ctx, CacheHit{Prompt: prompt, CachedPrompt: match.Text, Score: match.Score})
+		}
+		return metadataStrings(match.Metadata["messages"]), nil, nil
+	}
+	return nil, embedding, nil
+}
+
+// put stores the reply to a prompt
+func (c *SemanticCache) put(ctx context.Context, scope string, prompt string, embedding []float32, messages []string) error {
+	sum := sha256.Sum256([]byte(scope + "\n" + prompt))
+	id := "semcache-" + hex.EncodeToString(sum[:16])
+	err := c.store.Upsert(ctx, []Document{{
+		ID:   id,
+		Text: prompt,
+		Metadata: map[string]any{
+			"scope":     scope,
+			"messages":  messages,
+			"cached_at": c.now().UTC().Format(time.RFC3339Nano),
+		},
+		Embedding: embedding,
+	}})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[id] = struct{}{}
+	return nil
+}
+
+func (c *SemanticCache) expired(metadata map[string]any) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+	cachedAt, _ := metadata["cached_at"].(string)
+	t, err := time.Parse(time.RFC3339Nano, cachedAt)
+	return err != nil || c.now().Sub(t) > c.ttl
+}
+
+// metadataStrings reads a list of strings from metadata, which stores that
+// round-trip through JSON return as []any
+func metadataStrings(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// cachePrompt returns the text of a conversation, or false if it has non-text
+// messages and cannot be cached
+func cachePrompt(messages []Message) (string, bool) {
+	var b strings.Builder
+	for _, msg := range messages {
+		if !msg.IsText() || len(msg.ToolCalls()) > 0 || msg.Role() == RoleTool {
+			return "", false
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(string(msg.Role()) + ": " + msg.Text())
+	}
+	return b.String(), b.Len() > 0
+}
+
+// cacheScope identifies the agent configuration a reply is valid for
+func (agent *Agent) cacheScope(prompts runPrompts) string {
+	sum := sha256.Sum256([]byte(agent.model + "\x00" + prompts.system + "\x00" + prompts.instructions))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cacheRun returns a channel replaying a cached reply for the conversation. On a
+// miss it returns a function that wraps the run's responses and stores the reply
+// once the run succeeds, before the channel closes. Cache failures are logged
+// and never fail the run.
+func (agent *Agent) cacheRun(ctx context.Context, prompts runPrompts, messages []Message) (<-chan Response, func(<-chan Response) <-chan Response) {
+	passthrough := func(responses <-chan Response) <-chan Response { return responses }
+	prompt, ok := cachePrompt(messages)
+	if agent.semanticCache == nil || !ok {
+		return nil, passthrough
+	}
+
+	scope := agent.cacheScope(prompts)
+	cached, embedding, err := agent.semanticCache.lookup(ctx, scope, prompt)
+	if err != nil {
+		agent.logger.WarnContext(ctx, "semantic cache lookup failed", "error", err)
+		return nil, passthrough
+	}
+	if cached != nil {
+		agent.logger.InfoContext(ctx, "semantic cache hit")
+		responseChan := make(chan Response, len(cached))
+		for _, content := range cached {
+			responseChan <- NewContentResponse(content)
+		}
+		close(responseChan)
+		return responseChan, nil
+	}
+
+	return nil, func(responses <-chan Response) <-chan Response {
+		recorded := make(chan Response)
+		go func() {
+			defer close(recorded)
+			var contents []string
+			failed := false
+			for response := range responses {
+				switch {
+				case response.IsErrorResponse(), response.IsDegraded():
+					failed = true
+				case response.IsContentResponse():
+					contents = append(contents, response.Content())
+				}
+				recorded <- response
+			}
+			if !failed && len(contents) > 0 {
+				if err := agent.semanticCache.put(context.WithoutCancel(ctx), scope, prompt, embedding, contents); err != nil {
+					agent.logger.WarnContext(ctx, "semantic cache store failed", "error", err)
+				}
+			}
+		}()
+		return recorded
+	}
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// topicEmbedder embeds texts by the topics they mention, so rephrasings of a
+// question about the same topic are near-duplicates
+type topicEmbedder struct{}
+
+func (topicEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		text = strings.ToLower(text)
+		embedding := []float32{0.01, 0.01, 0.01}
+		for j, topic := range []string{"refund", "shipping", "hours"} {
+			if strings.Contains(text, topic) {
+				embedding[j] = 1
+			}
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+func TestSemanticCache(t *testing.T) {
+	requests := 0
+	var hits []CacheHit
+	cache := NewSemanticCache(topicEmbedder{}, NewMemoryVectorStore(), WithCacheHitHandler(func(ctx context.Context, hit CacheHit) {
+		hits = append(hits, hit)
+	}))
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeCompletion(w, "gpt-4o", "Refunds take 5 days")
+	}
+	testAgent := newTestAgent(t, "gpt-4o", handler, WithSemanticCache(cache))
+
+	ask := func(a *Agent, question string) Completion {
+		completion, err := a.ChatCompletion(context.Background(), []Message{UserTextMessage(question)})
+		require.NoError(t, err)
+		return completion
+	}
+
+	first := ask(testAgent, "How long does a refund take?")
+	assert.Equal(t, []string{"Refunds take 5 days"}, first.Messages)
+	assert.Equal(t, 1, requests)
+
+	// A rephrased question is served from the cache without a request
+	second := ask(testAgent, "When will my refund arrive?")
+	assert.Equal(t, []string{"Refunds take 5 days"}, second.Messages)
+	assert.Equal(t, 1, requests)
+	assert.Zero(t, second.Usage.TotalTokens)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "user: When will my refund arrive?", hits[0].Prompt)
+	assert.Equal(t, "user: How long does a refund take?", hits[0].CachedPrompt)
+	assert.InDelta(t, 1, hits[0].Score, 0.01)
+
+	// Unrelated questions and agents with other prompts miss
+	ask(testAgent, "What are your opening hours?")
+	assert.Equal(t, 2, requests)
+	ask(newTestAgent(t, "gpt-4o", handler, WithSemanticCache(cache), WithSystemPrompt("Answer in French")), "How long does a refund take?")
+	assert.Equal(t, 3, requests)
+
+	// Invalidated replies are fetched again
+	require.NoError(t, cache.Invalidate(context.Background(), "refund"))
+	ask(testAgent, "How long does a refund take?")
+	assert.Equal(t, 4, requests)
+
+	require.NoError(t, cache.Clear(context.Background()))
+	ask(testAgent, "What are your opening hours?")
+	assert.Equal(t, 5, requests)
+}
+
+func TestSemanticCacheExpiry(t *testing.T) {
+	requests := 0
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewSemanticCache(topicEmbedder{}, NewMemoryVectorStore(), WithCacheTTL(time.Hour))
+	cache.now = func() time.Time { return now }
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeCompletion(w, "gpt-4o", "We ship in 2 days")
+	}, WithSemanticCache(cache))
+
+	for range 2 {
+		_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Shipping time?")})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, requests)
+
+	now = now.Add(2 * time.Hour)
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Shipping time?")})
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+func TestSemanticCacheSkipsFailures(t *testing.T) {
+	requests := 0
+	cache := NewSemanticCache(topicEmbedder{}, NewMemoryVectorStore())
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"bad request"}}`))
+			return
+		}
+		writeCompletion(w, "gpt-4o", "Refunds take 5 days")
+	}, WithSemanticCache(cache))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Refund?")})
+	require.Error(t, err)
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Refund?")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Refunds take 5 days"}, completion.Messages)
+	assert.Equal(t, 2, requests)
+
+	// Conversations with attachments are never cached
+	_, err = testAgent.ChatCompletion(context.Background(), []Message{UserImageMessage(Image{Data: []byte("png"), Name: "refund.png"})})
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamChatCompletionSeq runs the conversation and returns its responses as an
+// iterator for use with range. An error response is yielded with its error, and
+// a run that fails to start yields a single zero Response with the error.
+// Breaking out of the loop cancels the run and waits for it to stop.
+//
+//	for response, err := range agent.StreamChatCompletionSeq(ctx, messages) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(response.Content())
+//	}
+func (agent *Agent) StreamChatCompletionSeq(
+	ctx context.Context,
+	messages []Message,
+	opts ...RunOption,
+) iter.Seq2[Response, error] {
+	return func(yield func(Response, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		responseChan, err := agent.StreamChatCompletion(ctx, messages, opts...)
+		if err != nil {
+			yield(Response{}, err)
+			return
+		}
+
+		for response := range responseChan {
+			var err error
+			if response.IsErrorResponse() {
+				err = response.Error()
+			}
+			if !yield(response, err) {
+				cancel()
+				// Drain so the run finishes and closes the channel
+				for range responseChan {
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamChatCompletionSeq(t *testing.T) {
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "gpt-4o", "Hello")
+	})
+
+	var contents []string
+	var usage Usage
+	for response, err := range testAgent.StreamChatCompletionSeq(context.Background(), []Message{UserTextMessage("Hi")}) {
+		require.NoError(t, err)
+		if response.IsUsageResponse() {
+			usage = usage.Add(response.Usage())
+		}
+		if response.IsContentResponse() {
+			contents = append(contents, response.Content())
+		}
+	}
+	assert.Equal(t, []string{"Hello"}, contents)
+	assert.Equal(t, int64(2), usage.TotalTokens)
+
+	// A run that cannot start yields its error once
+	limited := newTestAgent(t, "gpt-4o", nil, WithLimits(Limits{MaxMessages: 1}))
+	var errs []error
+	for _, err := range limited.StreamChatCompletionSeq(context.Background(), []Message{UserTextMessage("a"), UserTextMessage("b")}) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	var limitErr *LimitError
+	assert.ErrorAs(t, errs[0], &limitErr)
+}
+
+func TestStreamChatCompletionSeqBreak(t *testing.T) {
+	toolCanceled := make(chan struct{})
+	slowTool := MockTool{
+		name:       "slow",
+		parameters: Parameters{Properties: map[string]any{}},
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			<-ctx.Done()
+			close(toolCanceled)
+			return nil, ctx.Err()
+		},
+	}
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		writeToolCall(w, "gpt-4o", "slow", "{}")
+	}, WithTools([]Tool{slowTool}))
+
+	for response, err := range testAgent.StreamChatCompletionSeq(context.Background(), []Message{UserTextMessage("Hi")}) {
+		require.NoError(t, err)
+		assert.True(t, response.IsUsageResponse())
+		break
+	}
+
+	// Breaking cancels the run's in-flight tool call
+	select {
+	case <-toolCanceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not canceled")
+	}
+}
@@ -0,0 +1,127 @@
+package serve
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// chatMessage is a message of a chat completion request
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCallID string          `json:"tool_call_id"`
+	ToolCalls  []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+type chatContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+	File struct {
+		FileData string `json:"file_data"`
+		Filename string `json:"filename"`
+	} `json:"file"`
+}
+
+// toMessages converts the messages of a request. A user message mixing text,
+// images, and files becomes one message of each kind, in that order.
+func toMessages(chat []chatMessage) ([]agent.Message, error) {
+	if len(chat) == 0 {
+		return nil, errors.New("messages must not be empty")
+	}
+	var messages []agent.Message
+	for i, msg := range chat {
+		text, images, files, err := toContent(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		if msg.Role != "user" && (len(images) > 0 || len(files) > 0) {
+			return nil, fmt.Errorf("messages[%d]: only user messages may have images and files", i)
+		}
+
+		switch msg.Role {
+		case "system":
+			messages = append(messages, agent.SystemMessage(text))
+		case "developer":
+			messages = append(messages, agent.DeveloperMessage(text))
+		case "user":
+			if text != "" || (len(images) == 0 && len(files) == 0) {
+				messages = append(messages, agent.UserTextMessage(text))
+			}
+			if len(images) > 0 {
+				messages = append(messages, agent.UserImagesMessage(images...))
+			}
+			for _, file := range files {
+				messages = append(messages, agent.UserFileMessage(file))
+			}
+		case "assistant":
+			var calls []agent.ToolCall
+			for _, call := range msg.ToolCalls {
+				calls = append(calls, agent.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+			}
+			messages = append(messages, agent.AssistantToolCallMessage(text, calls...))
+		case "tool":
+			messages = append(messages, agent.ToolResultMessage(msg.ToolCallID, text))
+		default:
+			return nil, fmt.Errorf("messages[%d]: unknown role %q", i, msg.Role)
+		}
+	}
+	return messages, nil
+}
+
+// toContent converts message content, a string or an array of parts
+func toContent(content json.RawMessage) (string, []agent.Image, []agent.File, error) {
+	if len(content) == 0 || string(content) == "null" {
+		return "", nil, nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil, nil, nil
+	}
+
+	var parts []chatContentPart
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return "", nil, nil, fmt.Errorf("decode content: %w", err)
+	}
+	var texts []string
+	var images []agent.Image
+	var files []agent.File
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "image_url":
+			_, data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ";base64,")
+			if !ok {
+				return "", nil, nil, errors.New("images must be sent as base64 data URLs")
+			}
+			decoded, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return "", nil, nil, fmt.Errorf("decode image data: %w", err)
+			}
+			images = append(images, agent.Image{Data: decoded})
+		case "file":
+			decoded, err := base64.StdEncoding.DecodeString(part.File.FileData)
+			if err != nil {
+				return "", nil, nil, fmt.Errorf("decode file data: %w", err)
+			}
+			files = append(files, agent.File{Data: decoded, Name: part.File.Filename})
+		default:
+			return "", nil, nil, fmt.Errorf("unsupported content part %q", part.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), images, files, nil
+}
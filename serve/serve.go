@@ -0,0 +1,296 @@
+// Package serve exposes an agent as an OpenAI-compatible Chat Completions
+// endpoint, so existing OpenAI clients and chat UIs such as Open WebUI can talk
+// to it:
+//
+//	http.ListenAndServe(":8080", serve.NewServer(a, serve.WithModel("support")))
+//
+// The server answers POST /v1/chat/completions, in JSON or, for streaming
+// requests, as server-sent events, and lists the model at GET /v1/models. The
+// agent runs its own tool loop; tools declared in requests are ignored, and
+// replies carry only the agent's text. Each piece of text the run produces is
+// streamed as it arrives, separated by a blank line.
+package serve
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// DefaultModel is the model name a server reports unless WithModel is used
+const DefaultModel = "go-agents"
+
+// Option is a functional option for configuring a Server
+type Option func(*Server)
+
+// WithModel sets the model name the server reports and lists
+func WithModel(name string) Option {
+	return func(s *Server) {
+		s.model = name
+	}
+}
+
+// WithAPIKeys requires requests to carry one of keys as a bearer token
+func WithAPIKeys(keys ...string) Option {
+	return func(s *Server) {
+		s.apiKeys = append(s.apiKeys, keys...)
+	}
+}
+
+// WithRunOptions derives run options from each request, for example to set
+// the tenant from an authenticated header
+func WithRunOptions(fn func(r *http.Request) []agent.RunOption) Option {
+	return func(s *Server) {
+		s.runOptions = fn
+	}
+}
+
+// Server serves an agent over the OpenAI Chat Completions API. Conversations are
+// not kept; each request carries its full history, as with OpenAI.
+type Server struct {
+	agent      agent.ChatAgent
+	model      string
+	apiKeys    []string
+	runOptions func(r *http.Request) []agent.RunOption
+	created    int64
+	mux        *http.ServeMux
+}
+
+// NewServer creates a server for an agent
+func NewServer(a agent.ChatAgent, opts ...Option) *Server {
+	s := &Server{agent: a, model: DefaultModel, created: time.Now().Unix(), mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux.HandleFunc("POST /v1/chat/completions", s.serveChatCompletions)
+	s.mux.HandleFunc("GET /v1/models", s.serveModels)
+	s.mux.HandleFunc("GET /v1/models/{model}", s.serveModel)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_api_key", "invalid API key")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether the request carries one of the API keys, if any are required
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.apiKeys) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	for _, key := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// model describes the served model
+type model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+func (s *Server) serveModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"object": "list",
+		"data":   []model{{ID: s.model, Object: "model", Created: s.created, OwnedBy: "go-agents"}},
+	})
+}
+
+func (s *Server) serveModel(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("model") != s.model {
+		writeError(w, http.StatusNotFound, "model_not_found", fmt.Sprintf("model %s does not exist", r.PathValue("model")))
+		return
+	}
+	writeJSON(w, model{ID: s.model, Object: "model", Created: s.created, OwnedBy: "go-agents"})
+}
+
+// chatRequest is the part of a chat completion request the server reads
+type chatRequest struct {
+	Model         string        `json:"model"`
+	Messages      []chatMessage `json:"messages"`
+	Stream        bool          `json:"stream"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+func (s *Server) serveChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "decode request: "+err.Error())
+		return
+	}
+	messages, err := toMessages(req.Messages)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	var opts []agent.RunOption
+	if s.runOptions != nil {
+		opts = s.runOptions(r)
+	}
+
+	responses, err := s.agent.StreamChatCompletion(r.Context(), messages, opts...)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	id := "chatcmpl-" + newID()
+	if req.Stream {
+		s.stream(w, id, responses, req.StreamOptions.IncludeUsage)
+		return
+	}
+
+	completion, err := agent.Collect(responses)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   s.model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]any{"role": "assistant", "content": strings.Join(completion.Messages, "\n\n")},
+		}},
+		"usage": usage(completion.Usage),
+	})
+}
+
+// stream writes a run's text as chat completion chunks
+func (s *Server) stream(w http.ResponseWriter, id string, responses <-chan agent.Response, includeUsage bool) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	flusher, _ := w.(http.Flusher)
+	created := time.Now().Unix()
+	send := func(data any) {
+		encoded, _ := json.Marshal(data)
+		fmt.Fprintf(w, "data: %s\n\n", encoded)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   s.model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	send(chunk(map[string]any{"role": "assistant", "content": ""}, nil))
+	var total agent.Usage
+	separate := false
+	for response := range responses {
+		switch {
+		case response.IsContentResponse():
+			content := response.Content()
+			if separate {
+				content = "\n\n" + content
+			}
+			separate = true
+			send(chunk(map[string]any{"content": content}, nil))
+		case response.IsUsageResponse():
+			total = total.Add(response.Usage())
+		case response.IsErrorResponse():
+			// The status was sent with the first chunk, so the error goes in the stream
+			status, code := errorStatus(response.Error())
+			send(map[string]any{"error": map[string]any{"message": response.Error().Error(), "type": code, "code": status}})
+			return
+		}
+	}
+	send(chunk(map[string]any{}, "stop"))
+	if includeUsage {
+		send(map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   s.model,
+			"choices": []any{},
+			"usage":   usage(total),
+		})
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// usage converts a run's usage to the API's
+func usage(u agent.Usage) map[string]any {
+	return map[string]any{
+		"prompt_tokens":             u.PromptTokens,
+		"completion_tokens":         u.CompletionTokens,
+		"total_tokens":              u.TotalTokens,
+		"prompt_tokens_details":     map[string]any{"cached_tokens": u.CachedPromptTokens},
+		"completion_tokens_details": map[string]any{"reasoning_tokens": u.ReasoningTokens},
+	}
+}
+
+// errorStatus maps a run error to an HTTP status and an error type
+func errorStatus(err error) (int, string) {
+	var rateLimit *agent.RateLimitError
+	var contextLength *agent.ContextLengthExceededError
+	var contentFilter *agent.ContentFilterError
+	switch {
+	case errors.Is(err, agent.ErrLimitExceeded), errors.As(err, &contextLength):
+		return http.StatusBadRequest, "invalid_request_error"
+	case errors.As(err, &contentFilter), errors.Is(err, agent.ErrOutputBlocked), errors.Is(err, agent.ErrModerationBlocked):
+		return http.StatusBadRequest, "content_filter"
+	case errors.Is(err, agent.ErrAccessDenied):
+		return http.StatusForbidden, "permission_error"
+	case errors.As(err, &rateLimit), errors.Is(err, agent.ErrRateLimited), errors.Is(err, agent.ErrBudgetExceeded):
+		return http.StatusTooManyRequests, "rate_limit_error"
+	case errors.Is(err, agent.ErrRunTimeout):
+		return http.StatusGatewayTimeout, "timeout_error"
+	}
+	return http.StatusInternalServerError, "server_error"
+}
+
+func writeRunError(w http.ResponseWriter, err error) {
+	status, code := errorStatus(err)
+	writeError(w, status, code, err.Error())
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "type": code}})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
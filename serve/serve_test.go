@@ -0,0 +1,176 @@
+package serve
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clockTool struct{}
+
+func (clockTool) Name() string                 { return "get_time" }
+func (clockTool) Description() string          { return "Get the current time" }
+func (clockTool) Parameters() agent.Parameters { return agent.Parameters{Properties: map[string]any{}} }
+func (clockTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return "12:00", nil
+}
+
+// newTestServer serves an agent backed by a fake provider, returning a client of the server
+func newTestServer(t *testing.T, provider *agenttest.Server, opts ...Option) (openai.Client, *httptest.Server) {
+	t.Helper()
+	a := agent.NewAgent("provider-key", provider.URL, "gpt-test", agent.WithTools([]agent.Tool{clockTool{}}))
+	server := httptest.NewServer(NewServer(a, opts...))
+	t.Cleanup(server.Close)
+	client := openai.NewClient(option.WithAPIKey("client-key"), option.WithBaseURL(server.URL+"/v1"), option.WithMaxRetries(0))
+	return client, server
+}
+
+func TestChatCompletion(t *testing.T) {
+	provider := agenttest.NewServer(t,
+		agenttest.Reply{Content: "Let me check.", ToolCalls: []agent.ToolCall{{Name: "get_time", Arguments: `{}`}}},
+		agenttest.Text("It is 12:00."),
+	)
+	client, _ := newTestServer(t, provider, WithModel("clock"))
+
+	completion, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model: "clock",
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("Be brief."),
+			openai.UserMessage("What time is it?"),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "clock", completion.Model)
+	assert.Equal(t, "Let me check.\n\nIt is 12:00.", completion.Choices[0].Message.Content)
+	assert.Equal(t, "stop", completion.Choices[0].FinishReason)
+	assert.Positive(t, completion.Usage.TotalTokens)
+
+	requests := provider.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "Bearer provider-key", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, []string{"get_time"}, requests[0].Tools)
+	assert.Equal(t, "Be brief.", requests[0].Messages[0].Content)
+	assert.Equal(t, "12:00", requests[1].LastMessage().Content)
+}
+
+func TestChatCompletionStreaming(t *testing.T) {
+	provider := agenttest.NewServer(t,
+		agenttest.Reply{Content: "Let me check.", ToolCalls: []agent.ToolCall{{Name: "get_time", Arguments: `{}`}}},
+		agenttest.Text("It is 12:00."),
+	)
+	client, _ := newTestServer(t, provider)
+
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:         DefaultModel,
+		Messages:      []openai.ChatCompletionMessageParamUnion{openai.UserMessage("What time is it?")},
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+	})
+	var acc openai.ChatCompletionAccumulator
+	var deltas []string
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			deltas = append(deltas, chunk.Choices[0].Delta.Content)
+		}
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []string{"Let me check.", "\n\nIt is 12:00."}, deltas)
+	assert.Equal(t, "Let me check.\n\nIt is 12:00.", acc.Choices[0].Message.Content)
+	assert.Equal(t, "stop", acc.Choices[0].FinishReason)
+	assert.Positive(t, acc.Usage.TotalTokens)
+}
+
+func TestChatCompletionErrors(t *testing.T) {
+	provider := agenttest.NewServer(t, agenttest.Fail(http.StatusBadRequest, "bad model"))
+	client, _ := newTestServer(t, provider)
+	params := openai.ChatCompletionNewParams{
+		Model:    DefaultModel,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")},
+	}
+
+	_, err := client.Chat.Completions.New(context.Background(), params)
+	var apiErr *openai.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "bad model")
+
+	provider.Enqueue(agenttest.Fail(http.StatusBadRequest, "bad model"))
+	stream := client.Chat.Completions.NewStreaming(context.Background(), params)
+	for stream.Next() {
+	}
+	require.Error(t, stream.Err())
+	assert.Contains(t, stream.Err().Error(), "bad model")
+
+	_, err = client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{Model: DefaultModel})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+func TestErrorStatus(t *testing.T) {
+	status, _ := errorStatus(&agent.RateLimitError{Err: errors.New("slow down")})
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	status, _ = errorStatus(&agent.LimitError{Kind: agent.LimitKindMessages, Max: 1, Actual: 2, Index: -1})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = errorStatus(&agent.RunTimeoutError{Limit: time.Minute})
+	assert.Equal(t, http.StatusGatewayTimeout, status)
+	status, _ = errorStatus(errors.New("boom"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
+
+func TestAPIKeysAndModels(t *testing.T) {
+	provider := agenttest.NewServer(t)
+	client, server := newTestServer(t, provider, WithModel("clock"), WithAPIKeys("client-key"))
+
+	models, err := client.Models.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models.Data, 1)
+	assert.Equal(t, "clock", models.Data[0].ID)
+
+	_, err = client.Models.Get(context.Background(), "other")
+	require.Error(t, err)
+
+	resp, err := http.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestToMessages(t *testing.T) {
+	image := base64.StdEncoding.EncodeToString([]byte("png"))
+	var chat []chatMessage
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"role": "developer", "content": "Be brief."},
+		{"role": "user", "content": [{"type": "text", "text": "What is this?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,`+image+`"}}]},
+		{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "look", "arguments": "{}"}}]},
+		{"role": "tool", "tool_call_id": "call_1", "content": "a cat"}
+	]`), &chat))
+
+	messages, err := toMessages(chat)
+	require.NoError(t, err)
+	assert.Equal(t, []agent.Message{
+		agent.DeveloperMessage("Be brief."),
+		agent.UserTextMessage("What is this?"),
+		agent.UserImagesMessage(agent.Image{Data: []byte("png")}),
+		agent.AssistantToolCallMessage("", agent.ToolCall{ID: "call_1", Name: "look", Arguments: "{}"}),
+		agent.ToolResultMessage("call_1", "a cat"),
+	}, messages)
+
+	_, err = toMessages([]chatMessage{{Role: "narrator", Content: json.RawMessage(`"hi"`)}})
+	assert.EqualError(t, err, `messages[0]: unknown role "narrator"`)
+	_, err = toMessages([]chatMessage{{Role: "user", Content: json.RawMessage(`[{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]`)}})
+	assert.EqualError(t, err, "messages[0]: images must be sent as base64 data URLs")
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go"
+)
+
+// maxSpeechInput is the most text the speech endpoint accepts in one request
+const maxSpeechInput = 4096
+
+// speechChunkSize is the size of the audio chunks streamed as audio responses
+const speechChunkSize = 32 << 10
+
+// TTSConfig configures text-to-speech with WithTTS and Synthesize
+type TTSConfig struct {
+	// Model is the speech model, gpt-4o-mini-tts by default
+	Model string
+	// Voice is the voice to speak with, such as "alloy" (the default), "coral", or "verse"
+	Voice string
+	// Format is the audio format: mp3 (the default), opus, aac, flac, wav, or pcm
+	Format string
+	// Instructions steer the tone of the voice, for models that support them
+	Instructions string
+	// Speed is the playback speed from 0.25 to 4, or zero for normal speed
+	Speed float64
+}
+
+// AudioChunk is a piece of synthesized speech
+type AudioChunk struct {
+	// Format is the audio format, such as "mp3"
+	Format string `json:"format"`
+	Data   []byte `json:"data"`
+}
+
+// WithTTS speaks the final answer of each run: once the run succeeds, its last
+// content response is converted to speech, which follows on the stream as audio
+// responses. Collected completions hold the whole clip in Completion.Audio. If
+// speech fails, the run ends with the error after its text was delivered.
+func WithTTS(config TTSConfig) AgentOption {
+	return func(a *Agent) {
+		a.tts = &config
+	}
+}
+
+// Synthesize converts text to speech with the agent's TTS configuration, or the
+// defaults if WithTTS was not given. Text longer than the speech endpoint
+// accepts is spoken in several requests and joined, which is seamless for
+// formats that concatenate, such as mp3 and pcm.
+func (agent *Agent) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	var audio []byte
+	err := agent.speak(ctx, text, func(chunk AudioChunk) error {
+		audio = append(audio, chunk.Data...)
+		return nil
+	})
+	return audio, err
+}
+
+// speak synthesizes text, passing the audio to emit in chunks as it arrives
+func (agent *Agent) speak(ctx context.Context, text string, emit func(AudioChunk) error) error {
+	config := TTSConfig{}
+	if agent.tts != nil {
+		config = *agent.tts
+	}
+	if config.Model == "" {
+		config.Model = openai.SpeechModelGPT4oMiniTTS
+	}
+	if config.Voice == "" {
+		config.Voice = "alloy"
+	}
+	if config.Format == "" {
+		config.Format = "mp3"
+	}
+
+	for _, part := range splitSpeech(text, maxSpeechInput) {
+		params := openai.AudioSpeechNewParams{
+			Input:          part,
+			Model:          config.Model,
+			Voice:          openai.AudioSpeechNewParamsVoice(config.Voice),
+			ResponseFormat: openai.AudioSpeechNewParamsResponseFormat(config.Format),
+		}
+		if config.Instructions != "" {
+			params.Instructions = openai.String(config.Instructions)
+		}
+		if config.Speed > 0 {
+			params.Speed = openai.Float(config.Speed)
+		}
+		resp, err := agent.client.Audio.Speech.New(ctx, params, agent.debugOptions(agent.requestOptions)...)
+		if err != nil {
+			return wrapProviderError(err)
+		}
+		err = streamAudio(resp.Body, config.Format, emit)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamAudio reads audio from r, passing it to emit in chunks
+func streamAudio(r io.Reader, format string, emit func(AudioChunk) error) error {
+	for {
+		buf := make([]byte, speechChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := emit(AudioChunk{Format: format, Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// splitSpeech splits text into parts of at most limit bytes, preferring to
+// break after paragraphs, then sentences, then words
+func splitSpeech(text string, limit int) []string {
+	var parts []string
+	text = strings.TrimSpace(text)
+	for len(text) > limit {
+		window := text[:limit]
+		cut := -1
+		for _, sep := range []string{"\n\n", "\n", ". ", "! ", "? ", " "} {
+			if i := strings.LastIndex(window, sep); i > 0 {
+				cut = i + len(sep)
+				break
+			}
+		}
+		if cut <= 0 {
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// speakResponses passes responses through and, once they end without an error,
+// follows them with the speech of the last content response
+func (agent *Agent) speakResponses(ctx context.Context, in <-chan Response) <-chan Response {
+	if agent.tts == nil {
+		return in
+	}
+	out := make(chan Response)
+	go func() {
+		defer close(out)
+		var answer string
+		failed := false
+		for response := range in {
+			switch {
+			case response.IsErrorResponse():
+				failed = true
+			case response.IsContentResponse():
+				answer = response.Content()
+			}
+			out <- response
+		}
+		if failed || strings.TrimSpace(answer) == "" {
+			return
+		}
+		err := agent.speak(ctx, answer, func(chunk AudioChunk) error {
+			return send(ctx, out, NewAudioResponse(chunk))
+		})
+		if err != nil {
+			out <- NewErrorResponse(fmt.Errorf("speech: %w", err))
+		}
+	}()
+	return out
+}
+
+// NewAudioResponse creates a response carrying a chunk of synthesized speech
+func NewAudioResponse(chunk AudioChunk) Response {
+	return Response{
+		Kind:  ResponseKindAudio,
+		audio: chunk,
+	}
+}
+
+// IsAudioResponse reports whether the response carries synthesized speech
+func (r Response) IsAudioResponse() bool {
+	return r.Kind == ResponseKindAudio
+}
+
+// Audio returns the speech chunk of an audio response
+func (r Response) Audio() AudioChunk {
+	if r.Kind != ResponseKindAudio {
+		return AudioChunk{}
+	}
+	return r.audio
+}
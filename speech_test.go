@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSpeech(t *testing.T) {
+	assert.Equal(t, []string{"Short answer."}, splitSpeech("  Short answer. ", 100))
+	assert.Equal(t, []string{"First sentence.", "Second one."}, splitSpeech("First sentence. Second one.", 20))
+	assert.Equal(t, []string{"Para one.", "Para two is longer."}, splitSpeech("Para one.\n\nPara two is longer.", 24))
+	assert.Equal(t, []string{"abcd", "efgh"}, splitSpeech("abcdefgh", 4))
+	for _, part := range splitSpeech(strings.Repeat("é", 10), 5) {
+		assert.LessOrEqual(t, len(part), 5)
+		assert.Equal(t, strings.Repeat("é", len(part)/2), part)
+	}
+}
+
+func TestTTS(t *testing.T) {
+	audio := bytes.Repeat([]byte("mp3!"), speechChunkSize/2)
+	var spoken []map[string]any
+	var chatRequests int
+	speechStatus := http.StatusOK
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/audio/speech" {
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			spoken = append(spoken, body)
+			if speechStatus != http.StatusOK {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(speechStatus)
+				w.Write([]byte(`{"error":{"message":"speech unavailable"}}`))
+				return
+			}
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(audio)
+			return
+		}
+		chatRequests++
+		if chatRequests%2 == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"1","object":"chat.completion","model":"model","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"Let me check.","tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{}"}}]}}]}`))
+			return
+		}
+		writeCompletion(w, "model", "It shipped yesterday.")
+	}
+	testAgent := newTestAgent(t, "model", handler,
+		WithTools([]Tool{MockTool{name: "lookup"}}),
+		WithTTS(TTSConfig{Voice: "coral", Instructions: "Sound cheerful."}),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Where is my order?")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Let me check.", "It shipped yesterday."}, completion.Messages)
+	assert.Equal(t, audio, completion.Audio)
+
+	// Only the final answer is spoken, in chunks after the text
+	require.Len(t, spoken, 1)
+	assert.Equal(t, map[string]any{"input": "It shipped yesterday.", "model": "gpt-4o-mini-tts", "voice": "coral", "response_format": "mp3", "instructions": "Sound cheerful."}, spoken[0])
+	last := completion.Responses[len(completion.Responses)-3:]
+	assert.True(t, last[0].IsContentResponse())
+	assert.Equal(t, AudioChunk{Format: "mp3", Data: audio[:speechChunkSize]}, last[1].Audio())
+	assert.Len(t, last[2].Audio().Data, len(audio)-speechChunkSize)
+
+	// A speech failure fails the run after the text was delivered
+	speechStatus = http.StatusInternalServerError
+	completion, err = testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Where is my order?")})
+	assert.ErrorContains(t, err, "speech:")
+	assert.Equal(t, []string{"Let me check.", "It shipped yesterday."}, completion.Messages)
+}
+
+func TestSynthesize(t *testing.T) {
+	var body map[string]any
+	testAgent := newTestAgent(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte("pcm"))
+	})
+
+	audio, err := testAgent.Synthesize(context.Background(), "Hello there")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("pcm"), audio)
+	assert.Equal(t, "alloy", body["voice"])
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// StepState is the state of a run driven step by step, for example by a durable
+// workflow engine such as Temporal. It holds the whole request and encodes to
+// JSON, so it can be stored in workflow history and passed between activities.
+//
+// A step-driven run is a loop: PrepareRequest creates the state, CallModel and
+// ExecuteTool perform the side effects, which are safe to retry as activities,
+// and Advance, which is deterministic, folds their results into the next state
+// until it is Done:
+//
+//	state, err := a.PrepareRequest(ctx, messages)
+//	for err == nil && !state.Done {
+//		var result ModelResult
+//		result, err = a.CallModel(ctx, state)
+//		...
+//		outputs := make([]ToolOutput, 0, len(result.ToolCalls))
+//		for _, call := range result.ToolCalls {
+//			output, err := a.ExecuteTool(ctx, call)
+//			...
+//			outputs = append(outputs, output)
+//		}
+//		state, err = Advance(state, result, outputs)
+//	}
+type StepState struct {
+	Params        openai.ChatCompletionNewParams `json:"params"`
+	Iteration     int                            `json:"iteration"`
+	MaxIterations int                            `json:"max_iterations"`
+	// Done is set once the model answers without calling tools
+	Done bool `json:"done"`
+	// Answer is the model's final answer once Done is set
+	Answer string `json:"answer,omitempty"`
+}
+
+// ModelResult is the outcome of a CallModel step
+type ModelResult struct {
+	Content   string     `json:"content,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Usage     Usage      `json:"usage"`
+}
+
+// ToolOutput is the outcome of an ExecuteTool step, answering the call with CallID
+type ToolOutput struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+}
+
+// PrepareRequest creates the state of a step-driven run over messages, with the
+// agent's system prompt, instructions, and tools. Run options apply as they do
+// to StreamChatCompletion and must be passed to every step of the run.
+func (agent *Agent) PrepareRequest(ctx context.Context, messages []Message, opts ...RunOption) (StepState, error) {
+	options := newRunOptions(opts)
+	ctx = withMemorySubject(ctx, options.memorySubject)
+	if err := agent.limits.Check(messages); err != nil {
+		return StepState{}, err
+	}
+	plan, err := agent.plan(options)
+	if err != nil {
+		return StepState{}, err
+	}
+
+	tools := filterToolGroups(agent.runTools(ctx, options.tenant), options)
+	toolChoice, err := agent.toolChoiceParam(options, tools)
+	if err != nil {
+		return StepState{}, err
+	}
+	prompts, err := agent.prompts(ctx)
+	if err != nil {
+		return StepState{}, err
+	}
+	return StepState{
+		Params:        agent.newParams(prompts, messages, tools, toolChoice),
+		MaxIterations: plan.maxIterations,
+	}, nil
+}
+
+// CallModel requests the next completion of a step-driven run, falling back to
+// the agent's fallback models as a run would. It has no effect on state, so it
+// can be retried; each attempt is a separate, billed request.
+func (agent *Agent) CallModel(ctx context.Context, state StepState, opts ...RunOption) (ModelResult, error) {
+	if state.Done {
+		return ModelResult{}, errors.New("run is done")
+	}
+	options := newRunOptions(opts)
+	plan, err := agent.plan(options)
+	if err != nil {
+		return ModelResult{}, err
+	}
+	plan = agent.routePool(plan, options)
+	if err := checkKilled(agent.killSwitches(options)); err != nil {
+		return ModelResult{}, err
+	}
+
+	response, err := agent.createCompletion(ctx, state.Params, plan)
+	if err != nil {
+		return ModelResult{}, err
+	}
+	usage := convertUsage(response)
+	usage.Iteration = state.Iteration + 1
+	if response.Choices[0].FinishReason == "content_filter" {
+		return ModelResult{}, &ContentFilterError{}
+	}
+
+	result := ModelResult{Content: response.Choices[0].Message.Content, Usage: usage}
+	for _, call := range response.Choices[0].Message.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	return result, nil
+}
+
+// ExecuteTool runs a tool call of a step-driven run with the agent's tool
+// timeout, summarization, and truncation. Tools are retried as a whole, so
+// tools with side effects should be idempotent. Warnings and progress are not
+// reported, images and files the tool returns are dropped, and handoffs are not
+// supported.
+func (agent *Agent) ExecuteTool(ctx context.Context, call ToolCall, opts ...RunOption) (ToolOutput, error) {
+	options := newRunOptions(opts)
+	ctx = withMemorySubject(ctx, options.memorySubject)
+	toolsByName := map[string]Tool{}
+	for _, tool := range filterToolGroups(agent.runTools(ctx, options.tenant), options) {
+		toolsByName[tool.Name()] = tool
+	}
+
+	// Discard the responses the tool call reports along the way
+	discard := make(chan Response)
+	go func() {
+		for range discard {
+		}
+	}()
+	defer close(discard)
+
+	// A single tool call has no run budget to charge
+	content, _, err := agent.executeToolCall(agent.withSecrets(ctx), toolsByName, openai.ChatCompletionMessageToolCall{
+		ID:       call.ID,
+		Function: openai.ChatCompletionMessageToolCallFunction{Name: call.Name, Arguments: call.Arguments},
+	}, &spend{}, discard)
+	if err != nil {
+		return ToolOutput{}, err
+	}
+	return ToolOutput{CallID: call.ID, Content: content}, nil
+}
+
+// Advance folds the outcome of a model call and its tool calls into the next
+// state of a step-driven run. It is deterministic and has no side effects, so
+// workflow engines can replay it. Every tool call of result must be answered by
+// an output; the run fails with a *MaxIterationsError once the model keeps
+// calling tools past its iteration limit.
+func Advance(state StepState, result ModelResult, outputs []ToolOutput) (StepState, error) {
+	if state.Done {
+		return state, errors.New("run is done")
+	}
+	contents := make(map[string]string, len(outputs))
+	for _, output := range outputs {
+		contents[output.CallID] = output.Content
+	}
+	for _, call := range result.ToolCalls {
+		if _, ok := contents[call.ID]; !ok {
+			return state, fmt.Errorf("no output for tool call %s (%s)", call.ID, call.Name)
+		}
+	}
+
+	next := state
+	next.Params.Messages = append([]openai.ChatCompletionMessageParamUnion{}, state.Params.Messages...)
+	next.Iteration++
+	// The tool choice only applies to the first iteration
+	next.Params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+
+	if result.Content != "" || len(result.ToolCalls) > 0 {
+		next.Params.Messages = append(next.Params.Messages, convertMessage(AssistantToolCallMessage(result.Content, result.ToolCalls...)))
+	}
+	if len(result.ToolCalls) == 0 {
+		next.Done = true
+		next.Answer = result.Content
+		return next, nil
+	}
+	for _, call := range result.ToolCalls {
+		next.Params.Messages = append(next.Params.Messages, openai.ToolMessage(contents[call.ID], call.ID))
+	}
+	if next.Iteration >= next.MaxIterations {
+		return next, &MaxIterationsError{MaxIterations: next.MaxIterations}
+	}
+	return next, nil
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSteps(t *testing.T) {
+	var requests [][]map[string]any
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body.Messages)
+		if len(requests) == 1 {
+			writeToolCall(w, "model", "search", `{"query":"go"}`)
+			return
+		}
+		writeCompletion(w, "model", "Go is a language.")
+	}
+	search := MockTool{name: "search", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		return "results for " + input["query"].(string), nil
+	}}
+	testAgent := newTestAgent(t, "model", handler, WithTools([]Tool{search}), WithSystemPrompt("Be brief."))
+
+	ctx := context.Background()
+	state, err := testAgent.PrepareRequest(ctx, []Message{UserTextMessage("What is Go?")})
+	require.NoError(t, err)
+	assert.Equal(t, 100, state.MaxIterations)
+
+	// Each step's state survives a round trip through workflow history
+	roundTrip := func(state StepState) StepState {
+		data, err := json.Marshal(state)
+		require.NoError(t, err)
+		var decoded StepState
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		return decoded
+	}
+
+	for !state.Done {
+		result, err := testAgent.CallModel(ctx, roundTrip(state))
+		require.NoError(t, err)
+		var outputs []ToolOutput
+		for _, call := range result.ToolCalls {
+			output, err := testAgent.ExecuteTool(ctx, call)
+			require.NoError(t, err)
+			outputs = append(outputs, output)
+		}
+		state, err = Advance(roundTrip(state), result, outputs)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "Go is a language.", state.Answer)
+	assert.Equal(t, 2, state.Iteration)
+	require.Len(t, requests, 2)
+	assert.Equal(t, "system", requests[1][0]["role"])
+	assert.Equal(t, "assistant", requests[1][2]["role"])
+	assert.Equal(t, "search", requests[1][2]["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)["name"])
+	assert.Equal(t, map[string]any{"role": "tool", "tool_call_id": "call_1", "content": "results for go"}, requests[1][3])
+}
+
+func TestAdvance(t *testing.T) {
+	state := StepState{MaxIterations: 1}
+	call := ToolCall{ID: "call_1", Name: "search", Arguments: `{}`}
+
+	_, err := Advance(state, ModelResult{ToolCalls: []ToolCall{call}}, nil)
+	assert.EqualError(t, err, "no output for tool call call_1 (search)")
+
+	next, err := Advance(state, ModelResult{ToolCalls: []ToolCall{call}}, []ToolOutput{{CallID: "call_1", Content: "ok"}})
+	var maxErr *MaxIterationsError
+	require.ErrorAs(t, err, &maxErr)
+	assert.Len(t, next.Params.Messages, 2)
+	assert.Empty(t, state.Params.Messages, "advancing must not modify the previous state")
+
+	done, err := Advance(StepState{MaxIterations: 1}, ModelResult{Content: "hi"}, nil)
+	require.NoError(t, err)
+	assert.True(t, done.Done)
+	_, err = Advance(done, ModelResult{}, nil)
+	assert.Error(t, err)
+}
+
+func TestExecuteToolErrors(t *testing.T) {
+	testAgent := newTestAgent(t, "model", nil)
+	_, err := testAgent.ExecuteTool(context.Background(), ToolCall{ID: "call_1", Name: "missing", Arguments: `{}`})
+	var toolErr *ToolExecutionError
+	require.ErrorAs(t, err, &toolErr)
+	assert.Equal(t, "missing", toolErr.Tool)
+}
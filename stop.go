@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"regexp"
+	"slices"
+	"time"
+)
+
+// StopState is what a stop condition sees of a run after an iteration
+type StopState struct {
+	// Run identifies the run; Run.Iteration is the iteration just finished
+	Run     RunInfo
+	Elapsed time.Duration
+	// Messages holds the content the run has returned so far
+	Messages []string
+	// ToolCalls holds every tool call the model has made so far, in order,
+	// including calls that were denied
+	ToolCalls []ToolCall
+	Usage     Usage
+}
+
+// StopCondition reports whether a run should stop after an iteration
+type StopCondition func(state StopState) bool
+
+// WithStopCondition ends runs once any of the conditions holds after an
+// iteration, before the next request. A stopped run ends without an error,
+// as if the model had answered, with the content returned so far. Conditions
+// are checked in addition to the iteration limit, and repeated use adds to
+// them.
+func WithStopCondition(conditions ...StopCondition) AgentOption {
+	return func(a *Agent) {
+		a.stopConditions = append(slices.Clone(a.stopConditions), conditions...)
+	}
+}
+
+// StopAfterTool stops a run once the model has called any of the named tools,
+// for tools whose result ends the task, such as submitting an answer
+func StopAfterTool(names ...string) StopCondition {
+	return func(state StopState) bool {
+		return slices.ContainsFunc(state.ToolCalls, func(call ToolCall) bool {
+			return slices.Contains(names, call.Name)
+		})
+	}
+}
+
+// StopOnMatch stops a run once content it has returned matches pattern
+func StopOnMatch(pattern *regexp.Regexp) StopCondition {
+	return func(state StopState) bool {
+		return slices.ContainsFunc(state.Messages, pattern.MatchString)
+	}
+}
+
+// StopAfter stops a run once it has run for d. The iteration in progress is
+// not interrupted; cancel the run's context for a hard deadline.
+func StopAfter(d time.Duration) StopCondition {
+	return func(state StopState) bool {
+		return state.Elapsed >= d
+	}
+}
+
+// stopRequested reports whether any of the agent's stop conditions holds
+func (agent *Agent) stopRequested(state StopState) bool {
+	for _, condition := range agent.stopConditions {
+		if condition(state) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLoopingAgent creates an agent whose model calls search on the first
+// request and submit on every later one, counting the requests
+func newLoopingAgent(t *testing.T, opts ...AgentOption) (*Agent, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	tool := func(name string) Tool {
+		return MockTool{name: name, executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			return "ok", nil
+		}}
+	}
+	opts = append([]AgentOption{WithTools([]Tool{tool("search"), tool("submit")})}, opts...)
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			writeToolCall(w, "gpt-4o", "search", `{}`)
+			return
+		}
+		writeToolCall(w, "gpt-4o", "submit", `{"answer":"42"}`)
+	}, opts...)
+	return testAgent, &requests
+}
+
+func TestStopAfterTool(t *testing.T) {
+	testAgent, requests := newLoopingAgent(t, WithStopCondition(StopAfterTool("submit")))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is the answer?")})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+	require.Len(t, completion.ToolCalls, 2)
+	assert.Equal(t, "submit", completion.ToolCalls[1].Call.Name)
+}
+
+func TestStopConditionOnLastIteration(t *testing.T) {
+	testAgent, requests := newLoopingAgent(t, WithMaxIterations(1), WithStopCondition(StopAfterTool("search")))
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is the answer?")})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+	require.Len(t, completion.ToolCalls, 1)
+	assert.Equal(t, TerminationCompleted, completion.TerminationReason)
+}
+
+func TestStopConditionState(t *testing.T) {
+	var states []StopState
+	testAgent, requests := newLoopingAgent(t, WithStopCondition(func(state StopState) bool {
+		states = append(states, state)
+		return state.Usage.TotalTokens >= 6
+	}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is the answer?")}, WithRunID("run-1"))
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+	require.Len(t, states, 3)
+	assert.Equal(t, 1, states[0].Run.Iteration)
+	assert.Equal(t, "run-1", states[0].Run.ID)
+	assert.Equal(t, []ToolCall{{ID: "call_1", Name: "search", Arguments: `{}`}}, states[0].ToolCalls)
+	assert.Len(t, states[2].ToolCalls, 3)
+	assert.Equal(t, int64(6), states[2].Usage.TotalTokens)
+}
+
+func TestStopConditionsNotMet(t *testing.T) {
+	testAgent, requests := newLoopingAgent(t,
+		WithMaxIterations(3),
+		WithStopCondition(StopOnMatch(regexp.MustCompile(`done`)), StopAfter(time.Hour)),
+	)
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("What is the answer?")})
+
+	var maxErr *MaxIterationsError
+	assert.ErrorAs(t, err, &maxErr)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestStopConditions(t *testing.T) {
+	state := StopState{Elapsed: time.Minute, Messages: []string{"Working on it", "Final answer: 42"}}
+
+	assert.True(t, StopOnMatch(regexp.MustCompile(`Final answer: \d+`))(state))
+	assert.False(t, StopOnMatch(regexp.MustCompile(`error`))(state))
+	assert.True(t, StopAfter(time.Minute)(state))
+	assert.False(t, StopAfter(time.Hour)(state))
+	assert.False(t, StopAfterTool("submit")(state))
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// RunStore persists completed runs by run ID so that resubmitting a run with
+// the same ID returns the stored result instead of executing it again
+type RunStore interface {
+	// Get returns the completion stored for the run ID, reporting whether it was found
+	Get(ctx context.Context, runID string) (Completion, bool, error)
+	// Put stores the completion for the run ID
+	Put(ctx context.Context, runID string, completion Completion) error
+}
+
+// WithRunStore sets the store used to deduplicate runs submitted with WithRunID
+func WithRunStore(store RunStore) AgentOption {
+	return func(a *Agent) {
+		a.runStore = store
+	}
+}
+
+// MemoryRunStore is an in-memory RunStore, StatusStore, and TagStore safe for concurrent use
+type MemoryRunStore struct {
+	mu       sync.RWMutex
+	runs     map[string]Completion
+	statuses map[string]RunStatus
+	tags     map[string]RunTags
+}
+
+// NewMemoryRunStore creates an empty MemoryRunStore
+func NewMemoryRunStore() *MemoryRunStore {
+	return &MemoryRunStore{
+		runs:     map[string]Completion{},
+		statuses: map[string]RunStatus{},
+		tags:     map[string]RunTags{},
+	}
+}
+
+func (s *MemoryRunStore) Get(ctx context.Context, runID string) (Completion, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	completion, ok := s.runs[runID]
+	return completion, ok, nil
+}
+
+func (s *MemoryRunStore) Put(ctx context.Context, runID string, completion Completion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[runID] = completion
+	return nil
+}
+
+func (s *MemoryRunStore) PutStatus(ctx context.Context, status RunStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[status.RunID] = status
+	return nil
+}
+
+func (s *MemoryRunStore) Status(ctx context.Context, runID string) (RunStatus, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, ok := s.statuses[runID]
+	return status, ok, nil
+}
+
+func (s *MemoryRunStore) PutTags(ctx context.Context, tags RunTags) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags[tags.RunID] = tags
+	return nil
+}
+
+// FindTags returns the matching tags, oldest first
+func (s *MemoryRunStore) FindTags(ctx context.Context, filter TagFilter) ([]RunTags, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found []RunTags
+	for _, tags := range s.tags {
+		if filter.Match(tags) {
+			found = append(found, tags)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].TaggedAt.Equal(found[j].TaggedAt) {
+			return found[i].TaggedAt.Before(found[j].TaggedAt)
+		}
+		return found[i].RunID < found[j].RunID
+	})
+	return found, nil
+}
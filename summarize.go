@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// SummarizePolicy configures automatic summarization of large tool results
+// before they are fed back to the model
+type SummarizePolicy struct {
+	// Model is the model used to summarize, typically a cheaper one than the agent's
+	Model string
+	// Threshold is the estimated token count above which a result is summarized
+	Threshold int
+}
+
+// ToolWithSummaryHints is an optional interface for tools whose results contain
+// key fields that must survive summarization verbatim
+type ToolWithSummaryHints interface {
+	Tool
+	SummaryHints() []string
+}
+
+// WithToolResultSummarizer enables summarization of tool results that exceed the policy threshold
+func WithToolResultSummarizer(policy SummarizePolicy) AgentOption {
+	return func(a *Agent) {
+		a.summarizePolicy = policy
+	}
+}
+
+// shouldSummarize reports whether content exceeds the policy threshold
+func (p SummarizePolicy) shouldSummarize(content string) bool {
+	return p.Model != "" && p.Threshold > 0 && estimateTokens(content) > p.Threshold
+}
+
+// estimateTokens approximates the token count of s at four characters per token
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// summarizeToolResult condenses a tool result with model to at most maxTokens
+func (agent *Agent) summarizeToolResult(ctx context.Context, tool Tool, content string, model string, maxTokens int) (string, Usage, error) {
+	prompt := fmt.Sprintf(
+		"Summarize the following output of the %q tool in at most %d tokens. "+
+			"Keep all facts needed to answer questions about it and drop redundant detail.",
+		tool.Name(), maxTokens,
+	)
+	if t, ok := tool.(ToolWithSummaryHints); ok {
+		if hints := t.SummaryHints(); len(hints) > 0 {
+			prompt += " Preserve the values of these fields verbatim: " + strings.Join(hints, ", ") + "."
+		}
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(model),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(prompt),
+			openai.UserMessage(content),
+		},
+	}
+	estimated, err := agent.waitForRateLimit(ctx, model, params)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	response, err := agent.client.Chat.Completions.New(ctx, params, agent.debugOptions(agent.requestOptions)...)
+	if err != nil {
+		agent.recordRateLimit(model, estimated, 0)
+		return "", Usage{}, wrapProviderError(err)
+	}
+	agent.recordRateLimit(model, estimated, response.Usage.TotalTokens)
+	if len(response.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("summarizer returned no choices")
+	}
+
+	return response.Choices[0].Message.Content, convertUsage(response), nil
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// HintedTool is a MockTool that provides summary hints
+type HintedTool struct {
+	MockTool
+	hints []string
+}
+
+func (h HintedTool) SummaryHints() []string {
+	return h.hints
+}
+
+func TestToolResultSummarizer(t *testing.T) {
+	type request struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+
+	var requests []request
+	testAgent := newTestAgent(t, "main-model", func(w http.ResponseWriter, r *http.Request) {
+		var body request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+
+		switch {
+		case body.Model == "cheap-model":
+			writeCompletion(w, "cheap-model", "short summary")
+		case len(requests) == 1:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"main-model","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"search","arguments":"{}"}}]}}]}`)
+		default:
+			writeCompletion(w, "main-model", "done")
+		}
+	},
+		WithTools([]Tool{HintedTool{
+			MockTool: MockTool{
+				name: "search",
+				executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+					return strings.Repeat("lots of output ", 100), nil
+				},
+			},
+			hints: []string{"id", "url"},
+		}}),
+		WithToolResultSummarizer(SummarizePolicy{Model: "cheap-model", Threshold: 50}),
+	)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Search")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"done"}, completion.Messages)
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, "cheap-model", requests[1].Model)
+	assert.Contains(t, requests[1].Messages[0].Content, "id, url")
+
+	final := requests[2].Messages
+	assert.Equal(t, "tool", final[len(final)-1].Role)
+	assert.Equal(t, "short summary", final[len(final)-1].Content)
+}
+
+func TestSummarizePolicyThreshold(t *testing.T) {
+	policy := SummarizePolicy{Model: "cheap-model", Threshold: 10}
+
+	assert.False(t, policy.shouldSummarize(strings.Repeat("a", 40)))
+	assert.True(t, policy.shouldSummarize(strings.Repeat("a", 41)))
+	assert.False(t, SummarizePolicy{}.shouldSummarize(strings.Repeat("a", 1000)))
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Sentiment is the user's apparent sentiment during a run
+type Sentiment string
+
+const (
+	SentimentPositive Sentiment = "positive"
+	SentimentNeutral  Sentiment = "neutral"
+	SentimentNegative Sentiment = "negative"
+)
+
+// RunTags classify a completed run for filtering
+type RunTags struct {
+	RunID     string    `json:"run_id"`
+	Topic     string    `json:"topic"`
+	Sentiment Sentiment `json:"sentiment"`
+	// ToolFailure reports whether a tool call failed during the run
+	ToolFailure bool      `json:"tool_failure"`
+	Failed      bool      `json:"failed"`
+	TaggedAt    time.Time `json:"tagged_at"`
+}
+
+// TagFilter selects runs by their tags. Zero fields match every run.
+type TagFilter struct {
+	Topic       string
+	Sentiment   Sentiment
+	ToolFailure *bool
+	Failed      *bool
+}
+
+// Match reports whether tags satisfy the filter
+func (f TagFilter) Match(tags RunTags) bool {
+	return (f.Topic == "" || f.Topic == tags.Topic) &&
+		(f.Sentiment == "" || f.Sentiment == tags.Sentiment) &&
+		(f.ToolFailure == nil || *f.ToolFailure == tags.ToolFailure) &&
+		(f.Failed == nil || *f.Failed == tags.Failed)
+}
+
+// TagStore persists run tags and queries them
+type TagStore interface {
+	PutTags(ctx context.Context, tags RunTags) error
+	// FindTags returns the tags of the runs matching filter
+	FindTags(ctx context.Context, filter TagFilter) ([]RunTags, error)
+}
+
+// TagPolicy configures automatic tagging of runs
+type TagPolicy struct {
+	Store TagStore
+	// Model is the model used to classify runs, typically a cheap one
+	Model string
+	// Topics restricts the topic to one of a fixed set. When empty the model names
+	// the topic in a few words.
+	Topics []string
+}
+
+// WithTagging classifies every run started with WithRunID after it finishes and
+// stores its tags. Tagging happens in the background and does not delay the run.
+func WithTagging(policy TagPolicy) AgentOption {
+	return func(a *Agent) {
+		a.tagPolicy = policy
+	}
+}
+
+// FindRuns returns the tags of the runs matching filter
+func (agent *Agent) FindRuns(ctx context.Context, filter TagFilter) ([]RunTags, error) {
+	if agent.tagPolicy.Store == nil {
+		return nil, errors.New("tagging is not configured")
+	}
+	return agent.tagPolicy.Store.FindTags(ctx, filter)
+}
+
+// maxTagTranscriptBytes bounds the transcript sent to the tagging model
+const maxTagTranscriptBytes = 16000
+
+// tagRun classifies a finished run in the background
+func (agent *Agent) tagRun(ctx context.Context, runID string, history []Message, runErr error) {
+	if agent.tagPolicy.Store == nil || agent.tagPolicy.Model == "" || runID == "" {
+		return
+	}
+
+	// Tag even when the run was canceled, without holding up the caller
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		tags, err := agent.classifyRun(ctx, history)
+		if err != nil {
+			agent.logger.WarnContext(ctx, "agent tagging failed", "error", err)
+			return
+		}
+		var toolErr *ToolExecutionError
+		tags.RunID = runID
+		tags.ToolFailure = errors.As(runErr, &toolErr)
+		tags.Failed = runErr != nil
+		tags.TaggedAt = time.Now()
+		if err := agent.tagPolicy.Store.PutTags(ctx, tags); err != nil {
+			agent.logger.WarnContext(ctx, "agent tagging failed", "error", err)
+		}
+	}()
+}
+
+// classifyRun asks the tagging model for the topic and sentiment of a conversation
+func (agent *Agent) classifyRun(ctx context.Context, history []Message) (RunTags, error) {
+	topic := StringSchema("The topic of the conversation in a few lowercase words")
+	if len(agent.tagPolicy.Topics) > 0 {
+		topics := make([]any, len(agent.tagPolicy.Topics))
+		for i, t := range agent.tagPolicy.Topics {
+			topics[i] = t
+		}
+		topic = StringSchema("The topic of the conversation").Enum(topics...)
+	}
+	schema := ObjectSchema("Tags for a conversation", map[string]Schema{
+		"topic":     topic,
+		"sentiment": StringSchema("The user's sentiment").Enum("positive", "neutral", "negative"),
+	})
+
+	var transcript strings.Builder
+	for _, msg := range history {
+		if text := msg.Text(); text != "" {
+			fmt.Fprintf(&transcript, "%s: %s\n", msg.Role(), text)
+		}
+	}
+	text := transcript.String()
+	if len(text) > maxTagTranscriptBytes {
+		text = text[:maxTagTranscriptBytes]
+	}
+
+	start := time.Now()
+	response, err := agent.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(agent.tagPolicy.Model),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("Classify the conversation between a user and an assistant by topic and by the user's sentiment."),
+			openai.UserMessage(text),
+		},
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "run_tags",
+					Schema: map[string]any(schema),
+					Strict: openai.Bool(true),
+				},
+			},
+		},
+	}, agent.debugOptions(agent.requestOptions)...)
+	var usage Usage
+	if err == nil {
+		usage = convertUsage(response)
+	} else {
+		err = wrapProviderError(err)
+	}
+	agent.metrics.ObserveRequest(agent.tagPolicy.Model, time.Since(start), usage, err)
+	if err != nil {
+		return RunTags{}, err
+	}
+	if len(response.Choices) == 0 {
+		return RunTags{}, fmt.Errorf("tagger returned no choices")
+	}
+
+	var tags RunTags
+	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &tags); err != nil {
+		return RunTags{}, fmt.Errorf("parse tags: %w", err)
+	}
+	return tags, nil
+}
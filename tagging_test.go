@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagging(t *testing.T) {
+	store := NewMemoryRunStore()
+	failing := MockTool{
+		name:       "lookup_order",
+		parameters: Parameters{Properties: map[string]any{}},
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			return nil, errors.New("order service unavailable")
+		},
+	}
+
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model          string           `json:"model"`
+			Messages       []map[string]any `json:"messages"`
+			ResponseFormat map[string]any   `json:"response_format"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		if body.Model == "tagger" {
+			assert.Equal(t, "json_schema", body.ResponseFormat["type"])
+			transcript, _ := body.Messages[1]["content"].(string)
+			if strings.Contains(transcript, "order") {
+				writeCompletion(w, "tagger", `{"topic":"billing","sentiment":"negative"}`)
+			} else {
+				writeCompletion(w, "tagger", `{"topic":"greeting","sentiment":"positive"}`)
+			}
+			return
+		}
+
+		last := body.Messages[len(body.Messages)-1]["content"]
+		if last == "Where is my order?" {
+			writeToolCall(w, "gpt-4o", "lookup_order", `{}`)
+			return
+		}
+		writeCompletion(w, "gpt-4o", "Hello!")
+	}, WithTools([]Tool{failing}), WithTagging(TagPolicy{Store: store, Model: "tagger", Topics: []string{"billing", "greeting"}}))
+
+	ctx := context.Background()
+	_, err := testAgent.ChatCompletion(ctx, []Message{UserTextMessage("Hi there")}, WithRunID("run-1"))
+	require.NoError(t, err)
+	_, err = testAgent.ChatCompletion(ctx, []Message{UserTextMessage("Where is my order?")}, WithRunID("run-2"))
+	require.Error(t, err)
+	// Runs without an ID are not tagged
+	_, err = testAgent.ChatCompletion(ctx, []Message{UserTextMessage("Hi again")})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		all, err := testAgent.FindRuns(ctx, TagFilter{})
+		return err == nil && len(all) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	toolFailure := true
+	failed, err := testAgent.FindRuns(ctx, TagFilter{ToolFailure: &toolFailure})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "run-2", failed[0].RunID)
+	assert.Equal(t, "billing", failed[0].Topic)
+	assert.Equal(t, SentimentNegative, failed[0].Sentiment)
+	assert.True(t, failed[0].Failed)
+
+	positive, err := testAgent.FindRuns(ctx, TagFilter{Sentiment: SentimentPositive})
+	require.NoError(t, err)
+	require.Len(t, positive, 1)
+	assert.Equal(t, "run-1", positive[0].RunID)
+	assert.Equal(t, "greeting", positive[0].Topic)
+	assert.False(t, positive[0].ToolFailure)
+}
+
+func TestTaggingRequestOptions(t *testing.T) {
+	var mu sync.Mutex
+	var models []string
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "tenant-a", r.Header.Get("X-Tenant"))
+		if body.Model == "tagger" {
+			writeCompletion(w, "tagger", `{"topic":"greeting","sentiment":"positive"}`)
+			return
+		}
+		writeCompletion(w, "gpt-4o", "Hello!")
+	}, WithHeader("X-Tenant", "tenant-a"),
+		WithTagging(TagPolicy{Store: NewMemoryRunStore(), Model: "tagger", Topics: []string{"greeting"}}),
+		WithDebug(func(ctx context.Context, exchange WireExchange) {
+			var body struct {
+				Model string `json:"model"`
+			}
+			if json.Unmarshal([]byte(exchange.RequestBody), &body) == nil {
+				mu.Lock()
+				models = append(models, body.Model)
+				mu.Unlock()
+			}
+		}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi there")}, WithRunID("run-1"))
+	require.NoError(t, err)
+
+	// The tagger's request is captured like the run's own
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Contains(models, "tagger")
+	}, time.Second, 10*time.Millisecond)
+}
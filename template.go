@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// PromptTemplate is a system prompt or instructions with variables, written in
+// text/template syntax such as "You are helping {{.user}} on the {{.plan}} plan."
+// Every variable the template refers to is required when it is rendered.
+type PromptTemplate struct {
+	tmpl      *template.Template
+	variables []string
+}
+
+// NewPromptTemplate parses a prompt template
+func NewPromptTemplate(text string) (*PromptTemplate, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var variables []string
+	if tmpl.Tree != nil {
+		variables = templateVariables(tmpl.Tree.Root, variables)
+	}
+	slices.Sort(variables)
+	return &PromptTemplate{tmpl: tmpl, variables: slices.Compact(variables)}, nil
+}
+
+// MustPromptTemplate is like NewPromptTemplate but panics if text does not parse,
+// for templates defined in package variables
+func MustPromptTemplate(text string) *PromptTemplate {
+	tmpl, err := NewPromptTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+// Variables returns the names of the variables the template requires, sorted
+func (p *PromptTemplate) Variables() []string {
+	return append([]string{}, p.variables...)
+}
+
+// Render fills in the template's variables, failing with a *MissingVariablesError
+// if any is not given
+func (p *PromptTemplate) Render(vars map[string]any) (string, error) {
+	var missing []string
+	for _, name := range p.variables {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &MissingVariablesError{Variables: missing}
+	}
+	var b bytes.Buffer
+	if err := p.tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// MissingVariablesError is returned when a prompt template is rendered without
+// some of its variables
+type MissingVariablesError struct {
+	Variables []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return "prompt template: missing variables " + strings.Join(e.Variables, ", ")
+}
+
+// templateVariables appends the names of the top-level fields a template node
+// refers to. The bodies of range and with rebind the dot, so their fields are
+// not variables.
+func templateVariables(node parse.Node, variables []string) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return variables
+		}
+		for _, child := range n.Nodes {
+			variables = templateVariables(child, variables)
+		}
+	case *parse.ActionNode:
+		variables = templateVariables(n.Pipe, variables)
+	case *parse.TemplateNode:
+		variables = templateVariables(n.Pipe, variables)
+	case *parse.IfNode:
+		variables = templateVariables(n.Pipe, variables)
+		variables = templateVariables(n.List, variables)
+		variables = templateVariables(n.ElseList, variables)
+	case *parse.RangeNode:
+		variables = templateVariables(n.Pipe, variables)
+		variables = templateVariables(n.ElseList, variables)
+	case *parse.WithNode:
+		variables = templateVariables(n.Pipe, variables)
+		variables = templateVariables(n.ElseList, variables)
+	case *parse.PipeNode:
+		if n == nil {
+			return variables
+		}
+		for _, cmd := range n.Cmds {
+			variables = templateVariables(cmd, variables)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			variables = templateVariables(arg, variables)
+		}
+	case *parse.FieldNode:
+		variables = append(variables, n.Ident[0])
+	case *parse.ChainNode:
+		variables = templateVariables(n.Node, variables)
+	}
+	return variables
+}
+
+// promptVarsKey is the context key of the prompt variables of a request
+type promptVarsKey struct{}
+
+// ContextWithPromptVars returns a context carrying the variables prompt
+// templates are rendered with, such as the user's name and locale
+func ContextWithPromptVars(ctx context.Context, vars map[string]any) context.Context {
+	return context.WithValue(ctx, promptVarsKey{}, vars)
+}
+
+// PromptVarsFromContext returns the prompt variables carried by ctx
+func PromptVarsFromContext(ctx context.Context) map[string]any {
+	vars, _ := ctx.Value(promptVarsKey{}).(map[string]any)
+	return vars
+}
+
+// promptSource is a prompt template and how to find its variables for a request
+type promptSource struct {
+	template *PromptTemplate
+	vars     func(context.Context) map[string]any
+}
+
+// WithSystemPromptTemplate renders the system prompt from tmpl when each run
+// starts, with the variables vars returns for the run's context. A nil vars
+// uses PromptVarsFromContext. Runs missing a variable fail to start with a
+// *MissingVariablesError.
+func WithSystemPromptTemplate(tmpl *PromptTemplate, vars func(context.Context) map[string]any) AgentOption {
+	return func(a *Agent) {
+		a.systemPrompt = ""
+		a.systemPromptTemplate = &promptSource{template: tmpl, vars: vars}
+	}
+}
+
+// WithInstructionsTemplate renders the instructions from tmpl when each run
+// starts, like WithSystemPromptTemplate
+func WithInstructionsTemplate(tmpl *PromptTemplate, vars func(context.Context) map[string]any) AgentOption {
+	return func(a *Agent) {
+		a.instructions = ""
+		a.instructionsTemplate = &promptSource{template: tmpl, vars: vars}
+	}
+}
+
+// runPrompts is the system prompt and instructions of a run
+type runPrompts struct {
+	system       string
+	instructions string
+}
+
+// prompts returns the run's system prompt and instructions, rendering templates
+// with the variables for ctx
+func (agent *Agent) prompts(ctx context.Context) (runPrompts, error) {
+	prompts := runPrompts{system: agent.systemPrompt, instructions: agent.instructions}
+	var err error
+	if agent.systemPromptTemplate != nil {
+		if prompts.system, err = agent.systemPromptTemplate.render(ctx); err != nil {
+			return runPrompts{}, err
+		}
+	}
+	if agent.instructionsTemplate != nil {
+		if prompts.instructions, err = agent.instructionsTemplate.render(ctx); err != nil {
+			return runPrompts{}, err
+		}
+	}
+	return prompts, nil
+}
+
+// render renders the template with the variables for ctx
+func (s *promptSource) render(ctx context.Context) (string, error) {
+	vars := PromptVarsFromContext
+	if s.vars != nil {
+		vars = s.vars
+	}
+	return s.template.Render(vars(ctx))
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptTemplate(t *testing.T) {
+	tmpl, err := NewPromptTemplate(`You help {{.user}}{{if .company}} of {{.company}}{{end}}.{{range .rules}} {{.Text}}{{end}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"company", "rules", "user"}, tmpl.Variables())
+
+	rendered, err := tmpl.Render(map[string]any{
+		"user":    "Ada",
+		"company": "Acme",
+		"rules":   []struct{ Text string }{{Text: "Be brief."}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "You help Ada of Acme. Be brief.", rendered)
+
+	_, err = tmpl.Render(map[string]any{"company": ""})
+	var missingErr *MissingVariablesError
+	require.ErrorAs(t, err, &missingErr)
+	assert.Equal(t, []string{"rules", "user"}, missingErr.Variables)
+	assert.EqualError(t, err, "prompt template: missing variables rules, user")
+
+	_, err = NewPromptTemplate("{{.user")
+	assert.Error(t, err)
+	assert.Panics(t, func() { MustPromptTemplate("{{end}}") })
+}
+
+func TestSystemPromptTemplate(t *testing.T) {
+	var messages []map[string]any
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		messages = body.Messages
+		writeCompletion(w, "model", "hi")
+	}
+	testAgent := newTestAgent(t, "model", handler,
+		WithSystemPromptTemplate(MustPromptTemplate("You help {{.user}}."), nil),
+		WithInstructionsTemplate(MustPromptTemplate("Answer in {{.locale}}."), func(ctx context.Context) map[string]any {
+			return map[string]any{"locale": "French"}
+		}),
+	)
+
+	ctx := ContextWithPromptVars(context.Background(), map[string]any{"user": "Ada"})
+	_, err := testAgent.ChatCompletion(ctx, []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "You help Ada.", messages[0]["content"])
+	assert.Equal(t, "Answer in French.", messages[1]["content"])
+
+	// A run missing a variable fails before calling the provider
+	messages = nil
+	_, err = testAgent.StreamChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	var missingErr *MissingVariablesError
+	require.ErrorAs(t, err, &missingErr)
+	assert.Equal(t, []string{"user"}, missingErr.Variables)
+	assert.Nil(t, messages)
+
+	// A plain system prompt given later replaces the template
+	testAgent = newTestAgent(t, "model", handler,
+		WithSystemPromptTemplate(MustPromptTemplate("You help {{.user}}."), nil),
+		WithSystemPrompt("You help everyone."),
+	)
+	_, err = testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+	assert.Equal(t, "You help everyone.", messages[0]["content"])
+}
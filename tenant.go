@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/openai/openai-go/option"
+)
+
+// ErrAccessDenied is matched by every AccessError via errors.Is
+var ErrAccessDenied = errors.New("access denied")
+
+// Entitlement is what a tenant or role may use. Zero values leave the agent's
+// configuration unchanged.
+type Entitlement struct {
+	// Models are the permitted models. The agent's primary and fallback models that
+	// are not permitted are skipped. Empty permits every model.
+	Models []string
+	// Route replaces the agent's primary and fallback models, in order, for the
+	// tenant's runs. Routed models must also be permitted by Models.
+	Route []string
+	// MaxIterations caps the tool loop iterations of a run
+	MaxIterations int
+	// MaxCompletionTokens caps the tokens generated per request
+	MaxCompletionTokens int64
+	// MaxTotalTokens and MaxCostUSD cap the run budget set with WithRunBudget
+	MaxTotalTokens int64
+	MaxCostUSD     float64
+	// Tools restricts the tools the model may call in the tenant's runs
+	Tools ToolPolicy
+}
+
+// AccessPolicy maps tenants or roles to their entitlements. When an agent has a
+// policy, every run must name a tenant in the policy with WithTenant.
+type AccessPolicy map[string]Entitlement
+
+// AccessError is returned when a run's tenant is not entitled to use the agent
+type AccessError struct {
+	Tenant string
+	Reason string
+}
+
+func (e *AccessError) Error() string {
+	if e.Tenant == "" {
+		return fmt.Sprintf("access denied: %s", e.Reason)
+	}
+	return fmt.Sprintf("access denied for tenant %q: %s", e.Tenant, e.Reason)
+}
+
+func (e *AccessError) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+// WithAccessPolicy restricts the models and budgets available to each tenant
+func WithAccessPolicy(policy AccessPolicy) AgentOption {
+	return func(a *Agent) {
+		a.accessPolicy = policy
+	}
+}
+
+// WithTenant sets the tenant or role the run is made on behalf of
+func WithTenant(tenant string) RunOption {
+	return func(o *runOptions) {
+		o.tenant = tenant
+	}
+}
+
+// runPlan is the models, budgets, and request options of a single run
+type runPlan struct {
+	models              []string
+	maxIterations       int
+	maxCompletionTokens int64
+	maxTotalTokens      int64
+	maxCostUSD          float64
+	requestOptions      []option.RequestOption
+	// toolPolicies must each permit a tool call before it is executed
+	toolPolicies []ToolPolicy
+}
+
+// plan resolves the run's models and budgets from the agent's configuration and
+// the tenant's entitlement, rejecting runs the tenant is not entitled to
+func (agent *Agent) plan(options runOptions) (runPlan, error) {
+	plan := runPlan{
+		models:              append([]string{agent.model}, agent.fallbackModels...),
+		maxIterations:       agent.maxIterations,
+		maxCompletionTokens: agent.maxCompletionTokens,
+		maxTotalTokens:      agent.maxTotalTokens,
+		maxCostUSD:          agent.maxCostUSD,
+		requestOptions:      slices.Concat(agent.requestOptions, options.requestOptions),
+		toolPolicies:        addToolPolicies(nil, agent.toolPolicy),
+	}
+	plan.toolPolicies = addToolPolicies(plan.toolPolicies, options.toolPolicies...)
+	if agent.accessPolicy == nil {
+		return plan, nil
+	}
+
+	if options.tenant == "" {
+		return runPlan{}, &AccessError{Reason: "no tenant given"}
+	}
+	entitlement, ok := agent.accessPolicy[options.tenant]
+	if !ok {
+		return runPlan{}, &AccessError{Tenant: options.tenant, Reason: "unknown tenant"}
+	}
+
+	plan.toolPolicies = addToolPolicies(plan.toolPolicies, entitlement.Tools)
+	if len(entitlement.Route) > 0 {
+		plan.models = entitlement.Route
+	}
+	if len(entitlement.Models) > 0 {
+		plan.models = slices.DeleteFunc(slices.Clone(plan.models), func(model string) bool {
+			return !slices.Contains(entitlement.Models, model)
+		})
+	}
+	if len(plan.models) == 0 {
+		return runPlan{}, &AccessError{Tenant: options.tenant, Reason: "no permitted model"}
+	}
+
+	if entitlement.MaxIterations > 0 && entitlement.MaxIterations < plan.maxIterations {
+		plan.maxIterations = entitlement.MaxIterations
+	}
+	if n := entitlement.MaxCompletionTokens; n > 0 && (plan.maxCompletionTokens == 0 || n < plan.maxCompletionTokens) {
+		plan.maxCompletionTokens = n
+	}
+	if n := entitlement.MaxTotalTokens; n > 0 && (plan.maxTotalTokens == 0 || n < plan.maxTotalTokens) {
+		plan.maxTotalTokens = n
+	}
+	if n := entitlement.MaxCostUSD; n > 0 && (plan.maxCostUSD == 0 || n < plan.maxCostUSD) {
+		plan.maxCostUSD = n
+	}
+	return plan, nil
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessPolicyPlan(t *testing.T) {
+	testAgent := NewAgent("key", "http://localhost", "gpt-4o",
+		WithFallbackModels("gpt-4o-mini"),
+		WithMaxIterations(20),
+		WithMaxCompletionTokens(4000),
+		WithAccessPolicy(AccessPolicy{
+			"enterprise": {},
+			"free": {
+				Models:              []string{"gpt-4o-mini"},
+				MaxIterations:       5,
+				MaxCompletionTokens: 500,
+			},
+			"research": {Route: []string{"o3-mini", "gpt-4o"}, MaxIterations: 50},
+			"blocked":  {Models: []string{"gpt-3.5-turbo"}},
+		}),
+	)
+
+	tests := []struct {
+		tenant string
+		want   runPlan
+		reason string
+	}{
+		{tenant: "enterprise", want: runPlan{models: []string{"gpt-4o", "gpt-4o-mini"}, maxIterations: 20, maxCompletionTokens: 4000}},
+		{tenant: "free", want: runPlan{models: []string{"gpt-4o-mini"}, maxIterations: 5, maxCompletionTokens: 500}},
+		{tenant: "research", want: runPlan{models: []string{"o3-mini", "gpt-4o"}, maxIterations: 20, maxCompletionTokens: 4000}},
+		{tenant: "blocked", reason: "no permitted model"},
+		{tenant: "unknown", reason: "unknown tenant"},
+		{tenant: "", reason: "no tenant given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			plan, err := testAgent.plan(runOptions{tenant: tt.tenant})
+			if tt.reason != "" {
+				var accessErr *AccessError
+				require.ErrorAs(t, err, &accessErr)
+				assert.Equal(t, tt.tenant, accessErr.Tenant)
+				assert.Equal(t, tt.reason, accessErr.Reason)
+				assert.True(t, errors.Is(err, ErrAccessDenied))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+
+	// The agent's own models are not modified by filtering
+	assert.Equal(t, []string{"gpt-4o-mini"}, testAgent.fallbackModels)
+}
+
+func TestAccessPolicyEnforced(t *testing.T) {
+	var mu sync.Mutex
+	var requests []map[string]any
+	testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		requests = append(requests, body)
+		mu.Unlock()
+		writeCompletion(w, body["model"].(string), "hello")
+	},
+		WithRunStore(NewMemoryRunStore()),
+		WithAccessPolicy(AccessPolicy{
+			"free": {Models: []string{"gpt-4o-mini"}, Route: []string{"gpt-4o-mini"}, MaxCompletionTokens: 100},
+		}),
+	)
+	messages := []Message{UserTextMessage("Hello")}
+
+	completion, err := testAgent.ChatCompletion(context.Background(), messages, WithTenant("free"), WithRunID("run-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, completion.Messages)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "gpt-4o-mini", requests[0]["model"])
+	assert.Equal(t, float64(100), requests[0]["max_tokens"])
+
+	// Rejected at run start without reaching the provider, even for a stored run
+	_, err = testAgent.ChatCompletion(context.Background(), messages, WithTenant("guest"), WithRunID("run-1"))
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	_, err = testAgent.StreamChatCompletion(context.Background(), messages)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.Len(t, requests, 1)
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ToolTimeoutError is the cause of a tool call's context being canceled when
+// the tool runs past its timeout
+type ToolTimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool %s timed out after %s", e.Tool, e.Timeout)
+}
+
+// ToolWithTimeout is an optional interface for tools that set their own
+// execution timeout, overriding the agent's WithToolTimeout. A zero timeout
+// uses the agent's.
+type ToolWithTimeout interface {
+	Tool
+	Timeout() time.Duration
+}
+
+// WithToolTimeout bounds how long each tool call may run, so a hung tool cannot
+// stall the run. The tool's context is canceled with a *ToolTimeoutError cause
+// when the timeout passes, and the run goes on without waiting for the tool: the
+// model is told the call timed out and a warning response is sent on the stream.
+// Tools implementing ToolWithTimeout set their own timeout.
+func WithToolTimeout(d time.Duration) AgentOption {
+	return func(a *Agent) {
+		a.toolTimeout = d
+	}
+}
+
+// toolTimeoutFor returns the timeout for calls to tool, or zero for none
+func (agent *Agent) toolTimeoutFor(tool Tool) time.Duration {
+	if t, ok := tool.(ToolWithTimeout); ok {
+		if timeout := t.Timeout(); timeout > 0 {
+			return timeout
+		}
+	}
+	return agent.toolTimeout
+}
+
+// withToolTimeout returns the context a call to tool runs with, canceled with a
+// *ToolTimeoutError when the tool's timeout passes
+func (agent *Agent) withToolTimeout(ctx context.Context, tool Tool) (context.Context, context.CancelFunc) {
+	timeout := agent.toolTimeoutFor(tool)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, &ToolTimeoutError{Tool: tool.Name(), Timeout: timeout})
+}
+
+// NewWarningResponse creates a response carrying a warning about a run that
+// continues, such as a tool call that timed out
+func NewWarningResponse(warning string) Response {
+	return Response{
+		Kind:    ResponseKindWarning,
+		warning: warning,
+	}
+}
+
+// IsWarningResponse reports whether the response carries a warning
+func (r Response) IsWarningResponse() bool {
+	return r.Kind == ResponseKindWarning
+}
+
+// Warning returns the message of a warning response
+func (r Response) Warning() string {
+	if r.Kind != ResponseKindWarning {
+		return ""
+	}
+	return r.warning
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutTool is a tool with its own timeout
+type timeoutTool struct {
+	MockTool
+	timeout time.Duration
+}
+
+func (t timeoutTool) Timeout() time.Duration {
+	return t.timeout
+}
+
+func TestToolTimeout(t *testing.T) {
+	var toolMessage string
+	hang := func(ctx context.Context, input map[string]any) (any, error) {
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	stuck := func(ctx context.Context, input map[string]any) (any, error) {
+		<-release
+		return "late", nil
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if len(body.Messages) == 1 {
+			writeToolCall(w, "model", "search", `{}`)
+			return
+		}
+		toolMessage = body.Messages[len(body.Messages)-1].Content
+		writeCompletion(w, "model", "done")
+	}
+
+	tests := []struct {
+		name string
+		tool Tool
+		opts []AgentOption
+		want time.Duration
+	}{
+		{name: "agent timeout", tool: MockTool{name: "search", executeFunc: hang}, opts: []AgentOption{WithToolTimeout(50 * time.Millisecond)}, want: 50 * time.Millisecond},
+		{name: "tool timeout", tool: timeoutTool{MockTool{name: "search", executeFunc: hang}, 20 * time.Millisecond}, opts: []AgentOption{WithToolTimeout(time.Hour)}, want: 20 * time.Millisecond},
+		{name: "tool ignoring cancellation", tool: MockTool{name: "search", executeFunc: stuck}, opts: []AgentOption{WithToolTimeout(20 * time.Millisecond)}, want: 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testAgent := newTestAgent(t, "model", handler, append(tt.opts, WithTools([]Tool{tt.tool}))...)
+
+			completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+			require.NoError(t, err)
+			timeoutErr := &ToolTimeoutError{Tool: "search", Timeout: tt.want}
+			assert.Equal(t, []string{timeoutErr.Error()}, completion.Warnings)
+			assert.Contains(t, toolMessage, timeoutErr.Error())
+			assert.Equal(t, []string{"done"}, completion.Messages)
+		})
+	}
+}
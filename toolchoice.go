@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// ToolChoice controls whether the model calls tools on the first iteration of a
+// run. Later iterations let the model decide, so a forced call cannot loop. The
+// zero value leaves the choice to the provider's default.
+type ToolChoice struct {
+	mode     string
+	function string
+}
+
+var (
+	// ToolChoiceAuto lets the model decide whether to call tools
+	ToolChoiceAuto = ToolChoice{mode: "auto"}
+	// ToolChoiceNone prevents the model from calling tools
+	ToolChoiceNone = ToolChoice{mode: "none"}
+	// ToolChoiceRequired makes the model call at least one tool
+	ToolChoiceRequired = ToolChoice{mode: "required"}
+)
+
+// ToolChoiceFunction makes the model call the named tool
+func ToolChoiceFunction(name string) ToolChoice {
+	return ToolChoice{function: name}
+}
+
+// WithToolChoice sets the tool choice for the first iteration of every run
+func WithToolChoice(choice ToolChoice) AgentOption {
+	return func(a *Agent) {
+		a.toolChoice = choice
+	}
+}
+
+// WithRunToolChoice overrides the agent's tool choice for a single run
+func WithRunToolChoice(choice ToolChoice) RunOption {
+	return func(o *runOptions) {
+		o.toolChoice = &choice
+	}
+}
+
+// toolChoiceParam returns the tool choice for a run, checking that a forced tool exists
+func (agent *Agent) toolChoiceParam(options runOptions, tools []Tool) (openai.ChatCompletionToolChoiceOptionUnionParam, error) {
+	choice := agent.toolChoice
+	if options.toolChoice != nil {
+		choice = *options.toolChoice
+	}
+
+	// Providers reject a tool choice on requests without tools
+	if len(tools) == 0 {
+		if choice.function != "" {
+			return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("tool choice %q: agent has no tools", choice.function)
+		}
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, nil
+	}
+
+	switch {
+	case choice.function != "":
+		for _, tool := range tools {
+			if tool.Name() == choice.function {
+				return openai.ChatCompletionToolChoiceOptionParamOfChatCompletionNamedToolChoice(
+					openai.ChatCompletionNamedToolChoiceFunctionParam{Name: choice.function},
+				), nil
+			}
+		}
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, fmt.Errorf("tool choice %q: no such tool", choice.function)
+	case choice.mode != "":
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(choice.mode)}, nil
+	default:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{}, nil
+	}
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolChoice(t *testing.T) {
+	lookup := MockTool{name: "lookup", description: "Look up a record", parameters: Parameters{Properties: map[string]any{}}}
+
+	tests := []struct {
+		name      string
+		agentOpts []AgentOption
+		runOpts   []RunOption
+		want      any
+	}{
+		{name: "default", want: nil},
+		{name: "required", agentOpts: []AgentOption{WithToolChoice(ToolChoiceRequired)}, want: "required"},
+		{name: "none", agentOpts: []AgentOption{WithToolChoice(ToolChoiceNone)}, want: "none"},
+		{
+			name:      "function",
+			agentOpts: []AgentOption{WithToolChoice(ToolChoiceFunction("lookup"))},
+			want:      map[string]any{"type": "function", "function": map[string]any{"name": "lookup"}},
+		},
+		{
+			name:      "run override",
+			agentOpts: []AgentOption{WithToolChoice(ToolChoiceRequired)},
+			runOpts:   []RunOption{WithRunToolChoice(ToolChoiceAuto)},
+			want:      "auto",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var choices []any
+			testAgent := newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				choices = append(choices, body["tool_choice"])
+				if len(choices) == 1 {
+					writeToolCall(w, "gpt-4o", "lookup", `{}`)
+					return
+				}
+				writeCompletion(w, "gpt-4o", "done")
+			}, append([]AgentOption{WithTools([]Tool{lookup})}, tt.agentOpts...)...)
+
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, tt.runOpts...)
+			require.NoError(t, err)
+
+			// Only the first iteration is constrained, so a forced call cannot loop
+			require.Len(t, choices, 2)
+			assert.Equal(t, tt.want, choices[0])
+			assert.Nil(t, choices[1])
+		})
+	}
+}
+
+func TestToolChoiceUnknownTool(t *testing.T) {
+	lookup := MockTool{name: "lookup"}
+
+	testAgent := NewAgent("key", "http://localhost", "gpt-4o", WithTools([]Tool{lookup}), WithToolChoice(ToolChoiceFunction("search")))
+	_, err := testAgent.StreamChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	assert.ErrorContains(t, err, `tool choice "search": no such tool`)
+
+	noTools := NewAgent("key", "http://localhost", "gpt-4o")
+	_, err = noTools.StreamChatCompletion(context.Background(), []Message{UserTextMessage("Hi")}, WithRunToolChoice(ToolChoiceFunction("lookup")))
+	assert.ErrorContains(t, err, "agent has no tools")
+
+	// Modes are dropped rather than rejected when there are no tools
+	params, err := noTools.toolChoiceParam(newRunOptions([]RunOption{WithRunToolChoice(ToolChoiceRequired)}), nil)
+	require.NoError(t, err)
+	assert.False(t, params.OfAuto.Valid())
+}
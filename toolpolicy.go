@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/openai/openai-go"
+)
+
+// ToolPolicy restricts which tools the model may call. Tools are named as the
+// model sees them, or matched by path.Match patterns such as "fs__*". A call
+// the policy does not permit is not executed; the model gets a policy
+// violation as the tool's result instead, so it can answer without the tool.
+type ToolPolicy struct {
+	// Allow are the tools the model may call. Empty allows every tool.
+	Allow []string
+	// Deny are the tools the model may not call, even if allowed
+	Deny []string
+}
+
+// Permits reports whether the policy lets the model call the named tool
+func (p ToolPolicy) Permits(name string) bool {
+	if len(p.Allow) > 0 && !matchesToolPattern(p.Allow, name) {
+		return false
+	}
+	return !matchesToolPattern(p.Deny, name)
+}
+
+// matchesToolPattern reports whether name matches one of patterns
+func matchesToolPattern(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, err := path.Match(pattern, name)
+		return pattern == name || (err == nil && matched)
+	})
+}
+
+// addToolPolicies adds the policies that restrict anything to a run's policies
+func addToolPolicies(policies []ToolPolicy, add ...ToolPolicy) []ToolPolicy {
+	for _, policy := range add {
+		if len(policy.Allow) > 0 || len(policy.Deny) > 0 {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// WithToolPolicy restricts the tools the model may call in every run. Tenants
+// are further restricted by the Tools of their Entitlement, and single runs by
+// WithRunToolPolicy; a call must be permitted by each of them.
+func WithToolPolicy(policy ToolPolicy) AgentOption {
+	return func(a *Agent) {
+		a.toolPolicy = policy
+	}
+}
+
+// WithRunToolPolicy restricts the tools the model may call in a single run,
+// for example to those the requesting user may use
+func WithRunToolPolicy(policy ToolPolicy) RunOption {
+	return func(o *runOptions) {
+		o.toolPolicies = append(o.toolPolicies, policy)
+	}
+}
+
+// checkToolPolicies returns the result telling the model a call is not
+// permitted, or an empty string if it is
+func (agent *Agent) checkToolPolicies(ctx context.Context, plan runPlan, toolCall openai.ChatCompletionMessageToolCall) string {
+	name := toolCall.Function.Name
+	for _, policy := range plan.toolPolicies {
+		if !policy.Permits(name) {
+			agent.logger.WarnContext(ctx, "agent tool call denied by policy", "tool", name, "call_id", toolCall.ID)
+			return fmt.Sprintf("Error: policy violation: tool %s is not permitted for this request. Do not call it again; answer without it.", name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolPolicyPermits(t *testing.T) {
+	policy := ToolPolicy{Allow: []string{"fs__*", "search"}, Deny: []string{"fs__delete"}}
+
+	assert.True(t, policy.Permits("fs__read"))
+	assert.True(t, policy.Permits("search"))
+	assert.False(t, policy.Permits("fs__delete"))
+	assert.False(t, policy.Permits("shell"))
+	assert.True(t, ToolPolicy{}.Permits("shell"))
+	assert.False(t, ToolPolicy{Deny: []string{"shell"}}.Permits("shell"))
+}
+
+// newPolicyTestAgent creates an agent whose model calls delete_file once and
+// then answers, recording the tool message it got back
+func newPolicyTestAgent(t *testing.T, executed *atomic.Bool, toolMessage *string, opts ...AgentOption) *Agent {
+	t.Helper()
+	tool := MockTool{name: "delete_file", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		executed.Store(true)
+		return "deleted", nil
+	}}
+	var calls atomic.Int32
+	return newTestAgent(t, "gpt-4o", func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			writeToolCall(w, "gpt-4o", "delete_file", `{}`)
+			return
+		}
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*toolMessage = body.Messages[len(body.Messages)-1].Content
+		writeCompletion(w, "gpt-4o", "I can't delete files")
+	}, append([]AgentOption{WithTools([]Tool{tool})}, opts...)...)
+}
+
+func TestRunToolPolicy(t *testing.T) {
+	var executed atomic.Bool
+	var toolMessage string
+	testAgent := newPolicyTestAgent(t, &executed, &toolMessage)
+
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("delete it")},
+		WithRunToolPolicy(ToolPolicy{Deny: []string{"delete_*"}}))
+
+	require.NoError(t, err)
+	assert.False(t, executed.Load())
+	assert.Contains(t, toolMessage, "policy violation")
+	require.Len(t, completion.ToolCalls, 1)
+	assert.Equal(t, toolMessage, completion.ToolCalls[0].Result)
+	assert.Equal(t, []string{"tool delete_file call denied by policy"}, completion.Warnings)
+	assert.Equal(t, []string{"I can't delete files"}, completion.Messages)
+}
+
+func TestToolPolicyPermitsCall(t *testing.T) {
+	var executed atomic.Bool
+	var toolMessage string
+	testAgent := newPolicyTestAgent(t, &executed, &toolMessage, WithToolPolicy(ToolPolicy{Allow: []string{"delete_file"}}))
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("delete it")},
+		WithRunToolPolicy(ToolPolicy{Deny: []string{"shell"}}))
+
+	require.NoError(t, err)
+	assert.True(t, executed.Load())
+	assert.Equal(t, "deleted", toolMessage)
+}
+
+func TestTenantToolPolicy(t *testing.T) {
+	policy := WithAccessPolicy(AccessPolicy{
+		"admin":  {},
+		"viewer": {Tools: ToolPolicy{Allow: []string{"read_*"}}},
+	})
+	for tenant, permitted := range map[string]bool{"admin": true, "viewer": false} {
+		t.Run(tenant, func(t *testing.T) {
+			var executed atomic.Bool
+			var toolMessage string
+			testAgent := newPolicyTestAgent(t, &executed, &toolMessage, policy)
+
+			_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("delete it")}, WithTenant(tenant))
+			require.NoError(t, err)
+			assert.Equal(t, permitted, executed.Load())
+		})
+	}
+}
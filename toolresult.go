@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ToolResult is a tool result with attachments: text for the tool message plus
+// images and files for the model to look at, such as a screenshot or a rendered
+// report. By default, tool messages carry only the text and the attachments
+// follow the turn's tool results in a user message, which every vision model
+// accepts. With WithMultimodalToolMessages, they are sent as content parts of
+// the tool message itself.
+type ToolResult struct {
+	Text   string
+	Images []Image
+	Files  []File
+}
+
+// ImageResult is an optional interface for tool results that carry images, such
+// as generated pictures. The result itself is sent as the tool message, encoded
+// as JSON, and its images are attached like those of a ToolResult. Tools may
+// also return an Image directly.
+type ImageResult interface {
+	ResultImages() []Image
+}
+
+// splitToolResult separates the attachments from a tool result, describing them
+// in the tool message when the result has no text of its own
+func splitToolResult(result any) (any, ToolResult) {
+	var attachments ToolResult
+	switch r := result.(type) {
+	case ToolResult:
+		attachments = r
+	case *ToolResult:
+		if r == nil {
+			return result, ToolResult{}
+		}
+		attachments = *r
+	case ImageResult:
+		return result, ToolResult{Images: r.ResultImages()}
+	case Image:
+		attachments = ToolResult{Images: []Image{r}}
+	case *Image:
+		if r == nil {
+			return result, ToolResult{}
+		}
+		attachments = ToolResult{Images: []Image{*r}}
+	default:
+		return result, ToolResult{}
+	}
+
+	text := attachments.Text
+	if text == "" {
+		var names []string
+		for _, image := range attachments.Images {
+			names = append(names, image.Name)
+		}
+		for _, file := range attachments.Files {
+			names = append(names, file.Name)
+		}
+		text = "Attached: " + strings.Join(names, ", ")
+	}
+	attachments.Text = ""
+	return text, attachments
+}
+
+// hasAttachments reports whether the result carries images or files
+func (r ToolResult) hasAttachments() bool {
+	return len(r.Images) > 0 || len(r.Files) > 0
+}
+
+// WithMultimodalToolMessages sends the images and files of tool results as
+// content parts of the tool message itself, after its text, rather than in a
+// user message after the turn's tool results. Use it only with providers or
+// OpenAI-compatible servers that accept image and file parts in tool messages;
+// OpenAI's Chat Completions API accepts only text there.
+func WithMultimodalToolMessages() AgentOption {
+	return func(a *Agent) {
+		a.multimodalToolMessages = true
+	}
+}
+
+// toolMessageWithParts creates a tool message whose content is the text
+// followed by the attachments as content parts
+func toolMessageWithParts(content string, toolCallID string, attachments ToolResult) openai.ChatCompletionMessageParamUnion {
+	msg := openai.ToolMessage(content, toolCallID)
+	if !attachments.hasAttachments() {
+		return msg
+	}
+	// The SDK types tool message content as text parts only, so the parts
+	// replace the content field when the request is encoded
+	parts := append([]openai.ChatCompletionContentPartUnionParam{{
+		OfText: &openai.ChatCompletionContentPartTextParam{Text: content},
+	}}, attachmentParts(attachments)...)
+	msg.OfTool.SetExtraFields(map[string]any{"content": parts})
+	return msg
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chartResult is a tool result carrying a rendered chart
+type chartResult struct {
+	Title string `json:"title"`
+	chart Image
+}
+
+func (r chartResult) ResultImages() []Image {
+	return []Image{r.chart}
+}
+
+// runToolResult runs a conversation in which the model calls a tool returning
+// result, and returns the messages of the request that follows the call
+func runToolResult(t *testing.T, result any, opts ...AgentOption) []map[string]any {
+	t.Helper()
+	var messages []map[string]any
+	testAgent := newTestAgent(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		messages = body.Messages
+		if len(messages) == 1 {
+			writeToolCall(w, "model", "chart", `{}`)
+			return
+		}
+		writeCompletion(w, "model", "Revenue is up")
+	}, append(opts, WithTools([]Tool{MockTool{
+		name: "chart",
+		executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+			return result, nil
+		},
+	}}))...)
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Chart revenue")})
+	require.NoError(t, err)
+	return messages
+}
+
+func TestToolResultAttachments(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nchart")
+	pdf := []byte("%PDF-1.7 report")
+	imageURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	imagePart := map[string]any{"type": "image_url", "image_url": map[string]any{"url": imageURL}}
+	filePart := map[string]any{"type": "file", "file": map[string]any{"file_data": base64.StdEncoding.EncodeToString(pdf), "filename": "report.pdf"}}
+
+	tests := []struct {
+		name    string
+		result  any
+		content string
+		parts   []any
+	}{
+		{
+			name:    "tool result",
+			result:  ToolResult{Text: "Rendered the chart and report", Images: []Image{{Data: png, Name: "chart.png"}}, Files: []File{{Data: pdf, Name: "report.pdf"}}},
+			content: "Rendered the chart and report",
+			parts:   []any{imagePart, filePart},
+		},
+		{
+			name:    "tool result without text",
+			result:  &ToolResult{Files: []File{{Data: pdf, Name: "report.pdf"}}},
+			content: "Attached: report.pdf",
+			parts:   []any{filePart},
+		},
+		{
+			name:    "image result",
+			result:  chartResult{Title: "Revenue", chart: Image{Data: png, Name: "chart.png"}},
+			content: `{"title":"Revenue"}`,
+			parts:   []any{imagePart},
+		},
+		{
+			name:    "image",
+			result:  Image{Data: png, Name: "chart.png"},
+			content: "Attached: chart.png",
+			parts:   []any{imagePart},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The tool message answers the call and the attachments follow it
+			messages := runToolResult(t, tt.result)
+			require.Len(t, messages, 4)
+			assert.Equal(t, "tool", messages[2]["role"])
+			assert.Equal(t, tt.content, messages[2]["content"])
+			assert.Equal(t, "user", messages[3]["role"])
+			assert.Equal(t, tt.parts, messages[3]["content"])
+
+			// Multimodal tool messages carry the attachments themselves
+			messages = runToolResult(t, tt.result, WithMultimodalToolMessages())
+			require.Len(t, messages, 3)
+			assert.Equal(t, "tool", messages[2]["role"])
+			assert.Equal(t, "call_1", messages[2]["tool_call_id"])
+			want := append([]any{map[string]any{"type": "text", "text": tt.content}}, tt.parts...)
+			assert.Equal(t, want, messages[2]["content"])
+		})
+	}
+}
+
+func TestToolResultText(t *testing.T) {
+	messages := runToolResult(t, ToolResult{Text: "No chart needed"}, WithMultimodalToolMessages())
+	require.Len(t, messages, 3)
+	assert.Equal(t, "No chart needed", messages[2]["content"])
+
+	messages = runToolResult(t, ToolResult{Text: "No chart needed"})
+	require.Len(t, messages, 3)
+	assert.Equal(t, "No chart needed", messages[2]["content"])
+}
@@ -13,3 +13,11 @@ type Parameters struct {
 	Properties map[string]any `json:"properties"`
 	Required   []string       `json:"required"`
 }
+
+// ToolWithExamples is an optional interface for tools that provide example
+// invocations. Examples are attached to the tool's parameter schema to improve
+// call accuracy for complex tools.
+type ToolWithExamples interface {
+	Tool
+	Examples() []map[string]any
+}
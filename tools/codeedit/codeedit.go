@@ -0,0 +1,231 @@
+// Package codeedit provides a tool that lets coding agents edit files with
+// search/replace blocks or unified diffs, tolerating the whitespace and
+// line-number drift common in model-generated edits.
+package codeedit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Validator checks the edited content of a file before it is written
+type Validator func(path string, content []byte) error
+
+// Option is a functional option for configuring a Tool
+type Option func(*Tool)
+
+// WithRoot restricts edits to files under dir (default: the working directory)
+func WithRoot(dir string) Option {
+	return func(t *Tool) {
+		t.root = dir
+	}
+}
+
+// WithValidator rejects edits whose result fails validation, such as a syntax check
+func WithValidator(validator Validator) Option {
+	return func(t *Tool) {
+		t.validators = append(t.validators, validator)
+	}
+}
+
+// Tool is an agent.Tool that applies edits to files
+type Tool struct {
+	root       string
+	validators []Validator
+}
+
+// Result is the outcome of an edit
+type Result struct {
+	Path    string `json:"path"`
+	Created bool   `json:"created"`
+	Edits   int    `json:"edits"`
+}
+
+// New creates a code edit tool
+func New(opts ...Option) *Tool {
+	t := &Tool{root: "."}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "edit_file"
+}
+
+func (t *Tool) Description() string {
+	return "Edit a file with search/replace blocks or a unified diff. Each search block must match " +
+		"exactly one location in the file; include enough surrounding lines to make it unique. " +
+		"Use an empty search block to create a new file."
+}
+
+func (t *Tool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The path of the file to edit, relative to the project root",
+			},
+			"edits": map[string]any{
+				"type":        "array",
+				"description": "Search/replace blocks applied in order",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"search":  map[string]any{"type": "string", "description": "The exact lines to replace"},
+						"replace": map[string]any{"type": "string", "description": "The lines to put in their place"},
+					},
+					"required": []string{"search", "replace"},
+				},
+			},
+			"diff": map[string]any{
+				"type":        "string",
+				"description": "A unified diff for the file, used instead of edits",
+			},
+		},
+		Required: []string{"path"},
+	}
+}
+
+func (t *Tool) Examples() []map[string]any {
+	return []map[string]any{
+		{
+			"path": "main.go",
+			"edits": []map[string]any{
+				{"search": "\tfmt.Println(\"hello\")\n", "replace": "\tfmt.Println(\"hello, world\")\n"},
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	path, ok := input["path"].(string)
+	if !ok || path == "" {
+		return nil, errors.New("path must be a non-empty string")
+	}
+	diff, _ := input["diff"].(string)
+	edits, err := parseEdits(input["edits"])
+	if err != nil {
+		return nil, err
+	}
+	if (diff == "") == (len(edits) == 0) {
+		return nil, errors.New("provide exactly one of edits or diff")
+	}
+
+	fullPath, err := t.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	original, mode, err := readFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated string
+	count := len(edits)
+	if diff != "" {
+		updated, err = ApplyDiff(string(original), diff)
+		if hunks, parseErr := parseDiff(diff); parseErr == nil {
+			count = len(hunks)
+		}
+	} else {
+		updated, err = ApplyEdits(string(original), edits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, validate := range t.validators {
+		if err := validate(path, []byte(updated)); err != nil {
+			return nil, fmt.Errorf("edit rejected: %w", err)
+		}
+	}
+
+	if err := writeFile(fullPath, []byte(updated), mode); err != nil {
+		return nil, err
+	}
+
+	return Result{Path: path, Created: original == nil, Edits: count}, nil
+}
+
+// resolve returns the path joined to the root, rejecting paths that escape it
+func (t *Tool) resolve(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("path %q must be relative", path)
+	}
+	full := filepath.Join(t.root, path)
+	rel, err := filepath.Rel(t.root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the project root", path)
+	}
+	return full, nil
+}
+
+// parseEdits converts the edits argument decoded from JSON into Edits
+func parseEdits(value any) ([]Edit, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var edits []Edit
+	if err := json.Unmarshal(data, &edits); err != nil {
+		return nil, fmt.Errorf("edits must be a list of search/replace objects: %w", err)
+	}
+	return edits, nil
+}
+
+// readFile returns the file's content and mode, or nil content for a file that does not exist
+func readFile(path string) ([]byte, fs.FileMode, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0o644, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, info.Mode().Perm(), nil
+}
+
+// writeFile replaces the file atomically so a failed write never leaves it truncated
+func writeFile(path string, data []byte, mode fs.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".codeedit-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,163 @@
+package codeedit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const source = `package main
+
+func main() {
+	fmt.Println("hello")
+	if ok {
+		fmt.Println("ok")
+	}
+}
+`
+
+func TestApplyEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []Edit
+		want  string
+		err   error
+	}{
+		{
+			name:  "exact match",
+			edits: []Edit{{Search: "\tfmt.Println(\"hello\")\n", Replace: "\tfmt.Println(\"hi\")\n"}},
+			want:  "package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n\tif ok {\n\t\tfmt.Println(\"ok\")\n\t}\n}\n",
+		},
+		{
+			name:  "trailing whitespace ignored",
+			edits: []Edit{{Search: "\tfmt.Println(\"hello\")  \n", Replace: "\tfmt.Println(\"hi\")\n"}},
+			want:  "package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n\tif ok {\n\t\tfmt.Println(\"ok\")\n\t}\n}\n",
+		},
+		{
+			name:  "indentation reapplied",
+			edits: []Edit{{Search: "if ok {\n\tfmt.Println(\"ok\")\n}\n", Replace: "if !ok {\n\treturn\n}\n"}},
+			want:  "package main\n\nfunc main() {\n\tfmt.Println(\"hello\")\n\tif !ok {\n\t\treturn\n\t}\n}\n",
+		},
+		{
+			name:  "edits apply in order",
+			edits: []Edit{{Search: "\"hello\"", Replace: "\"a\""}, {Search: "\"a\"", Replace: "\"b\""}},
+			want:  "package main\n\nfunc main() {\n\tfmt.Println(\"b\")\n\tif ok {\n\t\tfmt.Println(\"ok\")\n\t}\n}\n",
+		},
+		{
+			name:  "no match",
+			edits: []Edit{{Search: "fmt.Println(\"bye\")", Replace: ""}},
+			err:   ErrNoMatch,
+		},
+		{
+			name:  "ambiguous match",
+			edits: []Edit{{Search: "fmt.Println", Replace: "log.Println"}},
+			err:   ErrAmbiguousMatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyEdits(source, tt.edits)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				var matchErr *MatchError
+				assert.True(t, errors.As(err, &matchErr))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyDiff(t *testing.T) {
+	// Line numbers are stale and the blank context line has lost its leading space
+	diff := `--- a/main.go
++++ b/main.go
+@@ -10,4 +10,4 @@ func main() {
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hi")
+ 	if ok {
+@@ -6,2 +6,3 @@
+ 		fmt.Println("ok")
++		return
+ 	}
+`
+	got, err := ApplyDiff(source, diff)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n\tif ok {\n\t\tfmt.Println(\"ok\")\n\t\treturn\n\t}\n}\n", got)
+
+	_, err = ApplyDiff(source, "@@ -1,1 +1,1 @@\n-package lib\n+package main\n")
+	assert.ErrorIs(t, err, ErrNoMatch)
+}
+
+func TestToolExecute(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte(source), 0o600))
+	tool := New(WithRoot(root))
+
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"path":  "main.go",
+		"edits": []any{map[string]any{"search": "\"hello\"", "replace": "\"hi\""}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Path: "main.go", Edits: 1}, result)
+
+	data, err := os.ReadFile(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "fmt.Println(\"hi\")")
+	info, err := os.Stat(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	result, err = tool.Execute(context.Background(), map[string]any{
+		"path": "pkg/new.go",
+		"diff": "--- /dev/null\n+++ b/pkg/new.go\n@@ -0,0 +1,1 @@\n+package pkg\n",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Path: "pkg/new.go", Created: true, Edits: 1}, result)
+	data, err = os.ReadFile(filepath.Join(root, "pkg", "new.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package pkg\n", string(data))
+}
+
+func TestToolRejectsInvalidEdits(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte(source), 0o644))
+	tool := New(WithRoot(root), WithValidator(func(path string, content []byte) error {
+		return errors.New("syntax error")
+	}))
+
+	tests := []struct {
+		name  string
+		input map[string]any
+	}{
+		{name: "escapes root", input: map[string]any{"path": "../main.go", "diff": "@@ -1 +1 @@\n+x\n"}},
+		{name: "absolute path", input: map[string]any{"path": "/etc/passwd", "diff": "@@ -1 +1 @@\n+x\n"}},
+		{name: "edits and diff", input: map[string]any{"path": "main.go", "diff": "@@ -1 +1 @@\n+x\n", "edits": []any{map[string]any{"search": "a", "replace": "b"}}}},
+		{name: "validator fails", input: map[string]any{"path": "main.go", "edits": []any{map[string]any{"search": "\"hello\"", "replace": "\"hi\""}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tool.Execute(context.Background(), tt.input)
+			assert.Error(t, err)
+		})
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, source, string(data))
+}
+
+func TestToolGeneratedInputs(t *testing.T) {
+	agenttest.GenerateToolTests(t, []agent.Tool{New(WithRoot(t.TempDir()))})
+}
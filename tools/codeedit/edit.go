@@ -0,0 +1,266 @@
+package codeedit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNoMatch is matched by MatchError when the search text is not in the file
+var ErrNoMatch = errors.New("search text not found")
+
+// ErrAmbiguousMatch is matched by MatchError when the search text occurs more than once
+var ErrAmbiguousMatch = errors.New("search text matches more than once")
+
+// Edit replaces one occurrence of Search with Replace. An empty Search on an
+// empty file writes Replace as the file's content.
+type Edit struct {
+	Search  string `json:"search"`
+	Replace string `json:"replace"`
+}
+
+// MatchError is returned when an edit's search text cannot be located exactly once
+type MatchError struct {
+	// Index is the position of the failing edit or diff hunk
+	Index  int
+	Search string
+	Err    error
+}
+
+func (e *MatchError) Error() string {
+	return fmt.Sprintf("edit %d: %v", e.Index, e.Err)
+}
+
+func (e *MatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyEdits applies search/replace edits in order. Each search text must occur
+// exactly once; when there is no exact match, lines are compared ignoring trailing
+// whitespace and then indentation, and the replacement is re-indented to fit.
+func ApplyEdits(content string, edits []Edit) (string, error) {
+	for i, edit := range edits {
+		if edit.Search == "" {
+			if content != "" {
+				return "", &MatchError{Index: i, Err: errors.New("search text is empty but the file is not")}
+			}
+			content = edit.Replace
+			continue
+		}
+		updated, err := replace(content, edit.Search, edit.Replace, -1)
+		if err != nil {
+			return "", &MatchError{Index: i, Search: edit.Search, Err: err}
+		}
+		content = updated
+	}
+	return content, nil
+}
+
+// ApplyDiff applies the hunks of a unified diff for a single file. Hunks are
+// located by their context and removed lines rather than their line numbers,
+// which only break ties, so diffs with stale offsets still apply.
+func ApplyDiff(content string, diff string) (string, error) {
+	hunks, err := parseDiff(diff)
+	if err != nil {
+		return "", err
+	}
+	for i, hunk := range hunks {
+		if hunk.search == "" {
+			// Only a new file can be written without context to anchor the hunk
+			if content != "" {
+				return "", &MatchError{Index: i, Err: errors.New("hunk has no context lines")}
+			}
+			content = hunk.replace
+			continue
+		}
+		updated, err := replace(content, hunk.search, hunk.replace, hunk.line)
+		if err != nil {
+			return "", &MatchError{Index: i, Search: hunk.search, Err: err}
+		}
+		content = updated
+	}
+	return content, nil
+}
+
+// hunk is a diff hunk as the text it removes and the text it inserts
+type hunk struct {
+	line    int
+	search  string
+	replace string
+}
+
+// parseDiff parses unified diff hunks, ignoring file headers
+func parseDiff(diff string) ([]hunk, error) {
+	var hunks []hunk
+	var current *hunk
+	var search, replace []string
+	finish := func() {
+		if current != nil {
+			current.search = joinLines(search)
+			current.replace = joinLines(replace)
+			hunks = append(hunks, *current)
+		}
+		search, replace = nil, nil
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			finish()
+			current = &hunk{line: hunkStart(line)}
+		case current == nil:
+			// Skip headers such as "diff --git", "---", and "+++"
+		case strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file"
+		case strings.HasPrefix(line, "-"):
+			search = append(search, line[1:])
+		case strings.HasPrefix(line, "+"):
+			replace = append(replace, line[1:])
+		case strings.HasPrefix(line, " "):
+			search = append(search, line[1:])
+			replace = append(replace, line[1:])
+		case line == "":
+			// Some generators drop the space on blank context lines
+			search = append(search, "")
+			replace = append(replace, "")
+		default:
+			return nil, fmt.Errorf("invalid diff line %q", line)
+		}
+	}
+	finish()
+
+	if len(hunks) == 0 {
+		return nil, errors.New("diff contains no hunks")
+	}
+	return hunks, nil
+}
+
+// hunkStart returns the original start line from a hunk header, or 0 if it is missing
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "-") {
+		return 0
+	}
+	start, _, _ := strings.Cut(fields[1][1:], ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// replace substitutes the single occurrence of search in content. near is the
+// 1-based line the match is expected at, used to choose between several matches,
+// or -1 to require a unique match.
+func replace(content string, search string, replacement string, near int) (string, error) {
+	switch strings.Count(content, search) {
+	case 1:
+		return strings.Replace(content, search, replacement, 1), nil
+	case 0:
+	default:
+		if near < 0 {
+			return "", ErrAmbiguousMatch
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	searchLines := strings.Split(strings.TrimSuffix(search, "\n"), "\n")
+	replaceLines := strings.Split(strings.TrimSuffix(replacement, "\n"), "\n")
+	if replacement == "" {
+		replaceLines = nil
+	}
+
+	for _, normalize := range []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return strings.TrimRight(s, " \t\r") },
+		strings.TrimSpace,
+	} {
+		matches := findLines(lines, searchLines, normalize)
+		if len(matches) == 0 {
+			continue
+		}
+		start := matches[0]
+		if len(matches) > 1 {
+			if near < 0 {
+				return "", ErrAmbiguousMatch
+			}
+			start = closest(matches, near-1)
+		}
+		indented := reindent(replaceLines, searchLines, lines[start:start+len(searchLines)])
+		result := append(append(append([]string{}, lines[:start]...), indented...), lines[start+len(searchLines):]...)
+		return strings.Join(result, "\n"), nil
+	}
+	return "", ErrNoMatch
+}
+
+// findLines returns the start of every run of lines equal to search after normalization
+func findLines(lines []string, search []string, normalize func(string) string) []int {
+	var matches []int
+	for start := 0; start+len(search) <= len(lines); start++ {
+		matched := true
+		for i, line := range search {
+			if normalize(lines[start+i]) != normalize(line) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, start)
+		}
+	}
+	return matches
+}
+
+// closest returns the match nearest to line
+func closest(matches []int, line int) int {
+	best := matches[0]
+	for _, match := range matches[1:] {
+		if abs(match-line) < abs(best-line) {
+			best = match
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// reindent shifts replacement lines by the difference between the indentation
+// of the search text and the indentation of the lines it matched
+func reindent(replacement []string, search []string, matched []string) []string {
+	for i, line := range search {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		from, to := indentation(line), indentation(matched[i])
+		if from == to {
+			return replacement
+		}
+		indented := make([]string, len(replacement))
+		for j, line := range replacement {
+			if strings.TrimSpace(line) == "" {
+				indented[j] = line
+				continue
+			}
+			indented[j] = to + strings.TrimPrefix(line, from)
+		}
+		return indented
+	}
+	return replacement
+}
+
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
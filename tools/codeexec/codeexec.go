@@ -0,0 +1,345 @@
+// Package codeexec provides a tool that runs model-generated Python or
+// JavaScript inside a constrained sandbox and returns its output.
+package codeexec
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Language is a programming language supported by the sandbox
+type Language string
+
+const (
+	Python     Language = "python"
+	JavaScript Language = "javascript"
+)
+
+// Result is the outcome of running a program
+type Result struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	TimedOut bool   `json:"timed_out"`
+	// Truncated is set when stdout or stderr was cut at Limits.OutputBytes
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Runner executes a program in a sandbox
+type Runner interface {
+	Run(ctx context.Context, language Language, code string) (Result, error)
+}
+
+// Limits constrains a sandboxed program. A zero value for any field other than
+// OutputBytes disables that limit.
+type Limits struct {
+	// Timeout is the maximum wall-clock time of a run
+	Timeout time.Duration
+	// MemoryBytes is the maximum memory available to the program
+	MemoryBytes int64
+	// OutputBytes is the most of each of stdout and stderr kept (default: 1 MiB)
+	OutputBytes int
+	// Network allows the program to access the network
+	Network bool
+}
+
+// defaultOutputBytes is the output kept per stream when Limits.OutputBytes is zero
+const defaultOutputBytes = 1 << 20
+
+// ErrNoUserNamespaces is returned by SubprocessRunner when network isolation is
+// requested on a host that does not allow unprivileged user namespaces
+var ErrNoUserNamespaces = errors.New("network isolation needs unprivileged user namespaces (unshare --net --map-root-user), which this host does not allow; set Limits.Network or use DockerRunner")
+
+// Option is a functional option for configuring a Tool
+type Option func(*Tool)
+
+// WithRunner sets the sandbox used to run programs (default: SubprocessRunner)
+func WithRunner(runner Runner) Option {
+	return func(t *Tool) {
+		t.runner = runner
+	}
+}
+
+// WithLanguages restricts the languages the tool accepts (default: Python and JavaScript)
+func WithLanguages(languages ...Language) Option {
+	return func(t *Tool) {
+		t.languages = languages
+	}
+}
+
+// Tool is an agent.Tool that executes code in a sandbox
+type Tool struct {
+	runner    Runner
+	languages []Language
+}
+
+// New creates a code execution tool
+func New(opts ...Option) *Tool {
+	t := &Tool{
+		runner:    &SubprocessRunner{Limits: Limits{Timeout: 30 * time.Second}},
+		languages: []Language{Python, JavaScript},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "execute_code"
+}
+
+func (t *Tool) Description() string {
+	return "Execute a program in an isolated sandbox and return its stdout, stderr, and exit code"
+}
+
+func (t *Tool) Parameters() agent.Parameters {
+	languages := make([]string, len(t.languages))
+	for i, language := range t.languages {
+		languages[i] = string(language)
+	}
+	return agent.Parameters{
+		Properties: map[string]any{
+			"language": map[string]any{
+				"type":        "string",
+				"enum":        languages,
+				"description": "The programming language of the code",
+			},
+			"code": map[string]any{
+				"type":        "string",
+				"description": "The complete program to run",
+			},
+		},
+		Required: []string{"language", "code"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	language, _ := input["language"].(string)
+	code, ok := input["code"].(string)
+	if !ok {
+		return nil, fmt.Errorf("code must be a string")
+	}
+	if !t.supports(Language(language)) {
+		return nil, fmt.Errorf("unsupported language %q", language)
+	}
+
+	result, err := t.runner.Run(ctx, Language(language), code)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t *Tool) supports(language Language) bool {
+	for _, l := range t.languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// SubprocessRunner runs programs as local subprocesses with resource limits applied
+// through ulimit. Network isolation uses unshare and is only available on Linux
+// hosts that allow unprivileged user namespaces; elsewhere Run fails with
+// ErrNoUserNamespaces unless Limits.Network is set.
+type SubprocessRunner struct {
+	Limits Limits
+	// Python and Node are the interpreter paths (default: python3 and node)
+	Python string
+	Node   string
+	// Env adds KEY=value variables to the program's environment, which
+	// otherwise holds only PATH, HOME (the program's working directory), and LANG
+	Env []string
+
+	isolation    sync.Once
+	isolationErr error
+}
+
+func (r *SubprocessRunner) Run(ctx context.Context, language Language, code string) (Result, error) {
+	dir, err := os.MkdirTemp("", "codeexec-")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	var interpreter, file string
+	switch language {
+	case Python:
+		interpreter, file = valueOr(r.Python, "python3"), "main.py"
+	case JavaScript:
+		interpreter, file = valueOr(r.Node, "node"), "main.js"
+	default:
+		return Result{}, fmt.Errorf("unsupported language %q", language)
+	}
+
+	path := filepath.Join(dir, file)
+	if err := os.WriteFile(path, []byte(code), 0o600); err != nil {
+		return Result{}, err
+	}
+
+	// Apply resource limits in a shell before exec'ing the interpreter
+	script := ""
+	if r.Limits.MemoryBytes > 0 {
+		script += "ulimit -v " + strconv.FormatInt(r.Limits.MemoryBytes/1024, 10) + "; "
+	}
+	if r.Limits.Timeout > 0 {
+		script += "ulimit -t " + strconv.Itoa(int(r.Limits.Timeout.Seconds())+1) + "; "
+	}
+	script += `exec "$0" "$1"`
+
+	args := []string{"sh", "-c", script, interpreter, path}
+	if !r.Limits.Network {
+		if err := r.checkIsolation(); err != nil {
+			return Result{}, err
+		}
+		args = append([]string{"unshare", "--net", "--map-root-user"}, args...)
+	}
+
+	env := append([]string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir, "LANG=C.UTF-8"}, r.Env...)
+	return run(ctx, r.Limits, dir, env, args)
+}
+
+// checkIsolation probes once whether unshare can create a network namespace
+// without privileges
+func (r *SubprocessRunner) checkIsolation() error {
+	r.isolation.Do(func() {
+		if err := exec.Command("unshare", "--net", "--map-root-user", "true").Run(); err != nil {
+			r.isolationErr = fmt.Errorf("%w: %v", ErrNoUserNamespaces, err)
+		}
+	})
+	return r.isolationErr
+}
+
+// DockerRunner runs programs in a throwaway Docker container. A container
+// still running when the run times out or is canceled is removed.
+type DockerRunner struct {
+	Limits Limits
+	// PythonImage and NodeImage are the container images (default: python:3-slim and node:lts-slim)
+	PythonImage string
+	NodeImage   string
+	// Env sets KEY=value variables in the container
+	Env []string
+}
+
+func (r *DockerRunner) Run(ctx context.Context, language Language, code string) (Result, error) {
+	var image string
+	var command []string
+	switch language {
+	case Python:
+		image, command = valueOr(r.PythonImage, "python:3-slim"), []string{"python3", "-c", code}
+	case JavaScript:
+		image, command = valueOr(r.NodeImage, "node:lts-slim"), []string{"node", "-e", code}
+	default:
+		return Result{}, fmt.Errorf("unsupported language %q", language)
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	name := "codeexec-" + hex.EncodeToString(id)
+
+	args := []string{"docker", "run", "--rm", "--name", name, "--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges"}
+	for _, variable := range r.Env {
+		args = append(args, "--env", variable)
+	}
+	if !r.Limits.Network {
+		args = append(args, "--network=none")
+	}
+	if r.Limits.MemoryBytes > 0 {
+		args = append(args, "--memory="+strconv.FormatInt(r.Limits.MemoryBytes, 10))
+	}
+	args = append(args, image)
+	args = append(args, command...)
+
+	result, err := run(ctx, r.Limits, "", nil, args)
+	if result.TimedOut || ctx.Err() != nil {
+		// Killing the docker client leaves the container running
+		removeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		exec.CommandContext(removeCtx, "docker", "rm", "--force", name).Run()
+	}
+	return result, err
+}
+
+// run executes args with env (the host's environment when nil) and captures
+// the result, killing the process when the timeout elapses
+func run(ctx context.Context, limits Limits, dir string, env []string, args []string) (Result, error) {
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	outputBytes := limits.OutputBytes
+	if outputBytes <= 0 {
+		outputBytes = defaultOutputBytes
+	}
+	stdout, stderr := &cappedWriter{limit: outputBytes}, &cappedWriter{limit: outputBytes}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Don't wait on output pipes held open by the program's own children
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result := Result{
+		Stdout:    string(stdout.data),
+		Stderr:    string(stderr.data),
+		TimedOut:  errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+// cappedWriter keeps the first limit bytes written to it and discards the rest
+type cappedWriter struct {
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.data); len(p) > room {
+		w.data = append(w.data, p[:room]...)
+		w.truncated = true
+	} else {
+		w.data = append(w.data, p...)
+	}
+	// Report the whole write so the program keeps running
+	return len(p), nil
+}
+
+func valueOr(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,153 @@
+package codeexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubprocessRunner(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+
+	tool := New(
+		WithRunner(&SubprocessRunner{Limits: Limits{Timeout: 5 * time.Second, Network: true}}),
+		WithLanguages(Python),
+	)
+
+	tests := []struct {
+		name     string
+		code     string
+		stdout   string
+		exitCode int
+	}{
+		{name: "prints output", code: "print(1 + 1)", stdout: "2\n"},
+		{name: "reports exit code", code: "import sys; sys.exit(3)", exitCode: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(context.Background(), map[string]any{
+				"language": "python",
+				"code":     tt.code,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.stdout, result.(Result).Stdout)
+			assert.Equal(t, tt.exitCode, result.(Result).ExitCode)
+		})
+	}
+}
+
+func TestSubprocessRunnerTimeout(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+
+	runner := &SubprocessRunner{Limits: Limits{Timeout: 500 * time.Millisecond, Network: true}}
+
+	result, err := runner.Run(context.Background(), Python, "while True: pass")
+	require.NoError(t, err)
+	assert.True(t, result.TimedOut)
+}
+
+func TestUnsupportedLanguage(t *testing.T) {
+	tool := New(WithLanguages(Python))
+
+	_, err := tool.Execute(context.Background(), map[string]any{
+		"language": "javascript",
+		"code":     "console.log(1)",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"python"}, tool.Parameters().Properties["language"].(map[string]any)["enum"])
+}
+
+func TestSubprocessRunnerEnvironment(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-secret")
+
+	runner := &SubprocessRunner{Limits: Limits{Timeout: 5 * time.Second, Network: true}, Env: []string{"GREETING=hi"}}
+
+	result, err := runner.Run(context.Background(), Python, "import os; print(os.environ.get('OPENAI_API_KEY'), os.environ['GREETING'])")
+	require.NoError(t, err)
+	assert.Equal(t, "None hi\n", result.Stdout)
+}
+
+func TestSubprocessRunnerOutputLimit(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+
+	runner := &SubprocessRunner{Limits: Limits{Timeout: 5 * time.Second, OutputBytes: 100, Network: true}}
+
+	result, err := runner.Run(context.Background(), Python, "print('x' * 100000)")
+	require.NoError(t, err)
+	assert.Len(t, result.Stdout, 100)
+	assert.True(t, result.Truncated)
+	assert.Zero(t, result.ExitCode)
+}
+
+func TestSubprocessRunnerNetworkIsolation(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+
+	runner := &SubprocessRunner{Limits: Limits{Timeout: 5 * time.Second}}
+
+	result, err := runner.Run(context.Background(), Python, "import socket; print([name for _, name in socket.if_nameindex()])")
+	if errors.Is(err, ErrNoUserNamespaces) {
+		t.Skip("host does not allow unprivileged user namespaces")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "['lo']\n", result.Stdout)
+}
+
+// fakeCommand puts an executable shell script named name first on PATH
+func fakeCommand(t *testing.T, name string, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSubprocessRunnerWithoutUserNamespaces(t *testing.T) {
+	fakeCommand(t, "unshare", "echo 'unshare: unshare failed: Operation not permitted' >&2; exit 1\n")
+
+	runner := &SubprocessRunner{Limits: Limits{Timeout: 5 * time.Second}}
+
+	_, err := runner.Run(context.Background(), Python, "print(1)")
+	assert.ErrorIs(t, err, ErrNoUserNamespaces)
+}
+
+func TestDockerRunnerRemovesContainerOnTimeout(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeCommand(t, "docker", `echo "$@" >> `+calls+`
+if [ "$1" = run ]; then exec sleep 10; fi
+`)
+
+	runner := &DockerRunner{Limits: Limits{Timeout: 200 * time.Millisecond}, Env: []string{"GREETING=hi"}}
+
+	result, err := runner.Run(context.Background(), Python, "while True: pass")
+	require.NoError(t, err)
+	assert.True(t, result.TimedOut)
+
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	name := regexp.MustCompile(`--name (codeexec-\w+)`).FindStringSubmatch(lines[0])
+	require.NotNil(t, name, lines[0])
+	assert.Contains(t, lines[0], "--env GREETING=hi")
+	assert.Equal(t, "rm --force "+name[1], lines[1])
+}
@@ -0,0 +1,329 @@
+// Package filesystem provides tools that let agents read, write, list, and
+// delete files under a root directory. Paths are resolved with os.Root, so
+// neither ".." components nor symbolic links can reach outside the root.
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Option is a functional option for configuring a FileSystem
+type Option func(*FileSystem)
+
+// WithRoot restricts the tools to files under dir (default: the working directory)
+func WithRoot(dir string) Option {
+	return func(f *FileSystem) {
+		f.root = dir
+	}
+}
+
+// WithReadOnly leaves out the write and delete tools
+func WithReadOnly() Option {
+	return func(f *FileSystem) {
+		f.readOnly = true
+	}
+}
+
+// WithMaxFileSize caps the bytes returned by a read and accepted by a write (default: 1 MiB)
+func WithMaxFileSize(n int64) Option {
+	return func(f *FileSystem) {
+		f.maxFileSize = n
+	}
+}
+
+// WithMaxEntries caps the entries returned by a listing (default: 1000)
+func WithMaxEntries(n int) Option {
+	return func(f *FileSystem) {
+		f.maxEntries = n
+	}
+}
+
+// FileSystem is a root directory exposed to agents through file tools
+type FileSystem struct {
+	root        string
+	readOnly    bool
+	maxFileSize int64
+	maxEntries  int
+}
+
+// New creates a file system rooted at the working directory, or the directory set with WithRoot
+func New(opts ...Option) *FileSystem {
+	f := &FileSystem{root: ".", maxFileSize: 1 << 20, maxEntries: 1000}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// Tools returns the read_file and list_files tools, plus write_file and
+// delete_file unless the file system is read-only
+func (f *FileSystem) Tools() []agent.Tool {
+	tools := []agent.Tool{&readTool{f}, &listTool{f}}
+	if !f.readOnly {
+		tools = append(tools, &writeTool{f}, &deleteTool{f})
+	}
+	return tools
+}
+
+// FileContent is the result of reading a file
+type FileContent struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Size    int64  `json:"size"`
+	// Truncated reports whether the content was cut to the maximum file size
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Entry is a file or directory in a listing
+type Entry struct {
+	Path string `json:"path"`
+	// Type is "file", "dir", or "symlink"
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// Listing is the result of listing a directory
+type Listing struct {
+	Path    string  `json:"path"`
+	Entries []Entry `json:"entries"`
+	// Truncated reports whether entries were cut to the maximum count
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// WriteResult is the result of writing a file
+type WriteResult struct {
+	Path    string `json:"path"`
+	Bytes   int    `json:"bytes"`
+	Created bool   `json:"created"`
+}
+
+// DeleteResult is the result of deleting a file or empty directory
+type DeleteResult struct {
+	Path    string `json:"path"`
+	Deleted bool   `json:"deleted"`
+}
+
+// SizeError is returned when content to be written exceeds the maximum file size
+type SizeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, over the %d byte limit", e.Path, e.Size, e.Limit)
+}
+
+// Read returns the content of the file at name, relative to the root, up to the maximum file size
+func (f *FileSystem) Read(ctx context.Context, name string) (FileContent, error) {
+	if err := ctx.Err(); err != nil {
+		return FileContent{}, err
+	}
+	name, err := clean(name)
+	if err != nil {
+		return FileContent{}, err
+	}
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return FileContent{}, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(name)
+	if err != nil {
+		return FileContent{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return FileContent{}, err
+	}
+	if info.IsDir() {
+		return FileContent{}, fmt.Errorf("%s is a directory; use list_files", name)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, f.maxFileSize))
+	if err != nil {
+		return FileContent{}, err
+	}
+	// Binary content is useless to the model and wastes its context
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return FileContent{}, fmt.Errorf("%s appears to be a binary file", name)
+	}
+	return FileContent{
+		Path:      name,
+		Content:   string(data),
+		Size:      info.Size(),
+		Truncated: info.Size() > int64(len(data)),
+	}, nil
+}
+
+// List returns the entries of the directory at name, relative to the root, and
+// of its subdirectories if recursive is set
+func (f *FileSystem) List(ctx context.Context, name string, recursive bool) (Listing, error) {
+	name, err := clean(name)
+	if err != nil {
+		return Listing{}, err
+	}
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return Listing{}, err
+	}
+	defer root.Close()
+
+	listing := Listing{Path: name, Entries: []Entry{}}
+	err = fs.WalkDir(root.FS(), name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p == name {
+			if !d.IsDir() {
+				return fmt.Errorf("%s is not a directory", name)
+			}
+			return nil
+		}
+		if len(listing.Entries) == f.maxEntries {
+			listing.Truncated = true
+			return fs.SkipAll
+		}
+
+		entry := Entry{Path: p, Type: "file"}
+		switch {
+		case d.IsDir():
+			entry.Type = "dir"
+		case d.Type()&fs.ModeSymlink != 0:
+			entry.Type = "symlink"
+		default:
+			if info, err := d.Info(); err == nil {
+				entry.Size = info.Size()
+			}
+		}
+		listing.Entries = append(listing.Entries, entry)
+
+		if d.IsDir() && !recursive {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return Listing{}, err
+	}
+	return listing, nil
+}
+
+// Write replaces the content of the file at name, relative to the root,
+// creating it and its parent directories if needed
+func (f *FileSystem) Write(ctx context.Context, name string, content string) (WriteResult, error) {
+	if f.readOnly {
+		return WriteResult{}, errors.New("file system is read-only")
+	}
+	if err := ctx.Err(); err != nil {
+		return WriteResult{}, err
+	}
+	name, err := clean(name)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	if int64(len(content)) > f.maxFileSize {
+		return WriteResult{}, &SizeError{Path: name, Size: int64(len(content)), Limit: f.maxFileSize}
+	}
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	defer root.Close()
+
+	if err := mkdirAll(root, path.Dir(name)); err != nil {
+		return WriteResult{}, err
+	}
+	_, err = root.Lstat(name)
+	created := errors.Is(err, fs.ErrNotExist)
+
+	file, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return WriteResult{}, err
+	}
+	if err := file.Close(); err != nil {
+		return WriteResult{}, err
+	}
+	return WriteResult{Path: name, Bytes: len(content), Created: created}, nil
+}
+
+// Delete removes the file or empty directory at name, relative to the root
+func (f *FileSystem) Delete(ctx context.Context, name string) (DeleteResult, error) {
+	if f.readOnly {
+		return DeleteResult{}, errors.New("file system is read-only")
+	}
+	if err := ctx.Err(); err != nil {
+		return DeleteResult{}, err
+	}
+	name, err := clean(name)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	if name == "." {
+		return DeleteResult{}, errors.New("cannot delete the root directory")
+	}
+	root, err := os.OpenRoot(f.root)
+	if err != nil {
+		return DeleteResult{}, err
+	}
+	defer root.Close()
+
+	if err := root.Remove(name); err != nil {
+		return DeleteResult{}, err
+	}
+	return DeleteResult{Path: name, Deleted: true}, nil
+}
+
+// clean converts a path from the model to a slash-separated path relative to
+// the root. Escapes through ".." are rejected here; escapes through symbolic
+// links are rejected by os.Root.
+func clean(name string) (string, error) {
+	if name == "" {
+		return ".", nil
+	}
+	name = filepath.ToSlash(name)
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("path %q must be relative to the root", name)
+	}
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("path %q is outside the root", name)
+	}
+	return name, nil
+}
+
+// mkdirAll creates dir and its missing parents inside root
+func mkdirAll(root *os.Root, dir string) error {
+	if dir == "." {
+		return nil
+	}
+	if err := mkdirAll(root, path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := root.Mkdir(dir, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
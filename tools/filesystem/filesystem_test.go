@@ -0,0 +1,140 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolNames(tools []agent.Tool) []string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name())
+	}
+	return names
+}
+
+func findTool(t *testing.T, tools []agent.Tool, name string) agent.Tool {
+	t.Helper()
+	for _, tool := range tools {
+		if tool.Name() == name {
+			return tool
+		}
+	}
+	t.Fatalf("tool %s not found", name)
+	return nil
+}
+
+func TestTools(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	tools := New(WithRoot(root)).Tools()
+	assert.Equal(t, []string{"read_file", "list_files", "write_file", "delete_file"}, toolNames(tools))
+
+	written, err := findTool(t, tools, "write_file").Execute(ctx, map[string]any{"path": "src/app/main.go", "content": "package main\n"})
+	require.NoError(t, err)
+	assert.Equal(t, WriteResult{Path: "src/app/main.go", Bytes: 13, Created: true}, written)
+
+	written, err = findTool(t, tools, "write_file").Execute(ctx, map[string]any{"path": "./src/app/main.go", "content": "package app\n"})
+	require.NoError(t, err)
+	assert.False(t, written.(WriteResult).Created)
+
+	read, err := findTool(t, tools, "read_file").Execute(ctx, map[string]any{"path": "src/app/main.go"})
+	require.NoError(t, err)
+	assert.Equal(t, FileContent{Path: "src/app/main.go", Content: "package app\n", Size: 12}, read)
+
+	list, err := findTool(t, tools, "list_files").Execute(ctx, map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, Listing{Path: ".", Entries: []Entry{{Path: "src", Type: "dir"}}}, list)
+
+	list, err = findTool(t, tools, "list_files").Execute(ctx, map[string]any{"path": "src", "recursive": true})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Path: "src/app", Type: "dir"},
+		{Path: "src/app/main.go", Type: "file", Size: 12},
+	}, list.(Listing).Entries)
+
+	_, err = findTool(t, tools, "delete_file").Execute(ctx, map[string]any{"path": "src/app"})
+	assert.Error(t, err, "non-empty directories are not deleted")
+	deleted, err := findTool(t, tools, "delete_file").Execute(ctx, map[string]any{"path": "src/app/main.go"})
+	require.NoError(t, err)
+	assert.Equal(t, DeleteResult{Path: "src/app/main.go", Deleted: true}, deleted)
+	assert.NoFileExists(t, filepath.Join(root, "src/app/main.go"))
+}
+
+func TestPathTraversal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(root, "link.txt")))
+	require.NoError(t, os.Symlink(dir, filepath.Join(root, "parent")))
+	fsys := New(WithRoot(root))
+
+	for _, name := range []string{"../secret.txt", "a/../../secret.txt", filepath.Join(dir, "secret.txt"), "link.txt", "parent/secret.txt"} {
+		_, err := fsys.Read(ctx, name)
+		assert.Error(t, err, name)
+		_, err = fsys.Write(ctx, name, "overwritten")
+		assert.Error(t, err, name)
+		_, err = fsys.List(ctx, name, false)
+		assert.Error(t, err, name)
+	}
+	_, err := fsys.Delete(ctx, ".")
+	assert.EqualError(t, err, "cannot delete the root directory")
+
+	data, err := os.ReadFile(filepath.Join(dir, "secret.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(data))
+
+	// Symbolic links are listed but not followed
+	listing, err := fsys.List(ctx, "", true)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Path: "link.txt", Type: "symlink"}, {Path: "parent", Type: "symlink"}}, listing.Entries)
+}
+
+func TestLimits(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("a", 20)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "image.png"), []byte{0x89, 'P', 'N', 'G', 0, 0}, 0o644))
+	fsys := New(WithRoot(root), WithMaxFileSize(10), WithMaxEntries(1))
+
+	content, err := fsys.Read(ctx, "big.txt")
+	require.NoError(t, err)
+	assert.Equal(t, FileContent{Path: "big.txt", Content: strings.Repeat("a", 10), Size: 20, Truncated: true}, content)
+
+	_, err = fsys.Read(ctx, "image.png")
+	assert.EqualError(t, err, "image.png appears to be a binary file")
+
+	_, err = fsys.Write(ctx, "new.txt", strings.Repeat("b", 11))
+	var sizeErr *SizeError
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, int64(10), sizeErr.Limit)
+
+	listing, err := fsys.List(ctx, ".", false)
+	require.NoError(t, err)
+	assert.Len(t, listing.Entries, 1)
+	assert.True(t, listing.Truncated)
+}
+
+func TestReadOnly(t *testing.T) {
+	fsys := New(WithRoot(t.TempDir()), WithReadOnly())
+	assert.Equal(t, []string{"read_file", "list_files"}, toolNames(fsys.Tools()))
+
+	_, err := fsys.Write(context.Background(), "a.txt", "a")
+	assert.EqualError(t, err, "file system is read-only")
+	_, err = fsys.Delete(context.Background(), "a.txt")
+	assert.EqualError(t, err, "file system is read-only")
+}
+
+func TestToolGeneratedInputs(t *testing.T) {
+	agenttest.GenerateToolTests(t, New(WithRoot(t.TempDir())).Tools())
+}
@@ -0,0 +1,145 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// pathSchema is the path parameter shared by the tools
+func pathSchema(description string) agent.Schema {
+	return agent.StringSchema(description + ", relative to the root directory")
+}
+
+// stringArg returns a required non-empty string argument
+func stringArg(input map[string]any, name string) (string, error) {
+	value, ok := input[name].(string)
+	if !ok || value == "" {
+		return "", errors.New(name + " must be a non-empty string")
+	}
+	return value, nil
+}
+
+// readTool reads a file
+type readTool struct {
+	fs *FileSystem
+}
+
+func (t *readTool) Name() string {
+	return "read_file"
+}
+
+func (t *readTool) Description() string {
+	return "Read the content of a text file. Content over the size limit is truncated."
+}
+
+func (t *readTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"path": pathSchema("The file to read"),
+		},
+		Required: []string{"path"},
+	}
+}
+
+func (t *readTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	name, err := stringArg(input, "path")
+	if err != nil {
+		return nil, err
+	}
+	return t.fs.Read(ctx, name)
+}
+
+// listTool lists a directory
+type listTool struct {
+	fs *FileSystem
+}
+
+func (t *listTool) Name() string {
+	return "list_files"
+}
+
+func (t *listTool) Description() string {
+	return "List the files and directories in a directory, optionally including subdirectories."
+}
+
+func (t *listTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"path":      pathSchema("The directory to list (default: the root)"),
+			"recursive": agent.BooleanSchema("Whether to include the contents of subdirectories"),
+		},
+	}
+}
+
+func (t *listTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	name, _ := input["path"].(string)
+	recursive, _ := input["recursive"].(bool)
+	return t.fs.List(ctx, name, recursive)
+}
+
+// writeTool writes a file
+type writeTool struct {
+	fs *FileSystem
+}
+
+func (t *writeTool) Name() string {
+	return "write_file"
+}
+
+func (t *writeTool) Description() string {
+	return "Write a text file, replacing its content. Missing parent directories are created."
+}
+
+func (t *writeTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"path":    pathSchema("The file to write"),
+			"content": agent.StringSchema("The complete new content of the file"),
+		},
+		Required: []string{"path", "content"},
+	}
+}
+
+func (t *writeTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	name, err := stringArg(input, "path")
+	if err != nil {
+		return nil, err
+	}
+	content, ok := input["content"].(string)
+	if !ok {
+		return nil, errors.New("content must be a string")
+	}
+	return t.fs.Write(ctx, name, content)
+}
+
+// deleteTool deletes a file or empty directory
+type deleteTool struct {
+	fs *FileSystem
+}
+
+func (t *deleteTool) Name() string {
+	return "delete_file"
+}
+
+func (t *deleteTool) Description() string {
+	return "Delete a file or an empty directory."
+}
+
+func (t *deleteTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"path": pathSchema("The file or empty directory to delete"),
+		},
+		Required: []string{"path"},
+	}
+}
+
+func (t *deleteTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	name, err := stringArg(input, "path")
+	if err != nil {
+		return nil, err
+	}
+	return t.fs.Delete(ctx, name)
+}
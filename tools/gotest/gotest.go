@@ -0,0 +1,257 @@
+// Package gotest provides a tool that runs a project's tests and reports
+// failures as structured results, closing the edit-test loop for coding agents.
+package gotest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Failure is a failed test or package
+type Failure struct {
+	Package string `json:"package"`
+	// Test is empty when the package failed outside a test, for example in TestMain
+	Test   string `json:"test,omitempty"`
+	Output string `json:"output"`
+}
+
+// Result is the outcome of a test run
+type Result struct {
+	Command  string    `json:"command"`
+	Passed   bool      `json:"passed"`
+	ExitCode int       `json:"exit_code"`
+	TimedOut bool      `json:"timed_out,omitempty"`
+	Passes   int       `json:"passes"`
+	Skips    int       `json:"skips"`
+	Failures []Failure `json:"failures,omitempty"`
+	// BuildErrors holds compiler output for packages that failed to build
+	BuildErrors string `json:"build_errors,omitempty"`
+	// Output is the raw output of commands whose output is not go test -json
+	Output string `json:"output,omitempty"`
+	// Truncated reports whether any output was cut to the configured maximum
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Option is a functional option for configuring a Tool
+type Option func(*Tool)
+
+// WithDir sets the directory tests run in (default: the working directory)
+func WithDir(dir string) Option {
+	return func(t *Tool) {
+		t.dir = dir
+	}
+}
+
+// WithTimeout sets the maximum duration of a test run (default: 5 minutes)
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Tool) {
+		t.timeout = timeout
+	}
+}
+
+// WithMaxOutput caps the output returned per failure and for build errors, in bytes (default: 4096)
+func WithMaxOutput(n int) Option {
+	return func(t *Tool) {
+		t.maxOutput = n
+	}
+}
+
+// WithCommand replaces go test with a custom command, such as a make target. Its
+// output is returned raw unless it is go test -json output.
+func WithCommand(name string, args ...string) Option {
+	return func(t *Tool) {
+		t.command = append([]string{name}, args...)
+	}
+}
+
+// Tool is an agent.Tool that runs tests
+type Tool struct {
+	dir       string
+	timeout   time.Duration
+	maxOutput int
+	command   []string
+}
+
+// New creates a test runner tool
+func New(opts ...Option) *Tool {
+	t := &Tool{
+		timeout:   5 * time.Minute,
+		maxOutput: 4096,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "run_tests"
+}
+
+func (t *Tool) Description() string {
+	if t.command != nil {
+		return "Run the project's tests and report whether they passed"
+	}
+	return "Run go test and report passing counts, failed tests with their output, and build errors"
+}
+
+func (t *Tool) Parameters() agent.Parameters {
+	if t.command != nil {
+		return agent.Parameters{Properties: map[string]any{}}
+	}
+	return agent.Parameters{
+		Properties: map[string]any{
+			"packages": agent.StringSchema("The package pattern to test (default: ./...)"),
+			"run":      agent.StringSchema("Only run tests matching this regular expression"),
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	args := t.command
+	if args == nil {
+		packages, _ := input["packages"].(string)
+		if packages == "" {
+			packages = "./..."
+		}
+		if strings.HasPrefix(packages, "-") {
+			return nil, fmt.Errorf("invalid package pattern %q", packages)
+		}
+		args = []string{"go", "test", "-json"}
+		if run, _ := input["run"].(string); run != "" {
+			args = append(args, "-run", run)
+		}
+		args = append(args, packages)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = t.dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	result := parse(stdout.Bytes(), t.maxOutput)
+	result.Command = strings.Join(args, " ")
+	result.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return nil, err
+	}
+	result.Passed = result.ExitCode == 0 && !result.TimedOut
+
+	// go test writes build failures to stderr on older toolchains
+	if stderr.Len() > 0 {
+		errorsOutput := result.BuildErrors + stderr.String()
+		result.BuildErrors, result.Truncated = truncate(errorsOutput, t.maxOutput, result.Truncated)
+	}
+
+	return result, nil
+}
+
+// event is a go test -json event
+type event struct {
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Output     string
+}
+
+// parse builds a result from go test -json output, falling back to raw output
+func parse(output []byte, maxOutput int) Result {
+	var result Result
+	var raw strings.Builder
+	var build strings.Builder
+	testOutput := map[[2]string]*strings.Builder{}
+	isJSON := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var e event
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			raw.Write(line)
+			raw.WriteByte('\n')
+			continue
+		}
+		isJSON = true
+
+		key := [2]string{e.Package, e.Test}
+		switch e.Action {
+		case "build-output":
+			build.WriteString(e.Output)
+		case "output":
+			if testOutput[key] == nil {
+				testOutput[key] = &strings.Builder{}
+			}
+			testOutput[key].WriteString(e.Output)
+		case "pass":
+			if e.Test != "" {
+				result.Passes++
+			}
+		case "skip":
+			if e.Test != "" {
+				result.Skips++
+			}
+		case "fail":
+			// Packages fail when any test fails; only report them without a failed test
+			if e.Test == "" && hasFailedTest(result.Failures, e.Package) {
+				continue
+			}
+			var text string
+			if b := testOutput[key]; b != nil {
+				text = b.String()
+			}
+			text, result.Truncated = truncate(text, maxOutput, result.Truncated)
+			result.Failures = append(result.Failures, Failure{Package: e.Package, Test: e.Test, Output: text})
+		}
+	}
+
+	result.BuildErrors, result.Truncated = truncate(build.String(), maxOutput, result.Truncated)
+	if !isJSON {
+		result.Output, result.Truncated = truncate(raw.String(), maxOutput, result.Truncated)
+	} else if raw.Len() > 0 {
+		result.BuildErrors, result.Truncated = truncate(result.BuildErrors+raw.String(), maxOutput, result.Truncated)
+	}
+	return result
+}
+
+func hasFailedTest(failures []Failure, pkg string) bool {
+	for _, failure := range failures {
+		if failure.Package == pkg && failure.Test != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate keeps the end of s, where test failures and summaries are, within max bytes
+func truncate(s string, max int, truncated bool) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, truncated
+	}
+	return "..." + s[len(s)-max:], true
+}
@@ -0,0 +1,106 @@
+package gotest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/project\n\ngo 1.24\n"
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestToolReportsFailures(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+
+	dir := writeModule(t, map[string]string{
+		"math.go": "package project\n\nfunc Add(a, b int) int { return a - b }\n",
+		"math_test.go": `package project
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if got := Add(2, 2); got != 4 {
+		t.Fatalf("Add(2, 2) = %d, want 4", got)
+	}
+}
+
+func TestZero(t *testing.T) {}
+`,
+	})
+
+	result, err := New(WithDir(dir)).Execute(context.Background(), map[string]any{})
+	require.NoError(t, err)
+
+	r := result.(Result)
+	assert.False(t, r.Passed)
+	assert.Equal(t, "go test -json ./...", r.Command)
+	assert.Equal(t, 1, r.Passes)
+	require.Len(t, r.Failures, 1)
+	assert.Equal(t, "TestAdd", r.Failures[0].Test)
+	assert.Contains(t, r.Failures[0].Output, "Add(2, 2) = 0, want 4")
+
+	result, err = New(WithDir(dir)).Execute(context.Background(), map[string]any{"run": "TestZero"})
+	require.NoError(t, err)
+	assert.True(t, result.(Result).Passed)
+}
+
+func TestToolReportsBuildErrors(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+
+	dir := writeModule(t, map[string]string{
+		"broken.go": "package project\n\nfunc Broken() int { return \"x\" }\n",
+	})
+
+	result, err := New(WithDir(dir)).Execute(context.Background(), map[string]any{})
+	require.NoError(t, err)
+
+	r := result.(Result)
+	assert.False(t, r.Passed)
+	assert.Contains(t, r.BuildErrors, "broken.go")
+}
+
+func TestToolCustomCommand(t *testing.T) {
+	tool := New(WithCommand("sh", "-c", "echo checking; exit 2"))
+
+	result, err := tool.Execute(context.Background(), map[string]any{})
+	require.NoError(t, err)
+
+	r := result.(Result)
+	assert.False(t, r.Passed)
+	assert.Equal(t, 2, r.ExitCode)
+	assert.Equal(t, "checking\n", r.Output)
+}
+
+func TestParseTruncatesOutput(t *testing.T) {
+	output := `{"Action":"output","Package":"p","Test":"TestBig","Output":"` + strings.Repeat("x", 100) + `end\n"}
+{"Action":"fail","Package":"p","Test":"TestBig"}
+{"Action":"fail","Package":"p"}
+`
+	result := parse([]byte(output), 10)
+
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, "...xxxxxxend\n", result.Failures[0].Output)
+	assert.True(t, result.Truncated)
+}
+
+func TestRejectsFlagsAsPackages(t *testing.T) {
+	_, err := New().Execute(context.Background(), map[string]any{"packages": "-exec=rm"})
+	assert.Error(t, err)
+}
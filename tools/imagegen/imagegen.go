@@ -0,0 +1,252 @@
+// Package imagegen provides a tool that generates images from a text prompt
+// through an OpenAI-compatible image generation endpoint (gpt-image-1 or
+// DALL-E). Generated images are returned as an agent.ImageResult, so the agent
+// shows them to the model in the conversation, and can also be saved to a
+// directory.
+package imagegen
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Sizes are the image sizes the model may ask for
+var Sizes = []string{"auto", "1024x1024", "1536x1024", "1024x1536", "1792x1024", "1024x1792", "512x512", "256x256"}
+
+// Image is a generated image
+type Image struct {
+	Name string `json:"name"`
+	// MediaType is the detected type of Data, such as "image/png"
+	MediaType string `json:"media_type,omitempty"`
+	// URL is set when the provider returned a link rather than image data
+	URL string `json:"url,omitempty"`
+	// Path is where the image was saved, when WithOutputDir is used
+	Path string `json:"path,omitempty"`
+	Data []byte `json:"-"`
+}
+
+// Result is the outcome of an image generation
+type Result struct {
+	Prompt string `json:"prompt"`
+	// RevisedPrompt is the prompt the provider actually used, if it rewrote it
+	RevisedPrompt string  `json:"revised_prompt,omitempty"`
+	Model         string  `json:"model"`
+	Size          string  `json:"size,omitempty"`
+	Images        []Image `json:"images"`
+}
+
+// ResultImages returns the images with data, for the agent to attach to the conversation
+func (r *Result) ResultImages() []agent.Image {
+	var images []agent.Image
+	for _, image := range r.Images {
+		if len(image.Data) > 0 {
+			images = append(images, agent.Image{Data: image.Data, Name: image.Name})
+		}
+	}
+	return images
+}
+
+// Option is a functional option for configuring a Tool
+type Option func(*Tool)
+
+// WithModel sets the image model (default: gpt-image-1)
+func WithModel(model string) Option {
+	return func(t *Tool) {
+		t.model = model
+	}
+}
+
+// WithSize sets the size used when the model does not ask for one (default: the provider's default)
+func WithSize(size string) Option {
+	return func(t *Tool) {
+		t.size = size
+	}
+}
+
+// WithQuality sets the quality, such as "low", "medium", "high" or, for
+// DALL-E 3, "standard" and "hd" (default: the provider's default)
+func WithQuality(quality string) Option {
+	return func(t *Tool) {
+		t.quality = quality
+	}
+}
+
+// WithMaxImages caps the images generated per call (default: 1)
+func WithMaxImages(n int) Option {
+	return func(t *Tool) {
+		t.maxImages = n
+	}
+}
+
+// WithOutputDir saves generated images to dir, which must exist
+func WithOutputDir(dir string) Option {
+	return func(t *Tool) {
+		t.outputDir = dir
+	}
+}
+
+// WithTimeout sets the maximum duration of a generation (default: 2 minutes)
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Tool) {
+		t.timeout = timeout
+	}
+}
+
+// Tool generates images from text prompts
+type Tool struct {
+	client    openai.Client
+	model     string
+	size      string
+	quality   string
+	maxImages int
+	outputDir string
+	timeout   time.Duration
+}
+
+// New creates an image generation tool with the given API key and base URL
+func New(apiKey string, baseURL string, opts ...Option) *Tool {
+	client := openai.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(baseURL),
+	)
+
+	return NewWithClient(client, opts...)
+}
+
+// NewWithClient creates an image generation tool with an existing OpenAI client
+func NewWithClient(client openai.Client, opts ...Option) *Tool {
+	t := &Tool{client: client, model: "gpt-image-1", maxImages: 1, timeout: 2 * time.Minute}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "generate_image"
+}
+
+func (t *Tool) Description() string {
+	return "Generate an image from a detailed text description. The image is shown to you after the call."
+}
+
+func (t *Tool) Parameters() agent.Parameters {
+	sizes := make([]any, len(Sizes))
+	for i, size := range Sizes {
+		sizes[i] = size
+	}
+	properties := map[string]any{
+		"prompt": agent.StringSchema("A detailed description of the image, including subject, style, composition, and colors").MinLength(1),
+		"size":   agent.StringSchema("The image size in pixels, as width x height").Enum(sizes...),
+	}
+	if t.maxImages > 1 {
+		properties["n"] = agent.IntegerSchema("The number of images to generate").Range(1, float64(t.maxImages))
+	}
+	return agent.Parameters{
+		Properties: properties,
+		Required:   []string{"prompt"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	prompt, ok := input["prompt"].(string)
+	if !ok || strings.TrimSpace(prompt) == "" {
+		return nil, errors.New("prompt must be a non-empty string")
+	}
+	size, _ := input["size"].(string)
+	n := 1
+	if value, ok := input["n"].(float64); ok {
+		n = int(value)
+	}
+	return t.Generate(ctx, prompt, size, n)
+}
+
+// Generate creates n images from prompt, capped at the maximum per call. An
+// empty size uses the configured size.
+func (t *Tool) Generate(ctx context.Context, prompt string, size string, n int) (*Result, error) {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	if size == "" {
+		size = t.size
+	}
+	n = max(1, min(n, t.maxImages))
+
+	params := openai.ImageGenerateParams{
+		Prompt: prompt,
+		Model:  openai.ImageModel(t.model),
+		N:      openai.Int(int64(n)),
+	}
+	if size != "" {
+		params.Size = openai.ImageGenerateParamsSize(size)
+	}
+	if t.quality != "" {
+		params.Quality = openai.ImageGenerateParamsQuality(t.quality)
+	}
+	// DALL-E returns links by default; gpt-image models always return data and reject the parameter
+	if strings.HasPrefix(t.model, "dall-e") {
+		params.ResponseFormat = openai.ImageGenerateParamsResponseFormatB64JSON
+	}
+
+	response, err := t.client.Images.Generate(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("generate image: %w", err)
+	}
+
+	result := &Result{Prompt: prompt, Model: t.model, Size: size}
+	stamp := time.Now().UTC().Format("20060102-150405.000000")
+	for i, data := range response.Data {
+		image := Image{Name: fmt.Sprintf("image-%s-%d", stamp, i+1), URL: data.URL}
+		if data.RevisedPrompt != "" {
+			result.RevisedPrompt = data.RevisedPrompt
+		}
+		if data.B64JSON != "" {
+			image.Data, err = base64.StdEncoding.DecodeString(data.B64JSON)
+			if err != nil {
+				return nil, fmt.Errorf("decode image %d: %w", i+1, err)
+			}
+			image.MediaType = http.DetectContentType(image.Data)
+			image.Name += extension(image.MediaType)
+			if t.outputDir != "" {
+				image.Path = filepath.Join(t.outputDir, image.Name)
+				if err := os.WriteFile(image.Path, image.Data, 0o644); err != nil {
+					return nil, fmt.Errorf("save image: %w", err)
+				}
+			}
+		}
+		result.Images = append(result.Images, image)
+	}
+	if len(result.Images) == 0 {
+		return nil, errors.New("generate image: the provider returned no images")
+	}
+	return result, nil
+}
+
+// extension returns the file extension for an image media type
+func extension(mediaType string) string {
+	switch mediaType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
+	}
+}
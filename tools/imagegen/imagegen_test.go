@@ -0,0 +1,100 @@
+package imagegen
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var png = []byte("\x89PNG\r\n\x1a\nimage")
+
+// newTestTool creates a tool backed by a server that returns n copies of png
+// and records the request body
+func newTestTool(t *testing.T, body *map[string]any, opts ...Option) *Tool {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/generations", r.URL.Path)
+		var request map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if body != nil {
+			*body = request
+		}
+		var data []map[string]string
+		for range int(request["n"].(float64)) {
+			data = append(data, map[string]string{"b64_json": base64.StdEncoding.EncodeToString(png), "revised_prompt": "a red fox, watercolor"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"created": 1, "data": data})
+	}))
+	t.Cleanup(server.Close)
+
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	return NewWithClient(client, opts...)
+}
+
+func TestGenerate(t *testing.T) {
+	var body map[string]any
+	dir := t.TempDir()
+	tool := newTestTool(t, &body, WithMaxImages(2), WithQuality("low"), WithSize("1024x1024"), WithOutputDir(dir))
+
+	result, err := tool.Execute(context.Background(), map[string]any{"prompt": "a fox", "n": float64(5)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"prompt": "a fox", "model": "gpt-image-1", "n": float64(2), "quality": "low", "size": "1024x1024"}, body)
+
+	generated := result.(*Result)
+	assert.Equal(t, "a red fox, watercolor", generated.RevisedPrompt)
+	require.Len(t, generated.Images, 2)
+	for _, image := range generated.Images {
+		assert.Equal(t, "image/png", image.MediaType)
+		assert.Regexp(t, `^image-.*\.png$`, image.Name)
+		saved, err := os.ReadFile(image.Path)
+		require.NoError(t, err)
+		assert.Equal(t, png, saved)
+	}
+
+	// The agent attaches the images and sends the rest as the tool message
+	var imageResult agent.ImageResult = generated
+	assert.Equal(t, agent.Image{Data: png, Name: generated.Images[0].Name}, imageResult.ResultImages()[0])
+	encoded, err := json.Marshal(generated)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), base64.StdEncoding.EncodeToString(png))
+}
+
+func TestGenerateDALLE(t *testing.T) {
+	var body map[string]any
+	tool := newTestTool(t, &body, WithModel("dall-e-3"))
+
+	_, err := tool.Generate(context.Background(), "a fox", "1792x1024", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "b64_json", body["response_format"])
+	assert.Equal(t, "1792x1024", body["size"])
+}
+
+func TestResultImages(t *testing.T) {
+	// Images returned as links are not attached
+	result := &Result{Images: []Image{{Name: "fox.png", Data: png}, {Name: "link", URL: "https://example.com/fox.png"}}}
+	assert.Equal(t, []agent.Image{{Data: png, Name: "fox.png"}}, result.ResultImages())
+}
+
+func TestParameters(t *testing.T) {
+	assert.NotContains(t, New("key", "http://localhost").Parameters().Properties, "n")
+	assert.Contains(t, New("key", "http://localhost", WithMaxImages(4)).Parameters().Properties, "n")
+
+	_, err := New("key", "http://localhost").Execute(context.Background(), map[string]any{"prompt": " "})
+	assert.EqualError(t, err, "prompt must be a non-empty string")
+}
+
+func TestToolGeneratedInputs(t *testing.T) {
+	agenttest.GenerateToolTests(t, []agent.Tool{newTestTool(t, nil, WithMaxImages(3))})
+}
@@ -0,0 +1,149 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Policy decides which commands run. An entry is a program name optionally
+// followed by leading arguments: "ls" matches any ls command, and "git status"
+// matches "git status -s" but not "git push". Deny entries match programs by
+// base name, so "rm" denies "/bin/rm" and "./rm". Allow entries only match the
+// program as written: "ls" allows "ls", found on PATH, but not "./ls" or
+// "/tmp/ls", and an absolute path such as "/usr/bin/ls" allows only itself.
+type Policy struct {
+	// Allow lists the commands that run without approval
+	Allow []string
+	// Deny lists the commands that never run, even with approval. Deny takes
+	// precedence over Allow.
+	Deny []string
+}
+
+// Command is a command the model asked to run
+type Command struct {
+	Args []string
+	// Dir is the working directory, relative to the tool's root
+	Dir string
+}
+
+func (c Command) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Approver decides whether a command that is not on the allowlist may run,
+// for example by asking a person
+type Approver func(ctx context.Context, command Command) (bool, error)
+
+// DeniedError is returned when a command is rejected by the policy or the approver
+type DeniedError struct {
+	Command string
+	Reason  string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("command %q denied: %s", e.Command, e.Reason)
+}
+
+// check returns the decision for args: allowed, denied with a reason, or
+// neither, in which case the command needs approval
+func (p Policy) check(args []string) (allowed bool, denied string) {
+	for _, entry := range p.Deny {
+		if matches(entry, args, true) {
+			return false, fmt.Sprintf("matches deny rule %q", entry)
+		}
+	}
+	for _, entry := range p.Allow {
+		if matches(entry, args, false) {
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// matches reports whether args start with the words of entry, comparing the
+// program by base name if byBase is set
+func matches(entry string, args []string, byBase bool) bool {
+	words := strings.Fields(entry)
+	if len(words) == 0 || len(words) > len(args) {
+		return false
+	}
+	if byBase {
+		if filepath.Base(args[0]) != filepath.Base(words[0]) {
+			return false
+		}
+	} else if args[0] != words[0] || (strings.ContainsRune(words[0], '/') && !filepath.IsAbs(words[0])) {
+		// A relative path could name any program the model has written
+		return false
+	}
+	for i := 1; i < len(words); i++ {
+		if args[i] != words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitWords splits a command line into arguments, honoring single quotes,
+// double quotes, and backslash escapes. Shell operators such as pipes,
+// redirection, and substitution are rejected because commands run without a shell.
+func splitWords(line string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+			case r == '$' || r == '`':
+				return nil, errors.New("shell substitution is not supported")
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case strings.ContainsRune("|&;<>()$`", r):
+			return nil, fmt.Errorf("shell operator %q is not supported; run one command at a time", r)
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,272 @@
+// Package shell provides a tool that runs commands for an agent under a
+// command policy. Commands run directly with exec.CommandContext, without a
+// shell, in a working directory confined to a root, with a scrubbed
+// environment, a timeout, and truncated output. This is a policy layer, not a
+// sandbox: run untrusted workloads with tools/codeexec instead.
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// DefaultEnv names the environment variables passed to commands unless WithPassEnv is used
+var DefaultEnv = []string{"PATH", "HOME", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// Result is the outcome of a command
+type Result struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+	// Truncated reports whether output was cut to the configured maximum
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Option is a functional option for configuring a Tool
+type Option func(*Tool)
+
+// WithRoot confines working directories to dir (default: the working directory)
+func WithRoot(dir string) Option {
+	return func(t *Tool) {
+		t.root = dir
+	}
+}
+
+// WithPolicy sets the allowlist and denylist
+func WithPolicy(policy Policy) Option {
+	return func(t *Tool) {
+		t.policy = policy
+	}
+}
+
+// WithApprover sets the hook consulted for commands that are not on the
+// allowlist. Without one, such commands are denied.
+func WithApprover(approver Approver) Option {
+	return func(t *Tool) {
+		t.approver = approver
+	}
+}
+
+// WithTimeout sets the maximum duration of a command (default: 1 minute)
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Tool) {
+		t.timeout = timeout
+	}
+}
+
+// WithMaxOutput caps stdout and stderr, in bytes each (default: 16384). The
+// start and end of longer output are kept.
+func WithMaxOutput(n int) Option {
+	return func(t *Tool) {
+		t.maxOutput = n
+	}
+}
+
+// WithPassEnv replaces DefaultEnv as the environment variables passed through from this process
+func WithPassEnv(names ...string) Option {
+	return func(t *Tool) {
+		t.passEnv = names
+	}
+}
+
+// WithEnv sets an environment variable for every command
+func WithEnv(name string, value string) Option {
+	return func(t *Tool) {
+		t.env = append(t.env, name+"="+value)
+	}
+}
+
+// Tool is an agent.Tool that runs commands
+type Tool struct {
+	root      string
+	policy    Policy
+	approver  Approver
+	timeout   time.Duration
+	maxOutput int
+	passEnv   []string
+	env       []string
+}
+
+// New creates a shell tool. With no allowlist and no approver every command is denied.
+func New(opts ...Option) *Tool {
+	t := &Tool{
+		root:      ".",
+		timeout:   time.Minute,
+		maxOutput: 16 << 10,
+		passEnv:   DefaultEnv,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "run_command"
+}
+
+func (t *Tool) Description() string {
+	description := "Run a command and return its exit code, stdout, and stderr. Commands run without a shell, " +
+		"so pipes, redirection, globs, and variable expansion are not available; run one command at a time."
+	if len(t.policy.Allow) > 0 {
+		description += " Allowed commands: " + strings.Join(t.policy.Allow, ", ") + "."
+		if t.approver != nil {
+			description += " Other commands need approval."
+		}
+	}
+	return description
+}
+
+func (t *Tool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"command": agent.StringSchema("The command line to run, such as: git status -s"),
+			"dir":     agent.StringSchema("The working directory, relative to the project root (default: the root)"),
+		},
+		Required: []string{"command"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	line, ok := input["command"].(string)
+	if !ok {
+		return nil, errors.New("command must be a string")
+	}
+	args, err := splitWords(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("command is empty")
+	}
+	dir, _ := input["dir"].(string)
+	command := Command{Args: args, Dir: dir}
+
+	if err := t.authorize(ctx, command); err != nil {
+		return nil, err
+	}
+	workDir, err := t.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	return t.run(ctx, command, workDir)
+}
+
+// authorize applies the policy, asking the approver about commands it does not allow
+func (t *Tool) authorize(ctx context.Context, command Command) error {
+	allowed, denied := t.policy.check(command.Args)
+	switch {
+	case denied != "":
+		return &DeniedError{Command: command.String(), Reason: denied}
+	case allowed:
+		return nil
+	case t.approver == nil:
+		return &DeniedError{Command: command.String(), Reason: "not on the allowlist"}
+	}
+
+	approved, err := t.approver(ctx, command)
+	if err != nil {
+		return fmt.Errorf("approving command %q: %w", command.String(), err)
+	}
+	if !approved {
+		return &DeniedError{Command: command.String(), Reason: "not approved"}
+	}
+	return nil
+}
+
+// resolve returns the working directory for dir, rejecting directories outside
+// the root, including through symbolic links
+func (t *Tool) resolve(dir string) (string, error) {
+	root, err := filepath.Abs(t.root)
+	if err != nil {
+		return "", err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return root, nil
+	}
+	if filepath.IsAbs(dir) {
+		return "", fmt.Errorf("dir %q must be relative", dir)
+	}
+	full, err := filepath.EvalSymlinks(filepath.Join(root, dir))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("dir %q is outside the project root", dir)
+	}
+	return full, nil
+}
+
+// run executes the command, killing it when the timeout elapses
+func (t *Tool) run(ctx context.Context, command Command, dir string) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command.Args[0], command.Args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = t.environ()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on children that keep the output pipes open after a kill
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result := Result{
+		Command:  command.String(),
+		TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}
+	result.Stdout, result.Truncated = truncate(stdout.String(), t.maxOutput, result.Truncated)
+	result.Stderr, result.Truncated = truncate(stderr.String(), t.maxOutput, result.Truncated)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+// environ returns the scrubbed environment for commands
+func (t *Tool) environ() []string {
+	var env []string
+	for _, name := range t.passEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, t.env...)
+}
+
+// truncate keeps the start and end of s within max bytes
+func truncate(s string, max int, truncated bool) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, truncated
+	}
+	head := max / 2
+	tail := max - head
+	return fmt.Sprintf("%s\n... [%d bytes truncated] ...\n%s", s[:head], len(s)-max, s[len(s)-tail:]), true
+}
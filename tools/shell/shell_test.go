@@ -0,0 +1,182 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  string
+	}{
+		{line: "git status -s", want: []string{"git", "status", "-s"}},
+		{line: `grep -n "hello world" 'it''s' a\ b`, want: []string{"grep", "-n", "hello world", "its", "a b"}},
+		{line: `echo "say \"hi\""`, want: []string{"echo", `say "hi"`}},
+		{line: `echo ""`, want: []string{"echo", ""}},
+		{line: "ls | wc -l", err: "shell operator '|'"},
+		{line: "echo hi > out.txt", err: "shell operator '>'"},
+		{line: "rm -rf build; ls", err: "shell operator ';'"},
+		{line: `echo "$HOME"`, err: "substitution"},
+		{line: `echo 'open`, err: "unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			args, err := splitWords(tt.line)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, args)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := Policy{
+		Allow: []string{"ls", "git status", "git diff", "go test", "/usr/bin/env", "./build.sh"},
+		Deny:  []string{"git push", "ls -R", "/bin/rm"},
+	}
+
+	tests := []struct {
+		command string
+		allowed bool
+		denied  bool
+	}{
+		{command: "ls -la", allowed: true},
+		// Only the bare name, resolved through PATH, is allowed
+		{command: "/bin/ls"},
+		{command: "./ls"},
+		{command: "/tmp/x/ls"},
+		{command: "/usr/bin/env", allowed: true},
+		{command: "env"},
+		{command: "./build.sh"},
+		// Deny rules catch the program under any path
+		{command: "/bin/ls -R", denied: true},
+		{command: "rm -rf x", denied: true},
+		{command: "./rm", denied: true},
+		{command: "git status -s", allowed: true},
+		{command: "git push origin main", denied: true},
+		{command: "ls -R", denied: true},
+		{command: "git commit -m x"},
+		{command: "gitx status"},
+		{command: "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			allowed, denied := policy.check(strings.Fields(tt.command))
+			assert.Equal(t, tt.allowed, allowed)
+			assert.Equal(t, tt.denied, denied != "")
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "a.txt"), []byte("hello\n"), 0o644))
+	t.Setenv("SHELL_TEST_SECRET", "s3cret")
+
+	var approvals []Command
+	tool := New(
+		WithRoot(root),
+		WithPolicy(Policy{Allow: []string{"cat", "env", "sh"}, Deny: []string{"rm"}}),
+		WithApprover(func(ctx context.Context, command Command) (bool, error) {
+			approvals = append(approvals, command)
+			return command.Args[0] == "pwd", nil
+		}),
+		WithEnv("CI", "true"),
+	)
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, map[string]any{"command": "cat a.txt", "dir": "sub"})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Command: "cat a.txt", Stdout: "hello\n"}, result)
+
+	result, err = tool.Execute(ctx, map[string]any{"command": "sh -c 'echo oops >&2; exit 3'"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.(Result).ExitCode)
+	assert.Equal(t, "oops\n", result.(Result).Stderr)
+
+	// Only the passed and configured variables reach the command
+	result, err = tool.Execute(ctx, map[string]any{"command": "env"})
+	require.NoError(t, err)
+	assert.Contains(t, result.(Result).Stdout, "CI=true")
+	assert.Contains(t, result.(Result).Stdout, "PATH=")
+	assert.NotContains(t, result.(Result).Stdout, "s3cret")
+
+	// Commands off the allowlist go to the approver; denied commands never do
+	result, err = tool.Execute(ctx, map[string]any{"command": "pwd", "dir": "sub"})
+	require.NoError(t, err)
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(resolvedRoot, "sub")+"\n", result.(Result).Stdout)
+
+	_, err = tool.Execute(ctx, map[string]any{"command": "touch x"})
+	var deniedErr *DeniedError
+	require.ErrorAs(t, err, &deniedErr)
+	assert.Equal(t, "not approved", deniedErr.Reason)
+
+	_, err = tool.Execute(ctx, map[string]any{"command": "rm -rf sub"})
+	require.ErrorAs(t, err, &deniedErr)
+	assert.Equal(t, `matches deny rule "rm"`, deniedErr.Reason)
+	assert.Equal(t, []Command{{Args: []string{"pwd"}, Dir: "sub"}, {Args: []string{"touch", "x"}}}, approvals)
+	assert.DirExists(t, filepath.Join(root, "sub"))
+}
+
+func TestExecuteConfinement(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0o755))
+	require.NoError(t, os.Symlink(dir, filepath.Join(root, "escape")))
+	tool := New(WithRoot(root), WithPolicy(Policy{Allow: []string{"ls"}}))
+
+	for _, workDir := range []string{"..", "../root/..", dir, "escape"} {
+		_, err := tool.Execute(context.Background(), map[string]any{"command": "ls", "dir": workDir})
+		assert.Error(t, err, workDir)
+	}
+
+	// Without an allowlist or approver nothing runs
+	_, err := New(WithRoot(root)).Execute(context.Background(), map[string]any{"command": "ls"})
+	var deniedErr *DeniedError
+	require.ErrorAs(t, err, &deniedErr)
+	assert.Equal(t, "not on the allowlist", deniedErr.Reason)
+}
+
+func TestExecuteLimits(t *testing.T) {
+	tool := New(
+		WithRoot(t.TempDir()),
+		WithPolicy(Policy{Allow: []string{"sleep", "seq"}}),
+		WithTimeout(100*time.Millisecond),
+		WithMaxOutput(20),
+	)
+
+	start := time.Now()
+	result, err := tool.Execute(context.Background(), map[string]any{"command": "sleep 10"})
+	require.NoError(t, err)
+	assert.True(t, result.(Result).TimedOut)
+	assert.Equal(t, -1, result.(Result).ExitCode)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	result, err = tool.Execute(context.Background(), map[string]any{"command": "seq 1000"})
+	require.NoError(t, err)
+	assert.True(t, result.(Result).Truncated)
+	assert.Equal(t, "1\n2\n3\n4\n5\n\n... [3873 bytes truncated] ...\n\n999\n1000\n", result.(Result).Stdout)
+}
+
+func TestToolGeneratedInputs(t *testing.T) {
+	agenttest.GenerateToolTests(t, []agent.Tool{New(WithRoot(t.TempDir()), WithPolicy(Policy{Allow: []string{"echo"}}))})
+}
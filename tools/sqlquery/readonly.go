@@ -0,0 +1,181 @@
+package sqlquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// readStatements are the keywords a read-only statement may start with
+var readStatements = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"EXPLAIN":  true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+}
+
+// writeKeywords may not appear anywhere in a read-only statement. This catches
+// data-modifying CTEs (WITH ... DELETE), SELECT ... INTO, row locks (FOR
+// UPDATE), and EXPLAIN ANALYZE of a write.
+var writeKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"UPSERT":   true,
+	"REPLACE":  true,
+	"INTO":     true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"RENAME":   true,
+	"GRANT":    true,
+	"REVOKE":   true,
+	"COPY":     true,
+	"CALL":     true,
+	"EXEC":     true,
+	"EXECUTE":  true,
+	"ATTACH":   true,
+	"DETACH":   true,
+	"PRAGMA":   true,
+	"VACUUM":   true,
+	"LOCK":     true,
+}
+
+// ReadOnlyError is returned when a query is rejected because it could modify
+// the database or runs more than one statement
+type ReadOnlyError struct {
+	Query  string
+	Reason string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return "query rejected: " + e.Reason
+}
+
+// CheckReadOnly returns a *ReadOnlyError unless query is a single statement
+// that only reads. Comments, string literals, and quoted identifiers are
+// skipped, so their content can neither trip the check nor hide a statement
+// from it.
+//
+// The check is conservative and dialect-agnostic: it may reject unusual reads,
+// and it cannot see inside functions or views, which is why queries also run
+// in a read-only transaction.
+func CheckReadOnly(query string) error {
+	reject := func(format string, args ...any) error {
+		return &ReadOnlyError{Query: query, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	words, statements, err := scan(query)
+	if err != nil {
+		return reject("%v", err)
+	}
+	if statements > 1 {
+		return reject("only one statement may be run at a time")
+	}
+	if len(words) == 0 {
+		return reject("query is empty")
+	}
+	if !readStatements[words[0]] {
+		return reject("%s statements are not allowed on a read-only database", words[0])
+	}
+	for _, word := range words {
+		if writeKeywords[word] {
+			return reject("%s is not allowed on a read-only database", word)
+		}
+	}
+	return nil
+}
+
+// scan returns the upper-cased bare words of query, skipping comments and
+// quoted text, and the number of non-empty statements it contains. Where
+// dialects disagree, it reads the query so that nothing is skipped that any
+// dialect would run: "--" only starts a comment when followed by whitespace, as
+// in MySQL, and quoted text with backslashes is rejected, since MySQL treats
+// them as escapes and standard SQL does not.
+func scan(query string) ([]string, int, error) {
+	var words []string
+	statements, inStatement := 0, false
+	token := func() {
+		if !inStatement {
+			inStatement = true
+			statements++
+		}
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || isSpace(query[i+2])):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return words, statements, nil
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*!"):
+			return nil, 0, fmt.Errorf("executable comments are not allowed")
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, 0, fmt.Errorf("unterminated comment")
+			}
+			i += 2 + end + 1
+		case c == '\'' || c == '"' || c == '`':
+			token()
+			end := closeQuote(query, i)
+			if end < 0 {
+				return nil, 0, fmt.Errorf("unterminated quote")
+			}
+			if strings.IndexByte(query[i:end], '\\') >= 0 {
+				return nil, 0, fmt.Errorf("backslashes in quoted text are ambiguous; pass values as parameters")
+			}
+			i = end
+		case c == ';':
+			inStatement = false
+		case isWordByte(c) && !isDigit(c):
+			token()
+			start := i
+			for i+1 < len(query) && isWordByte(query[i+1]) {
+				i++
+			}
+			words = append(words, strings.ToUpper(query[start:i+1]))
+		case isSpace(c):
+		default:
+			token()
+		}
+	}
+	return words, statements, nil
+}
+
+// closeQuote returns the index of the quote closing the one at query[start],
+// or -1. A doubled quote inside quoted text is an escaped quote.
+func closeQuote(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,97 @@
+package sqlquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Table is a table or view and its columns
+type Table struct {
+	// Name is qualified with its schema unless the schema is the default one
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+}
+
+// Column is a column of a table
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// introspectQueries read a database's columns as (table schema, table, column,
+// type) rows, in table and column order, for databases with
+// information_schema (PostgreSQL, MySQL, SQL Server) and SQLite
+var introspectQueries = []string{
+	`SELECT table_schema, table_name, column_name, data_type
+	FROM information_schema.columns
+	WHERE table_schema NOT IN ('information_schema', 'pg_catalog', 'mysql', 'sys', 'performance_schema')
+	ORDER BY table_schema, table_name, ordinal_position`,
+	`SELECT '', m.name, p.name, p.type
+	FROM sqlite_master m JOIN pragma_table_info(m.name) p
+	WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%'
+	ORDER BY m.name, p.cid`,
+}
+
+// defaultSchemas are left off table names
+var defaultSchemas = map[string]bool{"": true, "public": true, "dbo": true, "main": true}
+
+// Introspect reads the tables and columns of db, for use with WithTables. It
+// supports databases with information_schema, such as PostgreSQL, MySQL, and
+// SQL Server, and SQLite.
+func Introspect(ctx context.Context, db *sql.DB) ([]Table, error) {
+	var errs []error
+	for _, query := range introspectQueries {
+		tables, err := introspect(ctx, db, query)
+		if err == nil {
+			return tables, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("introspect schema: %w", errors.Join(errs...))
+}
+
+func introspect(ctx context.Context, db *sql.DB, query string) ([]Table, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []Table
+	for rows.Next() {
+		var schema, table string
+		var column Column
+		if err := rows.Scan(&schema, &table, &column.Name, &column.Type); err != nil {
+			return nil, err
+		}
+		if !defaultSchemas[schema] {
+			table = schema + "." + table
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Name != table {
+			tables = append(tables, Table{Name: table})
+		}
+		last := &tables[len(tables)-1]
+		last.Columns = append(last.Columns, column)
+	}
+	return tables, rows.Err()
+}
+
+// DescribeTables formats tables one per line, as "name(column type, ...)"
+func DescribeTables(tables []Table) string {
+	var b strings.Builder
+	for _, table := range tables {
+		b.WriteString(table.Name)
+		b.WriteString("(")
+		for i, column := range table.Columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strings.TrimSpace(column.Name + " " + column.Type))
+		}
+		b.WriteString(")\n")
+	}
+	return b.String()
+}
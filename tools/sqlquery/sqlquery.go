@@ -0,0 +1,213 @@
+// Package sqlquery provides a tool that lets an agent query a database through
+// database/sql. Queries are read-only by default: each is checked to be a
+// single statement that only reads, then run in a read-only transaction that
+// is always rolled back. Results are capped at a row limit, and values are
+// passed as query parameters rather than spliced into SQL.
+package sqlquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// Result is the outcome of a query
+type Result struct {
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated reports whether rows were cut to the row limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Option is a functional option for configuring a Tool
+type Option func(*Tool)
+
+// WithMaxRows caps the rows returned by a query (default: 100)
+func WithMaxRows(n int) Option {
+	return func(t *Tool) {
+		t.maxRows = n
+	}
+}
+
+// WithTimeout sets the maximum duration of a query (default: 30 seconds)
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Tool) {
+		t.timeout = timeout
+	}
+}
+
+// WithTables describes the tables in the tool's description, so the model can
+// write queries without exploring the schema first. Use Introspect to read
+// them from the database.
+func WithTables(tables []Table) Option {
+	return func(t *Tool) {
+		t.tables = tables
+	}
+}
+
+// WithAllowWrites lets the agent run any statement, including writes and
+// schema changes, outside a transaction. Prefer a database user with only the
+// privileges the agent needs.
+func WithAllowWrites() Option {
+	return func(t *Tool) {
+		t.allowWrites = true
+	}
+}
+
+// Tool runs SQL queries against a database
+type Tool struct {
+	db          *sql.DB
+	maxRows     int
+	timeout     time.Duration
+	tables      []Table
+	allowWrites bool
+}
+
+// New creates a tool that queries db
+func New(db *sql.DB, opts ...Option) *Tool {
+	t := &Tool{db: db, maxRows: 100, timeout: 30 * time.Second}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "sql_query"
+}
+
+func (t *Tool) Description() string {
+	var b strings.Builder
+	if t.allowWrites {
+		b.WriteString("Run a SQL statement against the database and return any rows.")
+	} else {
+		b.WriteString("Run a read-only SQL query against the database and return the rows. Only a single SELECT, WITH, VALUES, SHOW, DESCRIBE, or EXPLAIN statement is allowed.")
+	}
+	fmt.Fprintf(&b, " At most %d rows are returned.", t.maxRows)
+	b.WriteString(" Pass values in params with the database's placeholder syntax, such as ? or $1, instead of writing them into the query.")
+	if len(t.tables) > 0 {
+		b.WriteString("\n\nTables:\n")
+		b.WriteString(DescribeTables(t.tables))
+	}
+	return b.String()
+}
+
+func (t *Tool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{
+			"query": agent.StringSchema("The SQL statement to run"),
+			"params": agent.ArraySchema("Values for the query's placeholders, in order",
+				agent.Schema{"type": []string{"string", "number", "boolean", "null"}}),
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	query, ok := input["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return nil, errors.New("query must be a non-empty string")
+	}
+	var params []any
+	if raw, ok := input["params"]; ok && raw != nil {
+		list, ok := raw.([]any)
+		if !ok {
+			return nil, errors.New("params must be an array")
+		}
+		params = make([]any, len(list))
+		for i, param := range list {
+			switch v := param.(type) {
+			case string, bool, nil:
+				params[i] = v
+			case float64:
+				params[i] = normalizeNumber(v)
+			default:
+				return nil, fmt.Errorf("params[%d] must be a string, number, boolean, or null", i)
+			}
+		}
+	}
+	return t.Query(ctx, query, params...)
+}
+
+// Query runs a query with the tool's limits and read-only enforcement
+func (t *Tool) Query(ctx context.Context, query string, args ...any) (*Result, error) {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	if t.allowWrites {
+		rows, err := t.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		return t.collect(rows)
+	}
+
+	if err := CheckReadOnly(query); err != nil {
+		return nil, err
+	}
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return t.collect(rows)
+}
+
+// collect reads up to the row limit from rows and closes them
+func (t *Tool) collect(rows *sql.Rows) (*Result, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		if len(result.Rows) >= t.maxRows {
+			result.Truncated = true
+			break
+		}
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, value := range values {
+			// Text often arrives as bytes, which would otherwise encode as base64
+			if b, ok := value.([]byte); ok && utf8.Valid(b) {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, rows.Err()
+}
+
+// normalizeNumber turns whole JSON numbers into integers, which drivers
+// accept for integer columns
+func normalizeNumber(n float64) any {
+	if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+		return int64(n)
+	}
+	return n
+}
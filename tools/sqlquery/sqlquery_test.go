@@ -0,0 +1,282 @@
+package sqlquery
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/campbel/go-agents/agenttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{query: "SELECT * FROM users WHERE id = ?"},
+		{query: "select name from users;"},
+		{query: "WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent"},
+		{query: "EXPLAIN SELECT 1"},
+		{query: "SELECT 'drop table users; --' AS note -- trailing comment"},
+		{query: `SELECT "update" FROM t /* delete */`},
+		{query: "SELECT 'it''s'"},
+		{query: "SELECT 1 --\n"},
+		{query: "", err: "query is empty"},
+		{query: "-- just a comment", err: "query is empty"},
+		{query: "DELETE FROM users", err: "DELETE statements are not allowed"},
+		{query: "  insert into users values (1)", err: "INSERT statements are not allowed"},
+		{query: "SELECT 1; DROP TABLE users", err: "only one statement"},
+		{query: "SELECT 1;;SELECT 2", err: "only one statement"},
+		{query: "WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", err: "DELETE is not allowed"},
+		{query: "SELECT * INTO backup FROM users", err: "INTO is not allowed"},
+		{query: "SELECT * FROM users FOR UPDATE", err: "UPDATE is not allowed"},
+		{query: "EXPLAIN ANALYZE DELETE FROM users", err: "DELETE is not allowed"},
+		{query: "SELECT 1--1; DROP TABLE users", err: "only one statement"},
+		{query: `SELECT 'a\'' ; DROP TABLE users; -- '`, err: "backslashes"},
+		{query: "SELECT 1 /*!; DROP TABLE users */", err: "executable comments"},
+		{query: "SELECT 'open", err: "unterminated quote"},
+		{query: "SELECT 1 /* open", err: "unterminated comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			err := CheckReadOnly(tt.query)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var readOnlyErr *ReadOnlyError
+			require.ErrorAs(t, err, &readOnlyErr)
+			assert.Equal(t, tt.query, readOnlyErr.Query)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	fake := &fakeDB{results: map[string]fakeResult{
+		"SELECT id, name FROM users WHERE active = ?": {
+			columns: []string{"id", "name"},
+			rows:    [][]driver.Value{{int64(1), []byte("ada")}, {int64(2), "grace"}, {int64(3), nil}},
+		},
+	}}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+	tool := New(db, WithMaxRows(2))
+
+	result, err := tool.Execute(context.Background(), map[string]any{
+		"query":  "SELECT id, name FROM users WHERE active = ?",
+		"params": []any{float64(1)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Result{
+		Columns:   []string{"id", "name"},
+		Rows:      [][]any{{int64(1), "ada"}, {int64(2), "grace"}},
+		Truncated: true,
+	}, result)
+
+	// Whole JSON numbers are passed as integers, in a rolled back read-only transaction
+	assert.Equal(t, [][]driver.Value{{int64(1)}}, fake.args)
+	assert.Equal(t, []driver.TxOptions{{ReadOnly: true}}, fake.txOptions)
+	assert.Equal(t, 1, fake.rollbacks)
+
+	// Rejected queries never reach the database
+	_, err = tool.Execute(context.Background(), map[string]any{"query": "DELETE FROM users"})
+	var readOnlyErr *ReadOnlyError
+	assert.ErrorAs(t, err, &readOnlyErr)
+	assert.Len(t, fake.queries, 1)
+
+	_, err = tool.Execute(context.Background(), map[string]any{"query": "SELECT 1", "params": "1"})
+	assert.ErrorContains(t, err, "params must be an array")
+}
+
+func TestQueryAllowWrites(t *testing.T) {
+	fake := &fakeDB{results: map[string]fakeResult{"DELETE FROM sessions": {}}}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+	tool := New(db, WithAllowWrites())
+
+	result, err := tool.Query(context.Background(), "DELETE FROM sessions")
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows)
+	assert.Equal(t, []string{"DELETE FROM sessions"}, fake.queries)
+	assert.Empty(t, fake.txOptions)
+	assert.Contains(t, tool.Description(), "Run a SQL statement")
+}
+
+func TestQueryTimeout(t *testing.T) {
+	fake := &fakeDB{delay: time.Second}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+	tool := New(db, WithTimeout(10*time.Millisecond))
+
+	_, err := tool.Query(context.Background(), "SELECT 1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestIntrospect(t *testing.T) {
+	fake := &fakeDB{results: map[string]fakeResult{
+		introspectQueries[0]: {err: errors.New("no such table: information_schema.columns")},
+		introspectQueries[1]: {
+			columns: []string{"schema", "table", "column", "type"},
+			rows: [][]driver.Value{
+				{"", "orders", "id", "INTEGER"},
+				{"", "orders", "total", "REAL"},
+				{"", "users", "id", "INTEGER"},
+				{"", "users", "email", "TEXT"},
+			},
+		},
+	}}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+
+	tables, err := Introspect(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, []Table{
+		{Name: "orders", Columns: []Column{{Name: "id", Type: "INTEGER"}, {Name: "total", Type: "REAL"}}},
+		{Name: "users", Columns: []Column{{Name: "id", Type: "INTEGER"}, {Name: "email", Type: "TEXT"}}},
+	}, tables)
+
+	description := New(db, WithTables(tables)).Description()
+	assert.True(t, strings.HasSuffix(description, "Tables:\norders(id INTEGER, total REAL)\nusers(id INTEGER, email TEXT)\n"))
+
+	// Non-default schemas qualify table names
+	fake.results[introspectQueries[0]] = fakeResult{
+		columns: []string{"table_schema", "table_name", "column_name", "data_type"},
+		rows:    [][]driver.Value{{"public", "users", "id", "integer"}, {"billing", "invoices", "id", "integer"}},
+	}
+	tables, err = Introspect(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users", "billing.invoices"}, []string{tables[0].Name, tables[1].Name})
+
+	fake.results[introspectQueries[1]] = fakeResult{err: errors.New("no such table: sqlite_master")}
+	fake.results[introspectQueries[0]] = fakeResult{err: errors.New("permission denied")}
+	_, err = Introspect(context.Background(), db)
+	assert.ErrorContains(t, err, "permission denied")
+	assert.ErrorContains(t, err, "sqlite_master")
+}
+
+func TestToolGeneratedInputs(t *testing.T) {
+	db := sql.OpenDB(&fakeDB{})
+	defer db.Close()
+	agenttest.GenerateToolTests(t, []agent.Tool{New(db)})
+}
+
+// fakeResult is the outcome of a query against a fakeDB
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+// fakeDB is a database/sql driver that answers queries from a fixed set of
+// results and records what it was asked. Unknown queries return no rows.
+type fakeDB struct {
+	mu        sync.Mutex
+	results   map[string]fakeResult
+	delay     time.Duration
+	queries   []string
+	args      [][]driver.Value
+	txOptions []driver.TxOptions
+	rollbacks int
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: d}, nil
+}
+
+func (d *fakeDB) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.txOptions = append(c.db.txOptions, opts)
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rollbacks++
+	return nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.db.delay > 0 {
+		select {
+		case <-time.After(c.db.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.queries = append(c.db.queries, query)
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	if len(values) > 0 {
+		c.db.args = append(c.db.args, values)
+	}
+
+	result := c.db.results[query]
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &fakeRows{columns: result.columns, rows: result.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// TranscriberOption is a functional option for configuring a Transcriber
+type TranscriberOption func(*Transcriber)
+
+// WithLanguage hints the language of the audio as an ISO-639-1 code, such as
+// "en", which improves accuracy and latency
+func WithLanguage(language string) TranscriberOption {
+	return func(t *Transcriber) {
+		t.language = language
+	}
+}
+
+// WithTranscriptionPrompt hints the vocabulary of the audio, such as product
+// names, or continues the transcript of a previous segment
+func WithTranscriptionPrompt(prompt string) TranscriberOption {
+	return func(t *Transcriber) {
+		t.prompt = prompt
+	}
+}
+
+// WithWordTimestamps requests the start and end of each word in Transcript.Words.
+// Only whisper-1 supports word timestamps.
+func WithWordTimestamps() TranscriberOption {
+	return func(t *Transcriber) {
+		t.wordTimestamps = true
+	}
+}
+
+// Transcriber converts speech to text using a Whisper-compatible endpoint
+type Transcriber struct {
+	client         openai.Client
+	model          string
+	language       string
+	prompt         string
+	wordTimestamps bool
+}
+
+// Transcript is the text of transcribed audio
+type Transcript struct {
+	Text string `json:"text"`
+	// Language is the detected language, reported with word timestamps
+	Language string `json:"language,omitempty"`
+	// Duration is the length of the audio, reported with word timestamps
+	Duration time.Duration `json:"duration,omitempty"`
+	// Words holds each word and when it was spoken, with WithWordTimestamps
+	Words []Word `json:"words,omitempty"`
+}
+
+// Word is a transcribed word and when it was spoken
+type Word struct {
+	Word  string        `json:"word"`
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+}
+
+// NewTranscriber creates a new Transcriber with the given API key, base URL, and
+// model, such as "whisper-1" or "gpt-4o-transcribe"
+func NewTranscriber(apiKey string, baseURL string, model string, opts ...TranscriberOption) *Transcriber {
+	client := openai.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(baseURL),
+	)
+
+	return NewTranscriberWithClient(client, model, opts...)
+}
+
+// NewTranscriberWithClient creates a new Transcriber with an existing OpenAI client
+func NewTranscriberWithClient(client openai.Client, model string, opts ...TranscriberOption) *Transcriber {
+	transcriber := &Transcriber{
+		client: client,
+		model:  model,
+	}
+
+	for _, opt := range opts {
+		opt(transcriber)
+	}
+
+	return transcriber
+}
+
+// Transcribe converts audio to text. The filename's extension tells the
+// endpoint the audio format, such as "capture.wav" or "note.webm".
+func (t *Transcriber) Transcribe(ctx context.Context, audio io.Reader, filename string) (Transcript, error) {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	params := openai.AudioTranscriptionNewParams{
+		File:  openai.File(audio, filename, contentType),
+		Model: openai.AudioModel(t.model),
+	}
+	if t.language != "" {
+		params.Language = openai.String(t.language)
+	}
+	if t.prompt != "" {
+		params.Prompt = openai.String(t.prompt)
+	}
+	if t.wordTimestamps {
+		params.ResponseFormat = openai.AudioResponseFormatVerboseJSON
+		params.TimestampGranularities = []string{"word"}
+	}
+
+	response, err := t.client.Audio.Transcriptions.New(ctx, params)
+	if err != nil {
+		return Transcript{}, wrapProviderError(err)
+	}
+	if !t.wordTimestamps {
+		return Transcript{Text: strings.TrimSpace(response.Text)}, nil
+	}
+
+	// Verbose responses carry the language, duration, and words in seconds
+	var verbose struct {
+		Text     string  `json:"text"`
+		Language string  `json:"language"`
+		Duration float64 `json:"duration"`
+		Words    []struct {
+			Word  string  `json:"word"`
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+		} `json:"words"`
+	}
+	if err := json.Unmarshal([]byte(response.RawJSON()), &verbose); err != nil {
+		return Transcript{}, err
+	}
+	transcript := Transcript{
+		Text:     strings.TrimSpace(verbose.Text),
+		Language: verbose.Language,
+		Duration: seconds(verbose.Duration),
+	}
+	for _, word := range verbose.Words {
+		transcript.Words = append(transcript.Words, Word{Word: word.Word, Start: seconds(word.Start), End: seconds(word.End)})
+	}
+	return transcript, nil
+}
+
+// UserAudioMessage transcribes audio, such as a microphone capture, into a user
+// message for a conversation
+func (t *Transcriber) UserAudioMessage(ctx context.Context, audio io.Reader, filename string) (Message, Transcript, error) {
+	transcript, err := t.Transcribe(ctx, audio, filename)
+	if err != nil {
+		return Message{}, transcript, err
+	}
+	if transcript.Text == "" {
+		return Message{}, transcript, errors.New("transcription is empty")
+	}
+	return UserTextMessage(transcript.Text), transcript, nil
+}
+
+// TranscribeAndSend transcribes audio with transcriber, appends it to messages
+// as a user message, and streams the agent's reply. The transcript is returned
+// so it can be shown to the speaker.
+func (agent *Agent) TranscribeAndSend(
+	ctx context.Context,
+	transcriber *Transcriber,
+	messages []Message,
+	audio io.Reader,
+	filename string,
+	opts ...RunOption,
+) (<-chan Response, Transcript, error) {
+	message, transcript, err := transcriber.UserAudioMessage(ctx, audio, filename)
+	if err != nil {
+		return nil, transcript, err
+	}
+	conversation := append(append([]Message{}, messages...), message)
+	responses, err := agent.StreamChatCompletion(ctx, conversation, opts...)
+	return responses, transcript, err
+}
+
+// seconds converts fractional seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transcriptionServer answers transcription requests, recording their form fields
+func transcriptionServer(t *testing.T, fields map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chat/completions" {
+			var body struct {
+				Messages []struct {
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			writeCompletion(w, "model", "You said: "+body.Messages[len(body.Messages)-1].Content)
+			return
+		}
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		for key, values := range r.MultipartForm.Value {
+			fields[key] = values
+		}
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		audio, _ := io.ReadAll(file)
+		fields["file"] = []string{header.Filename, header.Header.Get("Content-Type"), string(audio)}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("response_format") == "verbose_json" {
+			fmt.Fprint(w, `{"text":" Hello world ","language":"english","duration":1.5,"words":[{"word":"Hello","start":0.1,"end":0.5},{"word":"world","start":0.6,"end":1.2}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"text":" Hello world "}`)
+	}))
+}
+
+func TestTranscribe(t *testing.T) {
+	fields := map[string][]string{}
+	server := transcriptionServer(t, fields)
+	defer server.Close()
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+
+	transcriber := NewTranscriberWithClient(client, "gpt-4o-transcribe", WithLanguage("en"), WithTranscriptionPrompt("go-agents"))
+	transcript, err := transcriber.Transcribe(context.Background(), strings.NewReader("RIFF"), "capture.wav")
+	require.NoError(t, err)
+	assert.Equal(t, Transcript{Text: "Hello world"}, transcript)
+	assert.Equal(t, []string{"gpt-4o-transcribe"}, fields["model"])
+	assert.Equal(t, []string{"en"}, fields["language"])
+	assert.Equal(t, []string{"go-agents"}, fields["prompt"])
+	assert.Equal(t, "capture.wav", fields["file"][0])
+	assert.Contains(t, fields["file"][1], "wav")
+	assert.Equal(t, "RIFF", fields["file"][2])
+
+	timed := NewTranscriberWithClient(client, "whisper-1", WithWordTimestamps())
+	transcript, err = timed.Transcribe(context.Background(), strings.NewReader("RIFF"), "capture.wav")
+	require.NoError(t, err)
+	assert.Equal(t, Transcript{
+		Text:     "Hello world",
+		Language: "english",
+		Duration: 1500 * time.Millisecond,
+		Words: []Word{
+			{Word: "Hello", Start: 100 * time.Millisecond, End: 500 * time.Millisecond},
+			{Word: "world", Start: 600 * time.Millisecond, End: 1200 * time.Millisecond},
+		},
+	}, transcript)
+	assert.Equal(t, []string{"word"}, fields["timestamp_granularities[]"])
+}
+
+func TestTranscribeAndSend(t *testing.T) {
+	server := transcriptionServer(t, map[string][]string{})
+	defer server.Close()
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	testAgent := NewAgentWithClient(client, "model")
+	transcriber := NewTranscriberWithClient(client, "whisper-1")
+
+	history := []Message{UserTextMessage("Hi"), AssistantTextMessage("Hello!")}
+	responses, transcript, err := testAgent.TranscribeAndSend(context.Background(), transcriber, history, strings.NewReader("RIFF"), "capture.wav")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", transcript.Text)
+	completion, err := Collect(responses)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"You said: Hello world"}, completion.Messages)
+	assert.Len(t, history, 2)
+}
@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/openai/openai-go/option"
+)
+
+// WithHTTPClient sends provider requests with client, for proxies, mTLS, or custom
+// timeouts. It applies to agents created with NewAgent and NewAgentWithClient.
+func WithHTTPClient(client *http.Client) AgentOption {
+	return WithRequestOptions(option.WithHTTPClient(client))
+}
+
+// WithHeader adds a header to every provider request
+func WithHeader(key string, value string) AgentOption {
+	return WithRequestOptions(option.WithHeaderAdd(key, value))
+}
+
+// WithRequestOptions applies provider client options to every request made by the agent
+func WithRequestOptions(opts ...option.RequestOption) AgentOption {
+	return func(a *Agent) {
+		a.requestOptions = append(a.requestOptions, opts...)
+	}
+}
+
+// WithRunHeader adds a header to the provider requests of a single run, such as a
+// correlation ID or a gateway routing key
+func WithRunHeader(key string, value string) RunOption {
+	return func(o *runOptions) {
+		o.requestOptions = append(o.requestOptions, option.WithHeaderAdd(key, value))
+	}
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTransport records the requests it forwards
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPClientAndHeaders(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		writeCompletion(w, "gpt-4o", "hello")
+	}))
+	t.Cleanup(server.Close)
+
+	transport := &recordingTransport{}
+	testAgent := NewAgent("test-key", server.URL, "gpt-4o",
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithHeader("X-Org", "acme"),
+	)
+
+	_, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")}, WithRunHeader("X-Request-Id", "req-1"))
+	require.NoError(t, err)
+	_, err = testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hello")})
+	require.NoError(t, err)
+
+	assert.Len(t, transport.requests, 2, "requests should use the injected client")
+	require.Len(t, headers, 2)
+	assert.Equal(t, "acme", headers[0].Get("X-Org"))
+	assert.Equal(t, "req-1", headers[0].Get("X-Request-Id"))
+	assert.Equal(t, "acme", headers[1].Get("X-Org"))
+	assert.Empty(t, headers[1].Get("X-Request-Id"), "run headers should not leak into later runs")
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// TruncateStrategy is how a tool result over the size limit is cut down
+type TruncateStrategy string
+
+const (
+	// TruncateHead keeps the beginning of the result
+	TruncateHead TruncateStrategy = "head"
+	// TruncateTail keeps the end of the result, such as the last lines of a log
+	TruncateTail TruncateStrategy = "tail"
+	// TruncateMiddle keeps the beginning and end of the result and drops the middle
+	TruncateMiddle TruncateStrategy = "middle"
+	// TruncateSummarize condenses the result with a model, falling back to
+	// TruncateHead if the summary is still over the limit
+	TruncateSummarize TruncateStrategy = "summarize"
+)
+
+// TruncatePolicy limits the size of tool results fed back to the model
+type TruncatePolicy struct {
+	// MaxBytes is the size above which a result is truncated
+	MaxBytes int
+	// Strategy is how the result is truncated, TruncateMiddle by default
+	Strategy TruncateStrategy
+	// Model summarizes results with TruncateSummarize
+	Model string
+}
+
+// WithToolResultLimit truncates tool results larger than the policy's MaxBytes,
+// so big JSON blobs or file contents cannot blow up the context window. The
+// result keeps at most MaxBytes of the original, plus a marker telling the model
+// how much was cut, and a warning response notes the truncation. The limit
+// applies after WithToolResultSummarizer.
+func WithToolResultLimit(policy TruncatePolicy) AgentOption {
+	return func(a *Agent) {
+		a.truncatePolicy = policy
+	}
+}
+
+// truncateToolResult applies the truncate policy to a tool result, sending a
+// warning and any summarization usage to the run's stream and adding the usage
+// to spent
+func (agent *Agent) truncateToolResult(ctx context.Context, tool Tool, content string, spent *spend, responseChan chan<- Response) (string, error) {
+	policy := agent.truncatePolicy
+	if policy.MaxBytes <= 0 || len(content) <= policy.MaxBytes {
+		return content, nil
+	}
+
+	strategy := policy.Strategy
+	if strategy == "" {
+		strategy = TruncateMiddle
+	}
+	var truncated string
+	if strategy == TruncateSummarize {
+		summary, usage, err := agent.summarizeToolResult(ctx, tool, content, policy.Model, policy.MaxBytes/4)
+		if err != nil {
+			return "", err
+		}
+		spent.add(agent.prices, usage)
+		if err := send(ctx, responseChan, NewUsageResponse(usage)); err != nil {
+			return "", err
+		}
+		truncated = fmt.Sprintf("[summarized from %d bytes]\n", len(content)) + truncate(summary, policy.MaxBytes, TruncateHead)
+	} else {
+		truncated = truncate(content, policy.MaxBytes, strategy)
+	}
+
+	warning := fmt.Sprintf("tool %s result truncated from %d bytes to %d (%s)", tool.Name(), len(content), policy.MaxBytes, strategy)
+	if err := send(ctx, responseChan, NewWarningResponse(warning)); err != nil {
+		return "", err
+	}
+	return truncated, nil
+}
+
+// truncate cuts s to at most maxBytes, without splitting a UTF-8 character,
+// marking where and how much was cut
+func truncate(s string, maxBytes int, strategy TruncateStrategy) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	switch strategy {
+	case TruncateTail:
+		tail := suffix(s, maxBytes)
+		return fmt.Sprintf("[... %d bytes truncated]\n", len(s)-len(tail)) + tail
+	case TruncateMiddle:
+		head := prefix(s, maxBytes/2)
+		tail := suffix(s, maxBytes-len(head))
+		return head + fmt.Sprintf("\n[... %d bytes truncated ...]\n", len(s)-len(head)-len(tail)) + tail
+	default:
+		head := prefix(s, maxBytes)
+		return head + fmt.Sprintf("\n[%d bytes truncated ...]", len(s)-len(head))
+	}
+}
+
+// prefix returns the longest prefix of s of at most n bytes ending on a character boundary
+func prefix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// suffix returns the longest suffix of s of at most n bytes starting on a character boundary
+func suffix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := len(s) - n
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return s[i:]
+}
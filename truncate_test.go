@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	s := "0123456789abcdefghij"
+	tests := []struct {
+		strategy TruncateStrategy
+		want     string
+	}{
+		{strategy: TruncateHead, want: "01234567\n[12 bytes truncated ...]"},
+		{strategy: TruncateTail, want: "[... 12 bytes truncated]\ncdefghij"},
+		{strategy: TruncateMiddle, want: "0123\n[... 12 bytes truncated ...]\nghij"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			assert.Equal(t, tt.want, truncate(s, 8, tt.strategy))
+			assert.Equal(t, "short", truncate("short", 8, tt.strategy))
+
+			// Multi-byte characters are never split
+			truncated := truncate(strings.Repeat("é", 10), 7, tt.strategy)
+			assert.True(t, utf8.ValidString(truncated), truncated)
+		})
+	}
+}
+
+func TestToolResultLimit(t *testing.T) {
+	var toolMessage string
+	var summarized bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch {
+		case body.Model == "cheap-model":
+			summarized = true
+			writeCompletion(w, "cheap-model", "a short summary")
+		case len(body.Messages) == 1:
+			writeToolCall(w, "model", "read_file", `{}`)
+		default:
+			toolMessage = body.Messages[len(body.Messages)-1].Content
+			writeCompletion(w, "model", "done")
+		}
+	}
+	tools := WithTools([]Tool{MockTool{name: "read_file", executeFunc: func(ctx context.Context, input map[string]any) (any, error) {
+		return "BEGIN" + strings.Repeat("x", 1000) + "END", nil
+	}}})
+
+	tests := []struct {
+		name   string
+		policy TruncatePolicy
+		want   []string
+	}{
+		{name: "default", policy: TruncatePolicy{MaxBytes: 100}, want: []string{"BEGIN", "END", "[... 908 bytes truncated ...]"}},
+		{name: "tail", policy: TruncatePolicy{MaxBytes: 100, Strategy: TruncateTail}, want: []string{"END", "[... 908 bytes truncated]"}},
+		{name: "summarize", policy: TruncatePolicy{MaxBytes: 100, Strategy: TruncateSummarize, Model: "cheap-model"}, want: []string{"[summarized from 1008 bytes]\na short summary"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summarized = false
+			testAgent := newTestAgent(t, "model", handler, tools, WithToolResultLimit(tt.policy))
+			completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+			require.NoError(t, err)
+
+			for _, want := range tt.want {
+				assert.Contains(t, toolMessage, want)
+			}
+			assert.Equal(t, tt.policy.Strategy == TruncateSummarize, summarized)
+			require.Len(t, completion.Warnings, 1)
+			assert.Contains(t, completion.Warnings[0], "tool read_file result truncated from 1008 bytes to 100")
+		})
+	}
+
+	// Results within the limit are untouched
+	testAgent := newTestAgent(t, "model", handler, tools, WithToolResultLimit(TruncatePolicy{MaxBytes: 2000}))
+	completion, err := testAgent.ChatCompletion(context.Background(), []Message{UserTextMessage("Hi")})
+	require.NoError(t, err)
+	assert.Len(t, toolMessage, 1008)
+	assert.Empty(t, completion.Warnings)
+}
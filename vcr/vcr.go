@@ -0,0 +1,279 @@
+// Package vcr records model provider HTTP traffic to fixture files and replays
+// it, so integration tests can run hermetically.
+//
+//	recorder, err := vcr.New("testdata/cassettes/weather.json", vcr.ModeAuto)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer recorder.Stop()
+//
+//	client := openai.NewClient(option.WithHTTPClient(recorder.Client()), ...)
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode controls whether a Recorder records or replays traffic
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and fails on unmatched requests
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the network and records them, overwriting the cassette
+	ModeRecord
+	// ModeAuto replays if the cassette exists and records otherwise
+	ModeAuto
+)
+
+// ErrNoMatch is returned in replay mode when no recorded interaction matches a request
+var ErrNoMatch = errors.New("no recorded interaction matches request")
+
+// scrubbedHeaders are removed from recorded requests and responses
+var scrubbedHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "Openai-Organization", "Openai-Project", "Cookie", "Set-Cookie"}
+
+// scrubbedParams are removed from recorded request URLs
+var scrubbedParams = []string{"key", "api-key", "api_key"}
+
+// Request is a recorded HTTP request
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Matcher reports whether a recorded request matches a live one
+type Matcher func(recorded Request, live Request) bool
+
+// DefaultMatcher matches on method, URL, and body, comparing JSON bodies semantically
+func DefaultMatcher(recorded Request, live Request) bool {
+	return recorded.Method == live.Method && recorded.URL == live.URL && bodiesEqual(recorded.Body, live.Body)
+}
+
+// Option is a functional option for configuring a Recorder
+type Option func(*Recorder)
+
+// WithMatcher sets how live requests are matched to recorded interactions
+func WithMatcher(matcher Matcher) Option {
+	return func(r *Recorder) {
+		r.matcher = matcher
+	}
+}
+
+// WithTransport sets the transport used to reach the network when recording
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// Recorder is an http.RoundTripper that records or replays interactions with a cassette file
+type Recorder struct {
+	path      string
+	mode      Mode
+	matcher   Matcher
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New creates a Recorder for the cassette at path
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		matcher:   DefaultMatcher,
+		transport: http.DefaultTransport,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.mode == ModeAuto {
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		} else {
+			r.mode = ModeRecord
+		}
+	}
+
+	if r.mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+
+	return r, nil
+}
+
+// Mode returns the resolved mode of the recorder
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an HTTP client that uses the recorder as its transport
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	live, err := captureRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, live)
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: live,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    scrubHeaders(resp.Header),
+			Body:       string(body),
+		},
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay serves the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, live Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || !r.matcher(interaction.Request, live) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			StatusCode:    interaction.Response.StatusCode,
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Headers.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoMatch, live.Method, live.URL)
+}
+
+// Stop writes recorded interactions to the cassette. It does nothing in replay mode.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0o644)
+}
+
+// captureRequest reads and restores the request body and returns a scrubbed copy of the request
+func captureRequest(req *http.Request) (Request, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return Request{}, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return Request{
+		Method:  req.Method,
+		URL:     scrubURL(req.URL),
+		Headers: scrubHeaders(req.Header),
+		Body:    string(body),
+	}, nil
+}
+
+// scrubHeaders returns a copy of headers without credentials
+func scrubHeaders(headers http.Header) http.Header {
+	scrubbed := headers.Clone()
+	for _, name := range scrubbedHeaders {
+		scrubbed.Del(name)
+	}
+	return scrubbed
+}
+
+// scrubURL returns the URL without credential query parameters
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for _, name := range scrubbedParams {
+		query.Del(name)
+	}
+	scrubbed.RawQuery = query.Encode()
+	return scrubbed.String()
+}
+
+// bodiesEqual compares bodies as JSON when both parse, and as strings otherwise
+func bodiesEqual(a string, b string) bool {
+	var va, vb any
+	if json.Unmarshal([]byte(a), &va) == nil && json.Unmarshal([]byte(b), &vb) == nil {
+		ja, _ := json.Marshal(va)
+		jb, _ := json.Marshal(vb)
+		return bytes.Equal(ja, jb)
+	}
+	return a == b
+}
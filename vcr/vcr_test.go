@@ -0,0 +1,71 @@
+package vcr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"recorded answer"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	cassette := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+
+	run := func(recorder *Recorder) (agent.Completion, error) {
+		client := openai.NewClient(
+			option.WithAPIKey("sk-secret-test-key"),
+			option.WithBaseURL(server.URL),
+			option.WithHTTPClient(recorder.Client()),
+			option.WithMaxRetries(0),
+		)
+		return agent.NewAgentWithClient(client, "test-model").ChatCompletion(context.Background(), []agent.Message{
+			agent.UserTextMessage("Hello"),
+		})
+	}
+
+	// Record against the live server
+	recorder, err := New(cassette, ModeAuto)
+	require.NoError(t, err)
+	assert.Equal(t, ModeRecord, recorder.Mode())
+
+	completion, err := run(recorder)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recorded answer"}, completion.Messages)
+	require.NoError(t, recorder.Stop())
+
+	data, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-secret-test-key")
+
+	// Replay with the server gone
+	server.Close()
+	recorder, err = New(cassette, ModeAuto)
+	require.NoError(t, err)
+	assert.Equal(t, ModeReplay, recorder.Mode())
+
+	completion, err = run(recorder)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recorded answer"}, completion.Messages)
+
+	// Each interaction is replayed once
+	_, err = run(recorder)
+	assert.ErrorIs(t, err, ErrNoMatch)
+}
+
+func TestBodiesEqual(t *testing.T) {
+	assert.True(t, bodiesEqual(`{"a":1,"b":2}`, `{"b":2, "a":1}`))
+	assert.False(t, bodiesEqual(`{"a":1}`, `{"a":2}`))
+	assert.True(t, bodiesEqual("plain", "plain"))
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Document is a chunk of text stored in a VectorStore
+type Document struct {
+	ID        string         `json:"id"`
+	Text      string         `json:"text"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Embedding []float32      `json:"-"`
+}
+
+// ScoredDocument is a Document returned from a query with its similarity score
+type ScoredDocument struct {
+	Document
+	Score float32 `json:"score"`
+}
+
+// VectorStore stores documents by embedding and retrieves the most similar ones
+type VectorStore interface {
+	// Upsert inserts documents, replacing any with the same ID
+	Upsert(ctx context.Context, documents []Document) error
+	// Query returns the k documents most similar to the embedding, most similar first
+	Query(ctx context.Context, embedding []float32, k int) ([]ScoredDocument, error)
+	// Delete removes the documents with the given IDs
+	Delete(ctx context.Context, ids []string) error
+}
+
+// TextEmbedder converts texts to embeddings. *Embedder implements it.
+type TextEmbedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// MemoryVectorStore is an in-memory VectorStore using cosine similarity.
+// It is safe for concurrent use.
+type MemoryVectorStore struct {
+	mu        sync.RWMutex
+	documents map[string]Document
+}
+
+// NewMemoryVectorStore creates an empty MemoryVectorStore
+func NewMemoryVectorStore() *MemoryVectorStore {
+	return &MemoryVectorStore{
+		documents: map[string]Document{},
+	}
+}
+
+func (s *MemoryVectorStore) Upsert(ctx context.Context, documents []Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, doc := range documents {
+		if doc.ID == "" {
+			return fmt.Errorf("document ID is required")
+		}
+		s.documents[doc.ID] = doc
+	}
+	return nil
+}
+
+func (s *MemoryVectorStore) Query(ctx context.Context, embedding []float32, k int) ([]ScoredDocument, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]ScoredDocument, 0, len(s.documents))
+	for _, doc := range s.documents {
+		results = append(results, ScoredDocument{Document: doc, Score: cosineSimilarity(embedding, doc.Embedding)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func (s *MemoryVectorStore) Delete(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.documents, id)
+	}
+	return nil
+}
+
+// cosineSimilarity returns the cosine similarity of a and b, or 0 if they differ in length
+func cosineSimilarity(a []float32, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// RetrievalTool is a Tool that embeds the model's query, searches a VectorStore,
+// and returns the top-k matching chunks
+type RetrievalTool struct {
+	embedder TextEmbedder
+	store    VectorStore
+	k        int
+}
+
+// NewRetrievalTool creates a RetrievalTool returning up to k documents per query
+func NewRetrievalTool(embedder TextEmbedder, store VectorStore, k int) *RetrievalTool {
+	return &RetrievalTool{
+		embedder: embedder,
+		store:    store,
+		k:        k,
+	}
+}
+
+func (r *RetrievalTool) Name() string {
+	return "search_knowledge_base"
+}
+
+func (r *RetrievalTool) Description() string {
+	return "Search the knowledge base for passages relevant to a query"
+}
+
+func (r *RetrievalTool) Parameters() Parameters {
+	return Parameters{
+		Properties: map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to search for",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (r *RetrievalTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	query, ok := input["query"].(string)
+	if !ok {
+		return nil, fmt.Errorf("query must be a string")
+	}
+
+	embeddings, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(embeddings))
+	}
+
+	return r.store.Query(ctx, embeddings[0], r.k)
+}
+
+// WithKnowledgeBase gives the agent a retrieval tool over the store, returning up to k documents per search
+func WithKnowledgeBase(embedder TextEmbedder, store VectorStore, k int) AgentOption {
+	return func(a *Agent) {
+		a.knowledgeBase = NewRetrievalTool(embedder, store, k)
+	}
+}
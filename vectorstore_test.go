@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmbedder embeds texts from a fixed lookup table
+type fakeEmbedder map[string][]float32
+
+func (f fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = f[text]
+	}
+	return embeddings, nil
+}
+
+func TestMemoryVectorStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryVectorStore()
+
+	require.NoError(t, store.Upsert(ctx, []Document{
+		{ID: "cats", Text: "Cats purr", Embedding: []float32{1, 0}},
+		{ID: "dogs", Text: "Dogs bark", Embedding: []float32{0, 1}},
+		{ID: "pets", Text: "Pets are nice", Embedding: []float32{1, 1}},
+	}))
+
+	results, err := store.Query(ctx, []float32{1, 0.1}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "cats", results[0].ID)
+	assert.Equal(t, "pets", results[1].ID)
+
+	require.NoError(t, store.Delete(ctx, []string{"cats"}))
+	results, err = store.Query(ctx, []float32{1, 0.1}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "pets", results[0].ID)
+
+	assert.Error(t, store.Upsert(ctx, []Document{{Text: "no id"}}))
+}
+
+func TestRetrievalTool(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryVectorStore()
+	require.NoError(t, store.Upsert(ctx, []Document{
+		{ID: "cats", Text: "Cats purr", Embedding: []float32{1, 0}},
+		{ID: "dogs", Text: "Dogs bark", Embedding: []float32{0, 1}},
+	}))
+
+	tool := NewRetrievalTool(fakeEmbedder{"purring": {1, 0}}, store, 1)
+	result, err := tool.Execute(ctx, map[string]any{"query": "purring"})
+	require.NoError(t, err)
+
+	documents := result.([]ScoredDocument)
+	require.Len(t, documents, 1)
+	assert.Equal(t, "Cats purr", documents[0].Text)
+}
+
+func TestWithKnowledgeBase(t *testing.T) {
+	testAgent := NewAgent("test-key", "https://api.example.com", "test-model",
+		WithKnowledgeBase(fakeEmbedder{}, NewMemoryVectorStore(), 3),
+		WithTools([]Tool{MockTool{name: "other"}}),
+	)
+
+	tools := testAgent.allTools(context.Background())
+	require.Len(t, tools, 2)
+	assert.Equal(t, "search_knowledge_base", tools[1].Name())
+}
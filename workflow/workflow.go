@@ -0,0 +1,250 @@
+// Package workflow runs graphs of agents, tools, and Go functions that pass a
+// typed state between nodes, with conditional edges, loops, and parallel branches.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	agent "github.com/campbel/go-agents"
+)
+
+// End is the name of the implicit terminal node
+const End = "__end__"
+
+// ErrMaxSteps is returned when a run visits more nodes than the configured maximum
+var ErrMaxSteps = errors.New("workflow exceeded maximum steps")
+
+// NodeFunc is a step of a workflow. It receives the current state and returns the next state.
+type NodeFunc[S any] func(ctx context.Context, state S) (S, error)
+
+// Condition picks the next node from the state after a node runs. It returns End to stop.
+type Condition[S any] func(state S) string
+
+// MergeFunc combines the states produced by parallel branches into one state
+type MergeFunc[S any] func(state S, branches []S) (S, error)
+
+// NodeError is returned when a node fails
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("workflow node %q: %v", e.Node, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// Option is a functional option for configuring a Graph
+type Option func(*settings)
+
+type settings struct {
+	maxSteps int
+}
+
+// WithMaxSteps bounds the number of nodes a run may visit, guarding against endless loops (default: 100)
+func WithMaxSteps(n int) Option {
+	return func(s *settings) {
+		s.maxSteps = n
+	}
+}
+
+// Graph is a workflow of named nodes connected by edges. Build it with the Add
+// methods and SetEntry, then call Run; a Graph is safe for concurrent runs once built.
+type Graph[S any] struct {
+	settings   settings
+	entry      string
+	nodes      map[string]NodeFunc[S]
+	edges      map[string]string
+	conditions map[string]Condition[S]
+}
+
+// New creates an empty graph
+func New[S any](opts ...Option) *Graph[S] {
+	g := &Graph[S]{
+		settings:   settings{maxSteps: 100},
+		nodes:      map[string]NodeFunc[S]{},
+		edges:      map[string]string{},
+		conditions: map[string]Condition[S]{},
+	}
+
+	for _, opt := range opts {
+		opt(&g.settings)
+	}
+
+	return g
+}
+
+// AddNode adds a node. The first node added is the entry unless SetEntry is called.
+func (g *Graph[S]) AddNode(name string, fn NodeFunc[S]) *Graph[S] {
+	if g.entry == "" {
+		g.entry = name
+	}
+	g.nodes[name] = fn
+	return g
+}
+
+// AddParallel adds a node that runs the branch nodes concurrently, each starting
+// from the current state, and combines their results with merge. Branches must not
+// mutate shared references in the state. The branches' own edges are not followed;
+// the graph continues from the parallel node's edges.
+func (g *Graph[S]) AddParallel(name string, merge MergeFunc[S], branches ...string) *Graph[S] {
+	return g.AddNode(name, func(ctx context.Context, state S) (S, error) {
+		results := make([]S, len(branches))
+		errs := make([]error, len(branches))
+
+		var wg sync.WaitGroup
+		for i, branch := range branches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = g.runNode(ctx, branch, state)
+			}()
+		}
+		wg.Wait()
+
+		if err := errors.Join(errs...); err != nil {
+			return state, err
+		}
+		return merge(state, results)
+	})
+}
+
+// AddEdge always continues from one node to another
+func (g *Graph[S]) AddEdge(from string, to string) *Graph[S] {
+	g.edges[from] = to
+	delete(g.conditions, from)
+	return g
+}
+
+// AddConditionalEdge continues from a node to the node named by condition.
+// Pointing a condition back at an earlier node forms a loop.
+func (g *Graph[S]) AddConditionalEdge(from string, condition Condition[S]) *Graph[S] {
+	g.conditions[from] = condition
+	delete(g.edges, from)
+	return g
+}
+
+// SetEntry sets the node a run starts from
+func (g *Graph[S]) SetEntry(name string) *Graph[S] {
+	g.entry = name
+	return g
+}
+
+// Run executes the graph from the entry node until a node has no outgoing edge
+// or an edge leads to End, and returns the final state. On failure it returns
+// the state as of the last completed node.
+func (g *Graph[S]) Run(ctx context.Context, state S) (S, error) {
+	if err := g.validate(); err != nil {
+		return state, err
+	}
+
+	node := g.entry
+	for steps := 0; node != End; steps++ {
+		if steps >= g.settings.maxSteps {
+			return state, ErrMaxSteps
+		}
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+
+		next, err := g.runNode(ctx, node, state)
+		if err != nil {
+			return state, err
+		}
+		state = next
+
+		node = g.next(node, state)
+	}
+	return state, nil
+}
+
+// runNode runs a single node, wrapping its error with the node name
+func (g *Graph[S]) runNode(ctx context.Context, name string, state S) (S, error) {
+	fn, ok := g.nodes[name]
+	if !ok {
+		return state, fmt.Errorf("workflow node %q does not exist", name)
+	}
+	next, err := fn(ctx, state)
+	if err != nil {
+		var nodeErr *NodeError
+		if errors.As(err, &nodeErr) {
+			return state, err
+		}
+		return state, &NodeError{Node: name, Err: err}
+	}
+	return next, nil
+}
+
+// next returns the node after name for the given state
+func (g *Graph[S]) next(name string, state S) string {
+	if condition, ok := g.conditions[name]; ok {
+		return condition(state)
+	}
+	if to, ok := g.edges[name]; ok {
+		return to
+	}
+	return End
+}
+
+// validate checks that the entry and every static edge refer to known nodes
+func (g *Graph[S]) validate() error {
+	if _, ok := g.nodes[g.entry]; !ok {
+		return fmt.Errorf("workflow entry node %q does not exist", g.entry)
+	}
+	for from, to := range g.edges {
+		if _, ok := g.nodes[from]; !ok {
+			return fmt.Errorf("workflow edge from unknown node %q", from)
+		}
+		if _, ok := g.nodes[to]; !ok && to != End {
+			return fmt.Errorf("workflow edge from %q to unknown node %q", from, to)
+		}
+	}
+	for from := range g.conditions {
+		if _, ok := g.nodes[from]; !ok {
+			return fmt.Errorf("workflow edge from unknown node %q", from)
+		}
+	}
+	return nil
+}
+
+// AgentNode runs an agent as a node. input builds the conversation from the
+// state and output stores the completion in the state.
+func AgentNode[S any](
+	a agent.ChatAgent,
+	input func(state S) []agent.Message,
+	output func(state S, completion agent.Completion) S,
+) NodeFunc[S] {
+	return func(ctx context.Context, state S) (S, error) {
+		completion, err := a.ChatCompletion(ctx, input(state))
+		if err != nil {
+			return state, err
+		}
+		return output(state, completion), nil
+	}
+}
+
+// ToolNode runs a tool as a node. input builds the arguments from the state and
+// output stores the result in the state.
+func ToolNode[S any](
+	tool agent.Tool,
+	input func(state S) map[string]any,
+	output func(state S, result any) S,
+) NodeFunc[S] {
+	return func(ctx context.Context, state S) (S, error) {
+		args := input(state)
+		if err := agent.ValidateArguments(tool.Parameters(), args); err != nil {
+			return state, &agent.ToolExecutionError{Tool: tool.Name(), Err: err}
+		}
+		result, err := tool.Execute(ctx, args)
+		if err != nil {
+			return state, &agent.ToolExecutionError{Tool: tool.Name(), Err: err}
+		}
+		return output(state, result), nil
+	}
+}
@@ -0,0 +1,161 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	agent "github.com/campbel/go-agents"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type state struct {
+	Draft    string
+	Attempts int
+	Reviews  []string
+}
+
+func TestGraphConditionalLoop(t *testing.T) {
+	graph := New[state]().
+		AddNode("write", func(ctx context.Context, s state) (state, error) {
+			s.Attempts++
+			s.Draft = fmt.Sprintf("draft %d", s.Attempts)
+			return s, nil
+		}).
+		AddNode("publish", func(ctx context.Context, s state) (state, error) {
+			s.Draft += " (published)"
+			return s, nil
+		}).
+		AddConditionalEdge("write", func(s state) string {
+			if s.Attempts < 3 {
+				return "write"
+			}
+			return "publish"
+		})
+
+	result, err := graph.Run(context.Background(), state{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, "draft 3 (published)", result.Draft)
+}
+
+func TestGraphParallel(t *testing.T) {
+	review := func(name string) NodeFunc[state] {
+		return func(ctx context.Context, s state) (state, error) {
+			s.Reviews = []string{name + " approves " + s.Draft}
+			return s, nil
+		}
+	}
+
+	graph := New[state]().
+		AddNode("draft", func(ctx context.Context, s state) (state, error) {
+			s.Draft = "v1"
+			return s, nil
+		}).
+		AddNode("style", review("style")).
+		AddNode("security", review("security")).
+		AddParallel("review", func(s state, branches []state) (state, error) {
+			for _, branch := range branches {
+				s.Reviews = append(s.Reviews, branch.Reviews...)
+			}
+			slices.Sort(s.Reviews)
+			return s, nil
+		}, "style", "security").
+		AddEdge("draft", "review")
+
+	result, err := graph.Run(context.Background(), state{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"security approves v1", "style approves v1"}, result.Reviews)
+}
+
+func TestGraphErrors(t *testing.T) {
+	boom := errors.New("boom")
+
+	t.Run("node error", func(t *testing.T) {
+		graph := New[state]().
+			AddNode("ok", func(ctx context.Context, s state) (state, error) {
+				s.Attempts = 1
+				return s, nil
+			}).
+			AddNode("fail", func(ctx context.Context, s state) (state, error) { return s, boom }).
+			AddEdge("ok", "fail")
+
+		result, err := graph.Run(context.Background(), state{})
+		var nodeErr *NodeError
+		require.ErrorAs(t, err, &nodeErr)
+		assert.Equal(t, "fail", nodeErr.Node)
+		assert.ErrorIs(t, err, boom)
+		assert.Equal(t, 1, result.Attempts)
+	})
+
+	t.Run("max steps", func(t *testing.T) {
+		graph := New[state](WithMaxSteps(5)).
+			AddNode("spin", func(ctx context.Context, s state) (state, error) { return s, nil }).
+			AddEdge("spin", "spin")
+
+		_, err := graph.Run(context.Background(), state{})
+		assert.ErrorIs(t, err, ErrMaxSteps)
+	})
+
+	t.Run("unknown edge target", func(t *testing.T) {
+		graph := New[state]().
+			AddNode("start", func(ctx context.Context, s state) (state, error) { return s, nil }).
+			AddEdge("start", "missing")
+
+		_, err := graph.Run(context.Background(), state{})
+		assert.ErrorContains(t, err, "missing")
+	})
+}
+
+func TestAgentAndToolNodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"a haiku"}}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	t.Cleanup(server.Close)
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	writer := agent.NewAgentWithClient(client, "test-model")
+
+	graph := New[state]().
+		AddNode("write", AgentNode(writer,
+			func(s state) []agent.Message { return []agent.Message{agent.UserTextMessage("write a haiku")} },
+			func(s state, completion agent.Completion) state {
+				s.Draft = completion.Messages[0]
+				return s
+			},
+		)).
+		AddNode("count", ToolNode[state](lengthTool{},
+			func(s state) map[string]any { return map[string]any{"text": s.Draft} },
+			func(s state, result any) state {
+				s.Attempts = result.(int)
+				return s
+			},
+		)).
+		AddEdge("write", "count")
+
+	result, err := graph.Run(context.Background(), state{})
+	require.NoError(t, err)
+	assert.Equal(t, "a haiku", result.Draft)
+	assert.Equal(t, 7, result.Attempts)
+}
+
+type lengthTool struct{}
+
+func (lengthTool) Name() string        { return "length" }
+func (lengthTool) Description() string { return "Count characters" }
+func (lengthTool) Parameters() agent.Parameters {
+	return agent.Parameters{
+		Properties: map[string]any{"text": agent.StringSchema("The text")},
+		Required:   []string{"text"},
+	}
+}
+func (lengthTool) Execute(ctx context.Context, input map[string]any) (any, error) {
+	return len(input["text"].(string)), nil
+}